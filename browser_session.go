@@ -0,0 +1,21 @@
+package main
+
+import (
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+)
+
+const (
+	defaultSessionIdleTimeout = 8 * time.Hour
+	defaultSessionMaxAge      = 7 * 24 * time.Hour
+)
+
+// browserSessionConfig ends browser sessions SESSION_IDLE_TIMEOUT after they were last used, and
+// SESSION_MAX_AGE after the login however much they are used.
+func browserSessionConfig() model.BrowserSessionConfig {
+	return model.BrowserSessionConfig{
+		IdleTimeout: envDuration("SESSION_IDLE_TIMEOUT", defaultSessionIdleTimeout),
+		MaxAge:      envDuration("SESSION_MAX_AGE", defaultSessionMaxAge),
+	}
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchAccounts godoc
+// @Summary      Search accounts
+// @Description  List the accounts whose username contains q, all accounts without q, sorted by username.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        q       query  string  false  "Part of the username"
+// @Param        limit   query  int     false  "Max results (default 50, max 200)"
+// @Param        offset  query  int     false  "Results to skip"
+// @Produce      json
+// @Success      200  {object}  model.AccountSearchResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/accounts [get]
+func (ctrl *apiController) SearchAccounts(ctx *gin.Context) {
+	var req model.AccountSearchRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.usecase.SearchAccounts(requestContext(ctx), req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// GetAccount godoc
+// @Summary      Get an account
+// @Description  Get the account with its roles, whether it is locked and whether it has to reset its password.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Produce      json
+// @Success      200  {object}  model.AdminAccountResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/accounts/{username} [get]
+func (ctrl *apiController) GetAccount(ctx *gin.Context) {
+	rsp, err := ctrl.usecase.GetAccount(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(adminAccountStatus(err), errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ListFailedLogins godoc
+// @Summary      List failed logins of an account
+// @Description  List the latest logins of the account that did not go through, newest first, from the audit log.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Produce      json
+// @Success      200  {object}  model.AuditQueryResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/accounts/{username}/failed-logins [get]
+func (ctrl *apiController) ListFailedLogins(ctx *gin.Context) {
+	rsp, err := ctrl.usecase.ListFailedLogins(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(adminAccountStatus(err), errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// LockAccount godoc
+// @Summary      Lock an account
+// @Description  Stop the account from logging in. Its browser sessions, personal access tokens and OAuth refresh tokens stop working until it is unlocked.
+// @Description  The reason is kept in the audit log and sent with an account.locked event.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string                    true   "Username"
+// @Param        lockAccountRequest body model.LockAccountRequest false "Lock Account Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.AdminAccountResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/accounts/{username}/lock [post]
+func (ctrl *apiController) LockAccount(ctx *gin.Context) {
+	var req model.LockAccountRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+	}
+
+	rsp, err := ctrl.usecase.LockAccount(requestContext(ctx), ctx.Param("username"), req)
+	if err != nil {
+		ctx.JSON(adminAccountStatus(err), errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// UnlockAccount godoc
+// @Summary      Unlock an account
+// @Description  Let a locked account log in again. It also lifts the block of too many failed login attempts.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Produce      json
+// @Success      200  {object}  model.AdminAccountResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/accounts/{username}/unlock [post]
+func (ctrl *apiController) UnlockAccount(ctx *gin.Context) {
+	rsp, err := ctrl.usecase.UnlockAccount(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(adminAccountStatus(err), errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ResetPassword godoc
+// @Summary      Force a password reset
+// @Description  Give the account a temporary password, only returned here. At its next login the account has to send new_password
+// @Description  with it, which replaces it. Only user accounts kept by the service have a password to reset.
+// @Description  Sessions and tokens the account already has keep working, lock the account to stop them.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Produce      json
+// @Success      200  {object}  model.PasswordResetResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError "Not A User Account Kept By The Service"
+// @Router       /admin/accounts/{username}/password-reset [post]
+func (ctrl *apiController) ResetPassword(ctx *gin.Context) {
+	rsp, err := ctrl.usecase.ResetPassword(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		ctx.JSON(adminAccountStatus(err), errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// SetAccountRoles godoc
+// @Summary      Set the roles of an account
+// @Description  Replace the roles of the account. Directory accounts get their roles from their groups and cannot be changed here.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Param        accountRolesRequest body model.AccountRolesRequest true "Account Roles Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.AdminAccountResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError "Account Kept By The Directory"
+// @Router       /admin/accounts/{username}/roles [put]
+func (ctrl *apiController) SetAccountRoles(ctx *gin.Context) {
+	var req model.AccountRolesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.usecase.SetAccountRoles(requestContext(ctx), ctx.Param("username"), req)
+	if err != nil {
+		ctx.JSON(adminAccountStatus(err), errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// adminAccountStatus is the status of a change support staff could not make to an account.
+func adminAccountStatus(err error) int {
+	switch err {
+	case model.ErrAccountNotFound:
+		return http.StatusNotFound
+	case model.ErrInvalidRole:
+		return http.StatusBadRequest
+	case model.ErrPasswordResetNotAllowed, model.ErrAccountFromDirectory:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newAccountAdminTestRoute(t *testing.T) (*gin.Engine, *model.MockUsecaseHandler) {
+	ctrl := gomock.NewController(t)
+	mockUsecase := model.NewMockUsecaseHandler(ctrl)
+	controller := NewController(mockUsecase, model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl),
+		model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl),
+		model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockUsecase
+}
+
+func TestAccountAdmin(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+	account := &model.AdminAccountResponse{ID: "id", Username: "alice", Type: "user", Roles: []string{"staff"}}
+
+	testCase := []struct {
+		name          string
+		method        string
+		path          string
+		body          interface{}
+		buildStubs    func(usecase *model.MockUsecaseHandler)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Search",
+			method: "GET",
+			path:   "/api/admin/accounts?q=ali&limit=10&offset=20",
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().SearchAccounts(gomock.Any(), model.AccountSearchRequest{Query: "ali", Limit: 10, Offset: 20}).
+					Return(&model.AccountSearchResponse{Accounts: []model.AdminAccountResponse{*account}}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				var rsp model.AccountSearchResponse
+				require.NoError(t, json.Unmarshal(r.Body.Bytes(), &rsp))
+				require.Equal(t, "alice", rsp.Accounts[0].Username)
+			},
+		},
+		{
+			name:   "GetNotFound",
+			method: "GET",
+			path:   "/api/admin/accounts/bob",
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().GetAccount(gomock.Any(), "bob").Return(nil, model.ErrAccountNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name:   "FailedLogins",
+			method: "GET",
+			path:   "/api/admin/accounts/alice/failed-logins",
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().ListFailedLogins(gomock.Any(), "alice").
+					Return(&model.AuditQueryResponse{Events: []model.AuditEventResponse{{ID: 1, Type: model.AuditLoginWrongPassword}}}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), model.AuditLoginWrongPassword)
+			},
+		},
+		{
+			name:   "Lock",
+			method: "POST",
+			path:   "/api/admin/accounts/alice/lock",
+			body:   model.LockAccountRequest{Reason: "phishing"},
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().LockAccount(gomock.Any(), "alice", model.LockAccountRequest{Reason: "phishing"}).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:   "LockWithoutBody",
+			method: "POST",
+			path:   "/api/admin/accounts/alice/lock",
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().LockAccount(gomock.Any(), "alice", model.LockAccountRequest{}).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:   "Unlock",
+			method: "POST",
+			path:   "/api/admin/accounts/alice/unlock",
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().UnlockAccount(gomock.Any(), "alice").Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:   "PasswordReset",
+			method: "POST",
+			path:   "/api/admin/accounts/alice/password-reset",
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().ResetPassword(gomock.Any(), "alice").
+					Return(&model.PasswordResetResponse{Username: "alice", TemporaryPassword: "Temp0rary"}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "Temp0rary")
+			},
+		},
+		{
+			name:   "PasswordResetNotAllowed",
+			method: "POST",
+			path:   "/api/admin/accounts/billing/password-reset",
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().ResetPassword(gomock.Any(), "billing").Return(nil, model.ErrPasswordResetNotAllowed)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+			},
+		},
+		{
+			name:   "Roles",
+			method: "PUT",
+			path:   "/api/admin/accounts/alice/roles",
+			body:   model.AccountRolesRequest{Roles: []string{"staff"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().SetAccountRoles(gomock.Any(), "alice", model.AccountRolesRequest{Roles: []string{"staff"}}).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+			},
+		},
+		{
+			name:   "InvalidRole",
+			method: "PUT",
+			path:   "/api/admin/accounts/alice/roles",
+			body:   model.AccountRolesRequest{Roles: []string{"help desk"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().SetAccountRoles(gomock.Any(), "alice", gomock.Any()).Return(nil, model.ErrInvalidRole)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+			},
+		},
+		{
+			name:   "DirectoryRoles",
+			method: "PUT",
+			path:   "/api/admin/accounts/bob/roles",
+			body:   model.AccountRolesRequest{Roles: []string{"staff"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler) {
+				usecase.EXPECT().SetAccountRoles(gomock.Any(), "bob", gomock.Any()).Return(nil, model.ErrAccountFromDirectory)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockUsecase := newAccountAdminTestRoute(t)
+			tc.buildStubs(mockUsecase)
+
+			var body []byte
+			if tc.body != nil {
+				body, _ = json.Marshal(tc.body)
+			}
+			httpReq, _ := http.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(t, r)
+		})
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		route, mockUsecase := newAccountAdminTestRoute(t)
+		mockUsecase.EXPECT().ResetPassword(gomock.Any(), gomock.Any()).Times(0)
+
+		httpReq, _ := http.NewRequest("POST", "/api/admin/accounts/alice/password-reset", nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusUnauthorized, r.Code)
+	})
+}
@@ -0,0 +1,487 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+const accountHome = "/account/"
+
+var (
+	errPasswordMismatch  = errors.New("Passwords do not match")
+	errSomethingWrong    = errors.New("Something went wrong, please try again later")
+	errAccountPageDenied = errors.New("This page has expired, please go back and try again")
+)
+
+// accountPage is the data of the pages of the account portal.
+type accountPage struct {
+	// Username is the account signed in, empty on the pages before signing in.
+	Username  string
+	CSRFToken string
+	// Redirect is where to go after signing in.
+	Redirect string
+	// Form is what was entered, shown again when it is not accepted. Passwords are never shown again.
+	Form map[string]string
+	// Errors are the reasons the fields named by their keys were not accepted, Reason is the reason
+	// for the whole form.
+	Errors      map[string]string
+	Reason      string
+	Notice      string
+	OTPRequired bool
+	// PasswordResetRequired asks for a new password on the login page, after support staff reset it.
+	PasswordResetRequired bool
+	TOTPEnabled           bool
+	TOTP                  *model.TOTPEnrollment
+	Sessions              []model.BrowserSessionResponse
+}
+
+type accountSignupForm struct {
+	Username        string `form:"username"`
+	Password        string `form:"password"`
+	ConfirmPassword string `form:"confirm_password"`
+}
+
+type accountLoginForm struct {
+	Username        string `form:"username"`
+	Password        string `form:"password"`
+	OTP             string `form:"otp"`
+	NewPassword     string `form:"new_password"`
+	ConfirmPassword string `form:"confirm_password"`
+	Redirect        string `form:"rd"`
+}
+
+type accountPasswordForm struct {
+	CurrentPassword string `form:"current_password"`
+	OTP             string `form:"otp"`
+	Password        string `form:"password"`
+	ConfirmPassword string `form:"confirm_password"`
+}
+
+type accountTOTPForm struct {
+	CurrentPassword string `form:"current_password"`
+	Secret          string `form:"secret"`
+	URI             string `form:"uri"`
+	Enrollment      string `form:"enrollment"`
+	Code            string `form:"code"`
+}
+
+// AccountHome shows the account signed in, with links to what it can change.
+func (ctrl *apiController) AccountHome(ctx *gin.Context) {
+	renderHTML(ctx, http.StatusOK, "account_home.html", newAccountPage(ctx))
+}
+
+// AccountSignupPage shows the form to create an account.
+func (ctrl *apiController) AccountSignupPage(ctx *gin.Context) {
+	renderHTML(ctx, http.StatusOK, "account_signup.html", newAccountPage(ctx))
+}
+
+// AccountSignup creates an account like POST /api/accounts, then signs the browser in to it.
+func (ctrl *apiController) AccountSignup(ctx *gin.Context) {
+	page := newAccountPage(ctx)
+	if !ctrl.originAllowed(ctx) {
+		page.Reason = errAccountPageDenied.Error()
+		renderHTML(ctx, http.StatusForbidden, "account_signup.html", page)
+		return
+	}
+	var form accountSignupForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Reason = err.Error()
+		renderHTML(ctx, http.StatusBadRequest, "account_signup.html", page)
+		return
+	}
+	page.Form["username"] = form.Username
+
+	req := model.AccountRequest{Username: form.Username, Password: form.Password}
+	if err := req.Validate(); err != nil {
+		page.Errors[validationField(err)] = err.Error()
+	} else if form.Password != form.ConfirmPassword {
+		page.Errors["confirm_password"] = errPasswordMismatch.Error()
+	}
+	if len(page.Errors) > 0 {
+		renderHTML(ctx, http.StatusBadRequest, "account_signup.html", page)
+		return
+	}
+
+	rsp, err := ctrl.usecase.CreateAccount(requestContext(ctx), req)
+	if err != nil {
+		switch err {
+		case model.ErrAccountIsAlreadyExisted:
+			page.Errors["username"] = rsp.Reason
+			renderHTML(ctx, http.StatusConflict, "account_signup.html", page)
+		case model.ErrAccountRequestValidationFailed:
+			page.Reason = rsp.Reason
+			renderHTML(ctx, http.StatusBadRequest, "account_signup.html", page)
+		case model.ErrHookDenied:
+			page.Reason = rsp.Reason
+			renderHTML(ctx, http.StatusForbidden, "account_signup.html", page)
+		default:
+			page.Reason = errSomethingWrong.Error()
+			renderHTML(ctx, http.StatusInternalServerError, "account_signup.html", page)
+		}
+		return
+	}
+	ctrl.accountSignedIn(ctx, page, "account_signup.html", form.Username, accountHome)
+}
+
+// AccountLoginPage shows the form to sign in, which goes to rd afterwards.
+func (ctrl *apiController) AccountLoginPage(ctx *gin.Context) {
+	renderHTML(ctx, http.StatusOK, "account_login.html", newAccountPage(ctx))
+}
+
+// AccountLogin logs in like POST /api/login, asking for the one-time code of accounts that have a
+// second factor, and signs the browser in.
+func (ctrl *apiController) AccountLogin(ctx *gin.Context) {
+	page := newAccountPage(ctx)
+	if !ctrl.originAllowed(ctx) {
+		page.Reason = errAccountPageDenied.Error()
+		renderHTML(ctx, http.StatusForbidden, "account_login.html", page)
+		return
+	}
+	var form accountLoginForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Reason = err.Error()
+		renderHTML(ctx, http.StatusBadRequest, "account_login.html", page)
+		return
+	}
+	page.Form["username"] = form.Username
+	page.OTPRequired = form.OTP != ""
+	if form.NewPassword != form.ConfirmPassword {
+		page.PasswordResetRequired = true
+		page.Errors["confirm_password"] = errPasswordMismatch.Error()
+		renderHTML(ctx, http.StatusBadRequest, "account_login.html", page)
+		return
+	}
+
+	login := model.AccountRequest{Username: form.Username, Password: form.Password, OTP: form.OTP, NewPassword: form.NewPassword}
+	rsp, err := ctrl.usecase.LoginAccount(requestContext(ctx), login)
+	if err != nil {
+		page.OTPRequired = page.OTPRequired || err == model.ErrLoginOTPRequired || err == model.ErrLoginWrongOTP
+		// A temporary password from support staff goes through once a new password is chosen.
+		if err == model.ErrLoginPasswordResetRequired || (err == model.ErrAccountRequestValidationFailed && form.NewPassword != "") {
+			page.PasswordResetRequired = true
+			if err == model.ErrAccountRequestValidationFailed {
+				page.Errors["new_password"] = rsp.Reason
+				renderHTML(ctx, http.StatusBadRequest, "account_login.html", page)
+				return
+			}
+		}
+		ctrl.accountLoginFailed(ctx, "account_login.html", page, rsp, err)
+		return
+	}
+	ctrl.accountSignedIn(ctx, page, "account_login.html", rsp.Username, ctrl.accountRedirect(form.Redirect))
+}
+
+// AccountLogout signs the browser out.
+func (ctrl *apiController) AccountLogout(ctx *gin.Context) {
+	principal := currentPrincipal(ctx)
+	if err := ctrl.sessions.RevokeSession(requestContext(ctx), principal, principal.TokenID); err != nil &&
+		err != model.ErrBrowserSessionNotFound {
+		renderHTML(ctx, http.StatusInternalServerError, "account_error.html", accountPage{Reason: errSomethingWrong.Error()})
+		return
+	}
+
+	ctrl.clearSessionCookie(ctx)
+	ctx.Redirect(http.StatusSeeOther, "/account/login")
+}
+
+// AccountPasswordPage shows the form to change the password.
+func (ctrl *apiController) AccountPasswordPage(ctx *gin.Context) {
+	renderHTML(ctx, http.StatusOK, "account_password.html", newAccountPage(ctx))
+}
+
+// AccountPassword sets a new password once the current one, and one-time code when TOTP is linked,
+// re-authenticated the account.
+func (ctrl *apiController) AccountPassword(ctx *gin.Context) {
+	page := newAccountPage(ctx)
+	var form accountPasswordForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Reason = err.Error()
+		renderHTML(ctx, http.StatusBadRequest, "account_password.html", page)
+		return
+	}
+
+	req := model.SetPasswordRequest{Password: form.Password}
+	if err := req.Validate(); err != nil {
+		page.Errors["password"] = err.Error()
+	} else if form.Password != form.ConfirmPassword {
+		page.Errors["confirm_password"] = errPasswordMismatch.Error()
+	}
+	if len(page.Errors) > 0 {
+		renderHTML(ctx, http.StatusBadRequest, "account_password.html", page)
+		return
+	}
+
+	reauth, ok := ctrl.accountReauthenticate(ctx, "account_password.html", &page, form.CurrentPassword, form.OTP)
+	if !ok {
+		return
+	}
+	rsp, err := ctrl.identities.SetPassword(requestContext(ctx), currentPrincipal(ctx), reauth, req)
+	if err != nil {
+		if err == model.ErrAccountRequestValidationFailed {
+			page.Errors["password"] = rsp.Reason
+			renderHTML(ctx, http.StatusBadRequest, "account_password.html", page)
+			return
+		}
+		page.Reason = errSomethingWrong.Error()
+		renderHTML(ctx, http.StatusInternalServerError, "account_password.html", page)
+		return
+	}
+
+	page.Notice = "Your password has been changed."
+	renderHTML(ctx, http.StatusOK, "account_password.html", page)
+}
+
+// AccountTOTPPage shows whether TOTP is linked, or a new secret to add to an authenticator app.
+func (ctrl *apiController) AccountTOTPPage(ctx *gin.Context) {
+	page := newAccountPage(ctx)
+	enrollment, err := ctrl.identities.BeginTOTP(requestContext(ctx), currentPrincipal(ctx))
+	if err != nil {
+		if err == model.ErrLoginMethodAlreadyLinked {
+			page.TOTPEnabled = true
+			renderHTML(ctx, http.StatusOK, "account_2fa.html", page)
+			return
+		}
+		page.Reason = errSomethingWrong.Error()
+		renderHTML(ctx, http.StatusInternalServerError, "account_2fa.html", page)
+		return
+	}
+
+	page.TOTP = enrollment
+	renderHTML(ctx, http.StatusOK, "account_2fa.html", page)
+}
+
+// AccountTOTP links the secret of the page once the password re-authenticated the account and the
+// authenticator app gave its first code.
+func (ctrl *apiController) AccountTOTP(ctx *gin.Context) {
+	page := newAccountPage(ctx)
+	var form accountTOTPForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Reason = err.Error()
+		renderHTML(ctx, http.StatusBadRequest, "account_2fa.html", page)
+		return
+	}
+	// The secret is shown again as it was, only the signed enrollment is trusted.
+	page.TOTP = &model.TOTPEnrollment{Secret: form.Secret, URI: form.URI, Enrollment: form.Enrollment}
+
+	reauth, ok := ctrl.accountReauthenticate(ctx, "account_2fa.html", &page, form.CurrentPassword, "")
+	if !ok {
+		return
+	}
+	confirm := model.ConfirmTOTPRequest{Enrollment: form.Enrollment, Code: strings.TrimSpace(form.Code)}
+	if _, err := ctrl.identities.ConfirmTOTP(requestContext(ctx), currentPrincipal(ctx), reauth, confirm); err != nil {
+		switch err {
+		case model.ErrLoginWrongOTP:
+			page.Errors["code"] = err.Error()
+			renderHTML(ctx, http.StatusBadRequest, "account_2fa.html", page)
+		case model.ErrInvalidEnrollment:
+			// The enrollment expired, the page links to a new secret.
+			page.TOTP, page.Reason = nil, err.Error()
+			renderHTML(ctx, http.StatusBadRequest, "account_2fa.html", page)
+		case model.ErrLoginMethodAlreadyLinked:
+			page.TOTP, page.TOTPEnabled = nil, true
+			renderHTML(ctx, http.StatusOK, "account_2fa.html", page)
+		default:
+			page.Reason = errSomethingWrong.Error()
+			renderHTML(ctx, http.StatusInternalServerError, "account_2fa.html", page)
+		}
+		return
+	}
+
+	page.TOTP, page.TOTPEnabled = nil, true
+	page.Notice = "Two-factor authentication is on. You will be asked for a code from your app when you sign in."
+	renderHTML(ctx, http.StatusOK, "account_2fa.html", page)
+}
+
+// AccountSessionsPage lists the browsers the account is signed in to.
+func (ctrl *apiController) AccountSessionsPage(ctx *gin.Context) {
+	page := newAccountPage(ctx)
+	sessions, err := ctrl.sessions.ListSessions(requestContext(ctx), currentPrincipal(ctx))
+	if err != nil {
+		page.Reason = errSomethingWrong.Error()
+		renderHTML(ctx, http.StatusInternalServerError, "account_sessions.html", page)
+		return
+	}
+
+	page.Sessions = sessions
+	renderHTML(ctx, http.StatusOK, "account_sessions.html", page)
+}
+
+// AccountRevokeSession signs the account out of a browser, this one included.
+func (ctrl *apiController) AccountRevokeSession(ctx *gin.Context) {
+	principal := currentPrincipal(ctx)
+	if err := ctrl.sessions.RevokeSession(requestContext(ctx), principal, ctx.Param("id")); err != nil {
+		switch err {
+		case model.ErrInvalidBrowserSessionID:
+			renderHTML(ctx, http.StatusBadRequest, "account_error.html", accountPage{Reason: err.Error()})
+		case model.ErrBrowserSessionNotFound:
+			renderHTML(ctx, http.StatusNotFound, "account_error.html", accountPage{Reason: err.Error()})
+		default:
+			renderHTML(ctx, http.StatusInternalServerError, "account_error.html", accountPage{Reason: errSomethingWrong.Error()})
+		}
+		return
+	}
+
+	if ctx.Param("id") == principal.TokenID {
+		ctrl.clearSessionCookie(ctx)
+		ctx.Redirect(http.StatusSeeOther, "/account/login")
+		return
+	}
+	ctx.Redirect(http.StatusSeeOther, "/account/sessions")
+}
+
+// accountPageAuth only lets through browsers signed in to a session, and sends the others to the login
+// page. Forms that change something must bring the CSRF token of the session.
+func (ctrl *apiController) accountPageAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		cookie, _ := ctx.Cookie(sessionCookie)
+		if cookie == "" {
+			accountLoginRedirect(ctx)
+			return
+		}
+		session, err := ctrl.sessions.Authenticate(requestContext(ctx), cookie)
+		if err != nil {
+			if errors.Is(err, model.ErrInvalidToken) {
+				ctrl.clearSessionCookie(ctx)
+				accountLoginRedirect(ctx)
+				return
+			}
+			renderHTML(ctx, http.StatusInternalServerError, "account_error.html", accountPage{Reason: errSomethingWrong.Error()})
+			ctx.Abort()
+			return
+		}
+		if !validCSRFToken(ctx, session) {
+			renderHTML(ctx, http.StatusForbidden, "account_error.html", accountPage{
+				Username:  session.Principal.Username,
+				CSRFToken: session.CSRFToken,
+				Reason:    errAccountPageDenied.Error(),
+			})
+			ctx.Abort()
+			return
+		}
+		ctx.Set(principalKey, session.Principal)
+		ctx.Set(sessionKey, session)
+		ctx.Set(actorKey, session.Principal.Username)
+		ctx.Next()
+	}
+}
+
+// accountLoginRedirect sends the browser to the login page, to come back to the page it asked for.
+func accountLoginRedirect(ctx *gin.Context) {
+	target := "/account/login"
+	if ctx.Request.Method == http.MethodGet {
+		target += "?" + url.Values{"rd": {ctx.Request.URL.RequestURI()}}.Encode()
+	}
+	ctx.Redirect(http.StatusSeeOther, target)
+	ctx.Abort()
+}
+
+// accountSignedIn signs the browser in to a new session of the account and sends it to redirect.
+// Accounts that may not have a session, like vouchers, get the form again with the reason.
+func (ctrl *apiController) accountSignedIn(ctx *gin.Context, page accountPage, name string, username string, redirect string) {
+	session, err := ctrl.sessions.CreateSession(requestContext(ctx), username)
+	if err != nil {
+		status := loginStatus(err)
+		if status == http.StatusInternalServerError {
+			page.Reason = errSomethingWrong.Error()
+		} else {
+			page.Reason = err.Error()
+		}
+		renderHTML(ctx, status, name, page)
+		return
+	}
+	ctrl.setSessionCookie(ctx, session.Token, session.EndsAt)
+	ctx.Redirect(http.StatusSeeOther, redirect)
+}
+
+// accountLoginFailed shows the form again with the reason the login failed.
+func (ctrl *apiController) accountLoginFailed(ctx *gin.Context, name string, page accountPage, rsp *model.AccountResponse, err error) {
+	status := loginStatus(err)
+	if rsp == nil || status == http.StatusInternalServerError {
+		page.Reason = errSomethingWrong.Error()
+		renderHTML(ctx, http.StatusInternalServerError, name, page)
+		return
+	}
+	page.Reason = rsp.Reason
+	renderHTML(ctx, status, name, page)
+}
+
+// accountReauthenticate checks the current password of the account before a change to how it signs in,
+// and shows the form again when it is wrong.
+func (ctrl *apiController) accountReauthenticate(ctx *gin.Context, name string, page *accountPage, password string, otp string) (string, bool) {
+	req := model.ReauthenticateRequest{Password: password, OTP: otp}
+	rsp, err := ctrl.identities.Reauthenticate(requestContext(ctx), currentPrincipal(ctx), req)
+	if err != nil {
+		switch err {
+		case model.ErrLoginWrongPassword:
+			page.Errors["current_password"] = err.Error()
+			renderHTML(ctx, http.StatusUnauthorized, name, page)
+		case model.ErrLoginOTPRequired, model.ErrLoginWrongOTP:
+			page.OTPRequired = true
+			page.Errors["otp"] = err.Error()
+			renderHTML(ctx, http.StatusUnauthorized, name, page)
+		default:
+			status := loginStatus(err)
+			page.Reason = err.Error()
+			if status == http.StatusInternalServerError {
+				page.Reason = errSomethingWrong.Error()
+			}
+			renderHTML(ctx, status, name, page)
+		}
+		return "", false
+	}
+	return rsp.ReauthToken, true
+}
+
+// accountRedirect is where to go after signing in: rd when it is a page of this service, or of a host
+// sharing the session cookie, and the account home otherwise.
+func (ctrl *apiController) accountRedirect(rd string) string {
+	if rd == "" || strings.Contains(rd, "\\") {
+		return accountHome
+	}
+	u, err := url.Parse(rd)
+	if err != nil {
+		return accountHome
+	}
+	if u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/") && !strings.HasPrefix(rd, "//") {
+		return u.String()
+	}
+	domain := strings.ToLower(strings.TrimPrefix(ctrl.sessionCookie.domain, "."))
+	host := strings.ToLower(u.Hostname())
+	if (u.Scheme == "http" || u.Scheme == "https") && domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+		return u.String()
+	}
+	return accountHome
+}
+
+// newAccountPage has the account and CSRF token of the session accountPageAuth let through, and rd
+// from the query string or form.
+func newAccountPage(ctx *gin.Context) accountPage {
+	page := accountPage{
+		Redirect: ctx.Query("rd"),
+		Form:     map[string]string{},
+		Errors:   map[string]string{},
+	}
+	if page.Redirect == "" {
+		page.Redirect = ctx.PostForm("rd")
+	}
+	if value, ok := ctx.Get(sessionKey); ok {
+		session := value.(*model.BrowserSession)
+		page.Username = session.Principal.Username
+		page.CSRFToken = session.CSRFToken
+	}
+	return page
+}
+
+// validationField is the field of an account form that the error of Validate is about.
+func validationField(err error) string {
+	switch err {
+	case model.ErrInvalidUsernameFormat, model.ErrUsernameIsTooLarge, model.ErrUsernameIsTooShort:
+		return "username"
+	}
+	return "password"
+}
@@ -0,0 +1,507 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type accountPageMocks struct {
+	usecase    *model.MockUsecaseHandler
+	identities *model.MockIdentityUsecase
+	sessions   *model.MockBrowserSessionUsecase
+}
+
+func newAccountPageTestRoute(t *testing.T) (*gin.Engine, accountPageMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := accountPageMocks{
+		usecase:    model.NewMockUsecaseHandler(ctrl),
+		identities: model.NewMockIdentityUsecase(ctrl),
+		sessions:   model.NewMockBrowserSessionUsecase(ctrl),
+	}
+	controller := NewController(mocks.usecase, model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), mocks.identities, model.NewMockAccountingUsecase(ctrl),
+		model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), mocks.sessions, nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mocks
+}
+
+// serveAccountPage sends a request to the account portal, signed in to session when it is not nil.
+func serveAccountPage(route *gin.Engine, method string, path string, form url.Values, session *model.BrowserSession) *httptest.ResponseRecorder {
+	httpReq, _ := http.NewRequest(method, path, strings.NewReader(form.Encode()))
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if session != nil {
+		httpReq.AddCookie(&http.Cookie{Name: sessionCookie, Value: session.Token})
+	}
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+	return r
+}
+
+func TestAccountPageAuth(t *testing.T) {
+	session := newTestBrowserSession()
+
+	t.Run("NotSignedIn", func(t *testing.T) {
+		route, _ := newAccountPageTestRoute(t)
+		r := serveAccountPage(route, "GET", "/account/sessions", nil, nil)
+		require.Equal(t, http.StatusSeeOther, r.Code)
+		require.Equal(t, "/account/login?rd=%2Faccount%2Fsessions", r.Header().Get("Location"))
+	})
+
+	t.Run("Assets", func(t *testing.T) {
+		route, _ := newAccountPageTestRoute(t)
+		r := serveAccountPage(route, "GET", "/account/assets/account.css", nil, nil)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Header().Get("Content-Type"), "text/css")
+	})
+
+	t.Run("SessionExpired", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(nil, model.ErrInvalidToken)
+		r := serveAccountPage(route, "GET", "/account/", nil, session)
+		require.Equal(t, http.StatusSeeOther, r.Code)
+		require.True(t, strings.HasPrefix(r.Header().Get("Location"), "/account/login"))
+		require.Negative(t, findCookie(r, sessionCookie).MaxAge)
+	})
+
+	t.Run("Home", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		r := serveAccountPage(route, "GET", "/account/", nil, session)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Welcome, alice")
+		require.Contains(t, r.Body.String(), `name="csrf_token" value="csrf-token"`)
+	})
+
+	t.Run("LogoutWithoutCSRFToken", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		r := serveAccountPage(route, "POST", "/account/logout", nil, session)
+		require.Equal(t, http.StatusForbidden, r.Code)
+		require.Nil(t, findCookie(r, sessionCookie))
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.sessions.EXPECT().RevokeSession(gomock.Any(), session.Principal, session.ID.String()).Return(nil)
+		r := serveAccountPage(route, "POST", "/account/logout", url.Values{"csrf_token": {"csrf-token"}}, session)
+		require.Equal(t, http.StatusSeeOther, r.Code)
+		require.Equal(t, "/account/login", r.Header().Get("Location"))
+		require.Negative(t, findCookie(r, sessionCookie).MaxAge)
+	})
+}
+
+func TestAccountSignup(t *testing.T) {
+	session := newTestBrowserSession()
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		buildStubs    func(mocks accountPageMocks)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "confirm_password": {"Passw0rd"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().CreateAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "Passw0rd"}).
+					Return(&model.AccountResponse{Success: true}, nil)
+				mocks.sessions.EXPECT().CreateSession(gomock.Any(), "alice").Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusSeeOther, r.Code)
+				require.Equal(t, "/account/", r.Header().Get("Location"))
+				require.Equal(t, session.Token, findCookie(r, sessionCookie).Value)
+			},
+		},
+		{
+			name: "InvalidPassword",
+			form: url.Values{"username": {"alice"}, "password": {"short"}, "confirm_password": {"short"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+				require.Contains(t, r.Body.String(), `<span class="field-error" role="alert">`+model.ErrPasswordIsTooShort.Error())
+				require.Contains(t, r.Body.String(), `value="alice"`)
+				require.NotContains(t, r.Body.String(), `value="short"`)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			form: url.Values{"username": {"al"}, "password": {"Passw0rd"}, "confirm_password": {"Passw0rd"}},
+			buildStubs: func(mocks accountPageMocks) {
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrUsernameIsTooShort.Error())
+			},
+		},
+		{
+			name: "PasswordsDoNotMatch",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "confirm_password": {"Passw0rd1"}},
+			buildStubs: func(mocks accountPageMocks) {
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+				require.Contains(t, r.Body.String(), errPasswordMismatch.Error())
+			},
+		},
+		{
+			name: "AlreadyExisted",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "confirm_password": {"Passw0rd"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrAccountIsAlreadyExisted.Error()}, model.ErrAccountIsAlreadyExisted)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrAccountIsAlreadyExisted.Error())
+				require.Nil(t, findCookie(r, sessionCookie))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mocks := newAccountPageTestRoute(t)
+			tc.buildStubs(mocks)
+
+			r := serveAccountPage(route, "POST", "/account/signup", tc.form, nil)
+			tc.checkResponse(t, r)
+		})
+	}
+}
+
+func TestAccountLogin(t *testing.T) {
+	session := newTestBrowserSession()
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		buildStubs    func(mocks accountPageMocks)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "rd": {"/account/sessions"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "alice"}, nil)
+				mocks.sessions.EXPECT().CreateSession(gomock.Any(), "alice").Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusSeeOther, r.Code)
+				require.Equal(t, "/account/sessions", r.Header().Get("Location"))
+				require.NotNil(t, findCookie(r, sessionCookie))
+			},
+		},
+		{
+			name: "RedirectToOtherSite",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "rd": {"https://evil.example.net/"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "alice"}, nil)
+				mocks.sessions.EXPECT().CreateSession(gomock.Any(), "alice").Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusSeeOther, r.Code)
+				require.Equal(t, "/account/", r.Header().Get("Location"))
+			},
+		},
+		{
+			name: "OTPRequired",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginOTPRequired.Error()}, model.ErrLoginOTPRequired)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Contains(t, r.Body.String(), `name="otp"`)
+				require.Nil(t, findCookie(r, sessionCookie))
+			},
+		},
+		{
+			name: "Blocked",
+			form: url.Values{"username": {"alice"}, "password": {"wrong"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginAttemptBlocked.Error()}, model.ErrLoginAttemptBlocked)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrLoginAttemptBlocked.Error())
+			},
+		},
+		{
+			name: "PasswordResetRequired",
+			form: url.Values{"username": {"alice"}, "password": {"Temp0rary"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginPasswordResetRequired.Error()}, model.ErrLoginPasswordResetRequired)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Contains(t, r.Body.String(), `name="new_password"`)
+			},
+		},
+		{
+			name: "NewPasswordMismatch",
+			form: url.Values{"username": {"alice"}, "password": {"Temp0rary"}, "new_password": {"N3wPassword"}, "confirm_password": {"other"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+				require.Contains(t, r.Body.String(), errPasswordMismatch.Error())
+			},
+		},
+		{
+			name: "NewPasswordInvalid",
+			form: url.Values{"username": {"alice"}, "password": {"Temp0rary"}, "new_password": {"short"}, "confirm_password": {"short"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "Temp0rary", NewPassword: "short"}).
+					Return(&model.AccountResponse{Reason: model.ErrPasswordIsTooShort.Error()}, model.ErrAccountRequestValidationFailed)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrPasswordIsTooShort.Error())
+				require.Contains(t, r.Body.String(), `name="new_password"`)
+			},
+		},
+		{
+			name: "Locked",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginAccountLocked.Error()}, model.ErrLoginAccountLocked)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Contains(t, r.Body.String(), "Account is locked")
+			},
+		},
+		{
+			name: "Voucher",
+			form: url.Values{"username": {"abcde-23456"}, "password": {"abcde-23456"}},
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "ABCDE23456"}, nil)
+				mocks.sessions.EXPECT().CreateSession(gomock.Any(), "ABCDE23456").Return(nil, model.ErrVoucherNoSelfService)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrVoucherNoSelfService.Error())
+				require.Nil(t, findCookie(r, sessionCookie))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mocks := newAccountPageTestRoute(t)
+			tc.buildStubs(mocks)
+
+			r := serveAccountPage(route, "POST", "/account/login", tc.form, nil)
+			tc.checkResponse(t, r)
+		})
+	}
+}
+
+func TestAccountPassword(t *testing.T) {
+	session := newTestBrowserSession()
+	form := url.Values{"csrf_token": {"csrf-token"}, "current_password": {"Passw0rd"}, "password": {"NewPassw0rd"},
+		"confirm_password": {"NewPassw0rd"}}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		buildStubs    func(mocks accountPageMocks)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			form: form,
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.identities.EXPECT().Reauthenticate(gomock.Any(), session.Principal, model.ReauthenticateRequest{Password: "Passw0rd"}).
+					Return(&model.ReauthenticateResponse{ReauthToken: "reauth"}, nil)
+				mocks.identities.EXPECT().SetPassword(gomock.Any(), session.Principal, "reauth", model.SetPasswordRequest{Password: "NewPassw0rd"}).
+					Return(&model.AccountResponse{Success: true}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "Your password has been changed.")
+			},
+		},
+		{
+			name: "InvalidNewPassword",
+			form: url.Values{"csrf_token": {"csrf-token"}, "current_password": {"Passw0rd"}, "password": {"alllowercase1"},
+				"confirm_password": {"alllowercase1"}},
+			buildStubs: func(mocks accountPageMocks) {
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrPasswordValidationFailed.Error())
+			},
+		},
+		{
+			name: "WrongCurrentPassword",
+			form: form,
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.identities.EXPECT().Reauthenticate(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, model.ErrLoginWrongPassword)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrLoginWrongPassword.Error())
+			},
+		},
+		{
+			name: "OTPRequired",
+			form: form,
+			buildStubs: func(mocks accountPageMocks) {
+				mocks.identities.EXPECT().Reauthenticate(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, model.ErrLoginOTPRequired)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Contains(t, r.Body.String(), `name="otp"`)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mocks := newAccountPageTestRoute(t)
+			mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+			tc.buildStubs(mocks)
+
+			r := serveAccountPage(route, "POST", "/account/password", tc.form, session)
+			tc.checkResponse(t, r)
+		})
+	}
+}
+
+func TestAccountTOTP(t *testing.T) {
+	session := newTestBrowserSession()
+	enrollment := &model.TOTPEnrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/alice", Enrollment: "enrollment"}
+
+	t.Run("Begin", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.identities.EXPECT().BeginTOTP(gomock.Any(), session.Principal).Return(enrollment, nil)
+		r := serveAccountPage(route, "GET", "/account/2fa", nil, session)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), enrollment.Secret)
+		require.Contains(t, r.Body.String(), `name="enrollment" value="enrollment"`)
+	})
+
+	t.Run("AlreadyOn", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.identities.EXPECT().BeginTOTP(gomock.Any(), session.Principal).Return(nil, model.ErrLoginMethodAlreadyLinked)
+		r := serveAccountPage(route, "GET", "/account/2fa", nil, session)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Two-factor authentication is on.")
+	})
+
+	form := url.Values{"csrf_token": {"csrf-token"}, "current_password": {"Passw0rd"}, "secret": {enrollment.Secret},
+		"uri": {enrollment.URI}, "enrollment": {"enrollment"}, "code": {"123456"}}
+
+	t.Run("Confirm", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.identities.EXPECT().Reauthenticate(gomock.Any(), session.Principal, model.ReauthenticateRequest{Password: "Passw0rd"}).
+			Return(&model.ReauthenticateResponse{ReauthToken: "reauth"}, nil)
+		mocks.identities.EXPECT().ConfirmTOTP(gomock.Any(), session.Principal, "reauth",
+			model.ConfirmTOTPRequest{Enrollment: "enrollment", Code: "123456"}).Return(&model.LoginMethod{}, nil)
+		r := serveAccountPage(route, "POST", "/account/2fa", form, session)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Two-factor authentication is on.")
+	})
+
+	t.Run("WrongCode", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.identities.EXPECT().Reauthenticate(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&model.ReauthenticateResponse{ReauthToken: "reauth"}, nil)
+		mocks.identities.EXPECT().ConfirmTOTP(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, model.ErrLoginWrongOTP)
+		r := serveAccountPage(route, "POST", "/account/2fa", form, session)
+		require.Equal(t, http.StatusBadRequest, r.Code)
+		require.Contains(t, r.Body.String(), model.ErrLoginWrongOTP.Error())
+		require.Contains(t, r.Body.String(), enrollment.Secret)
+	})
+}
+
+func TestAccountSessions(t *testing.T) {
+	session := newTestBrowserSession()
+	other := uuid.New().String()
+
+	t.Run("List", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.sessions.EXPECT().ListSessions(gomock.Any(), session.Principal).Return([]model.BrowserSessionResponse{
+			{ID: session.ID.String(), UserAgent: "Firefox", Current: true},
+			{ID: other, UserAgent: "Safari"},
+		}, nil)
+		r := serveAccountPage(route, "GET", "/account/sessions", nil, session)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Firefox</strong> (this browser)")
+		require.Contains(t, r.Body.String(), "/account/sessions/"+other+"/revoke")
+	})
+
+	t.Run("RevokeOther", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.sessions.EXPECT().RevokeSession(gomock.Any(), session.Principal, other).Return(nil)
+		r := serveAccountPage(route, "POST", "/account/sessions/"+other+"/revoke", url.Values{"csrf_token": {"csrf-token"}}, session)
+		require.Equal(t, http.StatusSeeOther, r.Code)
+		require.Equal(t, "/account/sessions", r.Header().Get("Location"))
+		require.Nil(t, findCookie(r, sessionCookie))
+	})
+
+	t.Run("RevokeCurrent", func(t *testing.T) {
+		route, mocks := newAccountPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.sessions.EXPECT().RevokeSession(gomock.Any(), session.Principal, session.ID.String()).Return(nil)
+		r := serveAccountPage(route, "POST", "/account/sessions/"+session.ID.String()+"/revoke", url.Values{"csrf_token": {"csrf-token"}}, session)
+		require.Equal(t, http.StatusSeeOther, r.Code)
+		require.Equal(t, "/account/login", r.Header().Get("Location"))
+		require.Negative(t, findCookie(r, sessionCookie).MaxAge)
+	})
+}
+
+func TestAccountRedirect(t *testing.T) {
+	ctrl := &apiController{sessionCookie: sessionCookieConfig{domain: "example.com"}}
+
+	testCases := []struct {
+		rd       string
+		expected string
+	}{
+		{rd: "", expected: "/account/"},
+		{rd: "/account/sessions?x=1", expected: "/account/sessions?x=1"},
+		{rd: "//evil.example.net/", expected: "/account/"},
+		{rd: "/\\evil.example.net/", expected: "/account/"},
+		{rd: "https://wiki.example.com/page", expected: "https://wiki.example.com/page"},
+		{rd: "https://example.com/", expected: "https://example.com/"},
+		{rd: "https://badexample.com/", expected: "/account/"},
+		{rd: "javascript://example.com/%0aalert(1)", expected: "/account/"},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, ctrl.accountRedirect(tc.rd), tc.rd)
+	}
+}
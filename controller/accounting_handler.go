@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ListOnlineSessions godoc
+// @Summary      List online network sessions
+// @Description  List the sessions NASes reported with RADIUS accounting that have not stopped, oldest first.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.AccountingSessionResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/accounting/sessions [get]
+func (ctrl *apiController) ListOnlineSessions(ctx *gin.Context) {
+	rsp, err := ctrl.accounting.OnlineSessions(requestContext(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// GetAccountingUsage godoc
+// @Summary      Get network usage of an account
+// @Description  Add up the time and octets of the account's sessions that were online since the given time, 30 days ago by default.
+// @Description  Sessions that started before are counted whole.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path   string  true   "Username"
+// @Param        since     query  string  false  "RFC3339 time"
+// @Produce      json
+// @Success      200  {object}  model.AccountingUsageResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/accounting/usage/{username} [get]
+func (ctrl *apiController) GetAccountingUsage(ctx *gin.Context) {
+	var req model.AccountingUsageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.accounting.Usage(requestContext(ctx), ctx.Param("username"), req)
+	if err != nil {
+		if err == model.ErrAccountNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// DisconnectSession godoc
+// @Summary      Disconnect a network session
+// @Description  Send a Disconnect-Request (RFC 5176) to the NAS of the session. The session stops once the NAS sends its accounting Stop.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Session id"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError "Session Already Stopped"
+// @Failure      501  {object}  model.DocResponseError "Disconnect And CoA Not Configured"
+// @Failure      502  {object}  model.DocResponseError "NAS Rejected Or Did Not Answer"
+// @Router       /admin/accounting/sessions/{id}/disconnect [post]
+func (ctrl *apiController) DisconnectSession(ctx *gin.Context) {
+	err := ctrl.accounting.Disconnect(requestContext(ctx), ctx.Param("id"))
+	if err != nil {
+		ctrl.sessionError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ChangeSessionAuthorization godoc
+// @Summary      Change what a network session allows
+// @Description  Send a CoA-Request (RFC 5176) with the attributes to the NAS of the session, for example "Session-Timeout=600;Filter-Id=restricted".
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Session id"
+// @Param        sessionAuthorizationRequest body model.SessionAuthorizationRequest true "Session Authorization Request Struct"
+// @Accept       json
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError "Session Already Stopped"
+// @Failure      501  {object}  model.DocResponseError "Disconnect And CoA Not Configured"
+// @Failure      502  {object}  model.DocResponseError "NAS Rejected Or Did Not Answer"
+// @Router       /admin/accounting/sessions/{id}/coa [post]
+func (ctrl *apiController) ChangeSessionAuthorization(ctx *gin.Context) {
+	var req model.SessionAuthorizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	err := ctrl.accounting.ChangeAuthorization(requestContext(ctx), ctx.Param("id"), req)
+	if err != nil {
+		ctrl.sessionError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ctrl *apiController) sessionError(ctx *gin.Context, err error) {
+	if err == model.ErrInvalidSessionID || errors.Is(err, model.ErrInvalidAuthorizationAttribute) {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	} else if err == model.ErrAccountingSessionNotFound {
+		ctx.JSON(http.StatusNotFound, errResponse(err))
+	} else if err == model.ErrAccountingSessionStopped {
+		ctx.JSON(http.StatusConflict, errResponse(err))
+	} else if err == model.ErrDynamicAuthorizationDisabled {
+		ctx.JSON(http.StatusNotImplemented, errResponse(err))
+	} else if errors.Is(err, model.ErrDynamicAuthorizationFailed) {
+		ctx.JSON(http.StatusBadGateway, errResponse(err))
+	} else {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	}
+}
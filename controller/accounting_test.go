@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newAccountingTestRoute(t *testing.T) (*gin.Engine, *model.MockAccountingUsecase) {
+	ctrl := gomock.NewController(t)
+	mockAccounting := model.NewMockAccountingUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), mockAccounting, model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockAccounting
+}
+
+func TestGetAccountingUsage(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name   string
+		query  string
+		err    error
+		status int
+	}{
+		{name: "ok", query: "?since=2026-10-01T00:00:00Z", status: http.StatusOK},
+		{name: "not found", err: model.ErrAccountNotFound, status: http.StatusNotFound},
+		{name: "invalid since", query: "?since=yesterday", status: http.StatusBadRequest},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockAccounting := newAccountingTestRoute(t)
+			if tc.status != http.StatusBadRequest {
+				var rsp *model.AccountingUsageResponse
+				if tc.err == nil {
+					rsp = &model.AccountingUsageResponse{Username: "alice", InputOctets: 100}
+				}
+				mockAccounting.EXPECT().Usage(gomock.Any(), "alice", gomock.Any()).Return(rsp, tc.err)
+			}
+
+			httpReq, _ := http.NewRequest("GET", "/api/admin/accounting/usage/alice"+tc.query, nil)
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
+
+func TestDisconnectSession(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusNoContent},
+		{name: "invalid id", err: model.ErrInvalidSessionID, status: http.StatusBadRequest},
+		{name: "not found", err: model.ErrAccountingSessionNotFound, status: http.StatusNotFound},
+		{name: "stopped", err: model.ErrAccountingSessionStopped, status: http.StatusConflict},
+		{name: "disabled", err: model.ErrDynamicAuthorizationDisabled, status: http.StatusNotImplemented},
+		{name: "nak", err: fmt.Errorf("%w: Session-Context-Not-Found", model.ErrDynamicAuthorizationFailed), status: http.StatusBadGateway},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockAccounting := newAccountingTestRoute(t)
+			mockAccounting.EXPECT().Disconnect(gomock.Any(), "42").Return(tc.err)
+
+			httpReq, _ := http.NewRequest("POST", "/api/admin/accounting/sessions/42/disconnect", nil)
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
+
+func TestChangeSessionAuthorization(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+	req := model.SessionAuthorizationRequest{Attributes: "Session-Timeout=600"}
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusNoContent},
+		{name: "invalid attributes", err: fmt.Errorf("%w: unknown attribute", model.ErrInvalidAuthorizationAttribute), status: http.StatusBadRequest},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockAccounting := newAccountingTestRoute(t)
+			mockAccounting.EXPECT().ChangeAuthorization(gomock.Any(), "42", req).Return(tc.err)
+
+			body, _ := json.Marshal(req)
+			httpReq, _ := http.NewRequest("POST", "/api/admin/accounting/sessions/42/coa", bytes.NewReader(body))
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// adminPageSize is how many accounts or audit events a page of the admin console lists.
+const adminPageSize = 50
+
+// adminTimeLayout is how datetime-local fields send times, which the console reads as UTC.
+const adminTimeLayout = "2006-01-02T15:04"
+
+var (
+	errAdminConsoleDenied = errors.New("Your account may not use the admin console")
+	errInvalidTime        = errors.New("Invalid time, use YYYY-MM-DDTHH:MM or RFC3339")
+)
+
+// adminPage is the data of the pages of the admin console.
+type adminPage struct {
+	// Username is the support staff signed in.
+	Username  string
+	CSRFToken string
+	Reason    string
+	Notice    string
+	Errors    map[string]string
+	// Query and the links to the pages before and after are those of the account search.
+	Query    string
+	Accounts []model.AdminAccountResponse
+	PrevURL  string
+	NextURL  string
+	// Account is the account on the detail page, with its latest failed logins.
+	Account      *model.AdminAccountResponse
+	FailedLogins []model.AuditEventResponse
+	// TemporaryPassword is only shown once, right after the password was reset.
+	TemporaryPassword string
+	// Filter is what the audit log is filtered on, as entered.
+	Filter adminAuditForm
+	Events []model.AuditEventResponse
+}
+
+type adminSearchForm struct {
+	Query  string `form:"q"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+type adminAuditForm struct {
+	Type    string `form:"type"`
+	Actor   string `form:"actor"`
+	Target  string `form:"target"`
+	Outcome string `form:"outcome"`
+	Since   string `form:"since"`
+	Until   string `form:"until"`
+	Offset  int    `form:"offset" binding:"min=0"`
+}
+
+type adminLockForm struct {
+	Reason string `form:"reason" binding:"max=200"`
+}
+
+type adminRolesForm struct {
+	// Roles are separated by spaces or commas.
+	Roles string `form:"roles"`
+}
+
+// loadAdminConsoleRoles reads ADMIN_CONSOLE_ROLES, the comma separated roles that let an account use
+// the admin console, "admin" when it is not set.
+func loadAdminConsoleRoles() []string {
+	var roles []string
+	for _, role := range strings.Split(os.Getenv("ADMIN_CONSOLE_ROLES"), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		return []string{"admin"}
+	}
+	return roles
+}
+
+// adminPageAuth only lets through accounts with one of the admin console roles. It comes after
+// accountPageAuth, which signs the browser in.
+func (ctrl *apiController) adminPageAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, _ := ctx.Get(sessionKey)
+		session := value.(*model.BrowserSession)
+		for _, role := range session.Roles {
+			for _, allowed := range ctrl.adminConsoleRoles {
+				if role == allowed {
+					ctx.Next()
+					return
+				}
+			}
+		}
+		page := newAdminPage(ctx)
+		page.Reason = errAdminConsoleDenied.Error()
+		renderHTML(ctx, http.StatusForbidden, "admin_error.html", page)
+		ctx.Abort()
+	}
+}
+
+// AdminHome sends support staff to the account search.
+func (ctrl *apiController) AdminHome(ctx *gin.Context) {
+	ctx.Redirect(http.StatusSeeOther, "/admin/accounts")
+}
+
+// AdminAccountsPage lists the accounts whose username contains the search, like GET /api/admin/accounts.
+func (ctrl *apiController) AdminAccountsPage(ctx *gin.Context) {
+	page := newAdminPage(ctx)
+	var form adminSearchForm
+	if err := ctx.ShouldBindQuery(&form); err != nil {
+		page.Reason = err.Error()
+		renderHTML(ctx, http.StatusBadRequest, "admin_accounts.html", page)
+		return
+	}
+	page.Query = form.Query
+
+	// One more than a page tells whether there is a next page.
+	req := model.AccountSearchRequest{Query: form.Query, Limit: adminPageSize + 1, Offset: form.Offset}
+	rsp, err := ctrl.usecase.SearchAccounts(requestContext(ctx), req)
+	if err != nil {
+		page.Reason = errSomethingWrong.Error()
+		renderHTML(ctx, http.StatusInternalServerError, "admin_accounts.html", page)
+		return
+	}
+
+	page.Accounts = rsp.Accounts
+	query := url.Values{}
+	if form.Query != "" {
+		query.Set("q", form.Query)
+	}
+	page.PrevURL, page.NextURL = adminPageLinks("/admin/accounts", query, form.Offset, len(page.Accounts) > adminPageSize)
+	if len(page.Accounts) > adminPageSize {
+		page.Accounts = page.Accounts[:adminPageSize]
+	}
+	renderHTML(ctx, http.StatusOK, "admin_accounts.html", page)
+}
+
+// AdminAccountPage shows an account with the forms to change it and its latest failed logins.
+func (ctrl *apiController) AdminAccountPage(ctx *gin.Context) {
+	ctrl.renderAdminAccount(ctx, http.StatusOK, newAdminPage(ctx))
+}
+
+// AdminLockAccount locks the account, like POST /api/admin/accounts/{username}/lock.
+func (ctrl *apiController) AdminLockAccount(ctx *gin.Context) {
+	page := newAdminPage(ctx)
+	var form adminLockForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Errors["reason"] = err.Error()
+		ctrl.renderAdminAccount(ctx, http.StatusBadRequest, page)
+		return
+	}
+	req := model.LockAccountRequest{Reason: strings.TrimSpace(form.Reason)}
+	if _, err := ctrl.usecase.LockAccount(requestContext(ctx), ctx.Param("username"), req); err != nil {
+		ctrl.adminActionFailed(ctx, page, err)
+		return
+	}
+
+	page.Notice = "The account is locked. It cannot sign in, and its browser sessions and personal access tokens stopped working."
+	ctrl.renderAdminAccount(ctx, http.StatusOK, page)
+}
+
+// AdminUnlockAccount unlocks the account, like POST /api/admin/accounts/{username}/unlock.
+func (ctrl *apiController) AdminUnlockAccount(ctx *gin.Context) {
+	page := newAdminPage(ctx)
+	if _, err := ctrl.usecase.UnlockAccount(requestContext(ctx), ctx.Param("username")); err != nil {
+		ctrl.adminActionFailed(ctx, page, err)
+		return
+	}
+
+	page.Notice = "The account is unlocked and can sign in again."
+	ctrl.renderAdminAccount(ctx, http.StatusOK, page)
+}
+
+// AdminResetPassword gives the account a temporary password, like POST
+// /api/admin/accounts/{username}/password-reset, and shows it once.
+func (ctrl *apiController) AdminResetPassword(ctx *gin.Context) {
+	page := newAdminPage(ctx)
+	rsp, err := ctrl.usecase.ResetPassword(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		ctrl.adminActionFailed(ctx, page, err)
+		return
+	}
+
+	page.TemporaryPassword = rsp.TemporaryPassword
+	// The temporary password must not stay in the cache of the browser.
+	ctx.Header("Cache-Control", "no-store")
+	ctrl.renderAdminAccount(ctx, http.StatusOK, page)
+}
+
+// AdminSetAccountRoles replaces the roles of the account, like PUT /api/admin/accounts/{username}/roles.
+func (ctrl *apiController) AdminSetAccountRoles(ctx *gin.Context) {
+	page := newAdminPage(ctx)
+	var form adminRolesForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Errors["roles"] = err.Error()
+		ctrl.renderAdminAccount(ctx, http.StatusBadRequest, page)
+		return
+	}
+	roles := strings.FieldsFunc(form.Roles, func(r rune) bool { return r == ',' || r == ' ' })
+	req := model.AccountRolesRequest{Roles: roles}
+	if _, err := ctrl.usecase.SetAccountRoles(requestContext(ctx), ctx.Param("username"), req); err != nil {
+		if err == model.ErrInvalidRole {
+			page.Errors["roles"] = err.Error()
+			ctrl.renderAdminAccount(ctx, http.StatusBadRequest, page)
+			return
+		}
+		ctrl.adminActionFailed(ctx, page, err)
+		return
+	}
+
+	page.Notice = "The roles are saved."
+	ctrl.renderAdminAccount(ctx, http.StatusOK, page)
+}
+
+// AdminAuditPage browses the audit log, latest events first, like GET /api/admin/audit/events.
+func (ctrl *apiController) AdminAuditPage(ctx *gin.Context) {
+	page := newAdminPage(ctx)
+	if err := ctx.ShouldBindQuery(&page.Filter); err != nil {
+		page.Reason = err.Error()
+		renderHTML(ctx, http.StatusBadRequest, "admin_audit.html", page)
+		return
+	}
+	since, err := parseAdminTime(page.Filter.Since)
+	if err != nil {
+		page.Errors["since"] = err.Error()
+	}
+	until, err := parseAdminTime(page.Filter.Until)
+	if err != nil {
+		page.Errors["until"] = err.Error()
+	}
+	if len(page.Errors) > 0 {
+		renderHTML(ctx, http.StatusBadRequest, "admin_audit.html", page)
+		return
+	}
+
+	req := model.AuditQueryRequest{
+		Type:    strings.TrimSpace(page.Filter.Type),
+		Actor:   strings.TrimSpace(page.Filter.Actor),
+		Target:  strings.TrimSpace(page.Filter.Target),
+		Outcome: page.Filter.Outcome,
+		Since:   since,
+		Until:   until,
+		Limit:   adminPageSize + 1,
+		Offset:  page.Filter.Offset,
+		Newest:  true,
+	}
+	rsp, err := ctrl.audit.QueryEvents(requestContext(ctx), req)
+	if err != nil {
+		page.Reason = errSomethingWrong.Error()
+		renderHTML(ctx, http.StatusInternalServerError, "admin_audit.html", page)
+		return
+	}
+
+	page.Events = rsp.Events
+	query := url.Values{}
+	for key, value := range map[string]string{"type": page.Filter.Type, "actor": page.Filter.Actor, "target": page.Filter.Target,
+		"outcome": page.Filter.Outcome, "since": page.Filter.Since, "until": page.Filter.Until} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	page.PrevURL, page.NextURL = adminPageLinks("/admin/audit", query, page.Filter.Offset, len(page.Events) > adminPageSize)
+	if len(page.Events) > adminPageSize {
+		page.Events = page.Events[:adminPageSize]
+	}
+	renderHTML(ctx, http.StatusOK, "admin_audit.html", page)
+}
+
+// renderAdminAccount shows the account of the path with its latest failed logins, after what the
+// page says was done to it.
+func (ctrl *apiController) renderAdminAccount(ctx *gin.Context, status int, page adminPage) {
+	username := ctx.Param("username")
+	account, err := ctrl.usecase.GetAccount(requestContext(ctx), username)
+	if err == nil {
+		var logins *model.AuditQueryResponse
+		if logins, err = ctrl.usecase.ListFailedLogins(requestContext(ctx), username); err == nil {
+			page.Account, page.FailedLogins = account, logins.Events
+			renderHTML(ctx, status, "admin_account.html", page)
+			return
+		}
+	}
+	if err == model.ErrAccountNotFound {
+		page.Reason, page.Notice, page.TemporaryPassword = err.Error(), "", ""
+		renderHTML(ctx, http.StatusNotFound, "admin_error.html", page)
+		return
+	}
+	page.Reason = errSomethingWrong.Error()
+	renderHTML(ctx, http.StatusInternalServerError, "admin_error.html", page)
+}
+
+// adminActionFailed shows the account again with the reason a change could not be made.
+func (ctrl *apiController) adminActionFailed(ctx *gin.Context, page adminPage, err error) {
+	status := adminAccountStatus(err)
+	if status == http.StatusInternalServerError {
+		page.Reason = errSomethingWrong.Error()
+		renderHTML(ctx, status, "admin_error.html", page)
+		return
+	}
+	page.Reason = err.Error()
+	ctrl.renderAdminAccount(ctx, status, page)
+}
+
+// adminPageLinks are the links to the pages before and after offset, empty when there is none.
+func adminPageLinks(path string, query url.Values, offset int, more bool) (string, string) {
+	link := func(offset int) string {
+		values := url.Values{}
+		for key, value := range query {
+			values[key] = value
+		}
+		if offset > 0 {
+			values.Set("offset", strconv.Itoa(offset))
+		}
+		if len(values) == 0 {
+			return path
+		}
+		return path + "?" + values.Encode()
+	}
+	var prev, next string
+	if offset > 0 {
+		prevOffset := offset - adminPageSize
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		prev = link(prevOffset)
+	}
+	if more {
+		next = link(offset + adminPageSize)
+	}
+	return prev, next
+}
+
+// parseAdminTime reads a time of the audit filter, zero when it is empty.
+func parseAdminTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(adminTimeLayout, value)
+	if err != nil {
+		return time.Time{}, errInvalidTime
+	}
+	return t, nil
+}
+
+func newAdminPage(ctx *gin.Context) adminPage {
+	page := adminPage{Errors: map[string]string{}}
+	if value, ok := ctx.Get(sessionKey); ok {
+		session := value.(*model.BrowserSession)
+		page.Username = session.Principal.Username
+		page.CSRFToken = session.CSRFToken
+	}
+	return page
+}
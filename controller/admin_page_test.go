@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type adminPageMocks struct {
+	usecase  *model.MockUsecaseHandler
+	audit    *model.MockAuditUsecase
+	sessions *model.MockBrowserSessionUsecase
+}
+
+func newAdminPageTestRoute(t *testing.T) (*gin.Engine, adminPageMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := adminPageMocks{
+		usecase:  model.NewMockUsecaseHandler(ctrl),
+		audit:    model.NewMockAuditUsecase(ctrl),
+		sessions: model.NewMockBrowserSessionUsecase(ctrl),
+	}
+	controller := NewController(mocks.usecase, mocks.audit, model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl),
+		model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), mocks.sessions, nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mocks
+}
+
+// newTestStaffSession is a browser session of an account that may use the admin console.
+func newTestStaffSession() *model.BrowserSession {
+	session := newTestBrowserSession()
+	session.Roles = []string{"staff", "admin"}
+	return session
+}
+
+// expectAdminAccount stubs what the account page shows: the account and its failed logins.
+func expectAdminAccount(mocks adminPageMocks, account *model.AdminAccountResponse) {
+	mocks.usecase.EXPECT().GetAccount(gomock.Any(), account.Username).Return(account, nil)
+	mocks.usecase.EXPECT().ListFailedLogins(gomock.Any(), account.Username).Return(&model.AuditQueryResponse{Events: []model.AuditEventResponse{
+		{ID: 1, CreatedAt: time.Now(), Type: model.AuditLoginWrongPassword, Target: account.Username, Outcome: model.AuditOutcomeFailure, IP: "10.0.0.9"},
+	}}, nil)
+}
+
+func TestAdminPageAuth(t *testing.T) {
+	t.Run("NotSignedIn", func(t *testing.T) {
+		route, _ := newAdminPageTestRoute(t)
+		r := serveAccountPage(route, "GET", "/admin/accounts", nil, nil)
+		require.Equal(t, http.StatusSeeOther, r.Code)
+		require.Equal(t, "/account/login?rd=%2Fadmin%2Faccounts", r.Header().Get("Location"))
+	})
+
+	t.Run("NoConsoleRole", func(t *testing.T) {
+		route, mocks := newAdminPageTestRoute(t)
+		session := newTestBrowserSession()
+		session.Roles = []string{"staff"}
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.usecase.EXPECT().SearchAccounts(gomock.Any(), gomock.Any()).Times(0)
+		r := serveAccountPage(route, "GET", "/admin/accounts", nil, session)
+		require.Equal(t, http.StatusForbidden, r.Code)
+		require.Contains(t, r.Body.String(), errAdminConsoleDenied.Error())
+	})
+
+	t.Run("ConsoleRolesFromEnv", func(t *testing.T) {
+		t.Setenv("ADMIN_CONSOLE_ROLES", "helpdesk, staff")
+		route, mocks := newAdminPageTestRoute(t)
+		session := newTestBrowserSession()
+		session.Roles = []string{"staff"}
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		r := serveAccountPage(route, "GET", "/admin/", nil, session)
+		require.Equal(t, http.StatusSeeOther, r.Code)
+		require.Equal(t, "/admin/accounts", r.Header().Get("Location"))
+	})
+
+	t.Run("MissingCSRFToken", func(t *testing.T) {
+		route, mocks := newAdminPageTestRoute(t)
+		session := newTestStaffSession()
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.usecase.EXPECT().LockAccount(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		r := serveAccountPage(route, "POST", "/admin/accounts/bob/lock", url.Values{}, session)
+		require.Equal(t, http.StatusForbidden, r.Code)
+	})
+
+	t.Run("Assets", func(t *testing.T) {
+		route, _ := newAdminPageTestRoute(t)
+		r := serveAccountPage(route, "GET", "/admin/assets/admin.css", nil, nil)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Header().Get("Content-Type"), "text/css")
+	})
+}
+
+func TestAdminAccountsPage(t *testing.T) {
+	session := newTestStaffSession()
+	lockedAt := time.Now()
+
+	t.Run("Search", func(t *testing.T) {
+		route, mocks := newAdminPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		accounts := make([]model.AdminAccountResponse, adminPageSize+1)
+		for i := range accounts {
+			accounts[i] = model.AdminAccountResponse{Username: "bob", Type: "user"}
+		}
+		accounts[0] = model.AdminAccountResponse{Username: "bobby", Type: "user", Roles: []string{"staff"}, LockedAt: &lockedAt}
+		mocks.usecase.EXPECT().SearchAccounts(gomock.Any(), model.AccountSearchRequest{Query: "bob", Limit: adminPageSize + 1, Offset: 50}).
+			Return(&model.AccountSearchResponse{Accounts: accounts}, nil)
+
+		r := serveAccountPage(route, "GET", "/admin/accounts?q=bob&offset=50", nil, session)
+		require.Equal(t, http.StatusOK, r.Code)
+		body := r.Body.String()
+		require.Contains(t, body, `href="/admin/accounts/bobby"`)
+		require.Contains(t, body, "Locked")
+		require.Contains(t, body, `href="/admin/accounts?q=bob"`)
+		require.Contains(t, body, `href="/admin/accounts?offset=100&amp;q=bob"`)
+		require.Equal(t, adminPageSize, strings.Count(body, `href="/admin/accounts/bob`))
+	})
+
+	t.Run("InvalidOffset", func(t *testing.T) {
+		route, mocks := newAdminPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		r := serveAccountPage(route, "GET", "/admin/accounts?offset=-1", nil, session)
+		require.Equal(t, http.StatusBadRequest, r.Code)
+	})
+}
+
+func TestAdminAccountPage(t *testing.T) {
+	session := newTestStaffSession()
+	account := &model.AdminAccountResponse{ID: "id", Username: "bob", Type: "user", Roles: []string{"staff"}, CreatedAt: time.Now()}
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		form          url.Values
+		buildStubs    func(mocks adminPageMocks)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Detail",
+			method: "GET",
+			path:   "/admin/accounts/bob",
+			buildStubs: func(mocks adminPageMocks) {
+				expectAdminAccount(mocks, account)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				body := r.Body.String()
+				require.Contains(t, body, model.AuditLoginWrongPassword)
+				require.Contains(t, body, "10.0.0.9")
+				require.Contains(t, body, `action="/admin/accounts/bob/lock"`)
+				require.Contains(t, body, `action="/admin/accounts/bob/password-reset"`)
+				require.Contains(t, body, `value="csrf-token"`)
+			},
+		},
+		{
+			name:   "NotFound",
+			method: "GET",
+			path:   "/admin/accounts/nobody",
+			buildStubs: func(mocks adminPageMocks) {
+				mocks.usecase.EXPECT().GetAccount(gomock.Any(), "nobody").Return(nil, model.ErrAccountNotFound)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, r.Code)
+			},
+		},
+		{
+			name:   "Lock",
+			method: "POST",
+			path:   "/admin/accounts/bob/lock",
+			form:   url.Values{"csrf_token": {"csrf-token"}, "reason": {" phishing "}},
+			buildStubs: func(mocks adminPageMocks) {
+				mocks.usecase.EXPECT().LockAccount(gomock.Any(), "bob", model.LockAccountRequest{Reason: "phishing"}).Return(account, nil)
+				lockedAt := time.Now()
+				locked := *account
+				locked.LockedAt = &lockedAt
+				expectAdminAccount(mocks, &locked)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "The account is locked")
+				require.Contains(t, r.Body.String(), `action="/admin/accounts/bob/unlock"`)
+			},
+		},
+		{
+			name:   "Unlock",
+			method: "POST",
+			path:   "/admin/accounts/bob/unlock",
+			form:   url.Values{"csrf_token": {"csrf-token"}},
+			buildStubs: func(mocks adminPageMocks) {
+				mocks.usecase.EXPECT().UnlockAccount(gomock.Any(), "bob").Return(account, nil)
+				expectAdminAccount(mocks, account)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "The account is unlocked")
+			},
+		},
+		{
+			name:   "PasswordReset",
+			method: "POST",
+			path:   "/admin/accounts/bob/password-reset",
+			form:   url.Values{"csrf_token": {"csrf-token"}},
+			buildStubs: func(mocks adminPageMocks) {
+				mocks.usecase.EXPECT().ResetPassword(gomock.Any(), "bob").
+					Return(&model.PasswordResetResponse{Username: "bob", TemporaryPassword: "Xy7TemporaryPass"}, nil)
+				expectAdminAccount(mocks, account)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "Xy7TemporaryPass")
+				require.Equal(t, "no-store", r.Header().Get("Cache-Control"))
+			},
+		},
+		{
+			name:   "PasswordResetNotAllowed",
+			method: "POST",
+			path:   "/admin/accounts/bob/password-reset",
+			form:   url.Values{"csrf_token": {"csrf-token"}},
+			buildStubs: func(mocks adminPageMocks) {
+				mocks.usecase.EXPECT().ResetPassword(gomock.Any(), "bob").Return(nil, model.ErrPasswordResetNotAllowed)
+				expectAdminAccount(mocks, account)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrPasswordResetNotAllowed.Error())
+			},
+		},
+		{
+			name:   "Roles",
+			method: "POST",
+			path:   "/admin/accounts/bob/roles",
+			form:   url.Values{"csrf_token": {"csrf-token"}, "roles": {"staff, admin  ops"}},
+			buildStubs: func(mocks adminPageMocks) {
+				mocks.usecase.EXPECT().SetAccountRoles(gomock.Any(), "bob", model.AccountRolesRequest{Roles: []string{"staff", "admin", "ops"}}).
+					Return(account, nil)
+				expectAdminAccount(mocks, account)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "The roles are saved.")
+			},
+		},
+		{
+			name:   "RolesOfDirectoryAccount",
+			method: "POST",
+			path:   "/admin/accounts/bob/roles",
+			form:   url.Values{"csrf_token": {"csrf-token"}, "roles": {"staff"}},
+			buildStubs: func(mocks adminPageMocks) {
+				mocks.usecase.EXPECT().SetAccountRoles(gomock.Any(), "bob", gomock.Any()).Return(nil, model.ErrAccountFromDirectory)
+				directory := *account
+				directory.Source = "ldap"
+				expectAdminAccount(mocks, &directory)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrAccountFromDirectory.Error())
+				require.NotContains(t, r.Body.String(), `action="/admin/accounts/bob/roles"`)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mocks := newAdminPageTestRoute(t)
+			mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+			tc.buildStubs(mocks)
+
+			r := serveAccountPage(route, tc.method, tc.path, tc.form, session)
+			tc.checkResponse(t, r)
+		})
+	}
+}
+
+func TestAdminAuditPage(t *testing.T) {
+	session := newTestStaffSession()
+
+	t.Run("Filter", func(t *testing.T) {
+		route, mocks := newAdminPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		since := time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC)
+		mocks.audit.EXPECT().QueryEvents(gomock.Any(), model.AuditQueryRequest{Target: "bob", Outcome: "failure", Since: since,
+			Limit: adminPageSize + 1, Newest: true}).
+			Return(&model.AuditQueryResponse{Events: []model.AuditEventResponse{
+				{ID: 2, CreatedAt: time.Now(), Type: model.AuditAccountLocked, Actor: "alice", Target: "bob", Detail: "<phishing>"},
+			}}, nil)
+
+		r := serveAccountPage(route, "GET", "/admin/audit?target=bob&outcome=failure&since=2026-10-01T08:30", nil, session)
+		require.Equal(t, http.StatusOK, r.Code)
+		body := r.Body.String()
+		require.Contains(t, body, model.AuditAccountLocked)
+		require.Contains(t, body, "&lt;phishing&gt;")
+		require.Contains(t, body, `value="failure" selected`)
+		require.NotContains(t, body, "Next")
+	})
+
+	t.Run("InvalidTime", func(t *testing.T) {
+		route, mocks := newAdminPageTestRoute(t)
+		mocks.sessions.EXPECT().Authenticate(gomock.Any(), session.Token).Return(session, nil)
+		mocks.audit.EXPECT().QueryEvents(gomock.Any(), gomock.Any()).Times(0)
+
+		r := serveAccountPage(route, "GET", "/admin/audit?until=yesterday", nil, session)
+		require.Equal(t, http.StatusBadRequest, r.Code)
+		require.Contains(t, r.Body.String(), errInvalidTime.Error())
+	})
+}
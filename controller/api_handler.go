@@ -1,7 +1,6 @@
 package controller
 
 import (
-	"context"
 	"net/http"
 
 	_ "github.com/ambroseqiu/senao_hw/docs"
@@ -24,6 +23,7 @@ func errResponse(err error) *gin.H {
 // @Param        accountRequest body model.AccountRequest true "Account Request Struct"
 // @Success      200  {object}  model.DocResponseSuccess
 // @Failure      400  {object}  model.DocResponseBadRequest
+// @Failure      403  {object}  model.DocResponseDenied "Denied By A Hook"
 // @Failure      409  {object}  model.DocResponseAlreadyExisted "Account Is Already Existed"
 // @Router       /accounts [post]
 func (ctrl *apiController) CreateAccount(ctx *gin.Context) {
@@ -33,7 +33,7 @@ func (ctrl *apiController) CreateAccount(ctx *gin.Context) {
 		return
 	}
 
-	rsp, err := ctrl.usecase.CreateAccount(context.Background(), req)
+	rsp, err := ctrl.usecase.CreateAccount(requestContext(ctx), req)
 	if err != nil {
 		if err == model.ErrAccountRequestValidationFailed {
 			ctx.JSON(http.StatusBadRequest, rsp)
@@ -41,6 +41,9 @@ func (ctrl *apiController) CreateAccount(ctx *gin.Context) {
 		} else if err == model.ErrAccountIsAlreadyExisted {
 			ctx.JSON(http.StatusConflict, rsp)
 			return
+		} else if err == model.ErrHookDenied {
+			ctx.JSON(http.StatusForbidden, rsp)
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errResponse(err))
 		return
@@ -54,13 +57,19 @@ func (ctrl *apiController) CreateAccount(ctx *gin.Context) {
 // @Description  Login account and verify username and password
 // @Description  Note:
 // @Description  If the password verification fails five times, the user should wait one minute before attempting to verify the password again.
+// @Description  Claims added by post-login hooks are returned in claims.
+// @Description  Service accounts cannot log in with a password.
+// @Description  Accounts with TOTP linked also need otp, the current one-time code. A wrong code counts as a failed attempt.
+// @Description  With an LDAP directory configured, directory users log in with their directory password and get an account on their first login.
+// @Description  Locked accounts cannot log in. After support staff reset the password, the temporary password only goes through with new_password, which replaces it.
 // @Tags         accounts
 // @Param        accountRequest body model.AccountRequest true "Account Request Struct"
 // @Accept       json
 // @Produce      json
 // @Success      200  {object}  model.DocResponseSuccess
-// @Failure      400  {object}  model.DocResponseAccountNotFound
-// @Failure      401  {object}  model.DocResponseWrongPassword
+// @Failure      400  {object}  model.DocResponseAccountNotFound "Account Not Found, Or An Invalid New Password"
+// @Failure      401  {object}  model.DocResponseWrongPassword "Wrong Password, Or One-Time Code Missing Or Wrong"
+// @Failure      403  {object}  model.DocResponseDenied "Denied By A Hook, A Service Account, A Directory User Named Like A Local Account, A Spent Voucher, A Locked Account, Or A New Password Needed"
 // @Failure      429  {object}  model.DocResponseTooManyRequest "Too Many Failed Login Attempts"
 // @Router       /login [post]
 func (ctrl *apiController) LoginAccount(ctx *gin.Context) {
@@ -70,14 +79,10 @@ func (ctrl *apiController) LoginAccount(ctx *gin.Context) {
 		return
 	}
 
-	rsp, err := ctrl.usecase.LoginAccount(context.Background(), req)
+	rsp, err := ctrl.usecase.LoginAccount(requestContext(ctx), req)
 	if err != nil {
-		if err == model.ErrLoginAccountNotFound {
-			ctx.JSON(http.StatusBadRequest, rsp)
-		} else if err == model.ErrLoginWrongPassword {
-			ctx.JSON(http.StatusUnauthorized, rsp)
-		} else if err == model.ErrLoginAttemptBlocked {
-			ctx.JSON(http.StatusTooManyRequests, rsp)
+		if status := loginStatus(err); status != http.StatusInternalServerError {
+			ctx.JSON(status, rsp)
 		} else {
 			ctx.JSON(http.StatusInternalServerError, err)
 		}
@@ -86,3 +91,45 @@ func (ctrl *apiController) LoginAccount(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+// loginStatus is the status of a login that did not go through.
+func loginStatus(err error) int {
+	switch err {
+	case model.ErrLoginAccountNotFound, model.ErrAccountRequestValidationFailed:
+		return http.StatusBadRequest
+	case model.ErrLoginWrongPassword, model.ErrLoginOTPRequired, model.ErrLoginWrongOTP:
+		return http.StatusUnauthorized
+	case model.ErrLoginAttemptBlocked:
+		return http.StatusTooManyRequests
+	case model.ErrHookDenied, model.ErrLoginServiceAccount, model.ErrLoginNoPassword,
+		model.ErrLoginDirectoryConflict, model.ErrVoucherExpired, model.ErrVoucherDataUsed,
+		model.ErrLoginAccountLocked, model.ErrLoginPasswordResetRequired, model.ErrVoucherNoSelfService:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// DeleteAccount godoc
+// @Summary      Delete an account
+// @Description  Delete the account and notify subscribers with an account.deleted event.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Produce      json
+// @Success      200  {object}  model.DocResponseSuccess
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseAccountNotFound
+// @Router       /admin/accounts/{username} [delete]
+func (ctrl *apiController) DeleteAccount(ctx *gin.Context) {
+	rsp, err := ctrl.usecase.DeleteAccount(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		if err == model.ErrAccountNotFound {
+			ctx.JSON(http.StatusNotFound, rsp)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -105,6 +105,23 @@ func TestCreatAccount(t *testing.T) {
 				require.Equal(t, http.StatusConflict, rr.Code)
 			},
 		},
+		{
+			name: "denied by hook",
+			body: gin.H{
+				"username": username,
+				"password": password,
+			},
+			setMockExpection: func(mockUsecase *model.MockUsecaseHandler) {
+				mockUsecase.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{
+						Success: false,
+						Reason:  "Username is reserved",
+					}, model.ErrHookDenied)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
 		{
 			name: "internal server error",
 			body: gin.H{
@@ -127,7 +144,9 @@ func TestCreatAccount(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			mockUsecase := model.NewMockUsecaseHandler(ctrl)
-			controller := NewController(mockUsecase)
+			controller := NewController(mockUsecase, model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl), model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+				model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+				model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
 			route := gin.Default()
 			controller.SetRoute(route)
 			url := "/api/accounts"
@@ -242,6 +261,75 @@ func TestLoginAccount(t *testing.T) {
 				require.Equal(t, model.ErrLoginWrongPassword.Error(), rsp.Reason)
 			},
 		},
+		{
+			name: "directory user named like a local account",
+			body: gin.H{
+				"username": username,
+				"password": password,
+			},
+			setMockExpection: func(mockUsecase *model.MockUsecaseHandler) {
+				mockUsecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: username, Password: password}).
+					Return(&model.AccountResponse{
+						Success: false,
+						Reason:  model.ErrLoginDirectoryConflict.Error(),
+					}, model.ErrLoginDirectoryConflict)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+		{
+			name: "one-time code required",
+			body: gin.H{
+				"username": username,
+				"password": password,
+			},
+			setMockExpection: func(mockUsecase *model.MockUsecaseHandler) {
+				mockUsecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: username, Password: password}).
+					Return(&model.AccountResponse{
+						Success: false,
+						Reason:  model.ErrLoginOTPRequired.Error(),
+					}, model.ErrLoginOTPRequired)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "wrong one-time code",
+			body: gin.H{
+				"username": username,
+				"password": password,
+				"otp":      "123456",
+			},
+			setMockExpection: func(mockUsecase *model.MockUsecaseHandler) {
+				mockUsecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: username, Password: password, OTP: "123456"}).
+					Return(&model.AccountResponse{
+						Success: false,
+						Reason:  model.ErrLoginWrongOTP.Error(),
+					}, model.ErrLoginWrongOTP)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name: "denied by hook",
+			body: gin.H{
+				"username": username,
+				"password": password,
+			},
+			setMockExpection: func(mockUsecase *model.MockUsecaseHandler) {
+				mockUsecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{
+						Success: false,
+						Reason:  "Outside office hours",
+					}, model.ErrHookDenied)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
 		{
 			name: "internal server error",
 			body: gin.H{
@@ -264,7 +352,9 @@ func TestLoginAccount(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			mockUsecase := model.NewMockUsecaseHandler(ctrl)
-			controller := NewController(mockUsecase)
+			controller := NewController(mockUsecase, model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl), model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+				model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+				model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
 			route := gin.Default()
 			controller.SetRoute(route)
 			url := "/api/login"
@@ -289,7 +379,9 @@ func TestLoginTooManyFailedLoginAttempt(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockUsecase := model.NewMockUsecaseHandler(ctrl)
-	controller := NewController(mockUsecase)
+	controller := NewController(mockUsecase, model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl), model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+		model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
 	route := gin.Default()
 	controller.SetRoute(route)
 	url := "/api/login"
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// QueryAuditEvents godoc
+// @Summary      Query audit events
+// @Description  List security-relevant events such as account creation, login attempts and admin actions.
+// @Description  Events are ordered by id, the latest first with newest, filters are optional and combined with AND.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        type         query  string  false  "Event type, e.g. login.wrong_password"
+// @Param        type_prefix  query  string  false  "Start of the event type, e.g. login."
+// @Param        actor        query  string  false  "Who performed the action"
+// @Param        target       query  string  false  "Who or what the action was performed on"
+// @Param        outcome      query  string  false  "success or failure"
+// @Param        since        query  string  false  "RFC3339 time, inclusive"
+// @Param        until        query  string  false  "RFC3339 time, exclusive"
+// @Param        limit        query  int     false  "Page size, default 100, max 1000"
+// @Param        offset       query  int     false  "Page offset"
+// @Param        newest       query  bool    false  "List the latest events first"
+// @Produce      json
+// @Success      200  {object}  model.AuditQueryResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/audit/events [get]
+func (ctrl *apiController) QueryAuditEvents(ctx *gin.Context) {
+	var req model.AuditQueryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.audit.QueryEvents(requestContext(ctx), req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ExportAuditEvents godoc
+// @Summary      Export audit events
+// @Description  Stream every matching audit event as JSON Lines, one event per line.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        type         query  string  false  "Event type, e.g. login.wrong_password"
+// @Param        type_prefix  query  string  false  "Start of the event type, e.g. login."
+// @Param        actor        query  string  false  "Who performed the action"
+// @Param        target       query  string  false  "Who or what the action was performed on"
+// @Param        outcome      query  string  false  "success or failure"
+// @Param        since        query  string  false  "RFC3339 time, inclusive"
+// @Param        until        query  string  false  "RFC3339 time, exclusive"
+// @Param        newest       query  bool    false  "Export the latest events first"
+// @Produce      application/x-ndjson
+// @Success      200  {object}  model.AuditEventResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/audit/export [get]
+func (ctrl *apiController) ExportAuditEvents(ctx *gin.Context) {
+	var req model.AuditQueryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", `attachment; filename="audit_events.jsonl"`)
+	ctx.Status(http.StatusOK)
+	if err := ctrl.audit.ExportEvents(requestContext(ctx), req, ctx.Writer); err != nil {
+		// The status line is already sent, so the best we can do is cut the stream short.
+		ctx.Error(err)
+	}
+}
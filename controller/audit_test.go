@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryAuditEvents(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name             string
+		token            string
+		query            string
+		setMockExpection func(mockAudit *model.MockAuditUsecase)
+		checkResponse    func(*httptest.ResponseRecorder)
+	}{
+		{
+			name:  "ok",
+			token: adminAPIKey,
+			query: "?target=alice&outcome=failure",
+			setMockExpection: func(mockAudit *model.MockAuditUsecase) {
+				mockAudit.EXPECT().QueryEvents(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, req model.AuditQueryRequest) (*model.AuditQueryResponse, error) {
+						require.Equal(t, "alice", req.Target)
+						require.Equal(t, model.AuditOutcomeFailure, req.Outcome)
+						require.Equal(t, adminActor, model.RequestInfoFromContext(ctx).Actor)
+						return &model.AuditQueryResponse{
+							Events: []model.AuditEventResponse{
+								{ID: 1, Type: model.AuditLoginWrongPassword, Target: "alice", Outcome: model.AuditOutcomeFailure},
+							},
+						}, nil
+					})
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				byteData, err := ioutil.ReadAll(rr.Body)
+				require.NoError(t, err)
+
+				rsp := &model.AuditQueryResponse{}
+				err = json.Unmarshal(byteData, rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Events, 1)
+			},
+		},
+		{
+			name:  "missing token",
+			token: "",
+			setMockExpection: func(mockAudit *model.MockAuditUsecase) {
+				mockAudit.EXPECT().QueryEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:  "wrong token",
+			token: util.RandomString(32),
+			setMockExpection: func(mockAudit *model.MockAuditUsecase) {
+				mockAudit.EXPECT().QueryEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			},
+		},
+		{
+			name:  "bad request",
+			token: adminAPIKey,
+			query: "?since=yesterday",
+			setMockExpection: func(mockAudit *model.MockAuditUsecase) {
+				mockAudit.EXPECT().QueryEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockAudit := model.NewMockAuditUsecase(ctrl)
+			controller := NewController(model.NewMockUsecaseHandler(ctrl), mockAudit, model.NewMockWebhookUsecase(ctrl), model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+				model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+				model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+			route := gin.Default()
+			controller.SetRoute(route)
+			url := "/api/admin/audit/events" + tc.query
+
+			tc.setMockExpection(mockAudit)
+
+			httpReq, _ := http.NewRequest("GET", url, nil)
+			if tc.token != "" {
+				httpReq.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestExportAuditEvents(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAudit := model.NewMockAuditUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), mockAudit, model.NewMockWebhookUsecase(ctrl), model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+		model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+
+	lines := "{\"id\":1}\n{\"id\":2}\n"
+	mockAudit.EXPECT().ExportEvents(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req model.AuditQueryRequest, w io.Writer) error {
+			_, err := io.WriteString(w, lines)
+			return err
+		})
+
+	httpReq, _ := http.NewRequest("GET", "/api/admin/audit/export", nil)
+	httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, "application/x-ndjson", r.Header().Get("Content-Type"))
+	require.NotEmpty(t, r.Header().Get(requestIDHeader))
+	require.Equal(t, lines, r.Body.String())
+}
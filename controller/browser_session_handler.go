@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionCookie = "session"
+	csrfHeader    = "X-CSRF-Token"
+	// csrfFormField carries the CSRF token in HTML forms, which cannot send headers.
+	csrfFormField = "csrf_token"
+)
+
+var (
+	errInvalidCSRFToken = errors.New("Invalid CSRF token")
+	errOriginNotAllowed = errors.New("Origin is not allowed")
+)
+
+// sessionCookieConfig is how the session cookie is set. It is Secure and SameSite=Lax unless set
+// otherwise with SESSION_COOKIE_SECURE and SESSION_COOKIE_SAMESITE; Lax still sends it on links from
+// other sites, which applications behind forward auth need. SESSION_COOKIE_DOMAIN shares it with the
+// hosts under a domain.
+type sessionCookieConfig struct {
+	domain   string
+	secure   bool
+	sameSite http.SameSite
+}
+
+func loadSessionCookieConfig() sessionCookieConfig {
+	config := sessionCookieConfig{domain: os.Getenv("SESSION_COOKIE_DOMAIN"), secure: true, sameSite: http.SameSiteLaxMode}
+	if secure, err := strconv.ParseBool(os.Getenv("SESSION_COOKIE_SECURE")); err == nil {
+		config.secure = secure
+	}
+	switch strings.ToLower(os.Getenv("SESSION_COOKIE_SAMESITE")) {
+	case "strict":
+		config.sameSite = http.SameSiteStrictMode
+	case "none":
+		config.sameSite = http.SameSiteNoneMode
+	}
+	return config
+}
+
+// CreateSession godoc
+// @Summary      Sign in to a browser session
+// @Description  Log in like /login, and keep the account signed in with an HttpOnly session cookie rather than a token. The session ends after SESSION_IDLE_TIMEOUT without use and SESSION_MAX_AGE after the login.
+// @Description  Requests with the cookie that change something must send the csrf_token of the response back in the X-CSRF-Token header.
+// @Tags         accounts
+// @Param        accountRequest body model.AccountRequest true "Account Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.CurrentSessionResponse
+// @Failure      400  {object}  model.DocResponseAccountNotFound
+// @Failure      401  {object}  model.DocResponseWrongPassword "Wrong Password, Or One-Time Code Missing Or Wrong"
+// @Failure      403  {object}  model.DocResponseDenied "Denied, A Voucher, Or From An Origin That Is Not Allowed"
+// @Failure      429  {object}  model.DocResponseTooManyRequest "Too Many Failed Login Attempts"
+// @Router       /sessions [post]
+func (ctrl *apiController) CreateSession(ctx *gin.Context) {
+	// Other sites cannot sign a browser in to an account of theirs either.
+	if !ctrl.originAllowed(ctx) {
+		ctx.JSON(http.StatusForbidden, errResponse(errOriginNotAllowed))
+		return
+	}
+	var req model.AccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.usecase.LoginAccount(requestContext(ctx), req)
+	if err != nil {
+		if status := loginStatus(err); status != http.StatusInternalServerError {
+			ctx.JSON(status, rsp)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+	session, err := ctrl.sessions.CreateSession(requestContext(ctx), rsp.Username)
+	if err != nil {
+		if status := loginStatus(err); status != http.StatusInternalServerError {
+			rsp.Success, rsp.Reason = false, err.Error()
+			ctx.JSON(status, rsp)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+
+	ctrl.setSessionCookie(ctx, session.Token, session.EndsAt)
+	ctx.JSON(http.StatusCreated, currentSessionResponse(session))
+}
+
+// GetCurrentSession godoc
+// @Summary      Get the browser session
+// @Description  Get the account and CSRF token of the session the session cookie is for, for pages loaded again.
+// @Tags         accounts
+// @Produce      json
+// @Success      200  {object}  model.CurrentSessionResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /sessions/current [get]
+func (ctrl *apiController) GetCurrentSession(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, currentSessionResponse(currentSession(ctx)))
+}
+
+// DeleteCurrentSession godoc
+// @Summary      Sign out of the browser session
+// @Description  End the session the session cookie is for, and clear the cookie. Needs the X-CSRF-Token header.
+// @Tags         accounts
+// @Success      204
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /sessions/current [delete]
+func (ctrl *apiController) DeleteCurrentSession(ctx *gin.Context) {
+	principal := currentPrincipal(ctx)
+	if err := ctrl.sessions.RevokeSession(requestContext(ctx), principal, principal.TokenID); err != nil &&
+		err != model.ErrBrowserSessionNotFound {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctrl.clearSessionCookie(ctx)
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListBrowserSessions godoc
+// @Summary      List browser sessions
+// @Description  List the browsers the current account is signed in to, with where they were last seen from. Needs the sessions scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.BrowserSessionResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /accounts/me/sessions [get]
+func (ctrl *apiController) ListBrowserSessions(ctx *gin.Context) {
+	rsp, err := ctrl.sessions.ListSessions(requestContext(ctx), currentPrincipal(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// RevokeBrowserSession godoc
+// @Summary      Sign out a browser session
+// @Description  Sign the current account out of a browser, like one left signed in elsewhere. Needs the sessions scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Session ID"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /accounts/me/sessions/{id} [delete]
+func (ctrl *apiController) RevokeBrowserSession(ctx *gin.Context) {
+	principal := currentPrincipal(ctx)
+	err := ctrl.sessions.RevokeSession(requestContext(ctx), principal, ctx.Param("id"))
+	if err != nil {
+		switch err {
+		case model.ErrInvalidBrowserSessionID:
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+		case model.ErrBrowserSessionNotFound:
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+
+	if _, ok := ctx.Get(sessionKey); ok && ctx.Param("id") == principal.TokenID {
+		ctrl.clearSessionCookie(ctx)
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// currentSession is the browser session that sessionAuth let through.
+func currentSession(ctx *gin.Context) *model.BrowserSession {
+	session, _ := ctx.MustGet(sessionKey).(*model.BrowserSession)
+	return session
+}
+
+func currentSessionResponse(session *model.BrowserSession) model.CurrentSessionResponse {
+	return model.CurrentSessionResponse{
+		Username:  session.Principal.Username,
+		CSRFToken: session.CSRFToken,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// originAllowed tells whether the browser sent the request from the service itself or an allowed origin.
+// Requests without an Origin do not come from a page of another site.
+func (ctrl *apiController) originAllowed(ctx *gin.Context) bool {
+	origin := ctx.GetHeader("Origin")
+	if origin == "" {
+		return true
+	}
+	scheme := "http"
+	if ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	if strings.EqualFold(origin, scheme+"://"+ctx.Request.Host) {
+		return true
+	}
+	for _, allowed := range ctrl.allowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// setSessionCookie keeps the session cookie until the session ends. The session may expire before when
+// it is not used, which the server checks.
+func (ctrl *apiController) setSessionCookie(ctx *gin.Context, token string, expiresAt time.Time) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Domain:   ctrl.sessionCookie.domain,
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		Secure:   ctrl.sessionCookie.secure,
+		HttpOnly: true,
+		SameSite: ctrl.sessionCookie.sameSite,
+	})
+}
+
+func (ctrl *apiController) clearSessionCookie(ctx *gin.Context) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		Domain:   ctrl.sessionCookie.domain,
+		MaxAge:   -1,
+		Secure:   ctrl.sessionCookie.secure,
+		HttpOnly: true,
+		SameSite: ctrl.sessionCookie.sameSite,
+	})
+}
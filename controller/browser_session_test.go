@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type browserSessionMocks struct {
+	usecase     *model.MockUsecaseHandler
+	sessions    *model.MockBrowserSessionUsecase
+	tokens      *model.MockPersonalAccessTokenUsecase
+	forwardAuth *model.MockForwardAuthUsecase
+}
+
+func newBrowserSessionTestRoute(t *testing.T) (*gin.Engine, browserSessionMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := browserSessionMocks{
+		usecase:     model.NewMockUsecaseHandler(ctrl),
+		sessions:    model.NewMockBrowserSessionUsecase(ctrl),
+		tokens:      model.NewMockPersonalAccessTokenUsecase(ctrl),
+		forwardAuth: model.NewMockForwardAuthUsecase(ctrl),
+	}
+	controller := NewController(mocks.usecase, model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		mocks.tokens, model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl),
+		model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), mocks.forwardAuth, mocks.sessions, nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mocks
+}
+
+func newTestBrowserSession() *model.BrowserSession {
+	id := uuid.New()
+	return &model.BrowserSession{
+		ID: id,
+		Principal: &model.Principal{AccountID: uuid.New(), Username: "alice", Scopes: model.BrowserSessionScopes,
+			TokenID: id.String()},
+		Token:     "session-token",
+		CSRFToken: "csrf-token",
+		ExpiresAt: time.Now().Add(time.Hour),
+		EndsAt:    time.Now().Add(24 * time.Hour),
+	}
+}
+
+func findCookie(r *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range r.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestCreateBrowserSession(t *testing.T) {
+	session := newTestBrowserSession()
+	body, _ := json.Marshal(model.AccountRequest{Username: "alice", Password: "Password1"})
+
+	testCases := []struct {
+		name          string
+		setupRequest  func(req *http.Request)
+		buildStubs    func(mocks browserSessionMocks)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Origin", "http://example.com")
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "alice"}, nil)
+				mocks.sessions.EXPECT().CreateSession(gomock.Any(), "alice").Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, r.Code)
+				var rsp model.CurrentSessionResponse
+				require.NoError(t, json.Unmarshal(r.Body.Bytes(), &rsp))
+				require.Equal(t, "csrf-token", rsp.CSRFToken)
+
+				cookie := findCookie(r, sessionCookie)
+				require.NotNil(t, cookie)
+				require.Equal(t, "session-token", cookie.Value)
+				require.True(t, cookie.HttpOnly)
+				require.True(t, cookie.Secure)
+				require.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+				require.Equal(t, "/", cookie.Path)
+			},
+		},
+		{
+			name: "OtherOrigin",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Origin", "https://evil.example.net")
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Nil(t, findCookie(r, sessionCookie))
+			},
+		},
+		{
+			name: "WrongPassword",
+			setupRequest: func(req *http.Request) {
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginWrongPassword.Error()}, model.ErrLoginWrongPassword)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Nil(t, findCookie(r, sessionCookie))
+			},
+		},
+		{
+			name: "Voucher",
+			setupRequest: func(req *http.Request) {
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "ABCDE23456"}, nil)
+				mocks.sessions.EXPECT().CreateSession(gomock.Any(), "ABCDE23456").Return(nil, model.ErrVoucherNoSelfService)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				var rsp model.AccountResponse
+				require.NoError(t, json.Unmarshal(r.Body.Bytes(), &rsp))
+				require.False(t, rsp.Success)
+				require.Equal(t, model.ErrVoucherNoSelfService.Error(), rsp.Reason)
+				require.Nil(t, findCookie(r, sessionCookie))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mocks := newBrowserSessionTestRoute(t)
+			tc.buildStubs(mocks)
+
+			httpReq, _ := http.NewRequest("POST", "/api/sessions", bytes.NewReader(body))
+			httpReq.Host = "example.com"
+			tc.setupRequest(httpReq)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(t, r)
+		})
+	}
+}
+
+func TestBrowserSessionAuth(t *testing.T) {
+	session := newTestBrowserSession()
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		setupRequest  func(req *http.Request)
+		buildStubs    func(mocks browserSessionMocks)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ReadWithCookie",
+			method: "GET",
+			url:    "/api/accounts/me",
+			setupRequest: func(req *http.Request) {
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.sessions.EXPECT().Authenticate(gomock.Any(), "session-token").Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), `"username":"alice"`)
+			},
+		},
+		{
+			name:   "ChangeWithoutCSRFToken",
+			method: "DELETE",
+			url:    "/api/accounts/me/tokens/1",
+			setupRequest: func(req *http.Request) {
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.sessions.EXPECT().Authenticate(gomock.Any(), "session-token").Return(session, nil)
+				mocks.tokens.EXPECT().RevokeToken(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Contains(t, r.Body.String(), errInvalidCSRFToken.Error())
+			},
+		},
+		{
+			name:   "ChangeWithCSRFToken",
+			method: "DELETE",
+			url:    "/api/accounts/me/tokens/1",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set(csrfHeader, "csrf-token")
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.sessions.EXPECT().Authenticate(gomock.Any(), "session-token").Return(session, nil)
+				mocks.tokens.EXPECT().RevokeToken(gomock.Any(), session.Principal, "1").Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, r.Code)
+			},
+		},
+		{
+			name:   "ExpiredSession",
+			method: "GET",
+			url:    "/api/accounts/me",
+			setupRequest: func(req *http.Request) {
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.sessions.EXPECT().Authenticate(gomock.Any(), "session-token").Return(nil, model.ErrInvalidToken)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				cookie := findCookie(r, sessionCookie)
+				require.NotNil(t, cookie)
+				require.Negative(t, cookie.MaxAge)
+			},
+		},
+		{
+			name:   "SignOut",
+			method: "DELETE",
+			url:    "/api/sessions/current",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set(csrfHeader, "csrf-token")
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.sessions.EXPECT().Authenticate(gomock.Any(), "session-token").Return(session, nil)
+				mocks.sessions.EXPECT().RevokeSession(gomock.Any(), session.Principal, session.ID.String()).Return(nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, r.Code)
+				cookie := findCookie(r, sessionCookie)
+				require.NotNil(t, cookie)
+				require.Negative(t, cookie.MaxAge)
+			},
+		},
+		{
+			name:   "ForwardAuth",
+			method: "GET",
+			url:    "/api/auth/verify",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("X-Forwarded-Host", "wiki.example.com")
+			},
+			buildStubs: func(mocks browserSessionMocks) {
+				mocks.sessions.EXPECT().Authenticate(gomock.Any(), "session-token").Return(session, nil)
+				mocks.forwardAuth.EXPECT().Verify(gomock.Any(), session.Principal, "wiki.example.com").
+					Return(&model.ForwardAuthIdentity{ID: session.Principal.AccountID.String(), Username: "alice"}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "alice", r.Header().Get("X-Auth-User"))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mocks := newBrowserSessionTestRoute(t)
+			tc.buildStubs(mocks)
+
+			httpReq, _ := http.NewRequest(tc.method, tc.url, nil)
+			httpReq.AddCookie(&http.Cookie{Name: sessionCookie, Value: "session-token"})
+			tc.setupRequest(httpReq)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(t, r)
+		})
+	}
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://admin.example.com")
+
+	testCases := []struct {
+		name          string
+		origin        string
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Allowed",
+			origin: "https://app.example.com",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, "https://app.example.com", r.Header().Get("Access-Control-Allow-Origin"))
+				require.Equal(t, "true", r.Header().Get("Access-Control-Allow-Credentials"))
+				require.Contains(t, r.Header().Get("Access-Control-Allow-Headers"), http.CanonicalHeaderKey(csrfHeader))
+			},
+		},
+		{
+			name:   "NotAllowed",
+			origin: "https://evil.example.net",
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Empty(t, r.Header().Get("Access-Control-Allow-Origin"))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, _ := newBrowserSessionTestRoute(t)
+
+			httpReq, _ := http.NewRequest("OPTIONS", "/api/sessions", nil)
+			httpReq.Header.Set("Origin", tc.origin)
+			httpReq.Header.Set("Access-Control-Request-Method", "POST")
+			httpReq.Header.Set("Access-Control-Request-Headers", "Content-Type, X-CSRF-Token")
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(t, r)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeviceImportBytes bounds the CSV file of a device import.
+const maxDeviceImportBytes = 5 << 20
+
+// RegisterOwnDevice godoc
+// @Summary      Register a device
+// @Description  Register the MAC address of a printer, camera or other device that cannot type a password, so the network lets it on by its MAC. Needs the devices scope.
+// @Description  The device is owned by the current account and stops working when the account is deleted or at expires_at. Only admins can give it a VLAN or role.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        deviceRequest body model.DeviceRequest true "Device Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.DeviceResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError
+// @Router       /accounts/me/devices [post]
+func (ctrl *apiController) RegisterOwnDevice(ctx *gin.Context) {
+	ctrl.registerDevice(ctx, currentPrincipal(ctx))
+}
+
+// ListOwnDevices godoc
+// @Summary      List devices
+// @Description  List the devices the current account registered. Needs the devices scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.DeviceResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /accounts/me/devices [get]
+func (ctrl *apiController) ListOwnDevices(ctx *gin.Context) {
+	ctrl.listDevices(ctx, currentPrincipal(ctx))
+}
+
+// DeleteOwnDevice godoc
+// @Summary      Remove a device
+// @Description  Remove a device of the current account, the network stops letting it on. Needs the devices scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Device ID"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /accounts/me/devices/{id} [delete]
+func (ctrl *apiController) DeleteOwnDevice(ctx *gin.Context) {
+	ctrl.deleteDevice(ctx, currentPrincipal(ctx))
+}
+
+// RegisterDevice godoc
+// @Summary      Register a device for any account
+// @Description  Register the MAC address of a device for MAC authentication bypass, with an optional owner, expiry, VLAN and role.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        deviceRequest body model.DeviceRequest true "Device Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.DeviceResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError
+// @Router       /admin/devices [post]
+func (ctrl *apiController) RegisterDevice(ctx *gin.Context) {
+	ctrl.registerDevice(ctx, nil)
+}
+
+// ListDevices godoc
+// @Summary      List all devices
+// @Description  List every registered device with its owner.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.DeviceResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/devices [get]
+func (ctrl *apiController) ListDevices(ctx *gin.Context) {
+	ctrl.listDevices(ctx, nil)
+}
+
+// DeleteDevice godoc
+// @Summary      Remove any device
+// @Description  Remove a registered device, the network stops letting it on.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Device ID"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/devices/{id} [delete]
+func (ctrl *apiController) DeleteDevice(ctx *gin.Context) {
+	ctrl.deleteDevice(ctx, nil)
+}
+
+// ImportDevices godoc
+// @Summary      Import devices from CSV
+// @Description  Register many devices at once from a CSV file, sent as the body or as the file field of a form. The header row names the columns: mac, and optionally owner, description, expires_at, vlan and role.
+// @Description  MACs may be written with colons, dashes, in dotted groups of four or bare. Nothing is imported when a row is not valid, the error names every line that is not.
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Param        file  formData  file  false  "CSV file"
+// @Produce      json
+// @Success      201  {object}  model.DeviceImportResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError
+// @Router       /admin/devices/import [post]
+func (ctrl *apiController) ImportDevices(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxDeviceImportBytes)
+	var file io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		header, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		upload, err := header.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		defer upload.Close()
+		file = upload
+	}
+
+	rsp, err := ctrl.devices.ImportDevices(requestContext(ctx), file)
+	if err != nil {
+		deviceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+func (ctrl *apiController) registerDevice(ctx *gin.Context, principal *model.Principal) {
+	var req model.DeviceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.devices.RegisterDevice(requestContext(ctx), principal, req)
+	if err != nil {
+		deviceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+func (ctrl *apiController) listDevices(ctx *gin.Context, principal *model.Principal) {
+	rsp, err := ctrl.devices.ListDevices(requestContext(ctx), principal)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (ctrl *apiController) deleteDevice(ctx *gin.Context, principal *model.Principal) {
+	err := ctrl.devices.DeleteDevice(requestContext(ctx), principal, ctx.Param("id"))
+	if err != nil {
+		deviceError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func deviceError(ctx *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == model.ErrInvalidMAC || err == model.ErrInvalidDeviceExpiry || err == model.ErrInvalidDeviceID ||
+		err == model.ErrAccountNotFound || errors.Is(err, model.ErrInvalidDeviceImport):
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	case errors.As(err, &tooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, errResponse(err))
+	case err == model.ErrDeviceAdminOnly || err == model.ErrTooManyDevices:
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+	case err == model.ErrDeviceNotFound:
+		ctx.JSON(http.StatusNotFound, errResponse(err))
+	case err == model.ErrDeviceAlreadyRegistered:
+		ctx.JSON(http.StatusConflict, errResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	}
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var devicePrincipal = &model.Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{model.ScopeDevices}}
+
+func newDeviceTestRoute(t *testing.T) (*gin.Engine, *model.MockDeviceUsecase) {
+	ctrl := gomock.NewController(t)
+	mockDevices := model.NewMockDeviceUsecase(ctrl)
+	mockAuth := model.NewMockAuthenticator(ctrl)
+	mockAuth.EXPECT().Authenticate(gomock.Any(), "token").AnyTimes().Return(devicePrincipal, nil)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), mockAuth, model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), mockDevices, model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockDevices
+}
+
+func TestRegisterOwnDevice(t *testing.T) {
+	testCase := []struct {
+		name   string
+		req    model.DeviceRequest
+		err    error
+		status int
+	}{
+		{name: "ok", req: model.DeviceRequest{MAC: "00:1a:2b:3c:4d:5e"}, status: http.StatusCreated},
+		{name: "invalid mac", req: model.DeviceRequest{MAC: "printer"}, err: model.ErrInvalidMAC, status: http.StatusBadRequest},
+		{name: "admin only", req: model.DeviceRequest{MAC: "00:1a:2b:3c:4d:5e", VLAN: 20}, err: model.ErrDeviceAdminOnly, status: http.StatusForbidden},
+		{name: "too many", req: model.DeviceRequest{MAC: "00:1a:2b:3c:4d:5e"}, err: model.ErrTooManyDevices, status: http.StatusForbidden},
+		{name: "duplicated", req: model.DeviceRequest{MAC: "00:1a:2b:3c:4d:5e"}, err: model.ErrDeviceAlreadyRegistered, status: http.StatusConflict},
+		{name: "missing mac", status: http.StatusBadRequest},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockDevices := newDeviceTestRoute(t)
+			if tc.req.MAC != "" {
+				var rsp *model.DeviceResponse
+				if tc.err == nil {
+					rsp = &model.DeviceResponse{ID: uuid.NewString(), MAC: tc.req.MAC, Owner: "alice"}
+				}
+				mockDevices.EXPECT().RegisterDevice(gomock.Any(), devicePrincipal, tc.req).Return(rsp, tc.err)
+			}
+
+			body, _ := json.Marshal(tc.req)
+			httpReq, _ := http.NewRequest("POST", "/api/accounts/me/devices", bytes.NewReader(body))
+			httpReq.Header.Set("Authorization", "Bearer token")
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
+
+func TestDeleteOwnDevice(t *testing.T) {
+	route, mockDevices := newDeviceTestRoute(t)
+	id := uuid.NewString()
+	mockDevices.EXPECT().DeleteDevice(gomock.Any(), devicePrincipal, id).Return(model.ErrDeviceNotFound)
+
+	httpReq, _ := http.NewRequest("DELETE", "/api/accounts/me/devices/"+id, nil)
+	httpReq.Header.Set("Authorization", "Bearer token")
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+
+	require.Equal(t, http.StatusNotFound, r.Code)
+}
+
+func TestAdminDevices(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+	route, mockDevices := newDeviceTestRoute(t)
+
+	req := model.DeviceRequest{MAC: "00:1a:2b:3c:4d:5e", Owner: "bob", VLAN: 30}
+	mockDevices.EXPECT().RegisterDevice(gomock.Any(), nil, req).
+		Return(&model.DeviceResponse{ID: uuid.NewString(), MAC: req.MAC, Owner: "bob", VLAN: 30}, nil)
+	mockDevices.EXPECT().ListDevices(gomock.Any(), nil).Return([]model.DeviceResponse{{MAC: req.MAC}}, nil)
+
+	body, _ := json.Marshal(req)
+	httpReq, _ := http.NewRequest("POST", "/api/admin/devices", bytes.NewReader(body))
+	httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+	require.Equal(t, http.StatusCreated, r.Code)
+
+	httpReq, _ = http.NewRequest("GET", "/api/admin/devices", nil)
+	httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+	r = httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+	require.Equal(t, http.StatusOK, r.Code)
+	var rsp []model.DeviceResponse
+	require.NoError(t, json.Unmarshal(r.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 1)
+}
+
+func TestImportDevices(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+	file := "mac,owner\n00:1a:2b:3c:4d:5e,bob\n"
+
+	testCase := []struct {
+		name      string
+		multipart bool
+		err       error
+		status    int
+	}{
+		{name: "body", status: http.StatusCreated},
+		{name: "form", multipart: true, status: http.StatusCreated},
+		{name: "invalid rows", err: fmt.Errorf("%w: line 2: bad", model.ErrInvalidDeviceImport), status: http.StatusBadRequest},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockDevices := newDeviceTestRoute(t)
+			var rsp *model.DeviceImportResponse
+			if tc.err == nil {
+				rsp = &model.DeviceImportResponse{Imported: 1}
+			}
+			mockDevices.EXPECT().ImportDevices(gomock.Any(), gomock.Any()).Return(rsp, tc.err)
+
+			body := bytes.NewBufferString(file)
+			contentType := "text/csv"
+			if tc.multipart {
+				body = &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				part, _ := writer.CreateFormFile("file", "devices.csv")
+				part.Write([]byte(file))
+				writer.Close()
+				contentType = writer.FormDataContentType()
+			}
+			httpReq, _ := http.NewRequest("POST", "/api/admin/devices/import", body)
+			httpReq.Header.Set("Content-Type", contentType)
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
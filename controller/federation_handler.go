@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	federationFlowCookie = "federation_flow"
+	federationCookiePath = "/federation/"
+	// federationFlowMaxAge matches how long the usecase accepts a flow.
+	federationFlowMaxAge = 600
+)
+
+// FederationLogin sends the browser to sign in at an external identity provider. next is where the
+// browser goes once it is back, such as the authorize request it came from, and link is set when the
+// identity is linked to a signed in account rather than used to log in.
+func (ctrl *apiController) FederationLogin(ctx *gin.Context) {
+	authURL, flow, err := ctrl.federation.Start(requestContext(ctx), ctx.Param("provider"), ctx.Query("next"), ctx.Query("link"))
+	if err != nil {
+		federationError(ctx, err)
+		return
+	}
+	setFederationCookie(ctx, flow, federationFlowMaxAge)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// FederationCallback is where the identity provider sends the browser back. An authorize request
+// that started the login continues with the account signed in; otherwise the page says who signed in.
+func (ctrl *apiController) FederationCallback(ctx *gin.Context) {
+	var req model.FederationCallbackRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderHTML(ctx, http.StatusBadRequest, "federation_error.html", gin.H{"Reason": err.Error()})
+		return
+	}
+	flow, _ := ctx.Cookie(federationFlowCookie)
+	setFederationCookie(ctx, "", -1)
+
+	login, err := ctrl.federation.Finish(requestContext(ctx), ctx.Param("provider"), req, flow)
+	if err != nil {
+		federationError(ctx, err)
+		return
+	}
+
+	if strings.HasPrefix(login.Next, "/oauth/authorize?") {
+		next, err := url.Parse(login.Next)
+		if err != nil {
+			renderHTML(ctx, http.StatusBadRequest, "oauth_error.html", gin.H{"Reason": err.Error()})
+			return
+		}
+		var authReq model.OAuthAuthorizeRequest
+		if err := binding.MapFormWithTag(&authReq, next.Query(), "form"); err != nil {
+			renderHTML(ctx, http.StatusBadRequest, "oauth_error.html", gin.H{"Reason": err.Error()})
+			return
+		}
+		redirectURL, err := ctrl.oauth.AuthorizeAccount(requestContext(ctx), authReq, login.Username)
+		if err != nil {
+			ctrl.authorizeError(ctx, authReq, err)
+			return
+		}
+		ctx.Redirect(http.StatusFound, redirectURL)
+		return
+	}
+	renderHTML(ctx, http.StatusOK, "federation_done.html", login)
+}
+
+// setFederationCookie keeps the flow for the callback only. It must be sent on the top-level
+// redirect back from the provider, so it is SameSite=Lax rather than Strict.
+func setFederationCookie(ctx *gin.Context, value string, maxAge int) {
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(federationFlowCookie, value, maxAge, federationCookiePath, "", secure, true)
+}
+
+func federationError(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	reason := "Something went wrong, please try again later"
+	switch err {
+	case model.ErrFederationProviderNotFound:
+		status, reason = http.StatusNotFound, err.Error()
+	case model.ErrInvalidFederationState:
+		status, reason = http.StatusBadRequest, err.Error()
+	case model.ErrFederationDenied, model.ErrLoginAccountLocked:
+		status, reason = http.StatusForbidden, err.Error()
+	case model.ErrFederationFailed:
+		status, reason = http.StatusBadGateway, err.Error()
+	case model.ErrAccountIsAlreadyExisted, model.ErrIdentityLinkedElsewhere, model.ErrIdentityEmailConflict:
+		status, reason = http.StatusConflict, err.Error()
+	}
+	renderHTML(ctx, status, "federation_error.html", gin.H{"Reason": reason})
+}
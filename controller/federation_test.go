@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newFederationTestRoute(t *testing.T) (*gin.Engine, *model.MockFederationUsecase, *model.MockOAuthUsecase) {
+	ctrl := gomock.NewController(t)
+	mockFederation := model.NewMockFederationUsecase(ctrl)
+	mockOAuth := model.NewMockOAuthUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		mockOAuth, model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		mockFederation, model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockFederation, mockOAuth
+}
+
+func TestFederationLogin(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		route, mockFederation, _ := newFederationTestRoute(t)
+		mockFederation.EXPECT().Start(gomock.Any(), "corp", "/oauth/authorize?client_id=web", "").
+			Return("https://sso.example.com/authorize?state=s", "flow", nil)
+
+		httpReq, _ := http.NewRequest("GET", "/federation/corp/login?next="+url.QueryEscape("/oauth/authorize?client_id=web"), nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusFound, r.Code)
+		require.Equal(t, "https://sso.example.com/authorize?state=s", r.Header().Get("Location"))
+		cookies := r.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.Equal(t, federationFlowCookie, cookies[0].Name)
+		require.Equal(t, "flow", cookies[0].Value)
+		require.True(t, cookies[0].HttpOnly)
+		require.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		route, mockFederation, _ := newFederationTestRoute(t)
+		mockFederation.EXPECT().Start(gomock.Any(), "other", "", "").Return("", "", model.ErrFederationProviderNotFound)
+
+		httpReq, _ := http.NewRequest("GET", "/federation/other/login", nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusNotFound, r.Code)
+	})
+}
+
+func TestFederationCallback(t *testing.T) {
+	callback := "/federation/corp/callback?code=abc&state=s"
+
+	t.Run("continues authorize request", func(t *testing.T) {
+		route, mockFederation, mockOAuth := newFederationTestRoute(t)
+		next := "/oauth/authorize?" + testAuthorizeQuery().Encode()
+		mockFederation.EXPECT().Finish(gomock.Any(), "corp", model.FederationCallbackRequest{Code: "abc", State: "s"}, "flow").
+			Return(&model.FederationLogin{Username: "jdoe", Provider: "corp", Next: next}, nil)
+		mockOAuth.EXPECT().AuthorizeAccount(gomock.Any(), gomock.Any(), "jdoe").
+			DoAndReturn(func(_ interface{}, req model.OAuthAuthorizeRequest, _ string) (string, error) {
+				require.Equal(t, "web", req.ClientID)
+				require.Equal(t, "challenge", req.CodeChallenge)
+				return "https://app.example.com/callback?code=xyz&state=xyz", nil
+			})
+
+		httpReq, _ := http.NewRequest("GET", callback, nil)
+		httpReq.AddCookie(&http.Cookie{Name: federationFlowCookie, Value: "flow"})
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusFound, r.Code)
+		require.Equal(t, "https://app.example.com/callback?code=xyz&state=xyz", r.Header().Get("Location"))
+	})
+
+	t.Run("signed in", func(t *testing.T) {
+		route, mockFederation, _ := newFederationTestRoute(t)
+		mockFederation.EXPECT().Finish(gomock.Any(), "corp", gomock.Any(), "flow").
+			Return(&model.FederationLogin{Username: "jdoe", Provider: "corp", Provisioned: true}, nil)
+
+		httpReq, _ := http.NewRequest("GET", callback, nil)
+		httpReq.AddCookie(&http.Cookie{Name: federationFlowCookie, Value: "flow"})
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Signed in as jdoe")
+	})
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "invalid state", err: model.ErrInvalidFederationState, status: http.StatusBadRequest},
+		{name: "denied", err: model.ErrFederationDenied, status: http.StatusForbidden},
+		{name: "locked", err: model.ErrLoginAccountLocked, status: http.StatusForbidden},
+		{name: "provider failed", err: model.ErrFederationFailed, status: http.StatusBadGateway},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockFederation, _ := newFederationTestRoute(t)
+			mockFederation.EXPECT().Finish(gomock.Any(), "corp", gomock.Any(), "").Return(nil, tc.err)
+
+			httpReq, _ := http.NewRequest("GET", callback, nil)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+			require.Contains(t, r.Body.String(), tc.err.Error())
+		})
+	}
+}
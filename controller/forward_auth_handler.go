@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// forwardAuthCookie holds an access token or personal access token for browsers, which cannot send
+// one in the Authorization header. It must be set on a domain the protected hosts share.
+const forwardAuthCookie = "access_token"
+
+// VerifyForwardAuth godoc
+// @Summary      Verify a request for a reverse proxy
+// @Description  For nginx auth_request, Traefik ForwardAuth and Caddy forward_auth: lets a request to an application behind the proxy through when it bears the token of an account with the profile scope, in the Authorization header or the access_token cookie, or comes with the session cookie of a browser, and the rule of its host allows the account.
+// @Description  The host and URL are read from X-Forwarded-Host, X-Forwarded-Proto and X-Forwarded-Uri, or X-Original-URL. A request let through gets X-Auth-User, X-Auth-Roles and X-Auth-Id to pass on. Browsers without a token are redirected to sign in when a login URL is set.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Success      200
+// @Failure      302  {string}  string  "Sign in at the login URL"
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /auth/verify [get]
+func (ctrl *apiController) VerifyForwardAuth(ctx *gin.Context) {
+	original := forwardedURL(ctx)
+	principal, err := ctrl.forwardAuthPrincipal(ctx)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidToken) {
+			ctrl.forwardAuthUnauthorized(ctx, original, `, error="invalid_token"`)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if principal == nil {
+		ctrl.forwardAuthUnauthorized(ctx, original, "")
+		return
+	}
+	if !principal.HasScope(model.ScopeProfile) {
+		ctx.JSON(http.StatusForbidden, errResponse(model.ErrInsufficientScope))
+		return
+	}
+
+	identity, err := ctrl.forwardAuth.Verify(requestContext(ctx), principal, original.Hostname())
+	if err != nil {
+		switch err {
+		case model.ErrInvalidToken:
+			ctrl.forwardAuthUnauthorized(ctx, original, `, error="invalid_token"`)
+		case model.ErrForwardAuthHostNotAllowed, model.ErrForwardAuthRoleRequired:
+			ctx.JSON(http.StatusForbidden, errResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+
+	ctx.Header("X-Auth-User", identity.Username)
+	ctx.Header("X-Auth-Roles", strings.Join(identity.Roles, ","))
+	ctx.Header("X-Auth-Id", identity.ID)
+	ctx.Status(http.StatusOK)
+}
+
+// forwardAuthPrincipal is the account of the bearer token of the request, of its browser session, or of
+// the token in its access_token cookie. It is nil when the request has none of them.
+func (ctrl *apiController) forwardAuthPrincipal(ctx *gin.Context) (*model.Principal, error) {
+	if token := bearerToken(ctx); token != "" {
+		return ctrl.auth.Authenticate(requestContext(ctx), token)
+	}
+	if cookie, _ := ctx.Cookie(sessionCookie); cookie != "" {
+		session, err := ctrl.sessions.Authenticate(requestContext(ctx), cookie)
+		if err != nil {
+			return nil, err
+		}
+		return session.Principal, nil
+	}
+	if token, _ := ctx.Cookie(forwardAuthCookie); token != "" {
+		return ctrl.auth.Authenticate(requestContext(ctx), token)
+	}
+	return nil, nil
+}
+
+// forwardAuthUnauthorized sends browsers to sign in and back, and tells other clients to bring a token.
+// nginx does not pass the redirect on, it is configured to redirect on the 401 itself.
+func (ctrl *apiController) forwardAuthUnauthorized(ctx *gin.Context, original *url.URL, challenge string) {
+	if strings.Contains(ctx.GetHeader("Accept"), "text/html") {
+		if login := ctrl.forwardAuth.LoginURL(original.String()); login != "" {
+			ctx.Redirect(http.StatusFound, login)
+			return
+		}
+	}
+	ctx.Header("WWW-Authenticate", `Bearer realm="api"`+challenge)
+	ctx.JSON(http.StatusUnauthorized, gin.H{"err": "unauthorized"})
+}
+
+// forwardedURL is the URL of the request the proxy checks: Traefik and Caddy send it in X-Forwarded-*
+// headers, nginx in X-Original-URL when configured to. The endpoint must only be reachable through the
+// proxy, as these headers are trusted.
+func forwardedURL(ctx *gin.Context) *url.URL {
+	if original, err := url.Parse(ctx.GetHeader("X-Original-URL")); err == nil && original.Host != "" {
+		return original
+	}
+	original := &url.URL{Scheme: ctx.GetHeader("X-Forwarded-Proto"), Host: ctx.GetHeader("X-Forwarded-Host")}
+	if original.Scheme == "" {
+		original.Scheme = "http"
+		if ctx.Request.TLS != nil {
+			original.Scheme = "https"
+		}
+	}
+	if original.Host == "" {
+		original.Host = ctx.Request.Host
+	}
+	// The host may be a list when the request went through more than one proxy, the first is the client's.
+	if first, _, ok := strings.Cut(original.Host, ","); ok {
+		original.Host = strings.TrimSpace(first)
+	}
+	if uri, err := url.ParseRequestURI(ctx.GetHeader("X-Forwarded-Uri")); err == nil {
+		original.Path, original.RawQuery = uri.Path, uri.RawQuery
+	} else {
+		original.Path = "/"
+	}
+	return original
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newForwardAuthTestRoute(t *testing.T) (*gin.Engine, *model.MockAuthenticator, *model.MockForwardAuthUsecase) {
+	ctrl := gomock.NewController(t)
+	mockAuth := model.NewMockAuthenticator(ctrl)
+	mockForwardAuth := model.NewMockForwardAuthUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), mockAuth, model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl),
+		model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), mockForwardAuth, model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockAuth, mockForwardAuth
+}
+
+func TestVerifyForwardAuth(t *testing.T) {
+	principal := &model.Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{model.ScopeProfile}}
+	identity := &model.ForwardAuthIdentity{ID: principal.AccountID.String(), Username: "alice", Roles: []string{"staff", "ops"}}
+
+	testCase := []struct {
+		name          string
+		setupRequest  func(req *http.Request)
+		buildStubs    func(auth *model.MockAuthenticator, forwardAuth *model.MockForwardAuthUsecase)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "BearerToken",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer token")
+				req.Header.Set("X-Forwarded-Proto", "https")
+				req.Header.Set("X-Forwarded-Host", "wiki.example.com")
+				req.Header.Set("X-Forwarded-Uri", "/page?a=1")
+			},
+			buildStubs: func(auth *model.MockAuthenticator, forwardAuth *model.MockForwardAuthUsecase) {
+				auth.EXPECT().Authenticate(gomock.Any(), "token").Return(principal, nil)
+				forwardAuth.EXPECT().Verify(gomock.Any(), principal, "wiki.example.com").Return(identity, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Equal(t, "alice", r.Header().Get("X-Auth-User"))
+				require.Equal(t, "staff,ops", r.Header().Get("X-Auth-Roles"))
+				require.Equal(t, identity.ID, r.Header().Get("X-Auth-Id"))
+			},
+		},
+		{
+			name: "CookieWithOriginalURL",
+			setupRequest: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: forwardAuthCookie, Value: "token"})
+				req.Header.Set("X-Original-URL", "https://grafana.example.com:8443/d/1")
+			},
+			buildStubs: func(auth *model.MockAuthenticator, forwardAuth *model.MockForwardAuthUsecase) {
+				auth.EXPECT().Authenticate(gomock.Any(), "token").Return(principal, nil)
+				forwardAuth.EXPECT().Verify(gomock.Any(), principal, "grafana.example.com").Return(nil, model.ErrForwardAuthRoleRequired)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Empty(t, r.Header().Get("X-Auth-User"))
+			},
+		},
+		{
+			name: "BrowserRedirectedToLogin",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Accept", "text/html,application/xhtml+xml")
+				req.Header.Set("X-Forwarded-Proto", "https")
+				req.Header.Set("X-Forwarded-Host", "wiki.example.com")
+				req.Header.Set("X-Forwarded-Uri", "/page")
+			},
+			buildStubs: func(auth *model.MockAuthenticator, forwardAuth *model.MockForwardAuthUsecase) {
+				forwardAuth.EXPECT().LoginURL("https://wiki.example.com/page").Return("https://id.example.com/login?rd=x")
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusFound, r.Code)
+				require.Equal(t, "https://id.example.com/login?rd=x", r.Header().Get("Location"))
+			},
+		},
+		{
+			name: "NoToken",
+			setupRequest: func(req *http.Request) {
+			},
+			buildStubs: func(auth *model.MockAuthenticator, forwardAuth *model.MockForwardAuthUsecase) {
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Equal(t, `Bearer realm="api"`, r.Header().Get("WWW-Authenticate"))
+			},
+		},
+		{
+			name: "InvalidToken",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer expired")
+			},
+			buildStubs: func(auth *model.MockAuthenticator, forwardAuth *model.MockForwardAuthUsecase) {
+				auth.EXPECT().Authenticate(gomock.Any(), "expired").Return(nil, model.ErrInvalidToken)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Contains(t, r.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+			},
+		},
+		{
+			name: "NoProfileScope",
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer token")
+			},
+			buildStubs: func(auth *model.MockAuthenticator, forwardAuth *model.MockForwardAuthUsecase) {
+				auth.EXPECT().Authenticate(gomock.Any(), "token").
+					Return(&model.Principal{AccountID: principal.AccountID, Username: "alice", Scopes: []string{model.ScopeTokens}}, nil)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockAuth, mockForwardAuth := newForwardAuthTestRoute(t)
+			tc.buildStubs(mockAuth, mockForwardAuth)
+
+			httpReq, _ := http.NewRequest("GET", "/api/auth/verify", nil)
+			tc.setupRequest(httpReq)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(t, r)
+		})
+	}
+}
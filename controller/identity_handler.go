@@ -0,0 +1,346 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// reauthHeader carries the token from Reauthenticate to the requests that change login methods.
+const reauthHeader = "X-Reauth-Token"
+
+// ListLoginMethods godoc
+// @Summary      List login methods
+// @Description  List the ways the current account signs in: its password, identities at external providers, passkeys and TOTP. Needs the identities scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.LoginMethod
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /accounts/me/login-methods [get]
+func (ctrl *apiController) ListLoginMethods(ctx *gin.Context) {
+	rsp, err := ctrl.identities.ListLoginMethods(requestContext(ctx), currentPrincipal(ctx))
+	if err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// Reauthenticate godoc
+// @Summary      Re-authenticate
+// @Description  Check the password, and otp when TOTP is linked, and return a token that allows changing login methods for five minutes.
+// @Description  Send it in the X-Reauth-Token header. Tokens from a sign-in within the last five minutes do not need it. Needs the identities scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        reauthenticateRequest body model.ReauthenticateRequest true "Reauthenticate Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.ReauthenticateResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError "Wrong Password, Or One-Time Code Missing Or Wrong"
+// @Failure      403  {object}  model.DocResponseError
+// @Failure      429  {object}  model.DocResponseError "Too Many Failed Login Attempts"
+// @Router       /accounts/me/reauthenticate [post]
+func (ctrl *apiController) Reauthenticate(ctx *gin.Context) {
+	var req model.ReauthenticateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.identities.Reauthenticate(requestContext(ctx), currentPrincipal(ctx), req)
+	if err != nil {
+		if err == model.ErrLoginWrongPassword || err == model.ErrLoginOTPRequired || err == model.ErrLoginWrongOTP {
+			ctx.JSON(http.StatusUnauthorized, errResponse(err))
+			return
+		} else if err == model.ErrLoginAttemptBlocked {
+			ctx.JSON(http.StatusTooManyRequests, errResponse(err))
+			return
+		} else if err == model.ErrLoginNoPassword || err == model.ErrHookDenied {
+			ctx.JSON(http.StatusForbidden, errResponse(err))
+			return
+		}
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// SetPassword godoc
+// @Summary      Set the password
+// @Description  Set or change the password of the current account, with the same rules as at sign-up. Needs the identities scope and re-authentication.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        X-Reauth-Token  header  string  false  "Re-authentication Token"
+// @Param        setPasswordRequest body model.SetPasswordRequest true "Set Password Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.DocResponseSuccess
+// @Failure      400  {object}  model.DocResponseBadRequest
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError "Re-authentication Required"
+// @Router       /accounts/me/login-methods/password [put]
+func (ctrl *apiController) SetPassword(ctx *gin.Context) {
+	var req model.SetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.identities.SetPassword(requestContext(ctx), currentPrincipal(ctx), ctx.GetHeader(reauthHeader), req)
+	if err != nil {
+		if err == model.ErrAccountRequestValidationFailed {
+			ctx.JSON(http.StatusBadRequest, rsp)
+			return
+		}
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// RemovePassword godoc
+// @Summary      Remove the password
+// @Description  Remove the password of the current account, which then signs in with its other methods only.
+// @Description  Fails while it is the last method that signs in on its own. Needs the identities scope and re-authentication.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        X-Reauth-Token  header  string  false  "Re-authentication Token"
+// @Success      204
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError "Re-authentication Required"
+// @Failure      409  {object}  model.DocResponseError "Last Login Method"
+// @Router       /accounts/me/login-methods/password [delete]
+func (ctrl *apiController) RemovePassword(ctx *gin.Context) {
+	if err := ctrl.identities.RemovePassword(requestContext(ctx), currentPrincipal(ctx), ctx.GetHeader(reauthHeader)); err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// BeginTOTP godoc
+// @Summary      Start linking TOTP
+// @Description  Generate a TOTP secret to add to an authenticator app, as the secret or the otpauth:// uri. Confirm it with a code to link it. Needs the identities scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  model.TOTPEnrollment
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError "TOTP Is Already Linked"
+// @Router       /accounts/me/login-methods/totp [post]
+func (ctrl *apiController) BeginTOTP(ctx *gin.Context) {
+	rsp, err := ctrl.identities.BeginTOTP(requestContext(ctx), currentPrincipal(ctx))
+	if err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ConfirmTOTP godoc
+// @Summary      Link TOTP
+// @Description  Link the TOTP secret of an enrollment with its current code. Logins then need a code as well. Needs the identities scope and re-authentication.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        X-Reauth-Token  header  string  false  "Re-authentication Token"
+// @Param        confirmTOTPRequest body model.ConfirmTOTPRequest true "Confirm TOTP Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.LoginMethod
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError "Re-authentication Required"
+// @Failure      409  {object}  model.DocResponseError "TOTP Is Already Linked"
+// @Router       /accounts/me/login-methods/totp/confirm [post]
+func (ctrl *apiController) ConfirmTOTP(ctx *gin.Context) {
+	var req model.ConfirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.identities.ConfirmTOTP(requestContext(ctx), currentPrincipal(ctx), ctx.GetHeader(reauthHeader), req)
+	if err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// BeginPasskey godoc
+// @Summary      Start registering a passkey
+// @Description  Return the options for navigator.credentials.create. Send its result back with the ceremony to register the passkey. Needs the identities scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  model.PasskeyCeremony
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /accounts/me/login-methods/passkeys [post]
+func (ctrl *apiController) BeginPasskey(ctx *gin.Context) {
+	rsp, err := ctrl.identities.BeginPasskey(requestContext(ctx), currentPrincipal(ctx))
+	if err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// FinishPasskey godoc
+// @Summary      Register a passkey
+// @Description  Check the result of navigator.credentials.create and link the passkey. Needs the identities scope and re-authentication.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        X-Reauth-Token  header  string  false  "Re-authentication Token"
+// @Param        finishPasskeyRequest body model.FinishPasskeyRequest true "Finish Passkey Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.LoginMethod
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError "Re-authentication Required"
+// @Failure      409  {object}  model.DocResponseError "Passkey Is Already Linked"
+// @Router       /accounts/me/login-methods/passkeys/finish [post]
+func (ctrl *apiController) FinishPasskey(ctx *gin.Context) {
+	var req model.FinishPasskeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.identities.FinishPasskey(requestContext(ctx), currentPrincipal(ctx), ctx.GetHeader(reauthHeader), req)
+	if err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// LinkProvider godoc
+// @Summary      Link an external identity
+// @Description  Return the URL to open in the browser to sign in at provider and link that identity to the current account. It works for five minutes.
+// @Description  Needs the identities scope and re-authentication.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        X-Reauth-Token  header  string  false  "Re-authentication Token"
+// @Param        provider  path  string  true  "Identity Provider"
+// @Produce      json
+// @Success      200  {object}  model.LinkProviderResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError "Re-authentication Required"
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /accounts/me/login-methods/providers/{provider} [post]
+func (ctrl *apiController) LinkProvider(ctx *gin.Context) {
+	linkURL, err := ctrl.identities.LinkProvider(requestContext(ctx), currentPrincipal(ctx), ctx.GetHeader(reauthHeader), ctx.Param("provider"))
+	if err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.LinkProviderResponse{URL: linkURL})
+}
+
+// UnlinkLoginMethod godoc
+// @Summary      Unlink a login method
+// @Description  Unlink an external identity, passkey or TOTP from the current account.
+// @Description  Fails for the last method that signs in on its own. Needs the identities scope and re-authentication.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        X-Reauth-Token  header  string  false  "Re-authentication Token"
+// @Param        id  path  string  true  "Login Method ID"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError "Re-authentication Required"
+// @Failure      404  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError "Last Login Method"
+// @Router       /accounts/me/login-methods/{id} [delete]
+func (ctrl *apiController) UnlinkLoginMethod(ctx *gin.Context) {
+	if err := ctrl.identities.Unlink(requestContext(ctx), currentPrincipal(ctx), ctx.GetHeader(reauthHeader), ctx.Param("id")); err != nil {
+		identityError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// BeginPasskeyLogin godoc
+// @Summary      Start a passkey login
+// @Description  Return the options for navigator.credentials.get. Send its result back with the ceremony to log in.
+// @Tags         accounts
+// @Produce      json
+// @Success      200  {object}  model.PasskeyCeremony
+// @Router       /login/passkey/begin [post]
+func (ctrl *apiController) BeginPasskeyLogin(ctx *gin.Context) {
+	rsp, err := ctrl.identities.BeginPasskeyLogin(requestContext(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// FinishPasskeyLogin godoc
+// @Summary      Log in with a passkey
+// @Description  Check the result of navigator.credentials.get and log in to the account of the passkey.
+// @Tags         accounts
+// @Param        finishPasskeyRequest body model.FinishPasskeyRequest true "Finish Passkey Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  model.PasskeyLoginResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.PasskeyLoginResponse
+// @Failure      403  {object}  model.PasskeyLoginResponse "Account Locked"
+// @Router       /login/passkey/finish [post]
+func (ctrl *apiController) FinishPasskeyLogin(ctx *gin.Context) {
+	var req model.FinishPasskeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.identities.FinishPasskeyLogin(requestContext(ctx), req)
+	if err != nil {
+		if err == model.ErrPasskeyLoginFailed {
+			ctx.JSON(http.StatusUnauthorized, rsp)
+			return
+		}
+		if err == model.ErrLoginAccountLocked {
+			ctx.JSON(http.StatusForbidden, rsp)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func identityError(ctx *gin.Context, err error) {
+	switch err {
+	case model.ErrInvalidLoginMethodID, model.ErrInvalidEnrollment, model.ErrInvalidPasskey, model.ErrLoginWrongOTP:
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	case model.ErrReauthenticationRequired:
+		ctx.JSON(http.StatusForbidden, errResponse(err))
+	case model.ErrLoginMethodNotFound, model.ErrAccountNotFound, model.ErrFederationProviderNotFound:
+		ctx.JSON(http.StatusNotFound, errResponse(err))
+	case model.ErrLastLoginMethod, model.ErrLoginMethodAlreadyLinked:
+		ctx.JSON(http.StatusConflict, errResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	}
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var identityPrincipal = &model.Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{model.ScopeIdentities}}
+
+func newIdentityTestRoute(t *testing.T) (*gin.Engine, *model.MockIdentityUsecase) {
+	ctrl := gomock.NewController(t)
+	mockIdentities := model.NewMockIdentityUsecase(ctrl)
+	mockAuth := model.NewMockAuthenticator(ctrl)
+	mockAuth.EXPECT().Authenticate(gomock.Any(), "token").AnyTimes().Return(identityPrincipal, nil)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), mockAuth, model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), mockIdentities, model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockIdentities
+}
+
+func serveIdentityRequest(route *gin.Engine, method string, path string, body interface{}, reauth string) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	httpReq, _ := http.NewRequest(method, path, bytes.NewReader(data))
+	httpReq.Header.Set("Authorization", "Bearer token")
+	if reauth != "" {
+		httpReq.Header.Set(reauthHeader, reauth)
+	}
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+	return r
+}
+
+func TestListLoginMethods(t *testing.T) {
+	route, mockIdentities := newIdentityTestRoute(t)
+	createdAt := time.Now()
+	mockIdentities.EXPECT().ListLoginMethods(gomock.Any(), identityPrincipal).Return([]model.LoginMethod{
+		{Type: model.LoginMethodPassword},
+		{ID: uuid.New().String(), Type: "oidc", Provider: "corp", Email: "alice@example.com", CreatedAt: &createdAt},
+	}, nil)
+
+	r := serveIdentityRequest(route, "GET", "/api/accounts/me/login-methods", nil, "")
+	require.Equal(t, http.StatusOK, r.Code)
+	var rsp []model.LoginMethod
+	require.NoError(t, json.Unmarshal(r.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 2)
+	require.Equal(t, "corp", rsp[1].Provider)
+}
+
+func TestReauthenticateHandler(t *testing.T) {
+	testCase := []struct {
+		name   string
+		body   gin.H
+		err    error
+		status int
+	}{
+		{name: "ok", body: gin.H{"password": "Secret123"}, status: http.StatusOK},
+		{name: "bad request", body: gin.H{}, status: http.StatusBadRequest},
+		{name: "wrong password", body: gin.H{"password": "wrong"}, err: model.ErrLoginWrongPassword, status: http.StatusUnauthorized},
+		{name: "code required", body: gin.H{"password": "Secret123"}, err: model.ErrLoginOTPRequired, status: http.StatusUnauthorized},
+		{name: "blocked", body: gin.H{"password": "wrong"}, err: model.ErrLoginAttemptBlocked, status: http.StatusTooManyRequests},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockIdentities := newIdentityTestRoute(t)
+			if tc.status == http.StatusBadRequest {
+				mockIdentities.EXPECT().Reauthenticate(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			} else if tc.err != nil {
+				mockIdentities.EXPECT().Reauthenticate(gomock.Any(), identityPrincipal, gomock.Any()).Return(nil, tc.err)
+			} else {
+				mockIdentities.EXPECT().Reauthenticate(gomock.Any(), identityPrincipal, model.ReauthenticateRequest{Password: "Secret123"}).
+					Return(&model.ReauthenticateResponse{ReauthToken: "reauth", ExpiresIn: 300}, nil)
+			}
+
+			r := serveIdentityRequest(route, "POST", "/api/accounts/me/reauthenticate", tc.body, "")
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
+
+func TestSetPasswordHandler(t *testing.T) {
+	route, mockIdentities := newIdentityTestRoute(t)
+	mockIdentities.EXPECT().SetPassword(gomock.Any(), identityPrincipal, "reauth", model.SetPasswordRequest{Password: "short"}).
+		Return(&model.AccountResponse{Reason: model.ErrPasswordIsTooShort.Error()}, model.ErrAccountRequestValidationFailed)
+	r := serveIdentityRequest(route, "PUT", "/api/accounts/me/login-methods/password", gin.H{"password": "short"}, "reauth")
+	require.Equal(t, http.StatusBadRequest, r.Code)
+
+	mockIdentities.EXPECT().SetPassword(gomock.Any(), identityPrincipal, "", model.SetPasswordRequest{Password: "Secret123"}).
+		Return(nil, model.ErrReauthenticationRequired)
+	r = serveIdentityRequest(route, "PUT", "/api/accounts/me/login-methods/password", gin.H{"password": "Secret123"}, "")
+	require.Equal(t, http.StatusForbidden, r.Code)
+
+	mockIdentities.EXPECT().SetPassword(gomock.Any(), identityPrincipal, "reauth", model.SetPasswordRequest{Password: "Secret123"}).
+		Return(&model.AccountResponse{Success: true}, nil)
+	r = serveIdentityRequest(route, "PUT", "/api/accounts/me/login-methods/password", gin.H{"password": "Secret123"}, "reauth")
+	require.Equal(t, http.StatusOK, r.Code)
+}
+
+func TestUnlinkLoginMethod(t *testing.T) {
+	id := uuid.New().String()
+	testCase := []struct {
+		name   string
+		path   string
+		setup  func(mockIdentities *model.MockIdentityUsecase)
+		status int
+	}{
+		{
+			name: "ok",
+			path: "/api/accounts/me/login-methods/" + id,
+			setup: func(mockIdentities *model.MockIdentityUsecase) {
+				mockIdentities.EXPECT().Unlink(gomock.Any(), identityPrincipal, "reauth", id).Return(nil)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "password",
+			path: "/api/accounts/me/login-methods/password",
+			setup: func(mockIdentities *model.MockIdentityUsecase) {
+				mockIdentities.EXPECT().RemovePassword(gomock.Any(), identityPrincipal, "reauth").Return(nil)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "last login method",
+			path: "/api/accounts/me/login-methods/" + id,
+			setup: func(mockIdentities *model.MockIdentityUsecase) {
+				mockIdentities.EXPECT().Unlink(gomock.Any(), identityPrincipal, "reauth", id).Return(model.ErrLastLoginMethod)
+			},
+			status: http.StatusConflict,
+		},
+		{
+			name: "not found",
+			path: "/api/accounts/me/login-methods/" + id,
+			setup: func(mockIdentities *model.MockIdentityUsecase) {
+				mockIdentities.EXPECT().Unlink(gomock.Any(), identityPrincipal, "reauth", id).Return(model.ErrLoginMethodNotFound)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name: "invalid id",
+			path: "/api/accounts/me/login-methods/1",
+			setup: func(mockIdentities *model.MockIdentityUsecase) {
+				mockIdentities.EXPECT().Unlink(gomock.Any(), identityPrincipal, "reauth", "1").Return(model.ErrInvalidLoginMethodID)
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockIdentities := newIdentityTestRoute(t)
+			tc.setup(mockIdentities)
+			r := serveIdentityRequest(route, "DELETE", tc.path, nil, "reauth")
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
+
+func TestLinkProviderHandler(t *testing.T) {
+	route, mockIdentities := newIdentityTestRoute(t)
+	mockIdentities.EXPECT().LinkProvider(gomock.Any(), identityPrincipal, "reauth", "corp").Return("https://auth.example.com/federation/corp/login?link=x", nil)
+
+	r := serveIdentityRequest(route, "POST", "/api/accounts/me/login-methods/providers/corp", nil, "reauth")
+	require.Equal(t, http.StatusOK, r.Code)
+	rsp := &model.LinkProviderResponse{}
+	require.NoError(t, json.Unmarshal(r.Body.Bytes(), rsp))
+	require.Equal(t, "https://auth.example.com/federation/corp/login?link=x", rsp.URL)
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	route, mockIdentities := newIdentityTestRoute(t)
+	mockIdentities.EXPECT().BeginPasskeyLogin(gomock.Any()).
+		Return(&model.PasskeyCeremony{Ceremony: "ceremony", PublicKey: model.PasskeyRequestOptions{Challenge: "challenge"}}, nil)
+	httpReq, _ := http.NewRequest("POST", "/api/login/passkey/begin", nil)
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Contains(t, r.Body.String(), `"challenge":"challenge"`)
+
+	finish := func(err error) *httptest.ResponseRecorder {
+		rsp := &model.PasskeyLoginResponse{Success: err == nil, Username: "alice"}
+		mockIdentities.EXPECT().FinishPasskeyLogin(gomock.Any(), gomock.Any()).Return(rsp, err)
+		body, _ := json.Marshal(gin.H{"ceremony": "ceremony", "credential": gin.H{"id": "credential"}})
+		httpReq, _ := http.NewRequest("POST", "/api/login/passkey/finish", bytes.NewReader(body))
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		return r
+	}
+	require.Equal(t, http.StatusOK, finish(nil).Code)
+	require.Equal(t, http.StatusUnauthorized, finish(model.ErrPasskeyLoginFailed).Code)
+	require.Equal(t, http.StatusForbidden, finish(model.ErrLoginAccountLocked).Code)
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	actorKey        = "actor"
+	adminActor      = "admin"
+	principalKey    = "principal"
+	sessionKey      = "session"
+)
+
+// requestID makes sure every request carries an X-Request-ID, reusing the one sent by the client if any.
+func requestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if id == "" {
+			id = uuid.New().String()
+		}
+		ctx.Set(requestIDHeader, id)
+		ctx.Header(requestIDHeader, id)
+		ctx.Next()
+	}
+}
+
+// adminAuth only lets through requests bearing the admin API key.
+func (ctrl *apiController) adminAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := bearerToken(ctx)
+		if ctrl.adminAPIKey == "" || token == "" ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(ctrl.adminAPIKey)) != 1 {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": "unauthorized"})
+			return
+		}
+		ctx.Set(actorKey, adminActor)
+		ctx.Next()
+	}
+}
+
+// accountAuth only lets through requests bearing an access token or personal access token of an
+// account, with the scope the route needs, or coming with the cookie of a browser session.
+func (ctrl *apiController) accountAuth(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := bearerToken(ctx)
+		if token == "" {
+			if cookie, _ := ctx.Cookie(sessionCookie); cookie != "" {
+				ctrl.browserSessionAuth(ctx, cookie)
+				return
+			}
+			ctx.Header("WWW-Authenticate", `Bearer realm="api"`)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": "unauthorized"})
+			return
+		}
+		principal, err := ctrl.auth.Authenticate(requestContext(ctx), token)
+		if err != nil {
+			if errors.Is(err, model.ErrInvalidToken) {
+				ctx.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": "unauthorized"})
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errResponse(err))
+			return
+		}
+		if !principal.HasScope(scope) {
+			ctx.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="api", error="insufficient_scope", scope="%s"`, scope))
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"err": model.ErrInsufficientScope.Error()})
+			return
+		}
+		ctx.Set(principalKey, principal)
+		ctx.Set(actorKey, principal.Username)
+		ctx.Next()
+	}
+}
+
+// sessionAuth only lets through requests coming with the cookie of a browser session.
+func (ctrl *apiController) sessionAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		cookie, _ := ctx.Cookie(sessionCookie)
+		if cookie == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": "unauthorized"})
+			return
+		}
+		ctrl.browserSessionAuth(ctx, cookie)
+	}
+}
+
+// browserSessionAuth lets through a request with the cookie of a browser session, which has every scope.
+// As browsers send the cookie with requests from other sites too, the requests that change something
+// must also bring the CSRF token of the session, which other sites cannot read.
+func (ctrl *apiController) browserSessionAuth(ctx *gin.Context, cookie string) {
+	session, err := ctrl.sessions.Authenticate(requestContext(ctx), cookie)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidToken) {
+			ctrl.clearSessionCookie(ctx)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"err": "unauthorized"})
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+	if !validCSRFToken(ctx, session) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errResponse(errInvalidCSRFToken))
+		return
+	}
+	ctx.Set(principalKey, session.Principal)
+	ctx.Set(sessionKey, session)
+	ctx.Set(actorKey, session.Principal.Username)
+	ctx.Next()
+}
+
+// validCSRFToken tells whether a request with the cookie of session may go on: requests that only read
+// always may, the others must bring its CSRF token.
+func validCSRFToken(ctx *gin.Context, session *model.BrowserSession) bool {
+	if isSafeMethod(ctx.Request.Method) {
+		return true
+	}
+	token := ctx.GetHeader(csrfHeader)
+	if token == "" {
+		token = ctx.PostForm(csrfFormField)
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// currentPrincipal is the account that accountAuth let through.
+func currentPrincipal(ctx *gin.Context) *model.Principal {
+	principal, _ := ctx.MustGet(principalKey).(*model.Principal)
+	return principal
+}
+
+func bearerToken(ctx *gin.Context) string {
+	header := ctx.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// requestContext passes the caller details of the request down to the usecases.
+func requestContext(ctx *gin.Context) context.Context {
+	return model.WithRequestInfo(ctx.Request.Context(), model.RequestInfo{
+		Actor:     ctx.GetString(actorKey),
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		RequestID: ctx.GetString(requestIDHeader),
+	})
+}
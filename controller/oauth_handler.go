@@ -0,0 +1,449 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+type oauthAuthorizePage struct {
+	Request  model.OAuthAuthorizeRequest
+	Consent  *model.OAuthConsent
+	Username string
+	Reason   string
+	// OTPRequired asks for the one-time code of accounts with two-factor authentication.
+	OTPRequired bool
+	// PasswordResetRequired asks for a new password, after support staff reset it.
+	PasswordResetRequired bool
+	// Providers are the identity providers offered instead of the password, Next brings the browser
+	// back to this request once it signed in there.
+	Providers []model.FederationProvider
+	Next      string
+}
+
+type oauthDevicePage struct {
+	UserCode              string
+	Consent               *model.OAuthConsent
+	Username              string
+	Reason                string
+	OTPRequired           bool
+	PasswordResetRequired bool
+}
+
+type oauthDeviceForm struct {
+	UserCode        string `form:"user_code"`
+	Username        string `form:"username"`
+	Password        string `form:"password"`
+	OTP             string `form:"otp"`
+	NewPassword     string `form:"new_password"`
+	ConfirmPassword string `form:"confirm_password"`
+	Action          string `form:"action"`
+}
+
+type oauthLoginForm struct {
+	Username        string `form:"username"`
+	Password        string `form:"password"`
+	OTP             string `form:"otp"`
+	NewPassword     string `form:"new_password"`
+	ConfirmPassword string `form:"confirm_password"`
+	Action          string `form:"action"`
+}
+
+func oauthErrorResponse(err *model.OAuthError) *gin.H {
+	return &gin.H{"error": err.Code, "error_description": err.Description}
+}
+
+// Authorize shows the login and consent page of the authorization code flow.
+func (ctrl *apiController) Authorize(ctx *gin.Context) {
+	var req model.OAuthAuthorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderHTML(ctx, http.StatusBadRequest, "oauth_error.html", gin.H{"Reason": err.Error()})
+		return
+	}
+
+	consent, err := ctrl.oauth.ValidateAuthorizeRequest(requestContext(ctx), req)
+	if err != nil {
+		ctrl.authorizeError(ctx, req, err)
+		return
+	}
+
+	renderHTML(ctx, http.StatusOK, "oauth_authorize.html", oauthAuthorizePage{
+		Request:   req,
+		Consent:   consent,
+		Providers: ctrl.federation.Providers(),
+		Next:      authorizePath(req),
+	})
+}
+
+// AuthorizeLogin logs the user in from the login and consent page and redirects back to the client.
+func (ctrl *apiController) AuthorizeLogin(ctx *gin.Context) {
+	var req model.OAuthAuthorizeRequest
+	var form oauthLoginForm
+	if err := ctx.ShouldBind(&req); err != nil {
+		renderHTML(ctx, http.StatusBadRequest, "oauth_error.html", gin.H{"Reason": err.Error()})
+		return
+	}
+	if err := ctx.ShouldBind(&form); err != nil {
+		renderHTML(ctx, http.StatusBadRequest, "oauth_error.html", gin.H{"Reason": err.Error()})
+		return
+	}
+
+	approved := form.Action == "allow"
+	if approved && form.NewPassword != form.ConfirmPassword {
+		ctrl.authorizeLoginFailed(ctx, req, form, http.StatusBadRequest, errPasswordMismatch.Error(), nil)
+		return
+	}
+
+	login := model.AccountRequest{
+		Username:    form.Username,
+		Password:    form.Password,
+		OTP:         form.OTP,
+		NewPassword: form.NewPassword,
+	}
+	redirectURL, rsp, err := ctrl.oauth.Authorize(requestContext(ctx), req, login, approved)
+	if err != nil {
+		if rsp == nil {
+			ctrl.authorizeError(ctx, req, err)
+			return
+		}
+		status, reason := loginStatus(err), rsp.Reason
+		if status == http.StatusInternalServerError {
+			reason = errSomethingWrong.Error()
+		}
+		ctrl.authorizeLoginFailed(ctx, req, form, status, reason, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, redirectURL)
+}
+
+// authorizeLoginFailed shows the login and consent page again with the reason the login failed. It
+// keeps asking for the one-time code or the new password once they were asked for.
+func (ctrl *apiController) authorizeLoginFailed(ctx *gin.Context, req model.OAuthAuthorizeRequest, form oauthLoginForm,
+	status int, reason string, err error) {
+	consent, validateErr := ctrl.oauth.ValidateAuthorizeRequest(requestContext(ctx), req)
+	if validateErr != nil {
+		ctrl.authorizeError(ctx, req, validateErr)
+		return
+	}
+	renderHTML(ctx, status, "oauth_authorize.html", oauthAuthorizePage{
+		Request:               req,
+		Consent:               consent,
+		Username:              form.Username,
+		Reason:                reason,
+		OTPRequired:           form.OTP != "" || err == model.ErrLoginOTPRequired || err == model.ErrLoginWrongOTP,
+		PasswordResetRequired: form.NewPassword != "" || err == model.ErrLoginPasswordResetRequired,
+		Providers:             ctrl.federation.Providers(),
+		Next:                  authorizePath(req),
+	})
+}
+
+// authorizePath is the authorize request as a path on this server.
+func authorizePath(req model.OAuthAuthorizeRequest) string {
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+		"nonce":                 req.Nonce,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	return "/oauth/authorize?" + query.Encode()
+}
+
+// authorizeError reports an authorize error back to the client when the redirect URI can be trusted,
+// and to the user otherwise.
+func (ctrl *apiController) authorizeError(ctx *gin.Context, req model.OAuthAuthorizeRequest, err error) {
+	var oauthErr *model.OAuthError
+	switch {
+	case errors.As(err, &oauthErr):
+		ctx.Redirect(http.StatusFound, req.RedirectURL(url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+		}))
+	case err == model.ErrInvalidOAuthClient || err == model.ErrInvalidRedirectURI:
+		renderHTML(ctx, http.StatusBadRequest, "oauth_error.html", gin.H{"Reason": err.Error()})
+	default:
+		renderHTML(ctx, http.StatusInternalServerError, "oauth_error.html", gin.H{"Reason": "Something went wrong, please try again later"})
+	}
+}
+
+// Token is the OAuth 2.0 token endpoint. Clients authenticate with HTTP Basic, with client_id and
+// client_secret in the form, or with a signed client_assertion (RFC 7523).
+func (ctrl *apiController) Token(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req model.OAuthTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(&model.OAuthError{Code: model.OAuthErrInvalidRequest, Description: err.Error()}))
+		return
+	}
+	basicAuth := clientCredentials(ctx, &req.ClientID, &req.ClientSecret)
+
+	rsp, err := ctrl.oauth.Token(requestContext(ctx), req)
+	if err != nil {
+		oauthEndpointError(ctx, err, basicAuth)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// DeviceAuthorization is the RFC 8628 device authorization endpoint. The device shows the user code
+// and polls the token endpoint while the user approves it at the verification URI.
+func (ctrl *apiController) DeviceAuthorization(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req model.OAuthDeviceAuthorizationRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(&model.OAuthError{Code: model.OAuthErrInvalidRequest, Description: err.Error()}))
+		return
+	}
+	basicAuth := clientCredentials(ctx, &req.ClientID, &req.ClientSecret)
+
+	rsp, err := ctrl.oauth.DeviceAuthorization(requestContext(ctx), req)
+	if err != nil {
+		oauthEndpointError(ctx, err, basicAuth)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// DevicePage asks for the user code, then shows the login and consent page for it.
+func (ctrl *apiController) DevicePage(ctx *gin.Context) {
+	userCode := ctx.Query("user_code")
+	if userCode == "" {
+		renderHTML(ctx, http.StatusOK, "oauth_device.html", oauthDevicePage{})
+		return
+	}
+
+	consent, err := ctrl.oauth.ValidateUserCode(requestContext(ctx), userCode)
+	if err != nil {
+		ctrl.deviceError(ctx, userCode, err)
+		return
+	}
+	renderHTML(ctx, http.StatusOK, "oauth_device.html", oauthDevicePage{
+		UserCode: userCode,
+		Consent:  consent,
+	})
+}
+
+// DeviceApprove logs the user in from the device page and approves or denies the device.
+func (ctrl *apiController) DeviceApprove(ctx *gin.Context) {
+	var form oauthDeviceForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		renderHTML(ctx, http.StatusBadRequest, "oauth_device.html", oauthDevicePage{Reason: err.Error()})
+		return
+	}
+
+	approved := form.Action == "allow"
+	if approved && form.NewPassword != form.ConfirmPassword {
+		ctrl.deviceLoginFailed(ctx, form, http.StatusBadRequest, errPasswordMismatch.Error(), nil)
+		return
+	}
+
+	login := model.AccountRequest{
+		Username:    form.Username,
+		Password:    form.Password,
+		OTP:         form.OTP,
+		NewPassword: form.NewPassword,
+	}
+	rsp, err := ctrl.oauth.ApproveDevice(requestContext(ctx), form.UserCode, login, approved)
+	if err != nil {
+		if rsp == nil {
+			ctrl.deviceError(ctx, form.UserCode, err)
+			return
+		}
+		status, reason := loginStatus(err), rsp.Reason
+		if status == http.StatusInternalServerError {
+			reason = errSomethingWrong.Error()
+		}
+		ctrl.deviceLoginFailed(ctx, form, status, reason, err)
+		return
+	}
+
+	renderHTML(ctx, http.StatusOK, "oauth_device_done.html", gin.H{"Approved": approved})
+}
+
+// deviceLoginFailed shows the device page again with the reason the login failed.
+func (ctrl *apiController) deviceLoginFailed(ctx *gin.Context, form oauthDeviceForm, status int, reason string, err error) {
+	consent, validateErr := ctrl.oauth.ValidateUserCode(requestContext(ctx), form.UserCode)
+	if validateErr != nil {
+		ctrl.deviceError(ctx, form.UserCode, validateErr)
+		return
+	}
+	renderHTML(ctx, status, "oauth_device.html", oauthDevicePage{
+		UserCode:              form.UserCode,
+		Consent:               consent,
+		Username:              form.Username,
+		Reason:                reason,
+		OTPRequired:           form.OTP != "" || err == model.ErrLoginOTPRequired || err == model.ErrLoginWrongOTP,
+		PasswordResetRequired: form.NewPassword != "" || err == model.ErrLoginPasswordResetRequired,
+	})
+}
+
+// deviceError asks for the code again when it is invalid or expired.
+func (ctrl *apiController) deviceError(ctx *gin.Context, userCode string, err error) {
+	if err == model.ErrInvalidUserCode {
+		renderHTML(ctx, http.StatusBadRequest, "oauth_device.html", oauthDevicePage{UserCode: userCode, Reason: err.Error()})
+		return
+	}
+	renderHTML(ctx, http.StatusInternalServerError, "oauth_error.html", gin.H{"Reason": "Something went wrong, please try again later"})
+}
+
+// Introspect is the RFC 7662 token introspection endpoint for resource servers. Only confidential
+// clients may call it.
+func (ctrl *apiController) Introspect(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req model.OAuthIntrospectRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(&model.OAuthError{Code: model.OAuthErrInvalidRequest, Description: err.Error()}))
+		return
+	}
+	basicAuth := clientCredentials(ctx, &req.ClientID, &req.ClientSecret)
+
+	rsp, err := ctrl.oauth.Introspect(requestContext(ctx), req)
+	if err != nil {
+		oauthEndpointError(ctx, err, basicAuth)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// Revoke is the RFC 7009 token revocation endpoint. It answers 200 for unknown tokens too.
+func (ctrl *apiController) Revoke(ctx *gin.Context) {
+	var req model.OAuthRevokeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(&model.OAuthError{Code: model.OAuthErrInvalidRequest, Description: err.Error()}))
+		return
+	}
+	basicAuth := clientCredentials(ctx, &req.ClientID, &req.ClientSecret)
+
+	if err := ctrl.oauth.Revoke(requestContext(ctx), req); err != nil {
+		oauthEndpointError(ctx, err, basicAuth)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// clientCredentials takes the client credentials from HTTP Basic when present, and reports whether it did.
+func clientCredentials(ctx *gin.Context, clientID *string, clientSecret *string) bool {
+	id, secret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		return false
+	}
+	// RFC 6749 section 2.3.1 form-encodes the credentials before Basic encoding them.
+	*clientID, _ = url.QueryUnescape(id)
+	*clientSecret, _ = url.QueryUnescape(secret)
+	return true
+}
+
+// oauthEndpointError writes the RFC 6749 section 5.2 error response.
+func oauthEndpointError(ctx *gin.Context, err error, basicAuth bool) {
+	var oauthErr *model.OAuthError
+	if errors.As(err, &oauthErr) {
+		if oauthErr.Code == model.OAuthErrInvalidClient {
+			if basicAuth {
+				ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErr))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErr))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, errResponse(err))
+}
+
+// RegisterOAuthClient godoc
+// @Summary      Register an OAuth client
+// @Description  Register an application that may request tokens. Confidential clients get a secret, which is only returned here.
+// @Description  Public clients have no secret and can only use the authorization_code and refresh_token grants.
+// @Description  Redirect URIs must be https, or http on localhost, and are matched exactly.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        oauthClientRequest body model.OAuthClientRequest true "OAuth Client Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.OAuthClientResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/oauth/clients [post]
+func (ctrl *apiController) RegisterOAuthClient(ctx *gin.Context) {
+	var req model.OAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.oauth.RegisterClient(requestContext(ctx), req)
+	if err != nil {
+		if err == model.ErrInvalidOAuthGrantType || err == model.ErrPublicClientCredentials ||
+			err == model.ErrOAuthRedirectURIRequired || err == model.ErrInvalidRedirectURI {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// ListOAuthClients godoc
+// @Summary      List OAuth clients
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.OAuthClientResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/oauth/clients [get]
+func (ctrl *apiController) ListOAuthClients(ctx *gin.Context) {
+	rsp, err := ctrl.oauth.ListClients(requestContext(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// DeleteOAuthClient godoc
+// @Summary      Delete an OAuth client
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "OAuth client ID"
+// @Success      204
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/oauth/clients/{id} [delete]
+func (ctrl *apiController) DeleteOAuthClient(ctx *gin.Context) {
+	err := ctrl.oauth.DeleteClient(requestContext(ctx), ctx.Param("id"))
+	if err != nil {
+		if err == model.ErrOAuthClientNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
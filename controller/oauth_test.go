@@ -0,0 +1,596 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newOAuthTestRoute(t *testing.T) (*gin.Engine, *model.MockOAuthUsecase) {
+	ctrl := gomock.NewController(t)
+	mockOAuth := model.NewMockOAuthUsecase(ctrl)
+	mockFederation := model.NewMockFederationUsecase(ctrl)
+	mockFederation.EXPECT().Providers().AnyTimes().Return([]model.FederationProvider{{Name: "corp", DisplayName: "Contractor SSO"}})
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl), mockOAuth, model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+		model.NewMockServiceAccountUsecase(ctrl), mockFederation, model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockOAuth
+}
+
+func testAuthorizeQuery() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"web"},
+		"redirect_uri":          {"https://app.example.com/callback"},
+		"state":                 {"xyz"},
+		"code_challenge":        {"challenge"},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func TestAuthorizePage(t *testing.T) {
+	testCase := []struct {
+		name          string
+		err           error
+		checkResponse func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ok",
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				require.Contains(t, rr.Body.String(), "Web App")
+				require.Contains(t, rr.Body.String(), `name="code_challenge" value="challenge"`)
+				require.Contains(t, rr.Body.String(), `href="/federation/corp/login?next=%2foauth%2fauthorize%3f`)
+			},
+		},
+		{
+			name: "invalid redirect uri",
+			err:  model.ErrInvalidRedirectURI,
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				require.Empty(t, rr.Header().Get("Location"))
+			},
+		},
+		{
+			name: "error sent to client",
+			err:  &model.OAuthError{Code: model.OAuthErrInvalidRequest, Description: "code_challenge is required"},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusFound, rr.Code)
+				location, err := url.Parse(rr.Header().Get("Location"))
+				require.NoError(t, err)
+				require.Equal(t, model.OAuthErrInvalidRequest, location.Query().Get("error"))
+				require.Equal(t, "xyz", location.Query().Get("state"))
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockOAuth := newOAuthTestRoute(t)
+			var consent *model.OAuthConsent
+			if tc.err == nil {
+				consent = &model.OAuthConsent{ClientName: "Web App", Scopes: []string{"profile"}}
+			}
+			mockOAuth.EXPECT().ValidateAuthorizeRequest(gomock.Any(), gomock.Any()).Return(consent, tc.err)
+
+			httpReq, _ := http.NewRequest("GET", "/oauth/authorize?"+testAuthorizeQuery().Encode(), nil)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestAuthorizeLogin(t *testing.T) {
+	form := testAuthorizeQuery()
+	form.Set("username", "alice")
+	form.Set("password", "Passw0rdx")
+	form.Set("action", "allow")
+
+	t.Run("ok", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().Authorize(gomock.Any(), gomock.Any(), model.AccountRequest{Username: "alice", Password: "Passw0rdx"}, true).
+			DoAndReturn(func(_ interface{}, req model.OAuthAuthorizeRequest, _ model.AccountRequest, _ bool) (string, *model.AccountResponse, error) {
+				require.Equal(t, "challenge", req.CodeChallenge)
+				return "https://app.example.com/callback?code=abc&state=xyz", &model.AccountResponse{Success: true}, nil
+			})
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/authorize", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusFound, r.Code)
+		require.Equal(t, "https://app.example.com/callback?code=abc&state=xyz", r.Header().Get("Location"))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().Authorize(gomock.Any(), gomock.Any(), gomock.Any(), true).
+			Return("", &model.AccountResponse{Reason: model.ErrLoginWrongPassword.Error()}, model.ErrLoginWrongPassword)
+		mockOAuth.EXPECT().ValidateAuthorizeRequest(gomock.Any(), gomock.Any()).
+			Return(&model.OAuthConsent{ClientName: "Web App"}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/authorize", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusUnauthorized, r.Code)
+		require.Contains(t, r.Body.String(), model.ErrLoginWrongPassword.Error())
+		require.Contains(t, r.Body.String(), `value="alice"`)
+	})
+
+	t.Run("otp required", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().Authorize(gomock.Any(), gomock.Any(), model.AccountRequest{Username: "alice", Password: "Passw0rdx"}, true).
+			Return("", &model.AccountResponse{Reason: model.ErrLoginOTPRequired.Error()}, model.ErrLoginOTPRequired)
+		mockOAuth.EXPECT().ValidateAuthorizeRequest(gomock.Any(), gomock.Any()).
+			Return(&model.OAuthConsent{ClientName: "Web App"}, nil)
+		mockOAuth.EXPECT().Authorize(gomock.Any(), gomock.Any(), model.AccountRequest{Username: "alice", Password: "Passw0rdx", OTP: "123456"}, true).
+			Return("https://app.example.com/callback?code=abc&state=xyz", &model.AccountResponse{Success: true}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/authorize", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusUnauthorized, r.Code)
+		require.Contains(t, r.Body.String(), `name="otp"`)
+
+		withOTP := url.Values{}
+		for name, value := range form {
+			withOTP[name] = value
+		}
+		withOTP.Set("otp", "123456")
+		httpReq, _ = http.NewRequest("POST", "/oauth/authorize", strings.NewReader(withOTP.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusFound, r.Code)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().Authorize(gomock.Any(), gomock.Any(), gomock.Any(), true).
+			Return("", &model.AccountResponse{Reason: model.ErrLoginAccountLocked.Error()}, model.ErrLoginAccountLocked)
+		mockOAuth.EXPECT().ValidateAuthorizeRequest(gomock.Any(), gomock.Any()).
+			Return(&model.OAuthConsent{ClientName: "Web App"}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/authorize", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusForbidden, r.Code)
+		require.Contains(t, r.Body.String(), model.ErrLoginAccountLocked.Error())
+	})
+
+	t.Run("password reset required", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().Authorize(gomock.Any(), gomock.Any(), model.AccountRequest{Username: "alice", Password: "Passw0rdx"}, true).
+			Return("", &model.AccountResponse{Reason: model.ErrLoginPasswordResetRequired.Error()}, model.ErrLoginPasswordResetRequired)
+		mockOAuth.EXPECT().ValidateAuthorizeRequest(gomock.Any(), gomock.Any()).
+			Return(&model.OAuthConsent{ClientName: "Web App"}, nil).Times(2)
+		mockOAuth.EXPECT().Authorize(gomock.Any(), gomock.Any(),
+			model.AccountRequest{Username: "alice", Password: "Passw0rdx", NewPassword: "N3wPassword"}, true).
+			Return("https://app.example.com/callback?code=abc&state=xyz", &model.AccountResponse{Success: true}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/authorize", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusForbidden, r.Code)
+		require.Contains(t, r.Body.String(), `name="new_password"`)
+
+		withNewPassword := url.Values{}
+		for name, value := range form {
+			withNewPassword[name] = value
+		}
+		withNewPassword.Set("new_password", "N3wPassword")
+		withNewPassword.Set("confirm_password", "Other1234")
+		httpReq, _ = http.NewRequest("POST", "/oauth/authorize", strings.NewReader(withNewPassword.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusBadRequest, r.Code)
+		require.Contains(t, r.Body.String(), errPasswordMismatch.Error())
+		require.Contains(t, r.Body.String(), `name="new_password"`)
+
+		withNewPassword.Set("confirm_password", "N3wPassword")
+		httpReq, _ = http.NewRequest("POST", "/oauth/authorize", strings.NewReader(withNewPassword.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusFound, r.Code)
+	})
+}
+
+func TestToken(t *testing.T) {
+	testCase := []struct {
+		name          string
+		basicAuth     bool
+		err           error
+		checkResponse func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "ok",
+			basicAuth: true,
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+				rsp := &model.OAuthTokenResponse{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), rsp))
+				require.Equal(t, "token", rsp.AccessToken)
+			},
+		},
+		{
+			name:      "invalid client",
+			basicAuth: true,
+			err:       &model.OAuthError{Code: model.OAuthErrInvalidClient, Description: "client authentication failed"},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			},
+		},
+		{
+			name: "invalid grant",
+			err:  &model.OAuthError{Code: model.OAuthErrInvalidGrant, Description: "authorization code is expired"},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				rsp := map[string]string{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+				require.Equal(t, model.OAuthErrInvalidGrant, rsp["error"])
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockOAuth := newOAuthTestRoute(t)
+			var rsp *model.OAuthTokenResponse
+			if tc.err == nil {
+				rsp = &model.OAuthTokenResponse{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 900}
+			}
+			mockOAuth.EXPECT().Token(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ interface{}, req model.OAuthTokenRequest) (*model.OAuthTokenResponse, error) {
+					require.Equal(t, "web", req.ClientID)
+					require.Equal(t, "s3cret", req.ClientSecret)
+					require.Equal(t, "abc", req.Code)
+					return rsp, tc.err
+				})
+
+			form := url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}}
+			if !tc.basicAuth {
+				form.Set("client_id", "web")
+				form.Set("client_secret", "s3cret")
+			}
+			httpReq, _ := http.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+			httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth {
+				httpReq.SetBasicAuth("web", "s3cret")
+			}
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	testCase := []struct {
+		name          string
+		form          url.Values
+		setMock       func(mockOAuth *model.MockOAuthUsecase)
+		checkResponse func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "active",
+			form: url.Values{"token": {"abc"}, "token_type_hint": {"access_token"}},
+			setMock: func(mockOAuth *model.MockOAuthUsecase) {
+				mockOAuth.EXPECT().Introspect(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, req model.OAuthIntrospectRequest) (*model.OAuthIntrospectResponse, error) {
+						require.Equal(t, "abc", req.Token)
+						require.Equal(t, model.TokenTypeHintAccessToken, req.TokenTypeHint)
+						require.Equal(t, "api", req.ClientID)
+						require.Equal(t, "s3cret", req.ClientSecret)
+						return &model.OAuthIntrospectResponse{Active: true, Username: "alice"}, nil
+					})
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+				rsp := &model.OAuthIntrospectResponse{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), rsp))
+				require.True(t, rsp.Active)
+				require.Equal(t, "alice", rsp.Username)
+			},
+		},
+		{
+			name: "inactive",
+			form: url.Values{"token": {"abc"}},
+			setMock: func(mockOAuth *model.MockOAuthUsecase) {
+				mockOAuth.EXPECT().Introspect(gomock.Any(), gomock.Any()).Return(&model.OAuthIntrospectResponse{}, nil)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				require.JSONEq(t, `{"active":false}`, rr.Body.String())
+			},
+		},
+		{
+			name: "invalid client",
+			form: url.Values{"token": {"abc"}},
+			setMock: func(mockOAuth *model.MockOAuthUsecase) {
+				mockOAuth.EXPECT().Introspect(gomock.Any(), gomock.Any()).
+					Return(nil, &model.OAuthError{Code: model.OAuthErrInvalidClient, Description: "client authentication failed"})
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			},
+		},
+		{
+			name: "missing token",
+			form: url.Values{},
+			setMock: func(mockOAuth *model.MockOAuthUsecase) {
+				mockOAuth.EXPECT().Introspect(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockOAuth := newOAuthTestRoute(t)
+			tc.setMock(mockOAuth)
+
+			httpReq, _ := http.NewRequest("POST", "/oauth/introspect", strings.NewReader(tc.form.Encode()))
+			httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			httpReq.SetBasicAuth("api", "s3cret")
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	testCase := []struct {
+		name          string
+		err           error
+		checkResponse func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ok",
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "another client",
+			err:  &model.OAuthError{Code: model.OAuthErrUnauthorizedClient, Description: "token was issued to another client"},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				rsp := map[string]string{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+				require.Equal(t, model.OAuthErrUnauthorizedClient, rsp["error"])
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockOAuth := newOAuthTestRoute(t)
+			mockOAuth.EXPECT().Revoke(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ interface{}, req model.OAuthRevokeRequest) error {
+					require.Equal(t, "abc", req.Token)
+					require.Equal(t, "web", req.ClientID)
+					return tc.err
+				})
+
+			form := url.Values{"token": {"abc"}, "client_id": {"web"}}
+			httpReq, _ := http.NewRequest("POST", "/oauth/revoke", strings.NewReader(form.Encode()))
+			httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestDeviceAuthorization(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().DeviceAuthorization(gomock.Any(), model.OAuthDeviceAuthorizationRequest{ClientID: "cli", Scope: "profile"}).
+			Return(&model.OAuthDeviceAuthorizationResponse{DeviceCode: "device", UserCode: "BCDF-GHJK", Interval: 5}, nil)
+
+		form := url.Values{"client_id": {"cli"}, "scope": {"profile"}}
+		httpReq, _ := http.NewRequest("POST", "/oauth/device/code", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Equal(t, "no-store", r.Header().Get("Cache-Control"))
+		rsp := &model.OAuthDeviceAuthorizationResponse{}
+		require.NoError(t, json.Unmarshal(r.Body.Bytes(), rsp))
+		require.Equal(t, "BCDF-GHJK", rsp.UserCode)
+	})
+
+	t.Run("authorization pending", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().Token(gomock.Any(), gomock.Any()).
+			Return(nil, &model.OAuthError{Code: model.OAuthErrAuthorizationPending, Description: "pending"})
+
+		form := url.Values{"grant_type": {model.GrantDeviceCode}, "client_id": {"cli"}, "device_code": {"device"}}
+		httpReq, _ := http.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusBadRequest, r.Code)
+		require.Contains(t, r.Body.String(), model.OAuthErrAuthorizationPending)
+	})
+}
+
+func TestDevicePage(t *testing.T) {
+	t.Run("enter code", func(t *testing.T) {
+		route, _ := newOAuthTestRoute(t)
+
+		httpReq, _ := http.NewRequest("GET", "/oauth/device", nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), `name="user_code"`)
+	})
+
+	t.Run("consent", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().ValidateUserCode(gomock.Any(), "BCDF-GHJK").
+			Return(&model.OAuthConsent{ClientName: "Deploy CLI", Scopes: []string{"profile"}}, nil)
+
+		httpReq, _ := http.NewRequest("GET", "/oauth/device?user_code=BCDF-GHJK", nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Deploy CLI")
+		require.Contains(t, r.Body.String(), `name="password"`)
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().ValidateUserCode(gomock.Any(), "XXXX").Return(nil, model.ErrInvalidUserCode)
+
+		httpReq, _ := http.NewRequest("GET", "/oauth/device?user_code=XXXX", nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusBadRequest, r.Code)
+		require.Contains(t, r.Body.String(), model.ErrInvalidUserCode.Error())
+	})
+}
+
+func TestDeviceApprove(t *testing.T) {
+	form := url.Values{"user_code": {"BCDF-GHJK"}, "username": {"alice"}, "password": {"Passw0rdx"}, "action": {"allow"}}
+
+	t.Run("ok", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().ApproveDevice(gomock.Any(), "BCDF-GHJK", model.AccountRequest{Username: "alice", Password: "Passw0rdx"}, true).
+			Return(&model.AccountResponse{Success: true}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/device", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Device connected")
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().ApproveDevice(gomock.Any(), "BCDF-GHJK", gomock.Any(), true).
+			Return(&model.AccountResponse{Reason: model.ErrLoginWrongPassword.Error()}, model.ErrLoginWrongPassword)
+		mockOAuth.EXPECT().ValidateUserCode(gomock.Any(), "BCDF-GHJK").
+			Return(&model.OAuthConsent{ClientName: "Deploy CLI"}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/device", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusUnauthorized, r.Code)
+		require.Contains(t, r.Body.String(), model.ErrLoginWrongPassword.Error())
+		require.Contains(t, r.Body.String(), `value="alice"`)
+		require.NotContains(t, r.Body.String(), `name="otp"`)
+	})
+
+	t.Run("otp required", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().ApproveDevice(gomock.Any(), "BCDF-GHJK", model.AccountRequest{Username: "alice", Password: "Passw0rdx"}, true).
+			Return(&model.AccountResponse{Reason: model.ErrLoginOTPRequired.Error()}, model.ErrLoginOTPRequired)
+		mockOAuth.EXPECT().ValidateUserCode(gomock.Any(), "BCDF-GHJK").
+			Return(&model.OAuthConsent{ClientName: "Deploy CLI"}, nil)
+		mockOAuth.EXPECT().ApproveDevice(gomock.Any(), "BCDF-GHJK", model.AccountRequest{Username: "alice", Password: "Passw0rdx", OTP: "123456"}, true).
+			Return(&model.AccountResponse{Success: true}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/device", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusUnauthorized, r.Code)
+		require.Contains(t, r.Body.String(), `name="otp"`)
+
+		withOTP := url.Values{"user_code": {"BCDF-GHJK"}, "username": {"alice"}, "password": {"Passw0rdx"}, "otp": {"123456"}, "action": {"allow"}}
+		httpReq, _ = http.NewRequest("POST", "/oauth/device", strings.NewReader(withOTP.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Device connected")
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().ApproveDevice(gomock.Any(), "BCDF-GHJK", gomock.Any(), true).
+			Return(&model.AccountResponse{Reason: model.ErrLoginAccountLocked.Error()}, model.ErrLoginAccountLocked)
+		mockOAuth.EXPECT().ValidateUserCode(gomock.Any(), "BCDF-GHJK").
+			Return(&model.OAuthConsent{ClientName: "Deploy CLI"}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/device", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusForbidden, r.Code)
+		require.Contains(t, r.Body.String(), model.ErrLoginAccountLocked.Error())
+	})
+
+	t.Run("password reset required", func(t *testing.T) {
+		route, mockOAuth := newOAuthTestRoute(t)
+		mockOAuth.EXPECT().ApproveDevice(gomock.Any(), "BCDF-GHJK", model.AccountRequest{Username: "alice", Password: "Passw0rdx"}, true).
+			Return(&model.AccountResponse{Reason: model.ErrLoginPasswordResetRequired.Error()}, model.ErrLoginPasswordResetRequired)
+		mockOAuth.EXPECT().ValidateUserCode(gomock.Any(), "BCDF-GHJK").
+			Return(&model.OAuthConsent{ClientName: "Deploy CLI"}, nil)
+		mockOAuth.EXPECT().ApproveDevice(gomock.Any(), "BCDF-GHJK",
+			model.AccountRequest{Username: "alice", Password: "Passw0rdx", NewPassword: "N3wPassword"}, true).
+			Return(&model.AccountResponse{Success: true}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/oauth/device", strings.NewReader(form.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusForbidden, r.Code)
+		require.Contains(t, r.Body.String(), `name="new_password"`)
+
+		withNewPassword := url.Values{"user_code": {"BCDF-GHJK"}, "username": {"alice"}, "password": {"Passw0rdx"},
+			"new_password": {"N3wPassword"}, "confirm_password": {"N3wPassword"}, "action": {"allow"}}
+		httpReq, _ = http.NewRequest("POST", "/oauth/device", strings.NewReader(withNewPassword.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, http.StatusOK, r.Code)
+		require.Contains(t, r.Body.String(), "Device connected")
+	})
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenIDConfiguration serves the OpenID Connect discovery document.
+func (ctrl *apiController) OpenIDConfiguration(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.JSON(http.StatusOK, ctrl.oidc.Discovery())
+}
+
+// JWKS serves the public signing keys. The cache is kept short so that a next key shows up well
+// before it starts signing.
+func (ctrl *apiController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, ctrl.oidc.JWKS())
+}
+
+// UserInfo is the OpenID Connect userinfo endpoint. The access token comes as a bearer token, or in
+// the access_token form field of a POST.
+func (ctrl *apiController) UserInfo(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	token := bearerToken(ctx)
+	if token == "" && ctx.Request.Method == http.MethodPost {
+		token = ctx.PostForm("access_token")
+	}
+	if token == "" {
+		ctx.Header("WWW-Authenticate", `Bearer realm="userinfo"`)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	info, err := ctrl.oidc.UserInfo(requestContext(ctx), token)
+	switch {
+	case errors.Is(err, model.ErrInvalidToken):
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+	case errors.Is(err, model.ErrInsufficientScope):
+		ctx.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	default:
+		ctx.JSON(http.StatusOK, info)
+	}
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCTestRoute(t *testing.T) (*gin.Engine, *model.MockOIDCUsecase) {
+	ctrl := gomock.NewController(t)
+	mockOIDC := model.NewMockOIDCUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), mockOIDC, model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+		model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockOIDC
+}
+
+func TestOpenIDConfiguration(t *testing.T) {
+	route, mockOIDC := newOIDCTestRoute(t)
+	mockOIDC.EXPECT().Discovery().Return(model.OIDCDiscovery{Issuer: "https://auth.example.com", JWKSURI: "https://auth.example.com/jwks.json"})
+
+	httpReq, _ := http.NewRequest("GET", "/.well-known/openid-configuration", nil)
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+
+	require.Equal(t, http.StatusOK, r.Code)
+	rsp := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(r.Body.Bytes(), &rsp))
+	require.Equal(t, "https://auth.example.com", rsp["issuer"])
+	require.Equal(t, "https://auth.example.com/jwks.json", rsp["jwks_uri"])
+}
+
+func TestJWKS(t *testing.T) {
+	route, mockOIDC := newOIDCTestRoute(t)
+	mockOIDC.EXPECT().JWKS().Return(&model.JSONWebKeySet{Keys: []model.JSONWebKey{
+		{Kty: "OKP", Kid: "current", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: "x", Status: model.KeyStatusCurrent},
+		{Kty: "OKP", Kid: "next", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: "y", Status: model.KeyStatusNext},
+	}})
+
+	httpReq, _ := http.NewRequest("GET", "/jwks.json", nil)
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Contains(t, r.Header().Get("Cache-Control"), "max-age")
+	rsp := &model.JSONWebKeySet{}
+	require.NoError(t, json.Unmarshal(r.Body.Bytes(), rsp))
+	require.Len(t, rsp.Keys, 2)
+	require.Equal(t, "next", rsp.Keys[1].Kid)
+}
+
+func TestUserInfo(t *testing.T) {
+	testCase := []struct {
+		name          string
+		form          bool
+		token         string
+		err           error
+		checkResponse func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "ok",
+			token: "token",
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				rsp := map[string]interface{}{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+				require.Equal(t, "alice", rsp["preferred_username"])
+			},
+		},
+		{
+			name:  "token in form",
+			form:  true,
+			token: "token",
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+			},
+		},
+		{
+			name: "no token",
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			},
+		},
+		{
+			name:  "invalid token",
+			token: "token",
+			err:   model.ErrInvalidToken,
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.Contains(t, rr.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+			},
+		},
+		{
+			name:  "insufficient scope",
+			token: "token",
+			err:   model.ErrInsufficientScope,
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockOIDC := newOIDCTestRoute(t)
+			if tc.token == "" {
+				mockOIDC.EXPECT().UserInfo(gomock.Any(), gomock.Any()).Times(0)
+			} else {
+				var info map[string]interface{}
+				if tc.err == nil {
+					info = map[string]interface{}{"sub": "id", "preferred_username": "alice"}
+				}
+				mockOIDC.EXPECT().UserInfo(gomock.Any(), tc.token).Return(info, tc.err)
+			}
+
+			var httpReq *http.Request
+			if tc.form {
+				form := url.Values{"access_token": {tc.token}}
+				httpReq, _ = http.NewRequest("POST", "/userinfo", strings.NewReader(form.Encode()))
+				httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				httpReq, _ = http.NewRequest("GET", "/userinfo", nil)
+				if tc.token != "" {
+					httpReq.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+			}
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
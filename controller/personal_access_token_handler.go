@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCurrentAccount godoc
+// @Summary      Show the current account
+// @Description  Show the account the bearer token acts for and the scopes it grants. Needs the profile scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  model.CurrentAccountResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /accounts/me [get]
+func (ctrl *apiController) GetCurrentAccount(ctx *gin.Context) {
+	principal := currentPrincipal(ctx)
+	ctx.JSON(http.StatusOK, model.CurrentAccountResponse{
+		ID:       principal.AccountID.String(),
+		Username: principal.Username,
+		Scopes:   principal.Scopes,
+	})
+}
+
+// CreatePersonalAccessToken godoc
+// @Summary      Create a personal access token
+// @Description  Create a named token for scripts and CI, to use as a bearer token instead of a password. Needs the tokens scope.
+// @Description  The token is only returned here. It can get the profile, tokens and devices scopes the caller has, and expires after expires_in_days (default 30, at most 365).
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        personalAccessTokenRequest body model.PersonalAccessTokenRequest true "Personal Access Token Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.PersonalAccessTokenResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /accounts/me/tokens [post]
+func (ctrl *apiController) CreatePersonalAccessToken(ctx *gin.Context) {
+	var req model.PersonalAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.tokens.CreateToken(requestContext(ctx), currentPrincipal(ctx), req)
+	if err != nil {
+		if err == model.ErrInvalidTokenScope {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// ListPersonalAccessTokens godoc
+// @Summary      List personal access tokens
+// @Description  List the unrevoked tokens of the current account with their last-used time. Needs the tokens scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.PersonalAccessTokenResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Router       /accounts/me/tokens [get]
+func (ctrl *apiController) ListPersonalAccessTokens(ctx *gin.Context) {
+	rsp, err := ctrl.tokens.ListTokens(requestContext(ctx), currentPrincipal(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// RevokePersonalAccessToken godoc
+// @Summary      Revoke a personal access token
+// @Description  Revoke a token of the current account. It stops working at once. Needs the tokens scope.
+// @Tags         accounts
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Token ID"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      403  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /accounts/me/tokens/{id} [delete]
+func (ctrl *apiController) RevokePersonalAccessToken(ctx *gin.Context) {
+	err := ctrl.tokens.RevokeToken(requestContext(ctx), currentPrincipal(ctx), ctx.Param("id"))
+	if err != nil {
+		if err == model.ErrInvalidTokenID {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		} else if err == model.ErrPersonalAccessTokenNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newPersonalAccessTokenTestRoute(t *testing.T) (*gin.Engine, *model.MockPersonalAccessTokenUsecase, *model.MockAuthenticator) {
+	ctrl := gomock.NewController(t)
+	mockTokens := model.NewMockPersonalAccessTokenUsecase(ctrl)
+	mockAuth := model.NewMockAuthenticator(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl), mockTokens, mockAuth,
+		model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockTokens, mockAuth
+}
+
+func TestAccountAuth(t *testing.T) {
+	principal := &model.Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{model.ScopeProfile}}
+
+	testCase := []struct {
+		name          string
+		token         string
+		setMock       func(mockAuth *model.MockAuthenticator)
+		checkResponse func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "ok",
+			token: "pat_abc",
+			setMock: func(mockAuth *model.MockAuthenticator) {
+				mockAuth.EXPECT().Authenticate(gomock.Any(), "pat_abc").Return(principal, nil)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rr.Code)
+				rsp := &model.CurrentAccountResponse{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), rsp))
+				require.Equal(t, "alice", rsp.Username)
+				require.Equal(t, principal.AccountID.String(), rsp.ID)
+			},
+		},
+		{
+			name: "missing token",
+			setMock: func(mockAuth *model.MockAuthenticator) {
+				mockAuth.EXPECT().Authenticate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			},
+		},
+		{
+			name:  "invalid token",
+			token: "pat_abc",
+			setMock: func(mockAuth *model.MockAuthenticator) {
+				mockAuth.EXPECT().Authenticate(gomock.Any(), "pat_abc").Return(nil, model.ErrInvalidToken)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.Contains(t, rr.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+			},
+		},
+		{
+			name:  "insufficient scope",
+			token: "pat_abc",
+			setMock: func(mockAuth *model.MockAuthenticator) {
+				mockAuth.EXPECT().Authenticate(gomock.Any(), "pat_abc").
+					Return(&model.Principal{Username: "alice", Scopes: []string{model.ScopeTokens}}, nil)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+				require.Contains(t, rr.Header().Get("WWW-Authenticate"), `scope="profile"`)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, _, mockAuth := newPersonalAccessTokenTestRoute(t)
+			tc.setMock(mockAuth)
+
+			httpReq, _ := http.NewRequest("GET", "/api/accounts/me", nil)
+			if tc.token != "" {
+				httpReq.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	principal := &model.Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{model.ScopeTokens}}
+
+	testCase := []struct {
+		name          string
+		body          gin.H
+		setMock       func(mockTokens *model.MockPersonalAccessTokenUsecase)
+		checkResponse func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ok",
+			body: gin.H{"name": "ci", "scopes": []string{"tokens"}, "expires_in_days": 7},
+			setMock: func(mockTokens *model.MockPersonalAccessTokenUsecase) {
+				mockTokens.EXPECT().CreateToken(gomock.Any(), principal, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, _ *model.Principal, req model.PersonalAccessTokenRequest) (*model.PersonalAccessTokenResponse, error) {
+						require.Equal(t, "ci", req.Name)
+						require.Equal(t, 7, req.ExpiresInDays)
+						require.Equal(t, "alice", model.RequestInfoFromContext(ctx).Actor)
+						return &model.PersonalAccessTokenResponse{ID: uuid.New().String(), Name: "ci", Token: "pat_abc"}, nil
+					})
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, rr.Code)
+				rsp := &model.PersonalAccessTokenResponse{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), rsp))
+				require.Equal(t, "pat_abc", rsp.Token)
+			},
+		},
+		{
+			name: "invalid scope",
+			body: gin.H{"name": "ci", "scopes": []string{"admin"}},
+			setMock: func(mockTokens *model.MockPersonalAccessTokenUsecase) {
+				mockTokens.EXPECT().CreateToken(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, model.ErrInvalidTokenScope)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "expiry too long",
+			body: gin.H{"name": "ci", "scopes": []string{"tokens"}, "expires_in_days": 1000},
+			setMock: func(mockTokens *model.MockPersonalAccessTokenUsecase) {
+				mockTokens.EXPECT().CreateToken(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockTokens, mockAuth := newPersonalAccessTokenTestRoute(t)
+			mockAuth.EXPECT().Authenticate(gomock.Any(), "pat_abc").Return(principal, nil)
+			tc.setMock(mockTokens)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			httpReq, _ := http.NewRequest("POST", "/api/accounts/me/tokens", bytes.NewReader(body))
+			httpReq.Header.Set("Authorization", "Bearer pat_abc")
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestRevokePersonalAccessToken(t *testing.T) {
+	principal := &model.Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{model.ScopeTokens}}
+	id := uuid.New().String()
+
+	testCase := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "ok", code: http.StatusNoContent},
+		{name: "not found", err: model.ErrPersonalAccessTokenNotFound, code: http.StatusNotFound},
+		{name: "invalid id", err: model.ErrInvalidTokenID, code: http.StatusBadRequest},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockTokens, mockAuth := newPersonalAccessTokenTestRoute(t)
+			mockAuth.EXPECT().Authenticate(gomock.Any(), "pat_abc").Return(principal, nil)
+			mockTokens.EXPECT().RevokeToken(gomock.Any(), principal, id).Return(tc.err)
+
+			httpReq, _ := http.NewRequest("DELETE", "/api/accounts/me/tokens/"+id, nil)
+			httpReq.Header.Set("Authorization", "Bearer pat_abc")
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.code, r.Code)
+		})
+	}
+}
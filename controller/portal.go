@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+//go:embed assets
+var assetFS embed.FS
+
+// PortalTheme is the look of the captive portal: the templates of its pages and the assets they link
+// to under /portal/assets.
+type PortalTheme struct {
+	templates *template.Template
+	assets    http.FileSystem
+}
+
+// LoadPortalTheme brands the captive portal with the templates and assets in dir, so each site can have
+// its own without a rebuild. A template in dir replaces the built-in one of the same name, either a whole
+// page like portal_login.html or a part of all of them like portal_header, and files in dir/assets are
+// served before the built-in ones. An empty dir is the built-in theme.
+func LoadPortalTheme(dir string) (*PortalTheme, error) {
+	builtin, err := fs.Sub(assetFS, "assets")
+	if err != nil {
+		return nil, err
+	}
+	// The built-in templates are parsed again, as templates cannot be cloned once they have been used.
+	theme := &PortalTheme{
+		templates: template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		assets:    layeredFS{http.FS(builtin)},
+	}
+	if dir == "" {
+		return theme, nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("portal theme %s is not a directory", dir)
+	}
+	pages, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) > 0 {
+		if _, err := theme.templates.ParseFiles(pages...); err != nil {
+			return nil, err
+		}
+	}
+	assets := filepath.Join(dir, "assets")
+	if info, err := os.Stat(assets); err == nil && info.IsDir() {
+		theme.assets = layeredFS{http.Dir(assets), http.FS(builtin)}
+	}
+	return theme, nil
+}
+
+func (theme *PortalTheme) render(ctx *gin.Context, status int, name string, data interface{}) {
+	ctx.Render(status, render.HTML{Template: theme.templates, Name: name, Data: data})
+}
+
+// layeredFS opens a file from the first file system that has it. Directories are not served, so their
+// contents are not listed.
+type layeredFS []http.FileSystem
+
+func (layers layeredFS) Open(name string) (http.File, error) {
+	for _, layer := range layers {
+		file, err := layer.Open(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		info, err := file.Stat()
+		if err == nil && !info.IsDir() {
+			return file, nil
+		}
+		file.Close()
+	}
+	return nil, fs.ErrNotExist
+}
@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+var errPortalTermsNotAccepted = errors.New("Please accept the terms of use to continue")
+
+// portalClient is what the access point told about the client when it sent it to the portal. Vendors
+// name the parameters differently, the first one given is kept.
+type portalClient struct {
+	ClientMAC string
+	APMAC     string
+	SSID      string
+	// URL is where the client was going, it is sent there once online.
+	URL string
+}
+
+var portalClientParams = struct {
+	clientMAC, apMAC, ssid, url []string
+}{
+	clientMAC: []string{"client_mac", "mac", "id", "clientmac"},
+	apMAC:     []string{"ap_mac", "ap", "apmac", "called"},
+	ssid:      []string{"ssid"},
+	url:       []string{"url", "redirect", "redirurl", "continue_url", "orig_url"},
+}
+
+type portalPage struct {
+	Client portalClient
+	// Query carries the client parameters in the links between the pages, it is encoded already.
+	Query       template.URL
+	Username    string
+	OTPRequired bool
+	Reason      string
+}
+
+type portalLoginForm struct {
+	Username    string `form:"username"`
+	Password    string `form:"password"`
+	OTP         string `form:"otp"`
+	AcceptTerms bool   `form:"accept_terms"`
+}
+
+type portalVoucherForm struct {
+	Code        string `form:"code"`
+	AcceptTerms bool   `form:"accept_terms"`
+}
+
+// PortalSplash welcomes a client the access point sent to the portal, and offers to sign in with an
+// account or a voucher.
+func (ctrl *apiController) PortalSplash(ctx *gin.Context) {
+	ctrl.portal.render(ctx, http.StatusOK, "portal_splash.html", newPortalPage(ctx))
+}
+
+// PortalTerms shows the terms of use clients accept to get online.
+func (ctrl *apiController) PortalTerms(ctx *gin.Context) {
+	ctrl.portal.render(ctx, http.StatusOK, "portal_terms.html", newPortalPage(ctx))
+}
+
+// PortalLoginPage shows the form to sign in with an account.
+func (ctrl *apiController) PortalLoginPage(ctx *gin.Context) {
+	ctrl.portal.render(ctx, http.StatusOK, "portal_login.html", newPortalPage(ctx))
+}
+
+// PortalLogin signs the client in with an account, asking for the one-time code of accounts that have
+// a second factor.
+func (ctrl *apiController) PortalLogin(ctx *gin.Context) {
+	page := newPortalPage(ctx)
+	var form portalLoginForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Reason = err.Error()
+		ctrl.portal.render(ctx, http.StatusBadRequest, "portal_login.html", page)
+		return
+	}
+	page.Username = form.Username
+	if !form.AcceptTerms {
+		page.Reason = errPortalTermsNotAccepted.Error()
+		ctrl.portal.render(ctx, http.StatusBadRequest, "portal_login.html", page)
+		return
+	}
+
+	login := model.AccountRequest{Username: form.Username, Password: form.Password, OTP: form.OTP}
+	rsp, err := ctrl.usecase.LoginAccount(requestContext(ctx), login)
+	if err != nil {
+		page.OTPRequired = err == model.ErrLoginOTPRequired || err == model.ErrLoginWrongOTP
+		ctrl.portalLoginFailed(ctx, "portal_login.html", page, rsp, err)
+		return
+	}
+	ctrl.portalOnline(ctx, page.Client, rsp.Username)
+}
+
+// PortalVoucherPage shows the form to enter the code of a voucher.
+func (ctrl *apiController) PortalVoucherPage(ctx *gin.Context) {
+	ctrl.portal.render(ctx, http.StatusOK, "portal_voucher.html", newPortalPage(ctx))
+}
+
+// PortalVoucher signs the client in with a voucher, whose code is both its username and password.
+func (ctrl *apiController) PortalVoucher(ctx *gin.Context) {
+	page := newPortalPage(ctx)
+	var form portalVoucherForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		page.Reason = err.Error()
+		ctrl.portal.render(ctx, http.StatusBadRequest, "portal_voucher.html", page)
+		return
+	}
+	if !form.AcceptTerms {
+		page.Reason = errPortalTermsNotAccepted.Error()
+		ctrl.portal.render(ctx, http.StatusBadRequest, "portal_voucher.html", page)
+		return
+	}
+
+	login := model.AccountRequest{Username: form.Code, Password: form.Code}
+	rsp, err := ctrl.usecase.LoginAccount(requestContext(ctx), login)
+	if err != nil {
+		ctrl.portalLoginFailed(ctx, "portal_voucher.html", page, rsp, err)
+		return
+	}
+	ctrl.portalOnline(ctx, page.Client, rsp.Username)
+}
+
+// portalLoginFailed shows the form again with the reason the login failed.
+func (ctrl *apiController) portalLoginFailed(ctx *gin.Context, name string, page portalPage, rsp *model.AccountResponse, err error) {
+	status := loginStatus(err)
+	if rsp == nil || status == http.StatusInternalServerError {
+		page.Reason = errSomethingWrong.Error()
+		ctrl.portal.render(ctx, http.StatusInternalServerError, name, page)
+		return
+	}
+	page.Reason = rsp.Reason
+	ctrl.portal.render(ctx, status, name, page)
+}
+
+// portalOnline records the session with the client and access point it is on, then sends the client
+// where it was going.
+func (ctrl *apiController) portalOnline(ctx *gin.Context, client portalClient, username string) {
+	entry := model.AuditEntry{
+		Type:    model.AuditPortalLogin,
+		Actor:   username,
+		Outcome: model.AuditOutcomeSuccess,
+	}
+	var detail []string
+	if client.ClientMAC != "" {
+		entry.Target = "client:" + client.ClientMAC
+		detail = append(detail, "client_mac="+client.ClientMAC)
+	}
+	if client.APMAC != "" {
+		detail = append(detail, "ap_mac="+client.APMAC)
+	}
+	if client.SSID != "" {
+		detail = append(detail, "ssid="+client.SSID)
+	}
+	if client.URL != "" {
+		detail = append(detail, "url="+client.URL)
+	}
+	entry.Detail = strings.Join(detail, " ")
+	ctrl.audit.Record(requestContext(ctx), entry)
+
+	if client.URL != "" {
+		ctx.Redirect(http.StatusSeeOther, client.URL)
+		return
+	}
+	ctrl.portal.render(ctx, http.StatusOK, "portal_success.html", portalPage{Client: client})
+}
+
+// newPortalPage reads the client parameters from the query string the access point redirected with,
+// or from the form they were carried in.
+func newPortalPage(ctx *gin.Context) portalPage {
+	ctx.Request.ParseForm()
+	values := ctx.Request.Form
+	client := portalClient{
+		ClientMAC: firstValue(values, portalClientParams.clientMAC),
+		APMAC:     firstValue(values, portalClientParams.apMAC),
+		SSID:      firstValue(values, portalClientParams.ssid),
+		URL:       portalRedirect(firstValue(values, portalClientParams.url)),
+	}
+
+	query := url.Values{}
+	for name, value := range map[string]string{"client_mac": client.ClientMAC, "ap_mac": client.APMAC, "ssid": client.SSID, "url": client.URL} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	return portalPage{Client: client, Query: template.URL(query.Encode())}
+}
+
+func firstValue(values url.Values, names []string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(values.Get(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// portalRedirect keeps the URL the client was going to when it is a web page.
+func portalRedirect(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
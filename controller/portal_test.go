@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newPortalTestRoute(t *testing.T, theme *PortalTheme) (*gin.Engine, *model.MockUsecaseHandler, *model.MockAuditUsecase) {
+	ctrl := gomock.NewController(t)
+	mockUsecase := model.NewMockUsecaseHandler(ctrl)
+	mockAudit := model.NewMockAuditUsecase(ctrl)
+	controller := NewController(mockUsecase, mockAudit, model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl),
+		model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), theme)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockUsecase, mockAudit
+}
+
+func postPortalForm(route *gin.Engine, path string, form url.Values) *httptest.ResponseRecorder {
+	httpReq, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+	return r
+}
+
+func TestPortalSplash(t *testing.T) {
+	route, _, _ := newPortalTestRoute(t, nil)
+
+	httpReq, _ := http.NewRequest("GET", "/portal?id=aa:bb:cc:dd:ee:ff&ap=11:22:33:44:55:66&ssid=Lobby&url=http://example.com/news", nil)
+	r := httptest.NewRecorder()
+	route.ServeHTTP(r, httpReq)
+
+	require.Equal(t, http.StatusOK, r.Code)
+	body := r.Body.String()
+	require.Contains(t, body, "on Lobby")
+	require.Contains(t, body, `/portal/login?ap_mac=11%3A22%3A33%3A44%3A55%3A66&amp;client_mac=aa%3Abb%3Acc%3Add%3Aee%3Aff&amp;ssid=Lobby&amp;url=http%3A%2F%2Fexample.com%2Fnews`)
+	require.Contains(t, body, `/portal/assets/portal.css`)
+}
+
+func TestPortalLogin(t *testing.T) {
+	client := url.Values{"client_mac": {"aa:bb:cc:dd:ee:ff"}, "ap_mac": {"11:22:33:44:55:66"}, "ssid": {"Lobby"}}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		buildStubs    func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase)
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name: "RedirectToURL",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "accept_terms": {"true"}, "url": {"http://example.com/news"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "Passw0rd"}).
+					Return(&model.AccountResponse{Success: true, Username: "alice"}, nil)
+				audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry model.AuditEntry) {
+					require.Equal(t, model.AuditPortalLogin, entry.Type)
+					require.Equal(t, "alice", entry.Actor)
+					require.Equal(t, "client:aa:bb:cc:dd:ee:ff", entry.Target)
+					require.Equal(t, "client_mac=aa:bb:cc:dd:ee:ff ap_mac=11:22:33:44:55:66 ssid=Lobby url=http://example.com/news", entry.Detail)
+				})
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusSeeOther, r.Code)
+				require.Equal(t, "http://example.com/news", r.Header().Get("Location"))
+			},
+		},
+		{
+			name: "OnlineWithoutURL",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "accept_terms": {"true"}, "url": {"javascript:alert(1)"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Return(&model.AccountResponse{Success: true, Username: "alice"}, nil)
+				audit.EXPECT().Record(gomock.Any(), gomock.Any())
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, r.Code)
+				require.Contains(t, r.Body.String(), "You are online")
+			},
+		},
+		{
+			name: "TermsNotAccepted",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, r.Code)
+				require.Contains(t, r.Body.String(), "Please accept the terms of use")
+				require.Contains(t, r.Body.String(), `name="client_mac" value="aa:bb:cc:dd:ee:ff"`)
+			},
+		},
+		{
+			name: "WrongPassword",
+			form: url.Values{"username": {"alice"}, "password": {"wrong"}, "accept_terms": {"true"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginWrongPassword.Error()}, model.ErrLoginWrongPassword)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrLoginWrongPassword.Error())
+				require.Contains(t, r.Body.String(), `value="alice"`)
+			},
+		},
+		{
+			name: "OTPRequired",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "accept_terms": {"true"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginOTPRequired.Error()}, model.ErrLoginOTPRequired)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, r.Code)
+				require.Contains(t, r.Body.String(), `name="otp"`)
+			},
+		},
+		{
+			name: "Locked",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "accept_terms": {"true"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginAccountLocked.Error()}, model.ErrLoginAccountLocked)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, r.Code)
+				require.Contains(t, r.Body.String(), model.ErrLoginAccountLocked.Error())
+			},
+		},
+		{
+			name: "Blocked",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "accept_terms": {"true"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginAttemptBlocked.Error()}, model.ErrLoginAttemptBlocked)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, r.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			form: url.Values{"username": {"alice"}, "password": {"Passw0rd"}, "accept_terms": {"true"}},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Return(nil, context.DeadlineExceeded)
+			},
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, r.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockUsecase, mockAudit := newPortalTestRoute(t, nil)
+			tc.buildStubs(mockUsecase, mockAudit)
+			form := url.Values{}
+			for name, values := range client {
+				form[name] = values
+			}
+			for name, values := range tc.form {
+				form[name] = values
+			}
+			tc.checkResponse(t, postPortalForm(route, "/portal/login", form))
+		})
+	}
+}
+
+func TestPortalVoucher(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "expired", err: model.ErrVoucherExpired, status: http.StatusForbidden},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockUsecase, mockAudit := newPortalTestRoute(t, nil)
+			rsp := &model.AccountResponse{Success: true, Username: "ABCDE23456"}
+			if tc.err != nil {
+				rsp = &model.AccountResponse{Reason: tc.err.Error()}
+			}
+			mockUsecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "abcde-23456", Password: "abcde-23456"}).
+				Return(rsp, tc.err)
+			if tc.err == nil {
+				mockAudit.EXPECT().Record(gomock.Any(), gomock.Any())
+			}
+
+			r := postPortalForm(route, "/portal/voucher", url.Values{"code": {"abcde-23456"}, "accept_terms": {"true"}})
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
+
+func TestPortalTheme(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "header.html"),
+		[]byte(`{{define "portal_header"}}<html><body><h1>Hotel Lobby</h1><main>{{end}}`), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "assets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "logo.svg"), []byte("<svg></svg>"), 0o644))
+
+	theme, err := LoadPortalTheme(dir)
+	require.NoError(t, err)
+	route, _, _ := newPortalTestRoute(t, theme)
+
+	testCases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{path: "/portal", status: http.StatusOK, body: "Hotel Lobby"},
+		{path: "/portal/terms", status: http.StatusOK, body: "Terms of use"},
+		{path: "/portal/assets/logo.svg", status: http.StatusOK, body: "<svg></svg>"},
+		{path: "/portal/assets/portal.css", status: http.StatusOK, body: "font-family"},
+		{path: "/portal/assets/", status: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		httpReq, _ := http.NewRequest("GET", tc.path, nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+		require.Equal(t, tc.status, r.Code, tc.path)
+		require.Contains(t, r.Body.String(), tc.body, tc.path)
+	}
+}
+
+func TestLoadPortalThemeErrors(t *testing.T) {
+	_, err := LoadPortalTheme(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "portal_splash.html"), []byte(`{{template "portal_header" .}`), 0o644))
+	_, err = LoadPortalTheme(dir)
+	require.Error(t, err)
+}
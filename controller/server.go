@@ -1,6 +1,9 @@
 package controller
 
 import (
+	"os"
+	"strings"
+
 	"github.com/ambroseqiu/senao_hw/model"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -9,13 +12,59 @@ import (
 )
 
 type apiController struct {
-	usecase model.UsecaseHandler
-	route   *gin.Engine
+	usecase         model.UsecaseHandler
+	audit           model.AuditUsecase
+	webhook         model.WebhookUsecase
+	oauth           model.OAuthUsecase
+	oidc            model.OIDCUsecase
+	signingKeys     model.SigningKeyUsecase
+	tokens          model.PersonalAccessTokenUsecase
+	auth            model.Authenticator
+	serviceAccounts model.ServiceAccountUsecase
+	federation      model.FederationUsecase
+	identities      model.IdentityUsecase
+	accounting      model.AccountingUsecase
+	vouchers        model.VoucherUsecase
+	devices         model.DeviceUsecase
+	forwardAuth     model.ForwardAuthUsecase
+	sessions        model.BrowserSessionUsecase
+	portal          *PortalTheme
+	adminAPIKey     string
+	// adminConsoleRoles are the roles that let an account use the admin console.
+	adminConsoleRoles []string
+	sessionCookie     sessionCookieConfig
+	// allowedOrigins are the origins of other sites whose pages may call the API with the session cookie.
+	allowedOrigins []string
+	route          *gin.Engine
 }
 
-func NewController(usecase model.UsecaseHandler) apiController {
+func NewController(usecase model.UsecaseHandler, audit model.AuditUsecase, webhook model.WebhookUsecase, oauth model.OAuthUsecase,
+	oidc model.OIDCUsecase, signingKeys model.SigningKeyUsecase, tokens model.PersonalAccessTokenUsecase, auth model.Authenticator,
+	serviceAccounts model.ServiceAccountUsecase, federation model.FederationUsecase, identities model.IdentityUsecase,
+	accounting model.AccountingUsecase, vouchers model.VoucherUsecase, devices model.DeviceUsecase,
+	forwardAuth model.ForwardAuthUsecase, sessions model.BrowserSessionUsecase, portal *PortalTheme) apiController {
+	if portal == nil {
+		// The built-in theme has nothing to fail on.
+		portal, _ = LoadPortalTheme("")
+	}
 	return apiController{
-		usecase: usecase,
+		usecase:         usecase,
+		audit:           audit,
+		webhook:         webhook,
+		oauth:           oauth,
+		oidc:            oidc,
+		signingKeys:     signingKeys,
+		tokens:          tokens,
+		auth:            auth,
+		serviceAccounts: serviceAccounts,
+		federation:      federation,
+		identities:      identities,
+		accounting:      accounting,
+		vouchers:        vouchers,
+		devices:         devices,
+		forwardAuth:     forwardAuth,
+		sessions:        sessions,
+		portal:          portal,
 	}
 }
 
@@ -23,15 +72,146 @@ func (ctrl *apiController) SetRoute(route *gin.Engine) {
 
 	// 設置CORS中間件
 	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			ctrl.allowedOrigins = append(ctrl.allowedOrigins, origin)
+		}
+	}
+	if len(ctrl.allowedOrigins) > 0 {
+		// Browsers only send the session cookie to the API from pages of origins it lists.
+		config.AllowOrigins = ctrl.allowedOrigins
+		config.AllowCredentials = true
+	} else {
+		config.AllowAllOrigins = true
+	}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-CSRF-Token"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-CSRF-Token", reauthHeader}
 	route.Use(cors.New(config))
+	route.Use(requestID())
 
 	route.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	oauthRoute := route.Group("/oauth")
+	oauthRoute.GET("/authorize", ctrl.Authorize)
+	oauthRoute.POST("/authorize", ctrl.AuthorizeLogin)
+	oauthRoute.POST("/token", ctrl.Token)
+	oauthRoute.POST("/introspect", ctrl.Introspect)
+	oauthRoute.POST("/revoke", ctrl.Revoke)
+	oauthRoute.POST("/device/code", ctrl.DeviceAuthorization)
+	oauthRoute.GET("/device", ctrl.DevicePage)
+	oauthRoute.POST("/device", ctrl.DeviceApprove)
+	route.GET("/federation/:provider/login", ctrl.FederationLogin)
+	route.GET("/federation/:provider/callback", ctrl.FederationCallback)
+	route.GET("/.well-known/openid-configuration", ctrl.OpenIDConfiguration)
+	route.GET("/jwks.json", ctrl.JWKS)
+	route.GET("/userinfo", ctrl.UserInfo)
+	route.POST("/userinfo", ctrl.UserInfo)
+	portalRoute := route.Group("/portal")
+	portalRoute.GET("", ctrl.PortalSplash)
+	portalRoute.GET("/terms", ctrl.PortalTerms)
+	portalRoute.GET("/login", ctrl.PortalLoginPage)
+	portalRoute.POST("/login", ctrl.PortalLogin)
+	portalRoute.GET("/voucher", ctrl.PortalVoucherPage)
+	portalRoute.POST("/voucher", ctrl.PortalVoucher)
+	portalRoute.StaticFS("/assets", ctrl.portal.assets)
+	accountRoute := route.Group("/account")
+	accountRoute.GET("/signup", ctrl.AccountSignupPage)
+	accountRoute.POST("/signup", ctrl.AccountSignup)
+	accountRoute.GET("/login", ctrl.AccountLoginPage)
+	accountRoute.POST("/login", ctrl.AccountLogin)
+	accountRoute.GET("/", ctrl.accountPageAuth(), ctrl.AccountHome)
+	accountRoute.POST("/logout", ctrl.accountPageAuth(), ctrl.AccountLogout)
+	accountRoute.GET("/password", ctrl.accountPageAuth(), ctrl.AccountPasswordPage)
+	accountRoute.POST("/password", ctrl.accountPageAuth(), ctrl.AccountPassword)
+	accountRoute.GET("/2fa", ctrl.accountPageAuth(), ctrl.AccountTOTPPage)
+	accountRoute.POST("/2fa", ctrl.accountPageAuth(), ctrl.AccountTOTP)
+	accountRoute.GET("/sessions", ctrl.accountPageAuth(), ctrl.AccountSessionsPage)
+	accountRoute.POST("/sessions/:id/revoke", ctrl.accountPageAuth(), ctrl.AccountRevokeSession)
+	accountRoute.StaticFS("/assets", accountAssets())
+	ctrl.adminConsoleRoles = loadAdminConsoleRoles()
+	consoleRoute := route.Group("/admin")
+	consoleRoute.StaticFS("/assets", accountAssets())
+	staffRoute := consoleRoute.Group("", ctrl.accountPageAuth(), ctrl.adminPageAuth())
+	staffRoute.GET("/", ctrl.AdminHome)
+	staffRoute.GET("/accounts", ctrl.AdminAccountsPage)
+	staffRoute.GET("/accounts/:username", ctrl.AdminAccountPage)
+	staffRoute.POST("/accounts/:username/lock", ctrl.AdminLockAccount)
+	staffRoute.POST("/accounts/:username/unlock", ctrl.AdminUnlockAccount)
+	staffRoute.POST("/accounts/:username/password-reset", ctrl.AdminResetPassword)
+	staffRoute.POST("/accounts/:username/roles", ctrl.AdminSetAccountRoles)
+	staffRoute.GET("/audit", ctrl.AdminAuditPage)
+
 	apiRoute := route.Group("/api")
 	apiRoute.POST("/accounts", ctrl.CreateAccount)
 	apiRoute.POST("/login", ctrl.LoginAccount)
+	apiRoute.POST("/login/passkey/begin", ctrl.BeginPasskeyLogin)
+	apiRoute.POST("/login/passkey/finish", ctrl.FinishPasskeyLogin)
+	// nginx auth_request asks with the method of the request it checks.
+	apiRoute.Any("/auth/verify", ctrl.VerifyForwardAuth)
+	ctrl.sessionCookie = loadSessionCookieConfig()
+	apiRoute.POST("/sessions", ctrl.CreateSession)
+	apiRoute.GET("/sessions/current", ctrl.sessionAuth(), ctrl.GetCurrentSession)
+	apiRoute.DELETE("/sessions/current", ctrl.sessionAuth(), ctrl.DeleteCurrentSession)
+	apiRoute.GET("/accounts/me", ctrl.accountAuth(model.ScopeProfile), ctrl.GetCurrentAccount)
+	apiRoute.GET("/accounts/me/tokens", ctrl.accountAuth(model.ScopeTokens), ctrl.ListPersonalAccessTokens)
+	apiRoute.POST("/accounts/me/tokens", ctrl.accountAuth(model.ScopeTokens), ctrl.CreatePersonalAccessToken)
+	apiRoute.DELETE("/accounts/me/tokens/:id", ctrl.accountAuth(model.ScopeTokens), ctrl.RevokePersonalAccessToken)
+	apiRoute.GET("/accounts/me/sessions", ctrl.accountAuth(model.ScopeSessions), ctrl.ListBrowserSessions)
+	apiRoute.DELETE("/accounts/me/sessions/:id", ctrl.accountAuth(model.ScopeSessions), ctrl.RevokeBrowserSession)
+	apiRoute.POST("/accounts/me/reauthenticate", ctrl.accountAuth(model.ScopeIdentities), ctrl.Reauthenticate)
+	methodRoute := apiRoute.Group("/accounts/me/login-methods", ctrl.accountAuth(model.ScopeIdentities))
+	methodRoute.GET("", ctrl.ListLoginMethods)
+	methodRoute.PUT("/password", ctrl.SetPassword)
+	methodRoute.DELETE("/password", ctrl.RemovePassword)
+	methodRoute.POST("/totp", ctrl.BeginTOTP)
+	methodRoute.POST("/totp/confirm", ctrl.ConfirmTOTP)
+	methodRoute.POST("/passkeys", ctrl.BeginPasskey)
+	methodRoute.POST("/passkeys/finish", ctrl.FinishPasskey)
+	methodRoute.POST("/providers/:provider", ctrl.LinkProvider)
+	methodRoute.DELETE("/:id", ctrl.UnlinkLoginMethod)
+	deviceRoute := apiRoute.Group("/accounts/me/devices", ctrl.accountAuth(model.ScopeDevices))
+	deviceRoute.GET("", ctrl.ListOwnDevices)
+	deviceRoute.POST("", ctrl.RegisterOwnDevice)
+	deviceRoute.DELETE("/:id", ctrl.DeleteOwnDevice)
+
+	ctrl.adminAPIKey = os.Getenv("ADMIN_API_KEY")
+	adminRoute := apiRoute.Group("/admin", ctrl.adminAuth())
+	adminRoute.GET("/audit/events", ctrl.QueryAuditEvents)
+	adminRoute.GET("/audit/export", ctrl.ExportAuditEvents)
+	adminRoute.GET("/accounts", ctrl.SearchAccounts)
+	adminRoute.GET("/accounts/:username", ctrl.GetAccount)
+	adminRoute.DELETE("/accounts/:username", ctrl.DeleteAccount)
+	adminRoute.GET("/accounts/:username/failed-logins", ctrl.ListFailedLogins)
+	adminRoute.POST("/accounts/:username/lock", ctrl.LockAccount)
+	adminRoute.POST("/accounts/:username/unlock", ctrl.UnlockAccount)
+	adminRoute.POST("/accounts/:username/password-reset", ctrl.ResetPassword)
+	adminRoute.PUT("/accounts/:username/roles", ctrl.SetAccountRoles)
+	adminRoute.POST("/webhooks", ctrl.RegisterWebhook)
+	adminRoute.GET("/webhooks", ctrl.ListWebhooks)
+	adminRoute.DELETE("/webhooks/:id", ctrl.DeleteWebhook)
+	adminRoute.GET("/webhooks/dead-letters", ctrl.ListWebhookDeadLetters)
+	adminRoute.POST("/webhooks/dead-letters/:id/replay", ctrl.ReplayWebhookDeadLetter)
+	adminRoute.POST("/oauth/clients", ctrl.RegisterOAuthClient)
+	adminRoute.GET("/oauth/clients", ctrl.ListOAuthClients)
+	adminRoute.DELETE("/oauth/clients/:id", ctrl.DeleteOAuthClient)
+	adminRoute.GET("/keys", ctrl.ListSigningKeys)
+	adminRoute.POST("/keys/rotate", ctrl.RotateSigningKeys)
+	adminRoute.POST("/keys/:id/revoke", ctrl.RevokeSigningKey)
+	adminRoute.POST("/service-accounts", ctrl.CreateServiceAccount)
+	adminRoute.GET("/service-accounts", ctrl.ListServiceAccounts)
+	adminRoute.DELETE("/service-accounts/:username", ctrl.DeleteServiceAccount)
+	adminRoute.POST("/service-accounts/:username/secret", ctrl.RotateServiceAccountSecret)
+	adminRoute.POST("/service-accounts/:username/keys", ctrl.AddServiceAccountKey)
+	adminRoute.DELETE("/service-accounts/:username/keys/:id", ctrl.RevokeServiceAccountKey)
+	adminRoute.GET("/accounting/sessions", ctrl.ListOnlineSessions)
+	adminRoute.GET("/accounting/usage/:username", ctrl.GetAccountingUsage)
+	adminRoute.POST("/accounting/sessions/:id/disconnect", ctrl.DisconnectSession)
+	adminRoute.POST("/accounting/sessions/:id/coa", ctrl.ChangeSessionAuthorization)
+	adminRoute.POST("/vouchers", ctrl.CreateVouchers)
+	adminRoute.GET("/vouchers/batches/:batch", ctrl.ListVouchers)
+	adminRoute.POST("/devices", ctrl.RegisterDevice)
+	adminRoute.GET("/devices", ctrl.ListDevices)
+	adminRoute.POST("/devices/import", ctrl.ImportDevices)
+	adminRoute.DELETE("/devices/:id", ctrl.DeleteDevice)
 
 	ctrl.route = route
 }
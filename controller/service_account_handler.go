@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateServiceAccount godoc
+// @Summary      Create a service account
+// @Description  Create an account for a machine, owned by an existing user account. It cannot log in with a password.
+// @Description  It gets tokens from /oauth/token with the client_credentials grant and the returned client ID and secret,
+// @Description  or with a JWT assertion (RFC 7523) signed by one of its keys. The secret is only returned here.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        serviceAccountRequest body model.ServiceAccountRequest true "Service Account Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.ServiceAccountResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError "Account Is Already Existed"
+// @Router       /admin/service-accounts [post]
+func (ctrl *apiController) CreateServiceAccount(ctx *gin.Context) {
+	var req model.ServiceAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.serviceAccounts.CreateServiceAccount(requestContext(ctx), req)
+	if err != nil {
+		if err == model.ErrInvalidUsernameFormat || err == model.ErrUsernameIsTooLarge ||
+			err == model.ErrUsernameIsTooShort || err == model.ErrInvalidServiceAccountOwner {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		} else if err == model.ErrAccountIsAlreadyExisted {
+			ctx.JSON(http.StatusConflict, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// ListServiceAccounts godoc
+// @Summary      List service accounts
+// @Description  List service accounts with their owner, client ID, scopes and keys, revoked keys included.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.ServiceAccountResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/service-accounts [get]
+func (ctrl *apiController) ListServiceAccounts(ctx *gin.Context) {
+	rsp, err := ctrl.serviceAccounts.ListServiceAccounts(requestContext(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// DeleteServiceAccount godoc
+// @Summary      Delete a service account
+// @Description  Delete the service account with its client and keys. Tokens it already has stay valid until they expire.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Success      204
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/service-accounts/{username} [delete]
+func (ctrl *apiController) DeleteServiceAccount(ctx *gin.Context) {
+	err := ctrl.serviceAccounts.DeleteServiceAccount(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		if err == model.ErrServiceAccountNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RotateServiceAccountSecret godoc
+// @Summary      Rotate a service account secret
+// @Description  Replace the client secret of the service account. The old secret stops working at once; the new one is only returned here.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Produce      json
+// @Success      200  {object}  model.ServiceAccountResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/service-accounts/{username}/secret [post]
+func (ctrl *apiController) RotateServiceAccountSecret(ctx *gin.Context) {
+	rsp, err := ctrl.serviceAccounts.RotateSecret(requestContext(ctx), ctx.Param("username"))
+	if err != nil {
+		if err == model.ErrServiceAccountNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// AddServiceAccountKey godoc
+// @Summary      Add a service account key
+// @Description  Register a PEM public key (RSA, ECDSA or Ed25519) the service account signs JWT assertions with.
+// @Description  Without public_key a key pair is generated for algorithm (default ES256) and the private key is only returned here.
+// @Description  Assertions name the key by the returned id in their kid header.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Param        serviceAccountKeyRequest body model.ServiceAccountKeyRequest true "Service Account Key Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.ServiceAccountKeyResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError
+// @Router       /admin/service-accounts/{username}/keys [post]
+func (ctrl *apiController) AddServiceAccountKey(ctx *gin.Context) {
+	var req model.ServiceAccountKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.serviceAccounts.AddKey(requestContext(ctx), ctx.Param("username"), req)
+	if err != nil {
+		if err == model.ErrInvalidServiceAccountKey {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		} else if err == model.ErrServiceAccountNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		} else if err == model.ErrServiceAccountKeyExists {
+			ctx.JSON(http.StatusConflict, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// RevokeServiceAccountKey godoc
+// @Summary      Revoke a service account key
+// @Description  Assertions signed by the key are rejected from now on.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        username  path  string  true  "Username"
+// @Param        id        path  string  true  "Key ID"
+// @Success      204
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/service-accounts/{username}/keys/{id} [delete]
+func (ctrl *apiController) RevokeServiceAccountKey(ctx *gin.Context) {
+	err := ctrl.serviceAccounts.RevokeKey(requestContext(ctx), ctx.Param("username"), ctx.Param("id"))
+	if err != nil {
+		if err == model.ErrServiceAccountNotFound || err == model.ErrServiceAccountKeyNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newServiceAccountTestRoute(t *testing.T) (*gin.Engine, *model.MockServiceAccountUsecase) {
+	ctrl := gomock.NewController(t)
+	mockServiceAccounts := model.NewMockServiceAccountUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), mockServiceAccounts,
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockServiceAccounts
+}
+
+func TestCreateServiceAccount(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+	req := model.ServiceAccountRequest{Username: "deploybot", Owner: "alice", Scopes: []string{"profile"}}
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusCreated},
+		{name: "invalid owner", err: model.ErrInvalidServiceAccountOwner, status: http.StatusBadRequest},
+		{name: "invalid username", err: model.ErrInvalidUsernameFormat, status: http.StatusBadRequest},
+		{name: "already existed", err: model.ErrAccountIsAlreadyExisted, status: http.StatusConflict},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockServiceAccounts := newServiceAccountTestRoute(t)
+			var rsp *model.ServiceAccountResponse
+			if tc.err == nil {
+				rsp = &model.ServiceAccountResponse{Username: req.Username, ClientID: "client", ClientSecret: "secret"}
+			}
+			mockServiceAccounts.EXPECT().CreateServiceAccount(gomock.Any(), req).Return(rsp, tc.err)
+
+			body, _ := json.Marshal(req)
+			httpReq, _ := http.NewRequest("POST", "/api/admin/service-accounts", bytes.NewReader(body))
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		route, mockServiceAccounts := newServiceAccountTestRoute(t)
+		mockServiceAccounts.EXPECT().CreateServiceAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		body, _ := json.Marshal(req)
+		httpReq, _ := http.NewRequest("POST", "/api/admin/service-accounts", bytes.NewReader(body))
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusUnauthorized, r.Code)
+	})
+}
+
+func TestAddServiceAccountKey(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusCreated},
+		{name: "invalid key", err: model.ErrInvalidServiceAccountKey, status: http.StatusBadRequest},
+		{name: "not found", err: model.ErrServiceAccountNotFound, status: http.StatusNotFound},
+		{name: "already registered", err: model.ErrServiceAccountKeyExists, status: http.StatusConflict},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockServiceAccounts := newServiceAccountTestRoute(t)
+			var rsp *model.ServiceAccountKeyResponse
+			if tc.err == nil {
+				rsp = &model.ServiceAccountKeyResponse{ID: "kid", Algorithm: model.KeyAlgorithmES256, PrivateKey: "pem"}
+			}
+			mockServiceAccounts.EXPECT().AddKey(gomock.Any(), "deploybot", model.ServiceAccountKeyRequest{}).Return(rsp, tc.err)
+
+			httpReq, _ := http.NewRequest("POST", "/api/admin/service-accounts/deploybot/keys", bytes.NewReader([]byte("{}")))
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
+
+func TestRevokeServiceAccountKey(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusNoContent},
+		{name: "key not found", err: model.ErrServiceAccountKeyNotFound, status: http.StatusNotFound},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockServiceAccounts := newServiceAccountTestRoute(t)
+			mockServiceAccounts.EXPECT().RevokeKey(gomock.Any(), "deploybot", "kid").Return(tc.err)
+
+			httpReq, _ := http.NewRequest("DELETE", "/api/admin/service-accounts/deploybot/keys/kid", nil)
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ListSigningKeys godoc
+// @Summary      List signing keys
+// @Description  List the keys of a purpose (token or audit) with their status: next, current, retired or revoked.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        purpose  query  string  true  "Key purpose"  Enums(token, audit)
+// @Produce      json
+// @Success      200  {array}   model.SigningKeyResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/keys [get]
+func (ctrl *apiController) ListSigningKeys(ctx *gin.Context) {
+	rsp, err := ctrl.signingKeys.ListKeys(requestContext(ctx), ctx.Query("purpose"))
+	if err != nil {
+		if err == model.ErrUnknownKeyPurpose {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// RotateSigningKeys godoc
+// @Summary      Rotate signing keys now
+// @Description  Retire the current key of a purpose, promote the next key and generate a new next key, without waiting for the schedule.
+// @Description  The retired key is still published for the overlap window.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        purpose  query  string  true  "Key purpose"  Enums(token, audit)
+// @Produce      json
+// @Success      200  {array}   model.SigningKeyResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/keys/rotate [post]
+func (ctrl *apiController) RotateSigningKeys(ctx *gin.Context) {
+	rsp, err := ctrl.signingKeys.RotateKeys(requestContext(ctx), ctx.Query("purpose"))
+	if err != nil {
+		if err == model.ErrUnknownKeyPurpose {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// RevokeSigningKey godoc
+// @Summary      Revoke a compromised signing key
+// @Description  Stop publishing the key at once, so nothing it signed verifies any more. A current or next key is replaced.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Key ID"
+// @Produce      json
+// @Success      200  {object}  model.SigningKeyResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/keys/{id}/revoke [post]
+func (ctrl *apiController) RevokeSigningKey(ctx *gin.Context) {
+	rsp, err := ctrl.signingKeys.RevokeKey(requestContext(ctx), ctx.Param("id"))
+	if err != nil {
+		if err == model.ErrSigningKeyNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
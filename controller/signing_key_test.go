@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigningKeyTestRoute(t *testing.T) (*gin.Engine, *model.MockSigningKeyUsecase) {
+	ctrl := gomock.NewController(t)
+	mockSigningKeys := model.NewMockSigningKeyUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), mockSigningKeys,
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+		model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockSigningKeys
+}
+
+func TestRotateSigningKeys(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "unknown purpose", err: model.ErrUnknownKeyPurpose, status: http.StatusBadRequest},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockSigningKeys := newSigningKeyTestRoute(t)
+			var rsp []model.SigningKeyResponse
+			if tc.err == nil {
+				rsp = []model.SigningKeyResponse{{ID: "kid", Purpose: model.KeyPurposeToken, Status: model.KeyStatusCurrent}}
+			}
+			mockSigningKeys.EXPECT().RotateKeys(gomock.Any(), model.KeyPurposeToken).Return(rsp, tc.err)
+
+			httpReq, _ := http.NewRequest("POST", "/api/admin/keys/rotate?purpose=token", nil)
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		route, mockSigningKeys := newSigningKeyTestRoute(t)
+		mockSigningKeys.EXPECT().RotateKeys(gomock.Any(), gomock.Any()).Times(0)
+
+		httpReq, _ := http.NewRequest("POST", "/api/admin/keys/rotate?purpose=token", nil)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusUnauthorized, r.Code)
+	})
+}
+
+func TestRevokeSigningKey(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	t.Run("ok", func(t *testing.T) {
+		route, mockSigningKeys := newSigningKeyTestRoute(t)
+		mockSigningKeys.EXPECT().RevokeKey(gomock.Any(), "kid").
+			Return(&model.SigningKeyResponse{ID: "kid", Status: model.KeyStatusRevoked}, nil)
+
+		httpReq, _ := http.NewRequest("POST", "/api/admin/keys/kid/revoke", nil)
+		httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusOK, r.Code)
+		rsp := &model.SigningKeyResponse{}
+		require.NoError(t, json.Unmarshal(r.Body.Bytes(), rsp))
+		require.Equal(t, model.KeyStatusRevoked, rsp.Status)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		route, mockSigningKeys := newSigningKeyTestRoute(t)
+		mockSigningKeys.EXPECT().RevokeKey(gomock.Any(), "kid").Return(nil, model.ErrSigningKeyNotFound)
+
+		httpReq, _ := http.NewRequest("POST", "/api/admin/keys/kid/revoke", nil)
+		httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+		r := httptest.NewRecorder()
+		route.ServeHTTP(r, httpReq)
+
+		require.Equal(t, http.StatusNotFound, r.Code)
+	})
+}
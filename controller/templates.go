@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+func renderHTML(ctx *gin.Context, status int, name string, data interface{}) {
+	ctx.Render(status, render.HTML{Template: templates, Name: name, Data: data})
+}
+
+// accountAssets are the built-in assets the account pages link to under /account/assets.
+func accountAssets() http.FileSystem {
+	assets, _ := fs.Sub(assetFS, "assets")
+	return layeredFS{http.FS(assets)}
+}
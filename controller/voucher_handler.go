@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// voucherPrintPage is a sheet of voucher cards to cut out and hand to guests.
+type voucherPrintPage struct {
+	Batch string
+	Cards []voucherCard
+}
+
+type voucherCard struct {
+	Code     string
+	Limits   string
+	RedeemBy string
+}
+
+// CreateVouchers godoc
+// @Summary      Create guest vouchers
+// @Description  Create a batch of voucher codes for guest network access, each limited in time, data or both from its first use.
+// @Description  Guests log in with the code as both username and password. The batch is returned as JSON, CSV or a printable HTML page.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        createVouchersRequest body model.CreateVouchersRequest true "Create Vouchers Request Struct"
+// @Param        format  query  string  false  "json, csv or html"
+// @Accept       json
+// @Produce      json
+// @Produce      text/csv
+// @Produce      html
+// @Success      201  {object}  model.VoucherBatchResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/vouchers [post]
+func (ctrl *apiController) CreateVouchers(ctx *gin.Context) {
+	var export model.VoucherExportRequest
+	if err := ctx.ShouldBindQuery(&export); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+	var req model.CreateVouchersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.vouchers.CreateVouchers(requestContext(ctx), req)
+	if err != nil {
+		if err == model.ErrInvalidVoucherLimits {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	writeVouchers(ctx, http.StatusCreated, export.Format, rsp)
+}
+
+// ListVouchers godoc
+// @Summary      List a batch of vouchers
+// @Description  List the vouchers of a batch with whether they were used, as JSON, CSV or a printable HTML page.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        batch   path   string  true   "Batch"
+// @Param        format  query  string  false  "json, csv or html"
+// @Produce      json
+// @Produce      text/csv
+// @Produce      html
+// @Success      200  {object}  model.VoucherBatchResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/vouchers/batches/{batch} [get]
+func (ctrl *apiController) ListVouchers(ctx *gin.Context) {
+	var export model.VoucherExportRequest
+	if err := ctx.ShouldBindQuery(&export); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.vouchers.ListVouchers(requestContext(ctx), ctx.Param("batch"))
+	if err != nil {
+		if err == model.ErrVoucherBatchNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	writeVouchers(ctx, http.StatusOK, export.Format, rsp)
+}
+
+func writeVouchers(ctx *gin.Context, status int, format string, rsp *model.VoucherBatchResponse) {
+	switch format {
+	case "csv":
+		ctx.Header("Content-Type", "text/csv")
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="vouchers_%s.csv"`, rsp.Batch))
+		ctx.Status(status)
+		w := csv.NewWriter(ctx.Writer)
+		w.Write([]string{"code", "status", "duration_minutes", "data_limit_mb", "redeem_by", "activated_at", "expires_at"})
+		for _, voucher := range rsp.Vouchers {
+			w.Write([]string{
+				voucher.Code,
+				voucher.Status,
+				strconv.FormatInt(voucher.DurationMinutes, 10),
+				strconv.FormatInt(voucher.DataLimitMB, 10),
+				csvTime(voucher.RedeemBy),
+				csvTime(voucher.ActivatedAt),
+				csvTime(voucher.ExpiresAt),
+			})
+		}
+		w.Flush()
+	case "html":
+		page := voucherPrintPage{Batch: rsp.Batch}
+		for _, voucher := range rsp.Vouchers {
+			card := voucherCard{Code: model.FormatVoucherCode(voucher.Code), Limits: voucherLimits(voucher)}
+			if voucher.RedeemBy != nil {
+				card.RedeemBy = voucher.RedeemBy.Format("2006-01-02")
+			}
+			page.Cards = append(page.Cards, card)
+		}
+		renderHTML(ctx, status, "voucher_print.html", page)
+	default:
+		ctx.JSON(status, rsp)
+	}
+}
+
+// voucherLimits describes what a voucher allows, for example "24 hours, 500 MB".
+func voucherLimits(voucher model.VoucherResponse) string {
+	var limits string
+	if voucher.DurationMinutes > 0 {
+		switch minutes := voucher.DurationMinutes; {
+		case minutes%(24*60) == 0:
+			limits = plural(minutes/(24*60), "day")
+		case minutes%60 == 0:
+			limits = plural(minutes/60, "hour")
+		default:
+			limits = plural(minutes, "minute")
+		}
+	}
+	if voucher.DataLimitMB > 0 {
+		if limits != "" {
+			limits += ", "
+		}
+		limits += fmt.Sprintf("%d MB", voucher.DataLimitMB)
+	}
+	return limits
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newVoucherTestRoute(t *testing.T) (*gin.Engine, *model.MockVoucherUsecase) {
+	ctrl := gomock.NewController(t)
+	mockVouchers := model.NewMockVoucherUsecase(ctrl)
+	controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), model.NewMockWebhookUsecase(ctrl),
+		model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+		model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl), model.NewMockServiceAccountUsecase(ctrl),
+		model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), mockVouchers, model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+	route := gin.Default()
+	controller.SetRoute(route)
+	return route, mockVouchers
+}
+
+func testVoucherBatch() *model.VoucherBatchResponse {
+	return &model.VoucherBatchResponse{Batch: "lobby", Vouchers: []model.VoucherResponse{
+		{Code: "ABCDE23456", Status: model.VoucherStatusUnused, DurationMinutes: 24 * 60, DataLimitMB: 500},
+	}}
+}
+
+func TestCreateVouchers(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+	req := model.CreateVouchersRequest{Count: 1, Batch: "lobby", DurationMinutes: 24 * 60, DataLimitMB: 500}
+
+	testCase := []struct {
+		name          string
+		query         string
+		req           model.CreateVouchersRequest
+		err           error
+		status        int
+		checkResponse func(t *testing.T, r *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "json",
+			req:    req,
+			status: http.StatusCreated,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				var rsp model.VoucherBatchResponse
+				require.NoError(t, json.Unmarshal(r.Body.Bytes(), &rsp))
+				require.Equal(t, "ABCDE23456", rsp.Vouchers[0].Code)
+			},
+		},
+		{
+			name:   "csv",
+			query:  "?format=csv",
+			req:    req,
+			status: http.StatusCreated,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Equal(t, "text/csv", r.Header().Get("Content-Type"))
+				require.Contains(t, r.Header().Get("Content-Disposition"), "vouchers_lobby.csv")
+				require.Contains(t, r.Body.String(), "code,status,duration_minutes")
+				require.Contains(t, r.Body.String(), "ABCDE23456,unused,1440,500,,,")
+			},
+		},
+		{
+			name:   "html",
+			query:  "?format=html",
+			req:    req,
+			status: http.StatusCreated,
+			checkResponse: func(t *testing.T, r *httptest.ResponseRecorder) {
+				require.Contains(t, r.Body.String(), "ABCDE-23456")
+				require.Contains(t, r.Body.String(), "1 day, 500 MB")
+			},
+		},
+		{name: "no limits", req: model.CreateVouchersRequest{Count: 1}, err: model.ErrInvalidVoucherLimits, status: http.StatusBadRequest},
+		{name: "invalid count", req: model.CreateVouchersRequest{DurationMinutes: 60}, status: http.StatusBadRequest},
+		{name: "invalid format", query: "?format=pdf", req: req, status: http.StatusBadRequest},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockVouchers := newVoucherTestRoute(t)
+			if tc.status == http.StatusCreated {
+				mockVouchers.EXPECT().CreateVouchers(gomock.Any(), tc.req).Return(testVoucherBatch(), nil)
+			} else if tc.err != nil {
+				mockVouchers.EXPECT().CreateVouchers(gomock.Any(), tc.req).Return(nil, tc.err)
+			}
+
+			body, _ := json.Marshal(tc.req)
+			httpReq, _ := http.NewRequest("POST", "/api/admin/vouchers"+tc.query, bytes.NewReader(body))
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+			if tc.checkResponse != nil {
+				tc.checkResponse(t, r)
+			}
+		})
+	}
+}
+
+func TestListVouchers(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "not found", err: model.ErrVoucherBatchNotFound, status: http.StatusNotFound},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			route, mockVouchers := newVoucherTestRoute(t)
+			var rsp *model.VoucherBatchResponse
+			if tc.err == nil {
+				rsp = testVoucherBatch()
+			}
+			mockVouchers.EXPECT().ListVouchers(gomock.Any(), "lobby").Return(rsp, tc.err)
+
+			httpReq, _ := http.NewRequest("GET", "/api/admin/vouchers/batches/lobby", nil)
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
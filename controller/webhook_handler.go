@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhook godoc
+// @Summary      Register a webhook endpoint
+// @Description  Subscribe an endpoint to account lifecycle events:
+// @Description  account.created, account.login_succeeded, account.login_failed, account.locked, account.deleted.
+// @Description  Every delivery is a JSON POST signed with HMAC-SHA256 over "<X-Webhook-Timestamp>.<body>" and sent as
+// @Description  X-Webhook-Signature: sha256=<hex>. A secret is generated when none is given and is only returned here.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        webhookRequest body model.WebhookEndpointRequest true "Webhook Endpoint Request Struct"
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  model.WebhookEndpointResponse
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/webhooks [post]
+func (ctrl *apiController) RegisterWebhook(ctx *gin.Context) {
+	var req model.WebhookEndpointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	rsp, err := ctrl.webhook.RegisterEndpoint(requestContext(ctx), req)
+	if err != nil {
+		if err == model.ErrInvalidWebhookEvent {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// ListWebhooks godoc
+// @Summary      List webhook endpoints
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   model.WebhookEndpointResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/webhooks [get]
+func (ctrl *apiController) ListWebhooks(ctx *gin.Context) {
+	rsp, err := ctrl.webhook.ListEndpoints(requestContext(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// DeleteWebhook godoc
+// @Summary      Delete a webhook endpoint
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Webhook endpoint ID"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Router       /admin/webhooks/{id} [delete]
+func (ctrl *apiController) DeleteWebhook(ctx *gin.Context) {
+	err := ctrl.webhook.DeleteEndpoint(requestContext(ctx), ctx.Param("id"))
+	if err != nil {
+		if err == model.ErrInvalidWebhookID {
+			ctx.JSON(http.StatusBadRequest, errResponse(err))
+		} else if err == model.ErrWebhookEndpointNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeadLetters godoc
+// @Summary      List webhook dead letters
+// @Description  Deliveries that still failed after every retry. Replayed ones are hidden unless all=true.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        all  query  bool  false  "Include dead letters that were already replayed"
+// @Produce      json
+// @Success      200  {array}   model.WebhookDeadLetterResponse
+// @Failure      401  {object}  model.DocResponseError
+// @Router       /admin/webhooks/dead-letters [get]
+func (ctrl *apiController) ListWebhookDeadLetters(ctx *gin.Context) {
+	includeReplayed, _ := strconv.ParseBool(ctx.Query("all"))
+	rsp, err := ctrl.webhook.ListDeadLetters(requestContext(ctx), includeReplayed)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ReplayWebhookDeadLetter godoc
+// @Summary      Replay a webhook dead letter
+// @Description  Deliver the dead letter once more. It is marked as replayed only when the endpoint accepts it.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id  path  int  true  "Dead letter ID"
+// @Success      204
+// @Failure      400  {object}  model.DocResponseError
+// @Failure      401  {object}  model.DocResponseError
+// @Failure      404  {object}  model.DocResponseError
+// @Failure      409  {object}  model.DocResponseError
+// @Failure      502  {object}  model.DocResponseError
+// @Router       /admin/webhooks/dead-letters/{id}/replay [post]
+func (ctrl *apiController) ReplayWebhookDeadLetter(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		return
+	}
+
+	err = ctrl.webhook.ReplayDeadLetter(requestContext(ctx), id)
+	if err != nil {
+		if err == model.ErrWebhookDeadLetterNotFound || err == model.ErrWebhookEndpointNotFound {
+			ctx.JSON(http.StatusNotFound, errResponse(err))
+		} else if err == model.ErrWebhookDeadLetterAlreadyReplayed {
+			ctx.JSON(http.StatusConflict, errResponse(err))
+		} else if errors.Is(err, model.ErrWebhookReplayFailed) {
+			ctx.JSON(http.StatusBadGateway, errResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
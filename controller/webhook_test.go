@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterWebhook(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name             string
+		body             gin.H
+		setMockExpection func(mockWebhook *model.MockWebhookUsecase)
+		checkResponse    func(*httptest.ResponseRecorder)
+	}{
+		{
+			name: "ok",
+			body: gin.H{
+				"url":    "https://crm.example.com/hooks",
+				"events": []string{model.EventAccountCreated},
+			},
+			setMockExpection: func(mockWebhook *model.MockWebhookUsecase) {
+				mockWebhook.EXPECT().RegisterEndpoint(gomock.Any(), gomock.Any()).
+					Return(&model.WebhookEndpointResponse{
+						ID:     "9a4a4f5e-3a36-4f2c-8b8e-5d0f0c5f3c1d",
+						URL:    "https://crm.example.com/hooks",
+						Events: []string{model.EventAccountCreated},
+						Secret: util.RandomString(64),
+					}, nil)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, rr.Code)
+				rsp := &model.WebhookEndpointResponse{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), rsp))
+				require.NotEmpty(t, rsp.Secret)
+			},
+		},
+		{
+			name: "invalid url",
+			body: gin.H{
+				"url":    "crm",
+				"events": []string{model.EventAccountCreated},
+			},
+			setMockExpection: func(mockWebhook *model.MockWebhookUsecase) {
+				mockWebhook.EXPECT().RegisterEndpoint(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "unknown event",
+			body: gin.H{
+				"url":    "https://crm.example.com/hooks",
+				"events": []string{"account.renamed"},
+			},
+			setMockExpection: func(mockWebhook *model.MockWebhookUsecase) {
+				mockWebhook.EXPECT().RegisterEndpoint(gomock.Any(), gomock.Any()).
+					Return(nil, model.ErrInvalidWebhookEvent)
+			},
+			checkResponse: func(rr *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockWebhook := model.NewMockWebhookUsecase(ctrl)
+			controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), mockWebhook, model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+				model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+				model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+			route := gin.Default()
+			controller.SetRoute(route)
+
+			tc.setMockExpection(mockWebhook)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			httpReq, _ := http.NewRequest("POST", "/api/admin/webhooks", bytes.NewReader(data))
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			tc.checkResponse(r)
+		})
+	}
+}
+
+func TestReplayWebhookDeadLetter(t *testing.T) {
+	adminAPIKey := util.RandomString(32)
+	t.Setenv("ADMIN_API_KEY", adminAPIKey)
+
+	testCase := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ok", err: nil, status: http.StatusNoContent},
+		{name: "not found", err: model.ErrWebhookDeadLetterNotFound, status: http.StatusNotFound},
+		{name: "already replayed", err: model.ErrWebhookDeadLetterAlreadyReplayed, status: http.StatusConflict},
+		{name: "endpoint still failing", err: fmt.Errorf("%w: unexpected response status 500", model.ErrWebhookReplayFailed), status: http.StatusBadGateway},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockWebhook := model.NewMockWebhookUsecase(ctrl)
+			controller := NewController(model.NewMockUsecaseHandler(ctrl), model.NewMockAuditUsecase(ctrl), mockWebhook, model.NewMockOAuthUsecase(ctrl), model.NewMockOIDCUsecase(ctrl), model.NewMockSigningKeyUsecase(ctrl),
+				model.NewMockPersonalAccessTokenUsecase(ctrl), model.NewMockAuthenticator(ctrl),
+				model.NewMockServiceAccountUsecase(ctrl), model.NewMockFederationUsecase(ctrl), model.NewMockIdentityUsecase(ctrl), model.NewMockAccountingUsecase(ctrl), model.NewMockVoucherUsecase(ctrl), model.NewMockDeviceUsecase(ctrl), model.NewMockForwardAuthUsecase(ctrl), model.NewMockBrowserSessionUsecase(ctrl), nil)
+			route := gin.Default()
+			controller.SetRoute(route)
+
+			mockWebhook.EXPECT().ReplayDeadLetter(gomock.Any(), uint64(42)).Return(tc.err)
+
+			httpReq, _ := http.NewRequest("POST", "/api/admin/webhooks/dead-letters/42/replay", nil)
+			httpReq.Header.Set("Authorization", "Bearer "+adminAPIKey)
+			r := httptest.NewRecorder()
+			route.ServeHTTP(r, httpReq)
+
+			require.Equal(t, tc.status, r.Code)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+)
+
+// loadLDAPDirectory sets up the LDAP directory in LDAP_URL, or returns nil when it is not set. Users are
+// searched for under LDAP_BASE_DN as LDAP_BIND_DN. LDAP_GROUP_ROLES maps groups to roles as
+// role=group DN pairs separated by semicolons.
+func loadLDAPDirectory() (model.Directory, error) {
+	if os.Getenv("LDAP_URL") == "" {
+		return nil, nil
+	}
+	startTLS, _ := strconv.ParseBool(os.Getenv("LDAP_START_TLS"))
+	poolSize, _ := strconv.Atoi(os.Getenv("LDAP_POOL_SIZE"))
+	timeout, _ := time.ParseDuration(os.Getenv("LDAP_TIMEOUT"))
+	config := model.LDAPConfig{
+		URL:               os.Getenv("LDAP_URL"),
+		StartTLS:          startTLS,
+		BindDN:            os.Getenv("LDAP_BIND_DN"),
+		BindPassword:      os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:            os.Getenv("LDAP_BASE_DN"),
+		UserFilter:        os.Getenv("LDAP_USER_FILTER"),
+		UsernameAttribute: os.Getenv("LDAP_USERNAME_ATTRIBUTE"),
+		EmailAttribute:    os.Getenv("LDAP_EMAIL_ATTRIBUTE"),
+		GroupAttribute:    os.Getenv("LDAP_GROUP_ATTRIBUTE"),
+		GroupBaseDN:       os.Getenv("LDAP_GROUP_BASE_DN"),
+		GroupFilter:       os.Getenv("LDAP_GROUP_FILTER"),
+		GroupRoles:        map[string]string{},
+		PoolSize:          poolSize,
+		Timeout:           timeout,
+	}
+	for _, pair := range strings.Split(os.Getenv("LDAP_GROUP_ROLES"), ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		role, group, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(role) == "" {
+			return nil, fmt.Errorf("LDAP_GROUP_ROLES: %q is not role=group DN", pair)
+		}
+		config.GroupRoles[strings.TrimSpace(group)] = strings.TrimSpace(role)
+	}
+	if path := os.Getenv("LDAP_CA_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("LDAP_CA_FILE: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, errors.New("LDAP_CA_FILE holds no PEM certificate")
+		}
+		config.TLS = &tls.Config{RootCAs: roots}
+	}
+	return model.NewLDAPDirectory(config)
+}
+
+// ldapSyncInterval is how often directory users are mirrored into accounts. They are not when
+// LDAP_SYNC_INTERVAL is not set, accounts are then created on the first login.
+func ldapSyncInterval() time.Duration {
+	return envDuration("LDAP_SYNC_INTERVAL", 0)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+)
+
+// loadOIDCProviders reads the identity providers named in OIDC_PROVIDERS. Each name has its settings
+// in OIDC_<NAME>_DISCOVERY_URL, _CLIENT_ID, _CLIENT_SECRET and optionally _DISPLAY_NAME, _SCOPES
+// (space separated), _USERNAME_CLAIM, _EMAIL_CLAIM and _LINK_BY_EMAIL.
+func loadOIDCProviders() ([]model.OIDCProviderConfig, error) {
+	var providers []model.OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		linkByEmail, _ := strconv.ParseBool(os.Getenv(prefix + "LINK_BY_EMAIL"))
+		config := model.OIDCProviderConfig{
+			Name:          name,
+			DisplayName:   os.Getenv(prefix + "DISPLAY_NAME"),
+			DiscoveryURL:  os.Getenv(prefix + "DISCOVERY_URL"),
+			ClientID:      os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:        strings.Fields(os.Getenv(prefix + "SCOPES")),
+			UsernameClaim: os.Getenv(prefix + "USERNAME_CLAIM"),
+			EmailClaim:    os.Getenv(prefix + "EMAIL_CLAIM"),
+			LinkByEmail:   linkByEmail,
+		}
+		if config.DiscoveryURL == "" || config.ClientID == "" {
+			return nil, fmt.Errorf("%sDISCOVERY_URL and %sCLIENT_ID are required", prefix, prefix)
+		}
+		providers = append(providers, config)
+	}
+	return providers, nil
+}
+
+// webAuthnConfig describes the relying party passkeys are registered with. It defaults to the host and
+// origin of the issuer, WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and WEBAUTHN_ORIGIN override it.
+func webAuthnConfig() (model.WebAuthnConfig, error) {
+	issuer, err := url.Parse(oauthIssuer())
+	if err != nil {
+		return model.WebAuthnConfig{}, fmt.Errorf("OAUTH_ISSUER: %w", err)
+	}
+	return model.WebAuthnConfig{
+		RPID:   envOrDefault("WEBAUTHN_RP_ID", issuer.Hostname()),
+		RPName: envOrDefault("WEBAUTHN_RP_NAME", issuer.Hostname()),
+		Origin: envOrDefault("WEBAUTHN_ORIGIN", issuer.Scheme+"://"+issuer.Host),
+	}, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+)
+
+// loadForwardAuthConfig reads the hosts reverse proxies check with /api/auth/verify. FORWARD_AUTH_RULES
+// limits hosts to roles as host=role,role pairs separated by semicolons, in order, where the host may be
+// *.example.com and the role * lets any account through. FORWARD_AUTH_DENY_UNLISTED refuses other hosts,
+// and browsers are sent to sign in at FORWARD_AUTH_LOGIN_URL.
+func loadForwardAuthConfig() (model.ForwardAuthConfig, error) {
+	config := model.ForwardAuthConfig{LoginURL: os.Getenv("FORWARD_AUTH_LOGIN_URL")}
+	for _, pair := range strings.Split(os.Getenv("FORWARD_AUTH_RULES"), ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		host, roles, ok := strings.Cut(pair, "=")
+		rule := model.ForwardAuthRule{Host: strings.TrimSpace(host)}
+		for _, role := range strings.Split(roles, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				rule.Roles = append(rule.Roles, role)
+			}
+		}
+		if !ok || rule.Host == "" || len(rule.Roles) == 0 {
+			return config, fmt.Errorf("FORWARD_AUTH_RULES: %q is not host=role,role", pair)
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+	config.DenyUnlisted, _ = strconv.ParseBool(os.Getenv("FORWARD_AUTH_DENY_UNLISTED"))
+	if config.LoginURL != "" {
+		if login, err := url.Parse(config.LoginURL); err != nil || login.Host == "" {
+			return config, fmt.Errorf("FORWARD_AUTH_LOGIN_URL %q is not an absolute URL", config.LoginURL)
+		}
+	}
+	return config, nil
+}
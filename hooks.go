@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+)
+
+var hookURLEnv = map[string]string{
+	model.HookPreCreate:  "HOOK_PRE_CREATE_URL",
+	model.HookPostCreate: "HOOK_POST_CREATE_URL",
+	model.HookPreLogin:   "HOOK_PRE_LOGIN_URL",
+	model.HookPostLogin:  "HOOK_POST_LOGIN_URL",
+}
+
+// loadHooks registers the reserved username list from RESERVED_USERNAMES and an HTTP callout for
+// every stage whose HOOK_<STAGE>_URL is set.
+func loadHooks() (model.Hooks, error) {
+	hooks := model.Hooks{}
+
+	if reserved := os.Getenv("RESERVED_USERNAMES"); reserved != "" {
+		if err := hooks.Register(model.HookPreCreate, model.NewReservedUsernameHook(strings.Split(reserved, ",")...)); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout, _ := time.ParseDuration(os.Getenv("HOOK_TIMEOUT"))
+	failOpen, _ := strconv.ParseBool(os.Getenv("HOOK_FAIL_OPEN"))
+	for _, stage := range []string{model.HookPreCreate, model.HookPostCreate, model.HookPreLogin, model.HookPostLogin} {
+		url := os.Getenv(hookURLEnv[stage])
+		if url == "" {
+			continue
+		}
+		if err := hooks.Register(stage, model.NewHTTPHook(url, os.Getenv("HOOK_SECRET"), timeout, failOpen)); err != nil {
+			return nil, err
+		}
+	}
+	return hooks, nil
+}
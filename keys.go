@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultKeyRotationPeriod  = 30 * 24 * time.Hour
+	defaultKeyRotationOverlap = 24 * time.Hour
+	defaultKeyRefreshInterval = time.Minute
+)
+
+// loadMasterKey reads the key in KEY_MASTER_KEY_FILE, which encrypts the secrets the service stores.
+func loadMasterKey() ([]byte, error) {
+	path := os.Getenv("KEY_MASTER_KEY_FILE")
+	if path == "" {
+		return nil, errors.New("KEY_MASTER_KEY_FILE is not set")
+	}
+	masterKey, err := util.LoadMasterKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("KEY_MASTER_KEY_FILE: %w", err)
+	}
+	return masterKey, nil
+}
+
+// loadKeyManager sets up the signing keys of the service. They are encrypted under the master key.
+// OAUTH_SIGNING_KEY and AUDIT_SIGNING_KEY, which used to configure the keys, are imported as the
+// current keys when the database has none yet.
+func loadKeyManager(gormDB *gorm.DB, masterKey []byte) (model.KeyManager, error) {
+	config := model.KeyManagerConfig{
+		Algorithms: map[string]string{
+			model.KeyPurposeToken: envOrDefault("TOKEN_SIGNING_ALGORITHM", model.KeyAlgorithmRS256),
+			model.KeyPurposeAudit: envOrDefault("AUDIT_SIGNING_ALGORITHM", model.KeyAlgorithmEdDSA),
+		},
+		RotationPeriod: envDuration("KEY_ROTATION_PERIOD", defaultKeyRotationPeriod),
+		Overlap:        envDuration("KEY_ROTATION_OVERLAP", defaultKeyRotationOverlap),
+		Seeds:          map[string]crypto.Signer{},
+	}
+	for purpose, algorithm := range config.Algorithms {
+		if _, err := model.GenerateSigningKey(algorithm); err != nil {
+			return nil, fmt.Errorf("%s signing algorithm %q: %w", purpose, algorithm, err)
+		}
+	}
+
+	if encoded := os.Getenv("OAUTH_SIGNING_KEY"); encoded != "" {
+		keys, err := util.ParsePrivateKeys(encoded)
+		if err != nil || len(keys) != 1 {
+			return nil, fmt.Errorf("OAUTH_SIGNING_KEY must hold one valid key: %v", err)
+		}
+		config.Seeds[model.KeyPurposeToken] = keys[0]
+	}
+	if encoded := os.Getenv("AUDIT_SIGNING_KEY"); encoded != "" {
+		key, err := util.DecodeEd25519PrivateKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("AUDIT_SIGNING_KEY: %w", err)
+		}
+		config.Seeds[model.KeyPurposeAudit] = key
+	}
+
+	return model.NewKeyManager(repository.NewSigningKeyRepository(gormDB), masterKey, config), nil
+}
+
+func keyRefreshInterval() time.Duration {
+	return envDuration("KEY_REFRESH_INTERVAL", defaultKeyRefreshInterval)
+}
+
+func envOrDefault(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
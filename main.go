@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -8,7 +9,9 @@ import (
 	"github.com/ambroseqiu/senao_hw/docs"
 	"github.com/ambroseqiu/senao_hw/migrations"
 	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/radius"
 	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/tacacs"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -20,6 +23,9 @@ import (
 // @contact.email  support@swagger.io
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
 func main() {
 
 	docs.SwaggerInfo.Title = "Swagger Example API"
@@ -40,11 +46,132 @@ func main() {
 		return
 	}
 
+	masterKey, err := loadMasterKey()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error loading master key")
+	}
+	keys, err := loadKeyManager(gormDB, masterKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up signing keys")
+	}
+	auditRepo := repository.NewAuditRepository(gormDB)
+	audit := model.NewAuditUsecase(auditRepo, keys.KeySet(model.KeyPurposeAudit))
+
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		os.Exit(verifyAudit(audit, keys))
+	}
+
 	migrations.RunMigration(gormDB)
 
+	if err := keys.Start(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("Error loading signing keys")
+	}
+	go keys.Run(context.Background(), keyRefreshInterval())
+	go audit.RunCheckpoints(context.Background(), auditCheckpointInterval())
+
+	webhookRepo := repository.NewWebhookRepository(gormDB)
+	webhook := model.NewWebhookUsecase(webhookRepo, audit)
+
+	outboxRepo := repository.NewOutboxRepository(gormDB)
+	sinks, err := outboxSinks(webhook)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up outbox sinks")
+	}
+	relay := model.NewOutboxRelay(outboxRepo, sinks...)
+	go relay.Run(context.Background(), outboxPollInterval())
+
+	hooks, err := loadHooks()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up hooks")
+	}
 	repo := repository.NewAccountRepository(gormDB)
-	usecase := model.NewUsecaseHandler(repo)
-	controller := controller.NewController(usecase)
+	identityRepo := repository.NewIdentityRepository(gormDB)
+	directory, err := loadLDAPDirectory()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up LDAP directory")
+	}
+	if interval := ldapSyncInterval(); directory != nil && interval > 0 {
+		go model.NewDirectorySync(directory, repo, audit).Run(context.Background(), interval)
+	}
+	radiusConfig, err := loadRADIUSConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up RADIUS")
+	}
+	var sessions model.SessionController
+	if radiusConfig != nil {
+		sessions = radius.NewDynamicAuthorization(*radiusConfig)
+	}
+	accountingRepo := repository.NewAccountingRepository(gormDB)
+	accounting := model.NewAccountingUsecase(accountingRepo, repo, sessions, audit)
+	vouchers := model.NewVoucherUsecase(repository.NewVoucherRepository(gormDB), accountingRepo, accounting, audit)
+	devices := model.NewDeviceUsecase(repository.NewDeviceRepository(gormDB), repo, audit)
+	usecase := model.NewUsecaseHandler(repo, audit, model.NewOutboxPublisher(outboxRepo), hooks,
+		model.NewTOTPVerifier(identityRepo, masterKey), directory, vouchers)
+
+	if radiusConfig != nil {
+		go vouchers.Run(context.Background(), voucherEnforceInterval())
+		server := radius.NewServer(*radiusConfig, usecase, accounting, devices, audit)
+		for _, address := range []string{radiusAddress(), radiusAccountingAddress()} {
+			go func(address string) {
+				if err := server.ListenAndServe(address); err != nil {
+					log.Fatal().Err(err).Str("address", address).Msg("RADIUS server stopped")
+				}
+			}(address)
+		}
+	}
+
+	tacacsConfig, err := loadTACACSConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up TACACS+")
+	}
+	if tacacsConfig != nil {
+		go func() {
+			if err := tacacs.NewServer(*tacacsConfig, usecase, repo, audit).ListenAndServe(tacacsAddress()); err != nil {
+				log.Fatal().Err(err).Msg("TACACS+ server stopped")
+			}
+		}()
+	}
+
+	tokenKeys := keys.KeySet(model.KeyPurposeToken)
+	tokenSigner := model.NewTokenSigner(tokenKeys)
+	oauthRepo := repository.NewOAuthRepository(gormDB)
+	revocations := model.NewRevocationList(oauthRepo)
+	if err := revocations.Sync(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("Error loading revoked tokens")
+	}
+	go revocations.Run(context.Background(), revocationSyncInterval())
+	oauth := model.NewOAuthUsecase(oauthRepo, repo, usecase, audit, tokenSigner, revocations, oauthIssuer())
+	oidc := model.NewOIDCUsecase(repo, tokenSigner, tokenKeys, revocations, oauthIssuer())
+	signingKeys := model.NewSigningKeyUsecase(keys, audit)
+	tokens := model.NewPersonalAccessTokenUsecase(repository.NewPersonalAccessTokenRepository(gormDB), repo, audit)
+	auth := model.NewAuthenticator(tokenSigner, revocations, oauthIssuer(), tokens)
+	serviceAccounts := model.NewServiceAccountUsecase(repository.NewServiceAccountRepository(gormDB), oauthRepo, repo, audit)
+	providers, err := loadOIDCProviders()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up identity providers")
+	}
+	federation := model.NewFederationUsecase(providers, identityRepo, repo, audit,
+		model.NewOutboxPublisher(outboxRepo), tokenSigner, oauthIssuer())
+	webauthn, err := webAuthnConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up passkeys")
+	}
+	identities := model.NewIdentityUsecase(identityRepo, repo, usecase, federation, audit,
+		model.NewOutboxPublisher(outboxRepo), tokenSigner, masterKey, webauthn, oauthIssuer())
+
+	forwardAuthConfig, err := loadForwardAuthConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up forward auth")
+	}
+	forwardAuth := model.NewForwardAuthUsecase(forwardAuthConfig, repo)
+	browserSessions := model.NewBrowserSessionUsecase(browserSessionConfig(), repository.NewBrowserSessionRepository(gormDB), repo, audit)
+
+	portal, err := controller.LoadPortalTheme(os.Getenv("PORTAL_THEME_DIR"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error loading captive portal theme")
+	}
+	controller := controller.NewController(usecase, audit, webhook, oauth, oidc, signingKeys, tokens, auth, serviceAccounts,
+		federation, identities, accounting, vouchers, devices, forwardAuth, browserSessions, portal)
 	route := gin.Default()
 	controller.SetRoute(route)
 	httpHost := fmt.Sprintf("%s:%s", os.Getenv("API_HOST"), os.Getenv("HTTP_PORT"))
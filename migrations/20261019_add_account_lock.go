@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccountWithLock struct {
+	ID                    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	LockedAt              *time.Time
+	PasswordResetRequired bool
+}
+
+func (AccountWithLock) TableName() string {
+	return "accounts"
+}
+
+func AddAccountLock() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190020",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"LockedAt", "PasswordResetRequired"} {
+				if err := tx.Migrator().AddColumn(&AccountWithLock{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, column := range []string{"PasswordResetRequired", "LockedAt"} {
+				if err := tx.Migrator().DropColumn(&AccountWithLock{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
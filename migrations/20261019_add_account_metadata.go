@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccountWithMetadata struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Username       string    `gorm:"uniqueIndex"`
+	HashedPassword string
+	Metadata       string
+	gorm.Model
+}
+
+func (AccountWithMetadata) TableName() string {
+	return "accounts"
+}
+
+func AddAccountMetadata() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190005",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&AccountWithMetadata{}, "Metadata")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&AccountWithMetadata{}, "Metadata")
+		},
+	}
+}
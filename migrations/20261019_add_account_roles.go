@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccountWithRoles struct {
+	ID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Roles  string
+	Source string `gorm:"index"`
+}
+
+func (AccountWithRoles) TableName() string {
+	return "accounts"
+}
+
+func AddAccountRoles() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190015",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"Roles", "Source"} {
+				if err := tx.Migrator().AddColumn(&AccountWithRoles{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&AccountWithRoles{}, "Source")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, column := range []string{"Source", "Roles"} {
+				if err := tx.Migrator().DropColumn(&AccountWithRoles{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccountingSession struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	AccountID        *uuid.UUID `gorm:"type:uuid;index"`
+	Username         string
+	Client           string `gorm:"uniqueIndex:idx_accounting_sessions_client_session"`
+	SessionID        string `gorm:"uniqueIndex:idx_accounting_sessions_client_session"`
+	NASAddress       string
+	NASIdentifier    string
+	CallingStationID string
+	CalledStationID  string
+	FramedIPAddress  string
+	StartedAt        time.Time
+	UpdatedAt        time.Time
+	StoppedAt        *time.Time `gorm:"index"`
+	SessionTime      int64
+	InputOctets      int64
+	OutputOctets     int64
+	TerminateCause   string
+}
+
+func CreateAccountingSessionTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190016",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&AccountingSession{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&AccountingSession{})
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type ChainedAuditEvent struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"index"`
+	Type      string    `gorm:"index"`
+	Actor     string    `gorm:"index"`
+	Target    string    `gorm:"index"`
+	IP        string
+	UserAgent string
+	RequestID string
+	Outcome   string
+	Detail    string
+	PrevHash  string
+	Hash      string `gorm:"index"`
+}
+
+func (ChainedAuditEvent) TableName() string {
+	return "audit_events"
+}
+
+type AuditCheckpoint struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time
+	EventID   uint64 `gorm:"index"`
+	EventHash string
+	KeyID     string
+	Signature string
+}
+
+func AddAuditChain() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190002",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(ChainedAuditEvent{}, AuditCheckpoint{}); err != nil {
+				return err
+			}
+			return chainExistingAuditEvents(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(AuditCheckpoint{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(ChainedAuditEvent{}, "PrevHash"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(ChainedAuditEvent{}, "Hash")
+		},
+	}
+}
+
+// chainExistingAuditEvents hashes the events recorded before the chain existed, so verification starts from the first one.
+func chainExistingAuditEvents(tx *gorm.DB) error {
+	var events []repository.AuditEvent
+	if err := tx.Order("id").Find(&events).Error; err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := tx.Exec("ALTER TABLE audit_events DISABLE TRIGGER audit_events_append_only").Error; err != nil {
+		return err
+	}
+	prevHash := ""
+	for i := range events {
+		events[i].PrevHash = prevHash
+		events[i].Hash = repository.AuditEventHash(&events[i])
+		if err := tx.Exec("UPDATE audit_events SET prev_hash = ?, hash = ? WHERE id = ?",
+			events[i].PrevHash, events[i].Hash, events[i].ID).Error; err != nil {
+			return err
+		}
+		prevHash = events[i].Hash
+	}
+	return tx.Exec("ALTER TABLE audit_events ENABLE TRIGGER audit_events_append_only").Error
+}
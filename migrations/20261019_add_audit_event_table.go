@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type AuditEvent struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"index"`
+	Type      string    `gorm:"index"`
+	Actor     string    `gorm:"index"`
+	Target    string    `gorm:"index"`
+	IP        string
+	UserAgent string
+	RequestID string
+	Outcome   string
+	Detail    string
+}
+
+// audit_events is append-only, so reject any UPDATE or DELETE at the database level.
+const auditEventAppendOnlyTrigger = `
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+	BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+`
+
+func CreateAuditEventTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190001",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(AuditEvent{}); err != nil {
+				return err
+			}
+			return tx.Exec(auditEventAppendOnlyTrigger).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Exec("DROP FUNCTION IF EXISTS audit_events_append_only() CASCADE").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(AuditEvent{})
+		},
+	}
+}
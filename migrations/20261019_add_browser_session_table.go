@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BrowserSession struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AccountID    uuid.UUID `gorm:"type:uuid;index"`
+	TokenHash    string    `gorm:"uniqueIndex"`
+	CSRFToken    string
+	IP           string
+	UserAgent    string
+	CreatedAt    time.Time
+	LastSeenAt   time.Time
+	ExpiresAt    time.Time `gorm:"index"`
+	MaxExpiresAt time.Time
+}
+
+func CreateBrowserSessionTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190019",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&BrowserSession{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&BrowserSession{})
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Device struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	MAC         string     `gorm:"uniqueIndex"`
+	OwnerID     *uuid.UUID `gorm:"type:uuid;index"`
+	Description string
+	VLAN        int
+	Role        string
+	ExpiresAt   *time.Time
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+func CreateDeviceTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190018",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&Device{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&Device{})
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IdentityWithMethods struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Type       string    `gorm:"not null;default:oidc"`
+	Email      string    `gorm:"index"`
+	Name       string
+	Credential []byte
+	Counter    int64
+}
+
+func (IdentityWithMethods) TableName() string {
+	return "identities"
+}
+
+func AddIdentityMethods() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190014",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"Type", "Name", "Credential", "Counter"} {
+				if err := tx.Migrator().AddColumn(&IdentityWithMethods{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&IdentityWithMethods{}, "Email")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&IdentityWithMethods{}, "Email"); err != nil {
+				return err
+			}
+			for _, column := range []string{"Counter", "Credential", "Name", "Type"} {
+				if err := tx.Migrator().DropColumn(&IdentityWithMethods{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
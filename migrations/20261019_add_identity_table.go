@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Identity struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CreatedAt   time.Time
+	AccountID   uuid.UUID `gorm:"type:uuid;index"`
+	Provider    string    `gorm:"uniqueIndex:idx_identities_provider_subject"`
+	Subject     string    `gorm:"uniqueIndex:idx_identities_provider_subject"`
+	Email       string
+	LastLoginAt *time.Time
+}
+
+func CreateIdentityTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190013",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&Identity{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&Identity{})
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OAuthDeviceCode struct {
+	DeviceCodeHash string `gorm:"primaryKey"`
+	UserCode       string `gorm:"uniqueIndex"`
+	CreatedAt      time.Time
+	ClientID       string
+	Scope          string
+	Status         string
+	AccountID      *uuid.UUID `gorm:"type:uuid"`
+	Username       string
+	AuthTime       *time.Time
+	PollInterval   int
+	LastPolledAt   *time.Time
+	ExpiresAt      time.Time `gorm:"index"`
+	UsedAt         *time.Time
+}
+
+func (OAuthDeviceCode) TableName() string {
+	return "oauth_device_codes"
+}
+
+func CreateOAuthDeviceCodeTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190012",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&OAuthDeviceCode{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&OAuthDeviceCode{})
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type OAuthRevokedToken struct {
+	JTI       string `gorm:"primaryKey"`
+	ClientID  string
+	RevokedAt time.Time `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (OAuthRevokedToken) TableName() string {
+	return "oauth_revoked_tokens"
+}
+
+func CreateOAuthRevokedTokenTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190009",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&OAuthRevokedToken{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&OAuthRevokedToken{})
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OAuthClient struct {
+	ID           string `gorm:"primaryKey"`
+	SecretHash   string
+	Name         string
+	RedirectURIs string
+	GrantTypes   string
+	Scopes       string
+	SkipConsent  bool
+	gorm.Model
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+type OAuthAuthorizationCode struct {
+	CodeHash            string `gorm:"primaryKey"`
+	CreatedAt           time.Time
+	ClientID            string
+	AccountID           uuid.UUID `gorm:"type:uuid"`
+	Username            string
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+type OAuthRefreshToken struct {
+	TokenHash string `gorm:"primaryKey"`
+	CreatedAt time.Time
+	ClientID  string    `gorm:"index"`
+	AccountID uuid.UUID `gorm:"type:uuid;index"`
+	Username  string
+	Scope     string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+func (OAuthRefreshToken) TableName() string {
+	return "oauth_refresh_tokens"
+}
+
+func CreateOAuthTables() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190006",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(OAuthClient{}, OAuthAuthorizationCode{}, OAuthRefreshToken{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(OAuthRefreshToken{}, OAuthAuthorizationCode{}, OAuthClient{})
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type OAuthAuthorizationCodeWithOIDC struct {
+	CodeHash string `gorm:"primaryKey"`
+	Nonce    string
+	AuthTime time.Time
+}
+
+func (OAuthAuthorizationCodeWithOIDC) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+type OAuthRefreshTokenWithOIDC struct {
+	TokenHash string `gorm:"primaryKey"`
+	AuthTime  time.Time
+}
+
+func (OAuthRefreshTokenWithOIDC) TableName() string {
+	return "oauth_refresh_tokens"
+}
+
+func AddOIDCColumns() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190007",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&OAuthAuthorizationCodeWithOIDC{}, "Nonce"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().AddColumn(&OAuthAuthorizationCodeWithOIDC{}, "AuthTime"); err != nil {
+				return err
+			}
+			return tx.Migrator().AddColumn(&OAuthRefreshTokenWithOIDC{}, "AuthTime")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&OAuthRefreshTokenWithOIDC{}, "AuthTime"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&OAuthAuthorizationCodeWithOIDC{}, "AuthTime"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&OAuthAuthorizationCodeWithOIDC{}, "Nonce")
+		},
+	}
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type OutboxMessage struct {
+	ID             uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt      time.Time
+	IdempotencyKey string `gorm:"uniqueIndex"`
+	Type           string
+	Payload        string
+	DeliveredSinks string
+	Attempts       int
+	LastError      string
+	NextAttemptAt  time.Time  `gorm:"index"`
+	PublishedAt    *time.Time `gorm:"index"`
+	FailedAt       *time.Time
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+func CreateOutboxTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190004",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(OutboxMessage{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(OutboxMessage{})
+		},
+	}
+}
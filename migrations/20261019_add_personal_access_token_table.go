@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CreatedAt  time.Time
+	AccountID  uuid.UUID `gorm:"type:uuid;index"`
+	Name       string
+	TokenHash  string `gorm:"uniqueIndex"`
+	Scopes     string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func CreatePersonalAccessTokenTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190010",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&PersonalAccessToken{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&PersonalAccessToken{})
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccountWithServiceAccount struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	Type        string     `gorm:"not null;default:user"`
+	OwnerID     *uuid.UUID `gorm:"type:uuid"`
+	Description string
+}
+
+func (AccountWithServiceAccount) TableName() string {
+	return "accounts"
+}
+
+type OAuthClientWithServiceAccount struct {
+	ID        string     `gorm:"primaryKey"`
+	AccountID *uuid.UUID `gorm:"type:uuid;index"`
+}
+
+func (OAuthClientWithServiceAccount) TableName() string {
+	return "oauth_clients"
+}
+
+type OAuthClientKey struct {
+	ID        string `gorm:"primaryKey"`
+	ClientID  string `gorm:"index"`
+	Algorithm string
+	PublicKey []byte
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type OAuthUsedAssertion struct {
+	ClientID  string    `gorm:"primaryKey"`
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func AddServiceAccounts() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190011",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"Type", "OwnerID", "Description"} {
+				if err := tx.Migrator().AddColumn(&AccountWithServiceAccount{}, column); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().AddColumn(&OAuthClientWithServiceAccount{}, "AccountID"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(&OAuthClientWithServiceAccount{}, "AccountID"); err != nil {
+				return err
+			}
+			return tx.AutoMigrate(&OAuthClientKey{}, &OAuthUsedAssertion{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&OAuthUsedAssertion{}, &OAuthClientKey{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&OAuthClientWithServiceAccount{}, "AccountID"); err != nil {
+				return err
+			}
+			for _, column := range []string{"Description", "OwnerID", "Type"} {
+				if err := tx.Migrator().DropColumn(&AccountWithServiceAccount{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
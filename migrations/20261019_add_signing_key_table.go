@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type SigningKey struct {
+	ID                  string `gorm:"primaryKey"`
+	CreatedAt           time.Time
+	Purpose             string `gorm:"index"`
+	Algorithm           string
+	Status              string
+	PublicKey           []byte
+	EncryptedPrivateKey []byte
+	ActivatedAt         *time.Time
+	RetiredAt           *time.Time
+	ExpiresAt           *time.Time
+	RevokedAt           *time.Time
+}
+
+func CreateSigningKeyTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190008",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&SigningKey{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&SigningKey{})
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Voucher struct {
+	AccountID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Batch       string    `gorm:"index"`
+	Duration    int64
+	DataLimit   int64
+	RedeemBy    *time.Time
+	ActivatedAt *time.Time
+	ExpiresAt   *time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+func CreateVoucherTable() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190017",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&Voucher{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&Voucher{})
+		},
+	}
+}
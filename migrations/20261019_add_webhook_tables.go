@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookEndpoint struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	URL         string
+	Secret      string
+	Events      string
+	Description string
+	gorm.Model
+}
+
+type WebhookDeadLetter struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt  time.Time
+	EndpointID uuid.UUID `gorm:"type:uuid;index"`
+	EventID    string
+	EventType  string
+	Payload    string
+	Attempts   int
+	LastError  string
+	ReplayedAt *time.Time
+}
+
+func CreateWebhookTables() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202610190003",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(WebhookEndpoint{}, WebhookDeadLetter{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(WebhookDeadLetter{}, WebhookEndpoint{})
+		},
+	}
+}
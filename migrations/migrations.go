@@ -9,6 +9,26 @@ import (
 func RunMigration(gormDB *gorm.DB) {
 	m := gormigrate.New(gormDB, gormigrate.DefaultOptions, []*gormigrate.Migration{
 		CreateAccountTable(),
+		CreateAuditEventTable(),
+		AddAuditChain(),
+		CreateWebhookTables(),
+		CreateOutboxTable(),
+		AddAccountMetadata(),
+		CreateOAuthTables(),
+		AddOIDCColumns(),
+		CreateSigningKeyTable(),
+		CreateOAuthRevokedTokenTable(),
+		CreatePersonalAccessTokenTable(),
+		AddServiceAccounts(),
+		CreateOAuthDeviceCodeTable(),
+		CreateIdentityTable(),
+		AddIdentityMethods(),
+		AddAccountRoles(),
+		CreateAccountingSessionTable(),
+		CreateVoucherTable(),
+		CreateDeviceTable(),
+		CreateBrowserSessionTable(),
+		AddAccountLock(),
 	})
 	if err := m.Migrate(); err != nil {
 		log.Fatal().Err(err).Msgf("migration failed: %v", err.Error())
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+)
+
+var (
+	defaultAccountSearchLimit = 50
+	maxAccountSearchLimit     = 200
+	// failedLoginHistoryLimit is how many of the latest failed logins of an account are listed.
+	failedLoginHistoryLimit = 50
+	temporaryPasswordLength = 16
+)
+
+var (
+	ErrPasswordResetNotAllowed = errors.New("Only the password of a user account kept by the service can be reset")
+	ErrAccountFromDirectory    = errors.New("Account is kept by the directory, change it there")
+	ErrInvalidRole             = errors.New("Roles cannot be empty or contain spaces")
+)
+
+func (u *usecaseHandler) SearchAccounts(ctx context.Context, req AccountSearchRequest) (*AccountSearchResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAccountSearchLimit
+	}
+	if limit > maxAccountSearchLimit {
+		limit = maxAccountSearchLimit
+	}
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	accounts, err := u.repo.SearchAccounts(ctx, strings.TrimSpace(req.Query), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &AccountSearchResponse{Accounts: make([]AdminAccountResponse, 0, len(accounts))}
+	for i := range accounts {
+		rsp.Accounts = append(rsp.Accounts, *adminAccountResponse(&accounts[i]))
+	}
+	return rsp, nil
+}
+
+func (u *usecaseHandler) GetAccount(ctx context.Context, username string) (*AdminAccountResponse, error) {
+	account, err := u.getAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return adminAccountResponse(account), nil
+}
+
+// ListFailedLogins lists the latest logins of the account that did not go through, newest first,
+// from the audit log.
+func (u *usecaseHandler) ListFailedLogins(ctx context.Context, username string) (*AuditQueryResponse, error) {
+	account, err := u.getAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return u.audit.QueryEvents(ctx, AuditQueryRequest{
+		TypePrefix: "login.",
+		Target:     account.Username,
+		Outcome:    AuditOutcomeFailure,
+		Limit:      failedLoginHistoryLimit,
+		Newest:     true,
+	})
+}
+
+// LockAccount stops the account from signing in, and ends its sessions, personal access tokens and
+// refresh tokens until it is unlocked. Locking a locked account keeps when it was first locked.
+func (u *usecaseHandler) LockAccount(ctx context.Context, username string, req LockAccountRequest) (*AdminAccountResponse, error) {
+	account, err := u.getAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account.LockedAt != nil {
+		return adminAccountResponse(account), nil
+	}
+	now := time.Now()
+	account.LockedAt = &now
+	if err := u.updateAccountLock(ctx, account); err != nil {
+		return nil, err
+	}
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditAccountLocked,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  req.Reason,
+	})
+	u.events.Publish(ctx, NewAccountEvent(EventAccountLocked, account, req.Reason))
+	return adminAccountResponse(account), nil
+}
+
+// UnlockAccount lets a locked account sign in again, and lifts the block of too many failed logins.
+func (u *usecaseHandler) UnlockAccount(ctx context.Context, username string) (*AdminAccountResponse, error) {
+	account, err := u.getAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account.LockedAt != nil {
+		account.LockedAt = nil
+		if err := u.updateAccountLock(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+	u.ClearFailedAttempt(account.Username)
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditAccountUnlocked,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
+	return adminAccountResponse(account), nil
+}
+
+// ResetPassword gives the account a temporary password, which it has to replace at its next login.
+// The temporary password is returned once and never kept in clear. Sessions and tokens the account
+// already has keep working, locking it stops them.
+func (u *usecaseHandler) ResetPassword(ctx context.Context, username string) (*PasswordResetResponse, error) {
+	account, err := u.getAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account.Type != repository.AccountTypeUser || account.Source == repository.AccountSourceLDAP {
+		return nil, ErrPasswordResetNotAllowed
+	}
+	password, err := util.RandomSecurePassword(temporaryPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := util.HashedPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	account.HashedPassword = hashedPassword
+	account.PasswordResetRequired = true
+	if err := u.repo.UpdateAccountPassword(ctx, account); err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	u.ClearFailedAttempt(account.Username)
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditPasswordReset,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
+	return &PasswordResetResponse{Username: account.Username, TemporaryPassword: password}, nil
+}
+
+// SetAccountRoles replaces the roles of the account. Directory accounts get their roles from their
+// groups, which would put them back at the next login.
+func (u *usecaseHandler) SetAccountRoles(ctx context.Context, username string, req AccountRolesRequest) (*AdminAccountResponse, error) {
+	for _, role := range req.Roles {
+		if role == "" || strings.IndexFunc(role, unicode.IsSpace) >= 0 {
+			return nil, ErrInvalidRole
+		}
+	}
+	account, err := u.getAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account.Source == repository.AccountSourceLDAP {
+		return nil, ErrAccountFromDirectory
+	}
+	roles := joinRoles(req.Roles)
+	if roles == account.Roles {
+		return adminAccountResponse(account), nil
+	}
+	account.Roles = roles
+	if err := u.repo.UpdateAccountRoles(ctx, account); err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditRolesChanged,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "roles=" + roles,
+	})
+	return adminAccountResponse(account), nil
+}
+
+func (u *usecaseHandler) getAccount(ctx context.Context, username string) (*repository.Account, error) {
+	account, err := u.repo.GetAccount(ctx, username)
+	if err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+func (u *usecaseHandler) updateAccountLock(ctx context.Context, account *repository.Account) error {
+	if err := u.repo.UpdateAccountLock(ctx, account); err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			return ErrAccountNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func adminAccountResponse(account *repository.Account) *AdminAccountResponse {
+	return &AdminAccountResponse{
+		ID:                    account.ID.String(),
+		Username:              account.Username,
+		Type:                  account.Type,
+		Source:                account.Source,
+		Roles:                 strings.Fields(account.Roles),
+		LockedAt:              account.LockedAt,
+		PasswordResetRequired: account.PasswordResetRequired,
+		CreatedAt:             account.CreatedAt,
+	}
+}
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchAccounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, nil, nil, nil, nil, nil, nil)
+	lockedAt := time.Now()
+
+	mockRepo.EXPECT().SearchAccounts(gomock.Any(), "ali", maxAccountSearchLimit, 0).Return([]repository.Account{
+		{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser, Roles: "admin staff", LockedAt: &lockedAt},
+		{ID: uuid.New(), Username: "alina", Type: repository.AccountTypeUser},
+	}, nil)
+	rsp, err := usecase.SearchAccounts(context.Background(), AccountSearchRequest{Query: " ali ", Limit: 5000, Offset: -1})
+	require.NoError(t, err)
+	require.Len(t, rsp.Accounts, 2)
+	require.Equal(t, []string{"admin", "staff"}, rsp.Accounts[0].Roles)
+	require.Equal(t, &lockedAt, rsp.Accounts[0].LockedAt)
+	require.Empty(t, rsp.Accounts[1].Roles)
+
+	mockRepo.EXPECT().SearchAccounts(gomock.Any(), "", defaultAccountSearchLimit, 50).Return(nil, nil)
+	rsp, err = usecase.SearchAccounts(context.Background(), AccountSearchRequest{Offset: 50})
+	require.NoError(t, err)
+	require.NotNil(t, rsp.Accounts)
+}
+
+func TestListFailedLogins(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, nil, nil, nil, nil, nil)
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "alice").Return(&repository.Account{ID: uuid.New(), Username: "alice"}, nil)
+	mockAudit.EXPECT().QueryEvents(gomock.Any(), AuditQueryRequest{TypePrefix: "login.", Target: "alice",
+		Outcome: AuditOutcomeFailure, Limit: failedLoginHistoryLimit, Newest: true}).
+		Return(&AuditQueryResponse{Events: []AuditEventResponse{
+			{ID: 3, Type: AuditLoginWrongPassword},
+			{ID: 1, Type: AuditLoginBlocked},
+		}}, nil)
+	rsp, err := usecase.ListFailedLogins(context.Background(), "alice")
+	require.NoError(t, err)
+	require.Len(t, rsp.Events, 2)
+	require.Equal(t, uint64(3), rsp.Events[0].ID)
+	require.Equal(t, uint64(1), rsp.Events[1].ID)
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "bob").Return(nil, repository.ErrAccountRecordNotFound)
+	_, err = usecase.ListFailedLogins(context.Background(), "bob")
+	require.Equal(t, ErrAccountNotFound, err)
+}
+
+func TestLockAndUnlockAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	mockEvents := NewMockEventPublisher(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, nil).(*usecaseHandler)
+	account := &repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser}
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil).Times(3)
+	mockRepo.EXPECT().UpdateAccountLock(gomock.Any(), account).Return(nil).Times(2)
+	mockAudit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+		require.Equal(t, AuditAccountLocked, entry.Type)
+		require.Equal(t, "alice", entry.Target)
+		require.Equal(t, "phishing", entry.Detail)
+	})
+	mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLocked))
+	rsp, err := usecase.LockAccount(context.Background(), "alice", LockAccountRequest{Reason: "phishing"})
+	require.NoError(t, err)
+	require.NotNil(t, rsp.LockedAt)
+
+	// Locking again keeps when the account was first locked.
+	lockedAt := account.LockedAt
+	rsp, err = usecase.LockAccount(context.Background(), "alice", LockAccountRequest{})
+	require.NoError(t, err)
+	require.Equal(t, lockedAt, rsp.LockedAt)
+
+	for i := 0; i < maxFailedAttempt; i++ {
+		usecase.AddFailedAttempt("alice")
+	}
+	require.Equal(t, ErrLoginAttemptBlocked, usecase.loginValidate("alice"))
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountUnlocked, AuditOutcomeSuccess))
+	rsp, err = usecase.UnlockAccount(context.Background(), "alice")
+	require.NoError(t, err)
+	require.Nil(t, rsp.LockedAt)
+	require.NoError(t, usecase.loginValidate("alice"))
+}
+
+func TestResetPassword(t *testing.T) {
+	testCases := []struct {
+		name       string
+		account    repository.Account
+		buildStubs func(repo *repository.MockAccountRepository, audit *MockAuditUsecase)
+		check      func(t *testing.T, rsp *PasswordResetResponse, err error)
+	}{
+		{
+			name:    "OK",
+			account: repository.Account{Type: repository.AccountTypeUser},
+			buildStubs: func(repo *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().UpdateAccountPassword(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, account *repository.Account) error {
+					require.True(t, account.PasswordResetRequired)
+					return nil
+				})
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditPasswordReset, AuditOutcomeSuccess))
+			},
+			check: func(t *testing.T, rsp *PasswordResetResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "alice", rsp.Username)
+				require.Len(t, rsp.TemporaryPassword, temporaryPasswordLength)
+			},
+		},
+		{
+			name:    "DirectoryAccount",
+			account: repository.Account{Type: repository.AccountTypeUser, Source: repository.AccountSourceLDAP},
+			buildStubs: func(repo *repository.MockAccountRepository, audit *MockAuditUsecase) {
+			},
+			check: func(t *testing.T, rsp *PasswordResetResponse, err error) {
+				require.Equal(t, ErrPasswordResetNotAllowed, err)
+			},
+		},
+		{
+			name:    "ServiceAccount",
+			account: repository.Account{Type: repository.AccountTypeService},
+			buildStubs: func(repo *repository.MockAccountRepository, audit *MockAuditUsecase) {
+			},
+			check: func(t *testing.T, rsp *PasswordResetResponse, err error) {
+				require.Equal(t, ErrPasswordResetNotAllowed, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := repository.NewMockAccountRepository(ctrl)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			account := tc.account
+			account.ID, account.Username = uuid.New(), "alice"
+			mockRepo.EXPECT().GetAccount(gomock.Any(), "alice").Return(&account, nil)
+			tc.buildStubs(mockRepo, mockAudit)
+
+			rsp, err := NewUsecaseHandler(mockRepo, mockAudit, nil, nil, nil, nil, nil).ResetPassword(context.Background(), "alice")
+			tc.check(t, rsp, err)
+			if err == nil {
+				require.NoError(t, util.CheckPassword(rsp.TemporaryPassword, account.HashedPassword))
+			}
+		})
+	}
+}
+
+func TestSetAccountRoles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, nil, nil, nil, nil, nil)
+	account := &repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser, Roles: "staff"}
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil).Times(2)
+	mockRepo.EXPECT().UpdateAccountRoles(gomock.Any(), account).Return(nil)
+	mockAudit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+		require.Equal(t, AuditRolesChanged, entry.Type)
+		require.Equal(t, "roles=admin staff", entry.Detail)
+	})
+	rsp, err := usecase.SetAccountRoles(context.Background(), "alice", AccountRolesRequest{Roles: []string{"staff", "admin", "staff"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin", "staff"}, rsp.Roles)
+
+	// The same roles change nothing.
+	_, err = usecase.SetAccountRoles(context.Background(), "alice", AccountRolesRequest{Roles: []string{"admin", "staff"}})
+	require.NoError(t, err)
+
+	_, err = usecase.SetAccountRoles(context.Background(), "alice", AccountRolesRequest{Roles: []string{"help desk"}})
+	require.Equal(t, ErrInvalidRole, err)
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "bob").
+		Return(&repository.Account{ID: uuid.New(), Username: "bob", Source: repository.AccountSourceLDAP}, nil)
+	_, err = usecase.SetAccountRoles(context.Background(), "bob", AccountRolesRequest{Roles: []string{"admin"}})
+	require.Equal(t, ErrAccountFromDirectory, err)
+}
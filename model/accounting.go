@@ -0,0 +1,260 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/google/uuid"
+)
+
+// Accounting status types, as RADIUS Acct-Status-Type names them.
+const (
+	AccountingStart         = "Start"
+	AccountingInterimUpdate = "Interim-Update"
+	AccountingStop          = "Stop"
+	AccountingOn            = "Accounting-On"
+	AccountingOff           = "Accounting-Off"
+)
+
+const defaultAccountingUsagePeriod = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidAccountingRecord       = errors.New("Accounting record has no session ID")
+	ErrInvalidSessionID              = errors.New("Invalid session id")
+	ErrAccountingSessionNotFound     = errors.New("Accounting session not found")
+	ErrAccountingSessionStopped      = errors.New("Accounting session already stopped")
+	ErrDynamicAuthorizationDisabled  = errors.New("Disconnect and CoA are not configured")
+	ErrDynamicAuthorizationFailed    = errors.New("NAS did not apply the request")
+	ErrInvalidAuthorizationAttribute = errors.New("Invalid authorization attributes")
+)
+
+// AccountingRecord is what a NAS reports about a session in an Accounting-Request.
+type AccountingRecord struct {
+	// Client is the name of the NAS client the record came from, and NASAddress its address.
+	Client           string
+	NASAddress       string
+	NASIdentifier    string
+	StatusType       string
+	SessionID        string
+	Username         string
+	CallingStationID string
+	CalledStationID  string
+	FramedIPAddress  string
+	SessionTime      time.Duration
+	InputOctets      int64
+	OutputOctets     int64
+	TerminateCause   string
+	// EventTime is when the NAS saw the event, which may be earlier than when the record arrived.
+	EventTime time.Time
+}
+
+// SessionController asks the NAS of a session to end it or to change what it allows (RFC 5176).
+type SessionController interface {
+	Disconnect(ctx context.Context, session *repository.AccountingSession) error
+	// ChangeAuthorization sends the attributes, written as ParseAttributes of the radius package reads
+	// them, in a CoA-Request.
+	ChangeAuthorization(ctx context.Context, session *repository.AccountingSession, attributes string) error
+}
+
+// AccountingUsecase keeps the network sessions NASes report, and lets admins look at and end them.
+type AccountingUsecase interface {
+	Record(ctx context.Context, record AccountingRecord) error
+	OnlineSessions(ctx context.Context) ([]AccountingSessionResponse, error)
+	Usage(ctx context.Context, username string, req AccountingUsageRequest) (*AccountingUsageResponse, error)
+	Disconnect(ctx context.Context, id string) error
+	ChangeAuthorization(ctx context.Context, id string, req SessionAuthorizationRequest) error
+}
+
+type accountingUsecase struct {
+	repo     repository.AccountingRepository
+	accounts repository.AccountRepository
+	sessions SessionController
+	audit    AuditUsecase
+}
+
+// NewAccountingUsecase keeps sessions in repo. Without sessions, Disconnect and ChangeAuthorization fail
+// with ErrDynamicAuthorizationDisabled.
+func NewAccountingUsecase(repo repository.AccountingRepository, accounts repository.AccountRepository,
+	sessions SessionController, audit AuditUsecase) AccountingUsecase {
+	return &accountingUsecase{
+		repo:     repo,
+		accounts: accounts,
+		sessions: sessions,
+		audit:    audit,
+	}
+}
+
+func (a *accountingUsecase) Record(ctx context.Context, record AccountingRecord) error {
+	eventTime := record.EventTime
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+	switch record.StatusType {
+	case AccountingOn, AccountingOff:
+		// The NAS started or is going down, none of its sessions are online any more.
+		cause := "NAS-Reboot"
+		if record.StatusType == AccountingOff {
+			cause = "NAS-Request"
+		}
+		_, err := a.repo.StopClientSessions(ctx, record.Client, eventTime, cause)
+		return err
+	case AccountingStart, AccountingInterimUpdate, AccountingStop:
+	default:
+		return nil
+	}
+	if record.SessionID == "" {
+		return ErrInvalidAccountingRecord
+	}
+
+	session := &repository.AccountingSession{
+		ID:               uuid.New(),
+		Username:         record.Username,
+		Client:           record.Client,
+		SessionID:        record.SessionID,
+		NASAddress:       record.NASAddress,
+		NASIdentifier:    record.NASIdentifier,
+		CallingStationID: record.CallingStationID,
+		CalledStationID:  record.CalledStationID,
+		FramedIPAddress:  record.FramedIPAddress,
+		StartedAt:        eventTime.Add(-record.SessionTime),
+		UpdatedAt:        eventTime,
+		SessionTime:      int64(record.SessionTime / time.Second),
+		InputOctets:      record.InputOctets,
+		OutputOctets:     record.OutputOctets,
+	}
+	if record.StatusType == AccountingStop {
+		session.StoppedAt = &eventTime
+		session.TerminateCause = record.TerminateCause
+	}
+	account, err := a.accounts.GetAccount(ctx, record.Username)
+	if err == nil {
+		session.AccountID = &account.ID
+		session.Username = account.Username
+	} else if err != repository.ErrAccountRecordNotFound {
+		return err
+	}
+	return a.repo.SaveAccountingSession(ctx, session)
+}
+
+func (a *accountingUsecase) OnlineSessions(ctx context.Context) ([]AccountingSessionResponse, error) {
+	sessions, err := a.repo.ListOnlineSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]AccountingSessionResponse, 0, len(sessions))
+	for i := range sessions {
+		rsp = append(rsp, accountingSessionResponse(&sessions[i]))
+	}
+	return rsp, nil
+}
+
+func (a *accountingUsecase) Usage(ctx context.Context, username string, req AccountingUsageRequest) (*AccountingUsageResponse, error) {
+	account, err := a.accounts.GetAccount(ctx, username)
+	if err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	since := req.Since
+	if since.IsZero() {
+		since = time.Now().Add(-defaultAccountingUsagePeriod)
+	}
+	sessions, err := a.repo.ListAccountingSessions(ctx, account.ID, since)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &AccountingUsageResponse{
+		Username: account.Username,
+		Since:    since,
+		Sessions: make([]AccountingSessionResponse, 0, len(sessions)),
+	}
+	for i := range sessions {
+		rsp.SessionTime += sessions[i].SessionTime
+		rsp.InputOctets += sessions[i].InputOctets
+		rsp.OutputOctets += sessions[i].OutputOctets
+		rsp.Sessions = append(rsp.Sessions, accountingSessionResponse(&sessions[i]))
+	}
+	return rsp, nil
+}
+
+func (a *accountingUsecase) Disconnect(ctx context.Context, id string) error {
+	session, err := a.onlineSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = a.sessions.Disconnect(ctx, session)
+	a.recordSessionAction(ctx, session, "disconnect session of "+session.Username, err)
+	return err
+}
+
+func (a *accountingUsecase) ChangeAuthorization(ctx context.Context, id string, req SessionAuthorizationRequest) error {
+	session, err := a.onlineSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = a.sessions.ChangeAuthorization(ctx, session, req.Attributes)
+	if errors.Is(err, ErrInvalidAuthorizationAttribute) {
+		return err
+	}
+	a.recordSessionAction(ctx, session, "change session of "+session.Username+" with "+req.Attributes, err)
+	return err
+}
+
+func (a *accountingUsecase) onlineSession(ctx context.Context, id string) (*repository.AccountingSession, error) {
+	if a.sessions == nil {
+		return nil, ErrDynamicAuthorizationDisabled
+	}
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidSessionID
+	}
+	session, err := a.repo.GetAccountingSession(ctx, sessionID)
+	if err != nil {
+		if err == repository.ErrAccountingSessionNotFound {
+			return nil, ErrAccountingSessionNotFound
+		}
+		return nil, err
+	}
+	if session.StoppedAt != nil {
+		return nil, ErrAccountingSessionStopped
+	}
+	return session, nil
+}
+
+func (a *accountingUsecase) recordSessionAction(ctx context.Context, session *repository.AccountingSession, detail string, err error) {
+	entry := AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "accounting_session:" + session.ID.String(),
+		Outcome: AuditOutcomeSuccess,
+		Detail:  detail,
+	}
+	if err != nil {
+		entry.Outcome = AuditOutcomeFailure
+		entry.Detail += ": " + err.Error()
+	}
+	a.audit.Record(ctx, entry)
+}
+
+func accountingSessionResponse(session *repository.AccountingSession) AccountingSessionResponse {
+	return AccountingSessionResponse{
+		ID:               session.ID.String(),
+		Username:         session.Username,
+		Client:           session.Client,
+		SessionID:        session.SessionID,
+		NASAddress:       session.NASAddress,
+		NASIdentifier:    session.NASIdentifier,
+		CallingStationID: session.CallingStationID,
+		CalledStationID:  session.CalledStationID,
+		FramedIPAddress:  session.FramedIPAddress,
+		StartedAt:        session.StartedAt,
+		UpdatedAt:        session.UpdatedAt,
+		StoppedAt:        session.StoppedAt,
+		SessionTime:      session.SessionTime,
+		InputOctets:      session.InputOctets,
+		OutputOctets:     session.OutputOctets,
+		TerminateCause:   session.TerminateCause,
+	}
+}
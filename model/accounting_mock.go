@@ -0,0 +1,159 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: accounting.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/ambroseqiu/senao_hw/repository"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSessionController is a mock of SessionController interface.
+type MockSessionController struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionControllerMockRecorder
+}
+
+// MockSessionControllerMockRecorder is the mock recorder for MockSessionController.
+type MockSessionControllerMockRecorder struct {
+	mock *MockSessionController
+}
+
+// NewMockSessionController creates a new mock instance.
+func NewMockSessionController(ctrl *gomock.Controller) *MockSessionController {
+	mock := &MockSessionController{ctrl: ctrl}
+	mock.recorder = &MockSessionControllerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionController) EXPECT() *MockSessionControllerMockRecorder {
+	return m.recorder
+}
+
+// ChangeAuthorization mocks base method.
+func (m *MockSessionController) ChangeAuthorization(ctx context.Context, session *repository.AccountingSession, attributes string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeAuthorization", ctx, session, attributes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeAuthorization indicates an expected call of ChangeAuthorization.
+func (mr *MockSessionControllerMockRecorder) ChangeAuthorization(ctx, session, attributes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAuthorization", reflect.TypeOf((*MockSessionController)(nil).ChangeAuthorization), ctx, session, attributes)
+}
+
+// Disconnect mocks base method.
+func (m *MockSessionController) Disconnect(ctx context.Context, session *repository.AccountingSession) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disconnect", ctx, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disconnect indicates an expected call of Disconnect.
+func (mr *MockSessionControllerMockRecorder) Disconnect(ctx, session interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disconnect", reflect.TypeOf((*MockSessionController)(nil).Disconnect), ctx, session)
+}
+
+// MockAccountingUsecase is a mock of AccountingUsecase interface.
+type MockAccountingUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountingUsecaseMockRecorder
+}
+
+// MockAccountingUsecaseMockRecorder is the mock recorder for MockAccountingUsecase.
+type MockAccountingUsecaseMockRecorder struct {
+	mock *MockAccountingUsecase
+}
+
+// NewMockAccountingUsecase creates a new mock instance.
+func NewMockAccountingUsecase(ctrl *gomock.Controller) *MockAccountingUsecase {
+	mock := &MockAccountingUsecase{ctrl: ctrl}
+	mock.recorder = &MockAccountingUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountingUsecase) EXPECT() *MockAccountingUsecaseMockRecorder {
+	return m.recorder
+}
+
+// ChangeAuthorization mocks base method.
+func (m *MockAccountingUsecase) ChangeAuthorization(ctx context.Context, id string, req SessionAuthorizationRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeAuthorization", ctx, id, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeAuthorization indicates an expected call of ChangeAuthorization.
+func (mr *MockAccountingUsecaseMockRecorder) ChangeAuthorization(ctx, id, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeAuthorization", reflect.TypeOf((*MockAccountingUsecase)(nil).ChangeAuthorization), ctx, id, req)
+}
+
+// Disconnect mocks base method.
+func (m *MockAccountingUsecase) Disconnect(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disconnect", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disconnect indicates an expected call of Disconnect.
+func (mr *MockAccountingUsecaseMockRecorder) Disconnect(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disconnect", reflect.TypeOf((*MockAccountingUsecase)(nil).Disconnect), ctx, id)
+}
+
+// OnlineSessions mocks base method.
+func (m *MockAccountingUsecase) OnlineSessions(ctx context.Context) ([]AccountingSessionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OnlineSessions", ctx)
+	ret0, _ := ret[0].([]AccountingSessionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OnlineSessions indicates an expected call of OnlineSessions.
+func (mr *MockAccountingUsecaseMockRecorder) OnlineSessions(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnlineSessions", reflect.TypeOf((*MockAccountingUsecase)(nil).OnlineSessions), ctx)
+}
+
+// Record mocks base method.
+func (m *MockAccountingUsecase) Record(ctx context.Context, record AccountingRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockAccountingUsecaseMockRecorder) Record(ctx, record interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockAccountingUsecase)(nil).Record), ctx, record)
+}
+
+// Usage mocks base method.
+func (m *MockAccountingUsecase) Usage(ctx context.Context, username string, req AccountingUsageRequest) (*AccountingUsageResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Usage", ctx, username, req)
+	ret0, _ := ret[0].(*AccountingUsageResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Usage indicates an expected call of Usage.
+func (mr *MockAccountingUsecaseMockRecorder) Usage(ctx, username, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Usage", reflect.TypeOf((*MockAccountingUsecase)(nil).Usage), ctx, username, req)
+}
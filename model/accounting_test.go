@@ -0,0 +1,268 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountingRecord(t *testing.T) {
+	alice := &repository.Account{ID: uuid.New(), Username: "alice"}
+	eventTime := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	record := AccountingRecord{
+		Client:       "ap1",
+		NASAddress:   "10.0.0.2",
+		SessionID:    "0001",
+		Username:     "alice",
+		SessionTime:  90 * time.Second,
+		InputOctets:  100,
+		OutputOctets: 200,
+		EventTime:    eventTime,
+	}
+
+	testCases := []struct {
+		name       string
+		record     func() AccountingRecord
+		buildStubs func(repo *repository.MockAccountingRepository, accounts *repository.MockAccountRepository)
+		err        error
+	}{
+		{
+			name: "InterimUpdate",
+			record: func() AccountingRecord {
+				r := record
+				r.StatusType = AccountingInterimUpdate
+				return r
+			},
+			buildStubs: func(repo *repository.MockAccountingRepository, accounts *repository.MockAccountRepository) {
+				accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(alice, nil)
+				repo.EXPECT().SaveAccountingSession(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, session *repository.AccountingSession) error {
+						require.Equal(t, &alice.ID, session.AccountID)
+						require.Equal(t, "ap1", session.Client)
+						require.Equal(t, "0001", session.SessionID)
+						require.Equal(t, eventTime.Add(-90*time.Second), session.StartedAt)
+						require.EqualValues(t, 90, session.SessionTime)
+						require.EqualValues(t, 100, session.InputOctets)
+						require.Nil(t, session.StoppedAt)
+						return nil
+					})
+			},
+		},
+		{
+			name: "Stop",
+			record: func() AccountingRecord {
+				r := record
+				r.StatusType = AccountingStop
+				r.TerminateCause = "User-Request"
+				return r
+			},
+			buildStubs: func(repo *repository.MockAccountingRepository, accounts *repository.MockAccountRepository) {
+				accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(alice, nil)
+				repo.EXPECT().SaveAccountingSession(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, session *repository.AccountingSession) error {
+						require.Equal(t, eventTime, *session.StoppedAt)
+						require.Equal(t, "User-Request", session.TerminateCause)
+						return nil
+					})
+			},
+		},
+		{
+			name: "UnknownUsername",
+			record: func() AccountingRecord {
+				r := record
+				r.StatusType = AccountingStart
+				r.Username = "aa-bb-cc-dd-ee-ff"
+				return r
+			},
+			buildStubs: func(repo *repository.MockAccountingRepository, accounts *repository.MockAccountRepository) {
+				accounts.EXPECT().GetAccount(gomock.Any(), "aa-bb-cc-dd-ee-ff").Return(nil, repository.ErrAccountRecordNotFound)
+				repo.EXPECT().SaveAccountingSession(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, session *repository.AccountingSession) error {
+						require.Nil(t, session.AccountID)
+						require.Equal(t, "aa-bb-cc-dd-ee-ff", session.Username)
+						return nil
+					})
+			},
+		},
+		{
+			name: "AccountingOn",
+			record: func() AccountingRecord {
+				return AccountingRecord{Client: "ap1", StatusType: AccountingOn, EventTime: eventTime}
+			},
+			buildStubs: func(repo *repository.MockAccountingRepository, accounts *repository.MockAccountRepository) {
+				repo.EXPECT().StopClientSessions(gomock.Any(), "ap1", eventTime, "NAS-Reboot").Return(int64(2), nil)
+			},
+		},
+		{
+			name: "NoSessionID",
+			record: func() AccountingRecord {
+				return AccountingRecord{Client: "ap1", StatusType: AccountingStart, Username: "alice"}
+			},
+			buildStubs: func(repo *repository.MockAccountingRepository, accounts *repository.MockAccountRepository) {
+				repo.EXPECT().SaveAccountingSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: ErrInvalidAccountingRecord,
+		},
+		{
+			name: "OtherStatusType",
+			record: func() AccountingRecord {
+				return AccountingRecord{Client: "ap1", StatusType: "Failed", SessionID: "0001"}
+			},
+			buildStubs: func(repo *repository.MockAccountingRepository, accounts *repository.MockAccountRepository) {
+				repo.EXPECT().SaveAccountingSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockAccountingRepository(ctrl)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			tc.buildStubs(repo, accounts)
+			accounting := NewAccountingUsecase(repo, accounts, nil, NewMockAuditUsecase(ctrl))
+
+			err := accounting.Record(context.Background(), tc.record())
+			require.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestAccountingUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockAccountingRepository(ctrl)
+	accounts := repository.NewMockAccountRepository(ctrl)
+	accounting := NewAccountingUsecase(repo, accounts, nil, NewMockAuditUsecase(ctrl))
+
+	alice := &repository.Account{ID: uuid.New(), Username: "alice"}
+	since := time.Now().Add(-time.Hour)
+	accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(alice, nil)
+	repo.EXPECT().ListAccountingSessions(gomock.Any(), alice.ID, since).Return([]repository.AccountingSession{
+		{ID: uuid.New(), Username: "alice", SessionTime: 60, InputOctets: 10, OutputOctets: 100},
+		{ID: uuid.New(), Username: "alice", SessionTime: 30, InputOctets: 5, OutputOctets: 50},
+	}, nil)
+
+	rsp, err := accounting.Usage(context.Background(), "alice", AccountingUsageRequest{Since: since})
+	require.NoError(t, err)
+	require.EqualValues(t, 90, rsp.SessionTime)
+	require.EqualValues(t, 15, rsp.InputOctets)
+	require.EqualValues(t, 150, rsp.OutputOctets)
+	require.Len(t, rsp.Sessions, 2)
+
+	accounts.EXPECT().GetAccount(gomock.Any(), "bob").Return(nil, repository.ErrAccountRecordNotFound)
+	_, err = accounting.Usage(context.Background(), "bob", AccountingUsageRequest{})
+	require.Equal(t, ErrAccountNotFound, err)
+}
+
+func TestAccountingDisconnect(t *testing.T) {
+	now := time.Now()
+	online := &repository.AccountingSession{ID: uuid.New(), Username: "alice", Client: "ap1", SessionID: "0001"}
+	stopped := &repository.AccountingSession{ID: uuid.New(), Username: "alice", StoppedAt: &now}
+
+	testCases := []struct {
+		name       string
+		id         string
+		buildStubs func(repo *repository.MockAccountingRepository, sessions *MockSessionController, audit *MockAuditUsecase)
+		err        error
+	}{
+		{
+			name: "OK",
+			id:   online.ID.String(),
+			buildStubs: func(repo *repository.MockAccountingRepository, sessions *MockSessionController, audit *MockAuditUsecase) {
+				repo.EXPECT().GetAccountingSession(gomock.Any(), online.ID).Return(online, nil)
+				sessions.EXPECT().Disconnect(gomock.Any(), online).Return(nil)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+			},
+		},
+		{
+			name: "NASRejected",
+			id:   online.ID.String(),
+			buildStubs: func(repo *repository.MockAccountingRepository, sessions *MockSessionController, audit *MockAuditUsecase) {
+				repo.EXPECT().GetAccountingSession(gomock.Any(), online.ID).Return(online, nil)
+				sessions.EXPECT().Disconnect(gomock.Any(), online).
+					Return(fmt.Errorf("%w: Session-Context-Not-Found", ErrDynamicAuthorizationFailed))
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeFailure))
+			},
+			err: ErrDynamicAuthorizationFailed,
+		},
+		{
+			name: "Stopped",
+			id:   stopped.ID.String(),
+			buildStubs: func(repo *repository.MockAccountingRepository, sessions *MockSessionController, audit *MockAuditUsecase) {
+				repo.EXPECT().GetAccountingSession(gomock.Any(), stopped.ID).Return(stopped, nil)
+				sessions.EXPECT().Disconnect(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: ErrAccountingSessionStopped,
+		},
+		{
+			name: "NotFound",
+			id:   online.ID.String(),
+			buildStubs: func(repo *repository.MockAccountingRepository, sessions *MockSessionController, audit *MockAuditUsecase) {
+				repo.EXPECT().GetAccountingSession(gomock.Any(), online.ID).Return(nil, repository.ErrAccountingSessionNotFound)
+			},
+			err: ErrAccountingSessionNotFound,
+		},
+		{
+			name: "InvalidID",
+			id:   "0001",
+			buildStubs: func(repo *repository.MockAccountingRepository, sessions *MockSessionController, audit *MockAuditUsecase) {
+			},
+			err: ErrInvalidSessionID,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockAccountingRepository(ctrl)
+			sessions := NewMockSessionController(ctrl)
+			audit := NewMockAuditUsecase(ctrl)
+			tc.buildStubs(repo, sessions, audit)
+			accounting := NewAccountingUsecase(repo, repository.NewMockAccountRepository(ctrl), sessions, audit)
+
+			err := accounting.Disconnect(context.Background(), tc.id)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestAccountingDisconnectDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	accounting := NewAccountingUsecase(repository.NewMockAccountingRepository(ctrl), repository.NewMockAccountRepository(ctrl),
+		nil, NewMockAuditUsecase(ctrl))
+
+	err := accounting.Disconnect(context.Background(), uuid.NewString())
+	require.Equal(t, ErrDynamicAuthorizationDisabled, err)
+}
+
+func TestAccountingChangeAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockAccountingRepository(ctrl)
+	sessions := NewMockSessionController(ctrl)
+	audit := NewMockAuditUsecase(ctrl)
+	accounting := NewAccountingUsecase(repo, repository.NewMockAccountRepository(ctrl), sessions, audit)
+
+	online := &repository.AccountingSession{ID: uuid.New(), Username: "alice"}
+	repo.EXPECT().GetAccountingSession(gomock.Any(), online.ID).Return(online, nil).Times(2)
+	sessions.EXPECT().ChangeAuthorization(gomock.Any(), online, "Session-Timeout=600").Return(nil)
+	audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+	err := accounting.ChangeAuthorization(context.Background(), online.ID.String(), SessionAuthorizationRequest{Attributes: "Session-Timeout=600"})
+	require.NoError(t, err)
+
+	// Attributes that cannot be sent are the caller's mistake, nothing reached the NAS to audit.
+	invalid := fmt.Errorf("%w: %v", ErrInvalidAuthorizationAttribute, errors.New(`unknown attribute "Bogus"`))
+	sessions.EXPECT().ChangeAuthorization(gomock.Any(), online, "Bogus=1").Return(invalid)
+	err = accounting.ChangeAuthorization(context.Background(), online.ID.String(), SessionAuthorizationRequest{Attributes: "Bogus=1"})
+	require.ErrorIs(t, err, ErrInvalidAuthorizationAttribute)
+}
@@ -0,0 +1,330 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	AuditAccountCreated       = "account.created"
+	AuditLoginSucceeded       = "login.succeeded"
+	AuditLoginWrongPassword   = "login.wrong_password"
+	AuditLoginWrongOTP        = "login.wrong_otp"
+	AuditLoginAccountNotFound = "login.account_not_found"
+	AuditLoginBlocked         = "login.blocked"
+	AuditLoginDenied          = "login.denied"
+	AuditAccountDeleted       = "account.deleted"
+	AuditPasswordChanged      = "password.changed"
+	AuditAdminAction          = "admin.action"
+	AuditOAuthTokenIssued     = "oauth.token_issued"
+	AuditOAuthTokenRevoked    = "oauth.token_revoked"
+	AuditOAuthDeviceApproved  = "oauth.device_approved"
+	AuditTokenCreated         = "token.created"
+	AuditTokenRevoked         = "token.revoked"
+	AuditIdentityLinked       = "identity.linked"
+	AuditIdentityUnlinked     = "identity.unlinked"
+	AuditRolesChanged         = "account.roles_changed"
+	AuditDeviceAuthorization  = "device.authorization"
+	AuditDeviceAccounting     = "device.accounting"
+	AuditPortalLogin          = "portal.login"
+	AuditDeviceRegistered     = "device.registered"
+	AuditDeviceRemoved        = "device.removed"
+	AuditDeviceMAB            = "device.mab"
+	AuditSessionCreated       = "session.created"
+	AuditSessionRevoked       = "session.revoked"
+	AuditAccountLocked        = "account.locked"
+	AuditAccountUnlocked      = "account.unlocked"
+	AuditPasswordReset        = "password.reset"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+const anonymousActor = "anonymous"
+
+var (
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 1000
+)
+
+var (
+	ErrAuditSigningKeyMissing = errors.New("audit signing key is not configured")
+	errStopAuditVerify        = errors.New("stop audit verification")
+)
+
+// AuditEntry is what a usecase knows about an event; the request details are taken from the context.
+type AuditEntry struct {
+	Type    string
+	Actor   string
+	Target  string
+	Outcome string
+	Detail  string
+}
+
+type AuditUsecase interface {
+	Record(ctx context.Context, entry AuditEntry)
+	QueryEvents(ctx context.Context, req AuditQueryRequest) (*AuditQueryResponse, error)
+	ExportEvents(ctx context.Context, req AuditQueryRequest, w io.Writer) error
+	CreateCheckpoint(ctx context.Context) error
+	RunCheckpoints(ctx context.Context, interval time.Duration)
+	VerifyChain(ctx context.Context, keys []VerificationKey) (*AuditVerifyReport, error)
+}
+
+type auditUsecase struct {
+	repo        repository.AuditRepository
+	signingKeys KeySet
+}
+
+// NewAuditUsecase creates the audit usecase. signingKeys may be nil, in which case no checkpoints are signed.
+func NewAuditUsecase(repo repository.AuditRepository, signingKeys KeySet) AuditUsecase {
+	return &auditUsecase{
+		repo:        repo,
+		signingKeys: signingKeys,
+	}
+}
+
+// Record stores the entry. Failing to audit must not fail the operation being audited, so errors are only logged.
+func (a *auditUsecase) Record(ctx context.Context, entry AuditEntry) {
+	info := RequestInfoFromContext(ctx)
+	actor := entry.Actor
+	if actor == "" {
+		actor = info.Actor
+	}
+	if actor == "" {
+		actor = anonymousActor
+	}
+
+	event := &repository.AuditEvent{
+		Type:      entry.Type,
+		Actor:     actor,
+		Target:    entry.Target,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		RequestID: info.RequestID,
+		Outcome:   entry.Outcome,
+		Detail:    entry.Detail,
+	}
+	if err := a.repo.CreateAuditEvent(ctx, event); err != nil {
+		log.Error().Err(err).Str("type", entry.Type).Str("target", entry.Target).Msg("failed to record audit event")
+	}
+}
+
+func (a *auditUsecase) QueryEvents(ctx context.Context, req AuditQueryRequest) (*AuditQueryResponse, error) {
+	filter := auditFilter(req)
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditQueryLimit
+	}
+	if filter.Limit > maxAuditQueryLimit {
+		filter.Limit = maxAuditQueryLimit
+	}
+
+	events, err := a.repo.ListAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "audit_events",
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "query audit events",
+	})
+
+	rsp := &AuditQueryResponse{
+		Events: make([]AuditEventResponse, 0, len(events)),
+	}
+	for i := range events {
+		rsp.Events = append(rsp.Events, auditEventResponse(&events[i]))
+	}
+	return rsp, nil
+}
+
+// ExportEvents streams every matching event to w as one JSON document per line.
+func (a *auditUsecase) ExportEvents(ctx context.Context, req AuditQueryRequest, w io.Writer) error {
+	a.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "audit_events",
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "export audit events",
+	})
+
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(interface{ Flush() })
+	return a.repo.IterateAuditEvents(ctx, auditFilter(req), func(event *repository.AuditEvent) error {
+		if err := encoder.Encode(auditEventResponse(event)); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+}
+
+// CreateCheckpoint signs the current head of the chain, unless it is already covered by the last checkpoint.
+func (a *auditUsecase) CreateCheckpoint(ctx context.Context) error {
+	if a.signingKeys == nil {
+		return ErrAuditSigningKeyMissing
+	}
+	key, err := a.signingKeys.Current()
+	if err != nil {
+		return err
+	}
+
+	head, err := a.repo.LastAuditEvent(ctx)
+	if err != nil {
+		if errors.Is(err, repository.ErrAuditEventNotFound) {
+			return nil
+		}
+		return err
+	}
+	last, err := a.repo.LastAuditCheckpoint(ctx)
+	if err != nil && !errors.Is(err, repository.ErrAuditCheckpointNotFound) {
+		return err
+	}
+	if last != nil && last.EventID == head.ID {
+		return nil
+	}
+
+	signature, err := SignMessage(key.Key, auditCheckpointMessage(head.ID, head.Hash))
+	if err != nil {
+		return err
+	}
+	return a.repo.CreateAuditCheckpoint(ctx, &repository.AuditCheckpoint{
+		EventID:   head.ID,
+		EventHash: head.Hash,
+		KeyID:     key.ID,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	})
+}
+
+// RunCheckpoints creates a checkpoint every interval until ctx is done.
+func (a *auditUsecase) RunCheckpoints(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.CreateCheckpoint(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to create audit checkpoint")
+			}
+		}
+	}
+}
+
+// VerifyChain walks the audit chain from the first event and reports the first broken link.
+// Every checkpoint must be signed by one of keys, before that key was revoked, and must still match
+// the event it covers.
+func (a *auditUsecase) VerifyChain(ctx context.Context, keys []VerificationKey) (*AuditVerifyReport, error) {
+	report := &AuditVerifyReport{}
+
+	checkpoints, err := a.repo.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keysByID := make(map[string]VerificationKey, len(keys))
+	for _, key := range keys {
+		keysByID[key.ID] = key
+	}
+	pending := make(map[uint64]*repository.AuditCheckpoint, len(checkpoints))
+	for i := range checkpoints {
+		checkpoint := &checkpoints[i]
+		key, ok := keysByID[checkpoint.KeyID]
+		signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+		if err != nil || !ok || (key.RevokedAt != nil && !checkpoint.CreatedAt.Before(*key.RevokedAt)) ||
+			!VerifySignature(key.Key, auditCheckpointMessage(checkpoint.EventID, checkpoint.EventHash), signature) {
+			report.BrokenEventID = checkpoint.EventID
+			report.Reason = fmt.Sprintf("checkpoint #%d has an invalid signature", checkpoint.ID)
+			return report, nil
+		}
+		pending[checkpoint.EventID] = checkpoint
+	}
+
+	var prev *repository.AuditEvent
+	err = a.repo.IterateAuditEvents(ctx, repository.AuditEventFilter{}, func(event *repository.AuditEvent) error {
+		report.EventsChecked++
+		switch {
+		case prev == nil && event.PrevHash != "":
+			report.Reason = "first event does not start the chain"
+		case prev != nil && event.PrevHash != prev.Hash:
+			report.Reason = fmt.Sprintf("previous hash does not match event #%d", prev.ID)
+		case repository.AuditEventHash(event) != event.Hash:
+			report.Reason = "event content does not match its hash"
+		}
+		if checkpoint, ok := pending[event.ID]; ok && report.Reason == "" {
+			report.CheckpointsChecked++
+			if checkpoint.EventHash != event.Hash {
+				report.Reason = fmt.Sprintf("event hash does not match checkpoint #%d", checkpoint.ID)
+			}
+			delete(pending, event.ID)
+		}
+		if report.Reason != "" {
+			report.BrokenEventID = event.ID
+			return errStopAuditVerify
+		}
+		prev = event
+		return nil
+	})
+	if err != nil && err != errStopAuditVerify {
+		return nil, err
+	}
+	if report.Reason != "" {
+		return report, nil
+	}
+
+	// Checkpoints left over cover events that are no longer in the table.
+	for i := range checkpoints {
+		if _, ok := pending[checkpoints[i].EventID]; ok {
+			report.BrokenEventID = checkpoints[i].EventID
+			report.Reason = fmt.Sprintf("event covered by checkpoint #%d is missing", checkpoints[i].ID)
+			return report, nil
+		}
+	}
+	return report, nil
+}
+
+func auditCheckpointMessage(eventID uint64, eventHash string) []byte {
+	return []byte(fmt.Sprintf("audit-checkpoint:%d:%s", eventID, eventHash))
+}
+
+func auditFilter(req AuditQueryRequest) repository.AuditEventFilter {
+	return repository.AuditEventFilter{
+		Type:       req.Type,
+		TypePrefix: req.TypePrefix,
+		Actor:      req.Actor,
+		Target:     req.Target,
+		Outcome:    req.Outcome,
+		Since:      req.Since,
+		Until:      req.Until,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+		Newest:     req.Newest,
+	}
+}
+
+func auditEventResponse(event *repository.AuditEvent) AuditEventResponse {
+	return AuditEventResponse{
+		ID:        event.ID,
+		CreatedAt: event.CreatedAt,
+		Type:      event.Type,
+		Actor:     event.Actor,
+		Target:    event.Target,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		RequestID: event.RequestID,
+		Outcome:   event.Outcome,
+		Detail:    event.Detail,
+	}
+}
@@ -0,0 +1,119 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: audit.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAuditUsecase is a mock of AuditUsecase interface.
+type MockAuditUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditUsecaseMockRecorder
+}
+
+// MockAuditUsecaseMockRecorder is the mock recorder for MockAuditUsecase.
+type MockAuditUsecaseMockRecorder struct {
+	mock *MockAuditUsecase
+}
+
+// NewMockAuditUsecase creates a new mock instance.
+func NewMockAuditUsecase(ctrl *gomock.Controller) *MockAuditUsecase {
+	mock := &MockAuditUsecase{ctrl: ctrl}
+	mock.recorder = &MockAuditUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditUsecase) EXPECT() *MockAuditUsecaseMockRecorder {
+	return m.recorder
+}
+
+// CreateCheckpoint mocks base method.
+func (m *MockAuditUsecase) CreateCheckpoint(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCheckpoint", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCheckpoint indicates an expected call of CreateCheckpoint.
+func (mr *MockAuditUsecaseMockRecorder) CreateCheckpoint(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCheckpoint", reflect.TypeOf((*MockAuditUsecase)(nil).CreateCheckpoint), ctx)
+}
+
+// ExportEvents mocks base method.
+func (m *MockAuditUsecase) ExportEvents(ctx context.Context, req AuditQueryRequest, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportEvents", ctx, req, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportEvents indicates an expected call of ExportEvents.
+func (mr *MockAuditUsecaseMockRecorder) ExportEvents(ctx, req, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportEvents", reflect.TypeOf((*MockAuditUsecase)(nil).ExportEvents), ctx, req, w)
+}
+
+// QueryEvents mocks base method.
+func (m *MockAuditUsecase) QueryEvents(ctx context.Context, req AuditQueryRequest) (*AuditQueryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryEvents", ctx, req)
+	ret0, _ := ret[0].(*AuditQueryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryEvents indicates an expected call of QueryEvents.
+func (mr *MockAuditUsecaseMockRecorder) QueryEvents(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryEvents", reflect.TypeOf((*MockAuditUsecase)(nil).QueryEvents), ctx, req)
+}
+
+// Record mocks base method.
+func (m *MockAuditUsecase) Record(ctx context.Context, entry AuditEntry) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", ctx, entry)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockAuditUsecaseMockRecorder) Record(ctx, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockAuditUsecase)(nil).Record), ctx, entry)
+}
+
+// RunCheckpoints mocks base method.
+func (m *MockAuditUsecase) RunCheckpoints(ctx context.Context, interval time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunCheckpoints", ctx, interval)
+}
+
+// RunCheckpoints indicates an expected call of RunCheckpoints.
+func (mr *MockAuditUsecaseMockRecorder) RunCheckpoints(ctx, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunCheckpoints", reflect.TypeOf((*MockAuditUsecase)(nil).RunCheckpoints), ctx, interval)
+}
+
+// VerifyChain mocks base method.
+func (m *MockAuditUsecase) VerifyChain(ctx context.Context, keys []VerificationKey) (*AuditVerifyReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyChain", ctx, keys)
+	ret0, _ := ret[0].(*AuditVerifyReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyChain indicates an expected call of VerifyChain.
+func (mr *MockAuditUsecaseMockRecorder) VerifyChain(ctx, keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyChain", reflect.TypeOf((*MockAuditUsecase)(nil).VerifyChain), ctx, keys)
+}
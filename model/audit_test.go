@@ -0,0 +1,331 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRecordTakesRequestInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAuditRepository(ctrl)
+	audit := NewAuditUsecase(mockRepo, nil)
+
+	username := util.RandomString(8)
+	info := RequestInfo{
+		IP:        "10.0.0.1",
+		UserAgent: "curl/8.0",
+		RequestID: util.RandomString(12),
+	}
+
+	mockRepo.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, event *repository.AuditEvent) error {
+			require.Equal(t, AuditLoginWrongPassword, event.Type)
+			require.Equal(t, anonymousActor, event.Actor)
+			require.Equal(t, username, event.Target)
+			require.Equal(t, info.IP, event.IP)
+			require.Equal(t, info.UserAgent, event.UserAgent)
+			require.Equal(t, info.RequestID, event.RequestID)
+			require.Equal(t, AuditOutcomeFailure, event.Outcome)
+			return nil
+		})
+
+	audit.Record(WithRequestInfo(context.Background(), info), AuditEntry{
+		Type:    AuditLoginWrongPassword,
+		Target:  username,
+		Outcome: AuditOutcomeFailure,
+	})
+}
+
+func TestAuditQueryEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAuditRepository(ctrl)
+	audit := NewAuditUsecase(mockRepo, nil)
+
+	target := util.RandomString(8)
+	mockRepo.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, filter repository.AuditEventFilter) ([]repository.AuditEvent, error) {
+			require.Equal(t, target, filter.Target)
+			require.Equal(t, maxAuditQueryLimit, filter.Limit)
+			return []repository.AuditEvent{
+				{ID: 1, Type: AuditLoginWrongPassword, Target: target, Outcome: AuditOutcomeFailure},
+				{ID: 2, Type: AuditLoginSucceeded, Target: target, Outcome: AuditOutcomeSuccess},
+			}, nil
+		})
+	mockRepo.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(nil)
+
+	ctx := WithRequestInfo(context.Background(), RequestInfo{Actor: "admin"})
+	rsp, err := audit.QueryEvents(ctx, AuditQueryRequest{Target: target, Limit: 5000})
+	require.NoError(t, err)
+	require.Len(t, rsp.Events, 2)
+	require.Equal(t, AuditLoginSucceeded, rsp.Events[1].Type)
+}
+
+func TestAuditExportEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAuditRepository(ctrl)
+	audit := NewAuditUsecase(mockRepo, nil)
+
+	events := []repository.AuditEvent{
+		{ID: 1, Type: AuditAccountCreated, Outcome: AuditOutcomeSuccess},
+		{ID: 2, Type: AuditLoginSucceeded, Outcome: AuditOutcomeSuccess},
+		{ID: 3, Type: AuditLoginBlocked, Outcome: AuditOutcomeFailure},
+	}
+	mockRepo.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(nil)
+	mockRepo.EXPECT().IterateAuditEvents(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, filter repository.AuditEventFilter, fn func(*repository.AuditEvent) error) error {
+			for i := range events {
+				if err := fn(&events[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	var buf bytes.Buffer
+	err := audit.ExportEvents(context.Background(), AuditQueryRequest{}, &buf)
+	require.NoError(t, err)
+
+	scanner := bufio.NewScanner(&buf)
+	lines := 0
+	for scanner.Scan() {
+		var event AuditEventResponse
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		require.Equal(t, events[lines].ID, event.ID)
+		require.Equal(t, events[lines].Type, event.Type)
+		lines++
+	}
+	require.Equal(t, len(events), lines)
+}
+
+func buildAuditChain(n int) []repository.AuditEvent {
+	events := make([]repository.AuditEvent, 0, n)
+	prevHash := ""
+	for i := 1; i <= n; i++ {
+		event := repository.AuditEvent{
+			ID:        uint64(i),
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+			Type:      AuditLoginWrongPassword,
+			Actor:     anonymousActor,
+			Target:    util.RandomString(8),
+			Outcome:   AuditOutcomeFailure,
+			PrevHash:  prevHash,
+		}
+		event.Hash = repository.AuditEventHash(&event)
+		prevHash = event.Hash
+		events = append(events, event)
+	}
+	return events
+}
+
+func newAuditSigningKey(t *testing.T, algorithm string) *SigningKey {
+	signer, err := GenerateSigningKey(algorithm)
+	require.NoError(t, err)
+	key, err := NewSigningKey(signer, KeyStatusCurrent)
+	require.NoError(t, err)
+	return key
+}
+
+func signAuditCheckpoint(t *testing.T, key *SigningKey, id uint64, event repository.AuditEvent) repository.AuditCheckpoint {
+	signature, err := SignMessage(key.Key, auditCheckpointMessage(event.ID, event.Hash))
+	require.NoError(t, err)
+	return repository.AuditCheckpoint{
+		ID:        id,
+		CreatedAt: time.Now(),
+		EventID:   event.ID,
+		EventHash: event.Hash,
+		KeyID:     key.ID,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}
+}
+
+func TestAuditVerifyChain(t *testing.T) {
+	signingKey := newAuditSigningKey(t, KeyAlgorithmEdDSA)
+	rotatedKey := newAuditSigningKey(t, KeyAlgorithmES256)
+	otherKey := newAuditSigningKey(t, KeyAlgorithmEdDSA)
+	revokedAt := time.Now().Add(-time.Hour)
+	keys := []VerificationKey{
+		{ID: signingKey.ID, Key: signingKey.Key.Public()},
+		{ID: rotatedKey.ID, Key: rotatedKey.Key.Public(), RevokedAt: &revokedAt},
+	}
+
+	testCase := []struct {
+		name    string
+		prepare func() ([]repository.AuditEvent, []repository.AuditCheckpoint)
+		verify  func(report *AuditVerifyReport)
+	}{
+		{
+			name: "intact",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				return events, []repository.AuditCheckpoint{
+					signAuditCheckpoint(t, signingKey, 1, events[2]),
+					signAuditCheckpoint(t, signingKey, 2, events[4]),
+				}
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.True(t, report.OK())
+				require.Equal(t, 5, report.EventsChecked)
+				require.Equal(t, 2, report.CheckpointsChecked)
+			},
+		},
+		{
+			name: "deleted event",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				return append(events[:2], events[3:]...), nil
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.False(t, report.OK())
+				require.Equal(t, uint64(4), report.BrokenEventID)
+			},
+		},
+		{
+			name: "modified event",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				events[1].Outcome = AuditOutcomeSuccess
+				return events, nil
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.False(t, report.OK())
+				require.Equal(t, uint64(2), report.BrokenEventID)
+			},
+		},
+		{
+			name: "rehashed after deletion",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				checkpoint := signAuditCheckpoint(t, signingKey, 1, events[4])
+				events = append(events[:2], events[3:]...)
+				for i := 2; i < len(events); i++ {
+					events[i].PrevHash = events[i-1].Hash
+					events[i].Hash = repository.AuditEventHash(&events[i])
+				}
+				return events, []repository.AuditCheckpoint{checkpoint}
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.False(t, report.OK())
+				require.Equal(t, uint64(5), report.BrokenEventID)
+			},
+		},
+		{
+			name: "truncated tail",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				return events[:3], []repository.AuditCheckpoint{signAuditCheckpoint(t, signingKey, 1, events[4])}
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.False(t, report.OK())
+				require.Equal(t, uint64(5), report.BrokenEventID)
+				require.Equal(t, 3, report.EventsChecked)
+			},
+		},
+		{
+			name: "forged checkpoint",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				return events, []repository.AuditCheckpoint{signAuditCheckpoint(t, otherKey, 1, events[4])}
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.False(t, report.OK())
+				require.Equal(t, uint64(5), report.BrokenEventID)
+				require.Equal(t, 0, report.EventsChecked)
+			},
+		},
+		{
+			name: "rotated key",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				rotated := signAuditCheckpoint(t, rotatedKey, 1, events[2])
+				rotated.CreatedAt = revokedAt.Add(-time.Hour)
+				return events, []repository.AuditCheckpoint{rotated, signAuditCheckpoint(t, signingKey, 2, events[4])}
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.True(t, report.OK())
+				require.Equal(t, 2, report.CheckpointsChecked)
+			},
+		},
+		{
+			name: "signed after revocation",
+			prepare: func() ([]repository.AuditEvent, []repository.AuditCheckpoint) {
+				events := buildAuditChain(5)
+				return events, []repository.AuditCheckpoint{signAuditCheckpoint(t, rotatedKey, 1, events[4])}
+			},
+			verify: func(report *AuditVerifyReport) {
+				require.False(t, report.OK())
+				require.Equal(t, uint64(5), report.BrokenEventID)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := repository.NewMockAuditRepository(ctrl)
+			audit := NewAuditUsecase(mockRepo, nil)
+
+			events, checkpoints := tc.prepare()
+			mockRepo.EXPECT().ListAuditCheckpoints(gomock.Any()).Return(checkpoints, nil)
+			mockRepo.EXPECT().IterateAuditEvents(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+				DoAndReturn(func(ctx context.Context, filter repository.AuditEventFilter, fn func(*repository.AuditEvent) error) error {
+					for i := range events {
+						if err := fn(&events[i]); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+
+			report, err := audit.VerifyChain(context.Background(), keys)
+			require.NoError(t, err)
+			tc.verify(report)
+		})
+	}
+}
+
+func TestAuditCreateCheckpoint(t *testing.T) {
+	signingKey := newAuditSigningKey(t, KeyAlgorithmRS256)
+
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAuditRepository(ctrl)
+	audit := NewAuditUsecase(mockRepo, NewStaticKeySet(*signingKey))
+
+	events := buildAuditChain(3)
+	head := events[2]
+	mockRepo.EXPECT().LastAuditEvent(gomock.Any()).Times(2).Return(&head, nil)
+	mockRepo.EXPECT().LastAuditCheckpoint(gomock.Any()).Return(nil, repository.ErrAuditCheckpointNotFound)
+
+	var created repository.AuditCheckpoint
+	mockRepo.EXPECT().CreateAuditCheckpoint(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, checkpoint *repository.AuditCheckpoint) error {
+			created = *checkpoint
+			return nil
+		})
+	require.NoError(t, audit.CreateCheckpoint(context.Background()))
+	require.Equal(t, head.ID, created.EventID)
+	require.Equal(t, head.Hash, created.EventHash)
+
+	signature, err := base64.StdEncoding.DecodeString(created.Signature)
+	require.NoError(t, err)
+	require.Equal(t, signingKey.ID, created.KeyID)
+	require.True(t, VerifySignature(signingKey.Key.Public(), auditCheckpointMessage(head.ID, head.Hash), signature))
+
+	// Nothing new since the last checkpoint, so nothing is signed.
+	mockRepo.EXPECT().LastAuditCheckpoint(gomock.Any()).Return(&created, nil)
+	require.NoError(t, audit.CreateCheckpoint(context.Background()))
+
+	unsigned := NewAuditUsecase(mockRepo, nil)
+	require.EqualError(t, unsigned.CreateCheckpoint(context.Background()), ErrAuditSigningKeyMissing.Error())
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Principal is the account an API request acts for, and the scopes its token grants.
+type Principal struct {
+	AccountID uuid.UUID
+	Username  string
+	Scopes    []string
+	// TokenID is the personal access token ID, or the jti of an access token.
+	TokenID string
+	// AuthTime is when the account signed in to get the token, zero when that is not known.
+	AuthTime time.Time
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return containsString(p.Scopes, scope)
+}
+
+// Authenticator turns the bearer token of an API request into the account it acts for. It takes
+// OAuth access tokens issued to an account as well as personal access tokens.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type authenticator struct {
+	signer      TokenSigner
+	revocations RevocationList
+	issuer      string
+	tokens      PersonalAccessTokenUsecase
+}
+
+func NewAuthenticator(signer TokenSigner, revocations RevocationList, issuer string, tokens PersonalAccessTokenUsecase) Authenticator {
+	return &authenticator{
+		signer:      signer,
+		revocations: revocations,
+		issuer:      issuer,
+		tokens:      tokens,
+	}
+}
+
+func (a *authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+		return a.tokens.Authenticate(ctx, token)
+	}
+
+	claims, err := verifyAccessToken(ctx, a.signer, a.revocations, a.issuer, token)
+	if err != nil {
+		return nil, err
+	}
+	// Client credentials tokens act for a client, not an account.
+	accountID, err := uuid.Parse(claims.Subject)
+	if err != nil || claims.Username == "" {
+		return nil, ErrInvalidToken
+	}
+	principal := &Principal{
+		AccountID: accountID,
+		Username:  claims.Username,
+		Scopes:    strings.Fields(claims.Scope),
+		TokenID:   claims.ID,
+	}
+	if claims.AuthTime != nil {
+		principal.AuthTime = claims.AuthTime.Time
+	}
+	return principal, nil
+}
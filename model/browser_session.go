@@ -0,0 +1,238 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ScopeSessions lets a token list and sign out the browser sessions of its account.
+const ScopeSessions = "sessions"
+
+// BrowserSessionScopes are what a browser session may do, all an account does for itself.
+var BrowserSessionScopes = []string{ScopeProfile, ScopeTokens, ScopeIdentities, ScopeDevices, ScopeSessions}
+
+var (
+	browserSessionTokenBytes = 32
+	// browserSessionTouchInterval limits how often a busy session writes its last-seen time and expiry.
+	browserSessionTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidBrowserSessionID = errors.New("Invalid session id")
+	ErrBrowserSessionNotFound  = errors.New("Session is not found")
+)
+
+type BrowserSessionConfig struct {
+	// IdleTimeout ends a session that is not used for that long, each use moves it on.
+	IdleTimeout time.Duration
+	// MaxAge ends a session that long after the login however much it is used.
+	MaxAge time.Duration
+}
+
+// BrowserSession is an account signed in to a browser.
+type BrowserSession struct {
+	ID        uuid.UUID
+	Principal *Principal
+	// Token is the value of the session cookie, only returned when the session is created.
+	Token string
+	// CSRFToken must come back with requests that change something, as cookies are sent with requests
+	// from other sites too.
+	CSRFToken string
+	// ExpiresAt is when the session ends if it is not used again, each use moves it on.
+	ExpiresAt time.Time
+	// EndsAt is when the session ends however much it is used. The cookie is kept until then, as
+	// requests checked through forward auth move ExpiresAt on without setting it again.
+	EndsAt time.Time
+	// Roles are the roles of the account, as they are when the session is used.
+	Roles []string
+}
+
+// BrowserSessionUsecase keeps the sessions of accounts signed in to a browser, which hold them in a
+// cookie rather than a bearer token.
+type BrowserSessionUsecase interface {
+	// CreateSession signs the account in to a new session once its login went through. Vouchers get
+	// ErrVoucherNoSelfService.
+	CreateSession(ctx context.Context, username string) (*BrowserSession, error)
+	// Authenticate accepts the token of an unexpired session of an account that still exists.
+	Authenticate(ctx context.Context, token string) (*BrowserSession, error)
+	ListSessions(ctx context.Context, principal *Principal) ([]BrowserSessionResponse, error)
+	// RevokeSession signs a session of the account out, its own session when id is its TokenID.
+	RevokeSession(ctx context.Context, principal *Principal, id string) error
+}
+
+type browserSessionUsecase struct {
+	config   BrowserSessionConfig
+	repo     repository.BrowserSessionRepository
+	accounts repository.AccountRepository
+	audit    AuditUsecase
+}
+
+func NewBrowserSessionUsecase(config BrowserSessionConfig, repo repository.BrowserSessionRepository,
+	accounts repository.AccountRepository, audit AuditUsecase) BrowserSessionUsecase {
+	return &browserSessionUsecase{
+		config:   config,
+		repo:     repo,
+		accounts: accounts,
+		audit:    audit,
+	}
+}
+
+func (b *browserSessionUsecase) CreateSession(ctx context.Context, username string) (*BrowserSession, error) {
+	account, err := b.accounts.GetAccount(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	// A session may do all an account does for itself, vouchers only get network access.
+	if account.Type == repository.AccountTypeVoucher {
+		return nil, ErrVoucherNoSelfService
+	}
+	token, err := util.RandomToken(browserSessionTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := util.RandomToken(browserSessionTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	info := RequestInfoFromContext(ctx)
+	now := time.Now()
+	record := &repository.BrowserSession{
+		ID:           uuid.New(),
+		AccountID:    account.ID,
+		TokenHash:    hashToken(token),
+		CSRFToken:    csrfToken,
+		IP:           info.IP,
+		UserAgent:    info.UserAgent,
+		CreatedAt:    now,
+		LastSeenAt:   now,
+		MaxExpiresAt: now.Add(b.config.MaxAge),
+	}
+	record.ExpiresAt = b.expiry(record, now)
+	if err := b.repo.CreateBrowserSession(ctx, record); err != nil {
+		return nil, err
+	}
+
+	b.audit.Record(ctx, AuditEntry{
+		Type:    AuditSessionCreated,
+		Actor:   account.Username,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  record.ID.String(),
+	})
+	session := browserSession(record, account)
+	session.Token = token
+	return session, nil
+}
+
+func (b *browserSessionUsecase) Authenticate(ctx context.Context, token string) (*BrowserSession, error) {
+	record, err := b.repo.GetBrowserSession(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrBrowserSessionNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !now.Before(record.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	account, err := b.accounts.GetAccountByID(ctx, record.AccountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	// Locking an account ends what it signed in to without waiting for it to expire. A voucher, expired
+	// or not, has no session to keep, should one be left from before vouchers were refused.
+	if account.LockedAt != nil || account.Type == repository.AccountTypeVoucher {
+		return nil, ErrInvalidToken
+	}
+
+	if now.Sub(record.LastSeenAt) >= browserSessionTouchInterval {
+		expiresAt := b.expiry(record, now)
+		if err := b.repo.TouchBrowserSession(ctx, record.ID, now, expiresAt); err != nil {
+			log.Error().Err(err).Str("session_id", record.ID.String()).Msg("failed to record session use")
+		} else {
+			record.LastSeenAt, record.ExpiresAt = now, expiresAt
+		}
+	}
+	return browserSession(record, account), nil
+}
+
+func (b *browserSessionUsecase) ListSessions(ctx context.Context, principal *Principal) ([]BrowserSessionResponse, error) {
+	sessions, err := b.repo.ListBrowserSessions(ctx, principal.AccountID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]BrowserSessionResponse, 0, len(sessions))
+	for i := range sessions {
+		rsp = append(rsp, BrowserSessionResponse{
+			ID:         sessions[i].ID.String(),
+			IP:         sessions[i].IP,
+			UserAgent:  sessions[i].UserAgent,
+			CreatedAt:  sessions[i].CreatedAt,
+			LastSeenAt: sessions[i].LastSeenAt,
+			ExpiresAt:  sessions[i].ExpiresAt,
+			Current:    sessions[i].ID.String() == principal.TokenID,
+		})
+	}
+	return rsp, nil
+}
+
+func (b *browserSessionUsecase) RevokeSession(ctx context.Context, principal *Principal, id string) error {
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidBrowserSessionID
+	}
+	if err := b.repo.DeleteBrowserSession(ctx, principal.AccountID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrBrowserSessionNotFound) {
+			return ErrBrowserSessionNotFound
+		}
+		return err
+	}
+
+	b.audit.Record(ctx, AuditEntry{
+		Type:    AuditSessionRevoked,
+		Target:  principal.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  id,
+	})
+	return nil
+}
+
+// expiry is when the session ends if it is not used again after now.
+func (b *browserSessionUsecase) expiry(record *repository.BrowserSession, now time.Time) time.Time {
+	expiresAt := now.Add(b.config.IdleTimeout)
+	if expiresAt.After(record.MaxExpiresAt) {
+		return record.MaxExpiresAt
+	}
+	return expiresAt
+}
+
+func browserSession(record *repository.BrowserSession, account *repository.Account) *BrowserSession {
+	return &BrowserSession{
+		ID: record.ID,
+		Principal: &Principal{
+			AccountID: account.ID,
+			Username:  account.Username,
+			Scopes:    BrowserSessionScopes,
+			TokenID:   record.ID.String(),
+			AuthTime:  record.CreatedAt,
+		},
+		CSRFToken: record.CSRFToken,
+		ExpiresAt: record.ExpiresAt,
+		EndsAt:    record.MaxExpiresAt,
+		Roles:     strings.Fields(account.Roles),
+	}
+}
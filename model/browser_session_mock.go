@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: browser_session.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockBrowserSessionUsecase is a mock of BrowserSessionUsecase interface.
+type MockBrowserSessionUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockBrowserSessionUsecaseMockRecorder
+}
+
+// MockBrowserSessionUsecaseMockRecorder is the mock recorder for MockBrowserSessionUsecase.
+type MockBrowserSessionUsecaseMockRecorder struct {
+	mock *MockBrowserSessionUsecase
+}
+
+// NewMockBrowserSessionUsecase creates a new mock instance.
+func NewMockBrowserSessionUsecase(ctrl *gomock.Controller) *MockBrowserSessionUsecase {
+	mock := &MockBrowserSessionUsecase{ctrl: ctrl}
+	mock.recorder = &MockBrowserSessionUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBrowserSessionUsecase) EXPECT() *MockBrowserSessionUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockBrowserSessionUsecase) Authenticate(ctx context.Context, token string) (*BrowserSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, token)
+	ret0, _ := ret[0].(*BrowserSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockBrowserSessionUsecaseMockRecorder) Authenticate(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockBrowserSessionUsecase)(nil).Authenticate), ctx, token)
+}
+
+// CreateSession mocks base method.
+func (m *MockBrowserSessionUsecase) CreateSession(ctx context.Context, username string) (*BrowserSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, username)
+	ret0, _ := ret[0].(*BrowserSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockBrowserSessionUsecaseMockRecorder) CreateSession(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockBrowserSessionUsecase)(nil).CreateSession), ctx, username)
+}
+
+// ListSessions mocks base method.
+func (m *MockBrowserSessionUsecase) ListSessions(ctx context.Context, principal *Principal) ([]BrowserSessionResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, principal)
+	ret0, _ := ret[0].([]BrowserSessionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockBrowserSessionUsecaseMockRecorder) ListSessions(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockBrowserSessionUsecase)(nil).ListSessions), ctx, principal)
+}
+
+// RevokeSession mocks base method.
+func (m *MockBrowserSessionUsecase) RevokeSession(ctx context.Context, principal *Principal, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, principal, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockBrowserSessionUsecaseMockRecorder) RevokeSession(ctx, principal, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockBrowserSessionUsecase)(nil).RevokeSession), ctx, principal, id)
+}
@@ -0,0 +1,212 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var testBrowserSessionConfig = BrowserSessionConfig{IdleTimeout: time.Hour, MaxAge: 24 * time.Hour}
+
+func TestCreateBrowserSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockBrowserSessionRepository(ctrl)
+	accounts := repository.NewMockAccountRepository(ctrl)
+	audit := NewMockAuditUsecase(ctrl)
+	account := &repository.Account{ID: uuid.New(), Username: "alice"}
+
+	accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil)
+	var created *repository.BrowserSession
+	repo.EXPECT().CreateBrowserSession(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, session *repository.BrowserSession) error {
+		created = session
+		return nil
+	})
+	audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+		require.Equal(t, AuditSessionCreated, entry.Type)
+		require.Equal(t, "alice", entry.Actor)
+	})
+
+	ctx := WithRequestInfo(context.Background(), RequestInfo{IP: "10.0.0.1", UserAgent: "browser"})
+	session, err := NewBrowserSessionUsecase(testBrowserSessionConfig, repo, accounts, audit).CreateSession(ctx, "alice")
+	require.NoError(t, err)
+	require.NotEmpty(t, session.Token)
+	require.NotEmpty(t, session.CSRFToken)
+	require.Equal(t, hashToken(session.Token), created.TokenHash)
+	require.Equal(t, account.ID, created.AccountID)
+	require.Equal(t, "10.0.0.1", created.IP)
+	require.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+	require.WithinDuration(t, time.Now().Add(24*time.Hour), session.EndsAt, time.Minute)
+	require.Equal(t, "alice", session.Principal.Username)
+	require.True(t, session.Principal.HasScope(ScopeProfile))
+	require.Equal(t, created.ID.String(), session.Principal.TokenID)
+}
+
+func TestCreateBrowserSessionVoucher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockBrowserSessionRepository(ctrl)
+	accounts := repository.NewMockAccountRepository(ctrl)
+	accounts.EXPECT().GetAccount(gomock.Any(), "ABCDE23456").
+		Return(&repository.Account{ID: uuid.New(), Username: "ABCDE23456", Type: repository.AccountTypeVoucher}, nil)
+	repo.EXPECT().CreateBrowserSession(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := NewBrowserSessionUsecase(testBrowserSessionConfig, repo, accounts, nil).CreateSession(context.Background(), "ABCDE23456")
+	require.Equal(t, ErrVoucherNoSelfService, err)
+}
+
+func TestAuthenticateBrowserSession(t *testing.T) {
+	account := &repository.Account{ID: uuid.New(), Username: "alice", Roles: "admin"}
+	now := time.Now()
+
+	testCases := []struct {
+		name       string
+		session    repository.BrowserSession
+		buildStubs func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession)
+		check      func(t *testing.T, session *BrowserSession, err error)
+	}{
+		{
+			name: "RecentlySeen",
+			session: repository.BrowserSession{LastSeenAt: now.Add(-time.Second), ExpiresAt: now.Add(time.Hour),
+				MaxExpiresAt: now.Add(time.Hour)},
+			buildStubs: func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession) {
+				accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+				repo.EXPECT().TouchBrowserSession(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, session *BrowserSession, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "alice", session.Principal.Username)
+				require.Equal(t, []string{"admin"}, session.Roles)
+			},
+		},
+		{
+			name: "Rolling",
+			session: repository.BrowserSession{LastSeenAt: now.Add(-30 * time.Minute), ExpiresAt: now.Add(30 * time.Minute),
+				MaxExpiresAt: now.Add(10 * time.Hour)},
+			buildStubs: func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession) {
+				accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+				repo.EXPECT().TouchBrowserSession(gomock.Any(), session.ID, gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uuid.UUID, seenAt time.Time, expiresAt time.Time) error {
+						require.Equal(t, seenAt.Add(time.Hour), expiresAt)
+						return nil
+					})
+			},
+			check: func(t *testing.T, session *BrowserSession, err error) {
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+			},
+		},
+		{
+			name: "RollingUpToMaxAge",
+			session: repository.BrowserSession{LastSeenAt: now.Add(-30 * time.Minute), ExpiresAt: now.Add(10 * time.Minute),
+				MaxExpiresAt: now.Add(10 * time.Minute)},
+			buildStubs: func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession) {
+				accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+				repo.EXPECT().TouchBrowserSession(gomock.Any(), session.ID, gomock.Any(), session.MaxExpiresAt).Return(nil)
+			},
+			check: func(t *testing.T, session *BrowserSession, err error) {
+				require.NoError(t, err)
+				require.Equal(t, session.EndsAt, session.ExpiresAt)
+			},
+		},
+		{
+			name: "Expired",
+			session: repository.BrowserSession{LastSeenAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour),
+				MaxExpiresAt: now.Add(time.Hour)},
+			buildStubs: func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession) {
+			},
+			check: func(t *testing.T, session *BrowserSession, err error) {
+				require.Equal(t, ErrInvalidToken, err)
+			},
+		},
+		{
+			name: "AccountDeleted",
+			session: repository.BrowserSession{LastSeenAt: now.Add(-time.Second), ExpiresAt: now.Add(time.Hour),
+				MaxExpiresAt: now.Add(time.Hour)},
+			buildStubs: func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession) {
+				accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(nil, repository.ErrAccountRecordNotFound)
+			},
+			check: func(t *testing.T, session *BrowserSession, err error) {
+				require.Equal(t, ErrInvalidToken, err)
+			},
+		},
+		{
+			name: "AccountLocked",
+			session: repository.BrowserSession{LastSeenAt: now.Add(-time.Second), ExpiresAt: now.Add(time.Hour),
+				MaxExpiresAt: now.Add(time.Hour)},
+			buildStubs: func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession) {
+				accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(&repository.Account{ID: account.ID, Username: "alice", LockedAt: &now}, nil)
+			},
+			check: func(t *testing.T, session *BrowserSession, err error) {
+				require.Equal(t, ErrInvalidToken, err)
+			},
+		},
+		{
+			name: "ExpiredVoucher",
+			session: repository.BrowserSession{LastSeenAt: now.Add(-time.Second), ExpiresAt: now.Add(time.Hour),
+				MaxExpiresAt: now.Add(time.Hour)},
+			buildStubs: func(repo *repository.MockBrowserSessionRepository, accounts *repository.MockAccountRepository, session *repository.BrowserSession) {
+				accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).
+					Return(&repository.Account{ID: account.ID, Username: "ABCDE23456", Type: repository.AccountTypeVoucher}, nil)
+			},
+			check: func(t *testing.T, session *BrowserSession, err error) {
+				require.Equal(t, ErrInvalidToken, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockBrowserSessionRepository(ctrl)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			record := tc.session
+			record.ID, record.AccountID, record.TokenHash = uuid.New(), account.ID, hashToken("token")
+			repo.EXPECT().GetBrowserSession(gomock.Any(), record.TokenHash).Return(&record, nil)
+			tc.buildStubs(repo, accounts, &record)
+
+			session, err := NewBrowserSessionUsecase(testBrowserSessionConfig, repo, accounts, nil).Authenticate(context.Background(), "token")
+			tc.check(t, session, err)
+		})
+	}
+}
+
+func TestAuthenticateBrowserSessionNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockBrowserSessionRepository(ctrl)
+	repo.EXPECT().GetBrowserSession(gomock.Any(), hashToken("token")).Return(nil, repository.ErrBrowserSessionNotFound)
+
+	_, err := NewBrowserSessionUsecase(testBrowserSessionConfig, repo, nil, nil).Authenticate(context.Background(), "token")
+	require.Equal(t, ErrInvalidToken, err)
+}
+
+func TestListAndRevokeBrowserSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockBrowserSessionRepository(ctrl)
+	audit := NewMockAuditUsecase(ctrl)
+	usecase := NewBrowserSessionUsecase(testBrowserSessionConfig, repo, nil, audit)
+	current, other := uuid.New(), uuid.New()
+	principal := &Principal{AccountID: uuid.New(), Username: "alice", TokenID: current.String()}
+
+	repo.EXPECT().ListBrowserSessions(gomock.Any(), principal.AccountID, gomock.Any()).
+		Return([]repository.BrowserSession{{ID: other, UserAgent: "phone"}, {ID: current, UserAgent: "laptop"}}, nil)
+	sessions, err := usecase.ListSessions(context.Background(), principal)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.False(t, sessions[0].Current)
+	require.True(t, sessions[1].Current)
+
+	repo.EXPECT().DeleteBrowserSession(gomock.Any(), principal.AccountID, other).Return(nil)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+		require.Equal(t, AuditSessionRevoked, entry.Type)
+		require.Equal(t, other.String(), entry.Detail)
+	})
+	require.NoError(t, usecase.RevokeSession(context.Background(), principal, other.String()))
+
+	repo.EXPECT().DeleteBrowserSession(gomock.Any(), principal.AccountID, other).Return(repository.ErrBrowserSessionNotFound)
+	require.Equal(t, ErrBrowserSessionNotFound, usecase.RevokeSession(context.Background(), principal, other.String()))
+	require.Equal(t, ErrInvalidBrowserSessionID, usecase.RevokeSession(context.Background(), principal, "laptop"))
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// maxAssertionLifetime bounds how far in the future an assertion may expire, so the replay cache
+// only has to remember a jti for that long.
+var maxAssertionLifetime = time.Hour
+
+var assertionAlgorithms = []string{KeyAlgorithmEdDSA, KeyAlgorithmRS256, KeyAlgorithmES256, KeyAlgorithmES384, KeyAlgorithmES512}
+
+// verifyClientAssertion authenticates a client by a JWT it signed with one of its registered keys
+// (RFC 7523). The kid header names the key, iss and sub are the client ID, aud is the issuer or the
+// token endpoint, and each jti is accepted once. Failures are reported as errCode.
+func (o *oauthUsecase) verifyClientAssertion(ctx context.Context, assertion string, errCode string) (*repository.OAuthClient, error) {
+	if assertion == "" {
+		return nil, newOAuthError(errCode, "assertion is required")
+	}
+	parser := jwt.NewParser(jwt.WithValidMethods(assertionAlgorithms))
+	unverified := &jwt.RegisteredClaims{}
+	if _, _, err := parser.ParseUnverified(assertion, unverified); err != nil || unverified.Issuer == "" {
+		return nil, newOAuthError(errCode, "assertion is malformed")
+	}
+	client, err := o.repo.GetClient(ctx, unverified.Issuer)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthClientNotFound) {
+			return nil, newOAuthError(errCode, "assertion issuer is unknown")
+		}
+		return nil, err
+	}
+	keys, err := o.repo.ListClientKeys(ctx, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err = parser.ParseWithClaims(assertion, claims, func(t *jwt.Token) (interface{}, error) {
+		for _, key := range keys {
+			if key.ID != t.Header["kid"] || key.RevokedAt != nil {
+				continue
+			}
+			if key.Algorithm != t.Method.Alg() {
+				return nil, fmt.Errorf("key %s does not sign with %s", key.ID, t.Method.Alg())
+			}
+			return x509.ParsePKIXPublicKey(key.PublicKey)
+		}
+		return nil, fmt.Errorf("unknown key %v", t.Header["kid"])
+	})
+	if err != nil {
+		return nil, newOAuthError(errCode, "assertion signature or lifetime is invalid")
+	}
+
+	switch {
+	case claims.Issuer != client.ID || claims.Subject != client.ID:
+		return nil, newOAuthError(errCode, "assertion iss and sub must be the client ID")
+	case !claims.VerifyAudience(o.issuer, true) && !claims.VerifyAudience(o.issuer+"/oauth/token", true):
+		return nil, newOAuthError(errCode, "assertion audience is not this server")
+	case claims.ExpiresAt == nil || time.Until(claims.ExpiresAt.Time) > maxAssertionLifetime:
+		return nil, newOAuthError(errCode, "assertion must expire within an hour")
+	case claims.ID == "":
+		return nil, newOAuthError(errCode, "assertion jti is required")
+	}
+
+	err = o.repo.UseAssertion(ctx, &repository.OAuthUsedAssertion{
+		ClientID:  client.ID,
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthAssertionReplayed) {
+			return nil, newOAuthError(errCode, "assertion was already used")
+		}
+		return nil, err
+	}
+	return client, nil
+}
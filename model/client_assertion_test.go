@@ -0,0 +1,239 @@
+package model
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClientKey(t *testing.T, clientID string) (crypto.Signer, repository.OAuthClientKey) {
+	signer, err := GenerateSigningKey(KeyAlgorithmES256)
+	require.NoError(t, err)
+	jwk, err := publicJWK(signer.Public())
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	require.NoError(t, err)
+	return signer, repository.OAuthClientKey{ID: jwk.Kid, ClientID: clientID, Algorithm: jwk.Alg, PublicKey: der}
+}
+
+func signTestAssertion(t *testing.T, signer crypto.Signer, kid string, claims jwt.RegisteredClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	assertion, err := token.SignedString(signer)
+	require.NoError(t, err)
+	return assertion
+}
+
+func newTestAssertionClaims(clientID string) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{testIssuer + "/oauth/token"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		ID:        uuid.New().String(),
+	}
+}
+
+func TestTokenJWTBearer(t *testing.T) {
+	client := newOAuthClient(t, util.RandomString(32), GrantClientCredentials+" "+GrantJWTBearer)
+	accountID := uuid.New()
+	client.AccountID = &accountID
+	signer, key := newTestClientKey(t, client.ID)
+	account := &repository.Account{ID: accountID, Username: "deploybot", Type: repository.AccountTypeService}
+
+	t.Run("ok", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ListClientKeys(gomock.Any(), client.ID).Return([]repository.OAuthClientKey{key}, nil)
+		mocks.repo.EXPECT().UseAssertion(gomock.Any(), gomock.Any()).Return(nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), accountID).Return(account, nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenIssued, AuditOutcomeSuccess)).
+			Do(func(_ context.Context, entry AuditEntry) {
+				require.Equal(t, "deploybot", entry.Actor)
+			})
+
+		rsp, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantJWTBearer,
+			Assertion: signTestAssertion(t, signer, key.ID, newTestAssertionClaims(client.ID)),
+		})
+		require.NoError(t, err)
+		require.Empty(t, rsp.RefreshToken)
+
+		claims := &AccessTokenClaims{}
+		require.NoError(t, mocks.signer.Verify(rsp.AccessToken, claims))
+		require.Equal(t, accountID.String(), claims.Subject)
+		require.Equal(t, "deploybot", claims.Username)
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ListClientKeys(gomock.Any(), client.ID).Return([]repository.OAuthClientKey{key}, nil)
+		mocks.repo.EXPECT().UseAssertion(gomock.Any(), gomock.Any()).Return(repository.ErrOAuthAssertionReplayed)
+
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantJWTBearer,
+			Assertion: signTestAssertion(t, signer, key.ID, newTestAssertionClaims(client.ID)),
+		})
+		requireOAuthError(t, err, OAuthErrInvalidGrant)
+	})
+
+	testCase := []struct {
+		name   string
+		claims func(claims *jwt.RegisteredClaims)
+		keys   func(key repository.OAuthClientKey) []repository.OAuthClientKey
+	}{
+		{
+			name:   "wrong audience",
+			claims: func(claims *jwt.RegisteredClaims) { claims.Audience = jwt.ClaimStrings{"https://other.example.com"} },
+		},
+		{
+			name:   "wrong subject",
+			claims: func(claims *jwt.RegisteredClaims) { claims.Subject = "someone" },
+		},
+		{
+			name: "expired",
+			claims: func(claims *jwt.RegisteredClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			},
+		},
+		{
+			name: "too long lived",
+			claims: func(claims *jwt.RegisteredClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(2 * time.Hour))
+			},
+		},
+		{
+			name:   "no jti",
+			claims: func(claims *jwt.RegisteredClaims) { claims.ID = "" },
+		},
+		{
+			name: "revoked key",
+			keys: func(key repository.OAuthClientKey) []repository.OAuthClientKey {
+				revokedAt := time.Now()
+				key.RevokedAt = &revokedAt
+				return []repository.OAuthClientKey{key}
+			},
+		},
+		{
+			name: "unknown key",
+			keys: func(key repository.OAuthClientKey) []repository.OAuthClientKey { return nil },
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			oauth, mocks := newTestOAuthUsecase(t)
+			claims := newTestAssertionClaims(client.ID)
+			if tc.claims != nil {
+				tc.claims(&claims)
+			}
+			keys := []repository.OAuthClientKey{key}
+			if tc.keys != nil {
+				keys = tc.keys(key)
+			}
+			mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+			mocks.repo.EXPECT().ListClientKeys(gomock.Any(), client.ID).Return(keys, nil)
+			mocks.repo.EXPECT().UseAssertion(gomock.Any(), gomock.Any()).Times(0)
+
+			_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+				GrantType: GrantJWTBearer,
+				Assertion: signTestAssertion(t, signer, key.ID, claims),
+			})
+			requireOAuthError(t, err, OAuthErrInvalidGrant)
+		})
+	}
+}
+
+func TestTokenClientAssertion(t *testing.T) {
+	client := newOAuthClient(t, util.RandomString(32), GrantClientCredentials)
+	signer, key := newTestClientKey(t, client.ID)
+
+	t.Run("ok", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ListClientKeys(gomock.Any(), client.ID).Return([]repository.OAuthClientKey{key}, nil)
+		mocks.repo.EXPECT().UseAssertion(gomock.Any(), gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenIssued, AuditOutcomeSuccess))
+
+		rsp, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType:           GrantClientCredentials,
+			ClientAssertionType: ClientAssertionTypeJWTBearer,
+			ClientAssertion:     signTestAssertion(t, signer, key.ID, newTestAssertionClaims(client.ID)),
+		})
+		require.NoError(t, err)
+
+		claims := &AccessTokenClaims{}
+		require.NoError(t, mocks.signer.Verify(rsp.AccessToken, claims))
+		require.Equal(t, client.ID, claims.Subject)
+	})
+
+	t.Run("client id mismatch", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ListClientKeys(gomock.Any(), client.ID).Return([]repository.OAuthClientKey{key}, nil)
+		mocks.repo.EXPECT().UseAssertion(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType:           GrantClientCredentials,
+			ClientID:            "other",
+			ClientAssertionType: ClientAssertionTypeJWTBearer,
+			ClientAssertion:     signTestAssertion(t, signer, key.ID, newTestAssertionClaims(client.ID)),
+		})
+		requireOAuthError(t, err, OAuthErrInvalidClient)
+	})
+
+	t.Run("unsupported assertion type", func(t *testing.T) {
+		oauth, _ := newTestOAuthUsecase(t)
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType:           GrantClientCredentials,
+			ClientAssertionType: "urn:example:saml",
+			ClientAssertion:     "assertion",
+		})
+		requireOAuthError(t, err, OAuthErrInvalidClient)
+	})
+}
+
+func TestTokenServiceAccountDeleted(t *testing.T) {
+	secret := util.RandomString(32)
+	client := newOAuthClient(t, secret, GrantClientCredentials)
+	accountID := uuid.New()
+	client.AccountID = &accountID
+
+	oauth, mocks := newTestOAuthUsecase(t)
+	mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+	mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), accountID).Return(nil, repository.ErrAccountRecordNotFound)
+
+	_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+		GrantType: GrantClientCredentials, ClientID: client.ID, ClientSecret: secret,
+	})
+	requireOAuthError(t, err, OAuthErrInvalidClient)
+}
+
+func TestTokenServiceAccountLocked(t *testing.T) {
+	secret := util.RandomString(32)
+	client := newOAuthClient(t, secret, GrantClientCredentials)
+	accountID := uuid.New()
+	client.AccountID = &accountID
+	lockedAt := time.Now()
+
+	oauth, mocks := newTestOAuthUsecase(t)
+	mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+	mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), accountID).
+		Return(&repository.Account{ID: accountID, Username: "deploybot", Type: repository.AccountTypeService, LockedAt: &lockedAt}, nil)
+
+	_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+		GrantType: GrantClientCredentials, ClientID: client.ID, ClientSecret: secret,
+	})
+	requireOAuthError(t, err, OAuthErrInvalidClient)
+}
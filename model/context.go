@@ -0,0 +1,22 @@
+package model
+
+import "context"
+
+type requestInfoKey struct{}
+
+// RequestInfo carries the caller details of an API request down to the usecases.
+type RequestInfo struct {
+	Actor     string
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
@@ -0,0 +1,430 @@
+package model
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/google/uuid"
+)
+
+// ScopeDevices lets a token list, register and remove the devices of its account.
+const ScopeDevices = "devices"
+
+const (
+	// maxOwnedDevices bounds the devices an account registers itself, admins are not bound.
+	maxOwnedDevices     = 20
+	maxDeviceImportRows = 5000
+)
+
+var (
+	ErrInvalidMAC              = errors.New("Invalid MAC address")
+	ErrInvalidDeviceID         = errors.New("Invalid device id")
+	ErrDeviceNotFound          = errors.New("Device is not found")
+	ErrDeviceAlreadyRegistered = errors.New("Device is already registered")
+	ErrDeviceAdminOnly         = errors.New("Only admins can set the owner, VLAN or role of a device")
+	ErrTooManyDevices          = errors.New("Account has registered too many devices")
+	ErrInvalidDeviceExpiry     = errors.New("Device expiry is in the past")
+	ErrInvalidDeviceImport     = errors.New("Invalid device import")
+	ErrDeviceNotRegistered     = errors.New("Device is not registered")
+	ErrDeviceExpired           = errors.New("Device registration has expired")
+	ErrDeviceOwnerNotFound     = errors.New("Device owner no longer exists")
+	ErrDeviceOwnerLocked       = errors.New("Device owner is locked")
+)
+
+// DeviceAuthorization is what a registered device is let on the network with.
+type DeviceAuthorization struct {
+	ID   uuid.UUID
+	MAC  string
+	VLAN int
+	Role string
+}
+
+// DeviceUsecase is the registry of devices let on the network by their MAC address, for MAC
+// authentication bypass. Accounts register their own devices, admins any device with a VLAN or role.
+// A nil principal is an admin.
+type DeviceUsecase interface {
+	RegisterDevice(ctx context.Context, principal *Principal, req DeviceRequest) (*DeviceResponse, error)
+	ListDevices(ctx context.Context, principal *Principal) ([]DeviceResponse, error)
+	DeleteDevice(ctx context.Context, principal *Principal, id string) error
+	// ImportDevices registers the devices of a CSV file with a header row naming its columns: mac, and
+	// optionally owner, description, expires_at, vlan and role. Nothing is imported when a row is not
+	// valid, the error names every line that is not.
+	ImportDevices(ctx context.Context, r io.Reader) (*DeviceImportResponse, error)
+	// Authorize checks the MAC a NAS sent as the username of a MAC authentication bypass request.
+	Authorize(ctx context.Context, mac string) (*DeviceAuthorization, error)
+}
+
+type deviceUsecase struct {
+	repo     repository.DeviceRepository
+	accounts repository.AccountRepository
+	audit    AuditUsecase
+}
+
+func NewDeviceUsecase(repo repository.DeviceRepository, accounts repository.AccountRepository, audit AuditUsecase) DeviceUsecase {
+	return &deviceUsecase{
+		repo:     repo,
+		accounts: accounts,
+		audit:    audit,
+	}
+}
+
+func (d *deviceUsecase) RegisterDevice(ctx context.Context, principal *Principal, req DeviceRequest) (*DeviceResponse, error) {
+	mac, err := NormalizeMAC(req.MAC)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidDeviceExpiry
+	}
+	device := &repository.Device{
+		ID:          uuid.New(),
+		MAC:         mac,
+		Description: req.Description,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   RequestInfoFromContext(ctx).Actor,
+		CreatedAt:   time.Now(),
+	}
+	owner := req.Owner
+	if principal != nil {
+		if req.Owner != "" || req.VLAN != 0 || req.Role != "" {
+			return nil, ErrDeviceAdminOnly
+		}
+		count, err := d.repo.CountDevices(ctx, principal.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= maxOwnedDevices {
+			return nil, ErrTooManyDevices
+		}
+		device.OwnerID = &principal.AccountID
+		owner = principal.Username
+	} else {
+		if owner != "" {
+			account, err := d.accounts.GetAccount(ctx, owner)
+			if err != nil {
+				if err == repository.ErrAccountRecordNotFound {
+					return nil, ErrAccountNotFound
+				}
+				return nil, err
+			}
+			device.OwnerID = &account.ID
+		}
+		device.VLAN = req.VLAN
+		device.Role = req.Role
+	}
+
+	if err := d.repo.CreateDevices(ctx, device); err != nil {
+		if err == repository.ErrDeviceIsDuplicated {
+			return nil, ErrDeviceAlreadyRegistered
+		}
+		return nil, err
+	}
+	d.audit.Record(ctx, AuditEntry{
+		Type:    AuditDeviceRegistered,
+		Target:  "device:" + device.ID.String(),
+		Outcome: AuditOutcomeSuccess,
+		Detail:  device.MAC,
+	})
+	rsp := deviceResponse(repository.DeviceOwner{Device: *device, Owner: owner})
+	return &rsp, nil
+}
+
+func (d *deviceUsecase) ListDevices(ctx context.Context, principal *Principal) ([]DeviceResponse, error) {
+	var ownerID *uuid.UUID
+	if principal != nil {
+		ownerID = &principal.AccountID
+	}
+	devices, err := d.repo.ListDevices(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]DeviceResponse, 0, len(devices))
+	for i := range devices {
+		rsp = append(rsp, deviceResponse(devices[i]))
+	}
+	return rsp, nil
+}
+
+func (d *deviceUsecase) DeleteDevice(ctx context.Context, principal *Principal, id string) error {
+	deviceID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidDeviceID
+	}
+	device, err := d.repo.GetDevice(ctx, deviceID)
+	if err != nil {
+		if err == repository.ErrDeviceNotFound {
+			return ErrDeviceNotFound
+		}
+		return err
+	}
+	// Devices of other accounts are not found, rather than forbidden, not to tell they exist.
+	if principal != nil && (device.OwnerID == nil || *device.OwnerID != principal.AccountID) {
+		return ErrDeviceNotFound
+	}
+	if err := d.repo.DeleteDevice(ctx, deviceID); err != nil {
+		if err == repository.ErrDeviceNotFound {
+			return ErrDeviceNotFound
+		}
+		return err
+	}
+	d.audit.Record(ctx, AuditEntry{
+		Type:    AuditDeviceRemoved,
+		Target:  "device:" + device.ID.String(),
+		Outcome: AuditOutcomeSuccess,
+		Detail:  device.MAC,
+	})
+	return nil
+}
+
+func (d *deviceUsecase) ImportDevices(ctx context.Context, r io.Reader) (*DeviceImportResponse, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDeviceImport, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["mac"]; !ok {
+		return nil, fmt.Errorf("%w: the header has no mac column", ErrInvalidDeviceImport)
+	}
+
+	now := time.Now()
+	actor := RequestInfoFromContext(ctx).Actor
+	owners := make(map[string]*uuid.UUID)
+	lines := make(map[string]int)
+	var devices []*repository.Device
+	var problems []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDeviceImport, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(devices)+len(problems) >= maxDeviceImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidDeviceImport, maxDeviceImportRows)
+		}
+		device, owner, err := importRow(columns, record)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		if owner != "" {
+			ownerID, ok := owners[owner]
+			if !ok {
+				account, err := d.accounts.GetAccount(ctx, owner)
+				if err != nil && err != repository.ErrAccountRecordNotFound {
+					return nil, err
+				}
+				if account != nil {
+					ownerID = &account.ID
+				}
+				owners[owner] = ownerID
+			}
+			if ownerID == nil {
+				problems = append(problems, fmt.Sprintf("line %d: owner %q: %v", line, owner, ErrAccountNotFound))
+				continue
+			}
+			device.OwnerID = ownerID
+		}
+		if first, ok := lines[device.MAC]; ok {
+			problems = append(problems, fmt.Sprintf("line %d: %s is also on line %d", line, device.MAC, first))
+			continue
+		}
+		lines[device.MAC] = line
+		device.ID = uuid.New()
+		device.CreatedBy = actor
+		device.CreatedAt = now
+		devices = append(devices, device)
+	}
+	if len(devices) > 0 {
+		macs := make([]string, 0, len(devices))
+		for _, device := range devices {
+			macs = append(macs, device.MAC)
+		}
+		registered, err := d.repo.ListDevicesByMAC(ctx, macs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range registered {
+			problems = append(problems, fmt.Sprintf("line %d: %s: %v", lines[registered[i].MAC], registered[i].MAC, ErrDeviceAlreadyRegistered))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDeviceImport, strings.Join(problems, "; "))
+	}
+	if len(devices) == 0 {
+		return &DeviceImportResponse{}, nil
+	}
+
+	if err := d.repo.CreateDevices(ctx, devices...); err != nil {
+		if err == repository.ErrDeviceIsDuplicated {
+			return nil, ErrDeviceAlreadyRegistered
+		}
+		return nil, err
+	}
+	d.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "devices",
+		Outcome: AuditOutcomeSuccess,
+		Detail:  fmt.Sprintf("import %d devices", len(devices)),
+	})
+	return &DeviceImportResponse{Imported: len(devices)}, nil
+}
+
+// importRow reads a device from a row of an import, and the username of its owner.
+func importRow(columns map[string]int, record []string) (*repository.Device, string, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	mac, err := NormalizeMAC(field("mac"))
+	if err != nil {
+		return nil, "", fmt.Errorf("%q: %w", field("mac"), err)
+	}
+	device := &repository.Device{MAC: mac, Description: field("description"), Role: field("role")}
+	if value := field("expires_at"); value != "" {
+		expiresAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if expiresAt, err = time.Parse("2006-01-02", value); err != nil {
+				return nil, "", fmt.Errorf("expires_at %q is not a date", value)
+			}
+		}
+		device.ExpiresAt = &expiresAt
+	}
+	if value := field("vlan"); value != "" {
+		vlan, err := strconv.Atoi(value)
+		if err != nil || vlan < 0 || vlan > 4094 {
+			return nil, "", fmt.Errorf("vlan %q is not a VLAN id", value)
+		}
+		device.VLAN = vlan
+	}
+	if len(device.Description) > 200 || len(device.Role) > 64 {
+		return nil, "", errors.New("description or role is too long")
+	}
+	return device, field("owner"), nil
+}
+
+func (d *deviceUsecase) Authorize(ctx context.Context, mac string) (*DeviceAuthorization, error) {
+	normalized, err := NormalizeMAC(mac)
+	if err != nil {
+		return nil, d.refuse(ctx, mac, "", ErrInvalidMAC)
+	}
+	device, err := d.repo.GetDeviceByMAC(ctx, normalized)
+	if err != nil {
+		if err == repository.ErrDeviceNotFound {
+			return nil, d.refuse(ctx, normalized, "", ErrDeviceNotRegistered)
+		}
+		return nil, err
+	}
+	target := "device:" + device.ID.String()
+	if device.ExpiresAt != nil && !time.Now().Before(*device.ExpiresAt) {
+		return nil, d.refuse(ctx, normalized, target, ErrDeviceExpired)
+	}
+	// A device is let on as long as the account responsible for it exists and is not locked.
+	if device.OwnerID != nil {
+		owner, err := d.accounts.GetAccountByID(ctx, *device.OwnerID)
+		if err != nil {
+			if err == repository.ErrAccountRecordNotFound {
+				return nil, d.refuse(ctx, normalized, target, ErrDeviceOwnerNotFound)
+			}
+			return nil, err
+		}
+		if owner.LockedAt != nil {
+			return nil, d.refuse(ctx, normalized, target, ErrDeviceOwnerLocked)
+		}
+	}
+
+	d.audit.Record(ctx, AuditEntry{
+		Type:    AuditDeviceMAB,
+		Actor:   normalized,
+		Target:  target,
+		Outcome: AuditOutcomeSuccess,
+	})
+	return &DeviceAuthorization{ID: device.ID, MAC: device.MAC, VLAN: device.VLAN, Role: device.Role}, nil
+}
+
+// refuse records a MAC authentication bypass that was refused.
+func (d *deviceUsecase) refuse(ctx context.Context, mac string, target string, err error) error {
+	d.audit.Record(ctx, AuditEntry{
+		Type:    AuditDeviceMAB,
+		Actor:   mac,
+		Target:  target,
+		Outcome: AuditOutcomeFailure,
+		Detail:  err.Error(),
+	})
+	return err
+}
+
+// NormalizeMAC reads a MAC address in any of the ways vendors write it: in groups of two separated by
+// colons or dashes, in groups of four separated by dots like Cisco, in halves like HP, or bare, in any
+// case. It returns it in lower case with colons, as devices are registered.
+func NormalizeMAC(mac string) (string, error) {
+	mac = strings.ToLower(strings.TrimSpace(mac))
+	var digits string
+	for _, group := range []int{2, 4, 6, 12} {
+		if len(mac) != 12+12/group-1 {
+			continue
+		}
+		digits = mac
+		if group < 12 {
+			separator := mac[group]
+			if !strings.ContainsRune(":-.", rune(separator)) {
+				return "", ErrInvalidMAC
+			}
+			var b strings.Builder
+			for i := 0; i < len(mac); i++ {
+				if (i+1)%(group+1) == 0 {
+					if mac[i] != separator {
+						return "", ErrInvalidMAC
+					}
+					continue
+				}
+				b.WriteByte(mac[i])
+			}
+			digits = b.String()
+		}
+		break
+	}
+	if _, err := hex.DecodeString(digits); err != nil || len(digits) != 12 {
+		return "", ErrInvalidMAC
+	}
+	var b strings.Builder
+	for i := 0; i < 12; i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.WriteString(digits[i : i+2])
+	}
+	return b.String(), nil
+}
+
+func deviceResponse(device repository.DeviceOwner) DeviceResponse {
+	return DeviceResponse{
+		ID:          device.ID.String(),
+		MAC:         device.MAC,
+		Owner:       device.Owner,
+		Description: device.Description,
+		VLAN:        device.VLAN,
+		Role:        device.Role,
+		ExpiresAt:   device.ExpiresAt,
+		CreatedBy:   device.CreatedBy,
+		CreatedAt:   device.CreatedAt,
+	}
+}
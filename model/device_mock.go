@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: device.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDeviceUsecase is a mock of DeviceUsecase interface.
+type MockDeviceUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeviceUsecaseMockRecorder
+}
+
+// MockDeviceUsecaseMockRecorder is the mock recorder for MockDeviceUsecase.
+type MockDeviceUsecaseMockRecorder struct {
+	mock *MockDeviceUsecase
+}
+
+// NewMockDeviceUsecase creates a new mock instance.
+func NewMockDeviceUsecase(ctrl *gomock.Controller) *MockDeviceUsecase {
+	mock := &MockDeviceUsecase{ctrl: ctrl}
+	mock.recorder = &MockDeviceUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeviceUsecase) EXPECT() *MockDeviceUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Authorize mocks base method.
+func (m *MockDeviceUsecase) Authorize(ctx context.Context, mac string) (*DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", ctx, mac)
+	ret0, _ := ret[0].(*DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize.
+func (mr *MockDeviceUsecaseMockRecorder) Authorize(ctx, mac interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockDeviceUsecase)(nil).Authorize), ctx, mac)
+}
+
+// DeleteDevice mocks base method.
+func (m *MockDeviceUsecase) DeleteDevice(ctx context.Context, principal *Principal, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDevice", ctx, principal, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDevice indicates an expected call of DeleteDevice.
+func (mr *MockDeviceUsecaseMockRecorder) DeleteDevice(ctx, principal, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDevice", reflect.TypeOf((*MockDeviceUsecase)(nil).DeleteDevice), ctx, principal, id)
+}
+
+// ImportDevices mocks base method.
+func (m *MockDeviceUsecase) ImportDevices(ctx context.Context, r io.Reader) (*DeviceImportResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportDevices", ctx, r)
+	ret0, _ := ret[0].(*DeviceImportResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportDevices indicates an expected call of ImportDevices.
+func (mr *MockDeviceUsecaseMockRecorder) ImportDevices(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportDevices", reflect.TypeOf((*MockDeviceUsecase)(nil).ImportDevices), ctx, r)
+}
+
+// ListDevices mocks base method.
+func (m *MockDeviceUsecase) ListDevices(ctx context.Context, principal *Principal) ([]DeviceResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDevices", ctx, principal)
+	ret0, _ := ret[0].([]DeviceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDevices indicates an expected call of ListDevices.
+func (mr *MockDeviceUsecaseMockRecorder) ListDevices(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDevices", reflect.TypeOf((*MockDeviceUsecase)(nil).ListDevices), ctx, principal)
+}
+
+// RegisterDevice mocks base method.
+func (m *MockDeviceUsecase) RegisterDevice(ctx context.Context, principal *Principal, req DeviceRequest) (*DeviceResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterDevice", ctx, principal, req)
+	ret0, _ := ret[0].(*DeviceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterDevice indicates an expected call of RegisterDevice.
+func (mr *MockDeviceUsecaseMockRecorder) RegisterDevice(ctx, principal, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterDevice", reflect.TypeOf((*MockDeviceUsecase)(nil).RegisterDevice), ctx, principal, req)
+}
@@ -0,0 +1,379 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeMAC(t *testing.T) {
+	testCases := []struct {
+		mac      string
+		expected string
+		err      error
+	}{
+		{mac: "00:1A:2b:3c:4D:5e", expected: "00:1a:2b:3c:4d:5e"},
+		{mac: "00-1a-2b-3c-4d-5e", expected: "00:1a:2b:3c:4d:5e"},
+		{mac: "001a.2b3c.4d5e", expected: "00:1a:2b:3c:4d:5e"},
+		{mac: "001a2b-3c4d5e", expected: "00:1a:2b:3c:4d:5e"},
+		{mac: " 001A2B3C4D5E ", expected: "00:1a:2b:3c:4d:5e"},
+		{mac: "00:1a-2b:3c:4d:5e", err: ErrInvalidMAC},
+		{mac: "00:1a:2b:3c:4d", err: ErrInvalidMAC},
+		{mac: "00:1a:2b:3c:4d:5g", err: ErrInvalidMAC},
+		{mac: "alice", err: ErrInvalidMAC},
+		{mac: "", err: ErrInvalidMAC},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mac, func(t *testing.T) {
+			mac, err := NormalizeMAC(tc.mac)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.expected, mac)
+		})
+	}
+}
+
+func TestRegisterDevice(t *testing.T) {
+	principal := &Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{ScopeDevices}}
+	past := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name       string
+		principal  *Principal
+		req        DeviceRequest
+		buildStubs func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase)
+		check      func(t *testing.T, rsp *DeviceResponse, err error)
+	}{
+		{
+			name:      "OwnDevice",
+			principal: principal,
+			req:       DeviceRequest{MAC: "001A.2B3C.4D5E", Description: "printer"},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().CountDevices(gomock.Any(), principal.AccountID).Return(int64(1), nil)
+				repo.EXPECT().CreateDevices(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, devices ...*repository.Device) error {
+					require.Len(t, devices, 1)
+					require.Equal(t, "00:1a:2b:3c:4d:5e", devices[0].MAC)
+					require.Equal(t, principal.AccountID, *devices[0].OwnerID)
+					return nil
+				})
+				audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+					require.Equal(t, AuditDeviceRegistered, entry.Type)
+				})
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "00:1a:2b:3c:4d:5e", rsp.MAC)
+				require.Equal(t, "alice", rsp.Owner)
+			},
+		},
+		{
+			name:      "OwnDeviceWithVLAN",
+			principal: principal,
+			req:       DeviceRequest{MAC: "00:1a:2b:3c:4d:5e", VLAN: 20},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().CreateDevices(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.Equal(t, ErrDeviceAdminOnly, err)
+			},
+		},
+		{
+			name:      "TooManyDevices",
+			principal: principal,
+			req:       DeviceRequest{MAC: "00:1a:2b:3c:4d:5e"},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().CountDevices(gomock.Any(), principal.AccountID).Return(int64(maxOwnedDevices), nil)
+				repo.EXPECT().CreateDevices(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.Equal(t, ErrTooManyDevices, err)
+			},
+		},
+		{
+			name: "AdminWithOwner",
+			req:  DeviceRequest{MAC: "00:1a:2b:3c:4d:5e", Owner: "bob", VLAN: 30, Role: "iot"},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				bob := &repository.Account{ID: uuid.New(), Username: "bob"}
+				accounts.EXPECT().GetAccount(gomock.Any(), "bob").Return(bob, nil)
+				repo.EXPECT().CreateDevices(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, devices ...*repository.Device) error {
+					require.Equal(t, bob.ID, *devices[0].OwnerID)
+					require.Equal(t, 30, devices[0].VLAN)
+					require.Equal(t, "iot", devices[0].Role)
+					return nil
+				})
+				audit.EXPECT().Record(gomock.Any(), gomock.Any())
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "bob", rsp.Owner)
+				require.Equal(t, 30, rsp.VLAN)
+			},
+		},
+		{
+			name: "OwnerNotFound",
+			req:  DeviceRequest{MAC: "00:1a:2b:3c:4d:5e", Owner: "nobody"},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				accounts.EXPECT().GetAccount(gomock.Any(), "nobody").Return(nil, repository.ErrAccountRecordNotFound)
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.Equal(t, ErrAccountNotFound, err)
+			},
+		},
+		{
+			name: "Duplicated",
+			req:  DeviceRequest{MAC: "00:1a:2b:3c:4d:5e"},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().CreateDevices(gomock.Any(), gomock.Any()).Return(repository.ErrDeviceIsDuplicated)
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.Equal(t, ErrDeviceAlreadyRegistered, err)
+			},
+		},
+		{
+			name: "ExpiryInThePast",
+			req:  DeviceRequest{MAC: "00:1a:2b:3c:4d:5e", ExpiresAt: &past},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.Equal(t, ErrInvalidDeviceExpiry, err)
+			},
+		},
+		{
+			name: "InvalidMAC",
+			req:  DeviceRequest{MAC: "printer"},
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+			},
+			check: func(t *testing.T, rsp *DeviceResponse, err error) {
+				require.Equal(t, ErrInvalidMAC, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockDeviceRepository(ctrl)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			audit := NewMockAuditUsecase(ctrl)
+			tc.buildStubs(repo, accounts, audit)
+
+			rsp, err := NewDeviceUsecase(repo, accounts, audit).RegisterDevice(context.Background(), tc.principal, tc.req)
+			tc.check(t, rsp, err)
+		})
+	}
+}
+
+func TestDeleteDevice(t *testing.T) {
+	principal := &Principal{AccountID: uuid.New(), Username: "alice"}
+	other := uuid.New()
+	device := &repository.Device{ID: uuid.New(), MAC: "00:1a:2b:3c:4d:5e", OwnerID: &other}
+
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockDeviceRepository(ctrl)
+	audit := NewMockAuditUsecase(ctrl)
+	usecase := NewDeviceUsecase(repo, nil, audit)
+
+	repo.EXPECT().GetDevice(gomock.Any(), device.ID).Return(device, nil).Times(2)
+	repo.EXPECT().DeleteDevice(gomock.Any(), device.ID).Return(nil)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+		require.Equal(t, AuditDeviceRemoved, entry.Type)
+	})
+
+	require.Equal(t, ErrDeviceNotFound, usecase.DeleteDevice(context.Background(), principal, device.ID.String()))
+	require.NoError(t, usecase.DeleteDevice(context.Background(), nil, device.ID.String()))
+	require.Equal(t, ErrInvalidDeviceID, usecase.DeleteDevice(context.Background(), nil, "printer"))
+}
+
+func TestImportDevices(t *testing.T) {
+	bob := &repository.Account{ID: uuid.New(), Username: "bob"}
+
+	testCases := []struct {
+		name       string
+		csv        string
+		buildStubs func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase)
+		check      func(t *testing.T, rsp *DeviceImportResponse, err error)
+	}{
+		{
+			name: "OK",
+			csv: "MAC,owner,description,expires_at,vlan,role\n" +
+				"00:1a:2b:3c:4d:5e,bob,printer,2099-01-01,20,iot\n" +
+				"001a.2b3c.4d5f,bob,camera,,,\n" +
+				"00-1a-2b-3c-4d-60,,,,,\n",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				accounts.EXPECT().GetAccount(gomock.Any(), "bob").Return(bob, nil)
+				repo.EXPECT().ListDevicesByMAC(gomock.Any(), []string{"00:1a:2b:3c:4d:5e", "00:1a:2b:3c:4d:5f", "00:1a:2b:3c:4d:60"}).Return(nil, nil)
+				repo.EXPECT().CreateDevices(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, devices ...*repository.Device) error {
+						require.Equal(t, bob.ID, *devices[0].OwnerID)
+						require.Equal(t, 20, devices[0].VLAN)
+						require.Equal(t, "iot", devices[0].Role)
+						require.NotNil(t, devices[0].ExpiresAt)
+						require.Nil(t, devices[2].OwnerID)
+						return nil
+					})
+				audit.EXPECT().Record(gomock.Any(), gomock.Any())
+			},
+			check: func(t *testing.T, rsp *DeviceImportResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 3, rsp.Imported)
+			},
+		},
+		{
+			name: "InvalidRows",
+			csv: "mac,owner,vlan\n" +
+				"00:1a:2b:3c:4d:5e,nobody,\n" +
+				"printer,,\n" +
+				"00:1a:2b:3c:4d:5f,,5000\n" +
+				"00:1A:2B:3C:4D:5E,,\n",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				accounts.EXPECT().GetAccount(gomock.Any(), "nobody").Return(nil, repository.ErrAccountRecordNotFound)
+				repo.EXPECT().ListDevicesByMAC(gomock.Any(), gomock.Any()).Return(nil, nil)
+				repo.EXPECT().CreateDevices(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp *DeviceImportResponse, err error) {
+				require.True(t, errors.Is(err, ErrInvalidDeviceImport))
+				for _, line := range []string{"line 2:", "line 3:", "line 4:"} {
+					require.True(t, strings.Contains(err.Error(), line), err.Error())
+				}
+				require.False(t, strings.Contains(err.Error(), "line 5:"), err.Error())
+			},
+		},
+		{
+			name: "AlreadyRegistered",
+			csv:  "mac\n00:1a:2b:3c:4d:5e\n00:1a:2b:3c:4d:5e\n",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().ListDevicesByMAC(gomock.Any(), []string{"00:1a:2b:3c:4d:5e"}).
+					Return([]repository.Device{{ID: uuid.New(), MAC: "00:1a:2b:3c:4d:5e"}}, nil)
+			},
+			check: func(t *testing.T, rsp *DeviceImportResponse, err error) {
+				require.True(t, errors.Is(err, ErrInvalidDeviceImport))
+				require.True(t, strings.Contains(err.Error(), "line 3: 00:1a:2b:3c:4d:5e is also on line 2"), err.Error())
+				require.True(t, strings.Contains(err.Error(), "line 2: 00:1a:2b:3c:4d:5e: "+ErrDeviceAlreadyRegistered.Error()), err.Error())
+			},
+		},
+		{
+			name: "NoMACColumn",
+			csv:  "owner\nbob\n",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository, audit *MockAuditUsecase) {
+			},
+			check: func(t *testing.T, rsp *DeviceImportResponse, err error) {
+				require.True(t, errors.Is(err, ErrInvalidDeviceImport))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockDeviceRepository(ctrl)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			audit := NewMockAuditUsecase(ctrl)
+			tc.buildStubs(repo, accounts, audit)
+
+			rsp, err := NewDeviceUsecase(repo, accounts, audit).ImportDevices(context.Background(), strings.NewReader(tc.csv))
+			tc.check(t, rsp, err)
+		})
+	}
+}
+
+func TestAuthorizeDevice(t *testing.T) {
+	owner := uuid.New()
+	past := time.Now().Add(-time.Hour)
+	device := repository.Device{ID: uuid.New(), MAC: "00:1a:2b:3c:4d:5e", OwnerID: &owner, VLAN: 20, Role: "iot"}
+
+	testCases := []struct {
+		name       string
+		mac        string
+		buildStubs func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository)
+		outcome    string
+		check      func(t *testing.T, rsp *DeviceAuthorization, err error)
+	}{
+		{
+			name: "OK",
+			mac:  "001A2B3C4D5E",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository) {
+				repo.EXPECT().GetDeviceByMAC(gomock.Any(), "00:1a:2b:3c:4d:5e").Return(&device, nil)
+				accounts.EXPECT().GetAccountByID(gomock.Any(), owner).Return(&repository.Account{ID: owner}, nil)
+			},
+			outcome: AuditOutcomeSuccess,
+			check: func(t *testing.T, rsp *DeviceAuthorization, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 20, rsp.VLAN)
+				require.Equal(t, "iot", rsp.Role)
+			},
+		},
+		{
+			name: "NotRegistered",
+			mac:  "00:1a:2b:3c:4d:5f",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository) {
+				repo.EXPECT().GetDeviceByMAC(gomock.Any(), "00:1a:2b:3c:4d:5f").Return(nil, repository.ErrDeviceNotFound)
+			},
+			outcome: AuditOutcomeFailure,
+			check: func(t *testing.T, rsp *DeviceAuthorization, err error) {
+				require.Equal(t, ErrDeviceNotRegistered, err)
+			},
+		},
+		{
+			name: "Expired",
+			mac:  "00:1a:2b:3c:4d:5e",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository) {
+				expired := device
+				expired.ExpiresAt = &past
+				repo.EXPECT().GetDeviceByMAC(gomock.Any(), "00:1a:2b:3c:4d:5e").Return(&expired, nil)
+			},
+			outcome: AuditOutcomeFailure,
+			check: func(t *testing.T, rsp *DeviceAuthorization, err error) {
+				require.Equal(t, ErrDeviceExpired, err)
+			},
+		},
+		{
+			name: "OwnerDeleted",
+			mac:  "00:1a:2b:3c:4d:5e",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository) {
+				repo.EXPECT().GetDeviceByMAC(gomock.Any(), "00:1a:2b:3c:4d:5e").Return(&device, nil)
+				accounts.EXPECT().GetAccountByID(gomock.Any(), owner).Return(nil, repository.ErrAccountRecordNotFound)
+			},
+			outcome: AuditOutcomeFailure,
+			check: func(t *testing.T, rsp *DeviceAuthorization, err error) {
+				require.Equal(t, ErrDeviceOwnerNotFound, err)
+			},
+		},
+		{
+			name: "OwnerLocked",
+			mac:  "00:1a:2b:3c:4d:5e",
+			buildStubs: func(repo *repository.MockDeviceRepository, accounts *repository.MockAccountRepository) {
+				repo.EXPECT().GetDeviceByMAC(gomock.Any(), "00:1a:2b:3c:4d:5e").Return(&device, nil)
+				accounts.EXPECT().GetAccountByID(gomock.Any(), owner).Return(&repository.Account{ID: owner, LockedAt: &past}, nil)
+			},
+			outcome: AuditOutcomeFailure,
+			check: func(t *testing.T, rsp *DeviceAuthorization, err error) {
+				require.Nil(t, rsp)
+				require.Equal(t, ErrDeviceOwnerLocked, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockDeviceRepository(ctrl)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			audit := NewMockAuditUsecase(ctrl)
+			tc.buildStubs(repo, accounts)
+			audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+				require.Equal(t, AuditDeviceMAB, entry.Type)
+				require.Equal(t, tc.outcome, entry.Outcome)
+			})
+
+			rsp, err := NewDeviceUsecase(repo, accounts, audit).Authorize(context.Background(), tc.mac)
+			tc.check(t, rsp, err)
+		})
+	}
+}
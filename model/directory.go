@@ -0,0 +1,211 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrDirectoryUserNotFound = errors.New("User not found in the directory")
+
+// DirectoryUser is a user as an external directory describes it.
+type DirectoryUser struct {
+	Username string
+	DN       string
+	Email    string
+	Roles    []string
+}
+
+// PasswordAuthenticator checks passwords kept outside the service. LoginAccount consults it for the
+// accounts mirrored from it and for usernames the service does not know yet.
+type PasswordAuthenticator interface {
+	// Authenticate returns the user when the password is right. It returns ErrDirectoryUserNotFound when
+	// there is no such user and ErrLoginWrongPassword when the password is wrong.
+	Authenticate(ctx context.Context, username string, password string) (*DirectoryUser, error)
+}
+
+// Directory is a PasswordAuthenticator whose users can be listed, so they can be mirrored into accounts.
+type Directory interface {
+	PasswordAuthenticator
+	Users(ctx context.Context) ([]DirectoryUser, error)
+}
+
+type DirectorySyncResult struct {
+	Created int
+	Updated int
+	Deleted int
+}
+
+// DirectorySync mirrors the users of a directory into accounts: it creates the missing ones, updates
+// their roles and deletes the accounts of users removed from the directory.
+type DirectorySync interface {
+	Sync(ctx context.Context) (*DirectorySyncResult, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type directorySync struct {
+	directory Directory
+	repo      repository.AccountRepository
+	audit     AuditUsecase
+}
+
+func NewDirectorySync(directory Directory, repo repository.AccountRepository, audit AuditUsecase) DirectorySync {
+	return &directorySync{
+		directory: directory,
+		repo:      repo,
+		audit:     audit,
+	}
+}
+
+func (s *directorySync) Sync(ctx context.Context) (*DirectorySyncResult, error) {
+	users, err := s.directory.Users(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.ListAccountsBySource(ctx, repository.AccountSourceLDAP)
+	if err != nil {
+		return nil, err
+	}
+	mirrored := make(map[string]*repository.Account, len(accounts))
+	for i := range accounts {
+		mirrored[accounts[i].Username] = &accounts[i]
+	}
+
+	result := &DirectorySyncResult{}
+	listed := make(map[string]bool, len(users))
+	for _, user := range users {
+		listed[user.Username] = true
+		if account, ok := mirrored[user.Username]; ok {
+			changed, err := updateDirectoryRoles(ctx, s.repo, s.audit, account, &user)
+			if err != nil {
+				return nil, err
+			}
+			if changed {
+				result.Updated++
+			}
+			continue
+		}
+		if _, err := createDirectoryAccount(ctx, s.repo, s.audit, &user); err != nil {
+			if err == repository.ErrAccountIsDuplicated {
+				log.Warn().Str("username", user.Username).Msg("directory user has the name of a local account, skipped")
+				continue
+			}
+			return nil, err
+		}
+		result.Created++
+	}
+
+	// An empty directory is more likely a wrong filter than everyone leaving at once.
+	if len(users) == 0 && len(accounts) > 0 {
+		log.Warn().Int("accounts", len(accounts)).Msg("directory returned no users, mirrored accounts are kept")
+		return result, nil
+	}
+	for i := range accounts {
+		account := &accounts[i]
+		if listed[account.Username] {
+			continue
+		}
+		message, err := NewOutboxMessage(NewAccountEvent(EventAccountDeleted, account, ErrDirectoryUserNotFound.Error()))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.DeleteAccount(ctx, account, message); err != nil {
+			if err == repository.ErrAccountRecordNotFound {
+				continue
+			}
+			return nil, err
+		}
+		s.audit.Record(ctx, AuditEntry{
+			Type:    AuditAccountDeleted,
+			Target:  account.Username,
+			Outcome: AuditOutcomeSuccess,
+			Detail:  "source=" + repository.AccountSourceLDAP,
+		})
+		result.Deleted++
+	}
+	return result, nil
+}
+
+func (s *directorySync) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.Sync(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to sync directory users")
+				continue
+			}
+			log.Info().Int("created", result.Created).Int("updated", result.Updated).Int("deleted", result.Deleted).
+				Msg("synced directory users")
+		}
+	}
+}
+
+// createDirectoryAccount creates the account mirroring a directory user. It has no password, the
+// directory checks it.
+func createDirectoryAccount(ctx context.Context, repo repository.AccountRepository, audit AuditUsecase,
+	user *DirectoryUser) (*repository.Account, error) {
+	account := &repository.Account{
+		ID:       uuid.New(),
+		Username: user.Username,
+		Type:     repository.AccountTypeUser,
+		Roles:    joinRoles(user.Roles),
+		Source:   repository.AccountSourceLDAP,
+	}
+	message, err := NewOutboxMessage(NewAccountEvent(EventAccountCreated, account, ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.CreateAccount(ctx, account, message); err != nil {
+		return nil, err
+	}
+	audit.Record(ctx, AuditEntry{
+		Type:    AuditAccountCreated,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "source=" + repository.AccountSourceLDAP,
+	})
+	return account, nil
+}
+
+// updateDirectoryRoles gives the account the roles the directory has for the user.
+func updateDirectoryRoles(ctx context.Context, repo repository.AccountRepository, audit AuditUsecase,
+	account *repository.Account, user *DirectoryUser) (bool, error) {
+	roles := joinRoles(user.Roles)
+	if account.Roles == roles {
+		return false, nil
+	}
+	account.Roles = roles
+	if err := repo.UpdateAccountRoles(ctx, account); err != nil {
+		return false, err
+	}
+	audit.Record(ctx, AuditEntry{
+		Type:    AuditRolesChanged,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "roles=" + roles,
+	})
+	return true, nil
+}
+
+// joinRoles stores roles sorted and without duplicates, so the same roles always compare equal.
+func joinRoles(roles []string) string {
+	unique := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if role != "" && !containsString(unique, role) {
+			unique = append(unique, role)
+		}
+	}
+	sort.Strings(unique)
+	return strings.Join(unique, " ")
+}
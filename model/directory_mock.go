@@ -0,0 +1,154 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: directory.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPasswordAuthenticator is a mock of PasswordAuthenticator interface.
+type MockPasswordAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordAuthenticatorMockRecorder
+}
+
+// MockPasswordAuthenticatorMockRecorder is the mock recorder for MockPasswordAuthenticator.
+type MockPasswordAuthenticatorMockRecorder struct {
+	mock *MockPasswordAuthenticator
+}
+
+// NewMockPasswordAuthenticator creates a new mock instance.
+func NewMockPasswordAuthenticator(ctrl *gomock.Controller) *MockPasswordAuthenticator {
+	mock := &MockPasswordAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockPasswordAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordAuthenticator) EXPECT() *MockPasswordAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockPasswordAuthenticator) Authenticate(ctx context.Context, username, password string) (*DirectoryUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, username, password)
+	ret0, _ := ret[0].(*DirectoryUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockPasswordAuthenticatorMockRecorder) Authenticate(ctx, username, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockPasswordAuthenticator)(nil).Authenticate), ctx, username, password)
+}
+
+// MockDirectory is a mock of Directory interface.
+type MockDirectory struct {
+	ctrl     *gomock.Controller
+	recorder *MockDirectoryMockRecorder
+}
+
+// MockDirectoryMockRecorder is the mock recorder for MockDirectory.
+type MockDirectoryMockRecorder struct {
+	mock *MockDirectory
+}
+
+// NewMockDirectory creates a new mock instance.
+func NewMockDirectory(ctrl *gomock.Controller) *MockDirectory {
+	mock := &MockDirectory{ctrl: ctrl}
+	mock.recorder = &MockDirectoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDirectory) EXPECT() *MockDirectoryMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockDirectory) Authenticate(ctx context.Context, username, password string) (*DirectoryUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, username, password)
+	ret0, _ := ret[0].(*DirectoryUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockDirectoryMockRecorder) Authenticate(ctx, username, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockDirectory)(nil).Authenticate), ctx, username, password)
+}
+
+// Users mocks base method.
+func (m *MockDirectory) Users(ctx context.Context) ([]DirectoryUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Users", ctx)
+	ret0, _ := ret[0].([]DirectoryUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Users indicates an expected call of Users.
+func (mr *MockDirectoryMockRecorder) Users(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Users", reflect.TypeOf((*MockDirectory)(nil).Users), ctx)
+}
+
+// MockDirectorySync is a mock of DirectorySync interface.
+type MockDirectorySync struct {
+	ctrl     *gomock.Controller
+	recorder *MockDirectorySyncMockRecorder
+}
+
+// MockDirectorySyncMockRecorder is the mock recorder for MockDirectorySync.
+type MockDirectorySyncMockRecorder struct {
+	mock *MockDirectorySync
+}
+
+// NewMockDirectorySync creates a new mock instance.
+func NewMockDirectorySync(ctrl *gomock.Controller) *MockDirectorySync {
+	mock := &MockDirectorySync{ctrl: ctrl}
+	mock.recorder = &MockDirectorySyncMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDirectorySync) EXPECT() *MockDirectorySyncMockRecorder {
+	return m.recorder
+}
+
+// Run mocks base method.
+func (m *MockDirectorySync) Run(ctx context.Context, interval time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx, interval)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockDirectorySyncMockRecorder) Run(ctx, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockDirectorySync)(nil).Run), ctx, interval)
+}
+
+// Sync mocks base method.
+func (m *MockDirectorySync) Sync(ctx context.Context) (*DirectorySyncResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sync", ctx)
+	ret0, _ := ret[0].(*DirectorySyncResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sync indicates an expected call of Sync.
+func (mr *MockDirectorySyncMockRecorder) Sync(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sync", reflect.TypeOf((*MockDirectorySync)(nil).Sync), ctx)
+}
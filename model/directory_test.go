@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectorySync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDirectory := NewMockDirectory(ctrl)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	sync := NewDirectorySync(mockDirectory, mockRepo, mockAudit)
+
+	alice := repository.Account{ID: uuid.New(), Username: "alice", Roles: "staff", Source: repository.AccountSourceLDAP}
+	bob := repository.Account{ID: uuid.New(), Username: "bob", Source: repository.AccountSourceLDAP}
+	carol := repository.Account{ID: uuid.New(), Username: "carol", Roles: "admin", Source: repository.AccountSourceLDAP}
+	mockDirectory.EXPECT().Users(gomock.Any()).Return([]DirectoryUser{
+		{Username: "alice", Roles: []string{"staff", "admin"}},
+		{Username: "bob"},
+		{Username: "dave", Roles: []string{"staff"}},
+		{Username: "root"},
+	}, nil)
+	mockRepo.EXPECT().ListAccountsBySource(gomock.Any(), repository.AccountSourceLDAP).
+		Return([]repository.Account{alice, bob, carol}, nil)
+
+	mockRepo.EXPECT().UpdateAccountRoles(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, account *repository.Account) error {
+			require.Equal(t, "alice", account.Username)
+			require.Equal(t, "admin staff", account.Roles)
+			return nil
+		})
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditRolesChanged, AuditOutcomeSuccess))
+	mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), outboxMessageOf(EventAccountCreated)).
+		DoAndReturn(func(_ context.Context, account *repository.Account, _ ...*repository.OutboxMessage) error {
+			if account.Username == "root" {
+				return repository.ErrAccountIsDuplicated
+			}
+			require.Equal(t, "dave", account.Username)
+			require.Equal(t, repository.AccountSourceLDAP, account.Source)
+			return nil
+		}).Times(2)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeSuccess))
+	mockRepo.EXPECT().DeleteAccount(gomock.Any(), gomock.Any(), outboxMessageOf(EventAccountDeleted)).
+		DoAndReturn(func(_ context.Context, account *repository.Account, _ ...*repository.OutboxMessage) error {
+			require.Equal(t, carol.ID, account.ID)
+			return nil
+		})
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountDeleted, AuditOutcomeSuccess))
+
+	result, err := sync.Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &DirectorySyncResult{Created: 1, Updated: 1, Deleted: 1}, result)
+}
+
+func TestDirectorySyncKeepsAccountsOfEmptyDirectory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDirectory := NewMockDirectory(ctrl)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	sync := NewDirectorySync(mockDirectory, mockRepo, NewMockAuditUsecase(ctrl))
+
+	mockDirectory.EXPECT().Users(gomock.Any()).Return(nil, nil)
+	mockRepo.EXPECT().ListAccountsBySource(gomock.Any(), repository.AccountSourceLDAP).
+		Return([]repository.Account{{ID: uuid.New(), Username: "alice", Source: repository.AccountSourceLDAP}}, nil)
+	mockRepo.EXPECT().DeleteAccount(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	result, err := sync.Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &DirectorySyncResult{}, result)
+}
+
+func TestDirectorySyncDirectoryDown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDirectory := NewMockDirectory(ctrl)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	sync := NewDirectorySync(mockDirectory, mockRepo, NewMockAuditUsecase(ctrl))
+
+	down := errors.New("connection refused")
+	mockDirectory.EXPECT().Users(gomock.Any()).Return(nil, down)
+	mockRepo.EXPECT().ListAccountsBySource(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := sync.Sync(context.Background())
+	require.ErrorIs(t, err, down)
+}
+
+func TestJoinRoles(t *testing.T) {
+	require.Equal(t, "", joinRoles(nil))
+	require.Equal(t, "admin staff", joinRoles([]string{"staff", "admin", "staff", ""}))
+}
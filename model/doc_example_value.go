@@ -29,3 +29,12 @@ type DocResponseTooManyRequest struct {
 	Success bool   `json:"success" example:"false"`
 	Reason  string `json:"reason" example:"too many failed login attempt, please try it later"`
 }
+
+type DocResponseDenied struct {
+	Success bool   `json:"success" example:"false"`
+	Reason  string `json:"reason" example:"Username is reserved"`
+}
+
+type DocResponseError struct {
+	Err string `json:"err" example:"unauthorized"`
+}
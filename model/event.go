@@ -0,0 +1,56 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	EventAccountCreated        = "account.created"
+	EventAccountLoginSucceeded = "account.login_succeeded"
+	EventAccountLoginFailed    = "account.login_failed"
+	EventAccountLocked         = "account.locked"
+	EventAccountDeleted        = "account.deleted"
+)
+
+var AccountEventTypes = []string{
+	EventAccountCreated,
+	EventAccountLoginSucceeded,
+	EventAccountLoginFailed,
+	EventAccountLocked,
+	EventAccountDeleted,
+}
+
+// Event is an account lifecycle event as delivered to subscribers.
+type Event struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Data       AccountEventData `json:"data"`
+}
+
+type AccountEventData struct {
+	AccountID string `json:"account_id"`
+	Username  string `json:"username"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+func NewAccountEvent(eventType string, account *repository.Account, reason string) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data: AccountEventData{
+			AccountID: account.ID.String(),
+			Username:  account.Username,
+			Reason:    reason,
+		},
+	}
+}
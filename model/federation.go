@@ -0,0 +1,672 @@
+package model
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// federationFlowAudience keeps a flow cookie from being accepted as any other token we sign, and
+// federationLinkAudience does the same for the token that starts linking an identity.
+const (
+	federationFlowAudience = "federation"
+	federationLinkAudience = "federation-link"
+)
+
+const (
+	defaultUsernameClaim = "preferred_username"
+	defaultEmailClaim    = "email"
+)
+
+var (
+	federationFlowTTL       = 10 * time.Minute
+	federationLinkTTL       = 5 * time.Minute
+	federationHTTPTimeout   = 10 * time.Second
+	maxFederationResponse   = int64(1 << 20)
+	federationUsernameTries = 3
+	// federationSuffixLength is the length of the random suffix added to a username that is taken.
+	federationSuffixLength = 6
+	defaultFederationScope = []string{ScopeOpenID, "email", ScopeProfile}
+)
+
+var (
+	ErrFederationProviderNotFound = errors.New("Identity provider not found")
+	ErrInvalidFederationState     = errors.New("The sign-in request is invalid or expired, please try again")
+	ErrFederationDenied           = errors.New("The identity provider did not sign you in")
+	ErrFederationFailed           = errors.New("Signing in with the identity provider failed")
+	ErrIdentityLinkedElsewhere    = errors.New("The identity is already linked to another account")
+	ErrIdentityEmailConflict      = errors.New("An account with this email address already exists, sign in to it and link the identity provider from its login methods")
+)
+
+// OIDCProviderConfig configures an external OpenID Connect provider users can sign in with.
+// UsernameClaim names the claim the username of a new account is derived from, EmailClaim the claim
+// stored with the identity. With LinkByEmail, a first sign-in whose verified email address matches an
+// identity of an existing account is linked to that account; only set it for providers trusted to
+// verify addresses.
+type OIDCProviderConfig struct {
+	Name          string
+	DisplayName   string
+	DiscoveryURL  string
+	ClientID      string
+	ClientSecret  string
+	Scopes        []string
+	UsernameClaim string
+	EmailClaim    string
+	LinkByEmail   bool
+}
+
+type FederationUsecase interface {
+	Providers() []FederationProvider
+	// Start returns the URL of the provider to send the browser to, and the flow to keep in a cookie
+	// until the browser comes back to the callback. link is a token from LinkURL, or empty for a login.
+	Start(ctx context.Context, provider string, next string, link string) (string, string, error)
+	Finish(ctx context.Context, provider string, req FederationCallbackRequest, flow string) (*FederationLogin, error)
+	// LinkURL returns the URL that links the identity of whoever signs in at provider to the account.
+	// It works for a few minutes.
+	LinkURL(ctx context.Context, provider string, accountID uuid.UUID) (string, error)
+}
+
+type federationUsecase struct {
+	providers map[string]*oidcProvider
+	names     []string
+	repo      repository.IdentityRepository
+	accounts  repository.AccountRepository
+	audit     AuditUsecase
+	events    EventPublisher
+	signer    TokenSigner
+	issuer    string
+}
+
+// federationFlowClaims carry the state of a login in flight through the browser. They are signed, so
+// the callback knows it started the login and with which nonce and PKCE verifier.
+type federationFlowClaims struct {
+	jwt.RegisteredClaims
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next,omitempty"`
+	// LinkAccount is the account the identity is linked to, set when the flow links rather than logs in.
+	LinkAccount string `json:"link_account,omitempty"`
+}
+
+// federationLinkClaims are the claims of a link token, Subject is the account to link to.
+type federationLinkClaims struct {
+	jwt.RegisteredClaims
+	Provider string `json:"provider"`
+}
+
+// NewFederationUsecase signs users in with external OpenID Connect providers. Someone signing in for
+// the first time gets an account provisioned and linked to their identity at the provider.
+func NewFederationUsecase(providers []OIDCProviderConfig, repo repository.IdentityRepository, accounts repository.AccountRepository,
+	audit AuditUsecase, events EventPublisher, signer TokenSigner, issuer string) FederationUsecase {
+	f := &federationUsecase{
+		providers: make(map[string]*oidcProvider, len(providers)),
+		repo:      repo,
+		accounts:  accounts,
+		audit:     audit,
+		events:    events,
+		signer:    signer,
+		issuer:    issuer,
+	}
+	for _, config := range providers {
+		if config.DisplayName == "" {
+			config.DisplayName = config.Name
+		}
+		if len(config.Scopes) == 0 {
+			config.Scopes = defaultFederationScope
+		}
+		if !containsString(config.Scopes, ScopeOpenID) {
+			config.Scopes = append([]string{ScopeOpenID}, config.Scopes...)
+		}
+		if config.UsernameClaim == "" {
+			config.UsernameClaim = defaultUsernameClaim
+		}
+		if config.EmailClaim == "" {
+			config.EmailClaim = defaultEmailClaim
+		}
+		f.providers[config.Name] = &oidcProvider{
+			config: config,
+			client: &http.Client{Timeout: federationHTTPTimeout},
+		}
+		f.names = append(f.names, config.Name)
+	}
+	return f
+}
+
+func (f *federationUsecase) Providers() []FederationProvider {
+	providers := make([]FederationProvider, 0, len(f.names))
+	for _, name := range f.names {
+		providers = append(providers, FederationProvider{Name: name, DisplayName: f.providers[name].config.DisplayName})
+	}
+	return providers
+}
+
+func (f *federationUsecase) Start(ctx context.Context, name string, next string, link string) (string, string, error) {
+	provider, ok := f.providers[name]
+	if !ok {
+		return "", "", ErrFederationProviderNotFound
+	}
+	var linkAccount string
+	if link != "" {
+		claims := &federationLinkClaims{}
+		if err := f.signer.Verify(link, claims); err != nil || !claims.VerifyAudience(federationLinkAudience, true) ||
+			claims.Issuer != f.issuer || claims.Provider != name {
+			return "", "", ErrInvalidFederationState
+		}
+		linkAccount = claims.Subject
+	}
+	metadata, err := provider.discover(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("provider", name).Msg("identity provider discovery failed")
+		return "", "", ErrFederationFailed
+	}
+
+	secrets := make([]string, 3)
+	for i := range secrets {
+		if secrets[i], err = util.RandomToken(oauthTokenBytes); err != nil {
+			return "", "", err
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+	now := time.Now()
+	flow, err := f.signer.Sign(federationFlowClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    f.issuer,
+			Audience:  jwt.ClaimStrings{federationFlowAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(federationFlowTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Provider:    name,
+		State:       state,
+		Nonce:       nonce,
+		Verifier:    verifier,
+		Next:        localPath(next),
+		LinkAccount: linkAccount,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", err
+	}
+	query := authURL.Query()
+	query.Set("response_type", oauthResponseTypeCode)
+	query.Set("client_id", provider.config.ClientID)
+	query.Set("redirect_uri", f.redirectURI(name))
+	query.Set("scope", strings.Join(provider.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", pkceChallenge(verifier))
+	query.Set("code_challenge_method", pkceMethodS256)
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), flow, nil
+}
+
+// Finish completes a login when the provider sends the browser back: it checks the flow started
+// here, exchanges the code for an ID token, verifies it and signs in to the linked account.
+func (f *federationUsecase) Finish(ctx context.Context, name string, req FederationCallbackRequest, flow string) (*FederationLogin, error) {
+	provider, ok := f.providers[name]
+	if !ok {
+		return nil, ErrFederationProviderNotFound
+	}
+	claims := &federationFlowClaims{}
+	if err := f.signer.Verify(flow, claims); err != nil || !claims.VerifyAudience(federationFlowAudience, true) ||
+		claims.Provider != name || subtle.ConstantTimeCompare([]byte(claims.State), []byte(req.State)) != 1 {
+		return nil, ErrInvalidFederationState
+	}
+	if req.Error != "" {
+		f.recordFailure(ctx, name, req.Error+" "+req.ErrorDescription)
+		return nil, ErrFederationDenied
+	}
+
+	idClaims, err := provider.authenticate(ctx, req.Code, claims.Verifier, f.redirectURI(name), claims.Nonce)
+	if err != nil {
+		log.Error().Err(err).Str("provider", name).Msg("federated login failed")
+		f.recordFailure(ctx, name, err.Error())
+		return nil, ErrFederationFailed
+	}
+	subject, _ := idClaims["sub"].(string)
+	email := stringClaim(idClaims, provider.config.EmailClaim)
+	if claims.LinkAccount != "" {
+		return f.link(ctx, name, claims, subject, email)
+	}
+
+	login := &FederationLogin{Provider: name, Next: claims.Next}
+	var account *repository.Account
+	identity, err := f.repo.GetIdentity(ctx, name, subject)
+	switch {
+	case err == nil:
+		if account, err = f.accounts.GetAccountByID(ctx, identity.AccountID); err != nil {
+			return nil, err
+		}
+		if err := f.repo.TouchIdentity(ctx, identity.ID, time.Now()); err != nil {
+			log.Warn().Err(err).Str("provider", name).Msg("failed to record identity login")
+		}
+	case errors.Is(err, repository.ErrIdentityNotFound):
+		if account, err = f.matchEmail(ctx, provider, subject, email, idClaims); err != nil {
+			return nil, err
+		}
+		if account == nil {
+			if account, login.Provisioned, err = f.provision(ctx, provider, subject, email, idClaims); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, err
+	}
+	if account.LockedAt != nil {
+		f.audit.Record(ctx, AuditEntry{
+			Type:    AuditLoginDenied,
+			Target:  account.Username,
+			Outcome: AuditOutcomeFailure,
+			Detail:  "provider=" + name + " " + ErrLoginAccountLocked.Error(),
+		})
+		return nil, ErrLoginAccountLocked
+	}
+
+	f.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginSucceeded,
+		Actor:   account.Username,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "provider=" + name,
+	})
+	f.events.Publish(ctx, NewAccountEvent(EventAccountLoginSucceeded, account, ""))
+	login.Username = account.Username
+	return login, nil
+}
+
+func (f *federationUsecase) LinkURL(ctx context.Context, name string, accountID uuid.UUID) (string, error) {
+	if _, ok := f.providers[name]; !ok {
+		return "", ErrFederationProviderNotFound
+	}
+	now := time.Now()
+	link, err := f.signer.Sign(federationLinkClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    f.issuer,
+			Subject:   accountID.String(),
+			Audience:  jwt.ClaimStrings{federationLinkAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(federationLinkTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Provider: name,
+	})
+	if err != nil {
+		return "", err
+	}
+	return f.issuer + "/federation/" + url.PathEscape(name) + "/login?" + url.Values{"link": {link}}.Encode(), nil
+}
+
+// link finishes a flow started from LinkURL: the identity is added to the account, unless it already
+// belongs to another one.
+func (f *federationUsecase) link(ctx context.Context, name string, claims *federationFlowClaims, subject string,
+	email string) (*FederationLogin, error) {
+	accountID, err := uuid.Parse(claims.LinkAccount)
+	if err != nil {
+		return nil, ErrInvalidFederationState
+	}
+	account, err := f.accounts.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := f.repo.GetIdentity(ctx, name, subject)
+	switch {
+	case err == nil:
+		if identity.AccountID != account.ID {
+			f.recordFailure(ctx, name, ErrIdentityLinkedElsewhere.Error())
+			return nil, ErrIdentityLinkedElsewhere
+		}
+	case errors.Is(err, repository.ErrIdentityNotFound):
+		if err := f.linkIdentity(ctx, account, name, subject, email, "provider="+name); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	return &FederationLogin{Username: account.Username, Provider: name, Linked: true, Next: claims.Next}, nil
+}
+
+// matchEmail handles a first sign-in whose email address is already on an identity of an existing
+// account. A provider trusted with LinkByEmail links the identity to that account when the address is
+// verified; otherwise the sign-in is refused, so the address does not get a second account beside the
+// one its owner already has.
+func (f *federationUsecase) matchEmail(ctx context.Context, provider *oidcProvider, subject string, email string,
+	claims jwt.MapClaims) (*repository.Account, error) {
+	if email == "" {
+		return nil, nil
+	}
+	matches, err := f.repo.FindIdentitiesByEmail(ctx, email)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	name := provider.config.Name
+	verified, _ := claims["email_verified"].(bool)
+	for _, match := range matches[1:] {
+		if match.AccountID != matches[0].AccountID {
+			verified = false
+		}
+	}
+	if !provider.config.LinkByEmail || !verified {
+		f.recordFailure(ctx, name, ErrIdentityEmailConflict.Error())
+		return nil, ErrIdentityEmailConflict
+	}
+	account, err := f.accounts.GetAccountByID(ctx, matches[0].AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.linkIdentity(ctx, account, name, subject, email, "provider="+name+" email="+email); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (f *federationUsecase) linkIdentity(ctx context.Context, account *repository.Account, name string, subject string,
+	email string, detail string) error {
+	now := time.Now()
+	err := f.repo.CreateIdentity(ctx, &repository.Identity{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		AccountID:   account.ID,
+		Type:        repository.IdentityTypeOIDC,
+		Provider:    name,
+		Subject:     subject,
+		Email:       email,
+		LastLoginAt: &now,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrIdentityIsDuplicated) {
+			return ErrIdentityLinkedElsewhere
+		}
+		return err
+	}
+	f.audit.Record(ctx, AuditEntry{
+		Type:    AuditIdentityLinked,
+		Actor:   account.Username,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  detail,
+	})
+	return nil
+}
+
+// provision creates the account of someone signing in for the first time. A taken username gets a
+// random suffix; when a concurrent login linked the identity first, that account is used.
+func (f *federationUsecase) provision(ctx context.Context, provider *oidcProvider, subject string, email string,
+	claims jwt.MapClaims) (*repository.Account, bool, error) {
+	name := provider.config.Name
+	base := federatedUsername(stringClaim(claims, provider.config.UsernameClaim))
+	username := base
+	for try := 1; ; try++ {
+		now := time.Now()
+		account := &repository.Account{
+			ID:       uuid.New(),
+			Username: username,
+			Type:     repository.AccountTypeUser,
+		}
+		message, err := NewOutboxMessage(NewAccountEvent(EventAccountCreated, account, ""))
+		if err != nil {
+			return nil, false, err
+		}
+		err = f.repo.CreateFederatedAccount(ctx, account, &repository.Identity{
+			ID:          uuid.New(),
+			CreatedAt:   now,
+			AccountID:   account.ID,
+			Type:        repository.IdentityTypeOIDC,
+			Provider:    name,
+			Subject:     subject,
+			Email:       email,
+			LastLoginAt: &now,
+		}, message)
+		if err == nil {
+			f.audit.Record(ctx, AuditEntry{
+				Type:    AuditAccountCreated,
+				Target:  account.Username,
+				Outcome: AuditOutcomeSuccess,
+				Detail:  "provider=" + name,
+			})
+			return account, true, nil
+		}
+		if !errors.Is(err, repository.ErrAccountIsDuplicated) {
+			return nil, false, err
+		}
+
+		if identity, err := f.repo.GetIdentity(ctx, name, subject); err == nil {
+			account, err := f.accounts.GetAccountByID(ctx, identity.AccountID)
+			return account, false, err
+		}
+		if try == federationUsernameTries {
+			return nil, false, ErrAccountIsAlreadyExisted
+		}
+		if len(base) > maxUsernameLength-federationSuffixLength {
+			base = base[:maxUsernameLength-federationSuffixLength]
+		}
+		username = base + util.RandomString(federationSuffixLength)
+	}
+}
+
+func (f *federationUsecase) recordFailure(ctx context.Context, provider string, detail string) {
+	f.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginDenied,
+		Outcome: AuditOutcomeFailure,
+		Detail:  "provider=" + provider + " " + strings.TrimSpace(detail),
+	})
+}
+
+func (f *federationUsecase) redirectURI(provider string) string {
+	return f.issuer + "/federation/" + url.PathEscape(provider) + "/callback"
+}
+
+// federatedUsername derives a valid username from a claim: the local part of an email address, with
+// everything but letters and digits dropped.
+func federatedUsername(value string) string {
+	if at := strings.Index(value, "@"); at >= 0 {
+		value = value[:at]
+	}
+	value = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, value)
+	if value == "" {
+		value = "user"
+	}
+	if len(value) > maxUsernameLength {
+		value = value[:maxUsernameLength]
+	}
+	if len(value) < minUsernameLength {
+		value += util.RandomString(federationSuffixLength)
+	}
+	return value
+}
+
+// localPath keeps only paths on this server, so the login cannot be used to redirect elsewhere.
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return ""
+	}
+	return next
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+type oidcProvider struct {
+	config OIDCProviderConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *oidcProviderMetadata
+	keys     map[string]crypto.PublicKey
+}
+
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discover fetches the discovery document once; a failed fetch is tried again on the next login.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcProviderMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	metadata := &oidcProviderMetadata{}
+	if err := p.getJSON(ctx, p.config.DiscoveryURL, metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Issuer == "" || metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s is incomplete", p.config.Name)
+	}
+	p.metadata = metadata
+	return metadata, nil
+}
+
+// authenticate exchanges the code at the token endpoint and returns the claims of the verified ID token.
+func (p *oidcProvider) authenticate(ctx context.Context, code string, verifier string, redirectURI string, nonce string) (jwt.MapClaims, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {GrantAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	// RFC 6749 section 2.3.1 form-encodes the credentials before they go into the Basic header.
+	httpReq.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	var rsp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(httpReq, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint answered %d: %s %s", status, rsp.Error, rsp.ErrorDescription)
+	}
+	if rsp.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return p.verifyIDToken(ctx, metadata, rsp.IDToken, nonce)
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, metadata *oidcProviderMetadata, idToken string, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, metadata, kid)
+	}, jwt.WithValidMethods([]string{KeyAlgorithmEdDSA, KeyAlgorithmRS256, KeyAlgorithmES256, KeyAlgorithmES384, KeyAlgorithmES512}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	switch {
+	case !claims.VerifyIssuer(metadata.Issuer, true):
+		return nil, fmt.Errorf("%w: issuer is not %s", ErrInvalidToken, metadata.Issuer)
+	case !claims.VerifyAudience(p.config.ClientID, true):
+		return nil, fmt.Errorf("%w: audience is not %s", ErrInvalidToken, p.config.ClientID)
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return nil, fmt.Errorf("%w: expired or without exp", ErrInvalidToken)
+	case stringClaim(claims, "nonce") != nonce:
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	case stringClaim(claims, "sub") == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// key returns the verification key kid of the provider. An unknown kid refetches the JWKS, since the
+// provider may have rotated its keys since we last looked.
+func (p *oidcProvider) key(ctx context.Context, metadata *oidcProviderMetadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	set := &JSONWebKeySet{}
+	if err := p.getJSON(ctx, metadata.JWKSURI, set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := parseJWK(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok = keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown key %s", kid)
+	}
+	return key, nil
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	status, err := p.do(httpReq, v)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%s answered %d", url, status)
+	}
+	return nil
+}
+
+// do sends httpReq and decodes the JSON response into v, whatever the status.
+func (p *oidcProvider) do(httpReq *http.Request, v interface{}) (int, error) {
+	httpRsp, err := p.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer httpRsp.Body.Close()
+	if err := json.NewDecoder(io.LimitReader(httpRsp.Body, maxFederationResponse)).Decode(v); err != nil && httpRsp.StatusCode == http.StatusOK {
+		return httpRsp.StatusCode, err
+	}
+	return httpRsp.StatusCode, nil
+}
@@ -0,0 +1,96 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: federation.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockFederationUsecase is a mock of FederationUsecase interface.
+type MockFederationUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockFederationUsecaseMockRecorder
+}
+
+// MockFederationUsecaseMockRecorder is the mock recorder for MockFederationUsecase.
+type MockFederationUsecaseMockRecorder struct {
+	mock *MockFederationUsecase
+}
+
+// NewMockFederationUsecase creates a new mock instance.
+func NewMockFederationUsecase(ctrl *gomock.Controller) *MockFederationUsecase {
+	mock := &MockFederationUsecase{ctrl: ctrl}
+	mock.recorder = &MockFederationUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFederationUsecase) EXPECT() *MockFederationUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Finish mocks base method.
+func (m *MockFederationUsecase) Finish(ctx context.Context, provider string, req FederationCallbackRequest, flow string) (*FederationLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", ctx, provider, req, flow)
+	ret0, _ := ret[0].(*FederationLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockFederationUsecaseMockRecorder) Finish(ctx, provider, req, flow interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockFederationUsecase)(nil).Finish), ctx, provider, req, flow)
+}
+
+// LinkURL mocks base method.
+func (m *MockFederationUsecase) LinkURL(ctx context.Context, provider string, accountID uuid.UUID) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkURL", ctx, provider, accountID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkURL indicates an expected call of LinkURL.
+func (mr *MockFederationUsecaseMockRecorder) LinkURL(ctx, provider, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkURL", reflect.TypeOf((*MockFederationUsecase)(nil).LinkURL), ctx, provider, accountID)
+}
+
+// Providers mocks base method.
+func (m *MockFederationUsecase) Providers() []FederationProvider {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Providers")
+	ret0, _ := ret[0].([]FederationProvider)
+	return ret0
+}
+
+// Providers indicates an expected call of Providers.
+func (mr *MockFederationUsecaseMockRecorder) Providers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Providers", reflect.TypeOf((*MockFederationUsecase)(nil).Providers))
+}
+
+// Start mocks base method.
+func (m *MockFederationUsecase) Start(ctx context.Context, provider, next, link string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx, provider, next, link)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockFederationUsecaseMockRecorder) Start(ctx, provider, next, link interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockFederationUsecase)(nil).Start), ctx, provider, next, link)
+}
@@ -0,0 +1,474 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	stubClientID     = "senao"
+	stubClientSecret = "stub-secret"
+	stubCode         = "stub-code"
+)
+
+// stubOIDCProvider is a local OpenID Connect provider: it serves discovery and its JWKS, and signs
+// an ID token for stubCode at its token endpoint.
+type stubOIDCProvider struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	key       *SigningKey
+	challenge string
+	nonce     string
+	claims    func(claims jwt.MapClaims)
+	signWith  *SigningKey
+}
+
+func newStubOIDCProvider(t *testing.T) *stubOIDCProvider {
+	stub := &stubOIDCProvider{key: newStubSigningKey(t)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeStubJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 stub.server.URL,
+			"authorization_endpoint": stub.server.URL + "/authorize",
+			"token_endpoint":         stub.server.URL + "/token",
+			"jwks_uri":               stub.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		writeStubJSON(w, http.StatusOK, NewJSONWebKeySet([]SigningKey{*stub.key}))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		id, secret, _ := r.BasicAuth()
+		switch {
+		case id != stubClientID || secret != stubClientSecret:
+			writeStubJSON(w, http.StatusUnauthorized, map[string]string{"error": OAuthErrInvalidClient})
+			return
+		case r.PostFormValue("code") != stubCode || pkceChallenge(r.PostFormValue("code_verifier")) != stub.challenge:
+			writeStubJSON(w, http.StatusBadRequest, map[string]string{"error": OAuthErrInvalidGrant})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":                stub.server.URL,
+			"sub":                "248289761001",
+			"aud":                stubClientID,
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"iat":                time.Now().Unix(),
+			"nonce":              stub.nonce,
+			"email":              "j.doe@contractor.example.com",
+			"preferred_username": "j.doe",
+		}
+		if stub.claims != nil {
+			stub.claims(claims)
+		}
+		key := stub.key
+		if stub.signWith != nil {
+			key = stub.signWith
+		}
+		token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+		token.Header["kid"] = key.ID
+		idToken, err := token.SignedString(key.Key)
+		require.NoError(t, err)
+		writeStubJSON(w, http.StatusOK, map[string]string{"access_token": "stub", "token_type": "Bearer", "id_token": idToken})
+	})
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func newStubSigningKey(t *testing.T) *SigningKey {
+	signer, err := GenerateSigningKey(KeyAlgorithmES256)
+	require.NoError(t, err)
+	key, err := NewSigningKey(signer, KeyStatusCurrent)
+	require.NoError(t, err)
+	return key
+}
+
+func writeStubJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *stubOIDCProvider) config() OIDCProviderConfig {
+	return OIDCProviderConfig{
+		Name:         "corp",
+		DisplayName:  "Contractor SSO",
+		DiscoveryURL: s.server.URL + "/.well-known/openid-configuration",
+		ClientID:     stubClientID,
+		ClientSecret: stubClientSecret,
+	}
+}
+
+type federationMocks struct {
+	repo     *repository.MockIdentityRepository
+	accounts *repository.MockAccountRepository
+	audit    *MockAuditUsecase
+	events   *MockEventPublisher
+}
+
+func newTestFederationUsecase(t *testing.T, stub *stubOIDCProvider) (FederationUsecase, federationMocks) {
+	return newTestFederationUsecaseWith(t, stub.config())
+}
+
+func newTestFederationUsecaseWith(t *testing.T, config OIDCProviderConfig) (FederationUsecase, federationMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := federationMocks{
+		repo:     repository.NewMockIdentityRepository(ctrl),
+		accounts: repository.NewMockAccountRepository(ctrl),
+		audit:    NewMockAuditUsecase(ctrl),
+		events:   NewMockEventPublisher(ctrl),
+	}
+	federation := NewFederationUsecase([]OIDCProviderConfig{config}, mocks.repo, mocks.accounts, mocks.audit, mocks.events,
+		NewTokenSigner(newTestKeySet(t)), testIssuer)
+	return federation, mocks
+}
+
+// startFederatedLogin starts a login and plays the part of the browser at the provider: it returns
+// the state the provider sends back and the flow cookie.
+func startFederatedLogin(t *testing.T, federation FederationUsecase, stub *stubOIDCProvider) (string, string) {
+	return startFederatedFlow(t, federation, stub, "")
+}
+
+func startFederatedFlow(t *testing.T, federation FederationUsecase, stub *stubOIDCProvider, link string) (string, string) {
+	authURL, flow, err := federation.Start(context.Background(), "corp", "/next", link)
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	stub.mu.Lock()
+	stub.challenge = query.Get("code_challenge")
+	stub.nonce = query.Get("nonce")
+	stub.mu.Unlock()
+	return query.Get("state"), flow
+}
+
+func TestFederationStart(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	federation, _ := newTestFederationUsecase(t, stub)
+
+	require.Equal(t, []FederationProvider{{Name: "corp", DisplayName: "Contractor SSO"}}, federation.Providers())
+
+	authURL, flow, err := federation.Start(context.Background(), "corp", "/next", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, flow)
+	require.True(t, strings.HasPrefix(authURL, stub.server.URL+"/authorize?"))
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	require.Equal(t, "code", query.Get("response_type"))
+	require.Equal(t, stubClientID, query.Get("client_id"))
+	require.Equal(t, testIssuer+"/federation/corp/callback", query.Get("redirect_uri"))
+	require.Equal(t, "openid email profile", query.Get("scope"))
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.NotEmpty(t, query.Get("state"))
+	require.NotEmpty(t, query.Get("nonce"))
+
+	_, _, err = federation.Start(context.Background(), "other", "/", "")
+	require.Equal(t, ErrFederationProviderNotFound, err)
+}
+
+func TestFederationFinish(t *testing.T) {
+	callback := func(state string) FederationCallbackRequest {
+		return FederationCallbackRequest{Code: stubCode, State: state}
+	}
+
+	t.Run("provision", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, mocks := newTestFederationUsecase(t, stub)
+		state, flow := startFederatedLogin(t, federation, stub)
+
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(nil, repository.ErrIdentityNotFound)
+		mocks.repo.EXPECT().FindIdentitiesByEmail(gomock.Any(), "j.doe@contractor.example.com").Return(nil, nil)
+		mocks.repo.EXPECT().CreateFederatedAccount(gomock.Any(), gomock.Any(), gomock.Any(), outboxMessageOf(EventAccountCreated)).
+			DoAndReturn(func(_ context.Context, account *repository.Account, identity *repository.Identity, _ ...*repository.OutboxMessage) error {
+				require.Equal(t, "jdoe", account.Username)
+				require.Equal(t, repository.AccountTypeUser, account.Type)
+				require.Empty(t, account.HashedPassword)
+				require.Equal(t, account.ID, identity.AccountID)
+				require.Equal(t, repository.IdentityTypeOIDC, identity.Type)
+				require.Equal(t, "corp", identity.Provider)
+				require.Equal(t, "j.doe@contractor.example.com", identity.Email)
+				return nil
+			})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeSuccess))
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+		mocks.events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+
+		login, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.NoError(t, err)
+		require.Equal(t, &FederationLogin{Username: "jdoe", Provider: "corp", Provisioned: true, Next: "/next"}, login)
+	})
+
+	t.Run("linked identity", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, mocks := newTestFederationUsecase(t, stub)
+		account := &repository.Account{ID: uuid.New(), Username: "contractor1"}
+		identity := &repository.Identity{ID: uuid.New(), AccountID: account.ID, Provider: "corp", Subject: "248289761001"}
+
+		for i := 0; i < 2; i++ {
+			// The second login is signed with a rotated key, which is fetched again.
+			if i == 1 {
+				stub.mu.Lock()
+				stub.key = newStubSigningKey(t)
+				stub.mu.Unlock()
+			}
+			state, flow := startFederatedLogin(t, federation, stub)
+			mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(identity, nil)
+			mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+			mocks.repo.EXPECT().TouchIdentity(gomock.Any(), identity.ID, gomock.Any()).Return(nil)
+			mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+			mocks.events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+
+			login, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+			require.NoError(t, err)
+			require.Equal(t, "contractor1", login.Username)
+			require.False(t, login.Provisioned)
+		}
+	})
+
+	t.Run("locked account", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, mocks := newTestFederationUsecase(t, stub)
+		lockedAt := time.Now()
+		account := &repository.Account{ID: uuid.New(), Username: "contractor1", LockedAt: &lockedAt}
+		identity := &repository.Identity{ID: uuid.New(), AccountID: account.ID, Provider: "corp", Subject: "248289761001"}
+		state, flow := startFederatedLogin(t, federation, stub)
+
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(identity, nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+		mocks.repo.EXPECT().TouchIdentity(gomock.Any(), identity.ID, gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+		mocks.events.EXPECT().Publish(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.Equal(t, ErrLoginAccountLocked, err)
+	})
+
+	t.Run("username taken", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, mocks := newTestFederationUsecase(t, stub)
+		state, flow := startFederatedLogin(t, federation, stub)
+
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Times(2).Return(nil, repository.ErrIdentityNotFound)
+		mocks.repo.EXPECT().FindIdentitiesByEmail(gomock.Any(), gomock.Any()).Return(nil, nil)
+		var usernames []string
+		mocks.repo.EXPECT().CreateFederatedAccount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2).
+			DoAndReturn(func(_ context.Context, account *repository.Account, _ *repository.Identity, _ ...*repository.OutboxMessage) error {
+				usernames = append(usernames, account.Username)
+				if len(usernames) == 1 {
+					return repository.ErrAccountIsDuplicated
+				}
+				return nil
+			})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeSuccess))
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+		mocks.events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+
+		login, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.NoError(t, err)
+		require.Equal(t, "jdoe", usernames[0])
+		require.True(t, strings.HasPrefix(login.Username, "jdoe"))
+		require.Len(t, login.Username, len("jdoe")+federationSuffixLength)
+	})
+
+	t.Run("email of another account", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.claims = func(claims jwt.MapClaims) { claims["email_verified"] = true }
+		federation, mocks := newTestFederationUsecase(t, stub)
+		state, flow := startFederatedLogin(t, federation, stub)
+
+		other := repository.Identity{ID: uuid.New(), AccountID: uuid.New(), Provider: "google", Email: "j.doe@contractor.example.com"}
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(nil, repository.ErrIdentityNotFound)
+		mocks.repo.EXPECT().FindIdentitiesByEmail(gomock.Any(), "j.doe@contractor.example.com").Return([]repository.Identity{other}, nil)
+		mocks.repo.EXPECT().CreateFederatedAccount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+		_, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.Equal(t, ErrIdentityEmailConflict, err)
+	})
+
+	t.Run("email linked by trusted provider", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.claims = func(claims jwt.MapClaims) { claims["email_verified"] = true }
+		config := stub.config()
+		config.LinkByEmail = true
+		federation, mocks := newTestFederationUsecaseWith(t, config)
+		state, flow := startFederatedLogin(t, federation, stub)
+
+		account := &repository.Account{ID: uuid.New(), Username: "jdoe"}
+		other := repository.Identity{ID: uuid.New(), AccountID: account.ID, Provider: "google", Email: "j.doe@contractor.example.com"}
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(nil, repository.ErrIdentityNotFound)
+		mocks.repo.EXPECT().FindIdentitiesByEmail(gomock.Any(), "j.doe@contractor.example.com").Return([]repository.Identity{other}, nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+		mocks.repo.EXPECT().CreateIdentity(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, identity *repository.Identity) error {
+			require.Equal(t, account.ID, identity.AccountID)
+			require.Equal(t, repository.IdentityTypeOIDC, identity.Type)
+			require.Equal(t, "248289761001", identity.Subject)
+			return nil
+		})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditIdentityLinked, AuditOutcomeSuccess))
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+		mocks.events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+
+		login, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.NoError(t, err)
+		require.Equal(t, &FederationLogin{Username: "jdoe", Provider: "corp", Next: "/next"}, login)
+	})
+
+	t.Run("unverified email is not linked", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		config := stub.config()
+		config.LinkByEmail = true
+		federation, mocks := newTestFederationUsecaseWith(t, config)
+		state, flow := startFederatedLogin(t, federation, stub)
+
+		other := repository.Identity{ID: uuid.New(), AccountID: uuid.New(), Provider: "google", Email: "j.doe@contractor.example.com"}
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(nil, repository.ErrIdentityNotFound)
+		mocks.repo.EXPECT().FindIdentitiesByEmail(gomock.Any(), "j.doe@contractor.example.com").Return([]repository.Identity{other}, nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+		_, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.Equal(t, ErrIdentityEmailConflict, err)
+	})
+
+	t.Run("wrong state", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, _ := newTestFederationUsecase(t, stub)
+		_, flow := startFederatedLogin(t, federation, stub)
+
+		_, err := federation.Finish(context.Background(), "corp", callback("forged"), flow)
+		require.Equal(t, ErrInvalidFederationState, err)
+	})
+
+	t.Run("denied at provider", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, mocks := newTestFederationUsecase(t, stub)
+		state, flow := startFederatedLogin(t, federation, stub)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+		_, err := federation.Finish(context.Background(), "corp", FederationCallbackRequest{State: state, Error: OAuthErrAccessDenied}, flow)
+		require.Equal(t, ErrFederationDenied, err)
+	})
+
+	testCase := []struct {
+		name     string
+		claims   func(claims jwt.MapClaims)
+		otherKey bool
+	}{
+		{name: "wrong issuer", claims: func(claims jwt.MapClaims) { claims["iss"] = "https://idp.example.com" }},
+		{name: "wrong audience", claims: func(claims jwt.MapClaims) { claims["aud"] = "someone-else" }},
+		{name: "wrong nonce", claims: func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }},
+		{name: "expired", claims: func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "no subject", claims: func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{name: "unknown key", otherKey: true},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubOIDCProvider(t)
+			stub.claims = tc.claims
+			if tc.otherKey {
+				stub.signWith = newStubSigningKey(t)
+			}
+			federation, mocks := newTestFederationUsecase(t, stub)
+			state, flow := startFederatedLogin(t, federation, stub)
+			mocks.repo.EXPECT().GetIdentity(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+			_, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+			require.Equal(t, ErrFederationFailed, err)
+		})
+	}
+}
+
+func TestFederationLink(t *testing.T) {
+	callback := func(state string) FederationCallbackRequest {
+		return FederationCallbackRequest{Code: stubCode, State: state}
+	}
+	account := &repository.Account{ID: uuid.New(), Username: "contractor1"}
+	startLink := func(t *testing.T, federation FederationUsecase, stub *stubOIDCProvider) (string, string) {
+		linkURL, err := federation.LinkURL(context.Background(), "corp", account.ID)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(linkURL, testIssuer+"/federation/corp/login?link="))
+		parsed, err := url.Parse(linkURL)
+		require.NoError(t, err)
+		return startFederatedFlow(t, federation, stub, parsed.Query().Get("link"))
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, mocks := newTestFederationUsecase(t, stub)
+		state, flow := startLink(t, federation, stub)
+
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(nil, repository.ErrIdentityNotFound)
+		mocks.repo.EXPECT().CreateIdentity(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, identity *repository.Identity) error {
+			require.Equal(t, account.ID, identity.AccountID)
+			require.Equal(t, "j.doe@contractor.example.com", identity.Email)
+			return nil
+		})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditIdentityLinked, AuditOutcomeSuccess))
+
+		login, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.NoError(t, err)
+		require.Equal(t, &FederationLogin{Username: "contractor1", Provider: "corp", Linked: true, Next: "/next"}, login)
+	})
+
+	t.Run("linked to another account", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, mocks := newTestFederationUsecase(t, stub)
+		state, flow := startLink(t, federation, stub)
+
+		identity := &repository.Identity{ID: uuid.New(), AccountID: uuid.New(), Provider: "corp", Subject: "248289761001"}
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), "corp", "248289761001").Return(identity, nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+		_, err := federation.Finish(context.Background(), "corp", callback(state), flow)
+		require.Equal(t, ErrIdentityLinkedElsewhere, err)
+	})
+
+	t.Run("invalid link", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		federation, _ := newTestFederationUsecase(t, stub)
+
+		_, _, err := federation.Start(context.Background(), "corp", "", "forged")
+		require.Equal(t, ErrInvalidFederationState, err)
+		_, err = federation.LinkURL(context.Background(), "other", account.ID)
+		require.Equal(t, ErrFederationProviderNotFound, err)
+	})
+}
+
+func TestFederatedUsername(t *testing.T) {
+	require.Equal(t, "jdoe", federatedUsername("j.doe@example.com"))
+	require.Equal(t, "JaneDoe", federatedUsername("Jane Doe"))
+	require.Len(t, federatedUsername(strings.Repeat("a", 40)), maxUsernameLength)
+	require.Len(t, federatedUsername("jo"), 2+federationSuffixLength)
+	require.True(t, strings.HasPrefix(federatedUsername("..."), "user"))
+}
+
+func TestLocalPath(t *testing.T) {
+	require.Equal(t, "/oauth/authorize?client_id=app", localPath("/oauth/authorize?client_id=app"))
+	require.Empty(t, localPath("https://evil.example.com"))
+	require.Empty(t, localPath("//evil.example.com"))
+	require.Empty(t, localPath("/\\evil.example.com"))
+}
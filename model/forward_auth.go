@@ -0,0 +1,123 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+)
+
+// ForwardAuthAnyRole in the roles of a rule lets any account through.
+const ForwardAuthAnyRole = "*"
+
+var (
+	ErrForwardAuthHostNotAllowed = errors.New("Host is not behind forward auth")
+	ErrForwardAuthRoleRequired   = errors.New("Account has none of the roles the host requires")
+)
+
+// ForwardAuthRule limits a host to the accounts with one of the roles. Host is a name like
+// grafana.example.com, or *.example.com for every host under example.com.
+type ForwardAuthRule struct {
+	Host  string
+	Roles []string
+}
+
+type ForwardAuthConfig struct {
+	// Rules are checked in order, the first one matching the host applies.
+	Rules []ForwardAuthRule
+	// DenyUnlisted refuses hosts without a rule, which otherwise let any account through.
+	DenyUnlisted bool
+	// LoginURL is where browsers are sent to sign in, they get a 401 like other clients without it.
+	LoginURL string
+}
+
+// ForwardAuthIdentity is the account a reverse proxy lets a request through for.
+type ForwardAuthIdentity struct {
+	ID       string
+	Username string
+	Roles    []string
+}
+
+// ForwardAuthUsecase decides for reverse proxies whether a request to a host they protect is let
+// through, so applications behind them get the account without knowing about it.
+type ForwardAuthUsecase interface {
+	Verify(ctx context.Context, principal *Principal, host string) (*ForwardAuthIdentity, error)
+	// LoginURL is where a browser going to returnTo signs in first, or empty when it is not sent anywhere.
+	LoginURL(returnTo string) string
+}
+
+type forwardAuthUsecase struct {
+	config   ForwardAuthConfig
+	accounts repository.AccountRepository
+}
+
+func NewForwardAuthUsecase(config ForwardAuthConfig, accounts repository.AccountRepository) ForwardAuthUsecase {
+	return &forwardAuthUsecase{
+		config:   config,
+		accounts: accounts,
+	}
+}
+
+func (f *forwardAuthUsecase) Verify(ctx context.Context, principal *Principal, host string) (*ForwardAuthIdentity, error) {
+	// The roles are read each time, so a change applies to the next request rather than the next token.
+	account, err := f.accounts.GetAccountByID(ctx, principal.AccountID)
+	if err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if account.LockedAt != nil {
+		return nil, ErrInvalidToken
+	}
+	roles := strings.Fields(account.Roles)
+
+	rule := f.rule(host)
+	if rule == nil {
+		if f.config.DenyUnlisted {
+			return nil, ErrForwardAuthHostNotAllowed
+		}
+	} else if !containsString(rule.Roles, ForwardAuthAnyRole) && !hasAnyRole(roles, rule.Roles) {
+		return nil, ErrForwardAuthRoleRequired
+	}
+	return &ForwardAuthIdentity{ID: account.ID.String(), Username: account.Username, Roles: roles}, nil
+}
+
+func (f *forwardAuthUsecase) LoginURL(returnTo string) string {
+	if f.config.LoginURL == "" {
+		return ""
+	}
+	login, err := url.Parse(f.config.LoginURL)
+	if err != nil {
+		return ""
+	}
+	if returnTo != "" {
+		query := login.Query()
+		query.Set("rd", returnTo)
+		login.RawQuery = query.Encode()
+	}
+	return login.String()
+}
+
+// rule is the first rule matching the host, or nil.
+func (f *forwardAuthUsecase) rule(host string) *ForwardAuthRule {
+	host = strings.ToLower(host)
+	for i := range f.config.Rules {
+		pattern := strings.ToLower(f.config.Rules[i].Host)
+		if pattern == host || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return &f.config.Rules[i]
+		}
+	}
+	return nil
+}
+
+func hasAnyRole(roles []string, wanted []string) bool {
+	for _, role := range wanted {
+		if containsString(roles, role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: forward_auth.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockForwardAuthUsecase is a mock of ForwardAuthUsecase interface.
+type MockForwardAuthUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockForwardAuthUsecaseMockRecorder
+}
+
+// MockForwardAuthUsecaseMockRecorder is the mock recorder for MockForwardAuthUsecase.
+type MockForwardAuthUsecaseMockRecorder struct {
+	mock *MockForwardAuthUsecase
+}
+
+// NewMockForwardAuthUsecase creates a new mock instance.
+func NewMockForwardAuthUsecase(ctrl *gomock.Controller) *MockForwardAuthUsecase {
+	mock := &MockForwardAuthUsecase{ctrl: ctrl}
+	mock.recorder = &MockForwardAuthUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockForwardAuthUsecase) EXPECT() *MockForwardAuthUsecaseMockRecorder {
+	return m.recorder
+}
+
+// LoginURL mocks base method.
+func (m *MockForwardAuthUsecase) LoginURL(returnTo string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginURL", returnTo)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// LoginURL indicates an expected call of LoginURL.
+func (mr *MockForwardAuthUsecaseMockRecorder) LoginURL(returnTo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginURL", reflect.TypeOf((*MockForwardAuthUsecase)(nil).LoginURL), returnTo)
+}
+
+// Verify mocks base method.
+func (m *MockForwardAuthUsecase) Verify(ctx context.Context, principal *Principal, host string) (*ForwardAuthIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, principal, host)
+	ret0, _ := ret[0].(*ForwardAuthIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockForwardAuthUsecaseMockRecorder) Verify(ctx, principal, host interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockForwardAuthUsecase)(nil).Verify), ctx, principal, host)
+}
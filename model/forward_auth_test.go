@@ -0,0 +1,71 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyForwardAuth(t *testing.T) {
+	account := &repository.Account{ID: uuid.New(), Username: "alice", Roles: "staff ops"}
+	principal := &Principal{AccountID: account.ID, Username: "alice", Scopes: []string{ScopeProfile}}
+	config := ForwardAuthConfig{Rules: []ForwardAuthRule{
+		{Host: "grafana.example.com", Roles: []string{"admin"}},
+		{Host: "*.tools.example.com", Roles: []string{"ops"}},
+		{Host: "wiki.example.com", Roles: []string{ForwardAuthAnyRole}},
+	}}
+
+	testCases := []struct {
+		name         string
+		host         string
+		denyUnlisted bool
+		err          error
+	}{
+		{name: "RoleRequired", host: "grafana.example.com", err: ErrForwardAuthRoleRequired},
+		{name: "Wildcard", host: "Jenkins.Tools.example.com"},
+		{name: "WildcardNotParent", host: "tools.example.com"},
+		{name: "AnyRole", host: "wiki.example.com"},
+		{name: "Unlisted", host: "intranet.example.com"},
+		{name: "UnlistedDenied", host: "intranet.example.com", denyUnlisted: true, err: ErrForwardAuthHostNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+
+			config := config
+			config.DenyUnlisted = tc.denyUnlisted
+			identity, err := NewForwardAuthUsecase(config, accounts).Verify(context.Background(), principal, tc.host)
+			require.Equal(t, tc.err, err)
+			if tc.err == nil {
+				require.Equal(t, account.ID.String(), identity.ID)
+				require.Equal(t, "alice", identity.Username)
+				require.Equal(t, []string{"staff", "ops"}, identity.Roles)
+			}
+		})
+	}
+}
+
+func TestVerifyForwardAuthDeletedAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	accounts := repository.NewMockAccountRepository(ctrl)
+	principal := &Principal{AccountID: uuid.New(), Username: "alice"}
+	accounts.EXPECT().GetAccountByID(gomock.Any(), principal.AccountID).Return(nil, repository.ErrAccountRecordNotFound)
+
+	_, err := NewForwardAuthUsecase(ForwardAuthConfig{}, accounts).Verify(context.Background(), principal, "wiki.example.com")
+	require.Equal(t, ErrInvalidToken, err)
+}
+
+func TestForwardAuthLoginURL(t *testing.T) {
+	require.Empty(t, NewForwardAuthUsecase(ForwardAuthConfig{}, nil).LoginURL("https://wiki.example.com/"))
+
+	forwardAuth := NewForwardAuthUsecase(ForwardAuthConfig{LoginURL: "https://id.example.com/account/login?theme=dark"}, nil)
+	require.Equal(t, "https://id.example.com/account/login?rd=https%3A%2F%2Fwiki.example.com%2Fpage%3Fa%3D1&theme=dark",
+		forwardAuth.LoginURL("https://wiki.example.com/page?a=1"))
+}
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	HookPreCreate  = "pre_create"
+	HookPostCreate = "post_create"
+	HookPreLogin   = "pre_login"
+	HookPostLogin  = "post_login"
+)
+
+var (
+	ErrHookDenied       = errors.New("Denied by hook")
+	ErrInvalidHookStage = errors.New("Invalid hook stage")
+)
+
+// HookRequest is what a hook is told about the signup or login it runs for.
+type HookRequest struct {
+	Stage     string            `json:"stage"`
+	Username  string            `json:"username"`
+	AccountID string            `json:"account_id,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// HookResult is a hook's decision. Claims are added to the login response and Metadata is merged into
+// the account. A post-create hook cannot deny, the account already exists by then.
+type HookResult struct {
+	Deny     bool                   `json:"deny"`
+	Reason   string                 `json:"reason,omitempty"`
+	Claims   map[string]interface{} `json:"claims,omitempty"`
+	Metadata map[string]string      `json:"metadata,omitempty"`
+}
+
+// Hook is run synchronously around signup and login. An error fails the operation closed.
+type Hook interface {
+	Run(ctx context.Context, req HookRequest) (*HookResult, error)
+}
+
+// HookFunc lets a plain function be registered as a Hook.
+type HookFunc func(ctx context.Context, req HookRequest) (*HookResult, error)
+
+func (f HookFunc) Run(ctx context.Context, req HookRequest) (*HookResult, error) {
+	return f(ctx, req)
+}
+
+// Hooks holds the hooks of every stage, run in the order they were registered.
+type Hooks map[string][]Hook
+
+func (h Hooks) Register(stage string, hook Hook) error {
+	switch stage {
+	case HookPreCreate, HookPostCreate, HookPreLogin, HookPostLogin:
+	default:
+		return ErrInvalidHookStage
+	}
+	h[stage] = append(h[stage], hook)
+	return nil
+}
+
+// Run runs the hooks of the stage one after the other. Metadata from a hook is passed on to the
+// next one, and the first denial stops the chain.
+func (h Hooks) Run(ctx context.Context, stage string, account *repository.Account) *HookResult {
+	if len(h[stage]) == 0 {
+		return &HookResult{}
+	}
+	result := &HookResult{
+		Metadata: accountMetadata(account),
+	}
+	info := RequestInfoFromContext(ctx)
+	for _, hook := range h[stage] {
+		req := HookRequest{
+			Stage:     stage,
+			Username:  account.Username,
+			Metadata:  result.Metadata,
+			IP:        info.IP,
+			UserAgent: info.UserAgent,
+			RequestID: info.RequestID,
+		}
+		if stage != HookPreCreate {
+			req.AccountID = account.ID.String()
+		}
+
+		rsp, err := hook.Run(ctx, req)
+		if err != nil {
+			log.Error().Err(err).Str("stage", stage).Str("username", account.Username).Msg("hook failed")
+			rsp = &HookResult{Deny: true}
+		}
+		if rsp == nil {
+			continue
+		}
+		if rsp.Deny {
+			result.Deny = true
+			result.Reason = rsp.Reason
+			if result.Reason == "" {
+				result.Reason = ErrHookDenied.Error()
+			}
+			return result
+		}
+		for k, v := range rsp.Claims {
+			if result.Claims == nil {
+				result.Claims = make(map[string]interface{})
+			}
+			result.Claims[k] = v
+		}
+		for k, v := range rsp.Metadata {
+			if result.Metadata == nil {
+				result.Metadata = make(map[string]string)
+			}
+			result.Metadata[k] = v
+		}
+	}
+	return result
+}
+
+// NewReservedUsernameHook denies signups for any of the given usernames, ignoring case.
+func NewReservedUsernameHook(usernames ...string) Hook {
+	reserved := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		if username = strings.TrimSpace(username); username != "" {
+			reserved[strings.ToLower(username)] = true
+		}
+	}
+	return HookFunc(func(ctx context.Context, req HookRequest) (*HookResult, error) {
+		if reserved[strings.ToLower(req.Username)] {
+			return &HookResult{Deny: true, Reason: "Username is reserved"}, nil
+		}
+		return nil, nil
+	})
+}
+
+func accountMetadata(account *repository.Account) map[string]string {
+	if account.Metadata == "" {
+		return nil
+	}
+	metadata := make(map[string]string)
+	if err := json.Unmarshal([]byte(account.Metadata), &metadata); err != nil {
+		log.Error().Err(err).Str("username", account.Username).Msg("invalid account metadata")
+		return nil
+	}
+	return metadata
+}
+
+// setAccountMetadata stores metadata on the account and reports whether it changed.
+func setAccountMetadata(account *repository.Account, metadata map[string]string) (bool, error) {
+	if len(metadata) == 0 {
+		return false, nil
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return false, err
+	}
+	if string(encoded) == account.Metadata {
+		return false, nil
+	}
+	account.Metadata = string(encoded)
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	HookTimestampHeader = "X-Hook-Timestamp"
+	HookSignatureHeader = "X-Hook-Signature"
+)
+
+var (
+	defaultHookTimeout = 2 * time.Second
+	maxHookResponse    = int64(64 << 10)
+)
+
+type httpHook struct {
+	url      string
+	secret   string
+	failOpen bool
+	client   *http.Client
+}
+
+// NewHTTPHook calls url with the HookRequest as JSON and expects a HookResult back. The request is
+// signed like a webhook when secret is set. With failOpen, a callout that errors or times out lets
+// the operation through instead of denying it.
+func NewHTTPHook(url string, secret string, timeout time.Duration, failOpen bool) Hook {
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	return &httpHook{
+		url:      url,
+		secret:   secret,
+		failOpen: failOpen,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (h *httpHook) Run(ctx context.Context, req HookRequest) (*HookResult, error) {
+	result, err := h.call(ctx, req)
+	if err != nil && h.failOpen {
+		log.Warn().Err(err).Str("stage", req.Stage).Str("url", h.url).Msg("hook failed open")
+		return nil, nil
+	}
+	return result, err
+}
+
+func (h *httpHook) call(ctx context.Context, hookReq HookRequest) (*HookResult, error) {
+	payload, err := json.Marshal(hookReq)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HookTimestampHeader, timestamp)
+		req.Header.Set(HookSignatureHeader, "sha256="+SignWebhookPayload(h.secret, timestamp, payload))
+	}
+
+	rsp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		io.Copy(ioutil.Discard, rsp.Body)
+		return nil, fmt.Errorf("unexpected response status %d", rsp.StatusCode)
+	}
+	result := &HookResult{}
+	if err := json.NewDecoder(io.LimitReader(rsp.Body, maxHookResponse)).Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func staticHook(result *HookResult, err error) Hook {
+	return HookFunc(func(ctx context.Context, req HookRequest) (*HookResult, error) {
+		return result, err
+	})
+}
+
+func TestHooksRun(t *testing.T) {
+	account := &repository.Account{ID: uuid.New(), Username: util.RandomString(8), Metadata: `{"plan":"free"}`}
+
+	testCases := []struct {
+		name   string
+		hooks  []Hook
+		verify func(result *HookResult)
+	}{
+		{
+			name: "no hooks",
+			verify: func(result *HookResult) {
+				require.False(t, result.Deny)
+				require.Nil(t, result.Metadata)
+			},
+		},
+		{
+			name: "merge claims and metadata",
+			hooks: []Hook{
+				staticHook(&HookResult{Claims: map[string]interface{}{"tier": "gold"}, Metadata: map[string]string{"plan": "pro"}}, nil),
+				HookFunc(func(ctx context.Context, req HookRequest) (*HookResult, error) {
+					require.Equal(t, "pro", req.Metadata["plan"])
+					require.Equal(t, account.ID.String(), req.AccountID)
+					return &HookResult{Claims: map[string]interface{}{"org": "senao"}}, nil
+				}),
+			},
+			verify: func(result *HookResult) {
+				require.False(t, result.Deny)
+				require.Equal(t, map[string]interface{}{"tier": "gold", "org": "senao"}, result.Claims)
+				require.Equal(t, map[string]string{"plan": "pro"}, result.Metadata)
+			},
+		},
+		{
+			name: "deny stops the chain",
+			hooks: []Hook{
+				staticHook(&HookResult{Deny: true, Reason: "Outside office hours"}, nil),
+				HookFunc(func(ctx context.Context, req HookRequest) (*HookResult, error) {
+					t.Fatal("hook after a denial must not run")
+					return nil, nil
+				}),
+			},
+			verify: func(result *HookResult) {
+				require.True(t, result.Deny)
+				require.Equal(t, "Outside office hours", result.Reason)
+			},
+		},
+		{
+			name:  "error fails closed",
+			hooks: []Hook{staticHook(nil, errors.New("boom"))},
+			verify: func(result *HookResult) {
+				require.True(t, result.Deny)
+				require.Equal(t, ErrHookDenied.Error(), result.Reason)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			hooks := Hooks{}
+			for _, hook := range tc.hooks {
+				require.NoError(t, hooks.Register(HookPostLogin, hook))
+			}
+			tc.verify(hooks.Run(context.Background(), HookPostLogin, account))
+		})
+	}
+}
+
+func TestHooksRegisterInvalidStage(t *testing.T) {
+	require.ErrorIs(t, Hooks{}.Register("pre_delete", staticHook(nil, nil)), ErrInvalidHookStage)
+}
+
+func TestReservedUsernameHook(t *testing.T) {
+	hook := NewReservedUsernameHook("admin", " Support ")
+
+	result, err := hook.Run(context.Background(), HookRequest{Stage: HookPreCreate, Username: "support"})
+	require.NoError(t, err)
+	require.True(t, result.Deny)
+
+	result, err = hook.Run(context.Background(), HookRequest{Stage: HookPreCreate, Username: "alice"})
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
+
+func TestHTTPHook(t *testing.T) {
+	secret := util.RandomString(32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		timestamp := r.Header.Get(HookTimestampHeader)
+		require.Equal(t, "sha256="+SignWebhookPayload(secret, timestamp, body), r.Header.Get(HookSignatureHeader))
+
+		req := HookRequest{}
+		require.NoError(t, json.Unmarshal(body, &req))
+		require.Equal(t, HookPreCreate, req.Stage)
+		json.NewEncoder(w).Encode(HookResult{Deny: req.Username == "partner", Reason: "Username is reserved by a partner"})
+	}))
+	defer server.Close()
+	hook := NewHTTPHook(server.URL, secret, time.Second, false)
+
+	result, err := hook.Run(context.Background(), HookRequest{Stage: HookPreCreate, Username: "partner"})
+	require.NoError(t, err)
+	require.True(t, result.Deny)
+	require.Equal(t, "Username is reserved by a partner", result.Reason)
+
+	result, err = hook.Run(context.Background(), HookRequest{Stage: HookPreCreate, Username: "alice"})
+	require.NoError(t, err)
+	require.False(t, result.Deny)
+}
+
+func TestHTTPHookTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	_, err := NewHTTPHook(server.URL, "", 50*time.Millisecond, false).Run(context.Background(), HookRequest{Stage: HookPreLogin})
+	require.Error(t, err)
+
+	result, err := NewHTTPHook(server.URL, "", 50*time.Millisecond, true).Run(context.Background(), HookRequest{Stage: HookPreLogin})
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
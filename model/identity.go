@@ -0,0 +1,618 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ScopeIdentities lets a token list, link and unlink the login methods of its account.
+const ScopeIdentities = "identities"
+
+// LoginMethodPassword is the type of the password in a list of login methods, the other types are
+// those of repository.Identity.
+const LoginMethodPassword = "password"
+
+// Audiences keep the tokens of this file from being accepted as any other token we sign.
+const (
+	reauthenticationAudience = "reauthentication"
+	totpEnrollmentAudience   = "totp-enrollment"
+	passkeyCeremonyAudience  = "passkey"
+)
+
+var (
+	// reauthenticationWindow is how long after signing in a token may change login methods without
+	// re-entering the password, and how long a re-authentication token lasts.
+	reauthenticationWindow = 5 * time.Minute
+	totpEnrollmentTTL      = 10 * time.Minute
+	passkeyCeremonyTTL     = 5 * time.Minute
+	passkeyChallengeBytes  = 32
+	defaultPasskeyName     = "Passkey"
+	defaultTOTPName        = "Authenticator app"
+)
+
+var (
+	ErrReauthenticationRequired = errors.New("Re-authentication is required, sign in again or send a re-authentication token")
+	ErrInvalidLoginMethodID     = errors.New("Invalid login method id")
+	ErrLoginMethodNotFound      = errors.New("Login method not found")
+	ErrLoginMethodAlreadyLinked = errors.New("Login method is already linked")
+	ErrLastLoginMethod          = errors.New("Cannot remove the last login method of the account")
+	ErrInvalidEnrollment        = errors.New("Invalid or expired enrollment, please start again")
+	ErrPasskeyLoginFailed       = errors.New("Passkey login failed")
+)
+
+// IdentityUsecase manages the ways an account signs in: a password, identities at external
+// providers, passkeys and TOTP. Changes need a recent sign-in or a re-authentication token, and the
+// last method that can sign in on its own (password, external identity or passkey) cannot be removed.
+type IdentityUsecase interface {
+	ListLoginMethods(ctx context.Context, principal *Principal) ([]LoginMethod, error)
+	// Reauthenticate checks the password, and one-time code when TOTP is linked, like a login and
+	// returns a short-lived token that allows changing login methods.
+	Reauthenticate(ctx context.Context, principal *Principal, req ReauthenticateRequest) (*ReauthenticateResponse, error)
+	SetPassword(ctx context.Context, principal *Principal, reauth string, req SetPasswordRequest) (*AccountResponse, error)
+	RemovePassword(ctx context.Context, principal *Principal, reauth string) error
+	BeginTOTP(ctx context.Context, principal *Principal) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, principal *Principal, reauth string, req ConfirmTOTPRequest) (*LoginMethod, error)
+	BeginPasskey(ctx context.Context, principal *Principal) (*PasskeyCeremony, error)
+	FinishPasskey(ctx context.Context, principal *Principal, reauth string, req FinishPasskeyRequest) (*LoginMethod, error)
+	// LinkProvider returns the URL the browser opens to link an identity at provider.
+	LinkProvider(ctx context.Context, principal *Principal, reauth string, provider string) (string, error)
+	Unlink(ctx context.Context, principal *Principal, reauth string, id string) error
+	BeginPasskeyLogin(ctx context.Context) (*PasskeyCeremony, error)
+	FinishPasskeyLogin(ctx context.Context, req FinishPasskeyRequest) (*PasskeyLoginResponse, error)
+}
+
+type identityUsecase struct {
+	repo       repository.IdentityRepository
+	accounts   repository.AccountRepository
+	usecase    UsecaseHandler
+	federation FederationUsecase
+	audit      AuditUsecase
+	events     EventPublisher
+	signer     TokenSigner
+	masterKey  []byte
+	webauthn   WebAuthnConfig
+	issuer     string
+}
+
+// reauthenticationClaims are the claims of a re-authentication token, Subject is the account ID.
+type reauthenticationClaims struct {
+	jwt.RegisteredClaims
+}
+
+// totpEnrollmentClaims carry a TOTP secret, sealed with the master key, until its first code confirms it.
+type totpEnrollmentClaims struct {
+	jwt.RegisteredClaims
+	Secret string `json:"secret"`
+}
+
+// passkeyCeremonyClaims carry the challenge of a WebAuthn ceremony. Subject is the account a passkey
+// is registered to, and empty for a login.
+type passkeyCeremonyClaims struct {
+	jwt.RegisteredClaims
+	Ceremony  string `json:"ceremony"`
+	Challenge string `json:"challenge"`
+}
+
+// NewIdentityUsecase manages login methods. TOTP secrets are sealed with masterKey, and passkeys are
+// registered with the relying party in webauthn, whose RPName also labels TOTP entries.
+func NewIdentityUsecase(repo repository.IdentityRepository, accounts repository.AccountRepository, usecase UsecaseHandler,
+	federation FederationUsecase, audit AuditUsecase, events EventPublisher, signer TokenSigner, masterKey []byte,
+	webauthn WebAuthnConfig, issuer string) IdentityUsecase {
+	return &identityUsecase{
+		repo:       repo,
+		accounts:   accounts,
+		usecase:    usecase,
+		federation: federation,
+		audit:      audit,
+		events:     events,
+		signer:     signer,
+		masterKey:  masterKey,
+		webauthn:   webauthn,
+		issuer:     issuer,
+	}
+}
+
+func (u *identityUsecase) ListLoginMethods(ctx context.Context, principal *Principal) ([]LoginMethod, error) {
+	account, err := u.account(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := u.repo.ListIdentities(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	methods := make([]LoginMethod, 0, len(identities)+1)
+	if account.HashedPassword != "" {
+		methods = append(methods, LoginMethod{Type: LoginMethodPassword})
+	}
+	for i := range identities {
+		methods = append(methods, loginMethod(&identities[i]))
+	}
+	return methods, nil
+}
+
+func (u *identityUsecase) Reauthenticate(ctx context.Context, principal *Principal, req ReauthenticateRequest) (*ReauthenticateResponse, error) {
+	account, err := u.account(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	// Going through the login keeps the lockout and the audit trail of password guesses.
+	if _, err := u.usecase.LoginAccount(ctx, AccountRequest{Username: account.Username, Password: req.Password, OTP: req.OTP}); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	token, err := u.signer.Sign(reauthenticationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    u.issuer,
+			Subject:   account.ID.String(),
+			Audience:  jwt.ClaimStrings{reauthenticationAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(reauthenticationWindow)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReauthenticateResponse{
+		ReauthToken: token,
+		ExpiresIn:   int(reauthenticationWindow.Seconds()),
+	}, nil
+}
+
+func (u *identityUsecase) SetPassword(ctx context.Context, principal *Principal, reauth string, req SetPasswordRequest) (*AccountResponse, error) {
+	rsp := &AccountResponse{
+		Success: false,
+		Reason:  "",
+	}
+	if err := req.Validate(); err != nil {
+		rsp.Reason = err.Error()
+		return rsp, ErrAccountRequestValidationFailed
+	}
+	if err := u.requireReauthentication(principal, reauth); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := util.HashedPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.SetPassword(ctx, principal.AccountID, hashedPassword); err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	rsp.Success = true
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditPasswordChanged,
+		Actor:   principal.Username,
+		Target:  principal.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
+	return rsp, nil
+}
+
+func (u *identityUsecase) RemovePassword(ctx context.Context, principal *Principal, reauth string) error {
+	if err := u.requireReauthentication(principal, reauth); err != nil {
+		return err
+	}
+	if err := u.repo.RemovePassword(ctx, principal.AccountID); err != nil {
+		return u.unlinkError(err)
+	}
+	u.recordUnlinked(ctx, principal, "type="+LoginMethodPassword)
+	return nil
+}
+
+func (u *identityUsecase) BeginTOTP(ctx context.Context, principal *Principal) (*TOTPEnrollment, error) {
+	identities, err := u.repo.ListIdentities(ctx, principal.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range identities {
+		if identities[i].Type == repository.IdentityTypeTOTP {
+			return nil, ErrLoginMethodAlreadyLinked
+		}
+	}
+	secret, err := randomBytes(totpSecretBytes)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := util.Seal(u.masterKey, secret, totpEnrollmentAdditionalData(principal.AccountID))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	enrollment, err := u.signer.Sign(totpEnrollmentClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    u.issuer,
+			Subject:   principal.AccountID.String(),
+			Audience:  jwt.ClaimStrings{totpEnrollmentAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(totpEnrollmentTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Secret: base64.RawURLEncoding.EncodeToString(sealed),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{
+		Secret:     base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret),
+		URI:        totpURI(u.webauthn.RPName, principal.Username, secret),
+		Enrollment: enrollment,
+	}, nil
+}
+
+func (u *identityUsecase) ConfirmTOTP(ctx context.Context, principal *Principal, reauth string, req ConfirmTOTPRequest) (*LoginMethod, error) {
+	if err := u.requireReauthentication(principal, reauth); err != nil {
+		return nil, err
+	}
+	claims := &totpEnrollmentClaims{}
+	if !u.verifyClaims(req.Enrollment, claims, totpEnrollmentAudience) || claims.Subject != principal.AccountID.String() {
+		return nil, ErrInvalidEnrollment
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(claims.Secret)
+	if err != nil {
+		return nil, ErrInvalidEnrollment
+	}
+	secret, err := util.Open(u.masterKey, sealed, totpEnrollmentAdditionalData(principal.AccountID))
+	if err != nil {
+		return nil, ErrInvalidEnrollment
+	}
+	step, ok := matchTOTP(secret, req.Code, time.Now(), 0)
+	if !ok {
+		return nil, ErrLoginWrongOTP
+	}
+
+	identity := &repository.Identity{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		AccountID: principal.AccountID,
+		Type:      repository.IdentityTypeTOTP,
+		Provider:  repository.IdentityTypeTOTP,
+		Subject:   principal.AccountID.String(),
+		Name:      defaultTOTPName,
+		Counter:   step,
+	}
+	if identity.Credential, err = sealTOTPSecret(u.masterKey, identity.ID, secret); err != nil {
+		return nil, err
+	}
+	if err := u.link(ctx, principal, identity); err != nil {
+		return nil, err
+	}
+	method := loginMethod(identity)
+	return &method, nil
+}
+
+func (u *identityUsecase) BeginPasskey(ctx context.Context, principal *Principal) (*PasskeyCeremony, error) {
+	identities, err := u.repo.ListIdentities(ctx, principal.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	// The browser refuses an authenticator that already holds a passkey of the account.
+	exclude := []PasskeyCredentialDescriptor{}
+	for _, identity := range identities {
+		if identity.Type == repository.IdentityTypePasskey {
+			exclude = append(exclude, PasskeyCredentialDescriptor{Type: "public-key", ID: identity.Subject})
+		}
+	}
+	ceremony, challenge, err := u.startCeremony(webauthnCeremonyCreate, principal.AccountID.String())
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyCeremony{
+		Ceremony: ceremony,
+		PublicKey: PasskeyCreationOptions{
+			Challenge: challenge,
+			RP:        PasskeyRelyingParty{ID: u.webauthn.RPID, Name: u.webauthn.RPName},
+			User: PasskeyUser{
+				ID:          passkeyUserHandle(principal.AccountID),
+				Name:        principal.Username,
+				DisplayName: principal.Username,
+			},
+			PubKeyCredParams: []PasskeyCredentialParameters{
+				{Type: "public-key", Alg: coseAlgES256},
+				{Type: "public-key", Alg: coseAlgEdDSA},
+				{Type: "public-key", Alg: coseAlgRS256},
+			},
+			Timeout:            int(passkeyCeremonyTTL.Milliseconds()),
+			ExcludeCredentials: exclude,
+			AuthenticatorSelection: PasskeyAuthenticatorSelection{
+				ResidentKey:      "required",
+				UserVerification: "required",
+			},
+			Attestation: "none",
+		},
+	}, nil
+}
+
+func (u *identityUsecase) FinishPasskey(ctx context.Context, principal *Principal, reauth string, req FinishPasskeyRequest) (*LoginMethod, error) {
+	if err := u.requireReauthentication(principal, reauth); err != nil {
+		return nil, err
+	}
+	claims := &passkeyCeremonyClaims{}
+	if !u.verifyClaims(req.Ceremony, claims, passkeyCeremonyAudience) || claims.Ceremony != webauthnCeremonyCreate ||
+		claims.Subject != principal.AccountID.String() {
+		return nil, ErrInvalidEnrollment
+	}
+	credential, err := u.webauthn.verifyRegistration(req.Credential, claims.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	name := req.Name
+	if name == "" {
+		name = defaultPasskeyName
+	}
+	identity := &repository.Identity{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now(),
+		AccountID:  principal.AccountID,
+		Type:       repository.IdentityTypePasskey,
+		Provider:   repository.IdentityTypePasskey,
+		Subject:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:       name,
+		Credential: publicKey,
+		Counter:    int64(credential.SignCount),
+	}
+	if err := u.link(ctx, principal, identity); err != nil {
+		return nil, err
+	}
+	method := loginMethod(identity)
+	return &method, nil
+}
+
+func (u *identityUsecase) LinkProvider(ctx context.Context, principal *Principal, reauth string, provider string) (string, error) {
+	if err := u.requireReauthentication(principal, reauth); err != nil {
+		return "", err
+	}
+	return u.federation.LinkURL(ctx, provider, principal.AccountID)
+}
+
+func (u *identityUsecase) Unlink(ctx context.Context, principal *Principal, reauth string, id string) error {
+	identityID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidLoginMethodID
+	}
+	if err := u.requireReauthentication(principal, reauth); err != nil {
+		return err
+	}
+	identity, err := u.repo.DeleteIdentity(ctx, principal.AccountID, identityID)
+	if err != nil {
+		return u.unlinkError(err)
+	}
+	u.recordUnlinked(ctx, principal, "type="+identity.Type+" provider="+identity.Provider)
+	return nil
+}
+
+func (u *identityUsecase) BeginPasskeyLogin(ctx context.Context) (*PasskeyCeremony, error) {
+	ceremony, challenge, err := u.startCeremony(webauthnCeremonyGet, "")
+	if err != nil {
+		return nil, err
+	}
+	// No credentials are listed, the authenticator offers the passkeys it holds for the RP ID.
+	return &PasskeyCeremony{
+		Ceremony: ceremony,
+		PublicKey: PasskeyRequestOptions{
+			Challenge:        challenge,
+			Timeout:          int(passkeyCeremonyTTL.Milliseconds()),
+			RPID:             u.webauthn.RPID,
+			UserVerification: "required",
+		},
+	}, nil
+}
+
+func (u *identityUsecase) FinishPasskeyLogin(ctx context.Context, req FinishPasskeyRequest) (*PasskeyLoginResponse, error) {
+	rsp := &PasskeyLoginResponse{
+		Success: false,
+		Reason:  "",
+	}
+	claims := &passkeyCeremonyClaims{}
+	if !u.verifyClaims(req.Ceremony, claims, passkeyCeremonyAudience) || claims.Ceremony != webauthnCeremonyGet {
+		return u.denyPasskeyLogin(ctx, rsp, "", ErrInvalidEnrollment.Error())
+	}
+	identity, err := u.repo.GetIdentity(ctx, repository.IdentityTypePasskey, strings.TrimRight(req.Credential.ID, "="))
+	if err != nil {
+		if errors.Is(err, repository.ErrIdentityNotFound) {
+			return u.denyPasskeyLogin(ctx, rsp, "", "unknown credential")
+		}
+		return nil, err
+	}
+	account, err := u.accounts.GetAccountByID(ctx, identity.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(identity.Credential)
+	if err != nil {
+		return nil, err
+	}
+	if handle := req.Credential.Response.UserHandle; handle != "" && handle != passkeyUserHandle(account.ID) {
+		return u.denyPasskeyLogin(ctx, rsp, account.Username, "user handle does not match")
+	}
+	signCount, err := u.webauthn.verifyAssertion(req.Credential, claims.Challenge, publicKey)
+	if err != nil {
+		return u.denyPasskeyLogin(ctx, rsp, account.Username, err.Error())
+	}
+	if account.LockedAt != nil {
+		rsp.Reason = ErrLoginAccountLocked.Error()
+		u.audit.Record(ctx, AuditEntry{
+			Type:    AuditLoginDenied,
+			Target:  account.Username,
+			Outcome: AuditOutcomeFailure,
+			Detail:  "type=" + repository.IdentityTypePasskey + " " + rsp.Reason,
+		})
+		return rsp, ErrLoginAccountLocked
+	}
+	// Authenticators that count signatures must move forward, or the passkey was cloned. Synced
+	// passkeys always send zero.
+	now := time.Now()
+	if signCount != 0 || identity.Counter != 0 {
+		if err := u.repo.UseIdentityCounter(ctx, identity.ID, int64(signCount), now); err != nil {
+			if err == repository.ErrIdentityCounterReplayed {
+				return u.denyPasskeyLogin(ctx, rsp, account.Username, "signature counter went back")
+			}
+			return nil, err
+		}
+	} else if err := u.repo.TouchIdentity(ctx, identity.ID, now); err != nil {
+		log.Warn().Err(err).Str("username", account.Username).Msg("failed to record passkey login")
+	}
+
+	rsp.Success = true
+	rsp.Username = account.Username
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginSucceeded,
+		Actor:   account.Username,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "type=" + repository.IdentityTypePasskey,
+	})
+	u.events.Publish(ctx, NewAccountEvent(EventAccountLoginSucceeded, account, ""))
+	return rsp, nil
+}
+
+func (u *identityUsecase) denyPasskeyLogin(ctx context.Context, rsp *PasskeyLoginResponse, username string, detail string) (*PasskeyLoginResponse, error) {
+	rsp.Reason = ErrPasskeyLoginFailed.Error()
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginDenied,
+		Target:  username,
+		Outcome: AuditOutcomeFailure,
+		Detail:  "type=" + repository.IdentityTypePasskey + " " + detail,
+	})
+	return rsp, ErrPasskeyLoginFailed
+}
+
+func (u *identityUsecase) account(ctx context.Context, principal *Principal) (*repository.Account, error) {
+	account, err := u.accounts.GetAccountByID(ctx, principal.AccountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+// requireReauthentication passes a token from a sign-in within reauthenticationWindow, or one that
+// comes with a re-authentication token of its account.
+func (u *identityUsecase) requireReauthentication(principal *Principal, reauth string) error {
+	if !principal.AuthTime.IsZero() && time.Since(principal.AuthTime) < reauthenticationWindow {
+		return nil
+	}
+	claims := &reauthenticationClaims{}
+	if reauth == "" || !u.verifyClaims(reauth, claims, reauthenticationAudience) || claims.Subject != principal.AccountID.String() {
+		return ErrReauthenticationRequired
+	}
+	return nil
+}
+
+func (u *identityUsecase) verifyClaims(token string, claims interface {
+	jwt.Claims
+	VerifyAudience(string, bool) bool
+	VerifyIssuer(string, bool) bool
+}, audience string) bool {
+	return u.signer.Verify(token, claims) == nil && claims.VerifyAudience(audience, true) && claims.VerifyIssuer(u.issuer, true)
+}
+
+// startCeremony returns a signed ceremony and the challenge in it.
+func (u *identityUsecase) startCeremony(ceremony string, subject string) (string, string, error) {
+	raw, err := randomBytes(passkeyChallengeBytes)
+	if err != nil {
+		return "", "", err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(raw)
+	now := time.Now()
+	token, err := u.signer.Sign(passkeyCeremonyClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    u.issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{passkeyCeremonyAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(passkeyCeremonyTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Ceremony:  ceremony,
+		Challenge: challenge,
+	})
+	return token, challenge, err
+}
+
+func (u *identityUsecase) link(ctx context.Context, principal *Principal, identity *repository.Identity) error {
+	if err := u.repo.CreateIdentity(ctx, identity); err != nil {
+		if errors.Is(err, repository.ErrIdentityIsDuplicated) {
+			return ErrLoginMethodAlreadyLinked
+		}
+		return err
+	}
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditIdentityLinked,
+		Actor:   principal.Username,
+		Target:  principal.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "type=" + identity.Type,
+	})
+	return nil
+}
+
+func (u *identityUsecase) unlinkError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrIdentityNotFound):
+		return ErrLoginMethodNotFound
+	case errors.Is(err, repository.ErrLastLoginMethod):
+		return ErrLastLoginMethod
+	case errors.Is(err, repository.ErrAccountRecordNotFound):
+		return ErrAccountNotFound
+	}
+	return err
+}
+
+func (u *identityUsecase) recordUnlinked(ctx context.Context, principal *Principal, detail string) {
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditIdentityUnlinked,
+		Actor:   principal.Username,
+		Target:  principal.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  detail,
+	})
+}
+
+func loginMethod(identity *repository.Identity) LoginMethod {
+	createdAt := identity.CreatedAt
+	return LoginMethod{
+		ID:         identity.ID.String(),
+		Type:       identity.Type,
+		Provider:   identity.Provider,
+		Name:       identity.Name,
+		Email:      identity.Email,
+		CreatedAt:  &createdAt,
+		LastUsedAt: identity.LastLoginAt,
+	}
+}
+
+// passkeyUserHandle is the WebAuthn user handle of an account, the bytes of its ID.
+func passkeyUserHandle(accountID uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(accountID[:])
+}
+
+func totpEnrollmentAdditionalData(accountID uuid.UUID) []byte {
+	return []byte("totp-enrollment:" + accountID.String())
+}
+
+func randomBytes(n int) ([]byte, error) {
+	value := make([]byte, n)
+	if _, err := rand.Read(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,213 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: identity.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIdentityUsecase is a mock of IdentityUsecase interface.
+type MockIdentityUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentityUsecaseMockRecorder
+}
+
+// MockIdentityUsecaseMockRecorder is the mock recorder for MockIdentityUsecase.
+type MockIdentityUsecaseMockRecorder struct {
+	mock *MockIdentityUsecase
+}
+
+// NewMockIdentityUsecase creates a new mock instance.
+func NewMockIdentityUsecase(ctrl *gomock.Controller) *MockIdentityUsecase {
+	mock := &MockIdentityUsecase{ctrl: ctrl}
+	mock.recorder = &MockIdentityUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdentityUsecase) EXPECT() *MockIdentityUsecaseMockRecorder {
+	return m.recorder
+}
+
+// BeginPasskey mocks base method.
+func (m *MockIdentityUsecase) BeginPasskey(ctx context.Context, principal *Principal) (*PasskeyCeremony, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginPasskey", ctx, principal)
+	ret0, _ := ret[0].(*PasskeyCeremony)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginPasskey indicates an expected call of BeginPasskey.
+func (mr *MockIdentityUsecaseMockRecorder) BeginPasskey(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginPasskey", reflect.TypeOf((*MockIdentityUsecase)(nil).BeginPasskey), ctx, principal)
+}
+
+// BeginPasskeyLogin mocks base method.
+func (m *MockIdentityUsecase) BeginPasskeyLogin(ctx context.Context) (*PasskeyCeremony, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginPasskeyLogin", ctx)
+	ret0, _ := ret[0].(*PasskeyCeremony)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginPasskeyLogin indicates an expected call of BeginPasskeyLogin.
+func (mr *MockIdentityUsecaseMockRecorder) BeginPasskeyLogin(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginPasskeyLogin", reflect.TypeOf((*MockIdentityUsecase)(nil).BeginPasskeyLogin), ctx)
+}
+
+// BeginTOTP mocks base method.
+func (m *MockIdentityUsecase) BeginTOTP(ctx context.Context, principal *Principal) (*TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginTOTP", ctx, principal)
+	ret0, _ := ret[0].(*TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginTOTP indicates an expected call of BeginTOTP.
+func (mr *MockIdentityUsecaseMockRecorder) BeginTOTP(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTOTP", reflect.TypeOf((*MockIdentityUsecase)(nil).BeginTOTP), ctx, principal)
+}
+
+// ConfirmTOTP mocks base method.
+func (m *MockIdentityUsecase) ConfirmTOTP(ctx context.Context, principal *Principal, reauth string, req ConfirmTOTPRequest) (*LoginMethod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", ctx, principal, reauth, req)
+	ret0, _ := ret[0].(*LoginMethod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockIdentityUsecaseMockRecorder) ConfirmTOTP(ctx, principal, reauth, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockIdentityUsecase)(nil).ConfirmTOTP), ctx, principal, reauth, req)
+}
+
+// FinishPasskey mocks base method.
+func (m *MockIdentityUsecase) FinishPasskey(ctx context.Context, principal *Principal, reauth string, req FinishPasskeyRequest) (*LoginMethod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishPasskey", ctx, principal, reauth, req)
+	ret0, _ := ret[0].(*LoginMethod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinishPasskey indicates an expected call of FinishPasskey.
+func (mr *MockIdentityUsecaseMockRecorder) FinishPasskey(ctx, principal, reauth, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishPasskey", reflect.TypeOf((*MockIdentityUsecase)(nil).FinishPasskey), ctx, principal, reauth, req)
+}
+
+// FinishPasskeyLogin mocks base method.
+func (m *MockIdentityUsecase) FinishPasskeyLogin(ctx context.Context, req FinishPasskeyRequest) (*PasskeyLoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishPasskeyLogin", ctx, req)
+	ret0, _ := ret[0].(*PasskeyLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinishPasskeyLogin indicates an expected call of FinishPasskeyLogin.
+func (mr *MockIdentityUsecaseMockRecorder) FinishPasskeyLogin(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishPasskeyLogin", reflect.TypeOf((*MockIdentityUsecase)(nil).FinishPasskeyLogin), ctx, req)
+}
+
+// LinkProvider mocks base method.
+func (m *MockIdentityUsecase) LinkProvider(ctx context.Context, principal *Principal, reauth, provider string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkProvider", ctx, principal, reauth, provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkProvider indicates an expected call of LinkProvider.
+func (mr *MockIdentityUsecaseMockRecorder) LinkProvider(ctx, principal, reauth, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkProvider", reflect.TypeOf((*MockIdentityUsecase)(nil).LinkProvider), ctx, principal, reauth, provider)
+}
+
+// ListLoginMethods mocks base method.
+func (m *MockIdentityUsecase) ListLoginMethods(ctx context.Context, principal *Principal) ([]LoginMethod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoginMethods", ctx, principal)
+	ret0, _ := ret[0].([]LoginMethod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoginMethods indicates an expected call of ListLoginMethods.
+func (mr *MockIdentityUsecaseMockRecorder) ListLoginMethods(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoginMethods", reflect.TypeOf((*MockIdentityUsecase)(nil).ListLoginMethods), ctx, principal)
+}
+
+// Reauthenticate mocks base method.
+func (m *MockIdentityUsecase) Reauthenticate(ctx context.Context, principal *Principal, req ReauthenticateRequest) (*ReauthenticateResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reauthenticate", ctx, principal, req)
+	ret0, _ := ret[0].(*ReauthenticateResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reauthenticate indicates an expected call of Reauthenticate.
+func (mr *MockIdentityUsecaseMockRecorder) Reauthenticate(ctx, principal, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reauthenticate", reflect.TypeOf((*MockIdentityUsecase)(nil).Reauthenticate), ctx, principal, req)
+}
+
+// RemovePassword mocks base method.
+func (m *MockIdentityUsecase) RemovePassword(ctx context.Context, principal *Principal, reauth string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePassword", ctx, principal, reauth)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePassword indicates an expected call of RemovePassword.
+func (mr *MockIdentityUsecaseMockRecorder) RemovePassword(ctx, principal, reauth interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePassword", reflect.TypeOf((*MockIdentityUsecase)(nil).RemovePassword), ctx, principal, reauth)
+}
+
+// SetPassword mocks base method.
+func (m *MockIdentityUsecase) SetPassword(ctx context.Context, principal *Principal, reauth string, req SetPasswordRequest) (*AccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassword", ctx, principal, reauth, req)
+	ret0, _ := ret[0].(*AccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetPassword indicates an expected call of SetPassword.
+func (mr *MockIdentityUsecaseMockRecorder) SetPassword(ctx, principal, reauth, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassword", reflect.TypeOf((*MockIdentityUsecase)(nil).SetPassword), ctx, principal, reauth, req)
+}
+
+// Unlink mocks base method.
+func (m *MockIdentityUsecase) Unlink(ctx context.Context, principal *Principal, reauth, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlink", ctx, principal, reauth, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlink indicates an expected call of Unlink.
+func (mr *MockIdentityUsecaseMockRecorder) Unlink(ctx, principal, reauth, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlink", reflect.TypeOf((*MockIdentityUsecase)(nil).Unlink), ctx, principal, reauth, id)
+}
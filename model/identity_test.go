@@ -0,0 +1,355 @@
+package model
+
+import (
+	"context"
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type identityMocks struct {
+	repo       *repository.MockIdentityRepository
+	accounts   *repository.MockAccountRepository
+	usecase    *MockUsecaseHandler
+	federation *MockFederationUsecase
+	audit      *MockAuditUsecase
+	events     *MockEventPublisher
+	masterKey  []byte
+}
+
+func newTestIdentityUsecase(t *testing.T) (IdentityUsecase, identityMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := identityMocks{
+		repo:       repository.NewMockIdentityRepository(ctrl),
+		accounts:   repository.NewMockAccountRepository(ctrl),
+		usecase:    NewMockUsecaseHandler(ctrl),
+		federation: NewMockFederationUsecase(ctrl),
+		audit:      NewMockAuditUsecase(ctrl),
+		events:     NewMockEventPublisher(ctrl),
+		masterKey:  newTestMasterKey(t),
+	}
+	identities := NewIdentityUsecase(mocks.repo, mocks.accounts, mocks.usecase, mocks.federation, mocks.audit, mocks.events,
+		NewTokenSigner(newTestKeySet(t)), mocks.masterKey, testWebAuthn, testIssuer)
+	return identities, mocks
+}
+
+func newTestPrincipal(authTime time.Time) *Principal {
+	return &Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{ScopeIdentities}, AuthTime: authTime}
+}
+
+func TestListLoginMethods(t *testing.T) {
+	identities, mocks := newTestIdentityUsecase(t)
+	principal := newTestPrincipal(time.Time{})
+	account := &repository.Account{ID: principal.AccountID, Username: "alice", HashedPassword: "hashed"}
+	passkey := repository.Identity{ID: uuid.New(), CreatedAt: time.Now(), AccountID: account.ID, Type: repository.IdentityTypePasskey,
+		Provider: repository.IdentityTypePasskey, Subject: "credential", Name: "Laptop"}
+	mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+	mocks.repo.EXPECT().ListIdentities(gomock.Any(), account.ID).Return([]repository.Identity{passkey}, nil)
+
+	methods, err := identities.ListLoginMethods(context.Background(), principal)
+	require.NoError(t, err)
+	require.Len(t, methods, 2)
+	require.Equal(t, LoginMethod{Type: LoginMethodPassword}, methods[0])
+	require.Equal(t, passkey.ID.String(), methods[1].ID)
+	require.Equal(t, "Laptop", methods[1].Name)
+}
+
+func TestReauthenticate(t *testing.T) {
+	identities, mocks := newTestIdentityUsecase(t)
+	principal := newTestPrincipal(time.Time{})
+	account := &repository.Account{ID: principal.AccountID, Username: "alice"}
+	mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Times(2).Return(account, nil)
+
+	mocks.usecase.EXPECT().LoginAccount(gomock.Any(), AccountRequest{Username: "alice", Password: "wrong"}).
+		Return(&AccountResponse{Reason: ErrLoginWrongPassword.Error()}, ErrLoginWrongPassword)
+	_, err := identities.Reauthenticate(context.Background(), principal, ReauthenticateRequest{Password: "wrong"})
+	require.Equal(t, ErrLoginWrongPassword, err)
+
+	mocks.usecase.EXPECT().LoginAccount(gomock.Any(), AccountRequest{Username: "alice", Password: "Secret123", OTP: "123456"}).
+		Return(&AccountResponse{Success: true}, nil)
+	rsp, err := identities.Reauthenticate(context.Background(), principal, ReauthenticateRequest{Password: "Secret123", OTP: "123456"})
+	require.NoError(t, err)
+	require.NotEmpty(t, rsp.ReauthToken)
+
+	// The token allows a change the old sign-in alone does not.
+	require.Equal(t, ErrReauthenticationRequired, identities.RemovePassword(context.Background(), principal, ""))
+	mocks.repo.EXPECT().RemovePassword(gomock.Any(), account.ID).Return(nil)
+	mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditIdentityUnlinked, AuditOutcomeSuccess))
+	require.NoError(t, identities.RemovePassword(context.Background(), principal, rsp.ReauthToken))
+
+	// It is only good for its own account.
+	other := newTestPrincipal(time.Time{})
+	require.Equal(t, ErrReauthenticationRequired, identities.RemovePassword(context.Background(), other, rsp.ReauthToken))
+}
+
+func TestRemovePassword(t *testing.T) {
+	testCase := []struct {
+		name      string
+		authTime  time.Time
+		buildStub func(mocks identityMocks, principal *Principal)
+		err       error
+	}{
+		{
+			name:     "ok",
+			authTime: time.Now().Add(-time.Minute),
+			buildStub: func(mocks identityMocks, principal *Principal) {
+				mocks.repo.EXPECT().RemovePassword(gomock.Any(), principal.AccountID).Return(nil)
+				mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditIdentityUnlinked, AuditOutcomeSuccess))
+			},
+		},
+		{
+			name:     "last login method",
+			authTime: time.Now().Add(-time.Minute),
+			buildStub: func(mocks identityMocks, principal *Principal) {
+				mocks.repo.EXPECT().RemovePassword(gomock.Any(), principal.AccountID).Return(repository.ErrLastLoginMethod)
+			},
+			err: ErrLastLoginMethod,
+		},
+		{
+			name:     "sign-in too old",
+			authTime: time.Now().Add(-time.Hour),
+			buildStub: func(mocks identityMocks, principal *Principal) {
+				mocks.repo.EXPECT().RemovePassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: ErrReauthenticationRequired,
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			identities, mocks := newTestIdentityUsecase(t)
+			principal := newTestPrincipal(tc.authTime)
+			tc.buildStub(mocks, principal)
+			require.Equal(t, tc.err, identities.RemovePassword(context.Background(), principal, ""))
+		})
+	}
+}
+
+func TestSetPassword(t *testing.T) {
+	identities, mocks := newTestIdentityUsecase(t)
+	principal := newTestPrincipal(time.Now())
+
+	rsp, err := identities.SetPassword(context.Background(), principal, "", SetPasswordRequest{Password: "short"})
+	require.Equal(t, ErrAccountRequestValidationFailed, err)
+	require.Equal(t, ErrPasswordIsTooShort.Error(), rsp.Reason)
+
+	mocks.repo.EXPECT().SetPassword(gomock.Any(), principal.AccountID, gomock.Any()).Return(nil)
+	mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditPasswordChanged, AuditOutcomeSuccess))
+	rsp, err = identities.SetPassword(context.Background(), principal, "", SetPasswordRequest{Password: "Secret123"})
+	require.NoError(t, err)
+	require.True(t, rsp.Success)
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	identities, mocks := newTestIdentityUsecase(t)
+	principal := newTestPrincipal(time.Now())
+	mocks.repo.EXPECT().ListIdentities(gomock.Any(), principal.AccountID).Return(nil, nil)
+
+	enrollment, err := identities.BeginTOTP(context.Background(), principal)
+	require.NoError(t, err)
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	require.NoError(t, err)
+	require.Len(t, secret, totpSecretBytes)
+	code := totpCode(secret, time.Now().Unix()/totpPeriod)
+
+	_, err = identities.ConfirmTOTP(context.Background(), principal, "", ConfirmTOTPRequest{Enrollment: enrollment.Enrollment, Code: "000000"})
+	require.Equal(t, ErrLoginWrongOTP, err)
+	_, err = identities.ConfirmTOTP(context.Background(), newTestPrincipal(time.Now()), "", ConfirmTOTPRequest{Enrollment: enrollment.Enrollment, Code: code})
+	require.Equal(t, ErrInvalidEnrollment, err)
+
+	var linked *repository.Identity
+	mocks.repo.EXPECT().CreateIdentity(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, identity *repository.Identity) error {
+		linked = identity
+		return nil
+	})
+	mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditIdentityLinked, AuditOutcomeSuccess))
+	method, err := identities.ConfirmTOTP(context.Background(), principal, "", ConfirmTOTPRequest{Enrollment: enrollment.Enrollment, Code: code})
+	require.NoError(t, err)
+	require.Equal(t, repository.IdentityTypeTOTP, method.Type)
+	require.Equal(t, principal.AccountID, linked.AccountID)
+	require.Equal(t, time.Now().Unix()/totpPeriod, linked.Counter)
+
+	// The stored secret opens for the login check only.
+	stored, err := openTOTPSecret(mocks.masterKey, linked.ID, linked.Credential)
+	require.NoError(t, err)
+	require.Equal(t, secret, stored)
+	_, err = openTOTPSecret(mocks.masterKey, uuid.New(), linked.Credential)
+	require.Error(t, err)
+
+	mocks.repo.EXPECT().ListIdentities(gomock.Any(), principal.AccountID).Return([]repository.Identity{*linked}, nil)
+	_, err = identities.BeginTOTP(context.Background(), principal)
+	require.Equal(t, ErrLoginMethodAlreadyLinked, err)
+}
+
+func TestPasskey(t *testing.T) {
+	identities, mocks := newTestIdentityUsecase(t)
+	principal := newTestPrincipal(time.Now())
+	account := &repository.Account{ID: principal.AccountID, Username: "alice"}
+	authenticator := newTestAuthenticator(t)
+
+	// Registration.
+	mocks.repo.EXPECT().ListIdentities(gomock.Any(), principal.AccountID).Return(nil, nil)
+	registration, err := identities.BeginPasskey(context.Background(), principal)
+	require.NoError(t, err)
+	options := registration.PublicKey.(PasskeyCreationOptions)
+	require.Equal(t, testWebAuthn.RPID, options.RP.ID)
+	require.Equal(t, passkeyUserHandle(account.ID), options.User.ID)
+
+	var passkey *repository.Identity
+	mocks.repo.EXPECT().CreateIdentity(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, identity *repository.Identity) error {
+		passkey = identity
+		return nil
+	})
+	mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditIdentityLinked, AuditOutcomeSuccess))
+	method, err := identities.FinishPasskey(context.Background(), principal, "", FinishPasskeyRequest{
+		Ceremony:   registration.Ceremony,
+		Name:       "Laptop",
+		Credential: authenticator.register(options.Challenge),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Laptop", method.Name)
+	require.Equal(t, authenticator.credentialID(), passkey.Subject)
+
+	// The ceremony of one account does not register a passkey on another.
+	_, err = identities.FinishPasskey(context.Background(), newTestPrincipal(time.Now()), "", FinishPasskeyRequest{
+		Ceremony:   registration.Ceremony,
+		Credential: authenticator.register(options.Challenge),
+	})
+	require.Equal(t, ErrInvalidEnrollment, err)
+
+	// Login.
+	login := func(t *testing.T) (*PasskeyLoginResponse, error) {
+		ceremony, err := identities.BeginPasskeyLogin(context.Background())
+		require.NoError(t, err)
+		challenge := ceremony.PublicKey.(PasskeyRequestOptions).Challenge
+		return identities.FinishPasskeyLogin(context.Background(), FinishPasskeyRequest{
+			Ceremony:   ceremony.Ceremony,
+			Credential: authenticator.assert(challenge, passkeyUserHandle(account.ID)),
+		})
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), repository.IdentityTypePasskey, authenticator.credentialID()).Return(passkey, nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+		mocks.repo.EXPECT().TouchIdentity(gomock.Any(), passkey.ID, gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+		mocks.events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+
+		rsp, err := login(t)
+		require.NoError(t, err)
+		require.Equal(t, &PasskeyLoginResponse{Success: true, Username: "alice"}, rsp)
+	})
+
+	t.Run("locked account", func(t *testing.T) {
+		lockedAt := time.Now()
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), repository.IdentityTypePasskey, authenticator.credentialID()).Return(passkey, nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).
+			Return(&repository.Account{ID: account.ID, Username: "alice", LockedAt: &lockedAt}, nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+		rsp, err := login(t)
+		require.Equal(t, ErrLoginAccountLocked, err)
+		require.False(t, rsp.Success)
+		require.Equal(t, ErrLoginAccountLocked.Error(), rsp.Reason)
+	})
+
+	t.Run("cloned authenticator", func(t *testing.T) {
+		authenticator.counter = 3
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), repository.IdentityTypePasskey, authenticator.credentialID()).Return(passkey, nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+		mocks.repo.EXPECT().UseIdentityCounter(gomock.Any(), passkey.ID, int64(3), gomock.Any()).Return(repository.ErrIdentityCounterReplayed)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+		rsp, err := login(t)
+		require.Equal(t, ErrPasskeyLoginFailed, err)
+		require.False(t, rsp.Success)
+	})
+
+	t.Run("unknown passkey", func(t *testing.T) {
+		mocks.repo.EXPECT().GetIdentity(gomock.Any(), repository.IdentityTypePasskey, authenticator.credentialID()).Return(nil, repository.ErrIdentityNotFound)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+		_, err := login(t)
+		require.Equal(t, ErrPasskeyLoginFailed, err)
+	})
+
+	t.Run("registration ceremony", func(t *testing.T) {
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+		_, err := identities.FinishPasskeyLogin(context.Background(), FinishPasskeyRequest{
+			Ceremony:   registration.Ceremony,
+			Credential: authenticator.assert(options.Challenge, ""),
+		})
+		require.Equal(t, ErrPasskeyLoginFailed, err)
+	})
+}
+
+func TestUnlink(t *testing.T) {
+	identityID := uuid.New()
+	testCase := []struct {
+		name      string
+		id        string
+		buildStub func(mocks identityMocks, principal *Principal)
+		err       error
+	}{
+		{
+			name: "ok",
+			id:   identityID.String(),
+			buildStub: func(mocks identityMocks, principal *Principal) {
+				mocks.repo.EXPECT().DeleteIdentity(gomock.Any(), principal.AccountID, identityID).
+					Return(&repository.Identity{ID: identityID, Type: repository.IdentityTypeOIDC, Provider: "corp"}, nil)
+				mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditIdentityUnlinked, AuditOutcomeSuccess))
+			},
+		},
+		{
+			name:      "invalid id",
+			id:        "1",
+			buildStub: func(mocks identityMocks, principal *Principal) {},
+			err:       ErrInvalidLoginMethodID,
+		},
+		{
+			name: "not found",
+			id:   identityID.String(),
+			buildStub: func(mocks identityMocks, principal *Principal) {
+				mocks.repo.EXPECT().DeleteIdentity(gomock.Any(), principal.AccountID, identityID).Return(nil, repository.ErrIdentityNotFound)
+			},
+			err: ErrLoginMethodNotFound,
+		},
+		{
+			name: "last login method",
+			id:   identityID.String(),
+			buildStub: func(mocks identityMocks, principal *Principal) {
+				mocks.repo.EXPECT().DeleteIdentity(gomock.Any(), principal.AccountID, identityID).Return(nil, repository.ErrLastLoginMethod)
+			},
+			err: ErrLastLoginMethod,
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			identities, mocks := newTestIdentityUsecase(t)
+			principal := newTestPrincipal(time.Now())
+			tc.buildStub(mocks, principal)
+			require.Equal(t, tc.err, identities.Unlink(context.Background(), principal, "", tc.id))
+		})
+	}
+}
+
+func TestLinkProvider(t *testing.T) {
+	identities, mocks := newTestIdentityUsecase(t)
+	principal := newTestPrincipal(time.Now())
+	mocks.federation.EXPECT().LinkURL(gomock.Any(), "corp", principal.AccountID).Return(testIssuer+"/federation/corp/login?link=x", nil)
+
+	linkURL, err := identities.LinkProvider(context.Background(), principal, "", "corp")
+	require.NoError(t, err)
+	require.Equal(t, testIssuer+"/federation/corp/login?link=x", linkURL)
+
+	_, err = identities.LinkProvider(context.Background(), newTestPrincipal(time.Time{}), "", "corp")
+	require.Equal(t, ErrReauthenticationRequired, err)
+}
@@ -0,0 +1,416 @@
+package model
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/rs/zerolog/log"
+)
+
+// What a signing key is used for. Each purpose has its own keys and rotation.
+const (
+	KeyPurposeToken = "token"
+	KeyPurposeAudit = "audit"
+)
+
+var KeyPurposes = []string{KeyPurposeToken, KeyPurposeAudit}
+
+var (
+	ErrUnknownKeyPurpose  = errors.New("Unknown key purpose")
+	ErrSigningKeyNotFound = errors.New("Signing key not found")
+	ErrSigningKeyMismatch = errors.New("Public key does not match the private key sealed under the master key")
+)
+
+type KeyManagerConfig struct {
+	// Algorithms maps a purpose to the algorithm of the keys generated for it.
+	Algorithms map[string]string
+	// RotationPeriod is how long a key stays current.
+	RotationPeriod time.Duration
+	// Overlap is how long a retired key is still published, so tokens it signed keep verifying.
+	Overlap time.Duration
+	// Seeds become the current key of a purpose that has no keys yet, to carry a configured key over.
+	Seeds map[string]crypto.Signer
+}
+
+// KeyManager generates the signing keys of the service, stores them encrypted under a master key and
+// rotates them: each purpose has a current key that signs, a next key that is published ahead of its
+// turn, and retired keys that are published until the overlap ends.
+type KeyManager interface {
+	// Start makes sure every purpose has a current and a next key, and loads them.
+	Start(ctx context.Context) error
+	// KeySet returns the published keys of purpose. It follows rotations.
+	KeySet(purpose string) KeySet
+	// VerificationKeys returns the public half of every key purpose ever had, revoked ones included.
+	// The rows of the signing_keys table are not authenticated, so a key is only returned once its
+	// private half opens under the master key and matches it. A key that does not fails the call.
+	VerificationKeys(ctx context.Context, purpose string) ([]VerificationKey, error)
+	ListKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error)
+	// Rotate retires the current key of purpose, promotes the next key and generates a new next key.
+	// Unless forced, it does nothing before the current key is due.
+	Rotate(ctx context.Context, purpose string, force bool) error
+	// Revoke stops publishing a key at once. When it was the current or next key it is replaced.
+	Revoke(ctx context.Context, id string) (*SigningKeyResponse, error)
+	// Run rotates keys that are due and reloads keys other replicas changed every interval until ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type keyManager struct {
+	repo      repository.SigningKeyRepository
+	masterKey []byte
+	config    KeyManagerConfig
+
+	mu sync.RWMutex
+	// keys holds the published keys of each purpose. decrypted caches private keys by ID.
+	keys      map[string][]SigningKey
+	decrypted map[string]crypto.Signer
+}
+
+func NewKeyManager(repo repository.SigningKeyRepository, masterKey []byte, config KeyManagerConfig) KeyManager {
+	return &keyManager{
+		repo:      repo,
+		masterKey: masterKey,
+		config:    config,
+		keys:      make(map[string][]SigningKey),
+		decrypted: make(map[string]crypto.Signer),
+	}
+}
+
+func (m *keyManager) Start(ctx context.Context) error {
+	for _, purpose := range KeyPurposes {
+		err := m.repo.UpdateSigningKeys(ctx, purpose, func(keys []repository.SigningKey) ([]repository.SigningKey, []repository.SigningKey, error) {
+			now := time.Now()
+			var seeded []repository.SigningKey
+			if seed := m.config.Seeds[purpose]; seed != nil && len(keys) == 0 {
+				key, err := m.newKey(purpose, seed, KeyStatusCurrent, now)
+				if err != nil {
+					return nil, nil, err
+				}
+				keys = append(keys, *key)
+				seeded = append(seeded, *key)
+			}
+			changed, created, err := m.fill(purpose, keys, now)
+			return changed, append(seeded, created...), err
+		})
+		if err != nil {
+			return fmt.Errorf("%s keys: %w", purpose, err)
+		}
+	}
+	return m.refresh(ctx)
+}
+
+func (m *keyManager) KeySet(purpose string) KeySet {
+	return &managedKeySet{
+		manager: m,
+		purpose: purpose,
+	}
+}
+
+func (m *keyManager) VerificationKeys(ctx context.Context, purpose string) ([]VerificationKey, error) {
+	keys, err := m.repo.ListSigningKeys(ctx, purpose)
+	if err != nil {
+		return nil, err
+	}
+	verificationKeys := make([]VerificationKey, 0, len(keys))
+	for i := range keys {
+		publicKey, err := x509.ParsePKIXPublicKey(keys[i].PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keys[i].ID, err)
+		}
+		// Anyone who can write to the database could add a key of their own to re-sign a rewritten
+		// audit chain, but only the master key seals a private key the service will open.
+		signer, err := m.decrypt(&keys[i])
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keys[i].ID, err)
+		}
+		if sealed, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !sealed.Equal(publicKey) {
+			return nil, fmt.Errorf("key %s: %w", keys[i].ID, ErrSigningKeyMismatch)
+		}
+		verificationKeys = append(verificationKeys, VerificationKey{
+			ID:        keys[i].ID,
+			Key:       publicKey,
+			RevokedAt: keys[i].RevokedAt,
+		})
+	}
+	return verificationKeys, nil
+}
+
+func (m *keyManager) ListKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error) {
+	if !containsString(KeyPurposes, purpose) {
+		return nil, ErrUnknownKeyPurpose
+	}
+	keys, err := m.repo.ListSigningKeys(ctx, purpose)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]SigningKeyResponse, 0, len(keys))
+	for i := range keys {
+		rsp = append(rsp, signingKeyResponse(&keys[i]))
+	}
+	return rsp, nil
+}
+
+func (m *keyManager) Rotate(ctx context.Context, purpose string, force bool) error {
+	if !containsString(KeyPurposes, purpose) {
+		return ErrUnknownKeyPurpose
+	}
+	if err := m.rotate(ctx, purpose, force); err != nil {
+		return err
+	}
+	return m.refresh(ctx)
+}
+
+func (m *keyManager) rotate(ctx context.Context, purpose string, force bool) error {
+	return m.repo.UpdateSigningKeys(ctx, purpose, func(keys []repository.SigningKey) ([]repository.SigningKey, []repository.SigningKey, error) {
+		now := time.Now()
+		current := findSigningKey(keys, KeyStatusCurrent)
+		// Another replica may have rotated while this one waited for the lock.
+		if current != nil && !force && current.ActivatedAt != nil && now.Before(current.ActivatedAt.Add(m.config.RotationPeriod)) {
+			return nil, nil, nil
+		}
+		var retired []repository.SigningKey
+		if current != nil {
+			expiresAt := now.Add(m.config.Overlap)
+			current.Status = KeyStatusRetired
+			current.RetiredAt = &now
+			current.ExpiresAt = &expiresAt
+			retired = append(retired, *current)
+		}
+		changed, created, err := m.fill(purpose, keys, now)
+		return append(retired, changed...), created, err
+	})
+}
+
+func (m *keyManager) Revoke(ctx context.Context, id string) (*SigningKeyResponse, error) {
+	key, err := m.repo.GetSigningKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrSigningKeyNotFound) {
+			return nil, ErrSigningKeyNotFound
+		}
+		return nil, err
+	}
+
+	var revoked repository.SigningKey
+	err = m.repo.UpdateSigningKeys(ctx, key.Purpose, func(keys []repository.SigningKey) ([]repository.SigningKey, []repository.SigningKey, error) {
+		now := time.Now()
+		var target *repository.SigningKey
+		for i := range keys {
+			if keys[i].ID == id && keys[i].Status != KeyStatusRevoked {
+				target = &keys[i]
+			}
+		}
+		if target == nil {
+			return nil, nil, ErrSigningKeyNotFound
+		}
+		target.Status = KeyStatusRevoked
+		target.RevokedAt = &now
+		revoked = *target
+		changed, created, err := m.fill(key.Purpose, keys, now)
+		return append([]repository.SigningKey{*target}, changed...), created, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := signingKeyResponse(&revoked)
+	return &rsp, m.refresh(ctx)
+}
+
+func (m *keyManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, purpose := range KeyPurposes {
+				if err := m.rotate(ctx, purpose, false); err != nil {
+					log.Error().Err(err).Msgf("failed to rotate %s keys", purpose)
+				}
+			}
+			if err := m.refresh(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to reload signing keys")
+			}
+		}
+	}
+}
+
+// fill promotes the next key when there is no current key, and generates whatever key is missing.
+func (m *keyManager) fill(purpose string, keys []repository.SigningKey, now time.Time) ([]repository.SigningKey, []repository.SigningKey, error) {
+	var changed, created []repository.SigningKey
+	current := findSigningKey(keys, KeyStatusCurrent)
+	next := findSigningKey(keys, KeyStatusNext)
+	if current == nil && next != nil {
+		next.Status = KeyStatusCurrent
+		next.ActivatedAt = &now
+		changed = append(changed, *next)
+		current, next = next, nil
+	}
+
+	var missing []string
+	if current == nil {
+		missing = append(missing, KeyStatusCurrent)
+	}
+	if next == nil {
+		missing = append(missing, KeyStatusNext)
+	}
+	for _, status := range missing {
+		signer, err := GenerateSigningKey(m.config.Algorithms[purpose])
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := m.newKey(purpose, signer, status, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		created = append(created, *key)
+	}
+	return changed, created, nil
+}
+
+func (m *keyManager) newKey(purpose string, signer crypto.Signer, status string, now time.Time) (*repository.SigningKey, error) {
+	signingKey, err := NewSigningKey(signer, status)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := util.Seal(m.masterKey, privateKey, []byte(signingKey.ID))
+	if err != nil {
+		return nil, err
+	}
+	key := &repository.SigningKey{
+		ID:                  signingKey.ID,
+		Purpose:             purpose,
+		Algorithm:           signingKey.Algorithm,
+		Status:              status,
+		PublicKey:           publicKey,
+		EncryptedPrivateKey: encrypted,
+	}
+	if status == KeyStatusCurrent {
+		key.ActivatedAt = &now
+	}
+	return key, nil
+}
+
+// refresh reloads the published keys of every purpose.
+func (m *keyManager) refresh(ctx context.Context) error {
+	now := time.Now()
+	published := make(map[string][]SigningKey, len(KeyPurposes))
+	for _, purpose := range KeyPurposes {
+		keys, err := m.repo.ListSigningKeys(ctx, purpose)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			key := &keys[i]
+			if key.Status == KeyStatusRevoked || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+				continue
+			}
+			signer, err := m.decrypt(key)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", key.ID, err)
+			}
+			published[purpose] = append(published[purpose], SigningKey{
+				ID:        key.ID,
+				Algorithm: key.Algorithm,
+				Status:    key.Status,
+				Key:       signer,
+			})
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = published
+	return nil
+}
+
+func (m *keyManager) decrypt(key *repository.SigningKey) (crypto.Signer, error) {
+	m.mu.RLock()
+	signer, ok := m.decrypted[key.ID]
+	m.mu.RUnlock()
+	if ok {
+		return signer, nil
+	}
+
+	der, err := util.Open(m.masterKey, key.EncryptedPrivateKey, []byte(key.ID))
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok = privateKey.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKeyType
+	}
+
+	m.mu.Lock()
+	m.decrypted[key.ID] = signer
+	m.mu.Unlock()
+	return signer, nil
+}
+
+func (m *keyManager) published(purpose string) []SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys[purpose]
+}
+
+func findSigningKey(keys []repository.SigningKey, status string) *repository.SigningKey {
+	for i := range keys {
+		if keys[i].Status == status {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+func signingKeyResponse(key *repository.SigningKey) SigningKeyResponse {
+	return SigningKeyResponse{
+		ID:          key.ID,
+		Purpose:     key.Purpose,
+		Algorithm:   key.Algorithm,
+		Status:      key.Status,
+		CreatedAt:   key.CreatedAt,
+		ActivatedAt: key.ActivatedAt,
+		RetiredAt:   key.RetiredAt,
+		ExpiresAt:   key.ExpiresAt,
+		RevokedAt:   key.RevokedAt,
+	}
+}
+
+// managedKeySet is the KeySet of one purpose, as last loaded by the key manager.
+type managedKeySet struct {
+	manager *keyManager
+	purpose string
+}
+
+func (s *managedKeySet) Current() (*SigningKey, error) {
+	keys := s.manager.published(s.purpose)
+	for i := range keys {
+		if keys[i].Status == KeyStatusCurrent {
+			return &keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+func (s *managedKeySet) Keys() []SigningKey {
+	return s.manager.published(s.purpose)
+}
@@ -0,0 +1,135 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: keymanager.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockKeyManager is a mock of KeyManager interface.
+type MockKeyManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyManagerMockRecorder
+}
+
+// MockKeyManagerMockRecorder is the mock recorder for MockKeyManager.
+type MockKeyManagerMockRecorder struct {
+	mock *MockKeyManager
+}
+
+// NewMockKeyManager creates a new mock instance.
+func NewMockKeyManager(ctrl *gomock.Controller) *MockKeyManager {
+	mock := &MockKeyManager{ctrl: ctrl}
+	mock.recorder = &MockKeyManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyManager) EXPECT() *MockKeyManagerMockRecorder {
+	return m.recorder
+}
+
+// KeySet mocks base method.
+func (m *MockKeyManager) KeySet(purpose string) KeySet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeySet", purpose)
+	ret0, _ := ret[0].(KeySet)
+	return ret0
+}
+
+// KeySet indicates an expected call of KeySet.
+func (mr *MockKeyManagerMockRecorder) KeySet(purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeySet", reflect.TypeOf((*MockKeyManager)(nil).KeySet), purpose)
+}
+
+// ListKeys mocks base method.
+func (m *MockKeyManager) ListKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListKeys", ctx, purpose)
+	ret0, _ := ret[0].([]SigningKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListKeys indicates an expected call of ListKeys.
+func (mr *MockKeyManagerMockRecorder) ListKeys(ctx, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListKeys", reflect.TypeOf((*MockKeyManager)(nil).ListKeys), ctx, purpose)
+}
+
+// Revoke mocks base method.
+func (m *MockKeyManager) Revoke(ctx context.Context, id string) (*SigningKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, id)
+	ret0, _ := ret[0].(*SigningKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockKeyManagerMockRecorder) Revoke(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockKeyManager)(nil).Revoke), ctx, id)
+}
+
+// Rotate mocks base method.
+func (m *MockKeyManager) Rotate(ctx context.Context, purpose string, force bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate", ctx, purpose, force)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rotate indicates an expected call of Rotate.
+func (mr *MockKeyManagerMockRecorder) Rotate(ctx, purpose, force interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockKeyManager)(nil).Rotate), ctx, purpose, force)
+}
+
+// Run mocks base method.
+func (m *MockKeyManager) Run(ctx context.Context, interval time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx, interval)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockKeyManagerMockRecorder) Run(ctx, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockKeyManager)(nil).Run), ctx, interval)
+}
+
+// Start mocks base method.
+func (m *MockKeyManager) Start(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockKeyManagerMockRecorder) Start(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockKeyManager)(nil).Start), ctx)
+}
+
+// VerificationKeys mocks base method.
+func (m *MockKeyManager) VerificationKeys(ctx context.Context, purpose string) ([]VerificationKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerificationKeys", ctx, purpose)
+	ret0, _ := ret[0].([]VerificationKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerificationKeys indicates an expected call of VerificationKeys.
+func (mr *MockKeyManagerMockRecorder) VerificationKeys(ctx, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerificationKeys", reflect.TypeOf((*MockKeyManager)(nil).VerificationKeys), ctx, purpose)
+}
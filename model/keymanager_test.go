@@ -0,0 +1,226 @@
+package model
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var testKeyManagerConfig = KeyManagerConfig{
+	Algorithms:     map[string]string{KeyPurposeToken: KeyAlgorithmES256, KeyPurposeAudit: KeyAlgorithmEdDSA},
+	RotationPeriod: 24 * time.Hour,
+	Overlap:        time.Hour,
+}
+
+// newTestKeyManager backs the repository mock with a map, so keys survive between calls.
+func newTestKeyManager(t *testing.T, config KeyManagerConfig) (KeyManager, map[string][]repository.SigningKey) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockSigningKeyRepository(ctrl)
+	store := map[string][]repository.SigningKey{}
+
+	repo.EXPECT().ListSigningKeys(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, purpose string) ([]repository.SigningKey, error) {
+			return append([]repository.SigningKey(nil), store[purpose]...), nil
+		})
+	repo.EXPECT().GetSigningKey(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, id string) (*repository.SigningKey, error) {
+			for _, keys := range store {
+				for i := range keys {
+					if keys[i].ID == id {
+						key := keys[i]
+						return &key, nil
+					}
+				}
+			}
+			return nil, repository.ErrSigningKeyNotFound
+		})
+	repo.EXPECT().UpdateSigningKeys(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, purpose string,
+			fn func([]repository.SigningKey) ([]repository.SigningKey, []repository.SigningKey, error)) error {
+			changed, created, err := fn(append([]repository.SigningKey(nil), store[purpose]...))
+			if err != nil {
+				return err
+			}
+			for _, key := range changed {
+				for i := range store[purpose] {
+					if store[purpose][i].ID == key.ID {
+						store[purpose][i] = key
+					}
+				}
+			}
+			for _, key := range created {
+				key.CreatedAt = time.Now()
+				store[purpose] = append(store[purpose], key)
+			}
+			return nil
+		})
+
+	masterKey := make([]byte, 32)
+	_, err := rand.Read(masterKey)
+	require.NoError(t, err)
+	return NewKeyManager(repo, masterKey, config), store
+}
+
+func keyWithStatus(keys []repository.SigningKey, status string) *repository.SigningKey {
+	return findSigningKey(keys, status)
+}
+
+func TestKeyManagerStart(t *testing.T) {
+	manager, store := newTestKeyManager(t, testKeyManagerConfig)
+	require.NoError(t, manager.Start(context.Background()))
+
+	for _, purpose := range KeyPurposes {
+		require.Len(t, store[purpose], 2)
+		require.NotNil(t, keyWithStatus(store[purpose], KeyStatusCurrent))
+		require.NotNil(t, keyWithStatus(store[purpose], KeyStatusNext))
+		for _, key := range store[purpose] {
+			require.Equal(t, testKeyManagerConfig.Algorithms[purpose], key.Algorithm)
+			require.NotEmpty(t, key.EncryptedPrivateKey)
+		}
+	}
+
+	current, err := manager.KeySet(KeyPurposeToken).Current()
+	require.NoError(t, err)
+	require.Equal(t, keyWithStatus(store[KeyPurposeToken], KeyStatusCurrent).ID, current.ID)
+	require.Len(t, manager.KeySet(KeyPurposeToken).Keys(), 2)
+
+	// A second replica starting finds the keys and leaves them alone.
+	require.NoError(t, manager.Start(context.Background()))
+	require.Len(t, store[KeyPurposeToken], 2)
+}
+
+func TestKeyManagerSeed(t *testing.T) {
+	_, seed, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	config := testKeyManagerConfig
+	config.Seeds = map[string]crypto.Signer{KeyPurposeAudit: seed}
+	manager, store := newTestKeyManager(t, config)
+	require.NoError(t, manager.Start(context.Background()))
+
+	seeded, err := NewSigningKey(seed, KeyStatusCurrent)
+	require.NoError(t, err)
+	require.Equal(t, seeded.ID, keyWithStatus(store[KeyPurposeAudit], KeyStatusCurrent).ID)
+	require.NotNil(t, keyWithStatus(store[KeyPurposeAudit], KeyStatusNext))
+}
+
+func TestKeyManagerRotate(t *testing.T) {
+	manager, store := newTestKeyManager(t, testKeyManagerConfig)
+	require.NoError(t, manager.Start(context.Background()))
+	keys := manager.KeySet(KeyPurposeToken)
+	signer := NewTokenSigner(keys)
+	before, err := signer.Sign(AccessTokenClaims{Username: "alice"})
+	require.NoError(t, err)
+
+	oldCurrent := *keyWithStatus(store[KeyPurposeToken], KeyStatusCurrent)
+	oldNext := *keyWithStatus(store[KeyPurposeToken], KeyStatusNext)
+
+	// Not due yet.
+	require.NoError(t, manager.Rotate(context.Background(), KeyPurposeToken, false))
+	require.Equal(t, oldCurrent.ID, keyWithStatus(store[KeyPurposeToken], KeyStatusCurrent).ID)
+
+	require.NoError(t, manager.Rotate(context.Background(), KeyPurposeToken, true))
+	require.Len(t, store[KeyPurposeToken], 3)
+	retired := keyWithStatus(store[KeyPurposeToken], KeyStatusRetired)
+	require.Equal(t, oldCurrent.ID, retired.ID)
+	require.WithinDuration(t, time.Now().Add(testKeyManagerConfig.Overlap), *retired.ExpiresAt, time.Minute)
+	require.Equal(t, oldNext.ID, keyWithStatus(store[KeyPurposeToken], KeyStatusCurrent).ID)
+	require.NotEqual(t, oldNext.ID, keyWithStatus(store[KeyPurposeToken], KeyStatusNext).ID)
+
+	current, err := keys.Current()
+	require.NoError(t, err)
+	require.Equal(t, oldNext.ID, current.ID)
+	// Tokens signed before the rotation verify during the overlap.
+	require.Len(t, keys.Keys(), 3)
+	require.NoError(t, signer.Verify(before, &AccessTokenClaims{}))
+
+	// Once the overlap is over the retired key is no longer published.
+	expired := time.Now().Add(-time.Second)
+	keyWithStatus(store[KeyPurposeToken], KeyStatusRetired).ExpiresAt = &expired
+	require.NoError(t, manager.Rotate(context.Background(), KeyPurposeToken, false))
+	require.Len(t, keys.Keys(), 2)
+	require.ErrorIs(t, signer.Verify(before, &AccessTokenClaims{}), ErrInvalidToken)
+
+	require.ErrorIs(t, manager.Rotate(context.Background(), "email", true), ErrUnknownKeyPurpose)
+}
+
+func TestKeyManagerRevoke(t *testing.T) {
+	manager, store := newTestKeyManager(t, testKeyManagerConfig)
+	require.NoError(t, manager.Start(context.Background()))
+	signer := NewTokenSigner(manager.KeySet(KeyPurposeToken))
+	token, err := signer.Sign(AccessTokenClaims{Username: "alice"})
+	require.NoError(t, err)
+
+	oldCurrent := *keyWithStatus(store[KeyPurposeToken], KeyStatusCurrent)
+	oldNext := *keyWithStatus(store[KeyPurposeToken], KeyStatusNext)
+	rsp, err := manager.Revoke(context.Background(), oldCurrent.ID)
+	require.NoError(t, err)
+	require.Equal(t, KeyStatusRevoked, rsp.Status)
+	require.NotNil(t, rsp.RevokedAt)
+
+	require.Equal(t, oldNext.ID, keyWithStatus(store[KeyPurposeToken], KeyStatusCurrent).ID)
+	require.NotNil(t, keyWithStatus(store[KeyPurposeToken], KeyStatusNext))
+	require.ErrorIs(t, signer.Verify(token, &AccessTokenClaims{}), ErrInvalidToken)
+
+	// Revoked keys still verify audit checkpoints signed before the revocation.
+	verificationKeys, err := manager.VerificationKeys(context.Background(), KeyPurposeToken)
+	require.NoError(t, err)
+	require.Len(t, verificationKeys, 3)
+	require.NotNil(t, verificationKeys[0].RevokedAt)
+
+	_, err = manager.Revoke(context.Background(), oldCurrent.ID)
+	require.ErrorIs(t, err, ErrSigningKeyNotFound)
+	_, err = manager.Revoke(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrSigningKeyNotFound)
+}
+
+func TestKeyManagerVerificationKeysForged(t *testing.T) {
+	manager, store := newTestKeyManager(t, testKeyManagerConfig)
+	require.NoError(t, manager.Start(context.Background()))
+	verificationKeys, err := manager.VerificationKeys(context.Background(), KeyPurposeAudit)
+	require.NoError(t, err)
+	require.Len(t, verificationKeys, 2)
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forgedPublicKey, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	// A row added by hand cannot seal its private key under the master key.
+	forged := store[KeyPurposeAudit][0]
+	forged.ID, forged.PublicKey = "forged", forgedPublicKey
+	store[KeyPurposeAudit] = append(store[KeyPurposeAudit], forged)
+	_, err = manager.VerificationKeys(context.Background(), KeyPurposeAudit)
+	require.Error(t, err)
+
+	// Swapping the public key of a real row does not match its sealed private key.
+	store[KeyPurposeAudit] = store[KeyPurposeAudit][:2]
+	store[KeyPurposeAudit][1].PublicKey = forgedPublicKey
+	_, err = manager.VerificationKeys(context.Background(), KeyPurposeAudit)
+	require.ErrorIs(t, err, ErrSigningKeyMismatch)
+}
+
+func TestSigningKeyUsecase(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	keys := NewMockKeyManager(ctrl)
+	audit := NewMockAuditUsecase(ctrl)
+	usecase := NewSigningKeyUsecase(keys, audit)
+
+	keys.EXPECT().Rotate(gomock.Any(), KeyPurposeToken, true).Return(nil)
+	keys.EXPECT().ListKeys(gomock.Any(), KeyPurposeToken).Return([]SigningKeyResponse{{ID: "kid", Status: KeyStatusCurrent}}, nil)
+	audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+	rsp, err := usecase.RotateKeys(context.Background(), KeyPurposeToken)
+	require.NoError(t, err)
+	require.Len(t, rsp, 1)
+
+	keys.EXPECT().Revoke(gomock.Any(), "missing").Return(nil, ErrSigningKeyNotFound)
+	_, err = usecase.RevokeKey(context.Background(), "missing")
+	require.ErrorIs(t, err, ErrSigningKeyNotFound)
+}
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+)
+
+const (
+	KeyStatusCurrent = "current"
+	KeyStatusNext    = "next"
+	KeyStatusRetired = "retired"
+	KeyStatusRevoked = "revoked"
+)
+
+// Algorithms signing keys can be generated for.
+const (
+	KeyAlgorithmEdDSA = "EdDSA"
+	KeyAlgorithmRS256 = "RS256"
+	KeyAlgorithmES256 = "ES256"
+	KeyAlgorithmES384 = "ES384"
+	KeyAlgorithmES512 = "ES512"
+)
+
+const rsaKeyBits = 2048
+
+var (
+	ErrNoSigningKey            = errors.New("No current signing key")
+	ErrUnsupportedKeyType      = errors.New("Unsupported signing key type")
+	ErrUnsupportedKeyAlgorithm = errors.New("Unsupported signing key algorithm")
+)
+
+// SigningKey is a token signing key. Only the current key signs; next and retired keys are published
+// so verifiers already know the next key and can still check tokens signed by a retired one.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Status    string
+	Key       crypto.Signer
+}
+
+// NewSigningKey picks the JWS algorithm for the key and names it by its RFC 7638 thumbprint.
+func NewSigningKey(key crypto.Signer, status string) (*SigningKey, error) {
+	jwk, err := publicJWK(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:        jwk.Kid,
+		Algorithm: jwk.Alg,
+		Status:    status,
+		Key:       key,
+	}, nil
+}
+
+// JWK returns the public key as published in the JWKS.
+func (k *SigningKey) JWK() JSONWebKey {
+	jwk, _ := publicJWK(k.Key.Public())
+	jwk.Kid = k.ID
+	jwk.Status = k.Status
+	return jwk
+}
+
+// VerificationKey is the public half of a signing key, for checking what it signed. RevokedAt is set
+// once the key was revoked; anything it signed after that must not be trusted.
+type VerificationKey struct {
+	ID        string
+	Key       crypto.PublicKey
+	RevokedAt *time.Time
+}
+
+// GenerateSigningKey creates a private key for a JWS algorithm.
+func GenerateSigningKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case KeyAlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case KeyAlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case KeyAlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmES384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyAlgorithmES512:
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	}
+	return nil, ErrUnsupportedKeyAlgorithm
+}
+
+// SignMessage signs message with the algorithm that goes with the key: Ed25519, RSA PKCS #1 v1.5 with
+// SHA-256, or ECDSA (ASN.1 encoded) with the hash that matches the curve.
+func SignMessage(key crypto.Signer, message []byte) ([]byte, error) {
+	hash, err := messageHash(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	if hash == 0 {
+		return key.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	h := hash.New()
+	h.Write(message)
+	return key.Sign(rand.Reader, h.Sum(nil), hash)
+}
+
+// VerifySignature checks a signature made by SignMessage.
+func VerifySignature(publicKey crypto.PublicKey, message []byte, signature []byte) bool {
+	hash, err := messageHash(publicKey)
+	if err != nil {
+		return false
+	}
+	if hash == 0 {
+		return ed25519.Verify(publicKey.(ed25519.PublicKey), message, signature)
+	}
+	h := hash.New()
+	h.Write(message)
+	digest := h.Sum(nil)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, signature)
+	}
+	return false
+}
+
+func messageHash(publicKey crypto.PublicKey) (crypto.Hash, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return 0, nil
+	case *rsa.PublicKey:
+		return crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return crypto.SHA256, nil
+		case elliptic.P384():
+			return crypto.SHA384, nil
+		case elliptic.P521():
+			return crypto.SHA512, nil
+		}
+	}
+	return 0, ErrUnsupportedKeyType
+}
+
+type KeySet interface {
+	// Current returns the key new tokens are signed with.
+	Current() (*SigningKey, error)
+	// Keys returns every published key, the current one included.
+	Keys() []SigningKey
+}
+
+type staticKeySet struct {
+	keys []SigningKey
+}
+
+// NewStaticKeySet is a KeySet that never changes, for keys loaded from configuration.
+func NewStaticKeySet(keys ...SigningKey) KeySet {
+	return &staticKeySet{
+		keys: keys,
+	}
+}
+
+func (s *staticKeySet) Current() (*SigningKey, error) {
+	for i := range s.keys {
+		if s.keys[i].Status == KeyStatusCurrent {
+			return &s.keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+func (s *staticKeySet) Keys() []SigningKey {
+	return s.keys
+}
+
+// NewJSONWebKeySet publishes the public half of every key.
+func NewJSONWebKeySet(keys []SigningKey) *JSONWebKeySet {
+	set := &JSONWebKeySet{
+		Keys: make([]JSONWebKey, 0, len(keys)),
+	}
+	for i := range keys {
+		set.Keys = append(set.Keys, keys[i].JWK())
+	}
+	return set
+}
+
+func publicJWK(publicKey crypto.PublicKey) (JSONWebKey, error) {
+	var jwk JSONWebKey
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk = JSONWebKey{Kty: "OKP", Alg: KeyAlgorithmEdDSA, Crv: "Ed25519", X: base64URL(key)}
+	case *rsa.PublicKey:
+		jwk = JSONWebKey{Kty: "RSA", Alg: KeyAlgorithmRS256, N: base64URL(key.N.Bytes()), E: base64URL(big.NewInt(int64(key.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		var alg string
+		switch key.Curve {
+		case elliptic.P256():
+			alg = KeyAlgorithmES256
+		case elliptic.P384():
+			alg = KeyAlgorithmES384
+		case elliptic.P521():
+			alg = KeyAlgorithmES512
+		default:
+			return jwk, ErrUnsupportedKeyType
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk = JSONWebKey{Kty: "EC", Alg: alg, Crv: key.Curve.Params().Name,
+			X: base64URL(key.X.FillBytes(make([]byte, size))), Y: base64URL(key.Y.FillBytes(make([]byte, size)))}
+	default:
+		return jwk, ErrUnsupportedKeyType
+	}
+	jwk.Use = "sig"
+	jwk.Kid = jwkThumbprint(jwk)
+	return jwk, nil
+}
+
+// parseJWK returns the public key of a JWK published by someone else, such as an identity provider.
+func parseJWK(jwk JSONWebKey) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "OKP":
+		x, err := decode(jwk.X)
+		if err != nil || jwk.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKeyType
+		}
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, ErrUnsupportedKeyType
+		}
+		e, err := decode(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, ErrUnsupportedKeyType
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKeyType
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, ErrUnsupportedKeyType
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, ErrUnsupportedKeyType
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrUnsupportedKeyType
+		}
+		return key, nil
+	}
+	return nil, ErrUnsupportedKeyType
+}
+
+// jwkThumbprint is the RFC 7638 thumbprint: the hash of the required members in lexical order.
+func jwkThumbprint(jwk JSONWebKey) string {
+	var members interface{}
+	switch jwk.Kty {
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	}
+	encoded, _ := json.Marshal(members)
+	sum := sha256.Sum256(encoded)
+	return base64URL(sum[:])
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
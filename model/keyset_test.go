@@ -0,0 +1,133 @@
+package model
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeySet(t *testing.T) KeySet {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	current, err := NewSigningKey(key, KeyStatusCurrent)
+	require.NoError(t, err)
+	return NewStaticKeySet(*current)
+}
+
+func TestNewSigningKey(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+		kty  string
+	}{
+		{name: "ed25519", key: edKey, alg: "EdDSA", kty: "OKP"},
+		{name: "rsa", key: rsaKey, alg: "RS256", kty: "RSA"},
+		{name: "ecdsa", key: ecKey, alg: "ES384", kty: "EC"},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := NewSigningKey(tc.key, KeyStatusNext)
+			require.NoError(t, err)
+			require.Equal(t, tc.alg, key.Algorithm)
+			require.NotEmpty(t, key.ID)
+
+			again, err := NewSigningKey(tc.key, KeyStatusRetired)
+			require.NoError(t, err)
+			require.Equal(t, key.ID, again.ID)
+
+			jwk := key.JWK()
+			require.Equal(t, tc.kty, jwk.Kty)
+			require.Equal(t, key.ID, jwk.Kid)
+			require.Equal(t, "sig", jwk.Use)
+			require.Equal(t, KeyStatusNext, jwk.Status)
+
+			parsed, err := parseJWK(jwk)
+			require.NoError(t, err)
+			require.Equal(t, tc.key.Public(), parsed)
+		})
+	}
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// The example key of RFC 7638 section 3.1.
+	jwk := JSONWebKey{
+		Kty: "RSA",
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMs" +
+			"tn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n" +
+			"91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	require.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", jwkThumbprint(jwk))
+}
+
+func TestStaticKeySet(t *testing.T) {
+	keys := make([]SigningKey, 0, 3)
+	for _, status := range []string{KeyStatusRetired, KeyStatusCurrent, KeyStatusNext} {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		signingKey, err := NewSigningKey(key, status)
+		require.NoError(t, err)
+		keys = append(keys, *signingKey)
+	}
+
+	current, err := NewStaticKeySet(keys...).Current()
+	require.NoError(t, err)
+	require.Equal(t, keys[1].ID, current.ID)
+
+	_, err = NewStaticKeySet(keys[0], keys[2]).Current()
+	require.ErrorIs(t, err, ErrNoSigningKey)
+
+	set := NewJSONWebKeySet(keys)
+	require.Len(t, set.Keys, 3)
+}
+
+func TestTokenSigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	retired, err := NewSigningKey(ecKey, KeyStatusRetired)
+	require.NoError(t, err)
+	current, err := NewSigningKey(edKey, KeyStatusCurrent)
+	require.NoError(t, err)
+
+	claims := jwt.RegisteredClaims{Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+	previous := *retired
+	previous.Status = KeyStatusCurrent
+	oldToken, err := NewTokenSigner(NewStaticKeySet(previous)).Sign(claims)
+	require.NoError(t, err)
+
+	signer := NewTokenSigner(NewStaticKeySet(*current, *retired))
+	token, err := signer.Sign(claims)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	require.Equal(t, current.ID, parsed.Header["kid"])
+	require.Equal(t, "EdDSA", parsed.Header["alg"])
+
+	// Tokens signed by a retired key still verify until the key is dropped.
+	require.NoError(t, signer.Verify(token, &jwt.RegisteredClaims{}))
+	require.NoError(t, signer.Verify(oldToken, &jwt.RegisteredClaims{}))
+	require.ErrorIs(t, NewTokenSigner(NewStaticKeySet(*current)).Verify(oldToken, &jwt.RegisteredClaims{}), ErrInvalidToken)
+
+	expired, err := signer.Sign(jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))})
+	require.NoError(t, err)
+	require.ErrorIs(t, signer.Verify(expired, &jwt.RegisteredClaims{}), ErrInvalidToken)
+}
@@ -0,0 +1,321 @@
+package model
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	defaultLDAPUserFilter        = "(objectClass=person)"
+	defaultLDAPUsernameAttribute = "uid"
+	defaultLDAPEmailAttribute    = "mail"
+	defaultLDAPGroupAttribute    = "memberOf"
+	defaultLDAPGroupFilter       = "(member={dn})"
+	defaultLDAPPoolSize          = 4
+	defaultLDAPTimeout           = 10 * time.Second
+	ldapPageSize                 = 500
+)
+
+var ErrLDAPAmbiguousUser = errors.New("More than one directory entry matches the username")
+
+// LDAPConfig describes how users are found and authenticated in an LDAP directory or Active Directory.
+// Users are searched for under BaseDN with the service account in BindDN, then their password is checked
+// by binding as the entry found.
+type LDAPConfig struct {
+	// URL is ldap://host:389, or ldaps://host:636 for TLS from the start.
+	URL string
+	// StartTLS upgrades an ldap:// connection to TLS before anything is sent.
+	StartTLS bool
+	TLS      *tls.Config
+	// BindDN and BindPassword are the service account searches are made with. An empty BindDN
+	// searches anonymously.
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserFilter selects the user entries. The username is matched against UsernameAttribute, which is
+	// sAMAccountName in Active Directory.
+	UserFilter        string
+	UsernameAttribute string
+	EmailAttribute    string
+	// GroupAttribute lists the groups on the user entry, as memberOf does. With GroupBaseDN, groups are
+	// also searched for under it with GroupFilter, where {dn} is replaced by the user's DN.
+	GroupAttribute string
+	GroupBaseDN    string
+	GroupFilter    string
+	// GroupRoles maps group DNs to the role their members get.
+	GroupRoles map[string]string
+	// PoolSize is the number of idle connections kept open.
+	PoolSize int
+	Timeout  time.Duration
+}
+
+type ldapConn struct {
+	conn *ldap.Conn
+	// service is whether the connection is bound as the service account.
+	service bool
+}
+
+type ldapDirectory struct {
+	config     LDAPConfig
+	groupRoles map[string]string
+	idle       chan *ldapConn
+}
+
+// NewLDAPDirectory checks the configuration and fills in its defaults. Connections are opened when
+// they are first needed.
+func NewLDAPDirectory(config LDAPConfig) (Directory, error) {
+	if config.URL == "" || config.BaseDN == "" {
+		return nil, errors.New("LDAP URL and base DN are required")
+	}
+	address, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP URL: %w", err)
+	}
+	if config.StartTLS && address.Scheme != "ldap" {
+		return nil, errors.New("StartTLS needs an ldap:// URL")
+	}
+	if config.TLS == nil {
+		config.TLS = &tls.Config{}
+	}
+	if config.TLS.ServerName == "" {
+		config.TLS = config.TLS.Clone()
+		config.TLS.ServerName = address.Hostname()
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = defaultLDAPUserFilter
+	}
+	if config.UsernameAttribute == "" {
+		config.UsernameAttribute = defaultLDAPUsernameAttribute
+	}
+	if config.EmailAttribute == "" {
+		config.EmailAttribute = defaultLDAPEmailAttribute
+	}
+	if config.GroupAttribute == "" {
+		config.GroupAttribute = defaultLDAPGroupAttribute
+	}
+	if config.GroupFilter == "" {
+		config.GroupFilter = defaultLDAPGroupFilter
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultLDAPPoolSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultLDAPTimeout
+	}
+	groupRoles := make(map[string]string, len(config.GroupRoles))
+	for group, role := range config.GroupRoles {
+		dn, err := normalizeDN(group)
+		if err != nil {
+			return nil, fmt.Errorf("LDAP group %q: %w", group, err)
+		}
+		groupRoles[dn] = role
+	}
+	return &ldapDirectory{
+		config:     config,
+		groupRoles: groupRoles,
+		idle:       make(chan *ldapConn, config.PoolSize),
+	}, nil
+}
+
+func (d *ldapDirectory) Authenticate(ctx context.Context, username string, password string) (*DirectoryUser, error) {
+	// A simple bind without a password is an anonymous bind, which most servers accept.
+	if password == "" {
+		return nil, ErrLoginWrongPassword
+	}
+	var user *DirectoryUser
+	err := d.withConn(func(c *ldapConn) error {
+		if err := d.bindService(c); err != nil {
+			return err
+		}
+		entry, err := d.findUser(c, username)
+		if err != nil {
+			return err
+		}
+		c.service = false
+		if err := c.conn.Bind(entry.DN, password); err != nil {
+			if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+				return ErrLoginWrongPassword
+			}
+			return err
+		}
+		// Groups are read as the service account, users may not be allowed to read them.
+		if err := d.bindService(c); err != nil {
+			return err
+		}
+		user, err = d.directoryUser(c, entry)
+		return err
+	})
+	return user, err
+}
+
+func (d *ldapDirectory) Users(ctx context.Context) ([]DirectoryUser, error) {
+	var users []DirectoryUser
+	err := d.withConn(func(c *ldapConn) error {
+		if err := d.bindService(c); err != nil {
+			return err
+		}
+		result, err := c.conn.SearchWithPaging(d.userSearch(fmt.Sprintf("(%s=*)", d.config.UsernameAttribute)), ldapPageSize)
+		if err != nil {
+			return err
+		}
+		users = make([]DirectoryUser, 0, len(result.Entries))
+		for _, entry := range result.Entries {
+			user, err := d.directoryUser(c, entry)
+			if err != nil {
+				return err
+			}
+			if user.Username != "" {
+				users = append(users, *user)
+			}
+		}
+		return nil
+	})
+	return users, err
+}
+
+func (d *ldapDirectory) userSearch(filter string) *ldap.SearchRequest {
+	return ldap.NewSearchRequest(d.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0,
+		int(d.config.Timeout/time.Second), false, fmt.Sprintf("(&%s%s)", d.config.UserFilter, filter),
+		[]string{d.config.UsernameAttribute, d.config.EmailAttribute, d.config.GroupAttribute}, nil)
+}
+
+func (d *ldapDirectory) findUser(c *ldapConn, username string) (*ldap.Entry, error) {
+	filter := fmt.Sprintf("(%s=%s)", d.config.UsernameAttribute, ldap.EscapeFilter(username))
+	result, err := c.conn.Search(d.userSearch(filter))
+	if err != nil {
+		return nil, err
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, ErrDirectoryUserNotFound
+	case 1:
+		return result.Entries[0], nil
+	default:
+		return nil, ErrLDAPAmbiguousUser
+	}
+}
+
+// directoryUser reads the user from its entry. The username is taken as the directory spells it.
+func (d *ldapDirectory) directoryUser(c *ldapConn, entry *ldap.Entry) (*DirectoryUser, error) {
+	groups := entry.GetAttributeValues(d.config.GroupAttribute)
+	if d.config.GroupBaseDN != "" {
+		filter := strings.ReplaceAll(d.config.GroupFilter, "{dn}", ldap.EscapeFilter(entry.DN))
+		result, err := c.conn.Search(ldap.NewSearchRequest(d.config.GroupBaseDN, ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases, 0, int(d.config.Timeout/time.Second), false, filter, []string{"1.1"}, nil))
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range result.Entries {
+			groups = append(groups, group.DN)
+		}
+	}
+
+	var roles []string
+	for _, group := range groups {
+		dn, err := normalizeDN(group)
+		if err != nil {
+			continue
+		}
+		if role, ok := d.groupRoles[dn]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return &DirectoryUser{
+		Username: entry.GetAttributeValue(d.config.UsernameAttribute),
+		DN:       entry.DN,
+		Email:    entry.GetAttributeValue(d.config.EmailAttribute),
+		Roles:    strings.Fields(joinRoles(roles)),
+	}, nil
+}
+
+func (d *ldapDirectory) bindService(c *ldapConn) error {
+	if c.service {
+		return nil
+	}
+	var err error
+	if d.config.BindDN == "" {
+		err = c.conn.UnauthenticatedBind("")
+	} else {
+		err = c.conn.Bind(d.config.BindDN, d.config.BindPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("LDAP service bind: %w", err)
+	}
+	c.service = true
+	return nil
+}
+
+// withConn runs fn on an idle connection, or a new one when none is idle. The connection goes back to
+// the pool unless it broke.
+func (d *ldapDirectory) withConn(fn func(c *ldapConn) error) error {
+	var c *ldapConn
+	select {
+	case c = <-d.idle:
+		if c.conn.IsClosing() {
+			c.conn.Close()
+			c = nil
+		}
+	default:
+	}
+	if c == nil {
+		conn, err := d.dial()
+		if err != nil {
+			return err
+		}
+		c = &ldapConn{conn: conn}
+	}
+
+	err := fn(c)
+	var ldapErr *ldap.Error
+	if c.conn.IsClosing() || errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.ErrorNetwork {
+		c.conn.Close()
+		return err
+	}
+	select {
+	case d.idle <- c:
+	default:
+		c.conn.Close()
+	}
+	return err
+}
+
+func (d *ldapDirectory) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(d.config.URL, ldap.DialWithTLSConfig(d.config.TLS),
+		ldap.DialWithDialer(&net.Dialer{Timeout: d.config.Timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(d.config.Timeout)
+	if d.config.StartTLS {
+		if err := conn.StartTLS(d.config.TLS); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// normalizeDN spells a DN the same way however it was written, so group DNs compare equal.
+func normalizeDN(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attributes := make([]string, 0, len(rdn.Attributes))
+		for _, attribute := range rdn.Attributes {
+			attributes = append(attributes, strings.ToLower(attribute.Type)+"="+strings.ToLower(attribute.Value))
+		}
+		rdns = append(rdns, strings.Join(attributes, "+"))
+	}
+	return strings.Join(rdns, ","), nil
+}
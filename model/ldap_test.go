@@ -0,0 +1,367 @@
+package model
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testLDAPBaseDN          = "dc=example,dc=com"
+	testLDAPServiceDN       = "cn=service,dc=example,dc=com"
+	testLDAPServicePassword = "service-secret"
+	ldapStartTLSOID         = "1.3.6.1.4.1.1466.20037"
+)
+
+type testLDAPEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// testLDAPServer is an in-process LDAP server that knows just enough of the protocol for the
+// directory: simple binds, searches with and/or/not/equality/present filters and StartTLS. Only the
+// service account may search.
+type testLDAPServer struct {
+	t        *testing.T
+	listener net.Listener
+	tls      *tls.Config
+	entries  []testLDAPEntry
+
+	mu          sync.Mutex
+	connections int
+	binds       []string
+}
+
+func newTestLDAPServer(t *testing.T, tlsConfig *tls.Config) *testLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &testLDAPServer{
+		t:        t,
+		listener: listener,
+		tls:      tlsConfig,
+		entries: []testLDAPEntry{
+			{dn: testLDAPServiceDN, password: testLDAPServicePassword, attributes: map[string][]string{
+				"objectclass": {"organizationalRole"},
+			}},
+			{dn: "uid=alice,ou=people,dc=example,dc=com", password: "alice-secret", attributes: map[string][]string{
+				"objectclass": {"person"},
+				"uid":         {"alice"},
+				"mail":        {"alice@example.com"},
+				"memberof":    {"CN=Admins, OU=Groups, DC=example, DC=com", "cn=unmapped,ou=groups,dc=example,dc=com"},
+			}},
+			{dn: "uid=bob,ou=people,dc=example,dc=com", password: "bob-secret", attributes: map[string][]string{
+				"objectclass": {"person"},
+				"uid":         {"bob"},
+			}},
+			{dn: "cn=staff,ou=groups,dc=example,dc=com", attributes: map[string][]string{
+				"objectclass": {"groupOfNames"},
+				"member":      {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+			}},
+		},
+	}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *testLDAPServer) url(scheme string) string {
+	return scheme + "://" + s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+func (s *testLDAPServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	bound := ""
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, password := op.Children[1].Value.(string), op.Children[2].Data.String()
+			code := int64(ldap.LDAPResultInvalidCredentials)
+			if entry := s.entry(dn); dn == "" && password == "" || entry != nil && entry.password != "" && entry.password == password {
+				code, bound = ldap.LDAPResultSuccess, dn
+				s.mu.Lock()
+				s.binds = append(s.binds, dn)
+				s.mu.Unlock()
+			}
+			s.write(conn, ldapResult(id, ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			if bound != testLDAPServiceDN {
+				s.write(conn, ldapResult(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights))
+				continue
+			}
+			base := strings.ToLower(op.Children[0].Value.(string))
+			var attributes []string
+			for _, attribute := range op.Children[7].Children {
+				attributes = append(attributes, attribute.Value.(string))
+			}
+			for i := range s.entries {
+				entry := &s.entries[i]
+				dn := strings.ToLower(entry.dn)
+				if (dn == base || strings.HasSuffix(dn, ","+base)) && matchTestFilter(entry, op.Children[6]) {
+					s.write(conn, searchResultEntry(id, entry, attributes))
+				}
+			}
+			s.write(conn, ldapResult(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		case ldap.ApplicationExtendedRequest:
+			if op.Children[0].Data.String() != ldapStartTLSOID || s.tls == nil {
+				s.write(conn, ldapResult(id, ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError))
+				continue
+			}
+			s.write(conn, ldapResult(id, ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess))
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *testLDAPServer) entry(dn string) *testLDAPEntry {
+	for i := range s.entries {
+		if strings.EqualFold(s.entries[i].dn, dn) {
+			return &s.entries[i]
+		}
+	}
+	return nil
+}
+
+func (s *testLDAPServer) write(conn net.Conn, packet *ber.Packet) {
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func (s *testLDAPServer) stats() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections, append([]string(nil), s.binds...)
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+func ldapResult(id int64, tag ber.Tag, code int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return ldapMessage(id, op)
+}
+
+func searchResultEntry(id int64, entry *testLDAPEntry, attributes []string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "DN"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, name := range attributes {
+		values, ok := entry.attributes[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	op.AppendChild(list)
+	return ldapMessage(id, op)
+}
+
+func matchTestFilter(entry *testLDAPEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchTestFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchTestFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchTestFilter(entry, filter.Children[0])
+	case ldap.FilterEqualityMatch:
+		for _, value := range entry.attributes[strings.ToLower(filter.Children[0].Data.String())] {
+			if strings.EqualFold(value, filter.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(entry.attributes[strings.ToLower(filter.Data.String())]) > 0
+	}
+	return false
+}
+
+func newTestLDAPConfig(server *testLDAPServer) LDAPConfig {
+	return LDAPConfig{
+		URL:          server.url("ldap"),
+		BindDN:       testLDAPServiceDN,
+		BindPassword: testLDAPServicePassword,
+		BaseDN:       "ou=people," + testLDAPBaseDN,
+		GroupRoles: map[string]string{
+			"cn=admins,ou=groups,dc=example,dc=com": "admin",
+			"cn=staff,ou=groups,dc=example,dc=com":  "staff",
+		},
+		Timeout: time.Second,
+	}
+}
+
+// newTestTLSConfigs returns a server config with a self-signed certificate for 127.0.0.1 and a client
+// config trusting it.
+func newTestTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldap.test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		&tls.Config{RootCAs: roots}
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	server := newTestLDAPServer(t, nil)
+	directory, err := NewLDAPDirectory(newTestLDAPConfig(server))
+	require.NoError(t, err)
+
+	user, err := directory.Authenticate(context.Background(), "ALICE", "alice-secret")
+	require.NoError(t, err)
+	require.Equal(t, &DirectoryUser{
+		Username: "alice",
+		DN:       "uid=alice,ou=people,dc=example,dc=com",
+		Email:    "alice@example.com",
+		Roles:    []string{"admin"},
+	}, user)
+
+	_, err = directory.Authenticate(context.Background(), "alice", "wrong")
+	require.Equal(t, ErrLoginWrongPassword, err)
+	_, err = directory.Authenticate(context.Background(), "alice", "")
+	require.Equal(t, ErrLoginWrongPassword, err)
+	_, err = directory.Authenticate(context.Background(), "carol", "carol-secret")
+	require.Equal(t, ErrDirectoryUserNotFound, err)
+	_, err = directory.Authenticate(context.Background(), "*", "alice-secret")
+	require.Equal(t, ErrDirectoryUserNotFound, err)
+
+	// The connection is reused. It is bound as the service account again after a user bind, whether the
+	// user bind went through or not, and only then.
+	connections, binds := server.stats()
+	require.Equal(t, 1, connections)
+	require.Equal(t, []string{
+		testLDAPServiceDN, "uid=alice,ou=people,dc=example,dc=com", testLDAPServiceDN, testLDAPServiceDN,
+	}, binds)
+}
+
+func TestLDAPGroupSearch(t *testing.T) {
+	server := newTestLDAPServer(t, nil)
+	config := newTestLDAPConfig(server)
+	config.GroupBaseDN = "ou=groups," + testLDAPBaseDN
+	directory, err := NewLDAPDirectory(config)
+	require.NoError(t, err)
+
+	user, err := directory.Authenticate(context.Background(), "alice", "alice-secret")
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin", "staff"}, user.Roles)
+
+	users, err := directory.Users(context.Background())
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	require.Equal(t, "alice", users[0].Username)
+	require.Equal(t, []string{"admin", "staff"}, users[0].Roles)
+	require.Equal(t, "bob", users[1].Username)
+	require.Equal(t, []string{"staff"}, users[1].Roles)
+}
+
+func TestLDAPStartTLS(t *testing.T) {
+	serverTLS, clientTLS := newTestTLSConfigs(t)
+	server := newTestLDAPServer(t, serverTLS)
+	config := newTestLDAPConfig(server)
+	config.StartTLS = true
+	config.TLS = clientTLS
+	directory, err := NewLDAPDirectory(config)
+	require.NoError(t, err)
+
+	user, err := directory.Authenticate(context.Background(), "bob", "bob-secret")
+	require.NoError(t, err)
+	require.Equal(t, "bob", user.Username)
+
+	// A certificate the client does not trust fails the handshake.
+	config.TLS = &tls.Config{RootCAs: x509.NewCertPool()}
+	untrusted, err := NewLDAPDirectory(config)
+	require.NoError(t, err)
+	_, err = untrusted.Authenticate(context.Background(), "bob", "bob-secret")
+	require.Error(t, err)
+}
+
+func TestNewLDAPDirectory(t *testing.T) {
+	_, err := NewLDAPDirectory(LDAPConfig{URL: "ldap://ldap.example.com"})
+	require.Error(t, err)
+	_, err = NewLDAPDirectory(LDAPConfig{URL: "ldaps://ldap.example.com", BaseDN: testLDAPBaseDN, StartTLS: true})
+	require.Error(t, err)
+	_, err = NewLDAPDirectory(LDAPConfig{URL: "ldap://ldap.example.com", BaseDN: testLDAPBaseDN,
+		GroupRoles: map[string]string{"not a dn": "admin"}})
+	require.Error(t, err)
+}
+
+func TestNormalizeDN(t *testing.T) {
+	dn, err := normalizeDN("CN=Admins, OU=Groups, DC=Example, DC=com")
+	require.NoError(t, err)
+	require.Equal(t, "cn=admins,ou=groups,dc=example,dc=com", dn)
+}
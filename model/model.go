@@ -5,14 +5,616 @@ import "time"
 type AccountRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// OTP is the one-time code, needed once the account has TOTP linked.
+	OTP string `json:"otp,omitempty"`
+	// NewPassword replaces a temporary password from support staff, the login is refused without it.
+	NewPassword string `json:"new_password,omitempty"`
 }
 
 type AccountResponse struct {
-	Success bool   `json:"success" binding:"required"`
-	Reason  string `json:"reason" binding:"required"`
+	Success bool                   `json:"success" binding:"required"`
+	Reason  string                 `json:"reason" binding:"required"`
+	Claims  map[string]interface{} `json:"claims,omitempty"`
+	// Username and Roles describe the account a login went through for, as the service spells them.
+	Username string   `json:"username,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	// ExpiresAt is when the login stops working, for vouchers with a duration.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type LoginAttempt struct {
 	FailedAttempt int
 	LastTime      time.Time
 }
+
+type AuditQueryRequest struct {
+	Type    string    `form:"type"`
+	Actor   string    `form:"actor"`
+	Target  string    `form:"target"`
+	Outcome string    `form:"outcome"`
+	Since   time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until   time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit   int       `form:"limit"`
+	Offset  int       `form:"offset"`
+	// Newest lists the latest events first instead of the oldest.
+	Newest bool `form:"newest"`
+	// TypePrefix keeps the events whose type starts with it, like "login." for every login event.
+	TypePrefix string `form:"type_prefix"`
+}
+
+type AuditEventResponse struct {
+	ID        uint64    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`
+	Target    string    `json:"target"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	RequestID string    `json:"request_id"`
+	Outcome   string    `json:"outcome"`
+	Detail    string    `json:"detail"`
+}
+
+type AuditQueryResponse struct {
+	Events []AuditEventResponse `json:"events"`
+}
+
+type AuditVerifyReport struct {
+	EventsChecked      int    `json:"events_checked"`
+	CheckpointsChecked int    `json:"checkpoints_checked"`
+	BrokenEventID      uint64 `json:"broken_event_id,omitempty"`
+	Reason             string `json:"reason,omitempty"`
+}
+
+func (r *AuditVerifyReport) OK() bool {
+	return r.Reason == ""
+}
+
+type WebhookEndpointRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Secret      string   `json:"secret"`
+	Description string   `json:"description"`
+}
+
+type WebhookEndpointResponse struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type WebhookDeadLetterResponse struct {
+	ID         uint64     `json:"id"`
+	EndpointID string     `json:"endpoint_id"`
+	EventID    string     `json:"event_id"`
+	EventType  string     `json:"event_type"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReplayedAt *time.Time `json:"replayed_at,omitempty"`
+}
+
+type OAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1"`
+	Scopes       []string `json:"scopes"`
+	Public       bool     `json:"public"`
+	SkipConsent  bool     `json:"skip_consent"`
+}
+
+type OAuthClientResponse struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types"`
+	Scopes       []string  `json:"scopes"`
+	Public       bool      `json:"public"`
+	SkipConsent  bool      `json:"skip_consent"`
+	Secret       string    `json:"secret,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+// OAuthConsent is what the login and consent page shows for a valid authorize request.
+type OAuthConsent struct {
+	ClientName  string
+	Scopes      []string
+	SkipConsent bool
+}
+
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	// Assertion is the JWT of the jwt-bearer grant; ClientAssertion authenticates the client of any
+	// other grant (RFC 7523).
+	Assertion           string `form:"assertion"`
+	ClientAssertionType string `form:"client_assertion_type"`
+	ClientAssertion     string `form:"client_assertion"`
+	DeviceCode          string `form:"device_code"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+type OAuthDeviceAuthorizationRequest struct {
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+// OAuthDeviceAuthorizationResponse is the RFC 8628 section 3.2 device authorization response.
+type OAuthDeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type OAuthIntrospectRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// OAuthIntrospectResponse is the RFC 7662 introspection response. Only Active is set for a token
+// that is not active.
+type OAuthIntrospectResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	JTI       string   `json:"jti,omitempty"`
+}
+
+type OAuthRevokeRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// JSONWebKey is the public half of a signing key as published in the JWKS (RFC 7517).
+type JSONWebKey struct {
+	Kty    string `json:"kty"`
+	Kid    string `json:"kid"`
+	Use    string `json:"use"`
+	Alg    string `json:"alg"`
+	Crv    string `json:"crv,omitempty"`
+	X      string `json:"x,omitempty"`
+	Y      string `json:"y,omitempty"`
+	N      string `json:"n,omitempty"`
+	E      string `json:"e,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type OIDCDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type SigningKeyResponse struct {
+	ID          string     `json:"id"`
+	Purpose     string     `json:"purpose"`
+	Algorithm   string     `json:"algorithm"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+type PersonalAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=64"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type PersonalAccessTokenResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type BrowserSessionResponse struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is the session the request came with.
+	Current bool `json:"current"`
+}
+
+// CurrentSessionResponse is the browser session a request came with, and the CSRF token the requests
+// that change something send back in the X-CSRF-Token header.
+type CurrentSessionResponse struct {
+	Username  string    `json:"username"`
+	CSRFToken string    `json:"csrf_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type CurrentAccountResponse struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Scopes   []string `json:"scopes"`
+}
+
+type ServiceAccountRequest struct {
+	Username    string   `json:"username" binding:"required"`
+	Owner       string   `json:"owner" binding:"required"`
+	Description string   `json:"description" binding:"max=256"`
+	Scopes      []string `json:"scopes"`
+}
+
+type ServiceAccountResponse struct {
+	ID           string                      `json:"id"`
+	Username     string                      `json:"username"`
+	Owner        string                      `json:"owner"`
+	Description  string                      `json:"description"`
+	ClientID     string                      `json:"client_id"`
+	ClientSecret string                      `json:"client_secret,omitempty"`
+	Scopes       []string                    `json:"scopes"`
+	Keys         []ServiceAccountKeyResponse `json:"keys"`
+	CreatedAt    time.Time                   `json:"created_at"`
+}
+
+// ServiceAccountKeyRequest registers PublicKey (PKIX PEM), or has a key pair of Algorithm generated
+// when it is empty.
+type ServiceAccountKeyRequest struct {
+	PublicKey string `json:"public_key"`
+	Algorithm string `json:"algorithm"`
+}
+
+type ServiceAccountKeyResponse struct {
+	ID         string     `json:"id"`
+	Algorithm  string     `json:"algorithm"`
+	PrivateKey string     `json:"private_key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// FederationProvider is an identity provider offered on the login pages.
+type FederationProvider struct {
+	Name        string
+	DisplayName string
+}
+
+// FederationCallbackRequest is what the identity provider sends back to the callback.
+type FederationCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+// FederationLogin is the account a federated login signed in to, and where to go next.
+type FederationLogin struct {
+	Username    string
+	Provider    string
+	Provisioned bool
+	// Linked is set when the identity was linked to an account that was already signed in.
+	Linked bool
+	Next   string
+}
+
+// LoginMethod is a way to sign in to an account: its password, an identity at an external provider,
+// a passkey or a TOTP authenticator. The password has no ID.
+type LoginMethod struct {
+	ID         string     `json:"id,omitempty"`
+	Type       string     `json:"type"`
+	Provider   string     `json:"provider,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Email      string     `json:"email,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type ReauthenticateRequest struct {
+	Password string `json:"password" binding:"required"`
+	OTP      string `json:"otp"`
+}
+
+type ReauthenticateResponse struct {
+	ReauthToken string `json:"reauth_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type SetPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// TOTPEnrollment is a new TOTP secret. Enrollment is sent back with the first code to confirm it.
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	URI        string `json:"uri"`
+	Enrollment string `json:"enrollment"`
+}
+
+type ConfirmTOTPRequest struct {
+	Enrollment string `json:"enrollment" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+}
+
+// PasskeyCeremony starts a WebAuthn ceremony. PublicKey is passed to navigator.credentials.create or
+// get, after PublicKeyCredential.parseCreationOptionsFromJSON or parseRequestOptionsFromJSON, and
+// Ceremony is sent back with the result.
+type PasskeyCeremony struct {
+	Ceremony  string      `json:"ceremony"`
+	PublicKey interface{} `json:"publicKey" swaggertype:"object"`
+}
+
+type PasskeyCreationOptions struct {
+	Challenge              string                        `json:"challenge"`
+	RP                     PasskeyRelyingParty           `json:"rp"`
+	User                   PasskeyUser                   `json:"user"`
+	PubKeyCredParams       []PasskeyCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int                           `json:"timeout"`
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                        `json:"attestation"`
+}
+
+type PasskeyRequestOptions struct {
+	Challenge        string `json:"challenge"`
+	Timeout          int    `json:"timeout"`
+	RPID             string `json:"rpId"`
+	UserVerification string `json:"userVerification"`
+}
+
+type PasskeyRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type PasskeyUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type PasskeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type PasskeyCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCredential is the result of navigator.credentials.create or get in its JSON form, with
+// binary values base64url encoded.
+type PasskeyCredential struct {
+	ID       string                    `json:"id" binding:"required"`
+	Type     string                    `json:"type"`
+	Response PasskeyCredentialResponse `json:"response"`
+}
+
+type PasskeyCredentialResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AttestationObject string `json:"attestationObject,omitempty"`
+	AuthenticatorData string `json:"authenticatorData,omitempty"`
+	Signature         string `json:"signature,omitempty"`
+	UserHandle        string `json:"userHandle,omitempty"`
+}
+
+type FinishPasskeyRequest struct {
+	Ceremony   string            `json:"ceremony" binding:"required"`
+	Name       string            `json:"name" binding:"max=64"`
+	Credential PasskeyCredential `json:"credential"`
+}
+
+type PasskeyLoginResponse struct {
+	Success  bool   `json:"success"`
+	Reason   string `json:"reason"`
+	Username string `json:"username,omitempty"`
+}
+
+type LinkProviderResponse struct {
+	URL string `json:"url"`
+}
+
+type AccountingSessionResponse struct {
+	ID               string     `json:"id"`
+	Username         string     `json:"username"`
+	Client           string     `json:"client"`
+	SessionID        string     `json:"session_id"`
+	NASAddress       string     `json:"nas_address"`
+	NASIdentifier    string     `json:"nas_identifier,omitempty"`
+	CallingStationID string     `json:"calling_station_id,omitempty"`
+	CalledStationID  string     `json:"called_station_id,omitempty"`
+	FramedIPAddress  string     `json:"framed_ip_address,omitempty"`
+	StartedAt        time.Time  `json:"started_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	StoppedAt        *time.Time `json:"stopped_at,omitempty"`
+	// SessionTime is in seconds, input octets are what the user sent and output octets what the user received.
+	SessionTime    int64  `json:"session_time"`
+	InputOctets    int64  `json:"input_octets"`
+	OutputOctets   int64  `json:"output_octets"`
+	TerminateCause string `json:"terminate_cause,omitempty"`
+}
+
+type AccountingUsageRequest struct {
+	// Since defaults to 30 days ago.
+	Since time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// AccountingUsageResponse adds up the sessions that were online since the time, whole sessions are
+// counted even when they started before.
+type AccountingUsageResponse struct {
+	Username     string                      `json:"username"`
+	Since        time.Time                   `json:"since"`
+	SessionTime  int64                       `json:"session_time"`
+	InputOctets  int64                       `json:"input_octets"`
+	OutputOctets int64                       `json:"output_octets"`
+	Sessions     []AccountingSessionResponse `json:"sessions"`
+}
+
+type SessionAuthorizationRequest struct {
+	// Attributes are sent in the CoA-Request as Name=value pairs separated by semicolons, such as
+	// "Session-Timeout=600;Filter-Id=restricted".
+	Attributes string `json:"attributes" binding:"required"`
+}
+
+type CreateVouchersRequest struct {
+	Count int `json:"count" binding:"required,min=1,max=1000"`
+	// Batch names the vouchers created together, the time they were created when empty.
+	Batch           string `json:"batch" binding:"max=64"`
+	DurationMinutes int    `json:"duration_minutes" binding:"omitempty,min=1,max=525600"`
+	DataLimitMB     int64  `json:"data_limit_mb" binding:"omitempty,min=1"`
+	// RedeemInDays is how long the vouchers can be started, forever when 0.
+	RedeemInDays int    `json:"redeem_in_days" binding:"omitempty,min=1,max=365"`
+	Role         string `json:"role" binding:"max=64"`
+}
+
+type VoucherResponse struct {
+	Code            string     `json:"code"`
+	Status          string     `json:"status"`
+	DurationMinutes int64      `json:"duration_minutes,omitempty"`
+	DataLimitMB     int64      `json:"data_limit_mb,omitempty"`
+	RedeemBy        *time.Time `json:"redeem_by,omitempty"`
+	ActivatedAt     *time.Time `json:"activated_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+}
+
+type VoucherBatchResponse struct {
+	Batch    string            `json:"batch"`
+	Vouchers []VoucherResponse `json:"vouchers"`
+}
+
+// VoucherExportRequest picks how a batch is returned: json, csv, or html to print.
+type VoucherExportRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=json csv html"`
+}
+
+type DeviceRequest struct {
+	// MAC may be written with colons, dashes, in dotted groups of four or bare.
+	MAC         string `json:"mac" binding:"required"`
+	Description string `json:"description" binding:"max=200"`
+	// ExpiresAt is when the device stops being let on, never when empty.
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Owner, VLAN and Role are for admins, a device registered by an account is owned by it.
+	Owner string `json:"owner" binding:"max=64"`
+	VLAN  int    `json:"vlan" binding:"min=0,max=4094"`
+	Role  string `json:"role" binding:"max=64"`
+}
+
+type DeviceResponse struct {
+	ID          string     `json:"id"`
+	MAC         string     `json:"mac"`
+	Owner       string     `json:"owner,omitempty"`
+	Description string     `json:"description,omitempty"`
+	VLAN        int        `json:"vlan,omitempty"`
+	Role        string     `json:"role,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type DeviceImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// AccountSearchRequest finds accounts by a part of their username, all accounts when Query is empty.
+type AccountSearchRequest struct {
+	Query  string `form:"q"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+// AdminAccountResponse is an account as support staff see it.
+type AdminAccountResponse struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Type     string   `json:"type"`
+	Source   string   `json:"source,omitempty"`
+	Roles    []string `json:"roles"`
+	// LockedAt is when the account was locked, it cannot sign in while it is set.
+	LockedAt              *time.Time `json:"locked_at,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+type AccountSearchResponse struct {
+	Accounts []AdminAccountResponse `json:"accounts"`
+}
+
+type LockAccountRequest struct {
+	// Reason is kept in the audit log.
+	Reason string `json:"reason" form:"reason" binding:"max=200"`
+}
+
+// PasswordResetResponse holds the temporary password, it is only shown once and only lets the
+// account choose a new password at its next login.
+type PasswordResetResponse struct {
+	Username          string `json:"username"`
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+type AccountRolesRequest struct {
+	Roles []string `json:"roles"`
+}
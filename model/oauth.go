@@ -0,0 +1,891 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+	GrantJWTBearer         = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	GrantDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// ClientAssertionTypeJWTBearer is the client_assertion_type of RFC 7523 section 2.2.
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// Error codes of RFC 6749 section 4.1.2.1 and 5.2.
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrUnauthorizedClient      = "unauthorized_client"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrInvalidScope            = "invalid_scope"
+	OAuthErrAccessDenied            = "access_denied"
+)
+
+// Error codes of the device authorization grant, RFC 8628 section 3.5.
+const (
+	OAuthErrAuthorizationPending = "authorization_pending"
+	OAuthErrSlowDown             = "slow_down"
+	OAuthErrExpiredToken         = "expired_token"
+)
+
+// Scopes with a meaning to OpenID Connect.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+)
+
+// Token type hints of RFC 7009 section 2.1.
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+const (
+	oauthResponseTypeCode = "code"
+	oauthTokenTypeBearer  = "Bearer"
+	pkceMethodS256        = "S256"
+	minPKCEVerifierLength = 43
+	maxPKCEVerifierLength = 128
+)
+
+var (
+	oauthCodeTTL         = 5 * time.Minute
+	oauthAccessTokenTTL  = 15 * time.Minute
+	oauthRefreshTokenTTL = 30 * 24 * time.Hour
+	oauthClientIDBytes   = 16
+	oauthSecretBytes     = 32
+	oauthTokenBytes      = 32
+)
+
+var (
+	ErrInvalidOAuthClient       = errors.New("Invalid OAuth client")
+	ErrInvalidRedirectURI       = errors.New("Invalid redirect URI")
+	ErrInvalidOAuthGrantType    = errors.New("Invalid OAuth grant type")
+	ErrPublicClientCredentials  = errors.New("Public clients cannot use the client_credentials grant")
+	ErrOAuthClientNotFound      = errors.New("OAuth client not found")
+	ErrOAuthRedirectURIRequired = errors.New("Redirect URI is required for the authorization_code grant")
+)
+
+var supportedGrantTypes = []string{GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials, GrantJWTBearer, GrantDeviceCode}
+
+// OAuthError is an error the OAuth endpoints report to the client as {"error", "error_description"}.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code string, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// RedirectURL adds params and the state to the redirect URI of the request. Only call it once the
+// redirect URI was validated.
+func (r OAuthAuthorizeRequest) RedirectURL(params url.Values) string {
+	redirect, err := url.Parse(r.RedirectURI)
+	if err != nil {
+		return ""
+	}
+	query := redirect.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	if r.State != "" {
+		query.Set("state", r.State)
+	}
+	redirect.RawQuery = query.Encode()
+	return redirect.String()
+}
+
+type OAuthUsecase interface {
+	RegisterClient(ctx context.Context, req OAuthClientRequest) (*OAuthClientResponse, error)
+	ListClients(ctx context.Context) ([]OAuthClientResponse, error)
+	DeleteClient(ctx context.Context, id string) error
+	ValidateAuthorizeRequest(ctx context.Context, req OAuthAuthorizeRequest) (*OAuthConsent, error)
+	Authorize(ctx context.Context, req OAuthAuthorizeRequest, login AccountRequest, approved bool) (string, *AccountResponse, error)
+	AuthorizeAccount(ctx context.Context, req OAuthAuthorizeRequest, username string) (string, error)
+	Token(ctx context.Context, req OAuthTokenRequest) (*OAuthTokenResponse, error)
+	DeviceAuthorization(ctx context.Context, req OAuthDeviceAuthorizationRequest) (*OAuthDeviceAuthorizationResponse, error)
+	ValidateUserCode(ctx context.Context, userCode string) (*OAuthConsent, error)
+	ApproveDevice(ctx context.Context, userCode string, login AccountRequest, approved bool) (*AccountResponse, error)
+	Introspect(ctx context.Context, req OAuthIntrospectRequest) (*OAuthIntrospectResponse, error)
+	Revoke(ctx context.Context, req OAuthRevokeRequest) error
+}
+
+type oauthUsecase struct {
+	repo        repository.OAuthRepository
+	accounts    repository.AccountRepository
+	usecase     UsecaseHandler
+	audit       AuditUsecase
+	signer      TokenSigner
+	revocations RevocationList
+	issuer      string
+}
+
+// NewOAuthUsecase creates the OAuth 2.0 authorization server. Logins go through usecase, so they
+// are rate limited, audited and hooked like any other login.
+func NewOAuthUsecase(repo repository.OAuthRepository, accounts repository.AccountRepository, usecase UsecaseHandler,
+	audit AuditUsecase, signer TokenSigner, revocations RevocationList, issuer string) OAuthUsecase {
+	return &oauthUsecase{
+		repo:        repo,
+		accounts:    accounts,
+		usecase:     usecase,
+		audit:       audit,
+		signer:      signer,
+		revocations: revocations,
+		issuer:      issuer,
+	}
+}
+
+func (o *oauthUsecase) RegisterClient(ctx context.Context, req OAuthClientRequest) (*OAuthClientResponse, error) {
+	for _, grantType := range req.GrantTypes {
+		if !containsString(supportedGrantTypes, grantType) {
+			return nil, ErrInvalidOAuthGrantType
+		}
+	}
+	if req.Public && containsString(req.GrantTypes, GrantClientCredentials) {
+		return nil, ErrPublicClientCredentials
+	}
+	if containsString(req.GrantTypes, GrantAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return nil, ErrOAuthRedirectURIRequired
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			return nil, ErrInvalidRedirectURI
+		}
+	}
+
+	id, err := util.RandomToken(oauthClientIDBytes)
+	if err != nil {
+		return nil, err
+	}
+	client := &repository.OAuthClient{
+		ID:           id,
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, " "),
+		GrantTypes:   strings.Join(req.GrantTypes, " "),
+		Scopes:       strings.Join(req.Scopes, " "),
+		SkipConsent:  req.SkipConsent,
+	}
+	var secret string
+	if !req.Public {
+		if secret, err = util.RandomToken(oauthSecretBytes); err != nil {
+			return nil, err
+		}
+		if client.SecretHash, err = util.HashedPassword(secret); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.repo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+
+	o.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "oauth_client:" + client.ID,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "register oauth client " + client.Name,
+	})
+
+	// The secret is only ever shown here.
+	rsp := oauthClientResponse(client)
+	rsp.Secret = secret
+	return &rsp, nil
+}
+
+func (o *oauthUsecase) ListClients(ctx context.Context) ([]OAuthClientResponse, error) {
+	clients, err := o.repo.ListClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]OAuthClientResponse, 0, len(clients))
+	for i := range clients {
+		rsp = append(rsp, oauthClientResponse(&clients[i]))
+	}
+	return rsp, nil
+}
+
+func (o *oauthUsecase) DeleteClient(ctx context.Context, id string) error {
+	if err := o.repo.DeleteClient(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrOAuthClientNotFound) {
+			return ErrOAuthClientNotFound
+		}
+		return err
+	}
+
+	o.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "oauth_client:" + id,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "delete oauth client",
+	})
+	return nil
+}
+
+// ValidateAuthorizeRequest checks an authorize request. ErrInvalidOAuthClient and ErrInvalidRedirectURI
+// must be shown to the user, any other problem is an *OAuthError to send back to the redirect URI.
+func (o *oauthUsecase) ValidateAuthorizeRequest(ctx context.Context, req OAuthAuthorizeRequest) (*OAuthConsent, error) {
+	client, err := o.repo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthClientNotFound) {
+			return nil, ErrInvalidOAuthClient
+		}
+		return nil, err
+	}
+	if req.RedirectURI == "" || !containsString(strings.Fields(client.RedirectURIs), req.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != oauthResponseTypeCode {
+		return nil, newOAuthError(OAuthErrUnsupportedResponseType, "response_type must be code")
+	}
+	if !clientAllowsGrant(client, GrantAuthorizationCode) {
+		return nil, newOAuthError(OAuthErrUnauthorizedClient, "client may not use the authorization_code grant")
+	}
+	if req.CodeChallenge == "" {
+		return nil, newOAuthError(OAuthErrInvalidRequest, "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != pkceMethodS256 {
+		return nil, newOAuthError(OAuthErrInvalidRequest, "code_challenge_method must be S256")
+	}
+	scope, oauthErr := resolveScope(strings.Fields(client.Scopes), req.Scope)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	return &OAuthConsent{
+		ClientName:  client.Name,
+		Scopes:      strings.Fields(scope),
+		SkipConsent: client.SkipConsent,
+	}, nil
+}
+
+// Authorize logs the user in and returns where to send the browser: back to the client with a code,
+// or with access_denied when the user did not approve. A failed login returns the login response and
+// error so the page can be shown again.
+func (o *oauthUsecase) Authorize(ctx context.Context, req OAuthAuthorizeRequest, login AccountRequest, approved bool) (string, *AccountResponse, error) {
+	consent, err := o.ValidateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+	if !approved {
+		return req.RedirectURL(url.Values{"error": {OAuthErrAccessDenied}}), nil, nil
+	}
+
+	account, err := o.loginAccount(ctx, login.Username)
+	if err != nil {
+		return "", nil, err
+	}
+	if account != nil && account.Type == repository.AccountTypeVoucher {
+		rsp, err := o.refuseVoucher(ctx, account)
+		return "", rsp, err
+	}
+	rsp, err := o.usecase.LoginAccount(ctx, login)
+	if err != nil {
+		return "", rsp, err
+	}
+	if account == nil {
+		if account, err = o.accounts.GetAccount(ctx, rsp.Username); err != nil {
+			return "", nil, err
+		}
+	}
+	redirectURL, err := o.issueAuthorizationCode(ctx, req, consent, account)
+	if err != nil {
+		return "", nil, err
+	}
+	return redirectURL, rsp, nil
+}
+
+// loginAccount looks up the account a login names, spelled as typed or, for a voucher, as printed.
+// It returns nil without one, as a directory user only gets an account once LoginAccount took the
+// password.
+func (o *oauthUsecase) loginAccount(ctx context.Context, username string) (*repository.Account, error) {
+	account, err := o.accounts.GetAccount(ctx, username)
+	if code := NormalizeVoucherCode(username); err == repository.ErrAccountRecordNotFound && code != username && IsVoucherCode(code) {
+		account, err = o.accounts.GetAccount(ctx, code)
+		if err == nil && account.Type != repository.AccountTypeVoucher {
+			err = repository.ErrAccountRecordNotFound
+		}
+	}
+	if err == repository.ErrAccountRecordNotFound {
+		return nil, nil
+	}
+	return account, err
+}
+
+// refuseVoucher turns a voucher away before LoginAccount redeems it, which would start its validity.
+// Vouchers only get network access, no tokens to manage an account with.
+func (o *oauthUsecase) refuseVoucher(ctx context.Context, account *repository.Account) (*AccountResponse, error) {
+	rsp := &AccountResponse{
+		Success: false,
+		Reason:  ErrVoucherNoSelfService.Error(),
+	}
+	o.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginDenied,
+		Target:  account.Username,
+		Outcome: AuditOutcomeFailure,
+		Detail:  rsp.Reason,
+	})
+	return rsp, ErrVoucherNoSelfService
+}
+
+// AuthorizeAccount returns the redirect back to the client with a code for an account that already
+// signed in another way, such as through an external identity provider.
+func (o *oauthUsecase) AuthorizeAccount(ctx context.Context, req OAuthAuthorizeRequest, username string) (string, error) {
+	consent, err := o.ValidateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	account, err := o.accounts.GetAccount(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	if account.LockedAt != nil {
+		return "", newOAuthError(OAuthErrAccessDenied, "the account is locked")
+	}
+	return o.issueAuthorizationCode(ctx, req, consent, account)
+}
+
+func (o *oauthUsecase) issueAuthorizationCode(ctx context.Context, req OAuthAuthorizeRequest, consent *OAuthConsent,
+	account *repository.Account) (string, error) {
+	code, err := util.RandomToken(oauthTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	err = o.repo.CreateAuthorizationCode(ctx, &repository.OAuthAuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            req.ClientID,
+		AccountID:           account.ID,
+		Username:            account.Username,
+		RedirectURI:         req.RedirectURI,
+		Scope:               strings.Join(consent.Scopes, " "),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            time.Now(),
+		ExpiresAt:           time.Now().Add(oauthCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return req.RedirectURL(url.Values{"code": {code}}), nil
+}
+
+// Token implements the token endpoint. Errors meant for the client are *OAuthError.
+func (o *oauthUsecase) Token(ctx context.Context, req OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	if !containsString(supportedGrantTypes, req.GrantType) {
+		return nil, newOAuthError(OAuthErrUnsupportedGrantType, "unsupported grant_type "+req.GrantType)
+	}
+	client, err := o.tokenClient(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !clientAllowsGrant(client, req.GrantType) {
+		return nil, newOAuthError(OAuthErrUnauthorizedClient, "client may not use the "+req.GrantType+" grant")
+	}
+
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		return o.exchangeAuthorizationCode(ctx, client, req)
+	case GrantRefreshToken:
+		return o.exchangeRefreshToken(ctx, client, req)
+	case GrantDeviceCode:
+		return o.exchangeDeviceCode(ctx, client, req)
+	default:
+		return o.clientCredentials(ctx, client, req)
+	}
+}
+
+// tokenClient authenticates the client of a token request. The jwt-bearer grant is authenticated by
+// its assertion, other grants by a client assertion when there is one, or else by the client secret.
+func (o *oauthUsecase) tokenClient(ctx context.Context, req OAuthTokenRequest) (*repository.OAuthClient, error) {
+	if req.GrantType == GrantJWTBearer {
+		return o.verifyClientAssertion(ctx, req.Assertion, OAuthErrInvalidGrant)
+	}
+	if req.ClientAssertionType == "" && req.ClientAssertion == "" {
+		return o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	}
+	if req.ClientAssertionType != ClientAssertionTypeJWTBearer {
+		return nil, newOAuthError(OAuthErrInvalidClient, "unsupported client_assertion_type")
+	}
+	client, err := o.verifyClientAssertion(ctx, req.ClientAssertion, OAuthErrInvalidClient)
+	if err != nil {
+		return nil, err
+	}
+	if req.ClientID != "" && req.ClientID != client.ID {
+		return nil, newOAuthError(OAuthErrInvalidClient, "client_id does not match the client assertion")
+	}
+	return client, nil
+}
+
+// Introspect tells a resource server whether a token is active. Only confidential clients may ask.
+// Refresh tokens are only described to the client they were issued to.
+func (o *oauthUsecase) Introspect(ctx context.Context, req OAuthIntrospectRequest) (*OAuthIntrospectResponse, error) {
+	client, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.SecretHash == "" {
+		return nil, newOAuthError(OAuthErrInvalidClient, "public clients may not introspect tokens")
+	}
+
+	for _, tokenType := range tokenTypeOrder(req.TokenTypeHint) {
+		var rsp *OAuthIntrospectResponse
+		if tokenType == TokenTypeHintAccessToken {
+			rsp, err = o.introspectAccessToken(ctx, req.Token)
+		} else {
+			rsp, err = o.introspectRefreshToken(ctx, client, req.Token)
+		}
+		if err != nil || rsp != nil {
+			return rsp, err
+		}
+	}
+	return &OAuthIntrospectResponse{Active: false}, nil
+}
+
+// Revoke revokes an access or refresh token of the client. As RFC 7009 asks, an unknown or already
+// invalid token is not an error. Access tokens issued from a revoked refresh token stay valid until
+// they expire.
+func (o *oauthUsecase) Revoke(ctx context.Context, req OAuthRevokeRequest) error {
+	client, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	for _, tokenType := range tokenTypeOrder(req.TokenTypeHint) {
+		var revoked bool
+		if tokenType == TokenTypeHintAccessToken {
+			revoked, err = o.revokeAccessToken(ctx, client, req.Token)
+		} else {
+			revoked, err = o.revokeRefreshToken(ctx, client, req.Token)
+		}
+		if err != nil {
+			return err
+		}
+		if revoked {
+			o.audit.Record(ctx, AuditEntry{
+				Type:    AuditOAuthTokenRevoked,
+				Actor:   "oauth_client:" + client.ID,
+				Target:  "oauth_client:" + client.ID,
+				Outcome: AuditOutcomeSuccess,
+				Detail:  tokenType,
+			})
+			return nil
+		}
+	}
+	return nil
+}
+
+func (o *oauthUsecase) introspectAccessToken(ctx context.Context, token string) (*OAuthIntrospectResponse, error) {
+	claims, err := verifyAccessToken(ctx, o.signer, o.revocations, o.issuer, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	rsp := &OAuthIntrospectResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.Username,
+		TokenType: oauthTokenTypeBearer,
+		Subject:   claims.Subject,
+		Audience:  claims.Audience,
+		Issuer:    claims.Issuer,
+		JTI:       claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		rsp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		rsp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return rsp, nil
+}
+
+func (o *oauthUsecase) introspectRefreshToken(ctx context.Context, client *repository.OAuthClient, token string) (*OAuthIntrospectResponse, error) {
+	refreshToken, err := o.repo.GetRefreshToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthRefreshTokenNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if refreshToken.ClientID != client.ID || refreshToken.RevokedAt != nil || time.Now().After(refreshToken.ExpiresAt) {
+		return nil, nil
+	}
+	return &OAuthIntrospectResponse{
+		Active:    true,
+		Scope:     refreshToken.Scope,
+		ClientID:  refreshToken.ClientID,
+		Username:  refreshToken.Username,
+		TokenType: TokenTypeHintRefreshToken,
+		ExpiresAt: refreshToken.ExpiresAt.Unix(),
+		IssuedAt:  refreshToken.CreatedAt.Unix(),
+		Subject:   refreshToken.AccountID.String(),
+		Issuer:    o.issuer,
+	}, nil
+}
+
+func (o *oauthUsecase) revokeAccessToken(ctx context.Context, client *repository.OAuthClient, token string) (bool, error) {
+	claims := &AccessTokenClaims{}
+	if err := o.signer.Verify(token, claims); err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return false, nil
+	}
+	if claims.ClientID != client.ID {
+		return false, newOAuthError(OAuthErrUnauthorizedClient, "token was issued to another client")
+	}
+	if err := o.revocations.Revoke(ctx, claims.ID, client.ID, claims.ExpiresAt.Time); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (o *oauthUsecase) revokeRefreshToken(ctx context.Context, client *repository.OAuthClient, token string) (bool, error) {
+	err := o.repo.RevokeRefreshToken(ctx, hashToken(token), client.ID, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthRefreshTokenNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// tokenTypeOrder tries the hinted token type first. An unknown hint is ignored.
+func tokenTypeOrder(hint string) []string {
+	if hint == TokenTypeHintRefreshToken {
+		return []string{TokenTypeHintRefreshToken, TokenTypeHintAccessToken}
+	}
+	return []string{TokenTypeHintAccessToken, TokenTypeHintRefreshToken}
+}
+
+func (o *oauthUsecase) authenticateClient(ctx context.Context, id string, secret string) (*repository.OAuthClient, error) {
+	if id == "" {
+		return nil, newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+	}
+	client, err := o.repo.GetClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthClientNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+		}
+		return nil, err
+	}
+	if client.SecretHash != "" && util.CheckPassword(secret, client.SecretHash) != nil {
+		return nil, newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+func (o *oauthUsecase) exchangeAuthorizationCode(ctx context.Context, client *repository.OAuthClient, req OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	code, err := o.repo.ConsumeAuthorizationCode(ctx, hashToken(req.Code), time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthAuthorizationCodeNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, "authorization code is invalid or already used")
+		}
+		return nil, err
+	}
+	switch {
+	case code.ClientID != client.ID:
+		return nil, newOAuthError(OAuthErrInvalidGrant, "authorization code was issued to another client")
+	case time.Now().After(code.ExpiresAt):
+		return nil, newOAuthError(OAuthErrInvalidGrant, "authorization code is expired")
+	case code.RedirectURI != req.RedirectURI:
+		return nil, newOAuthError(OAuthErrInvalidGrant, "redirect_uri does not match the authorize request")
+	case !verifyPKCE(req.CodeVerifier, code.CodeChallenge):
+		return nil, newOAuthError(OAuthErrInvalidGrant, "code_verifier does not match the code_challenge")
+	}
+	return o.issueTokens(ctx, client, GrantAuthorizationCode, oauthGrant{
+		AccountID: code.AccountID,
+		Username:  code.Username,
+		Scope:     code.Scope,
+		Nonce:     code.Nonce,
+		AuthTime:  code.AuthTime,
+	})
+}
+
+func (o *oauthUsecase) exchangeRefreshToken(ctx context.Context, client *repository.OAuthClient, req OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	token, err := o.repo.ConsumeRefreshToken(ctx, hashToken(req.RefreshToken), time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthRefreshTokenNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, "refresh token is invalid or already used")
+		}
+		return nil, err
+	}
+	if token.ClientID != client.ID {
+		return nil, newOAuthError(OAuthErrInvalidGrant, "refresh token was issued to another client")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, newOAuthError(OAuthErrInvalidGrant, "refresh token is expired")
+	}
+	// The new tokens may only narrow the scope of the refresh token.
+	scope, oauthErr := resolveScope(strings.Fields(token.Scope), req.Scope)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	// A refresh token outlives the login it came from, the account may be gone or locked since.
+	account, err := o.accounts.GetAccountByID(ctx, token.AccountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, "the account was deleted")
+		}
+		return nil, err
+	}
+	if account.LockedAt != nil {
+		return nil, newOAuthError(OAuthErrInvalidGrant, "the account is locked")
+	}
+	return o.issueTokens(ctx, client, GrantRefreshToken, oauthGrant{
+		AccountID: token.AccountID,
+		Username:  token.Username,
+		Scope:     scope,
+		AuthTime:  token.AuthTime,
+	})
+}
+
+// clientCredentials serves the client_credentials and jwt-bearer grants. The client acts for itself,
+// or for its service account when it belongs to one.
+func (o *oauthUsecase) clientCredentials(ctx context.Context, client *repository.OAuthClient, req OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	if req.GrantType == GrantClientCredentials && client.SecretHash == "" {
+		return nil, newOAuthError(OAuthErrUnauthorizedClient, "public clients may not use the client_credentials grant")
+	}
+	scope, oauthErr := resolveScope(strings.Fields(client.Scopes), req.Scope)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	grant := oauthGrant{Scope: scope}
+	if client.AccountID != nil {
+		account, err := o.accounts.GetAccountByID(ctx, *client.AccountID)
+		if err != nil {
+			if errors.Is(err, repository.ErrAccountRecordNotFound) {
+				return nil, newOAuthError(OAuthErrInvalidClient, "the service account of the client was deleted")
+			}
+			return nil, err
+		}
+		if account.LockedAt != nil {
+			return nil, newOAuthError(OAuthErrInvalidClient, "the service account of the client is locked")
+		}
+		grant.AccountID = account.ID
+		grant.Username = account.Username
+	}
+	return o.issueTokens(ctx, client, req.GrantType, grant)
+}
+
+// oauthGrant is what a grant authorizes. AccountID is uuid.Nil when the client acts for itself.
+type oauthGrant struct {
+	AccountID uuid.UUID
+	Username  string
+	Scope     string
+	Nonce     string
+	AuthTime  time.Time
+}
+
+// issueTokens signs an access token, an ID token when the openid scope was granted to an account, and
+// a refresh token for an account when the client may refresh.
+func (o *oauthUsecase) issueTokens(ctx context.Context, client *repository.OAuthClient, grantType string,
+	grant oauthGrant) (*OAuthTokenResponse, error) {
+	now := time.Now()
+	accountID, username, scope := grant.AccountID, grant.Username, grant.Scope
+	subject := client.ID
+	if accountID != uuid.Nil {
+		subject = accountID.String()
+	}
+	accessToken, err := o.signer.Sign(AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    o.issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{client.ID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(oauthAccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.New().String(),
+		},
+		ClientID: client.ID,
+		Username: username,
+		Scope:    scope,
+		AuthTime: authTimeClaim(grant.AuthTime),
+	})
+	if err != nil {
+		return nil, err
+	}
+	rsp := &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   oauthTokenTypeBearer,
+		ExpiresIn:   int(oauthAccessTokenTTL.Seconds()),
+		Scope:       scope,
+	}
+
+	if accountID != uuid.Nil && containsString(strings.Fields(scope), ScopeOpenID) {
+		if rsp.IDToken, err = o.signIDToken(ctx, client, grant, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if accountID != uuid.Nil && clientAllowsGrant(client, GrantRefreshToken) {
+		refreshToken, err := util.RandomToken(oauthTokenBytes)
+		if err != nil {
+			return nil, err
+		}
+		err = o.repo.CreateRefreshToken(ctx, &repository.OAuthRefreshToken{
+			TokenHash: hashToken(refreshToken),
+			ClientID:  client.ID,
+			AccountID: accountID,
+			Username:  username,
+			Scope:     scope,
+			AuthTime:  grant.AuthTime,
+			ExpiresAt: now.Add(oauthRefreshTokenTTL),
+		})
+		if err != nil {
+			return nil, err
+		}
+		rsp.RefreshToken = refreshToken
+	}
+
+	target := username
+	if target == "" {
+		target = "oauth_client:" + client.ID
+	}
+	// A service account is audited as itself rather than as its client.
+	actor := "oauth_client:" + client.ID
+	if client.AccountID != nil && username != "" {
+		actor = username
+	}
+	o.audit.Record(ctx, AuditEntry{
+		Type:    AuditOAuthTokenIssued,
+		Actor:   actor,
+		Target:  target,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  grantType + " scope=" + scope,
+	})
+	return rsp, nil
+}
+
+// signIDToken signs the OpenID Connect ID token, with the profile claims when that scope was granted.
+func (o *oauthUsecase) signIDToken(ctx context.Context, client *repository.OAuthClient, grant oauthGrant, now time.Time) (string, error) {
+	account, err := o.accounts.GetAccount(ctx, grant.Username)
+	if err != nil {
+		return "", err
+	}
+	claims := IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    o.issuer,
+			Subject:   account.ID.String(),
+			Audience:  jwt.ClaimStrings{client.ID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(oauthAccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		AuthTime: authTimeClaim(grant.AuthTime),
+		Nonce:    grant.Nonce,
+	}
+	if containsString(strings.Fields(grant.Scope), ScopeProfile) {
+		claims.PreferredUsername = account.Username
+		claims.UpdatedAt = account.UpdatedAt.Unix()
+	}
+	return o.signer.Sign(claims)
+}
+
+// authTimeClaim is the auth_time claim of a grant, left out when the grant has no sign-in time.
+func authTimeClaim(authTime time.Time) *jwt.NumericDate {
+	if authTime.IsZero() {
+		return nil
+	}
+	return jwt.NewNumericDate(authTime)
+}
+
+// resolveScope returns the requested scope, or all of allowed when none was requested.
+func resolveScope(allowed []string, requested string) (string, *OAuthError) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(allowed, " "), nil
+	}
+	scopes := strings.Fields(requested)
+	for _, scope := range scopes {
+		if !containsString(allowed, scope) {
+			return "", newOAuthError(OAuthErrInvalidScope, "scope "+scope+" is not allowed")
+		}
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+func verifyPKCE(verifier string, challenge string) bool {
+	if len(verifier) < minPKCEVerifierLength || len(verifier) > maxPKCEVerifierLength {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(pkceChallenge(verifier)), []byte(challenge)) == 1
+}
+
+// pkceChallenge is the S256 code challenge of verifier.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// validRedirectURI accepts absolute https URIs without a fragment, and plain http on loopback for
+// native and development clients.
+func validRedirectURI(redirectURI string) bool {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" || parsed.Fragment != "" {
+		return false
+	}
+	switch parsed.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := parsed.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	}
+	return false
+}
+
+func clientAllowsGrant(client *repository.OAuthClient, grantType string) bool {
+	return containsString(strings.Fields(client.GrantTypes), grantType)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func oauthClientResponse(client *repository.OAuthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		GrantTypes:   strings.Fields(client.GrantTypes),
+		Scopes:       strings.Fields(client.Scopes),
+		Public:       client.SecretHash == "",
+		SkipConsent:  client.SkipConsent,
+		CreatedAt:    client.CreatedAt,
+	}
+}
@@ -0,0 +1,249 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+)
+
+// userCodeAlphabet has no vowels, so user codes do not spell words, and no digits that look like
+// letters (RFC 8628 section 6.1).
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+var (
+	oauthDeviceCodeTTL      = 10 * time.Minute
+	oauthDevicePollInterval = 5 * time.Second
+	// oauthDeviceSlowDown is added to the poll interval each time a device polls too fast.
+	oauthDeviceSlowDown = 5 * time.Second
+	userCodeLength      = 8
+)
+
+var ErrInvalidUserCode = errors.New("The code is invalid or expired")
+
+// DeviceAuthorization starts the device authorization grant (RFC 8628) for a device that cannot
+// open a browser. The user enters the user code at the verification URI while the device polls the
+// token endpoint with the device code.
+func (o *oauthUsecase) DeviceAuthorization(ctx context.Context, req OAuthDeviceAuthorizationRequest) (*OAuthDeviceAuthorizationResponse, error) {
+	client, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !clientAllowsGrant(client, GrantDeviceCode) {
+		return nil, newOAuthError(OAuthErrUnauthorizedClient, "client may not use the device_code grant")
+	}
+	scope, oauthErr := resolveScope(strings.Fields(client.Scopes), req.Scope)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	deviceCode, err := util.RandomToken(oauthTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := newUserCode()
+	if err != nil {
+		return nil, err
+	}
+	err = o.repo.CreateDeviceCode(ctx, &repository.OAuthDeviceCode{
+		DeviceCodeHash: hashToken(deviceCode),
+		UserCode:       userCode,
+		ClientID:       client.ID,
+		Scope:          scope,
+		Status:         repository.OAuthDeviceCodePending,
+		PollInterval:   int(oauthDevicePollInterval.Seconds()),
+		ExpiresAt:      time.Now().Add(oauthDeviceCodeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	verificationURI := o.issuer + "/oauth/device"
+	display := formatUserCode(userCode)
+	return &OAuthDeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                display,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {display}}.Encode(),
+		ExpiresIn:               int(oauthDeviceCodeTTL.Seconds()),
+		Interval:                int(oauthDevicePollInterval.Seconds()),
+	}, nil
+}
+
+// ValidateUserCode returns what the verification page shows for a user code that is still pending.
+func (o *oauthUsecase) ValidateUserCode(ctx context.Context, userCode string) (*OAuthConsent, error) {
+	code, client, err := o.pendingDeviceCode(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthConsent{
+		ClientName:  client.Name,
+		Scopes:      strings.Fields(code.Scope),
+		SkipConsent: client.SkipConsent,
+	}, nil
+}
+
+// ApproveDevice logs the user in and approves the device, or denies it. A failed login returns the
+// login response and error so the page can be shown again.
+func (o *oauthUsecase) ApproveDevice(ctx context.Context, userCode string, login AccountRequest, approved bool) (*AccountResponse, error) {
+	code, client, err := o.pendingDeviceCode(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+	if !approved {
+		if err := o.repo.DenyDeviceCode(ctx, code.UserCode, time.Now()); err != nil {
+			if errors.Is(err, repository.ErrOAuthDeviceCodeNotFound) {
+				return nil, ErrInvalidUserCode
+			}
+			return nil, err
+		}
+		o.audit.Record(ctx, AuditEntry{
+			Type:    AuditOAuthDeviceApproved,
+			Target:  "oauth_client:" + client.ID,
+			Outcome: AuditOutcomeFailure,
+			Detail:  "denied",
+		})
+		return nil, nil
+	}
+
+	account, err := o.loginAccount(ctx, login.Username)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil && account.Type == repository.AccountTypeVoucher {
+		return o.refuseVoucher(ctx, account)
+	}
+	rsp, err := o.usecase.LoginAccount(ctx, login)
+	if err != nil {
+		return rsp, err
+	}
+	if account == nil {
+		if account, err = o.accounts.GetAccount(ctx, rsp.Username); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.repo.ApproveDeviceCode(ctx, code.UserCode, account.ID, account.Username, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrOAuthDeviceCodeNotFound) {
+			return nil, ErrInvalidUserCode
+		}
+		return nil, err
+	}
+
+	o.audit.Record(ctx, AuditEntry{
+		Type:    AuditOAuthDeviceApproved,
+		Actor:   account.Username,
+		Target:  "oauth_client:" + client.ID,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "scope=" + code.Scope,
+	})
+	return rsp, nil
+}
+
+// exchangeDeviceCode answers a poll of the device. Until the user decides it gets
+// authorization_pending, and slow_down with a longer interval when it polls faster than it was told.
+func (o *oauthUsecase) exchangeDeviceCode(ctx context.Context, client *repository.OAuthClient, req OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	now := time.Now()
+	code, err := o.repo.PollDeviceCode(ctx, hashToken(req.DeviceCode), now)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthDeviceCodeNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, "device code is invalid")
+		}
+		return nil, err
+	}
+	switch {
+	case code.ClientID != client.ID:
+		return nil, newOAuthError(OAuthErrInvalidGrant, "device code was issued to another client")
+	case code.UsedAt != nil:
+		return nil, newOAuthError(OAuthErrInvalidGrant, "device code was already used")
+	case now.After(code.ExpiresAt):
+		return nil, newOAuthError(OAuthErrExpiredToken, "device code is expired")
+	case code.Status == repository.OAuthDeviceCodeDenied:
+		return nil, newOAuthError(OAuthErrAccessDenied, "the user denied the request")
+	}
+
+	interval := time.Duration(code.PollInterval) * time.Second
+	if code.LastPolledAt != nil && now.Sub(*code.LastPolledAt) < interval {
+		slower := int((interval + oauthDeviceSlowDown).Seconds())
+		if err := o.repo.UpdateDevicePollInterval(ctx, code.DeviceCodeHash, slower); err != nil {
+			return nil, err
+		}
+		return nil, newOAuthError(OAuthErrSlowDown, "poll at most every "+(interval+oauthDeviceSlowDown).String())
+	}
+	if code.Status == repository.OAuthDeviceCodePending {
+		return nil, newOAuthError(OAuthErrAuthorizationPending, "the user has not approved the request yet")
+	}
+
+	code, err = o.repo.ConsumeDeviceCode(ctx, code.DeviceCodeHash, now)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthDeviceCodeNotFound) {
+			return nil, newOAuthError(OAuthErrInvalidGrant, "device code was already used")
+		}
+		return nil, err
+	}
+	grant := oauthGrant{
+		AccountID: *code.AccountID,
+		Username:  code.Username,
+		Scope:     code.Scope,
+	}
+	if code.AuthTime != nil {
+		grant.AuthTime = *code.AuthTime
+	}
+	return o.issueTokens(ctx, client, GrantDeviceCode, grant)
+}
+
+func (o *oauthUsecase) pendingDeviceCode(ctx context.Context, userCode string) (*repository.OAuthDeviceCode, *repository.OAuthClient, error) {
+	code, err := o.repo.GetDeviceCodeByUserCode(ctx, normalizeUserCode(userCode))
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthDeviceCodeNotFound) {
+			return nil, nil, ErrInvalidUserCode
+		}
+		return nil, nil, err
+	}
+	if code.Status != repository.OAuthDeviceCodePending || time.Now().After(code.ExpiresAt) {
+		return nil, nil, ErrInvalidUserCode
+	}
+	client, err := o.repo.GetClient(ctx, code.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthClientNotFound) {
+			return nil, nil, ErrInvalidUserCode
+		}
+		return nil, nil, err
+	}
+	return code, client, nil
+}
+
+func newUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// formatUserCode splits the code in two halves to make it easier to type.
+func formatUserCode(code string) string {
+	half := len(code) / 2
+	return code[:half] + "-" + code[half:]
+}
+
+// normalizeUserCode accepts the code as typed: any case, with or without the dash and spaces.
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceAuthorization(t *testing.T) {
+	client := newOAuthClient(t, "", GrantDeviceCode)
+
+	t.Run("ok", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		var stored *repository.OAuthDeviceCode
+		mocks.repo.EXPECT().CreateDeviceCode(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, code *repository.OAuthDeviceCode) error {
+				stored = code
+				return nil
+			})
+
+		rsp, err := oauth.DeviceAuthorization(context.Background(), OAuthDeviceAuthorizationRequest{ClientID: client.ID, Scope: "profile"})
+		require.NoError(t, err)
+		require.Equal(t, hashToken(rsp.DeviceCode), stored.DeviceCodeHash)
+		require.Equal(t, repository.OAuthDeviceCodePending, stored.Status)
+		require.Equal(t, "profile", stored.Scope)
+		require.Equal(t, stored.UserCode, normalizeUserCode(rsp.UserCode))
+		require.Len(t, rsp.UserCode, 9)
+		require.Equal(t, testIssuer+"/oauth/device", rsp.VerificationURI)
+		require.Equal(t, rsp.VerificationURI+"?"+url.Values{"user_code": {rsp.UserCode}}.Encode(), rsp.VerificationURIComplete)
+		require.Equal(t, 5, rsp.Interval)
+		for _, r := range stored.UserCode {
+			require.True(t, strings.ContainsRune(userCodeAlphabet, r))
+		}
+	})
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		other := newOAuthClient(t, "", GrantAuthorizationCode)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), other.ID).Return(other, nil)
+		mocks.repo.EXPECT().CreateDeviceCode(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := oauth.DeviceAuthorization(context.Background(), OAuthDeviceAuthorizationRequest{ClientID: other.ID})
+		requireOAuthError(t, err, OAuthErrUnauthorizedClient)
+	})
+}
+
+func newTestDeviceCode(clientID string) *repository.OAuthDeviceCode {
+	return &repository.OAuthDeviceCode{
+		DeviceCodeHash: hashToken("device-code"),
+		UserCode:       "BCDFGHJK",
+		ClientID:       clientID,
+		Scope:          "profile",
+		Status:         repository.OAuthDeviceCodePending,
+		PollInterval:   5,
+		ExpiresAt:      time.Now().Add(time.Minute),
+	}
+}
+
+func TestApproveDevice(t *testing.T) {
+	client := newOAuthClient(t, "", GrantDeviceCode)
+	login := AccountRequest{Username: "alice", Password: "Password1"}
+	account := &repository.Account{ID: uuid.New(), Username: "alice"}
+
+	t.Run("approve", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(newTestDeviceCode(client.ID), nil)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), login).Return(&AccountResponse{Success: true}, nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil)
+		mocks.repo.EXPECT().ApproveDeviceCode(gomock.Any(), "BCDFGHJK", account.ID, "alice", gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthDeviceApproved, AuditOutcomeSuccess))
+
+		rsp, err := oauth.ApproveDevice(context.Background(), "bcdf-ghjk", login, true)
+		require.NoError(t, err)
+		require.True(t, rsp.Success)
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(newTestDeviceCode(client.ID), nil)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+		mocks.repo.EXPECT().DenyDeviceCode(gomock.Any(), "BCDFGHJK", gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthDeviceApproved, AuditOutcomeFailure))
+
+		rsp, err := oauth.ApproveDevice(context.Background(), "BCDF-GHJK", AccountRequest{}, false)
+		require.NoError(t, err)
+		require.Nil(t, rsp)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(newTestDeviceCode(client.ID), nil)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil)
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), login).
+			Return(&AccountResponse{Reason: ErrLoginWrongPassword.Error()}, ErrLoginWrongPassword)
+		mocks.repo.EXPECT().ApproveDeviceCode(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		rsp, err := oauth.ApproveDevice(context.Background(), "BCDF-GHJK", login, true)
+		require.Equal(t, ErrLoginWrongPassword, err)
+		require.NotNil(t, rsp)
+	})
+
+	t.Run("voucher", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		login := AccountRequest{Username: "abcde-23456", Password: "abcde-23456"}
+		mocks.repo.EXPECT().GetDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(newTestDeviceCode(client.ID), nil)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "abcde-23456").Return(nil, repository.ErrAccountRecordNotFound)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "ABCDE23456").
+			Return(&repository.Account{ID: uuid.New(), Username: "ABCDE23456", Type: repository.AccountTypeVoucher}, nil)
+		// The voucher is not redeemed, its validity does not start.
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+		mocks.repo.EXPECT().ApproveDeviceCode(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		rsp, err := oauth.ApproveDevice(context.Background(), "BCDF-GHJK", login, true)
+		require.Equal(t, ErrVoucherNoSelfService, err)
+		require.Equal(t, ErrVoucherNoSelfService.Error(), rsp.Reason)
+	})
+
+	t.Run("already decided", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		code := newTestDeviceCode(client.ID)
+		code.Status = repository.OAuthDeviceCodeApproved
+		mocks.repo.EXPECT().GetDeviceCodeByUserCode(gomock.Any(), "BCDFGHJK").Return(code, nil)
+
+		_, err := oauth.ValidateUserCode(context.Background(), "BCDF-GHJK")
+		require.Equal(t, ErrInvalidUserCode, err)
+	})
+}
+
+func TestTokenDeviceCode(t *testing.T) {
+	client := newOAuthClient(t, "", GrantDeviceCode)
+	req := OAuthTokenRequest{GrantType: GrantDeviceCode, ClientID: client.ID, DeviceCode: "device-code"}
+	longAgo := time.Now().Add(-time.Minute)
+	justNow := time.Now().Add(-time.Second)
+
+	testCase := []struct {
+		name    string
+		code    func(code *repository.OAuthDeviceCode)
+		errCode string
+	}{
+		{name: "pending", code: func(code *repository.OAuthDeviceCode) { code.LastPolledAt = &longAgo }, errCode: OAuthErrAuthorizationPending},
+		{name: "first poll", errCode: OAuthErrAuthorizationPending},
+		{name: "denied", code: func(code *repository.OAuthDeviceCode) { code.Status = repository.OAuthDeviceCodeDenied }, errCode: OAuthErrAccessDenied},
+		{name: "expired", code: func(code *repository.OAuthDeviceCode) { code.ExpiresAt = longAgo }, errCode: OAuthErrExpiredToken},
+		{name: "other client", code: func(code *repository.OAuthDeviceCode) { code.ClientID = "other" }, errCode: OAuthErrInvalidGrant},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			oauth, mocks := newTestOAuthUsecase(t)
+			code := newTestDeviceCode(client.ID)
+			if tc.code != nil {
+				tc.code(code)
+			}
+			mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+			mocks.repo.EXPECT().PollDeviceCode(gomock.Any(), hashToken("device-code"), gomock.Any()).Return(code, nil)
+
+			_, err := oauth.Token(context.Background(), req)
+			requireOAuthError(t, err, tc.errCode)
+		})
+	}
+
+	t.Run("slow down", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		code := newTestDeviceCode(client.ID)
+		code.LastPolledAt = &justNow
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().PollDeviceCode(gomock.Any(), hashToken("device-code"), gomock.Any()).Return(code, nil)
+		mocks.repo.EXPECT().UpdateDevicePollInterval(gomock.Any(), code.DeviceCodeHash, 10).Return(nil)
+
+		_, err := oauth.Token(context.Background(), req)
+		requireOAuthError(t, err, OAuthErrSlowDown)
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		accountID := uuid.New()
+		authTime := time.Now()
+		code := newTestDeviceCode(client.ID)
+		code.Status = repository.OAuthDeviceCodeApproved
+		code.LastPolledAt = &longAgo
+		approved := *code
+		approved.AccountID = &accountID
+		approved.Username = "alice"
+		approved.AuthTime = &authTime
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().PollDeviceCode(gomock.Any(), hashToken("device-code"), gomock.Any()).Return(code, nil)
+		mocks.repo.EXPECT().ConsumeDeviceCode(gomock.Any(), code.DeviceCodeHash, gomock.Any()).Return(&approved, nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenIssued, AuditOutcomeSuccess))
+
+		rsp, err := oauth.Token(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "profile", rsp.Scope)
+
+		claims := &AccessTokenClaims{}
+		require.NoError(t, mocks.signer.Verify(rsp.AccessToken, claims))
+		require.Equal(t, accountID.String(), claims.Subject)
+		require.Equal(t, "alice", claims.Username)
+	})
+
+	t.Run("already used", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		code := newTestDeviceCode(client.ID)
+		code.Status = repository.OAuthDeviceCodeApproved
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().PollDeviceCode(gomock.Any(), hashToken("device-code"), gomock.Any()).Return(code, nil)
+		mocks.repo.EXPECT().ConsumeDeviceCode(gomock.Any(), code.DeviceCodeHash, gomock.Any()).Return(nil, repository.ErrOAuthDeviceCodeNotFound)
+
+		_, err := oauth.Token(context.Background(), req)
+		requireOAuthError(t, err, OAuthErrInvalidGrant)
+	})
+}
@@ -0,0 +1,214 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: oauth.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOAuthUsecase is a mock of OAuthUsecase interface.
+type MockOAuthUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthUsecaseMockRecorder
+}
+
+// MockOAuthUsecaseMockRecorder is the mock recorder for MockOAuthUsecase.
+type MockOAuthUsecaseMockRecorder struct {
+	mock *MockOAuthUsecase
+}
+
+// NewMockOAuthUsecase creates a new mock instance.
+func NewMockOAuthUsecase(ctrl *gomock.Controller) *MockOAuthUsecase {
+	mock := &MockOAuthUsecase{ctrl: ctrl}
+	mock.recorder = &MockOAuthUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthUsecase) EXPECT() *MockOAuthUsecaseMockRecorder {
+	return m.recorder
+}
+
+// ApproveDevice mocks base method.
+func (m *MockOAuthUsecase) ApproveDevice(ctx context.Context, userCode string, login AccountRequest, approved bool) (*AccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveDevice", ctx, userCode, login, approved)
+	ret0, _ := ret[0].(*AccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveDevice indicates an expected call of ApproveDevice.
+func (mr *MockOAuthUsecaseMockRecorder) ApproveDevice(ctx, userCode, login, approved interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveDevice", reflect.TypeOf((*MockOAuthUsecase)(nil).ApproveDevice), ctx, userCode, login, approved)
+}
+
+// Authorize mocks base method.
+func (m *MockOAuthUsecase) Authorize(ctx context.Context, req OAuthAuthorizeRequest, login AccountRequest, approved bool) (string, *AccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", ctx, req, login, approved)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*AccountResponse)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Authorize indicates an expected call of Authorize.
+func (mr *MockOAuthUsecaseMockRecorder) Authorize(ctx, req, login, approved interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockOAuthUsecase)(nil).Authorize), ctx, req, login, approved)
+}
+
+// AuthorizeAccount mocks base method.
+func (m *MockOAuthUsecase) AuthorizeAccount(ctx context.Context, req OAuthAuthorizeRequest, username string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeAccount", ctx, req, username)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeAccount indicates an expected call of AuthorizeAccount.
+func (mr *MockOAuthUsecaseMockRecorder) AuthorizeAccount(ctx, req, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeAccount", reflect.TypeOf((*MockOAuthUsecase)(nil).AuthorizeAccount), ctx, req, username)
+}
+
+// DeleteClient mocks base method.
+func (m *MockOAuthUsecase) DeleteClient(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteClient", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteClient indicates an expected call of DeleteClient.
+func (mr *MockOAuthUsecaseMockRecorder) DeleteClient(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteClient", reflect.TypeOf((*MockOAuthUsecase)(nil).DeleteClient), ctx, id)
+}
+
+// DeviceAuthorization mocks base method.
+func (m *MockOAuthUsecase) DeviceAuthorization(ctx context.Context, req OAuthDeviceAuthorizationRequest) (*OAuthDeviceAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeviceAuthorization", ctx, req)
+	ret0, _ := ret[0].(*OAuthDeviceAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeviceAuthorization indicates an expected call of DeviceAuthorization.
+func (mr *MockOAuthUsecaseMockRecorder) DeviceAuthorization(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeviceAuthorization", reflect.TypeOf((*MockOAuthUsecase)(nil).DeviceAuthorization), ctx, req)
+}
+
+// Introspect mocks base method.
+func (m *MockOAuthUsecase) Introspect(ctx context.Context, req OAuthIntrospectRequest) (*OAuthIntrospectResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Introspect", ctx, req)
+	ret0, _ := ret[0].(*OAuthIntrospectResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Introspect indicates an expected call of Introspect.
+func (mr *MockOAuthUsecaseMockRecorder) Introspect(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Introspect", reflect.TypeOf((*MockOAuthUsecase)(nil).Introspect), ctx, req)
+}
+
+// ListClients mocks base method.
+func (m *MockOAuthUsecase) ListClients(ctx context.Context) ([]OAuthClientResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClients", ctx)
+	ret0, _ := ret[0].([]OAuthClientResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClients indicates an expected call of ListClients.
+func (mr *MockOAuthUsecaseMockRecorder) ListClients(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClients", reflect.TypeOf((*MockOAuthUsecase)(nil).ListClients), ctx)
+}
+
+// RegisterClient mocks base method.
+func (m *MockOAuthUsecase) RegisterClient(ctx context.Context, req OAuthClientRequest) (*OAuthClientResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterClient", ctx, req)
+	ret0, _ := ret[0].(*OAuthClientResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterClient indicates an expected call of RegisterClient.
+func (mr *MockOAuthUsecaseMockRecorder) RegisterClient(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterClient", reflect.TypeOf((*MockOAuthUsecase)(nil).RegisterClient), ctx, req)
+}
+
+// Revoke mocks base method.
+func (m *MockOAuthUsecase) Revoke(ctx context.Context, req OAuthRevokeRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockOAuthUsecaseMockRecorder) Revoke(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockOAuthUsecase)(nil).Revoke), ctx, req)
+}
+
+// Token mocks base method.
+func (m *MockOAuthUsecase) Token(ctx context.Context, req OAuthTokenRequest) (*OAuthTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Token", ctx, req)
+	ret0, _ := ret[0].(*OAuthTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Token indicates an expected call of Token.
+func (mr *MockOAuthUsecaseMockRecorder) Token(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*MockOAuthUsecase)(nil).Token), ctx, req)
+}
+
+// ValidateAuthorizeRequest mocks base method.
+func (m *MockOAuthUsecase) ValidateAuthorizeRequest(ctx context.Context, req OAuthAuthorizeRequest) (*OAuthConsent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAuthorizeRequest", ctx, req)
+	ret0, _ := ret[0].(*OAuthConsent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateAuthorizeRequest indicates an expected call of ValidateAuthorizeRequest.
+func (mr *MockOAuthUsecaseMockRecorder) ValidateAuthorizeRequest(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAuthorizeRequest", reflect.TypeOf((*MockOAuthUsecase)(nil).ValidateAuthorizeRequest), ctx, req)
+}
+
+// ValidateUserCode mocks base method.
+func (m *MockOAuthUsecase) ValidateUserCode(ctx context.Context, userCode string) (*OAuthConsent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateUserCode", ctx, userCode)
+	ret0, _ := ret[0].(*OAuthConsent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateUserCode indicates an expected call of ValidateUserCode.
+func (mr *MockOAuthUsecaseMockRecorder) ValidateUserCode(ctx, userCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateUserCode", reflect.TypeOf((*MockOAuthUsecase)(nil).ValidateUserCode), ctx, userCode)
+}
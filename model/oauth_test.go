@@ -0,0 +1,803 @@
+package model
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://auth.example.com"
+
+type oauthMocks struct {
+	repo        *repository.MockOAuthRepository
+	accounts    *repository.MockAccountRepository
+	usecase     *MockUsecaseHandler
+	audit       *MockAuditUsecase
+	signer      TokenSigner
+	revocations *MockRevocationList
+}
+
+func newTestOAuthUsecase(t *testing.T) (OAuthUsecase, oauthMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := oauthMocks{
+		repo:        repository.NewMockOAuthRepository(ctrl),
+		accounts:    repository.NewMockAccountRepository(ctrl),
+		usecase:     NewMockUsecaseHandler(ctrl),
+		audit:       NewMockAuditUsecase(ctrl),
+		signer:      NewTokenSigner(newTestKeySet(t)),
+		revocations: NewMockRevocationList(ctrl),
+	}
+	oauth := NewOAuthUsecase(mocks.repo, mocks.accounts, mocks.usecase, mocks.audit, mocks.signer, mocks.revocations, testIssuer)
+	return oauth, mocks
+}
+
+func newOAuthClient(t *testing.T, secret string, grantTypes string) *repository.OAuthClient {
+	client := &repository.OAuthClient{
+		ID:           util.RandomString(16),
+		Name:         "Web App",
+		RedirectURIs: "https://app.example.com/callback",
+		GrantTypes:   grantTypes,
+		Scopes:       "profile email",
+	}
+	if secret != "" {
+		hash, err := util.HashedPassword(secret)
+		require.NoError(t, err)
+		client.SecretHash = hash
+	}
+	return client
+}
+
+func newAuthorizeRequest(client *repository.OAuthClient, verifier string) OAuthAuthorizeRequest {
+	return OAuthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               "profile",
+		State:               "xyz",
+		CodeChallenge:       pkceChallenge(verifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func requireOAuthError(t *testing.T, err error, code string) {
+	oauthErr, ok := err.(*OAuthError)
+	require.True(t, ok, "expected an OAuth error, got %v", err)
+	require.Equal(t, code, oauthErr.Code)
+}
+
+func TestRegisterOAuthClient(t *testing.T) {
+	testCases := []struct {
+		name   string
+		req    OAuthClientRequest
+		verify func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error)
+		expect func(mocks oauthMocks)
+	}{
+		{
+			name: "confidential",
+			req: OAuthClientRequest{Name: "Web App", RedirectURIs: []string{"https://app.example.com/callback"},
+				GrantTypes: []string{GrantAuthorizationCode, GrantRefreshToken}, Scopes: []string{"profile"}},
+			expect: func(mocks oauthMocks) {
+				mocks.repo.EXPECT().CreateClient(gomock.Any(), gomock.Any()).Return(nil)
+				mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+			},
+			verify: func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error) {
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.ID)
+				require.NotEmpty(t, rsp.Secret)
+				require.False(t, rsp.Public)
+			},
+		},
+		{
+			name: "loopback redirect for a public client",
+			req: OAuthClientRequest{Name: "CLI", RedirectURIs: []string{"http://127.0.0.1:8400/callback"},
+				GrantTypes: []string{GrantAuthorizationCode}, Public: true},
+			expect: func(mocks oauthMocks) {
+				mocks.repo.EXPECT().CreateClient(gomock.Any(), gomock.Any()).Return(nil)
+				mocks.audit.EXPECT().Record(gomock.Any(), gomock.Any())
+			},
+			verify: func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error) {
+				require.NoError(t, err)
+				require.Empty(t, rsp.Secret)
+				require.True(t, rsp.Public)
+			},
+		},
+		{
+			name: "plain http redirect",
+			req: OAuthClientRequest{Name: "Web App", RedirectURIs: []string{"http://app.example.com/callback"},
+				GrantTypes: []string{GrantAuthorizationCode}},
+			verify: func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error) {
+				require.ErrorIs(t, err, ErrInvalidRedirectURI)
+			},
+		},
+		{
+			name: "redirect with fragment",
+			req: OAuthClientRequest{Name: "Web App", RedirectURIs: []string{"https://app.example.com/callback#x"},
+				GrantTypes: []string{GrantAuthorizationCode}},
+			verify: func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error) {
+				require.ErrorIs(t, err, ErrInvalidRedirectURI)
+			},
+		},
+		{
+			name: "missing redirect",
+			req:  OAuthClientRequest{Name: "Web App", GrantTypes: []string{GrantAuthorizationCode}},
+			verify: func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error) {
+				require.ErrorIs(t, err, ErrOAuthRedirectURIRequired)
+			},
+		},
+		{
+			name: "public client credentials",
+			req:  OAuthClientRequest{Name: "Job", GrantTypes: []string{GrantClientCredentials}, Public: true},
+			verify: func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error) {
+				require.ErrorIs(t, err, ErrPublicClientCredentials)
+			},
+		},
+		{
+			name: "implicit grant",
+			req:  OAuthClientRequest{Name: "SPA", GrantTypes: []string{"implicit"}},
+			verify: func(t *testing.T, mocks oauthMocks, rsp *OAuthClientResponse, err error) {
+				require.ErrorIs(t, err, ErrInvalidOAuthGrantType)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			oauth, mocks := newTestOAuthUsecase(t)
+			if tc.expect != nil {
+				tc.expect(mocks)
+			}
+			rsp, err := oauth.RegisterClient(context.Background(), tc.req)
+			tc.verify(t, mocks, rsp, err)
+		})
+	}
+}
+
+func TestValidateAuthorizeRequest(t *testing.T) {
+	client := newOAuthClient(t, "", GrantAuthorizationCode)
+	verifier := util.RandomString(64)
+
+	testCases := []struct {
+		name   string
+		modify func(req *OAuthAuthorizeRequest)
+		verify func(t *testing.T, consent *OAuthConsent, err error)
+	}{
+		{
+			name:   "ok",
+			modify: func(req *OAuthAuthorizeRequest) {},
+			verify: func(t *testing.T, consent *OAuthConsent, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "Web App", consent.ClientName)
+				require.Equal(t, []string{"profile"}, consent.Scopes)
+			},
+		},
+		{
+			name:   "unregistered redirect",
+			modify: func(req *OAuthAuthorizeRequest) { req.RedirectURI = "https://evil.example.com/callback" },
+			verify: func(t *testing.T, consent *OAuthConsent, err error) {
+				require.ErrorIs(t, err, ErrInvalidRedirectURI)
+			},
+		},
+		{
+			name:   "missing PKCE",
+			modify: func(req *OAuthAuthorizeRequest) { req.CodeChallenge = "" },
+			verify: func(t *testing.T, consent *OAuthConsent, err error) {
+				requireOAuthError(t, err, OAuthErrInvalidRequest)
+			},
+		},
+		{
+			name:   "plain PKCE",
+			modify: func(req *OAuthAuthorizeRequest) { req.CodeChallengeMethod = "plain" },
+			verify: func(t *testing.T, consent *OAuthConsent, err error) {
+				requireOAuthError(t, err, OAuthErrInvalidRequest)
+			},
+		},
+		{
+			name:   "scope not allowed",
+			modify: func(req *OAuthAuthorizeRequest) { req.Scope = "profile admin" },
+			verify: func(t *testing.T, consent *OAuthConsent, err error) {
+				requireOAuthError(t, err, OAuthErrInvalidScope)
+			},
+		},
+		{
+			name:   "token response type",
+			modify: func(req *OAuthAuthorizeRequest) { req.ResponseType = "token" },
+			verify: func(t *testing.T, consent *OAuthConsent, err error) {
+				requireOAuthError(t, err, OAuthErrUnsupportedResponseType)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			oauth, mocks := newTestOAuthUsecase(t)
+			mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+			req := newAuthorizeRequest(client, verifier)
+			tc.modify(&req)
+			consent, err := oauth.ValidateAuthorizeRequest(context.Background(), req)
+			tc.verify(t, consent, err)
+		})
+	}
+}
+
+func TestValidateAuthorizeRequestUnknownClient(t *testing.T) {
+	oauth, mocks := newTestOAuthUsecase(t)
+	mocks.repo.EXPECT().GetClient(gomock.Any(), "missing").Return(nil, repository.ErrOAuthClientNotFound)
+
+	_, err := oauth.ValidateAuthorizeRequest(context.Background(), OAuthAuthorizeRequest{ClientID: "missing"})
+	require.ErrorIs(t, err, ErrInvalidOAuthClient)
+}
+
+func TestAuthorize(t *testing.T) {
+	client := newOAuthClient(t, "", GrantAuthorizationCode)
+	verifier := util.RandomString(64)
+	login := AccountRequest{Username: "alice", Password: util.RandomPassword(8)}
+
+	t.Run("ok", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		account := &repository.Account{ID: uuid.New(), Username: "alice"}
+		req := newAuthorizeRequest(client, verifier)
+		req.Nonce = "n-0S6_WzA2Mj"
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), login).Return(&AccountResponse{Success: true}, nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil)
+		var stored *repository.OAuthAuthorizationCode
+		mocks.repo.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, code *repository.OAuthAuthorizationCode) error {
+				stored = code
+				return nil
+			})
+
+		redirectURL, _, err := oauth.Authorize(context.Background(), req, login, true)
+		require.NoError(t, err)
+		redirect, err := url.Parse(redirectURL)
+		require.NoError(t, err)
+		require.Equal(t, "app.example.com", redirect.Host)
+		require.Equal(t, "xyz", redirect.Query().Get("state"))
+		require.Equal(t, hashToken(redirect.Query().Get("code")), stored.CodeHash)
+		require.Equal(t, account.ID, stored.AccountID)
+		require.Equal(t, req.CodeChallenge, stored.CodeChallenge)
+		require.Equal(t, "profile", stored.Scope)
+		require.Equal(t, req.Nonce, stored.Nonce)
+		require.WithinDuration(t, time.Now(), stored.AuthTime, time.Minute)
+	})
+
+	t.Run("denied by user", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		redirectURL, _, err := oauth.Authorize(context.Background(), newAuthorizeRequest(client, verifier), AccountRequest{}, false)
+		require.NoError(t, err)
+		redirect, err := url.Parse(redirectURL)
+		require.NoError(t, err)
+		require.Equal(t, OAuthErrAccessDenied, redirect.Query().Get("error"))
+		require.Equal(t, "xyz", redirect.Query().Get("state"))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(&repository.Account{ID: uuid.New(), Username: "alice"}, nil)
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), login).
+			Return(&AccountResponse{Reason: ErrLoginWrongPassword.Error()}, ErrLoginWrongPassword)
+		mocks.repo.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+
+		redirectURL, rsp, err := oauth.Authorize(context.Background(), newAuthorizeRequest(client, verifier), login, true)
+		require.ErrorIs(t, err, ErrLoginWrongPassword)
+		require.Empty(t, redirectURL)
+		require.Equal(t, ErrLoginWrongPassword.Error(), rsp.Reason)
+	})
+
+	t.Run("first directory login", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		account := &repository.Account{ID: uuid.New(), Username: "bob", Source: repository.AccountSourceLDAP}
+		login := AccountRequest{Username: "Bob", Password: util.RandomPassword(8)}
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		gomock.InOrder(
+			mocks.accounts.EXPECT().GetAccount(gomock.Any(), "Bob").Return(nil, repository.ErrAccountRecordNotFound),
+			mocks.usecase.EXPECT().LoginAccount(gomock.Any(), login).Return(&AccountResponse{Success: true, Username: "bob"}, nil),
+			mocks.accounts.EXPECT().GetAccount(gomock.Any(), "bob").Return(account, nil),
+		)
+		mocks.repo.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).Return(nil)
+
+		redirectURL, _, err := oauth.Authorize(context.Background(), newAuthorizeRequest(client, verifier), login, true)
+		require.NoError(t, err)
+		require.NotEmpty(t, redirectURL)
+	})
+
+	t.Run("voucher", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		login := AccountRequest{Username: "abcde-23456", Password: "abcde-23456"}
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "abcde-23456").Return(nil, repository.ErrAccountRecordNotFound)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "ABCDE23456").
+			Return(&repository.Account{ID: uuid.New(), Username: "ABCDE23456", Type: repository.AccountTypeVoucher}, nil)
+		// The voucher is not redeemed, its validity does not start.
+		mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+		mocks.repo.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+
+		redirectURL, rsp, err := oauth.Authorize(context.Background(), newAuthorizeRequest(client, verifier), login, true)
+		require.Equal(t, ErrVoucherNoSelfService, err)
+		require.Empty(t, redirectURL)
+		require.False(t, rsp.Success)
+		require.Equal(t, ErrVoucherNoSelfService.Error(), rsp.Reason)
+	})
+}
+
+func TestAuthorizeAccount(t *testing.T) {
+	client := newOAuthClient(t, "", GrantAuthorizationCode)
+	oauth, mocks := newTestOAuthUsecase(t)
+	account := &repository.Account{ID: uuid.New(), Username: "jdoe"}
+	mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+	mocks.usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+	mocks.accounts.EXPECT().GetAccount(gomock.Any(), "jdoe").Return(account, nil)
+	var stored *repository.OAuthAuthorizationCode
+	mocks.repo.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, code *repository.OAuthAuthorizationCode) error {
+			stored = code
+			return nil
+		})
+
+	redirectURL, err := oauth.AuthorizeAccount(context.Background(), newAuthorizeRequest(client, util.RandomString(64)), "jdoe")
+	require.NoError(t, err)
+	redirect, err := url.Parse(redirectURL)
+	require.NoError(t, err)
+	require.Equal(t, hashToken(redirect.Query().Get("code")), stored.CodeHash)
+	require.Equal(t, account.ID, stored.AccountID)
+}
+
+func TestAuthorizeAccountLocked(t *testing.T) {
+	client := newOAuthClient(t, "", GrantAuthorizationCode)
+	oauth, mocks := newTestOAuthUsecase(t)
+	lockedAt := time.Now()
+	mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+	mocks.accounts.EXPECT().GetAccount(gomock.Any(), "jdoe").Return(&repository.Account{ID: uuid.New(), Username: "jdoe", LockedAt: &lockedAt}, nil)
+	mocks.repo.EXPECT().CreateAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := oauth.AuthorizeAccount(context.Background(), newAuthorizeRequest(client, util.RandomString(64)), "jdoe")
+	requireOAuthError(t, err, OAuthErrAccessDenied)
+}
+
+func TestTokenAuthorizationCode(t *testing.T) {
+	secret := util.RandomString(32)
+	client := newOAuthClient(t, secret, GrantAuthorizationCode+" "+GrantRefreshToken)
+	verifier := util.RandomString(64)
+	accountID := uuid.New()
+	newCode := func() *repository.OAuthAuthorizationCode {
+		return &repository.OAuthAuthorizationCode{
+			CodeHash:            hashToken("code"),
+			ClientID:            client.ID,
+			AccountID:           accountID,
+			Username:            "alice",
+			RedirectURI:         "https://app.example.com/callback",
+			Scope:               "profile",
+			CodeChallenge:       pkceChallenge(verifier),
+			CodeChallengeMethod: "S256",
+			ExpiresAt:           time.Now().Add(time.Minute),
+		}
+	}
+	newRequest := func() OAuthTokenRequest {
+		return OAuthTokenRequest{
+			GrantType:    GrantAuthorizationCode,
+			Code:         "code",
+			RedirectURI:  "https://app.example.com/callback",
+			CodeVerifier: verifier,
+			ClientID:     client.ID,
+			ClientSecret: secret,
+		}
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashToken("code"), gomock.Any()).Return(newCode(), nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+		mocks.repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, token *repository.OAuthRefreshToken) error {
+				require.Equal(t, accountID, token.AccountID)
+				require.Equal(t, "profile", token.Scope)
+				return nil
+			})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenIssued, AuditOutcomeSuccess))
+
+		rsp, err := oauth.Token(context.Background(), newRequest())
+		require.NoError(t, err)
+		require.Equal(t, "Bearer", rsp.TokenType)
+		require.NotEmpty(t, rsp.RefreshToken)
+		require.Equal(t, "profile", rsp.Scope)
+
+		claims := &AccessTokenClaims{}
+		require.NoError(t, mocks.signer.Verify(rsp.AccessToken, claims))
+		require.Equal(t, accountID.String(), claims.Subject)
+		require.Equal(t, testIssuer, claims.Issuer)
+		require.Equal(t, client.ID, claims.ClientID)
+		require.Equal(t, "alice", claims.Username)
+		require.Empty(t, rsp.IDToken)
+	})
+
+	t.Run("openid", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		code := newCode()
+		code.Scope = "openid profile"
+		code.Nonce = "n-0S6_WzA2Mj"
+		code.AuthTime = time.Now().Add(-time.Minute).Truncate(time.Second)
+		account := &repository.Account{ID: accountID, Username: "alice"}
+		account.UpdatedAt = time.Now().Add(-time.Hour).Truncate(time.Second)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeAuthorizationCode(gomock.Any(), hashToken("code"), gomock.Any()).Return(code, nil)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil)
+		mocks.repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, token *repository.OAuthRefreshToken) error {
+				require.Equal(t, code.AuthTime, token.AuthTime)
+				return nil
+			})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenIssued, AuditOutcomeSuccess))
+
+		rsp, err := oauth.Token(context.Background(), newRequest())
+		require.NoError(t, err)
+		require.NotEmpty(t, rsp.IDToken)
+
+		claims := &IDTokenClaims{}
+		require.NoError(t, mocks.signer.Verify(rsp.IDToken, claims))
+		require.Equal(t, testIssuer, claims.Issuer)
+		require.Equal(t, accountID.String(), claims.Subject)
+		require.Equal(t, []string{client.ID}, []string(claims.Audience))
+		require.Equal(t, code.Nonce, claims.Nonce)
+		require.Equal(t, code.AuthTime.Unix(), claims.AuthTime.Unix())
+		require.Equal(t, "alice", claims.PreferredUsername)
+		require.Equal(t, account.UpdatedAt.Unix(), claims.UpdatedAt)
+	})
+
+	failures := []struct {
+		name   string
+		modify func(req *OAuthTokenRequest, code *repository.OAuthAuthorizationCode)
+		code   string
+	}{
+		{
+			name: "wrong verifier",
+			modify: func(req *OAuthTokenRequest, code *repository.OAuthAuthorizationCode) {
+				req.CodeVerifier = util.RandomString(64)
+			},
+			code: OAuthErrInvalidGrant,
+		},
+		{
+			name:   "missing verifier",
+			modify: func(req *OAuthTokenRequest, code *repository.OAuthAuthorizationCode) { req.CodeVerifier = "" },
+			code:   OAuthErrInvalidGrant,
+		},
+		{
+			name: "redirect mismatch",
+			modify: func(req *OAuthTokenRequest, code *repository.OAuthAuthorizationCode) {
+				req.RedirectURI = "https://app.example.com/other"
+			},
+			code: OAuthErrInvalidGrant,
+		},
+		{
+			name: "expired",
+			modify: func(req *OAuthTokenRequest, code *repository.OAuthAuthorizationCode) {
+				code.ExpiresAt = time.Now().Add(-time.Second)
+			},
+			code: OAuthErrInvalidGrant,
+		},
+		{
+			name:   "other client",
+			modify: func(req *OAuthTokenRequest, code *repository.OAuthAuthorizationCode) { code.ClientID = "other" },
+			code:   OAuthErrInvalidGrant,
+		},
+	}
+	for i := range failures {
+		tc := failures[i]
+		t.Run(tc.name, func(t *testing.T) {
+			oauth, mocks := newTestOAuthUsecase(t)
+			req, code := newRequest(), newCode()
+			tc.modify(&req, code)
+			mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+			mocks.repo.EXPECT().ConsumeAuthorizationCode(gomock.Any(), gomock.Any(), gomock.Any()).Return(code, nil)
+
+			_, err := oauth.Token(context.Background(), req)
+			requireOAuthError(t, err, tc.code)
+		})
+	}
+
+	t.Run("code already used", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeAuthorizationCode(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, repository.ErrOAuthAuthorizationCodeNotFound)
+
+		_, err := oauth.Token(context.Background(), newRequest())
+		requireOAuthError(t, err, OAuthErrInvalidGrant)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		req := newRequest()
+		req.ClientSecret = "wrong"
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeAuthorizationCode(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := oauth.Token(context.Background(), req)
+		requireOAuthError(t, err, OAuthErrInvalidClient)
+	})
+}
+
+func TestTokenRefresh(t *testing.T) {
+	client := newOAuthClient(t, "", GrantAuthorizationCode+" "+GrantRefreshToken)
+	accountID := uuid.New()
+	newToken := func() *repository.OAuthRefreshToken {
+		return &repository.OAuthRefreshToken{
+			TokenHash: hashToken("refresh"),
+			ClientID:  client.ID,
+			AccountID: accountID,
+			Username:  "alice",
+			Scope:     "profile email",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("rotated with narrower scope", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeRefreshToken(gomock.Any(), hashToken("refresh"), gomock.Any()).Return(newToken(), nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), accountID).Return(&repository.Account{ID: accountID, Username: "alice"}, nil)
+		mocks.repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenIssued, AuditOutcomeSuccess))
+
+		rsp, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantRefreshToken, RefreshToken: "refresh", Scope: "email", ClientID: client.ID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "email", rsp.Scope)
+		require.NotEqual(t, "refresh", rsp.RefreshToken)
+	})
+
+	t.Run("wider scope", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		token := newToken()
+		token.Scope = "profile"
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeRefreshToken(gomock.Any(), gomock.Any(), gomock.Any()).Return(token, nil)
+
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantRefreshToken, RefreshToken: "refresh", Scope: "profile email", ClientID: client.ID,
+		})
+		requireOAuthError(t, err, OAuthErrInvalidScope)
+	})
+
+	t.Run("account locked", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		lockedAt := time.Now()
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeRefreshToken(gomock.Any(), gomock.Any(), gomock.Any()).Return(newToken(), nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), accountID).
+			Return(&repository.Account{ID: accountID, Username: "alice", LockedAt: &lockedAt}, nil)
+		mocks.repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantRefreshToken, RefreshToken: "refresh", ClientID: client.ID,
+		})
+		requireOAuthError(t, err, OAuthErrInvalidGrant)
+	})
+
+	t.Run("already used", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().ConsumeRefreshToken(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, repository.ErrOAuthRefreshTokenNotFound)
+
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantRefreshToken, RefreshToken: "refresh", ClientID: client.ID,
+		})
+		requireOAuthError(t, err, OAuthErrInvalidGrant)
+	})
+}
+
+func TestTokenClientCredentials(t *testing.T) {
+	secret := util.RandomString(32)
+	client := newOAuthClient(t, secret, GrantClientCredentials)
+
+	t.Run("ok", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenIssued, AuditOutcomeSuccess))
+
+		rsp, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantClientCredentials, ClientID: client.ID, ClientSecret: secret,
+		})
+		require.NoError(t, err)
+		require.Empty(t, rsp.RefreshToken)
+		require.Equal(t, "profile email", rsp.Scope)
+
+		claims := &AccessTokenClaims{}
+		require.NoError(t, mocks.signer.Verify(rsp.AccessToken, claims))
+		require.Equal(t, client.ID, claims.Subject)
+	})
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{
+			GrantType: GrantRefreshToken, RefreshToken: "refresh", ClientID: client.ID, ClientSecret: secret,
+		})
+		requireOAuthError(t, err, OAuthErrUnauthorizedClient)
+	})
+
+	t.Run("unsupported grant", func(t *testing.T) {
+		oauth, _ := newTestOAuthUsecase(t)
+		_, err := oauth.Token(context.Background(), OAuthTokenRequest{GrantType: "password", ClientID: client.ID})
+		requireOAuthError(t, err, OAuthErrUnsupportedGrantType)
+	})
+}
+
+func newTestAccessToken(t *testing.T, signer TokenSigner, clientID string) (string, *AccessTokenClaims) {
+	claims := &AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   uuid.New().String(),
+			Audience:  jwt.ClaimStrings{clientID},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+		},
+		ClientID: clientID,
+		Username: "alice",
+		Scope:    "profile",
+	}
+	token, err := signer.Sign(claims)
+	require.NoError(t, err)
+	return token, claims
+}
+
+func TestIntrospect(t *testing.T) {
+	secret := util.RandomString(32)
+	client := newOAuthClient(t, secret, GrantClientCredentials)
+
+	t.Run("active access token", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		token, claims := newTestAccessToken(t, mocks.signer, "web")
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.revocations.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(false, nil)
+
+		rsp, err := oauth.Introspect(context.Background(), OAuthIntrospectRequest{
+			Token: token, ClientID: client.ID, ClientSecret: secret,
+		})
+		require.NoError(t, err)
+		require.True(t, rsp.Active)
+		require.Equal(t, "web", rsp.ClientID)
+		require.Equal(t, "alice", rsp.Username)
+		require.Equal(t, "profile", rsp.Scope)
+		require.Equal(t, claims.ID, rsp.JTI)
+		require.Equal(t, claims.ExpiresAt.Unix(), rsp.ExpiresAt)
+	})
+
+	t.Run("revoked access token", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		token, claims := newTestAccessToken(t, mocks.signer, "web")
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.revocations.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(true, nil)
+		mocks.repo.EXPECT().GetRefreshToken(gomock.Any(), hashToken(token)).Return(nil, repository.ErrOAuthRefreshTokenNotFound)
+
+		rsp, err := oauth.Introspect(context.Background(), OAuthIntrospectRequest{
+			Token: token, ClientID: client.ID, ClientSecret: secret,
+		})
+		require.NoError(t, err)
+		require.Equal(t, &OAuthIntrospectResponse{Active: false}, rsp)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		refreshToken := &repository.OAuthRefreshToken{
+			TokenHash: hashToken("refresh"),
+			ClientID:  client.ID,
+			AccountID: uuid.New(),
+			Username:  "alice",
+			Scope:     "profile",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().GetRefreshToken(gomock.Any(), hashToken("refresh")).Return(refreshToken, nil)
+
+		rsp, err := oauth.Introspect(context.Background(), OAuthIntrospectRequest{
+			Token: "refresh", TokenTypeHint: TokenTypeHintRefreshToken, ClientID: client.ID, ClientSecret: secret,
+		})
+		require.NoError(t, err)
+		require.True(t, rsp.Active)
+		require.Equal(t, TokenTypeHintRefreshToken, rsp.TokenType)
+		require.Equal(t, refreshToken.AccountID.String(), rsp.Subject)
+	})
+
+	t.Run("refresh token of another client", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().GetRefreshToken(gomock.Any(), hashToken("refresh")).Return(&repository.OAuthRefreshToken{
+			ClientID:  "other",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}, nil)
+
+		rsp, err := oauth.Introspect(context.Background(), OAuthIntrospectRequest{
+			Token: "refresh", TokenTypeHint: TokenTypeHintRefreshToken, ClientID: client.ID, ClientSecret: secret,
+		})
+		require.NoError(t, err)
+		require.False(t, rsp.Active)
+	})
+
+	t.Run("public client", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		public := newOAuthClient(t, "", GrantAuthorizationCode)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), public.ID).Return(public, nil)
+
+		_, err := oauth.Introspect(context.Background(), OAuthIntrospectRequest{Token: "token", ClientID: public.ID})
+		requireOAuthError(t, err, OAuthErrInvalidClient)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+
+		_, err := oauth.Introspect(context.Background(), OAuthIntrospectRequest{
+			Token: "token", ClientID: client.ID, ClientSecret: "wrong",
+		})
+		requireOAuthError(t, err, OAuthErrInvalidClient)
+	})
+}
+
+func TestRevoke(t *testing.T) {
+	client := newOAuthClient(t, "", GrantAuthorizationCode+" "+GrantRefreshToken)
+
+	t.Run("access token", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		token, claims := newTestAccessToken(t, mocks.signer, client.ID)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.revocations.EXPECT().Revoke(gomock.Any(), claims.ID, client.ID, claims.ExpiresAt.Time).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenRevoked, AuditOutcomeSuccess))
+
+		err := oauth.Revoke(context.Background(), OAuthRevokeRequest{Token: token, ClientID: client.ID})
+		require.NoError(t, err)
+	})
+
+	t.Run("access token of another client", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		token, _ := newTestAccessToken(t, mocks.signer, "other")
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.revocations.EXPECT().Revoke(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		err := oauth.Revoke(context.Background(), OAuthRevokeRequest{Token: token, ClientID: client.ID})
+		requireOAuthError(t, err, OAuthErrUnauthorizedClient)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().RevokeRefreshToken(gomock.Any(), hashToken("refresh"), client.ID, gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditOAuthTokenRevoked, AuditOutcomeSuccess))
+
+		err := oauth.Revoke(context.Background(), OAuthRevokeRequest{
+			Token: "refresh", TokenTypeHint: TokenTypeHintRefreshToken, ClientID: client.ID,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		oauth, mocks := newTestOAuthUsecase(t)
+		mocks.repo.EXPECT().GetClient(gomock.Any(), client.ID).Return(client, nil)
+		mocks.repo.EXPECT().RevokeRefreshToken(gomock.Any(), hashToken("unknown"), client.ID, gomock.Any()).
+			Return(repository.ErrOAuthRefreshTokenNotFound)
+		mocks.audit.EXPECT().Record(gomock.Any(), gomock.Any()).Times(0)
+
+		err := oauth.Revoke(context.Background(), OAuthRevokeRequest{Token: "unknown", ClientID: client.ID})
+		require.NoError(t, err)
+	})
+}
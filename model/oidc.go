@@ -0,0 +1,99 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+)
+
+var ErrInsufficientScope = errors.New("Insufficient scope")
+
+// OIDCUsecase serves the OpenID Connect endpoints next to the OAuth 2.0 authorization server.
+type OIDCUsecase interface {
+	Discovery() OIDCDiscovery
+	JWKS() *JSONWebKeySet
+	UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error)
+}
+
+type oidcUsecase struct {
+	accounts    repository.AccountRepository
+	signer      TokenSigner
+	keys        KeySet
+	revocations RevocationList
+	issuer      string
+}
+
+func NewOIDCUsecase(accounts repository.AccountRepository, signer TokenSigner, keys KeySet, revocations RevocationList, issuer string) OIDCUsecase {
+	return &oidcUsecase{
+		accounts:    accounts,
+		signer:      signer,
+		keys:        keys,
+		revocations: revocations,
+		issuer:      issuer,
+	}
+}
+
+func (o *oidcUsecase) Discovery() OIDCDiscovery {
+	var algs []string
+	for _, key := range o.keys.Keys() {
+		if !containsString(algs, key.Algorithm) {
+			algs = append(algs, key.Algorithm)
+		}
+	}
+	return OIDCDiscovery{
+		Issuer:                            o.issuer,
+		AuthorizationEndpoint:             o.issuer + "/oauth/authorize",
+		TokenEndpoint:                     o.issuer + "/oauth/token",
+		UserInfoEndpoint:                  o.issuer + "/userinfo",
+		JWKSURI:                           o.issuer + "/jwks.json",
+		IntrospectionEndpoint:             o.issuer + "/oauth/introspect",
+		RevocationEndpoint:                o.issuer + "/oauth/revoke",
+		DeviceAuthorizationEndpoint:       o.issuer + "/oauth/device/code",
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile},
+		ResponseTypesSupported:            []string{oauthResponseTypeCode},
+		GrantTypesSupported:               supportedGrantTypes,
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "private_key_jwt", "none"},
+		CodeChallengeMethodsSupported:     []string{pkceMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "updated_at"},
+	}
+}
+
+// JWKS publishes the current, next and retired keys so tokens verify across a rotation.
+func (o *oidcUsecase) JWKS() *JSONWebKeySet {
+	return NewJSONWebKeySet(o.keys.Keys())
+}
+
+// UserInfo returns the claims about the account an access token with the openid scope was issued for.
+func (o *oidcUsecase) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	claims, err := verifyAccessToken(ctx, o.signer, o.revocations, o.issuer, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Username == "" {
+		return nil, ErrInvalidToken
+	}
+	scopes := strings.Fields(claims.Scope)
+	if !containsString(scopes, ScopeOpenID) {
+		return nil, ErrInsufficientScope
+	}
+	account, err := o.accounts.GetAccount(ctx, claims.Username)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	info := map[string]interface{}{
+		"sub": account.ID.String(),
+	}
+	if containsString(scopes, ScopeProfile) {
+		info["preferred_username"] = account.Username
+		info["updated_at"] = account.UpdatedAt.Unix()
+	}
+	return info, nil
+}
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: oidc.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOIDCUsecase is a mock of OIDCUsecase interface.
+type MockOIDCUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCUsecaseMockRecorder
+}
+
+// MockOIDCUsecaseMockRecorder is the mock recorder for MockOIDCUsecase.
+type MockOIDCUsecaseMockRecorder struct {
+	mock *MockOIDCUsecase
+}
+
+// NewMockOIDCUsecase creates a new mock instance.
+func NewMockOIDCUsecase(ctrl *gomock.Controller) *MockOIDCUsecase {
+	mock := &MockOIDCUsecase{ctrl: ctrl}
+	mock.recorder = &MockOIDCUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCUsecase) EXPECT() *MockOIDCUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Discovery mocks base method.
+func (m *MockOIDCUsecase) Discovery() OIDCDiscovery {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discovery")
+	ret0, _ := ret[0].(OIDCDiscovery)
+	return ret0
+}
+
+// Discovery indicates an expected call of Discovery.
+func (mr *MockOIDCUsecaseMockRecorder) Discovery() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discovery", reflect.TypeOf((*MockOIDCUsecase)(nil).Discovery))
+}
+
+// JWKS mocks base method.
+func (m *MockOIDCUsecase) JWKS() *JSONWebKeySet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(*JSONWebKeySet)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockOIDCUsecaseMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockOIDCUsecase)(nil).JWKS))
+}
+
+// UserInfo mocks base method.
+func (m *MockOIDCUsecase) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserInfo", ctx, accessToken)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserInfo indicates an expected call of UserInfo.
+func (mr *MockOIDCUsecaseMockRecorder) UserInfo(ctx, accessToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserInfo", reflect.TypeOf((*MockOIDCUsecase)(nil).UserInfo), ctx, accessToken)
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCDiscovery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	keys := newTestKeySet(t)
+	oidc := NewOIDCUsecase(repository.NewMockAccountRepository(ctrl), NewTokenSigner(keys), keys, NewMockRevocationList(ctrl), testIssuer)
+
+	discovery := oidc.Discovery()
+	require.Equal(t, testIssuer, discovery.Issuer)
+	require.Equal(t, testIssuer+"/jwks.json", discovery.JWKSURI)
+	require.Equal(t, testIssuer+"/userinfo", discovery.UserInfoEndpoint)
+	require.Equal(t, testIssuer+"/oauth/revoke", discovery.RevocationEndpoint)
+	require.Equal(t, []string{"EdDSA"}, discovery.IDTokenSigningAlgValuesSupported)
+	require.Contains(t, discovery.ScopesSupported, ScopeOpenID)
+
+	jwks := oidc.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	current, err := keys.Current()
+	require.NoError(t, err)
+	require.Equal(t, current.ID, jwks.Keys[0].Kid)
+}
+
+func TestUserInfo(t *testing.T) {
+	account := &repository.Account{ID: uuid.New(), Username: "alice"}
+	account.UpdatedAt = time.Now().Truncate(time.Second)
+	newClaims := func(scope string) AccessTokenClaims {
+		return AccessTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    testIssuer,
+				Subject:   account.ID.String(),
+				ID:        uuid.New().String(),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+			ClientID: "web",
+			Username: "alice",
+			Scope:    scope,
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		claims  AccessTokenClaims
+		lookups int
+		revoked bool
+		err     error
+		verify  func(t *testing.T, info map[string]interface{})
+	}{
+		{
+			name:    "profile",
+			claims:  newClaims("openid profile"),
+			lookups: 1,
+			verify: func(t *testing.T, info map[string]interface{}) {
+				require.Equal(t, account.ID.String(), info["sub"])
+				require.Equal(t, "alice", info["preferred_username"])
+				require.Equal(t, account.UpdatedAt.Unix(), info["updated_at"])
+			},
+		},
+		{
+			name:    "openid only",
+			claims:  newClaims("openid"),
+			lookups: 1,
+			verify: func(t *testing.T, info map[string]interface{}) {
+				require.Equal(t, map[string]interface{}{"sub": account.ID.String()}, info)
+			},
+		},
+		{
+			name:   "no openid scope",
+			claims: newClaims("profile"),
+			err:    ErrInsufficientScope,
+		},
+		{
+			name:    "revoked",
+			claims:  newClaims("openid"),
+			revoked: true,
+			err:     ErrInvalidToken,
+		},
+		{
+			name: "other issuer",
+			claims: func() AccessTokenClaims {
+				claims := newClaims("openid")
+				claims.Issuer = "https://evil.example.com"
+				return claims
+			}(),
+			err: ErrInvalidToken,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			keys := newTestKeySet(t)
+			signer := NewTokenSigner(keys)
+			revocations := NewMockRevocationList(ctrl)
+			oidc := NewOIDCUsecase(accounts, signer, keys, revocations, testIssuer)
+			revocations.EXPECT().IsRevoked(gomock.Any(), gomock.Any()).Return(tc.revoked, nil).AnyTimes()
+			accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil).Times(tc.lookups)
+
+			token, err := signer.Sign(tc.claims)
+			require.NoError(t, err)
+			info, err := oidc.UserInfo(context.Background(), token)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			tc.verify(t, info)
+		})
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		keys := newTestKeySet(t)
+		oidc := NewOIDCUsecase(repository.NewMockAccountRepository(ctrl), NewTokenSigner(keys), keys, NewMockRevocationList(ctrl), testIssuer)
+		_, err := oidc.UserInfo(context.Background(), "not-a-token")
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
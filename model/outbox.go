@@ -0,0 +1,179 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	outboxBatchSize           = 100
+	outboxLease               = time.Minute
+	outboxMaxAttempts         = 10
+	outboxInitialBackoff      = time.Second
+	outboxMaxBackoff          = 10 * time.Minute
+	defaultOutboxPollInterval = time.Second
+)
+
+// OutboxMessage is what a sink receives from the relay. The same message can be sent more
+// than once, so sinks pass IdempotencyKey on for consumers to drop duplicates.
+type OutboxMessage struct {
+	IdempotencyKey string
+	Type           string
+	Payload        []byte
+	Attempt        int
+	LastAttempt    bool
+}
+
+type OutboxSink interface {
+	Name() string
+	Send(ctx context.Context, message OutboxMessage) error
+}
+
+type OutboxRelay interface {
+	RelayBatch(ctx context.Context) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// NewOutboxMessage turns the event into an outbox row, keyed by the event ID.
+func NewOutboxMessage(event Event) (*repository.OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.OutboxMessage{
+		IdempotencyKey: event.ID,
+		Type:           event.Type,
+		Payload:        string(payload),
+	}, nil
+}
+
+type outboxPublisher struct {
+	repo repository.OutboxRepository
+}
+
+// NewOutboxPublisher publishes events by writing them to the outbox, for events that are not
+// part of an account write.
+func NewOutboxPublisher(repo repository.OutboxRepository) EventPublisher {
+	return &outboxPublisher{
+		repo: repo,
+	}
+}
+
+func (p *outboxPublisher) Publish(ctx context.Context, event Event) {
+	message, err := NewOutboxMessage(event)
+	if err == nil {
+		err = p.repo.CreateOutboxMessage(ctx, message)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("event", event.Type).Str("event_id", event.ID).Msg("failed to write event to outbox")
+	}
+}
+
+type outboxRelay struct {
+	repo  repository.OutboxRepository
+	sinks []OutboxSink
+}
+
+func NewOutboxRelay(repo repository.OutboxRepository, sinks ...OutboxSink) OutboxRelay {
+	return &outboxRelay{
+		repo:  repo,
+		sinks: sinks,
+	}
+}
+
+// Run relays due messages every interval until ctx is done. A full batch is followed by the next
+// one straight away.
+func (r *outboxRelay) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultOutboxPollInterval
+	}
+	for {
+		relayed, err := r.RelayBatch(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to relay outbox")
+		}
+		if err == nil && relayed == outboxBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RelayBatch claims a batch of due messages and sends each to every sink it has not reached yet.
+func (r *outboxRelay) RelayBatch(ctx context.Context) (int, error) {
+	messages, err := r.repo.ClaimOutboxMessages(ctx, outboxBatchSize, outboxLease)
+	if err != nil {
+		return 0, err
+	}
+	for i := range messages {
+		r.relay(ctx, &messages[i])
+	}
+	return len(messages), nil
+}
+
+func (r *outboxRelay) relay(ctx context.Context, message *repository.OutboxMessage) {
+	message.Attempts++
+	delivered := make(map[string]bool)
+	for _, name := range strings.Split(message.DeliveredSinks, ",") {
+		delivered[name] = name != ""
+	}
+	msg := OutboxMessage{
+		IdempotencyKey: message.IdempotencyKey,
+		Type:           message.Type,
+		Payload:        []byte(message.Payload),
+		Attempt:        message.Attempts,
+		LastAttempt:    message.Attempts >= outboxMaxAttempts,
+	}
+
+	var deliveredSinks, failures []string
+	for _, sink := range r.sinks {
+		if !delivered[sink.Name()] {
+			if err := sink.Send(ctx, msg); err != nil {
+				failures = append(failures, sink.Name()+": "+err.Error())
+				continue
+			}
+		}
+		deliveredSinks = append(deliveredSinks, sink.Name())
+	}
+	message.DeliveredSinks = strings.Join(deliveredSinks, ",")
+
+	now := time.Now()
+	if len(failures) == 0 {
+		message.LastError = ""
+		message.PublishedAt = &now
+	} else {
+		message.LastError = strings.Join(failures, "; ")
+		if msg.LastAttempt {
+			message.FailedAt = &now
+			log.Error().Str("event_id", message.IdempotencyKey).Str("err", message.LastError).
+				Msg("giving up on outbox message")
+		} else {
+			message.NextAttemptAt = now.Add(outboxBackoff(message.Attempts))
+		}
+	}
+
+	if err := r.repo.UpdateOutboxMessage(ctx, message); err != nil {
+		log.Error().Err(err).Str("event_id", message.IdempotencyKey).Msg("failed to update outbox message")
+	}
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxInitialBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff
+}
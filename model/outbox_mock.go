@@ -0,0 +1,114 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: outbox.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOutboxSink is a mock of OutboxSink interface.
+type MockOutboxSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxSinkMockRecorder
+}
+
+// MockOutboxSinkMockRecorder is the mock recorder for MockOutboxSink.
+type MockOutboxSinkMockRecorder struct {
+	mock *MockOutboxSink
+}
+
+// NewMockOutboxSink creates a new mock instance.
+func NewMockOutboxSink(ctrl *gomock.Controller) *MockOutboxSink {
+	mock := &MockOutboxSink{ctrl: ctrl}
+	mock.recorder = &MockOutboxSinkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxSink) EXPECT() *MockOutboxSinkMockRecorder {
+	return m.recorder
+}
+
+// Name mocks base method.
+func (m *MockOutboxSink) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockOutboxSinkMockRecorder) Name() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockOutboxSink)(nil).Name))
+}
+
+// Send mocks base method.
+func (m *MockOutboxSink) Send(ctx context.Context, message OutboxMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockOutboxSinkMockRecorder) Send(ctx, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockOutboxSink)(nil).Send), ctx, message)
+}
+
+// MockOutboxRelay is a mock of OutboxRelay interface.
+type MockOutboxRelay struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxRelayMockRecorder
+}
+
+// MockOutboxRelayMockRecorder is the mock recorder for MockOutboxRelay.
+type MockOutboxRelayMockRecorder struct {
+	mock *MockOutboxRelay
+}
+
+// NewMockOutboxRelay creates a new mock instance.
+func NewMockOutboxRelay(ctrl *gomock.Controller) *MockOutboxRelay {
+	mock := &MockOutboxRelay{ctrl: ctrl}
+	mock.recorder = &MockOutboxRelayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxRelay) EXPECT() *MockOutboxRelayMockRecorder {
+	return m.recorder
+}
+
+// RelayBatch mocks base method.
+func (m *MockOutboxRelay) RelayBatch(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RelayBatch", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RelayBatch indicates an expected call of RelayBatch.
+func (mr *MockOutboxRelayMockRecorder) RelayBatch(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RelayBatch", reflect.TypeOf((*MockOutboxRelay)(nil).RelayBatch), ctx)
+}
+
+// Run mocks base method.
+func (m *MockOutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx, interval)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockOutboxRelayMockRecorder) Run(ctx, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockOutboxRelay)(nil).Run), ctx, interval)
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	natsSinkName             = "nats"
+	fileSinkName             = "file"
+	defaultNATSSubjectPrefix = "accounts"
+)
+
+var natsFlushTimeout = 5 * time.Second
+
+type natsSink struct {
+	conn          *nats.Conn
+	subjectPrefix string
+}
+
+// NewNATSSink publishes every message on "<subjectPrefix>.<event type>" with the idempotency key as
+// Nats-Msg-Id, so a JetStream stream on those subjects drops duplicates.
+func NewNATSSink(conn *nats.Conn, subjectPrefix string) OutboxSink {
+	if subjectPrefix == "" {
+		subjectPrefix = defaultNATSSubjectPrefix
+	}
+	return &natsSink{
+		conn:          conn,
+		subjectPrefix: subjectPrefix,
+	}
+}
+
+func (s *natsSink) Name() string {
+	return natsSinkName
+}
+
+func (s *natsSink) Send(ctx context.Context, message OutboxMessage) error {
+	msg := nats.NewMsg(s.subjectPrefix + "." + message.Type)
+	msg.Header.Set(nats.MsgIdHdr, message.IdempotencyKey)
+	msg.Data = message.Payload
+	if err := s.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// Core NATS publishing is fire and forget, flushing makes sure the server has the message.
+	return s.conn.FlushTimeout(natsFlushTimeout)
+}
+
+type fileSinkRecord struct {
+	IdempotencyKey string          `json:"idempotency_key"`
+	Type           string          `json:"type"`
+	Event          json.RawMessage `json:"event"`
+}
+
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSink appends every message to the file at path as one JSON document per line.
+func NewFileSink(path string) OutboxSink {
+	return &fileSink{
+		path: path,
+	}
+}
+
+func (s *fileSink) Name() string {
+	return fileSinkName
+}
+
+func (s *fileSink) Send(ctx context.Context, message OutboxMessage) error {
+	line, err := json.Marshal(fileSinkRecord{
+		IdempotencyKey: message.IdempotencyKey,
+		Type:           message.Type,
+		Event:          json.RawMessage(message.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The file is reopened on every message so it can be rotated underneath us.
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSSink(t *testing.T) {
+	server := test.RunRandClientPortServer()
+	defer server.Shutdown()
+	conn, err := nats.Connect(server.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sub, err := conn.SubscribeSync("accounts.>")
+	require.NoError(t, err)
+
+	message := newOutboxMessage(t, EventAccountLocked)
+	require.NoError(t, NewNATSSink(conn, "").Send(context.Background(), message))
+
+	received, err := sub.NextMsg(time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "accounts."+EventAccountLocked, received.Subject)
+	require.Equal(t, message.IdempotencyKey, received.Header.Get(nats.MsgIdHdr))
+	require.Equal(t, message.Payload, received.Data)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := NewFileSink(path)
+	messages := []OutboxMessage{newOutboxMessage(t, EventAccountCreated), newOutboxMessage(t, EventAccountDeleted)}
+	for _, message := range messages {
+		require.NoError(t, sink.Send(context.Background(), message))
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for _, message := range messages {
+		require.True(t, scanner.Scan())
+		record := fileSinkRecord{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		require.Equal(t, message.IdempotencyKey, record.IdempotencyKey)
+		require.Equal(t, message.Type, record.Type)
+		require.JSONEq(t, string(message.Payload), string(record.Event))
+	}
+	require.False(t, scanner.Scan())
+}
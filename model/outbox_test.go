@@ -0,0 +1,134 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockSink(ctrl *gomock.Controller, name string) *MockOutboxSink {
+	sink := NewMockOutboxSink(ctrl)
+	sink.EXPECT().Name().Return(name).AnyTimes()
+	return sink
+}
+
+func TestOutboxRelayBatch(t *testing.T) {
+	testCases := []struct {
+		name             string
+		message          repository.OutboxMessage
+		setMockExpection func(webhook, nats *MockOutboxSink)
+		checkMessage     func(t *testing.T, message *repository.OutboxMessage)
+	}{
+		{
+			name:    "delivered",
+			message: repository.OutboxMessage{ID: 1, IdempotencyKey: "event-1", Type: EventAccountCreated},
+			setMockExpection: func(webhook, nats *MockOutboxSink) {
+				webhook.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+				nats.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			checkMessage: func(t *testing.T, message *repository.OutboxMessage) {
+				require.Equal(t, 1, message.Attempts)
+				require.Equal(t, "webhook,nats", message.DeliveredSinks)
+				require.NotNil(t, message.PublishedAt)
+				require.Empty(t, message.LastError)
+			},
+		},
+		{
+			name:    "one sink failed",
+			message: repository.OutboxMessage{ID: 1, IdempotencyKey: "event-1", Type: EventAccountCreated},
+			setMockExpection: func(webhook, nats *MockOutboxSink) {
+				webhook.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+				nats.EXPECT().Send(gomock.Any(), gomock.Any()).Return(errors.New("nats: timeout"))
+			},
+			checkMessage: func(t *testing.T, message *repository.OutboxMessage) {
+				require.Equal(t, "webhook", message.DeliveredSinks)
+				require.Nil(t, message.PublishedAt)
+				require.Nil(t, message.FailedAt)
+				require.Contains(t, message.LastError, "nats: timeout")
+				require.WithinDuration(t, time.Now().Add(outboxInitialBackoff), message.NextAttemptAt, time.Second)
+			},
+		},
+		{
+			name: "retry only undelivered sinks",
+			message: repository.OutboxMessage{ID: 1, IdempotencyKey: "event-1", Type: EventAccountCreated,
+				DeliveredSinks: "webhook", Attempts: 1},
+			setMockExpection: func(webhook, nats *MockOutboxSink) {
+				webhook.EXPECT().Send(gomock.Any(), gomock.Any()).Times(0)
+				nats.EXPECT().Send(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, message OutboxMessage) error {
+						require.Equal(t, 2, message.Attempt)
+						require.False(t, message.LastAttempt)
+						return nil
+					})
+			},
+			checkMessage: func(t *testing.T, message *repository.OutboxMessage) {
+				require.Equal(t, "webhook,nats", message.DeliveredSinks)
+				require.NotNil(t, message.PublishedAt)
+			},
+		},
+		{
+			name: "last attempt failed",
+			message: repository.OutboxMessage{ID: 1, IdempotencyKey: "event-1", Type: EventAccountCreated,
+				DeliveredSinks: "webhook", Attempts: outboxMaxAttempts - 1},
+			setMockExpection: func(webhook, nats *MockOutboxSink) {
+				nats.EXPECT().Send(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, message OutboxMessage) error {
+						require.True(t, message.LastAttempt)
+						return errors.New("nats: no responders")
+					})
+			},
+			checkMessage: func(t *testing.T, message *repository.OutboxMessage) {
+				require.Equal(t, outboxMaxAttempts, message.Attempts)
+				require.NotNil(t, message.FailedAt)
+				require.Nil(t, message.PublishedAt)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := repository.NewMockOutboxRepository(ctrl)
+			webhook := newMockSink(ctrl, "webhook")
+			nats := newMockSink(ctrl, "nats")
+			tc.setMockExpection(webhook, nats)
+
+			mockRepo.EXPECT().ClaimOutboxMessages(gomock.Any(), outboxBatchSize, outboxLease).
+				Return([]repository.OutboxMessage{tc.message}, nil)
+			mockRepo.EXPECT().UpdateOutboxMessage(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, message *repository.OutboxMessage) error {
+					tc.checkMessage(t, message)
+					return nil
+				})
+
+			relayed, err := NewOutboxRelay(mockRepo, webhook, nats).RelayBatch(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, relayed)
+		})
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	require.Equal(t, outboxInitialBackoff, outboxBackoff(1))
+	require.Equal(t, 4*outboxInitialBackoff, outboxBackoff(3))
+	require.Equal(t, outboxMaxBackoff, outboxBackoff(outboxMaxAttempts*10))
+}
+
+func TestOutboxPublisher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockOutboxRepository(ctrl)
+	event := Event{ID: "event-1", Type: EventAccountLoginFailed}
+	mockRepo.EXPECT().CreateOutboxMessage(gomock.Any(), outboxMessageOf(EventAccountLoginFailed)).
+		DoAndReturn(func(ctx context.Context, message *repository.OutboxMessage) error {
+			require.Equal(t, event.ID, message.IdempotencyKey)
+			return nil
+		})
+
+	NewOutboxPublisher(mockRepo).Publish(context.Background(), event)
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, so they are told apart from access
+// tokens and easy to spot in leaked logs or source code.
+const PersonalAccessTokenPrefix = "pat_"
+
+// ScopeTokens lets a token list, create and revoke the personal access tokens of its account.
+const ScopeTokens = "tokens"
+
+// PersonalAccessTokenScopes are the scopes a personal access token may be created with. Scripts may
+// register devices, but login methods and browser sessions are left to the account owner, so a leaked
+// token cannot link a way in or sign the owner out.
+var PersonalAccessTokenScopes = []string{ScopeProfile, ScopeTokens, ScopeDevices}
+
+var (
+	personalAccessTokenBytes       = 32
+	defaultPersonalAccessTokenDays = 30
+	// personalAccessTokenTouchInterval limits how often a busy token writes its last-used time.
+	personalAccessTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidTokenScope           = errors.New("Invalid token scope")
+	ErrInvalidTokenID              = errors.New("Invalid token id")
+	ErrPersonalAccessTokenNotFound = errors.New("Personal access token not found")
+)
+
+// PersonalAccessTokenUsecase manages the personal access tokens accounts use for scripts and CI.
+type PersonalAccessTokenUsecase interface {
+	// CreateToken returns the token itself, which is not shown again. A token cannot get scopes the
+	// caller does not have.
+	CreateToken(ctx context.Context, principal *Principal, req PersonalAccessTokenRequest) (*PersonalAccessTokenResponse, error)
+	ListTokens(ctx context.Context, principal *Principal) ([]PersonalAccessTokenResponse, error)
+	RevokeToken(ctx context.Context, principal *Principal, id string) error
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type personalAccessTokenUsecase struct {
+	repo     repository.PersonalAccessTokenRepository
+	accounts repository.AccountRepository
+	audit    AuditUsecase
+}
+
+func NewPersonalAccessTokenUsecase(repo repository.PersonalAccessTokenRepository, accounts repository.AccountRepository,
+	audit AuditUsecase) PersonalAccessTokenUsecase {
+	return &personalAccessTokenUsecase{
+		repo:     repo,
+		accounts: accounts,
+		audit:    audit,
+	}
+}
+
+func (p *personalAccessTokenUsecase) CreateToken(ctx context.Context, principal *Principal, req PersonalAccessTokenRequest) (*PersonalAccessTokenResponse, error) {
+	for _, scope := range req.Scopes {
+		if !containsString(PersonalAccessTokenScopes, scope) || !principal.HasScope(scope) {
+			return nil, ErrInvalidTokenScope
+		}
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultPersonalAccessTokenDays
+	}
+
+	secret, err := util.RandomToken(personalAccessTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	token := PersonalAccessTokenPrefix + secret
+	now := time.Now()
+	record := &repository.PersonalAccessToken{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		AccountID: principal.AccountID,
+		Name:      req.Name,
+		TokenHash: hashToken(token),
+		Scopes:    strings.Join(req.Scopes, " "),
+		ExpiresAt: now.AddDate(0, 0, days),
+	}
+	if err := p.repo.CreatePersonalAccessToken(ctx, record); err != nil {
+		return nil, err
+	}
+
+	p.audit.Record(ctx, AuditEntry{
+		Type:    AuditTokenCreated,
+		Target:  principal.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  record.ID.String(),
+	})
+	rsp := personalAccessTokenResponse(record)
+	rsp.Token = token
+	return &rsp, nil
+}
+
+func (p *personalAccessTokenUsecase) ListTokens(ctx context.Context, principal *Principal) ([]PersonalAccessTokenResponse, error) {
+	tokens, err := p.repo.ListPersonalAccessTokens(ctx, principal.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]PersonalAccessTokenResponse, 0, len(tokens))
+	for i := range tokens {
+		rsp = append(rsp, personalAccessTokenResponse(&tokens[i]))
+	}
+	return rsp, nil
+}
+
+func (p *personalAccessTokenUsecase) RevokeToken(ctx context.Context, principal *Principal, id string) error {
+	tokenID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidTokenID
+	}
+	if err := p.repo.RevokePersonalAccessToken(ctx, principal.AccountID, tokenID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+			return ErrPersonalAccessTokenNotFound
+		}
+		return err
+	}
+
+	p.audit.Record(ctx, AuditEntry{
+		Type:    AuditTokenRevoked,
+		Target:  principal.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  id,
+	})
+	return nil
+}
+
+// Authenticate accepts an unexpired, unrevoked token of an account that still exists.
+func (p *personalAccessTokenUsecase) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	record, err := p.repo.GetPersonalAccessToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if record.RevokedAt != nil || now.After(record.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	account, err := p.accounts.GetAccountByID(ctx, record.AccountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	// Locking an account ends what it signed in to without waiting for it to expire.
+	if account.LockedAt != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= personalAccessTokenTouchInterval {
+		if err := p.repo.TouchPersonalAccessToken(ctx, record.ID, now); err != nil {
+			log.Error().Err(err).Str("token_id", record.ID.String()).Msg("failed to record token use")
+		}
+	}
+	return &Principal{
+		AccountID: account.ID,
+		Username:  account.Username,
+		Scopes:    strings.Fields(record.Scopes),
+		TokenID:   record.ID.String(),
+	}, nil
+}
+
+func personalAccessTokenResponse(token *repository.PersonalAccessToken) PersonalAccessTokenResponse {
+	return PersonalAccessTokenResponse{
+		ID:         token.ID.String(),
+		Name:       token.Name,
+		Scopes:     strings.Fields(token.Scopes),
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
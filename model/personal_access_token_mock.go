@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: personal_access_token.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPersonalAccessTokenUsecase is a mock of PersonalAccessTokenUsecase interface.
+type MockPersonalAccessTokenUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockPersonalAccessTokenUsecaseMockRecorder
+}
+
+// MockPersonalAccessTokenUsecaseMockRecorder is the mock recorder for MockPersonalAccessTokenUsecase.
+type MockPersonalAccessTokenUsecaseMockRecorder struct {
+	mock *MockPersonalAccessTokenUsecase
+}
+
+// NewMockPersonalAccessTokenUsecase creates a new mock instance.
+func NewMockPersonalAccessTokenUsecase(ctrl *gomock.Controller) *MockPersonalAccessTokenUsecase {
+	mock := &MockPersonalAccessTokenUsecase{ctrl: ctrl}
+	mock.recorder = &MockPersonalAccessTokenUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPersonalAccessTokenUsecase) EXPECT() *MockPersonalAccessTokenUsecaseMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockPersonalAccessTokenUsecase) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, token)
+	ret0, _ := ret[0].(*Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockPersonalAccessTokenUsecaseMockRecorder) Authenticate(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockPersonalAccessTokenUsecase)(nil).Authenticate), ctx, token)
+}
+
+// CreateToken mocks base method.
+func (m *MockPersonalAccessTokenUsecase) CreateToken(ctx context.Context, principal *Principal, req PersonalAccessTokenRequest) (*PersonalAccessTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, principal, req)
+	ret0, _ := ret[0].(*PersonalAccessTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateToken indicates an expected call of CreateToken.
+func (mr *MockPersonalAccessTokenUsecaseMockRecorder) CreateToken(ctx, principal, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockPersonalAccessTokenUsecase)(nil).CreateToken), ctx, principal, req)
+}
+
+// ListTokens mocks base method.
+func (m *MockPersonalAccessTokenUsecase) ListTokens(ctx context.Context, principal *Principal) ([]PersonalAccessTokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTokens", ctx, principal)
+	ret0, _ := ret[0].([]PersonalAccessTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTokens indicates an expected call of ListTokens.
+func (mr *MockPersonalAccessTokenUsecaseMockRecorder) ListTokens(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTokens", reflect.TypeOf((*MockPersonalAccessTokenUsecase)(nil).ListTokens), ctx, principal)
+}
+
+// RevokeToken mocks base method.
+func (m *MockPersonalAccessTokenUsecase) RevokeToken(ctx context.Context, principal *Principal, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", ctx, principal, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockPersonalAccessTokenUsecaseMockRecorder) RevokeToken(ctx, principal, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockPersonalAccessTokenUsecase)(nil).RevokeToken), ctx, principal, id)
+}
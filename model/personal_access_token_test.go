@@ -0,0 +1,260 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type personalAccessTokenMocks struct {
+	repo     *repository.MockPersonalAccessTokenRepository
+	accounts *repository.MockAccountRepository
+	audit    *MockAuditUsecase
+}
+
+func newTestPersonalAccessTokenUsecase(t *testing.T) (PersonalAccessTokenUsecase, personalAccessTokenMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := personalAccessTokenMocks{
+		repo:     repository.NewMockPersonalAccessTokenRepository(ctrl),
+		accounts: repository.NewMockAccountRepository(ctrl),
+		audit:    NewMockAuditUsecase(ctrl),
+	}
+	return NewPersonalAccessTokenUsecase(mocks.repo, mocks.accounts, mocks.audit), mocks
+}
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	principal := &Principal{AccountID: uuid.New(), Username: "alice", Scopes: []string{ScopeProfile, ScopeTokens}}
+
+	t.Run("ok", func(t *testing.T) {
+		tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+		var stored *repository.PersonalAccessToken
+		mocks.repo.EXPECT().CreatePersonalAccessToken(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, token *repository.PersonalAccessToken) error {
+				stored = token
+				return nil
+			})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditTokenCreated, AuditOutcomeSuccess))
+
+		rsp, err := tokens.CreateToken(context.Background(), principal, PersonalAccessTokenRequest{
+			Name: "ci", Scopes: []string{ScopeProfile},
+		})
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(rsp.Token, PersonalAccessTokenPrefix))
+		require.Equal(t, hashToken(rsp.Token), stored.TokenHash)
+		require.NotContains(t, stored.TokenHash, rsp.Token)
+		require.Equal(t, principal.AccountID, stored.AccountID)
+		require.Equal(t, ScopeProfile, stored.Scopes)
+		require.WithinDuration(t, time.Now().AddDate(0, 0, defaultPersonalAccessTokenDays), rsp.ExpiresAt, time.Minute)
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+		mocks.repo.EXPECT().CreatePersonalAccessToken(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := tokens.CreateToken(context.Background(), principal, PersonalAccessTokenRequest{
+			Name: "ci", Scopes: []string{"admin"},
+		})
+		require.ErrorIs(t, err, ErrInvalidTokenScope)
+	})
+
+	t.Run("scope not for tokens", func(t *testing.T) {
+		tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+		mocks.repo.EXPECT().CreatePersonalAccessToken(gomock.Any(), gomock.Any()).Times(0)
+		owner := &Principal{AccountID: principal.AccountID, Username: "alice", Scopes: BrowserSessionScopes}
+
+		_, err := tokens.CreateToken(context.Background(), owner, PersonalAccessTokenRequest{
+			Name: "ci", Scopes: []string{ScopeIdentities},
+		})
+		require.ErrorIs(t, err, ErrInvalidTokenScope)
+	})
+
+	t.Run("devices", func(t *testing.T) {
+		tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+		mocks.repo.EXPECT().CreatePersonalAccessToken(gomock.Any(), gomock.Any())
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditTokenCreated, AuditOutcomeSuccess))
+		owner := &Principal{AccountID: principal.AccountID, Username: "alice", Scopes: BrowserSessionScopes}
+
+		_, err := tokens.CreateToken(context.Background(), owner, PersonalAccessTokenRequest{
+			Name: "printers", Scopes: []string{ScopeDevices},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("scope the caller lacks", func(t *testing.T) {
+		tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+		mocks.repo.EXPECT().CreatePersonalAccessToken(gomock.Any(), gomock.Any()).Times(0)
+		narrow := &Principal{AccountID: principal.AccountID, Username: "alice", Scopes: []string{ScopeTokens}}
+
+		_, err := tokens.CreateToken(context.Background(), narrow, PersonalAccessTokenRequest{
+			Name: "ci", Scopes: []string{ScopeProfile},
+		})
+		require.ErrorIs(t, err, ErrInvalidTokenScope)
+	})
+}
+
+func TestRevokePersonalAccessToken(t *testing.T) {
+	principal := &Principal{AccountID: uuid.New(), Username: "alice"}
+	id := uuid.New()
+
+	testCases := []struct {
+		name    string
+		id      string
+		repoErr error
+		err     error
+	}{
+		{name: "ok", id: id.String()},
+		{name: "not found", id: id.String(), repoErr: repository.ErrPersonalAccessTokenNotFound, err: ErrPersonalAccessTokenNotFound},
+		{name: "invalid id", id: "abc", err: ErrInvalidTokenID},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+			if tc.err != ErrInvalidTokenID {
+				mocks.repo.EXPECT().RevokePersonalAccessToken(gomock.Any(), principal.AccountID, id, gomock.Any()).Return(tc.repoErr)
+			}
+			if tc.err == nil {
+				mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditTokenRevoked, AuditOutcomeSuccess))
+			}
+
+			err := tokens.RevokeToken(context.Background(), principal, tc.id)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAuthenticatePersonalAccessToken(t *testing.T) {
+	account := &repository.Account{ID: uuid.New(), Username: "alice"}
+	recentlyUsed := time.Now().Add(-time.Second)
+	newRecord := func() *repository.PersonalAccessToken {
+		return &repository.PersonalAccessToken{
+			ID:        uuid.New(),
+			AccountID: account.ID,
+			TokenHash: hashToken("pat_abc"),
+			Scopes:    "profile tokens",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		record  func() *repository.PersonalAccessToken
+		repoErr error
+		lookup  bool
+		touch   bool
+		err     error
+	}{
+		{name: "ok", record: newRecord, lookup: true, touch: true},
+		{
+			name: "recently used",
+			record: func() *repository.PersonalAccessToken {
+				record := newRecord()
+				record.LastUsedAt = &recentlyUsed
+				return record
+			},
+			lookup: true,
+		},
+		{name: "unknown", repoErr: repository.ErrPersonalAccessTokenNotFound, err: ErrInvalidToken},
+		{
+			name: "expired",
+			record: func() *repository.PersonalAccessToken {
+				record := newRecord()
+				record.ExpiresAt = time.Now().Add(-time.Second)
+				return record
+			},
+			err: ErrInvalidToken,
+		},
+		{
+			name: "revoked",
+			record: func() *repository.PersonalAccessToken {
+				record := newRecord()
+				record.RevokedAt = &recentlyUsed
+				return record
+			},
+			err: ErrInvalidToken,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+			var record *repository.PersonalAccessToken
+			if tc.record != nil {
+				record = tc.record()
+			}
+			mocks.repo.EXPECT().GetPersonalAccessToken(gomock.Any(), hashToken("pat_abc")).Return(record, tc.repoErr)
+			if tc.lookup {
+				mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(account, nil)
+			}
+			if tc.touch {
+				mocks.repo.EXPECT().TouchPersonalAccessToken(gomock.Any(), record.ID, gomock.Any()).Return(nil)
+			}
+
+			principal, err := tokens.Authenticate(context.Background(), "pat_abc")
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, account.ID, principal.AccountID)
+			require.Equal(t, "alice", principal.Username)
+			require.True(t, principal.HasScope(ScopeTokens))
+		})
+	}
+
+	t.Run("deleted account", func(t *testing.T) {
+		tokens, mocks := newTestPersonalAccessTokenUsecase(t)
+		mocks.repo.EXPECT().GetPersonalAccessToken(gomock.Any(), gomock.Any()).Return(newRecord(), nil)
+		mocks.accounts.EXPECT().GetAccountByID(gomock.Any(), account.ID).Return(nil, repository.ErrAccountRecordNotFound)
+
+		_, err := tokens.Authenticate(context.Background(), "pat_abc")
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestAuthenticator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	keys := newTestKeySet(t)
+	signer := NewTokenSigner(keys)
+	revocations := NewMockRevocationList(ctrl)
+	tokens := NewMockPersonalAccessTokenUsecase(ctrl)
+	auth := NewAuthenticator(signer, revocations, testIssuer, tokens)
+
+	t.Run("personal access token", func(t *testing.T) {
+		principal := &Principal{Username: "alice"}
+		tokens.EXPECT().Authenticate(gomock.Any(), "pat_abc").Return(principal, nil)
+
+		got, err := auth.Authenticate(context.Background(), "pat_abc")
+		require.NoError(t, err)
+		require.Equal(t, principal, got)
+	})
+
+	t.Run("access token", func(t *testing.T) {
+		token, claims := newTestAccessToken(t, signer, "web")
+		revocations.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(false, nil)
+
+		principal, err := auth.Authenticate(context.Background(), token)
+		require.NoError(t, err)
+		require.Equal(t, claims.Subject, principal.AccountID.String())
+		require.Equal(t, "alice", principal.Username)
+		require.Equal(t, []string{"profile"}, principal.Scopes)
+	})
+
+	t.Run("revoked access token", func(t *testing.T) {
+		token, claims := newTestAccessToken(t, signer, "web")
+		revocations.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(true, nil)
+
+		_, err := auth.Authenticate(context.Background(), token)
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
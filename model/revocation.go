@@ -0,0 +1,124 @@
+package model
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	revocationFilterSize        = 100000
+	revocationFalsePositiveRate = 0.001
+	// revocationSyncSkew re-reads revocations a little older than the last sync, so that ones another
+	// replica committed late, or with a clock slightly behind, are not missed.
+	revocationSyncSkew = 5 * time.Second
+)
+
+// RevocationList knows which access tokens were revoked before they expired. Lookups go to a bloom
+// filter first, so only revoked tokens and the rare false positive reach the database. Revocations
+// made by other replicas show up after the next Sync.
+type RevocationList interface {
+	Revoke(ctx context.Context, jti string, clientID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// Sync loads the revocations made since the last sync. Now and then it rebuilds the filter from
+	// scratch, so expired tokens fall out of it.
+	Sync(ctx context.Context) error
+	// Run syncs every interval until ctx is done; interval bounds how long a revocation made on another
+	// replica takes to apply here.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type revocationList struct {
+	repo repository.OAuthRepository
+
+	mu        sync.RWMutex
+	filter    *util.BloomFilter
+	syncedAt  time.Time
+	rebuiltAt time.Time
+}
+
+func NewRevocationList(repo repository.OAuthRepository) RevocationList {
+	return &revocationList{
+		repo: repo,
+	}
+}
+
+func (l *revocationList) Revoke(ctx context.Context, jti string, clientID string, expiresAt time.Time) error {
+	err := l.repo.RevokeAccessToken(ctx, &repository.OAuthRevokedToken{
+		JTI:       jti,
+		ClientID:  clientID,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.filter != nil {
+		l.filter.Add(jti)
+	}
+	return nil
+}
+
+func (l *revocationList) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	l.mu.RLock()
+	filter := l.filter
+	l.mu.RUnlock()
+	// Before the first sync there is no filter to rule anything out.
+	if filter != nil && !filter.MayContain(jti) {
+		return false, nil
+	}
+	return l.repo.IsAccessTokenRevoked(ctx, jti)
+}
+
+func (l *revocationList) Sync(ctx context.Context) error {
+	now := time.Now()
+	l.mu.RLock()
+	rebuild := l.filter == nil || now.Sub(l.rebuiltAt) > oauthAccessTokenTTL
+	since := l.syncedAt.Add(-revocationSyncSkew)
+	l.mu.RUnlock()
+
+	if rebuild {
+		if err := l.repo.PurgeRevokedAccessTokens(ctx, now); err != nil {
+			return err
+		}
+		since = time.Time{}
+	}
+	tokens, err := l.repo.ListRevokedAccessTokens(ctx, since, now)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rebuild {
+		l.filter = util.NewBloomFilter(revocationFilterSize, revocationFalsePositiveRate)
+		l.rebuiltAt = now
+	}
+	for i := range tokens {
+		l.filter.Add(tokens[i].JTI)
+	}
+	l.syncedAt = now
+	return nil
+}
+
+func (l *revocationList) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Sync(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to sync revoked tokens")
+			}
+		}
+	}
+}
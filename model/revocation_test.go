@@ -0,0 +1,67 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockOAuthRepository(ctrl)
+	list := NewRevocationList(repo)
+	ctx := context.Background()
+
+	// Before the first sync every lookup goes to the database.
+	repo.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti-1").Return(true, nil)
+	revoked, err := list.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	repo.EXPECT().PurgeRevokedAccessTokens(gomock.Any(), gomock.Any()).Return(nil)
+	repo.EXPECT().ListRevokedAccessTokens(gomock.Any(), time.Time{}, gomock.Any()).
+		Return([]repository.OAuthRevokedToken{{JTI: "jti-1"}}, nil)
+	require.NoError(t, list.Sync(ctx))
+
+	// The filter rules out tokens that were never revoked.
+	repo.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti-2").Times(0)
+	revoked, err = list.IsRevoked(ctx, "jti-2")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	repo.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti-1").Return(true, nil)
+	revoked, err = list.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// A local revocation applies at once.
+	expiresAt := time.Now().Add(time.Minute)
+	repo.EXPECT().RevokeAccessToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, token *repository.OAuthRevokedToken) error {
+			require.Equal(t, "jti-2", token.JTI)
+			require.Equal(t, "web", token.ClientID)
+			require.Equal(t, expiresAt, token.ExpiresAt)
+			return nil
+		})
+	require.NoError(t, list.Revoke(ctx, "jti-2", "web", expiresAt))
+	repo.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti-2").Return(true, nil)
+	revoked, err = list.IsRevoked(ctx, "jti-2")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// One made by another replica applies after the next sync, which only reads recent revocations.
+	repo.EXPECT().ListRevokedAccessTokens(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, since time.Time, now time.Time) ([]repository.OAuthRevokedToken, error) {
+			require.False(t, since.IsZero())
+			return []repository.OAuthRevokedToken{{JTI: "jti-3"}}, nil
+		})
+	require.NoError(t, list.Sync(ctx))
+	repo.EXPECT().IsAccessTokenRevoked(gomock.Any(), "jti-3").Return(true, nil)
+	revoked, err = list.IsRevoked(ctx, "jti-3")
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
@@ -0,0 +1,351 @@
+package model
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/google/uuid"
+)
+
+// serviceAccountGrantTypes are the grants a service account client authenticates with.
+var serviceAccountGrantTypes = []string{GrantClientCredentials, GrantJWTBearer}
+
+var (
+	ErrServiceAccountNotFound     = errors.New("Service account not found")
+	ErrInvalidServiceAccountOwner = errors.New("Owner must be an existing user account")
+	ErrInvalidServiceAccountKey   = errors.New("Invalid service account key")
+	ErrServiceAccountKeyExists    = errors.New("Service account key is already registered")
+	ErrServiceAccountKeyNotFound  = errors.New("Service account key not found")
+)
+
+// ServiceAccountUsecase manages service accounts: accounts for machines that cannot log in with a
+// password and get tokens from the token endpoint instead, with their client secret or with a JWT
+// assertion signed by one of their keys.
+type ServiceAccountUsecase interface {
+	// CreateServiceAccount returns the client secret, which is not shown again.
+	CreateServiceAccount(ctx context.Context, req ServiceAccountRequest) (*ServiceAccountResponse, error)
+	ListServiceAccounts(ctx context.Context) ([]ServiceAccountResponse, error)
+	DeleteServiceAccount(ctx context.Context, username string) error
+	// RotateSecret replaces the client secret. The old secret stops working at once.
+	RotateSecret(ctx context.Context, username string) (*ServiceAccountResponse, error)
+	// AddKey registers a public key for JWT assertions. A generated private key is only returned here.
+	AddKey(ctx context.Context, username string, req ServiceAccountKeyRequest) (*ServiceAccountKeyResponse, error)
+	RevokeKey(ctx context.Context, username string, id string) error
+}
+
+type serviceAccountUsecase struct {
+	repo     repository.ServiceAccountRepository
+	oauth    repository.OAuthRepository
+	accounts repository.AccountRepository
+	audit    AuditUsecase
+}
+
+func NewServiceAccountUsecase(repo repository.ServiceAccountRepository, oauth repository.OAuthRepository,
+	accounts repository.AccountRepository, audit AuditUsecase) ServiceAccountUsecase {
+	return &serviceAccountUsecase{
+		repo:     repo,
+		oauth:    oauth,
+		accounts: accounts,
+		audit:    audit,
+	}
+}
+
+func (s *serviceAccountUsecase) CreateServiceAccount(ctx context.Context, req ServiceAccountRequest) (*ServiceAccountResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	owner, err := s.accounts.GetAccount(ctx, req.Owner)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountRecordNotFound) {
+			return nil, ErrInvalidServiceAccountOwner
+		}
+		return nil, err
+	}
+	if owner.Type == repository.AccountTypeService {
+		return nil, ErrInvalidServiceAccountOwner
+	}
+
+	account := &repository.Account{
+		ID:          uuid.New(),
+		Username:    req.Username,
+		Type:        repository.AccountTypeService,
+		OwnerID:     &owner.ID,
+		Description: req.Description,
+	}
+	clientID, err := util.RandomToken(oauthClientIDBytes)
+	if err != nil {
+		return nil, err
+	}
+	secret, secretHash, err := newClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	client := &repository.OAuthClient{
+		ID:         clientID,
+		Name:       "service account " + req.Username,
+		SecretHash: secretHash,
+		GrantTypes: strings.Join(serviceAccountGrantTypes, " "),
+		Scopes:     strings.Join(req.Scopes, " "),
+		AccountID:  &account.ID,
+	}
+	message, err := NewOutboxMessage(NewAccountEvent(EventAccountCreated, account, ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateServiceAccount(ctx, account, client, message); err != nil {
+		if errors.Is(err, repository.ErrAccountIsDuplicated) {
+			return nil, ErrAccountIsAlreadyExisted
+		}
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "create service account owned by " + owner.Username,
+	})
+
+	rsp := serviceAccountResponse(account, owner.Username, client, nil)
+	rsp.ClientSecret = secret
+	return &rsp, nil
+}
+
+func (s *serviceAccountUsecase) ListServiceAccounts(ctx context.Context) ([]ServiceAccountResponse, error) {
+	accounts, err := s.repo.ListServiceAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]ServiceAccountResponse, 0, len(accounts))
+	for i := range accounts {
+		client, err := s.repo.GetServiceAccountClient(ctx, &accounts[i])
+		if err != nil {
+			return nil, err
+		}
+		keys, err := s.oauth.ListClientKeys(ctx, client.ID)
+		if err != nil {
+			return nil, err
+		}
+		rsp = append(rsp, serviceAccountResponse(&accounts[i], s.ownerName(ctx, &accounts[i]), client, keys))
+	}
+	return rsp, nil
+}
+
+func (s *serviceAccountUsecase) DeleteServiceAccount(ctx context.Context, username string) error {
+	account, err := s.getServiceAccount(ctx, username)
+	if err != nil {
+		return err
+	}
+	message, err := NewOutboxMessage(NewAccountEvent(EventAccountDeleted, account, ""))
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteServiceAccount(ctx, account, message); err != nil {
+		if errors.Is(err, repository.ErrServiceAccountNotFound) {
+			return ErrServiceAccountNotFound
+		}
+		return err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "delete service account",
+	})
+	return nil
+}
+
+func (s *serviceAccountUsecase) RotateSecret(ctx context.Context, username string) (*ServiceAccountResponse, error) {
+	account, client, err := s.getServiceAccountClient(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	secret, secretHash, err := newClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.oauth.UpdateClientSecret(ctx, client.ID, secretHash); err != nil {
+		return nil, err
+	}
+	keys, err := s.oauth.ListClientKeys(ctx, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "rotate service account secret",
+	})
+
+	rsp := serviceAccountResponse(account, s.ownerName(ctx, account), client, keys)
+	rsp.ClientSecret = secret
+	return &rsp, nil
+}
+
+func (s *serviceAccountUsecase) AddKey(ctx context.Context, username string, req ServiceAccountKeyRequest) (*ServiceAccountKeyResponse, error) {
+	_, client, err := s.getServiceAccountClient(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey crypto.PublicKey
+	var privateKey string
+	if req.PublicKey != "" {
+		if publicKey, err = util.ParsePublicKey(req.PublicKey); err != nil {
+			return nil, ErrInvalidServiceAccountKey
+		}
+	} else {
+		algorithm := req.Algorithm
+		if algorithm == "" {
+			algorithm = KeyAlgorithmES256
+		}
+		signer, err := GenerateSigningKey(algorithm)
+		if err != nil {
+			return nil, ErrInvalidServiceAccountKey
+		}
+		if privateKey, err = util.EncodePrivateKey(signer); err != nil {
+			return nil, err
+		}
+		publicKey = signer.Public()
+	}
+	jwk, err := publicJWK(publicKey)
+	if err != nil {
+		return nil, ErrInvalidServiceAccountKey
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, ErrInvalidServiceAccountKey
+	}
+
+	key := &repository.OAuthClientKey{
+		ID:        jwk.Kid,
+		ClientID:  client.ID,
+		Algorithm: jwk.Alg,
+		PublicKey: der,
+		CreatedAt: time.Now(),
+	}
+	if err := s.oauth.CreateClientKey(ctx, key); err != nil {
+		if errors.Is(err, repository.ErrOAuthClientKeyDuplicated) {
+			return nil, ErrServiceAccountKeyExists
+		}
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "add service account key " + key.ID,
+	})
+
+	rsp := serviceAccountKeyResponse(key)
+	rsp.PrivateKey = privateKey
+	return &rsp, nil
+}
+
+func (s *serviceAccountUsecase) RevokeKey(ctx context.Context, username string, id string) error {
+	_, client, err := s.getServiceAccountClient(ctx, username)
+	if err != nil {
+		return err
+	}
+	if err := s.oauth.RevokeClientKey(ctx, client.ID, id, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrOAuthClientKeyNotFound) {
+			return ErrServiceAccountKeyNotFound
+		}
+		return err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  username,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "revoke service account key " + id,
+	})
+	return nil
+}
+
+func (s *serviceAccountUsecase) getServiceAccount(ctx context.Context, username string) (*repository.Account, error) {
+	account, err := s.repo.GetServiceAccount(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrServiceAccountNotFound) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *serviceAccountUsecase) getServiceAccountClient(ctx context.Context, username string) (*repository.Account, *repository.OAuthClient, error) {
+	account, err := s.getServiceAccount(ctx, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := s.repo.GetServiceAccountClient(ctx, account)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthClientNotFound) {
+			return nil, nil, ErrServiceAccountNotFound
+		}
+		return nil, nil, err
+	}
+	return account, client, nil
+}
+
+// ownerName is empty when the owner was deleted since.
+func (s *serviceAccountUsecase) ownerName(ctx context.Context, account *repository.Account) string {
+	if account.OwnerID == nil {
+		return ""
+	}
+	owner, err := s.accounts.GetAccountByID(ctx, *account.OwnerID)
+	if err != nil {
+		return ""
+	}
+	return owner.Username
+}
+
+func newClientSecret() (string, string, error) {
+	secret, err := util.RandomToken(oauthSecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+	secretHash, err := util.HashedPassword(secret)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, secretHash, nil
+}
+
+func serviceAccountResponse(account *repository.Account, owner string, client *repository.OAuthClient,
+	keys []repository.OAuthClientKey) ServiceAccountResponse {
+	rsp := ServiceAccountResponse{
+		ID:          account.ID.String(),
+		Username:    account.Username,
+		Owner:       owner,
+		Description: account.Description,
+		ClientID:    client.ID,
+		Scopes:      strings.Fields(client.Scopes),
+		Keys:        make([]ServiceAccountKeyResponse, 0, len(keys)),
+		CreatedAt:   account.CreatedAt,
+	}
+	for i := range keys {
+		rsp.Keys = append(rsp.Keys, serviceAccountKeyResponse(&keys[i]))
+	}
+	return rsp
+}
+
+func serviceAccountKeyResponse(key *repository.OAuthClientKey) ServiceAccountKeyResponse {
+	return ServiceAccountKeyResponse{
+		ID:        key.ID,
+		Algorithm: key.Algorithm,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
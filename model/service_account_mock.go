@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service_account.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockServiceAccountUsecase is a mock of ServiceAccountUsecase interface.
+type MockServiceAccountUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceAccountUsecaseMockRecorder
+}
+
+// MockServiceAccountUsecaseMockRecorder is the mock recorder for MockServiceAccountUsecase.
+type MockServiceAccountUsecaseMockRecorder struct {
+	mock *MockServiceAccountUsecase
+}
+
+// NewMockServiceAccountUsecase creates a new mock instance.
+func NewMockServiceAccountUsecase(ctrl *gomock.Controller) *MockServiceAccountUsecase {
+	mock := &MockServiceAccountUsecase{ctrl: ctrl}
+	mock.recorder = &MockServiceAccountUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServiceAccountUsecase) EXPECT() *MockServiceAccountUsecaseMockRecorder {
+	return m.recorder
+}
+
+// AddKey mocks base method.
+func (m *MockServiceAccountUsecase) AddKey(ctx context.Context, username string, req ServiceAccountKeyRequest) (*ServiceAccountKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddKey", ctx, username, req)
+	ret0, _ := ret[0].(*ServiceAccountKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddKey indicates an expected call of AddKey.
+func (mr *MockServiceAccountUsecaseMockRecorder) AddKey(ctx, username, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddKey", reflect.TypeOf((*MockServiceAccountUsecase)(nil).AddKey), ctx, username, req)
+}
+
+// CreateServiceAccount mocks base method.
+func (m *MockServiceAccountUsecase) CreateServiceAccount(ctx context.Context, req ServiceAccountRequest) (*ServiceAccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateServiceAccount", ctx, req)
+	ret0, _ := ret[0].(*ServiceAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateServiceAccount indicates an expected call of CreateServiceAccount.
+func (mr *MockServiceAccountUsecaseMockRecorder) CreateServiceAccount(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateServiceAccount", reflect.TypeOf((*MockServiceAccountUsecase)(nil).CreateServiceAccount), ctx, req)
+}
+
+// DeleteServiceAccount mocks base method.
+func (m *MockServiceAccountUsecase) DeleteServiceAccount(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteServiceAccount", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServiceAccount indicates an expected call of DeleteServiceAccount.
+func (mr *MockServiceAccountUsecaseMockRecorder) DeleteServiceAccount(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServiceAccount", reflect.TypeOf((*MockServiceAccountUsecase)(nil).DeleteServiceAccount), ctx, username)
+}
+
+// ListServiceAccounts mocks base method.
+func (m *MockServiceAccountUsecase) ListServiceAccounts(ctx context.Context) ([]ServiceAccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListServiceAccounts", ctx)
+	ret0, _ := ret[0].([]ServiceAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListServiceAccounts indicates an expected call of ListServiceAccounts.
+func (mr *MockServiceAccountUsecaseMockRecorder) ListServiceAccounts(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServiceAccounts", reflect.TypeOf((*MockServiceAccountUsecase)(nil).ListServiceAccounts), ctx)
+}
+
+// RevokeKey mocks base method.
+func (m *MockServiceAccountUsecase) RevokeKey(ctx context.Context, username, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeKey", ctx, username, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeKey indicates an expected call of RevokeKey.
+func (mr *MockServiceAccountUsecaseMockRecorder) RevokeKey(ctx, username, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeKey", reflect.TypeOf((*MockServiceAccountUsecase)(nil).RevokeKey), ctx, username, id)
+}
+
+// RotateSecret mocks base method.
+func (m *MockServiceAccountUsecase) RotateSecret(ctx context.Context, username string) (*ServiceAccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSecret", ctx, username)
+	ret0, _ := ret[0].(*ServiceAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateSecret indicates an expected call of RotateSecret.
+func (mr *MockServiceAccountUsecaseMockRecorder) RotateSecret(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSecret", reflect.TypeOf((*MockServiceAccountUsecase)(nil).RotateSecret), ctx, username)
+}
@@ -0,0 +1,208 @@
+package model
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type serviceAccountMocks struct {
+	repo     *repository.MockServiceAccountRepository
+	oauth    *repository.MockOAuthRepository
+	accounts *repository.MockAccountRepository
+	audit    *MockAuditUsecase
+}
+
+func newTestServiceAccountUsecase(t *testing.T) (ServiceAccountUsecase, serviceAccountMocks) {
+	ctrl := gomock.NewController(t)
+	mocks := serviceAccountMocks{
+		repo:     repository.NewMockServiceAccountRepository(ctrl),
+		oauth:    repository.NewMockOAuthRepository(ctrl),
+		accounts: repository.NewMockAccountRepository(ctrl),
+		audit:    NewMockAuditUsecase(ctrl),
+	}
+	return NewServiceAccountUsecase(mocks.repo, mocks.oauth, mocks.accounts, mocks.audit), mocks
+}
+
+func newTestServiceAccount() (*repository.Account, *repository.OAuthClient) {
+	account := &repository.Account{ID: uuid.New(), Username: "deploybot", Type: repository.AccountTypeService}
+	client := &repository.OAuthClient{ID: util.RandomString(16), AccountID: &account.ID, Scopes: "profile"}
+	return account, client
+}
+
+func TestCreateServiceAccount(t *testing.T) {
+	owner := &repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser}
+	req := ServiceAccountRequest{Username: "deploybot", Owner: "alice", Description: "CI deploys", Scopes: []string{"profile"}}
+
+	t.Run("ok", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(owner, nil)
+		mocks.repo.EXPECT().CreateServiceAccount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, account *repository.Account, client *repository.OAuthClient, _ ...*repository.OutboxMessage) error {
+				require.Equal(t, repository.AccountTypeService, account.Type)
+				require.Equal(t, owner.ID, *account.OwnerID)
+				require.Empty(t, account.HashedPassword)
+				require.Equal(t, account.ID, *client.AccountID)
+				require.True(t, clientAllowsGrant(client, GrantJWTBearer))
+				return nil
+			})
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+
+		rsp, err := usecase.CreateServiceAccount(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, "alice", rsp.Owner)
+		require.NotEmpty(t, rsp.ClientID)
+		require.NotEmpty(t, rsp.ClientSecret)
+	})
+
+	t.Run("owner is a service account", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").
+			Return(&repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeService}, nil)
+		mocks.repo.EXPECT().CreateServiceAccount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := usecase.CreateServiceAccount(context.Background(), req)
+		require.Equal(t, ErrInvalidServiceAccountOwner, err)
+	})
+
+	t.Run("owner not found", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(nil, repository.ErrAccountRecordNotFound)
+
+		_, err := usecase.CreateServiceAccount(context.Background(), req)
+		require.Equal(t, ErrInvalidServiceAccountOwner, err)
+	})
+
+	t.Run("duplicated", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(owner, nil)
+		mocks.repo.EXPECT().CreateServiceAccount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(repository.ErrAccountIsDuplicated)
+
+		_, err := usecase.CreateServiceAccount(context.Background(), req)
+		require.Equal(t, ErrAccountIsAlreadyExisted, err)
+	})
+
+	t.Run("invalid username", func(t *testing.T) {
+		usecase, _ := newTestServiceAccountUsecase(t)
+		_, err := usecase.CreateServiceAccount(context.Background(), ServiceAccountRequest{Username: "deploy-bot", Owner: "alice"})
+		require.Equal(t, ErrInvalidUsernameFormat, err)
+	})
+}
+
+func TestRotateServiceAccountSecret(t *testing.T) {
+	account, client := newTestServiceAccount()
+	usecase, mocks := newTestServiceAccountUsecase(t)
+	mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), account.Username).Return(account, nil)
+	mocks.repo.EXPECT().GetServiceAccountClient(gomock.Any(), account).Return(client, nil)
+	var secretHash string
+	mocks.oauth.EXPECT().UpdateClientSecret(gomock.Any(), client.ID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, hash string) error {
+			secretHash = hash
+			return nil
+		})
+	mocks.oauth.EXPECT().ListClientKeys(gomock.Any(), client.ID).Return(nil, nil)
+	mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+
+	rsp, err := usecase.RotateSecret(context.Background(), account.Username)
+	require.NoError(t, err)
+	require.NoError(t, util.CheckPassword(rsp.ClientSecret, secretHash))
+}
+
+func TestAddServiceAccountKey(t *testing.T) {
+	account, client := newTestServiceAccount()
+
+	t.Run("generated", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), account.Username).Return(account, nil)
+		mocks.repo.EXPECT().GetServiceAccountClient(gomock.Any(), account).Return(client, nil)
+		mocks.oauth.EXPECT().CreateClientKey(gomock.Any(), gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+
+		rsp, err := usecase.AddKey(context.Background(), account.Username, ServiceAccountKeyRequest{})
+		require.NoError(t, err)
+		require.Equal(t, KeyAlgorithmES256, rsp.Algorithm)
+
+		keys, err := util.ParsePrivateKeys(rsp.PrivateKey)
+		require.NoError(t, err)
+		key, err := NewSigningKey(keys[0], KeyStatusCurrent)
+		require.NoError(t, err)
+		require.Equal(t, rsp.ID, key.ID)
+	})
+
+	t.Run("uploaded", func(t *testing.T) {
+		signer, err := GenerateSigningKey(KeyAlgorithmEdDSA)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(signer.Public())
+		require.NoError(t, err)
+		publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), account.Username).Return(account, nil)
+		mocks.repo.EXPECT().GetServiceAccountClient(gomock.Any(), account).Return(client, nil)
+		mocks.oauth.EXPECT().CreateClientKey(gomock.Any(), gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+
+		rsp, err := usecase.AddKey(context.Background(), account.Username, ServiceAccountKeyRequest{PublicKey: publicKey})
+		require.NoError(t, err)
+		require.Equal(t, KeyAlgorithmEdDSA, rsp.Algorithm)
+		require.Empty(t, rsp.PrivateKey)
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), account.Username).Return(account, nil)
+		mocks.repo.EXPECT().GetServiceAccountClient(gomock.Any(), account).Return(client, nil)
+
+		_, err := usecase.AddKey(context.Background(), account.Username, ServiceAccountKeyRequest{PublicKey: "not a key"})
+		require.Equal(t, ErrInvalidServiceAccountKey, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), "nobody").Return(nil, repository.ErrServiceAccountNotFound)
+
+		_, err := usecase.AddKey(context.Background(), "nobody", ServiceAccountKeyRequest{})
+		require.Equal(t, ErrServiceAccountNotFound, err)
+	})
+}
+
+func TestRevokeServiceAccountKey(t *testing.T) {
+	account, client := newTestServiceAccount()
+
+	t.Run("ok", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), account.Username).Return(account, nil)
+		mocks.repo.EXPECT().GetServiceAccountClient(gomock.Any(), account).Return(client, nil)
+		mocks.oauth.EXPECT().RevokeClientKey(gomock.Any(), client.ID, "kid", gomock.Any()).Return(nil)
+		mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+
+		require.NoError(t, usecase.RevokeKey(context.Background(), account.Username, "kid"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		usecase, mocks := newTestServiceAccountUsecase(t)
+		mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), account.Username).Return(account, nil)
+		mocks.repo.EXPECT().GetServiceAccountClient(gomock.Any(), account).Return(client, nil)
+		mocks.oauth.EXPECT().RevokeClientKey(gomock.Any(), client.ID, "kid", gomock.Any()).Return(repository.ErrOAuthClientKeyNotFound)
+
+		require.Equal(t, ErrServiceAccountKeyNotFound, usecase.RevokeKey(context.Background(), account.Username, "kid"))
+	})
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+	account, _ := newTestServiceAccount()
+	usecase, mocks := newTestServiceAccountUsecase(t)
+	mocks.repo.EXPECT().GetServiceAccount(gomock.Any(), account.Username).Return(account, nil)
+	mocks.repo.EXPECT().DeleteServiceAccount(gomock.Any(), account, gomock.Any()).Return(nil)
+	mocks.audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+
+	require.NoError(t, usecase.DeleteServiceAccount(context.Background(), account.Username))
+}
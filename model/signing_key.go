@@ -0,0 +1,59 @@
+package model
+
+import (
+	"context"
+)
+
+// SigningKeyUsecase lets admins inspect the signing keys, force a rotation or revoke a compromised key.
+type SigningKeyUsecase interface {
+	ListKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error)
+	RotateKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error)
+	RevokeKey(ctx context.Context, id string) (*SigningKeyResponse, error)
+}
+
+type signingKeyUsecase struct {
+	keys  KeyManager
+	audit AuditUsecase
+}
+
+func NewSigningKeyUsecase(keys KeyManager, audit AuditUsecase) SigningKeyUsecase {
+	return &signingKeyUsecase{
+		keys:  keys,
+		audit: audit,
+	}
+}
+
+func (s *signingKeyUsecase) ListKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error) {
+	return s.keys.ListKeys(ctx, purpose)
+}
+
+// RotateKeys rotates the keys of purpose now, whether or not they are due, and returns the keys after it.
+func (s *signingKeyUsecase) RotateKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error) {
+	if err := s.keys.Rotate(ctx, purpose, true); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "signing_keys:" + purpose,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "rotate signing keys",
+	})
+	return s.keys.ListKeys(ctx, purpose)
+}
+
+// RevokeKey takes a compromised key out of service at once. Tokens it signed stop verifying.
+func (s *signingKeyUsecase) RevokeKey(ctx context.Context, id string) (*SigningKeyResponse, error) {
+	rsp, err := s.keys.Revoke(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "signing_key:" + id,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "revoke " + rsp.Purpose + " signing key",
+	})
+	return rsp, nil
+}
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: signing_key.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSigningKeyUsecase is a mock of SigningKeyUsecase interface.
+type MockSigningKeyUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockSigningKeyUsecaseMockRecorder
+}
+
+// MockSigningKeyUsecaseMockRecorder is the mock recorder for MockSigningKeyUsecase.
+type MockSigningKeyUsecaseMockRecorder struct {
+	mock *MockSigningKeyUsecase
+}
+
+// NewMockSigningKeyUsecase creates a new mock instance.
+func NewMockSigningKeyUsecase(ctrl *gomock.Controller) *MockSigningKeyUsecase {
+	mock := &MockSigningKeyUsecase{ctrl: ctrl}
+	mock.recorder = &MockSigningKeyUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSigningKeyUsecase) EXPECT() *MockSigningKeyUsecaseMockRecorder {
+	return m.recorder
+}
+
+// ListKeys mocks base method.
+func (m *MockSigningKeyUsecase) ListKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListKeys", ctx, purpose)
+	ret0, _ := ret[0].([]SigningKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListKeys indicates an expected call of ListKeys.
+func (mr *MockSigningKeyUsecaseMockRecorder) ListKeys(ctx, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListKeys", reflect.TypeOf((*MockSigningKeyUsecase)(nil).ListKeys), ctx, purpose)
+}
+
+// RevokeKey mocks base method.
+func (m *MockSigningKeyUsecase) RevokeKey(ctx context.Context, id string) (*SigningKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeKey", ctx, id)
+	ret0, _ := ret[0].(*SigningKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeKey indicates an expected call of RevokeKey.
+func (mr *MockSigningKeyUsecaseMockRecorder) RevokeKey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeKey", reflect.TypeOf((*MockSigningKeyUsecase)(nil).RevokeKey), ctx, id)
+}
+
+// RotateKeys mocks base method.
+func (m *MockSigningKeyUsecase) RotateKeys(ctx context.Context, purpose string) ([]SigningKeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateKeys", ctx, purpose)
+	ret0, _ := ret[0].([]SigningKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateKeys indicates an expected call of RotateKeys.
+func (mr *MockSigningKeyUsecaseMockRecorder) RotateKeys(ctx, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateKeys", reflect.TypeOf((*MockSigningKeyUsecase)(nil).RotateKeys), ctx, purpose)
+}
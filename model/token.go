@@ -0,0 +1,97 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var ErrInvalidToken = errors.New("Invalid token")
+
+// AccessTokenClaims are the claims of the JWT access tokens we issue. Subject is the account ID, or
+// the client ID for client_credentials tokens.
+type AccessTokenClaims struct {
+	jwt.RegisteredClaims
+	ClientID string `json:"client_id"`
+	Username string `json:"username,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// AuthTime is when the account last signed in, it does not move when the token is refreshed.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+}
+
+// IDTokenClaims are the OpenID Connect ID token claims we issue.
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	Nonce             string           `json:"nonce,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	UpdatedAt         int64            `json:"updated_at,omitempty"`
+}
+
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+	Verify(token string, claims jwt.Claims) error
+}
+
+type tokenSigner struct {
+	keys KeySet
+}
+
+// NewTokenSigner signs with the current key of keys and verifies against any published key.
+func NewTokenSigner(keys KeySet) TokenSigner {
+	return &tokenSigner{
+		keys: keys,
+	}
+}
+
+func (s *tokenSigner) Sign(claims jwt.Claims) (string, error) {
+	key, err := s.keys.Current()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Key)
+}
+
+// Verify checks the signature and the time claims of token and decodes it into claims.
+func (s *tokenSigner) Verify(token string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		keys := s.keys.Keys()
+		for i := range keys {
+			if keys[i].ID == t.Header["kid"] {
+				if keys[i].Algorithm != t.Method.Alg() {
+					return nil, fmt.Errorf("key %s does not sign with %s", keys[i].ID, t.Method.Alg())
+				}
+				return keys[i].Key.Public(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key %v", t.Header["kid"])
+	}, jwt.WithValidMethods([]string{KeyAlgorithmEdDSA, KeyAlgorithmRS256, KeyAlgorithmES256, KeyAlgorithmES384, KeyAlgorithmES512}))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// verifyAccessToken checks an access token we issued: its signature, lifetime and issuer, and that
+// it was not revoked.
+func verifyAccessToken(ctx context.Context, signer TokenSigner, revocations RevocationList, issuer string, token string) (*AccessTokenClaims, error) {
+	claims := &AccessTokenClaims{}
+	if err := signer.Verify(token, claims); err != nil {
+		return nil, err
+	}
+	if claims.Issuer != issuer || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	revoked, err := revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/google/uuid"
+)
+
+// TOTP follows RFC 6238 with the parameters every authenticator app supports: HMAC-SHA1, six digits
+// and a 30 second step.
+const (
+	totpDigits      = 6
+	totpPeriod      = 30
+	totpSecretBytes = 20
+	// totpSkew is how many steps a code may be off, for phones whose clocks drift.
+	totpSkew = 1
+)
+
+var (
+	ErrLoginOTPRequired = errors.New("One-time code is required")
+	ErrLoginWrongOTP    = errors.New("Wrong one-time code")
+)
+
+// SecondFactor checks the one-time code of an account that has TOTP linked.
+type SecondFactor interface {
+	// Verify returns nil for an account without TOTP, ErrLoginOTPRequired when code is empty and
+	// ErrLoginWrongOTP when it is wrong or was already used.
+	Verify(ctx context.Context, account *repository.Account, code string) error
+}
+
+type totpVerifier struct {
+	repo      repository.IdentityRepository
+	masterKey []byte
+}
+
+// NewTOTPVerifier checks codes against the TOTP secret linked to the account, sealed with masterKey.
+func NewTOTPVerifier(repo repository.IdentityRepository, masterKey []byte) SecondFactor {
+	return &totpVerifier{
+		repo:      repo,
+		masterKey: masterKey,
+	}
+}
+
+func (v *totpVerifier) Verify(ctx context.Context, account *repository.Account, code string) error {
+	identities, err := v.repo.ListIdentities(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	var totp *repository.Identity
+	for i := range identities {
+		if identities[i].Type == repository.IdentityTypeTOTP {
+			totp = &identities[i]
+		}
+	}
+	if totp == nil {
+		return nil
+	}
+	if code == "" {
+		return ErrLoginOTPRequired
+	}
+	secret, err := openTOTPSecret(v.masterKey, totp.ID, totp.Credential)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	step, ok := matchTOTP(secret, code, now, totp.Counter)
+	if !ok {
+		return ErrLoginWrongOTP
+	}
+	// The step is stored so a code that was seen once cannot be used again.
+	if err := v.repo.UseIdentityCounter(ctx, totp.ID, step, now); err != nil {
+		if err == repository.ErrIdentityCounterReplayed {
+			return ErrLoginWrongOTP
+		}
+		return err
+	}
+	return nil
+}
+
+// totpCode is the HOTP value (RFC 4226) of secret for a time step.
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTP returns the step code is valid for, looking only at steps after lastStep.
+func matchTOTP(secret []byte, code string, now time.Time, lastStep int64) (int64, bool) {
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI is the otpauth:// URI authenticator apps read from a QR code.
+func totpURI(issuer string, username string, secret []byte) string {
+	query := url.Values{
+		"secret":    {base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(totpPeriod)},
+	}
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+username) + "?" + query.Encode()
+}
+
+// The secret is sealed under the identity ID, so it cannot be copied to another row.
+func sealTOTPSecret(masterKey []byte, id uuid.UUID, secret []byte) ([]byte, error) {
+	return util.Seal(masterKey, secret, []byte("totp:"+id.String()))
+}
+
+func openTOTPSecret(masterKey []byte, id uuid.UUID, sealed []byte) ([]byte, error) {
+	return util.Open(masterKey, sealed, []byte("totp:"+id.String()))
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: totp.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/ambroseqiu/senao_hw/repository"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSecondFactor is a mock of SecondFactor interface.
+type MockSecondFactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecondFactorMockRecorder
+}
+
+// MockSecondFactorMockRecorder is the mock recorder for MockSecondFactor.
+type MockSecondFactorMockRecorder struct {
+	mock *MockSecondFactor
+}
+
+// NewMockSecondFactor creates a new mock instance.
+func NewMockSecondFactor(ctrl *gomock.Controller) *MockSecondFactor {
+	mock := &MockSecondFactor{ctrl: ctrl}
+	mock.recorder = &MockSecondFactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecondFactor) EXPECT() *MockSecondFactorMockRecorder {
+	return m.recorder
+}
+
+// Verify mocks base method.
+func (m *MockSecondFactor) Verify(ctx context.Context, account *repository.Account, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, account, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockSecondFactorMockRecorder) Verify(ctx, account, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockSecondFactor)(nil).Verify), ctx, account, code)
+}
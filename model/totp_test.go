@@ -0,0 +1,106 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMasterKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+func TestTOTPCode(t *testing.T) {
+	// The SHA1 vectors of RFC 6238 appendix B, cut to six digits.
+	secret := []byte("12345678901234567890")
+	testCase := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+	for _, tc := range testCase {
+		require.Equal(t, tc.code, totpCode(secret, tc.unix/totpPeriod))
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / totpPeriod
+
+	step, ok := matchTOTP(secret, totpCode(secret, current), now, 0)
+	require.True(t, ok)
+	require.Equal(t, current, step)
+
+	step, ok = matchTOTP(secret, totpCode(secret, current-1), now, 0)
+	require.True(t, ok)
+	require.Equal(t, current-1, step)
+
+	_, ok = matchTOTP(secret, totpCode(secret, current-2), now, 0)
+	require.False(t, ok)
+
+	// A code of a step that was already used does not match again.
+	_, ok = matchTOTP(secret, totpCode(secret, current), now, current)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("Senao", "alice", []byte("12345678901234567890"))
+	require.Equal(t, "otpauth://totp/Senao:alice?algorithm=SHA1&digits=6&issuer=Senao&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+}
+
+func TestTOTPVerifier(t *testing.T) {
+	masterKey := newTestMasterKey(t)
+	secret := []byte("12345678901234567890")
+	account := &repository.Account{ID: uuid.New(), Username: "alice"}
+	identity := repository.Identity{ID: uuid.New(), AccountID: account.ID, Type: repository.IdentityTypeTOTP}
+	var err error
+	identity.Credential, err = sealTOTPSecret(masterKey, identity.ID, secret)
+	require.NoError(t, err)
+	code := totpCode(secret, time.Now().Unix()/totpPeriod)
+
+	t.Run("no totp", func(t *testing.T) {
+		repo := repository.NewMockIdentityRepository(gomock.NewController(t))
+		repo.EXPECT().ListIdentities(gomock.Any(), account.ID).Return(nil, nil)
+		require.NoError(t, NewTOTPVerifier(repo, masterKey).Verify(context.Background(), account, ""))
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		repo := repository.NewMockIdentityRepository(gomock.NewController(t))
+		repo.EXPECT().ListIdentities(gomock.Any(), account.ID).Return([]repository.Identity{identity}, nil)
+		repo.EXPECT().UseIdentityCounter(gomock.Any(), identity.ID, gomock.Any(), gomock.Any()).Return(nil)
+		require.NoError(t, NewTOTPVerifier(repo, masterKey).Verify(context.Background(), account, code))
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		repo := repository.NewMockIdentityRepository(gomock.NewController(t))
+		repo.EXPECT().ListIdentities(gomock.Any(), account.ID).Return([]repository.Identity{identity}, nil)
+		require.Equal(t, ErrLoginOTPRequired, NewTOTPVerifier(repo, masterKey).Verify(context.Background(), account, ""))
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		repo := repository.NewMockIdentityRepository(gomock.NewController(t))
+		repo.EXPECT().ListIdentities(gomock.Any(), account.ID).Return([]repository.Identity{identity}, nil)
+		require.Equal(t, ErrLoginWrongOTP, NewTOTPVerifier(repo, masterKey).Verify(context.Background(), account, "000000"))
+	})
+
+	t.Run("replayed code", func(t *testing.T) {
+		repo := repository.NewMockIdentityRepository(gomock.NewController(t))
+		repo.EXPECT().ListIdentities(gomock.Any(), account.ID).Return([]repository.Identity{identity}, nil)
+		repo.EXPECT().UseIdentityCounter(gomock.Any(), identity.ID, gomock.Any(), gomock.Any()).Return(repository.ErrIdentityCounterReplayed)
+		require.Equal(t, ErrLoginWrongOTP, NewTOTPVerifier(repo, masterKey).Verify(context.Background(), account, code))
+	})
+}
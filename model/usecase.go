@@ -3,12 +3,14 @@ package model
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ambroseqiu/senao_hw/repository"
 	"github.com/ambroseqiu/senao_hw/util"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 var (
@@ -22,23 +24,54 @@ var (
 	ErrLoginAccountNotFound           = errors.New("Login account not found")
 	ErrLoginWrongPassword             = errors.New("Wrong password")
 	ErrLoginAttemptBlocked            = errors.New("too many failed login attempt, please try it later")
+	ErrAccountNotFound                = errors.New("Account not found")
+	ErrLoginServiceAccount            = errors.New("Service accounts cannot log in with a password")
+	ErrLoginNoPassword                = errors.New("Account has no password, sign in with its identity provider")
+	ErrLoginDirectoryConflict         = errors.New("An account kept by the service has the name of this directory user")
+	ErrLoginAccountLocked             = errors.New("Account is locked, please contact support")
+	ErrLoginPasswordResetRequired     = errors.New("Password was reset, log in again with a new password")
 )
 
 type UsecaseHandler interface {
 	CreateAccount(ctx context.Context, req AccountRequest) (*AccountResponse, error)
 	LoginAccount(ctx context.Context, req AccountRequest) (*AccountResponse, error)
+	DeleteAccount(ctx context.Context, username string) (*AccountResponse, error)
+	// The methods below are for support staff, through the admin API and the admin console.
+	SearchAccounts(ctx context.Context, req AccountSearchRequest) (*AccountSearchResponse, error)
+	GetAccount(ctx context.Context, username string) (*AdminAccountResponse, error)
+	ListFailedLogins(ctx context.Context, username string) (*AuditQueryResponse, error)
+	LockAccount(ctx context.Context, username string, req LockAccountRequest) (*AdminAccountResponse, error)
+	UnlockAccount(ctx context.Context, username string) (*AdminAccountResponse, error)
+	ResetPassword(ctx context.Context, username string) (*PasswordResetResponse, error)
+	SetAccountRoles(ctx context.Context, username string, req AccountRolesRequest) (*AdminAccountResponse, error)
 }
 
 type usecaseHandler struct {
-	mu      sync.RWMutex
-	loginAC map[string]LoginAttempt
-	repo    repository.AccountRepository
+	mu        sync.RWMutex
+	loginAC   map[string]LoginAttempt
+	repo      repository.AccountRepository
+	audit     AuditUsecase
+	events    EventPublisher
+	hooks     Hooks
+	second    SecondFactor
+	directory PasswordAuthenticator
+	vouchers  VoucherRedeemer
 }
 
-func NewUsecaseHandler(repo repository.AccountRepository) UsecaseHandler {
+// NewUsecaseHandler creates the account usecase. hooks may be nil when no hooks are configured,
+// second may be nil when one-time codes are not checked, directory may be nil when passwords are
+// only kept by the service and vouchers may be nil when voucher accounts cannot log in.
+func NewUsecaseHandler(repo repository.AccountRepository, audit AuditUsecase, events EventPublisher, hooks Hooks,
+	second SecondFactor, directory PasswordAuthenticator, vouchers VoucherRedeemer) UsecaseHandler {
 	return &usecaseHandler{
-		loginAC: make(map[string]LoginAttempt, 100),
-		repo:    repo,
+		loginAC:   make(map[string]LoginAttempt, 100),
+		repo:      repo,
+		audit:     audit,
+		events:    events,
+		hooks:     hooks,
+		second:    second,
+		directory: directory,
+		vouchers:  vouchers,
 	}
 }
 
@@ -54,26 +87,64 @@ func (u *usecaseHandler) CreateAccount(ctx context.Context, req AccountRequest)
 		return rsp, ErrAccountRequestValidationFailed
 	}
 
-	uuid := uuid.New()
+	account := &repository.Account{
+		ID:       uuid.New(),
+		Username: req.Username,
+		Type:     repository.AccountTypeUser,
+	}
+	pre := u.hooks.Run(ctx, HookPreCreate, account)
+	if pre.Deny {
+		rsp.Success = false
+		rsp.Reason = pre.Reason
+		u.audit.Record(ctx, AuditEntry{
+			Type:    AuditAccountCreated,
+			Target:  req.Username,
+			Outcome: AuditOutcomeFailure,
+			Detail:  rsp.Reason,
+		})
+		return rsp, ErrHookDenied
+	}
+	if _, err := setAccountMetadata(account, pre.Metadata); err != nil {
+		return nil, err
+	}
+
 	hashedPassword, err := util.HashedPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
-	account := &repository.Account{
-		ID:             uuid,
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-	}
+	account.HashedPassword = hashedPassword
 
-	if err := u.repo.CreateAccount(ctx, account); err != nil {
+	message, err := NewOutboxMessage(NewAccountEvent(EventAccountCreated, account, ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.CreateAccount(ctx, account, message); err != nil {
 		rsp.Success = false
 		if errors.Is(err, repository.ErrAccountIsDuplicated) {
 			rsp.Reason = ErrAccountIsAlreadyExisted.Error()
+			u.audit.Record(ctx, AuditEntry{
+				Type:    AuditAccountCreated,
+				Target:  req.Username,
+				Outcome: AuditOutcomeFailure,
+				Detail:  rsp.Reason,
+			})
 			return rsp, ErrAccountIsAlreadyExisted
 		}
 		return nil, err
 	}
 
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditAccountCreated,
+		Target:  req.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
+
+	post := u.hooks.Run(ctx, HookPostCreate, account)
+	if post.Deny {
+		log.Warn().Str("username", account.Username).Str("reason", post.Reason).Msg("post-create hook cannot deny")
+	} else {
+		u.saveHookMetadata(ctx, account, post.Metadata)
+	}
 	return rsp, nil
 }
 
@@ -84,29 +155,378 @@ func (u *usecaseHandler) LoginAccount(ctx context.Context, req AccountRequest) (
 	}
 	if err := u.loginValidate(req.Username); err != nil {
 		rsp.Reason = err.Error()
+		u.audit.Record(ctx, AuditEntry{
+			Type:    AuditLoginBlocked,
+			Target:  req.Username,
+			Outcome: AuditOutcomeFailure,
+			Detail:  rsp.Reason,
+		})
 		return rsp, err
 	}
 	account, err := u.repo.GetAccount(ctx, req.Username)
+	// A directory user logging in for the first time gets an account once the directory took the password.
+	directoryChecked := false
+	if err == repository.ErrAccountRecordNotFound && u.directory != nil {
+		account, err = u.provisionDirectoryAccount(ctx, req)
+		if err == ErrLoginWrongPassword {
+			return u.failPassword(ctx, nil, req.Username, rsp)
+		}
+		directoryChecked = err == nil
+	}
+	// Guests may type a voucher code as it is printed.
+	if code := NormalizeVoucherCode(req.Username); err == repository.ErrAccountRecordNotFound && code != req.Username && IsVoucherCode(code) {
+		account, err = u.repo.GetAccount(ctx, code)
+		if err == nil && account.Type != repository.AccountTypeVoucher {
+			err = repository.ErrAccountRecordNotFound
+		}
+	}
 	if err != nil {
 		if err == repository.ErrAccountRecordNotFound {
 			rsp.Reason = ErrLoginAccountNotFound.Error()
+			u.audit.Record(ctx, AuditEntry{
+				Type:    AuditLoginAccountNotFound,
+				Target:  req.Username,
+				Outcome: AuditOutcomeFailure,
+				Detail:  rsp.Reason,
+			})
 			return rsp, ErrLoginAccountNotFound
 		}
+		if err == ErrLoginDirectoryConflict {
+			rsp.Reason = err.Error()
+			u.audit.Record(ctx, AuditEntry{
+				Type:    AuditLoginDenied,
+				Target:  req.Username,
+				Outcome: AuditOutcomeFailure,
+				Detail:  rsp.Reason,
+			})
+			return rsp, err
+		}
+		return nil, err
+	}
+	if account.LockedAt != nil {
+		rsp.Reason = ErrLoginAccountLocked.Error()
+		u.audit.Record(ctx, AuditEntry{
+			Type:    AuditLoginDenied,
+			Target:  account.Username,
+			Outcome: AuditOutcomeFailure,
+			Detail:  rsp.Reason,
+		})
+		return rsp, ErrLoginAccountLocked
+	}
+	if account.Type == repository.AccountTypeService {
+		rsp.Reason = ErrLoginServiceAccount.Error()
+		u.audit.Record(ctx, AuditEntry{
+			Type:    AuditLoginDenied,
+			Target:  account.Username,
+			Outcome: AuditOutcomeFailure,
+			Detail:  rsp.Reason,
+		})
+		return rsp, ErrLoginServiceAccount
+	}
+	if account.Type == repository.AccountTypeVoucher {
+		return u.loginVoucher(ctx, account, req, rsp)
+	}
+	// Accounts provisioned by a federated login have no password until one is set.
+	if account.HashedPassword == "" && !u.fromDirectory(account) {
+		rsp.Reason = ErrLoginNoPassword.Error()
+		u.audit.Record(ctx, AuditEntry{
+			Type:    AuditLoginDenied,
+			Target:  account.Username,
+			Outcome: AuditOutcomeFailure,
+			Detail:  rsp.Reason,
+		})
+		return rsp, ErrLoginNoPassword
+	}
+	pre := u.hooks.Run(ctx, HookPreLogin, account)
+	if pre.Deny {
+		return u.denyLogin(ctx, account, rsp, pre.Reason)
+	}
+	if !directoryChecked {
+		if err := u.checkPassword(ctx, account, req.Password); err != nil {
+			if err == ErrLoginWrongPassword {
+				return u.failPassword(ctx, account, account.Username, rsp)
+			}
+			return nil, err
+		}
+	}
+	if u.second != nil {
+		if err := u.second.Verify(ctx, account, req.OTP); err != nil {
+			return u.failSecondFactor(ctx, account, rsp, err)
+		}
+	}
+	if account.PasswordResetRequired {
+		if err := u.replaceTemporaryPassword(ctx, account, req.NewPassword, rsp); err != nil {
+			if err == ErrLoginPasswordResetRequired || err == ErrAccountRequestValidationFailed {
+				return rsp, err
+			}
+			return nil, err
+		}
+	}
+	// Post-login hooks see what pre-login hooks added, both are saved once the login went through.
+	stored := account.Metadata
+	if _, err := setAccountMetadata(account, pre.Metadata); err != nil {
+		return nil, err
+	}
+	post := u.hooks.Run(ctx, HookPostLogin, account)
+	if post.Deny {
+		return u.denyLogin(ctx, account, rsp, post.Reason)
+	}
+	if _, err := setAccountMetadata(account, post.Metadata); err != nil {
+		return nil, err
+	}
+	if account.Metadata != stored {
+		if err := u.repo.UpdateAccountMetadata(ctx, account); err != nil {
+			log.Error().Err(err).Str("username", account.Username).Msg("failed to save account metadata from hooks")
+		}
+	}
+
+	rsp.Success = true
+	rsp.Claims = post.Claims
+	rsp.Username = account.Username
+	rsp.Roles = strings.Fields(account.Roles)
+	u.ClearFailedAttempt(account.Username)
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginSucceeded,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
+	u.events.Publish(ctx, NewAccountEvent(EventAccountLoginSucceeded, account, ""))
+	return rsp, nil
+}
+
+// replaceTemporaryPassword sets the new password of an account whose password support staff reset,
+// the temporary password only lets its owner choose another one.
+func (u *usecaseHandler) replaceTemporaryPassword(ctx context.Context, account *repository.Account, password string, rsp *AccountResponse) error {
+	if password == "" {
+		rsp.Reason = ErrLoginPasswordResetRequired.Error()
+		return ErrLoginPasswordResetRequired
+	}
+	req := SetPasswordRequest{Password: password}
+	if err := req.Validate(); err != nil {
+		rsp.Reason = err.Error()
+		return ErrAccountRequestValidationFailed
+	}
+	hashedPassword, err := util.HashedPassword(password)
+	if err != nil {
+		return err
+	}
+	account.HashedPassword = hashedPassword
+	account.PasswordResetRequired = false
+	if err := u.repo.UpdateAccountPassword(ctx, account); err != nil {
+		return err
+	}
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditPasswordChanged,
+		Actor:   account.Username,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
+	return nil
+}
+
+// loginVoucher logs a guest in with the code of a voucher, given as the password. Vouchers have no
+// hooks nor second factor, their limits are checked instead.
+func (u *usecaseHandler) loginVoucher(ctx context.Context, account *repository.Account, req AccountRequest, rsp *AccountResponse) (*AccountResponse, error) {
+	if !voucherCodeMatches(req.Password, account.Username) {
+		return u.failPassword(ctx, account, account.Username, rsp)
+	}
+	if u.vouchers == nil {
+		return u.refuseVoucher(ctx, account, rsp, ErrVoucherExpired)
+	}
+	expiresAt, err := u.vouchers.Redeem(ctx, account)
+	if err != nil {
+		if err == ErrVoucherExpired || err == ErrVoucherDataUsed {
+			return u.refuseVoucher(ctx, account, rsp, err)
+		}
+		return nil, err
+	}
+
+	rsp.Success = true
+	rsp.Username = account.Username
+	rsp.Roles = strings.Fields(account.Roles)
+	rsp.ExpiresAt = expiresAt
+	u.ClearFailedAttempt(account.Username)
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginSucceeded,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
+	u.events.Publish(ctx, NewAccountEvent(EventAccountLoginSucceeded, account, ""))
+	return rsp, nil
+}
+
+func (u *usecaseHandler) refuseVoucher(ctx context.Context, account *repository.Account, rsp *AccountResponse, err error) (*AccountResponse, error) {
+	rsp.Reason = err.Error()
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginDenied,
+		Target:  account.Username,
+		Outcome: AuditOutcomeFailure,
+		Detail:  rsp.Reason,
+	})
+	u.events.Publish(ctx, NewAccountEvent(EventAccountLoginFailed, account, rsp.Reason))
+	return rsp, err
+}
+
+func (u *usecaseHandler) DeleteAccount(ctx context.Context, username string) (*AccountResponse, error) {
+	rsp := &AccountResponse{
+		Success: false,
+		Reason:  "",
+	}
+	account, err := u.repo.GetAccount(ctx, username)
+	if err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			rsp.Reason = ErrAccountNotFound.Error()
+			return rsp, ErrAccountNotFound
+		}
 		return nil, err
 	}
-	if err = util.CheckPassword(req.Password, account.HashedPassword); err != nil {
-		if err == util.ErrMismatchedPassword {
-			u.AddFailedAttempt(account.Username)
-			rsp.Reason = ErrLoginWrongPassword.Error()
-			return rsp, ErrLoginWrongPassword
+	message, err := NewOutboxMessage(NewAccountEvent(EventAccountDeleted, account, ""))
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.DeleteAccount(ctx, account, message); err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			rsp.Reason = ErrAccountNotFound.Error()
+			return rsp, ErrAccountNotFound
 		}
 		return nil, err
 	}
+
 	rsp.Success = true
 	u.ClearFailedAttempt(account.Username)
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditAccountDeleted,
+		Target:  account.Username,
+		Outcome: AuditOutcomeSuccess,
+	})
 	return rsp, nil
 }
 
+// checkPassword checks the password of the account, with the directory for accounts mirrored from it.
+func (u *usecaseHandler) checkPassword(ctx context.Context, account *repository.Account, password string) error {
+	if !u.fromDirectory(account) {
+		if err := util.CheckPassword(password, account.HashedPassword); err != nil {
+			if err == util.ErrMismatchedPassword {
+				return ErrLoginWrongPassword
+			}
+			return err
+		}
+		return nil
+	}
+	user, err := u.directory.Authenticate(ctx, account.Username, password)
+	if err != nil {
+		// A user removed from the directory keeps its account until the next sync, but cannot log in.
+		if err == ErrDirectoryUserNotFound {
+			return ErrLoginWrongPassword
+		}
+		return err
+	}
+	_, err = updateDirectoryRoles(ctx, u.repo, u.audit, account, user)
+	return err
+}
+
+func (u *usecaseHandler) fromDirectory(account *repository.Account) bool {
+	return u.directory != nil && account.Source == repository.AccountSourceLDAP
+}
+
+// provisionDirectoryAccount creates the account of a directory user the service does not know yet.
+// It returns ErrAccountRecordNotFound when the directory does not know the user either.
+func (u *usecaseHandler) provisionDirectoryAccount(ctx context.Context, req AccountRequest) (*repository.Account, error) {
+	user, err := u.directory.Authenticate(ctx, req.Username, req.Password)
+	if err != nil {
+		if err == ErrDirectoryUserNotFound {
+			return nil, repository.ErrAccountRecordNotFound
+		}
+		return nil, err
+	}
+	account, err := createDirectoryAccount(ctx, u.repo, u.audit, user)
+	if err != repository.ErrAccountIsDuplicated {
+		return account, err
+	}
+	// The directory spells the username differently, or a sync created the account meanwhile. An account
+	// of the same name kept by the service is not taken over.
+	account, err = u.repo.GetAccount(ctx, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	if account.Source != repository.AccountSourceLDAP {
+		return nil, ErrLoginDirectoryConflict
+	}
+	if _, err := updateDirectoryRoles(ctx, u.repo, u.audit, account, user); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// failPassword answers a login with a wrong password. account is nil when the directory rejected the
+// password of a user without an account yet.
+func (u *usecaseHandler) failPassword(ctx context.Context, account *repository.Account, username string, rsp *AccountResponse) (*AccountResponse, error) {
+	failedAttempt := u.AddFailedAttempt(username)
+	rsp.Reason = ErrLoginWrongPassword.Error()
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginWrongPassword,
+		Target:  username,
+		Outcome: AuditOutcomeFailure,
+		Detail:  rsp.Reason,
+	})
+	if account != nil {
+		u.events.Publish(ctx, NewAccountEvent(EventAccountLoginFailed, account, rsp.Reason))
+		// The count is kept once a block expired, so every failure past the limit starts a new block.
+		if failedAttempt >= maxFailedAttempt {
+			u.events.Publish(ctx, NewAccountEvent(EventAccountLocked, account, ErrLoginAttemptBlocked.Error()))
+		}
+	}
+	return rsp, ErrLoginWrongPassword
+}
+
+func (u *usecaseHandler) denyLogin(ctx context.Context, account *repository.Account, rsp *AccountResponse, reason string) (*AccountResponse, error) {
+	rsp.Reason = reason
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginDenied,
+		Target:  account.Username,
+		Outcome: AuditOutcomeFailure,
+		Detail:  reason,
+	})
+	u.events.Publish(ctx, NewAccountEvent(EventAccountLoginFailed, account, reason))
+	return rsp, ErrHookDenied
+}
+
+// failSecondFactor answers a login whose password was right but whose one-time code was missing or
+// wrong. A wrong code counts as a failed attempt, or the password would be enough to guess codes.
+func (u *usecaseHandler) failSecondFactor(ctx context.Context, account *repository.Account, rsp *AccountResponse, err error) (*AccountResponse, error) {
+	if err != ErrLoginOTPRequired && err != ErrLoginWrongOTP {
+		return nil, err
+	}
+	rsp.Reason = err.Error()
+	if err == ErrLoginOTPRequired {
+		return rsp, err
+	}
+	failedAttempt := u.AddFailedAttempt(account.Username)
+	u.audit.Record(ctx, AuditEntry{
+		Type:    AuditLoginWrongOTP,
+		Target:  account.Username,
+		Outcome: AuditOutcomeFailure,
+		Detail:  rsp.Reason,
+	})
+	u.events.Publish(ctx, NewAccountEvent(EventAccountLoginFailed, account, rsp.Reason))
+	if failedAttempt >= maxFailedAttempt {
+		u.events.Publish(ctx, NewAccountEvent(EventAccountLocked, account, ErrLoginAttemptBlocked.Error()))
+	}
+	return rsp, err
+}
+
+// saveHookMetadata stores metadata returned by hooks on the account. The operation already
+// succeeded, so a failure is only logged.
+func (u *usecaseHandler) saveHookMetadata(ctx context.Context, account *repository.Account, metadata map[string]string) {
+	changed, err := setAccountMetadata(account, metadata)
+	if err == nil && changed {
+		err = u.repo.UpdateAccountMetadata(ctx, account)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("username", account.Username).Msg("failed to save account metadata from hooks")
+	}
+}
+
 func (u *usecaseHandler) loginValidate(username string) error {
 	defer u.mu.RUnlock()
 	u.mu.RLock()
@@ -123,7 +543,8 @@ func (u *usecaseHandler) loginValidate(username string) error {
 	return nil
 }
 
-func (u *usecaseHandler) AddFailedAttempt(username string) {
+// AddFailedAttempt records a failed login and returns how many there have been in a row.
+func (u *usecaseHandler) AddFailedAttempt(username string) int {
 	defer u.mu.Unlock()
 	u.mu.Lock()
 	loginAttempt, ok := u.loginAC[username]
@@ -132,7 +553,7 @@ func (u *usecaseHandler) AddFailedAttempt(username string) {
 			FailedAttempt: 1,
 			LastTime:      time.Now(),
 		}
-		return
+		return 1
 	}
 	loginAttempt.FailedAttempt++
 	loginAttempt.LastTime = time.Now()
@@ -140,6 +561,7 @@ func (u *usecaseHandler) AddFailedAttempt(username string) {
 		FailedAttempt: loginAttempt.FailedAttempt,
 		LastTime:      loginAttempt.LastTime,
 	}
+	return loginAttempt.FailedAttempt
 }
 
 func (u *usecaseHandler) ClearFailedAttempt(username string) {
@@ -49,6 +49,66 @@ func (mr *MockUsecaseHandlerMockRecorder) CreateAccount(ctx, req interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockUsecaseHandler)(nil).CreateAccount), ctx, req)
 }
 
+// DeleteAccount mocks base method.
+func (m *MockUsecaseHandler) DeleteAccount(ctx context.Context, username string) (*AccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", ctx, username)
+	ret0, _ := ret[0].(*AccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockUsecaseHandlerMockRecorder) DeleteAccount(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockUsecaseHandler)(nil).DeleteAccount), ctx, username)
+}
+
+// GetAccount mocks base method.
+func (m *MockUsecaseHandler) GetAccount(ctx context.Context, username string) (*AdminAccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", ctx, username)
+	ret0, _ := ret[0].(*AdminAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockUsecaseHandlerMockRecorder) GetAccount(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockUsecaseHandler)(nil).GetAccount), ctx, username)
+}
+
+// ListFailedLogins mocks base method.
+func (m *MockUsecaseHandler) ListFailedLogins(ctx context.Context, username string) (*AuditQueryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFailedLogins", ctx, username)
+	ret0, _ := ret[0].(*AuditQueryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFailedLogins indicates an expected call of ListFailedLogins.
+func (mr *MockUsecaseHandlerMockRecorder) ListFailedLogins(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFailedLogins", reflect.TypeOf((*MockUsecaseHandler)(nil).ListFailedLogins), ctx, username)
+}
+
+// LockAccount mocks base method.
+func (m *MockUsecaseHandler) LockAccount(ctx context.Context, username string, req LockAccountRequest) (*AdminAccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockAccount", ctx, username, req)
+	ret0, _ := ret[0].(*AdminAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockAccount indicates an expected call of LockAccount.
+func (mr *MockUsecaseHandlerMockRecorder) LockAccount(ctx, username, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockAccount", reflect.TypeOf((*MockUsecaseHandler)(nil).LockAccount), ctx, username, req)
+}
+
 // LoginAccount mocks base method.
 func (m *MockUsecaseHandler) LoginAccount(ctx context.Context, req AccountRequest) (*AccountResponse, error) {
 	m.ctrl.T.Helper()
@@ -63,3 +123,63 @@ func (mr *MockUsecaseHandlerMockRecorder) LoginAccount(ctx, req interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginAccount", reflect.TypeOf((*MockUsecaseHandler)(nil).LoginAccount), ctx, req)
 }
+
+// ResetPassword mocks base method.
+func (m *MockUsecaseHandler) ResetPassword(ctx context.Context, username string) (*PasswordResetResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, username)
+	ret0, _ := ret[0].(*PasswordResetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUsecaseHandlerMockRecorder) ResetPassword(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUsecaseHandler)(nil).ResetPassword), ctx, username)
+}
+
+// SearchAccounts mocks base method.
+func (m *MockUsecaseHandler) SearchAccounts(ctx context.Context, req AccountSearchRequest) (*AccountSearchResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAccounts", ctx, req)
+	ret0, _ := ret[0].(*AccountSearchResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAccounts indicates an expected call of SearchAccounts.
+func (mr *MockUsecaseHandlerMockRecorder) SearchAccounts(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAccounts", reflect.TypeOf((*MockUsecaseHandler)(nil).SearchAccounts), ctx, req)
+}
+
+// SetAccountRoles mocks base method.
+func (m *MockUsecaseHandler) SetAccountRoles(ctx context.Context, username string, req AccountRolesRequest) (*AdminAccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAccountRoles", ctx, username, req)
+	ret0, _ := ret[0].(*AdminAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetAccountRoles indicates an expected call of SetAccountRoles.
+func (mr *MockUsecaseHandlerMockRecorder) SetAccountRoles(ctx, username, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountRoles", reflect.TypeOf((*MockUsecaseHandler)(nil).SetAccountRoles), ctx, username, req)
+}
+
+// UnlockAccount mocks base method.
+func (m *MockUsecaseHandler) UnlockAccount(ctx context.Context, username string) (*AdminAccountResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockAccount", ctx, username)
+	ret0, _ := ret[0].(*AdminAccountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlockAccount indicates an expected call of UnlockAccount.
+func (mr *MockUsecaseHandlerMockRecorder) UnlockAccount(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockAccount", reflect.TypeOf((*MockUsecaseHandler)(nil).UnlockAccount), ctx, username)
+}
@@ -2,14 +2,69 @@ package model
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/ambroseqiu/senao_hw/repository"
 	"github.com/ambroseqiu/senao_hw/util"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
+type auditEntryMatcher struct {
+	eventType string
+	outcome   string
+}
+
+func (m auditEntryMatcher) Matches(x interface{}) bool {
+	entry, ok := x.(AuditEntry)
+	return ok && entry.Type == m.eventType && entry.Outcome == m.outcome
+}
+
+func (m auditEntryMatcher) String() string {
+	return "audit entry " + m.eventType + " " + m.outcome
+}
+
+func auditEntryOf(eventType string, outcome string) gomock.Matcher {
+	return auditEntryMatcher{eventType: eventType, outcome: outcome}
+}
+
+type eventMatcher struct {
+	eventType string
+}
+
+func (m eventMatcher) Matches(x interface{}) bool {
+	event, ok := x.(Event)
+	return ok && event.Type == m.eventType
+}
+
+func (m eventMatcher) String() string {
+	return "event " + m.eventType
+}
+
+func eventOf(eventType string) gomock.Matcher {
+	return eventMatcher{eventType: eventType}
+}
+
+type outboxMessageMatcher struct {
+	eventType string
+}
+
+func (m outboxMessageMatcher) Matches(x interface{}) bool {
+	message, ok := x.(*repository.OutboxMessage)
+	return ok && message.Type == m.eventType && message.IdempotencyKey != ""
+}
+
+func (m outboxMessageMatcher) String() string {
+	return "outbox message " + m.eventType
+}
+
+func outboxMessageOf(eventType string) gomock.Matcher {
+	return outboxMessageMatcher{eventType: eventType}
+}
+
 func TestCreateAccount(t *testing.T) {
 	correctUsername := util.RandomString(8)
 	correctPassword := util.RandomPassword(8)
@@ -17,7 +72,7 @@ func TestCreateAccount(t *testing.T) {
 	testCase := []struct {
 		name             string
 		request          AccountRequest
-		setMockExpection func(mockRepo *repository.MockAccountRepository)
+		setMockExpection func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher)
 		verify           func(rsp *AccountResponse, err error)
 	}{
 		{
@@ -26,8 +81,9 @@ func TestCreateAccount(t *testing.T) {
 				Username: correctUsername,
 				Password: correctPassword,
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
-				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Return(nil)
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
+				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), outboxMessageOf(EventAccountCreated)).Return(nil)
+				mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeSuccess))
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.NoError(t, err)
@@ -41,8 +97,8 @@ func TestCreateAccount(t *testing.T) {
 				Username: util.RandomString(1),
 				Password: correctPassword,
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
-				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
+				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.EqualError(t, err, ErrAccountRequestValidationFailed.Error())
@@ -56,8 +112,8 @@ func TestCreateAccount(t *testing.T) {
 				Username: correctUsername,
 				Password: util.RandomPassword(6),
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
-				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
+				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.EqualError(t, err, ErrAccountRequestValidationFailed.Error())
@@ -71,8 +127,8 @@ func TestCreateAccount(t *testing.T) {
 				Username: correctUsername,
 				Password: "12345678",
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
-				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
+				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.EqualError(t, err, ErrAccountRequestValidationFailed.Error())
@@ -86,8 +142,9 @@ func TestCreateAccount(t *testing.T) {
 				Username: correctUsername,
 				Password: correctPassword,
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
-				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Return(repository.ErrAccountIsDuplicated)
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
+				mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), gomock.Any()).Return(repository.ErrAccountIsDuplicated)
+				mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeFailure))
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.EqualError(t, err, ErrAccountIsAlreadyExisted.Error())
@@ -104,9 +161,11 @@ func TestCreateAccount(t *testing.T) {
 			ctrl := gomock.NewController(t)
 
 			mockRepo := repository.NewMockAccountRepository(ctrl)
-			usecase := NewUsecaseHandler(mockRepo)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			mockEvents := NewMockEventPublisher(ctrl)
+			usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, nil)
 
-			tc.setMockExpection(mockRepo)
+			tc.setMockExpection(mockRepo, mockAudit, mockEvents)
 
 			rsp, err := usecase.CreateAccount(context.Background(), tc.request)
 			tc.verify(rsp, err)
@@ -124,7 +183,7 @@ func TestLoginAccount(t *testing.T) {
 	testCase := []struct {
 		name             string
 		request          AccountRequest
-		setMockExpection func(mockRepo *repository.MockAccountRepository)
+		setMockExpection func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher)
 		verify           func(rsp *AccountResponse, err error)
 	}{
 		{
@@ -133,12 +192,14 @@ func TestLoginAccount(t *testing.T) {
 				Username: correctUsername,
 				Password: correctPassword,
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
 				mockRepo.EXPECT().GetAccount(gomock.Any(), correctUsername).
 					Return(&repository.Account{
 						Username:       correctUsername,
 						HashedPassword: hashedPassword,
 					}, nil)
+				mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+				mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.NoError(t, err)
@@ -152,9 +213,10 @@ func TestLoginAccount(t *testing.T) {
 				Username: correctUsername,
 				Password: correctPassword,
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
 				mockRepo.EXPECT().GetAccount(gomock.Any(), correctUsername).
 					Return(nil, repository.ErrAccountRecordNotFound)
+				mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginAccountNotFound, AuditOutcomeFailure))
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.EqualError(t, err, ErrLoginAccountNotFound.Error())
@@ -168,12 +230,14 @@ func TestLoginAccount(t *testing.T) {
 				Username: correctUsername,
 				Password: util.RandomPassword(8),
 			},
-			setMockExpection: func(mockRepo *repository.MockAccountRepository) {
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
 				mockRepo.EXPECT().GetAccount(gomock.Any(), correctUsername).
 					Return(&repository.Account{
 						Username:       correctUsername,
 						HashedPassword: hashedPassword,
 					}, nil)
+				mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginWrongPassword, AuditOutcomeFailure))
+				mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginFailed))
 			},
 			verify: func(rsp *AccountResponse, err error) {
 				require.EqualError(t, err, ErrLoginWrongPassword.Error())
@@ -190,9 +254,11 @@ func TestLoginAccount(t *testing.T) {
 			ctrl := gomock.NewController(t)
 
 			mockRepo := repository.NewMockAccountRepository(ctrl)
-			usecase := NewUsecaseHandler(mockRepo)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			mockEvents := NewMockEventPublisher(ctrl)
+			usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, nil)
 
-			tc.setMockExpection(mockRepo)
+			tc.setMockExpection(mockRepo, mockAudit, mockEvents)
 
 			rsp, err := usecase.LoginAccount(context.Background(), tc.request)
 			tc.verify(rsp, err)
@@ -210,13 +276,19 @@ func TestLoginFailedAttemptFiveTime(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	mockEvents := NewMockEventPublisher(ctrl)
 
-	usecase := NewUsecaseHandler(mockRepo)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, nil)
 
 	mockRepo.EXPECT().GetAccount(gomock.Any(), username).Times(5).Return(&repository.Account{
 		Username:       username,
 		HashedPassword: hashedPassword,
 	}, nil)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginWrongPassword, AuditOutcomeFailure)).Times(5)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginBlocked, AuditOutcomeFailure)).Times(1)
+	mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginFailed)).Times(5)
+	mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLocked)).Times(1)
 
 	req := AccountRequest{
 		Username: username,
@@ -235,3 +307,552 @@ func TestLoginFailedAttemptFiveTime(t *testing.T) {
 	require.False(t, rsp.Success)
 	require.Equal(t, ErrLoginAttemptBlocked.Error(), rsp.Reason)
 }
+
+func TestLoginBlockedAgainAfterBlockExpired(t *testing.T) {
+	username := util.RandomString(10)
+	hashedPassword, err := util.HashedPassword(util.RandomPassword(10))
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	mockEvents := NewMockEventPublisher(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, nil).(*usecaseHandler)
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), username).Times(6).
+		Return(&repository.Account{Username: username, HashedPassword: hashedPassword}, nil)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginWrongPassword, AuditOutcomeFailure)).Times(6)
+	mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginFailed)).Times(6)
+	// Once when the limit is reached, and again when the first failure after the block blocks again.
+	mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLocked)).Times(2)
+
+	req := AccountRequest{Username: username, Password: util.RandomPassword(10)}
+	for i := 1; i <= maxFailedAttempt; i++ {
+		_, err := usecase.LoginAccount(context.Background(), req)
+		require.Equal(t, ErrLoginWrongPassword, err)
+	}
+	require.Equal(t, ErrLoginAttemptBlocked, usecase.loginValidate(username))
+
+	// The block expires.
+	attempt := usecase.loginAC[username]
+	attempt.LastTime = time.Now().Add(-2 * timeBlockLoginAttempt)
+	usecase.loginAC[username] = attempt
+	_, err = usecase.LoginAccount(context.Background(), req)
+	require.Equal(t, ErrLoginWrongPassword, err)
+	require.Equal(t, ErrLoginAttemptBlocked, usecase.loginValidate(username))
+}
+
+func TestDeleteAccount(t *testing.T) {
+	username := util.RandomString(8)
+	account := &repository.Account{
+		ID:       uuid.New(),
+		Username: username,
+	}
+
+	testCase := []struct {
+		name             string
+		setMockExpection func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher)
+		verify           func(rsp *AccountResponse, err error)
+	}{
+		{
+			name: "ok",
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
+				mockRepo.EXPECT().GetAccount(gomock.Any(), username).Return(account, nil)
+				mockRepo.EXPECT().DeleteAccount(gomock.Any(), account, outboxMessageOf(EventAccountDeleted)).
+					DoAndReturn(func(ctx context.Context, account *repository.Account, messages ...*repository.OutboxMessage) error {
+						event := Event{}
+						require.NoError(t, json.Unmarshal([]byte(messages[0].Payload), &event))
+						require.Equal(t, event.ID, messages[0].IdempotencyKey)
+						require.Equal(t, account.ID.String(), event.Data.AccountID)
+						require.Equal(t, username, event.Data.Username)
+						return nil
+					})
+				mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountDeleted, AuditOutcomeSuccess))
+			},
+			verify: func(rsp *AccountResponse, err error) {
+				require.NoError(t, err)
+				require.True(t, rsp.Success)
+			},
+		},
+		{
+			name: "account not found",
+			setMockExpection: func(mockRepo *repository.MockAccountRepository, mockAudit *MockAuditUsecase, mockEvents *MockEventPublisher) {
+				mockRepo.EXPECT().GetAccount(gomock.Any(), username).Return(nil, repository.ErrAccountRecordNotFound)
+				mockRepo.EXPECT().DeleteAccount(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockEvents.EXPECT().Publish(gomock.Any(), gomock.Any()).Times(0)
+			},
+			verify: func(rsp *AccountResponse, err error) {
+				require.EqualError(t, err, ErrAccountNotFound.Error())
+				require.False(t, rsp.Success)
+				require.Equal(t, ErrAccountNotFound.Error(), rsp.Reason)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockRepo := repository.NewMockAccountRepository(ctrl)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			mockEvents := NewMockEventPublisher(ctrl)
+			usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, nil)
+
+			tc.setMockExpection(mockRepo, mockAudit, mockEvents)
+
+			rsp, err := usecase.DeleteAccount(context.Background(), username)
+			tc.verify(rsp, err)
+		})
+	}
+}
+
+func TestCreateAccountWithHooks(t *testing.T) {
+	request := AccountRequest{
+		Username: util.RandomString(8),
+		Password: util.RandomPassword(8),
+	}
+
+	t.Run("denied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := repository.NewMockAccountRepository(ctrl)
+		mockAudit := NewMockAuditUsecase(ctrl)
+		hooks := Hooks{}
+		require.NoError(t, hooks.Register(HookPreCreate, NewReservedUsernameHook(request.Username)))
+		usecase := NewUsecaseHandler(mockRepo, mockAudit, NewMockEventPublisher(ctrl), hooks, nil, nil, nil)
+
+		mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeFailure))
+
+		rsp, err := usecase.CreateAccount(context.Background(), request)
+		require.ErrorIs(t, err, ErrHookDenied)
+		require.False(t, rsp.Success)
+		require.Equal(t, "Username is reserved", rsp.Reason)
+	})
+
+	t.Run("enriched", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := repository.NewMockAccountRepository(ctrl)
+		mockAudit := NewMockAuditUsecase(ctrl)
+		hooks := Hooks{}
+		require.NoError(t, hooks.Register(HookPreCreate, staticHook(&HookResult{Metadata: map[string]string{"source": "partner"}}, nil)))
+		require.NoError(t, hooks.Register(HookPostCreate, staticHook(&HookResult{Metadata: map[string]string{"crm_id": "42"}}, nil)))
+		usecase := NewUsecaseHandler(mockRepo, mockAudit, NewMockEventPublisher(ctrl), hooks, nil, nil, nil)
+
+		mockRepo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, account *repository.Account, messages ...*repository.OutboxMessage) error {
+				require.JSONEq(t, `{"source":"partner"}`, account.Metadata)
+				return nil
+			})
+		mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeSuccess))
+		mockRepo.EXPECT().UpdateAccountMetadata(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, account *repository.Account) error {
+				require.JSONEq(t, `{"source":"partner","crm_id":"42"}`, account.Metadata)
+				return nil
+			})
+
+		rsp, err := usecase.CreateAccount(context.Background(), request)
+		require.NoError(t, err)
+		require.True(t, rsp.Success)
+	})
+}
+
+func TestLoginAccountWithHooks(t *testing.T) {
+	username := util.RandomString(8)
+	password := util.RandomPassword(8)
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	request := AccountRequest{
+		Username: username,
+		Password: password,
+	}
+
+	t.Run("denied before password check", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := repository.NewMockAccountRepository(ctrl)
+		mockAudit := NewMockAuditUsecase(ctrl)
+		mockEvents := NewMockEventPublisher(ctrl)
+		hooks := Hooks{}
+		require.NoError(t, hooks.Register(HookPreLogin, staticHook(&HookResult{Deny: true, Reason: "Outside office hours"}, nil)))
+		usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, hooks, nil, nil, nil)
+
+		mockRepo.EXPECT().GetAccount(gomock.Any(), username).
+			Return(&repository.Account{ID: uuid.New(), Username: username, HashedPassword: "not checked"}, nil)
+		mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+		mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginFailed))
+
+		rsp, err := usecase.LoginAccount(context.Background(), request)
+		require.ErrorIs(t, err, ErrHookDenied)
+		require.False(t, rsp.Success)
+		require.Equal(t, "Outside office hours", rsp.Reason)
+	})
+
+	t.Run("claims and metadata", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := repository.NewMockAccountRepository(ctrl)
+		mockAudit := NewMockAuditUsecase(ctrl)
+		mockEvents := NewMockEventPublisher(ctrl)
+		hooks := Hooks{}
+		require.NoError(t, hooks.Register(HookPreLogin, staticHook(&HookResult{Metadata: map[string]string{"last_ip": "10.0.0.1"}}, nil)))
+		require.NoError(t, hooks.Register(HookPostLogin, staticHook(&HookResult{Claims: map[string]interface{}{"tier": "gold"}}, nil)))
+		usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, hooks, nil, nil, nil)
+
+		mockRepo.EXPECT().GetAccount(gomock.Any(), username).
+			Return(&repository.Account{ID: uuid.New(), Username: username, HashedPassword: hashedPassword}, nil)
+		mockRepo.EXPECT().UpdateAccountMetadata(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, account *repository.Account) error {
+				require.JSONEq(t, `{"last_ip":"10.0.0.1"}`, account.Metadata)
+				return nil
+			})
+		mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+		mockEvents.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+
+		rsp, err := usecase.LoginAccount(context.Background(), request)
+		require.NoError(t, err)
+		require.True(t, rsp.Success)
+		require.Equal(t, map[string]interface{}{"tier": "gold"}, rsp.Claims)
+	})
+}
+
+func TestLoginServiceAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, NewMockEventPublisher(ctrl), nil, nil, nil, nil)
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "billing").
+		Return(&repository.Account{ID: uuid.New(), Username: "billing", Type: repository.AccountTypeService}, nil)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+	rsp, err := usecase.LoginAccount(context.Background(), AccountRequest{Username: "billing", Password: "Passw0rd"})
+	require.ErrorIs(t, err, ErrLoginServiceAccount)
+	require.False(t, rsp.Success)
+	require.Equal(t, ErrLoginServiceAccount.Error(), rsp.Reason)
+}
+
+func TestLoginWithoutPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, NewMockEventPublisher(ctrl), nil, nil, nil, nil)
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "contractor").
+		Return(&repository.Account{ID: uuid.New(), Username: "contractor", Type: repository.AccountTypeUser}, nil)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+	rsp, err := usecase.LoginAccount(context.Background(), AccountRequest{Username: "contractor", Password: "Passw0rd"})
+	require.ErrorIs(t, err, ErrLoginNoPassword)
+	require.False(t, rsp.Success)
+}
+
+func TestLoginVoucher(t *testing.T) {
+	voucher := &repository.Account{ID: uuid.New(), Username: "ABCDE23456", Type: repository.AccountTypeVoucher, Roles: "guest"}
+	expiresAt := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(repo *repository.MockAccountRepository, vouchers *MockVoucherRedeemer, audit *MockAuditUsecase, events *MockEventPublisher)
+		check      func(t *testing.T, rsp *AccountResponse, err error)
+	}{
+		{
+			name: "CodeAsPrinted",
+			buildStubs: func(repo *repository.MockAccountRepository, vouchers *MockVoucherRedeemer, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().GetAccount(gomock.Any(), "abcde-23456").Return(nil, repository.ErrAccountRecordNotFound)
+				repo.EXPECT().GetAccount(gomock.Any(), "ABCDE23456").Return(voucher, nil)
+				vouchers.EXPECT().Redeem(gomock.Any(), voucher).Return(&expiresAt, nil)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+				events.EXPECT().Publish(gomock.Any(), gomock.Any())
+			},
+			check: func(t *testing.T, rsp *AccountResponse, err error) {
+				require.NoError(t, err)
+				require.True(t, rsp.Success)
+				require.Equal(t, "ABCDE23456", rsp.Username)
+				require.Equal(t, []string{"guest"}, rsp.Roles)
+				require.Equal(t, &expiresAt, rsp.ExpiresAt)
+			},
+		},
+		{
+			name: "Expired",
+			buildStubs: func(repo *repository.MockAccountRepository, vouchers *MockVoucherRedeemer, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().GetAccount(gomock.Any(), "abcde-23456").Return(nil, repository.ErrAccountRecordNotFound)
+				repo.EXPECT().GetAccount(gomock.Any(), "ABCDE23456").Return(voucher, nil)
+				vouchers.EXPECT().Redeem(gomock.Any(), voucher).Return(nil, ErrVoucherExpired)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+				events.EXPECT().Publish(gomock.Any(), gomock.Any())
+			},
+			check: func(t *testing.T, rsp *AccountResponse, err error) {
+				require.ErrorIs(t, err, ErrVoucherExpired)
+				require.False(t, rsp.Success)
+				require.Equal(t, ErrVoucherExpired.Error(), rsp.Reason)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := repository.NewMockAccountRepository(ctrl)
+			mockVouchers := NewMockVoucherRedeemer(ctrl)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			mockEvents := NewMockEventPublisher(ctrl)
+			tc.buildStubs(mockRepo, mockVouchers, mockAudit, mockEvents)
+			usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, mockVouchers)
+
+			rsp, err := usecase.LoginAccount(context.Background(), AccountRequest{Username: "abcde-23456", Password: "ABCDE-23456"})
+			tc.check(t, rsp, err)
+		})
+	}
+}
+
+func TestLoginSecondFactor(t *testing.T) {
+	hashedPassword, err := util.HashedPassword("Passw0rd")
+	require.NoError(t, err)
+	account := &repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser, HashedPassword: hashedPassword}
+
+	testCase := []struct {
+		name      string
+		buildStub func(second *MockSecondFactor, audit *MockAuditUsecase, events *MockEventPublisher)
+		err       error
+	}{
+		{
+			name: "ok",
+			buildStub: func(second *MockSecondFactor, audit *MockAuditUsecase, events *MockEventPublisher) {
+				second.EXPECT().Verify(gomock.Any(), account, "123456").Return(nil)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+				events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+			},
+		},
+		{
+			name: "code required",
+			buildStub: func(second *MockSecondFactor, audit *MockAuditUsecase, events *MockEventPublisher) {
+				second.EXPECT().Verify(gomock.Any(), account, "123456").Return(ErrLoginOTPRequired)
+			},
+			err: ErrLoginOTPRequired,
+		},
+		{
+			name: "wrong code",
+			buildStub: func(second *MockSecondFactor, audit *MockAuditUsecase, events *MockEventPublisher) {
+				second.EXPECT().Verify(gomock.Any(), account, "123456").Return(ErrLoginWrongOTP)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginWrongOTP, AuditOutcomeFailure))
+				events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginFailed))
+			},
+			err: ErrLoginWrongOTP,
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := repository.NewMockAccountRepository(ctrl)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			mockEvents := NewMockEventPublisher(ctrl)
+			mockSecond := NewMockSecondFactor(ctrl)
+			usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, mockSecond, nil, nil)
+
+			mockRepo.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil)
+			tc.buildStub(mockSecond, mockAudit, mockEvents)
+
+			rsp, err := usecase.LoginAccount(context.Background(), AccountRequest{Username: "alice", Password: "Passw0rd", OTP: "123456"})
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.err == nil, rsp.Success)
+		})
+	}
+}
+
+func TestLoginDirectory(t *testing.T) {
+	mirrored := &repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser,
+		Roles: "staff", Source: repository.AccountSourceLDAP}
+	user := &DirectoryUser{Username: "alice", DN: "uid=alice,ou=people,dc=example,dc=com", Roles: []string{"admin", "staff"}}
+
+	testCase := []struct {
+		name      string
+		username  string
+		buildStub func(repo *repository.MockAccountRepository, directory *MockPasswordAuthenticator, audit *MockAuditUsecase, events *MockEventPublisher)
+		err       error
+	}{
+		{
+			name: "first login creates the account",
+			buildStub: func(repo *repository.MockAccountRepository, directory *MockPasswordAuthenticator, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().GetAccount(gomock.Any(), "alice").Return(nil, repository.ErrAccountRecordNotFound)
+				directory.EXPECT().Authenticate(gomock.Any(), "alice", "Passw0rd").Return(user, nil)
+				repo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), outboxMessageOf(EventAccountCreated)).
+					DoAndReturn(func(_ context.Context, account *repository.Account, _ ...*repository.OutboxMessage) error {
+						require.Equal(t, "admin staff", account.Roles)
+						require.Equal(t, repository.AccountSourceLDAP, account.Source)
+						require.Empty(t, account.HashedPassword)
+						return nil
+					})
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAccountCreated, AuditOutcomeSuccess))
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+				events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+			},
+		},
+		{
+			name: "not in the directory",
+			buildStub: func(repo *repository.MockAccountRepository, directory *MockPasswordAuthenticator, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().GetAccount(gomock.Any(), "alice").Return(nil, repository.ErrAccountRecordNotFound)
+				directory.EXPECT().Authenticate(gomock.Any(), "alice", "Passw0rd").Return(nil, ErrDirectoryUserNotFound)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginAccountNotFound, AuditOutcomeFailure))
+			},
+			err: ErrLoginAccountNotFound,
+		},
+		{
+			name: "wrong password before the first login",
+			buildStub: func(repo *repository.MockAccountRepository, directory *MockPasswordAuthenticator, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().GetAccount(gomock.Any(), "alice").Return(nil, repository.ErrAccountRecordNotFound)
+				directory.EXPECT().Authenticate(gomock.Any(), "alice", "Passw0rd").Return(nil, ErrLoginWrongPassword)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginWrongPassword, AuditOutcomeFailure))
+			},
+			err: ErrLoginWrongPassword,
+		},
+		{
+			name: "mirrored account gets its roles",
+			buildStub: func(repo *repository.MockAccountRepository, directory *MockPasswordAuthenticator, audit *MockAuditUsecase, events *MockEventPublisher) {
+				account := *mirrored
+				repo.EXPECT().GetAccount(gomock.Any(), "alice").Return(&account, nil)
+				directory.EXPECT().Authenticate(gomock.Any(), "alice", "Passw0rd").Return(user, nil)
+				repo.EXPECT().UpdateAccountRoles(gomock.Any(), &account).Return(nil)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditRolesChanged, AuditOutcomeSuccess))
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+				events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+			},
+		},
+		{
+			name: "mirrored account with a wrong password",
+			buildStub: func(repo *repository.MockAccountRepository, directory *MockPasswordAuthenticator, audit *MockAuditUsecase, events *MockEventPublisher) {
+				account := *mirrored
+				repo.EXPECT().GetAccount(gomock.Any(), "alice").Return(&account, nil)
+				directory.EXPECT().Authenticate(gomock.Any(), "alice", "Passw0rd").Return(nil, ErrLoginWrongPassword)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginWrongPassword, AuditOutcomeFailure))
+				events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginFailed))
+			},
+			err: ErrLoginWrongPassword,
+		},
+		{
+			name:     "local account of the same name",
+			username: "Alice",
+			buildStub: func(repo *repository.MockAccountRepository, directory *MockPasswordAuthenticator, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().GetAccount(gomock.Any(), "Alice").Return(nil, repository.ErrAccountRecordNotFound)
+				directory.EXPECT().Authenticate(gomock.Any(), "Alice", "Passw0rd").Return(user, nil)
+				repo.EXPECT().CreateAccount(gomock.Any(), gomock.Any(), gomock.Any()).Return(repository.ErrAccountIsDuplicated)
+				repo.EXPECT().GetAccount(gomock.Any(), "alice").
+					Return(&repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser, HashedPassword: "hash"}, nil)
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+			},
+			err: ErrLoginDirectoryConflict,
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := repository.NewMockAccountRepository(ctrl)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			mockEvents := NewMockEventPublisher(ctrl)
+			mockDirectory := NewMockPasswordAuthenticator(ctrl)
+			usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, mockDirectory, nil)
+			tc.buildStub(mockRepo, mockDirectory, mockAudit, mockEvents)
+
+			username := "alice"
+			if tc.username != "" {
+				username = tc.username
+			}
+			rsp, err := usecase.LoginAccount(context.Background(), AccountRequest{Username: username, Password: "Passw0rd"})
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.err == nil, rsp.Success)
+			if tc.err == nil {
+				require.Equal(t, "alice", rsp.Username)
+				require.Equal(t, []string{"admin", "staff"}, rsp.Roles)
+			}
+		})
+	}
+}
+
+func TestLoginLockedAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockAccountRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	usecase := NewUsecaseHandler(mockRepo, mockAudit, NewMockEventPublisher(ctrl), nil, nil, nil, nil)
+	lockedAt := time.Now()
+
+	mockRepo.EXPECT().GetAccount(gomock.Any(), "alice").
+		Return(&repository.Account{ID: uuid.New(), Username: "alice", Type: repository.AccountTypeUser, HashedPassword: "not checked", LockedAt: &lockedAt}, nil)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginDenied, AuditOutcomeFailure))
+
+	rsp, err := usecase.LoginAccount(context.Background(), AccountRequest{Username: "alice", Password: "Passw0rd"})
+	require.ErrorIs(t, err, ErrLoginAccountLocked)
+	require.False(t, rsp.Success)
+	require.Equal(t, ErrLoginAccountLocked.Error(), rsp.Reason)
+}
+
+func TestLoginPasswordResetRequired(t *testing.T) {
+	hashedPassword, err := util.HashedPassword("Temp0rary")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		newPassword string
+		buildStubs  func(repo *repository.MockAccountRepository, audit *MockAuditUsecase, events *MockEventPublisher)
+		check       func(t *testing.T, rsp *AccountResponse, err error)
+	}{
+		{
+			name: "NewPasswordMissing",
+			buildStubs: func(repo *repository.MockAccountRepository, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().UpdateAccountPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp *AccountResponse, err error) {
+				require.Equal(t, ErrLoginPasswordResetRequired, err)
+				require.False(t, rsp.Success)
+			},
+		},
+		{
+			name:        "NewPasswordInvalid",
+			newPassword: "short",
+			buildStubs: func(repo *repository.MockAccountRepository, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().UpdateAccountPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp *AccountResponse, err error) {
+				require.Equal(t, ErrAccountRequestValidationFailed, err)
+				require.Equal(t, ErrPasswordIsTooShort.Error(), rsp.Reason)
+			},
+		},
+		{
+			name:        "NewPasswordSet",
+			newPassword: "N3wPassword",
+			buildStubs: func(repo *repository.MockAccountRepository, audit *MockAuditUsecase, events *MockEventPublisher) {
+				repo.EXPECT().UpdateAccountPassword(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, account *repository.Account) error {
+					require.False(t, account.PasswordResetRequired)
+					require.NoError(t, util.CheckPassword("N3wPassword", account.HashedPassword))
+					return nil
+				})
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditPasswordChanged, AuditOutcomeSuccess))
+				audit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditLoginSucceeded, AuditOutcomeSuccess))
+				events.EXPECT().Publish(gomock.Any(), eventOf(EventAccountLoginSucceeded))
+			},
+			check: func(t *testing.T, rsp *AccountResponse, err error) {
+				require.NoError(t, err)
+				require.True(t, rsp.Success)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := repository.NewMockAccountRepository(ctrl)
+			mockAudit := NewMockAuditUsecase(ctrl)
+			mockEvents := NewMockEventPublisher(ctrl)
+			usecase := NewUsecaseHandler(mockRepo, mockAudit, mockEvents, nil, nil, nil, nil)
+
+			mockRepo.EXPECT().GetAccount(gomock.Any(), "alice").Return(&repository.Account{ID: uuid.New(), Username: "alice",
+				Type: repository.AccountTypeUser, HashedPassword: hashedPassword, PasswordResetRequired: true}, nil)
+			tc.buildStubs(mockRepo, mockAudit, mockEvents)
+
+			rsp, err := usecase.LoginAccount(context.Background(), AccountRequest{Username: "alice", Password: "Temp0rary", NewPassword: tc.newPassword})
+			tc.check(t, rsp, err)
+		})
+	}
+}
@@ -66,3 +66,29 @@ func validPassword(password string) error {
 	}
 	return nil
 }
+
+func (req *ServiceAccountRequest) Validate() error {
+	if !isValidateFormat(req.Username) {
+		return ErrInvalidUsernameFormat
+	}
+	if len(req.Username) > maxUsernameLength {
+		return ErrUsernameIsTooLarge
+	}
+	if len(req.Username) < minUsernameLength {
+		return ErrUsernameIsTooShort
+	}
+	return nil
+}
+
+func (req *SetPasswordRequest) Validate() error {
+	if !isValidateFormat(req.Password) {
+		return ErrInvalidPasswordFormat
+	}
+	if len(req.Password) > maxPasswordLength {
+		return ErrPasswordIsTooLarge
+	}
+	if len(req.Password) < minPasswordLength {
+		return ErrPasswordIsTooShort
+	}
+	return validPassword(req.Password)
+}
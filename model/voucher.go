@@ -0,0 +1,336 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Voucher codes leave out 0, 1, I and O, which are easily mistaken for each other when typed from paper.
+	voucherCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	voucherCodeLength   = 10
+	voucherCodeAttempts = 3
+	defaultVoucherRole  = "guest"
+	bytesPerMB          = 1 << 20
+
+	VoucherStatusUnused  = "unused"
+	VoucherStatusActive  = "active"
+	VoucherStatusExpired = "expired"
+)
+
+var (
+	ErrVoucherExpired       = errors.New("Voucher has expired")
+	ErrVoucherDataUsed      = errors.New("Voucher has used up its data")
+	ErrVoucherNoSelfService = errors.New("Vouchers cannot sign in to manage an account")
+	ErrInvalidVoucherLimits = errors.New("Voucher needs a duration or a data limit")
+	ErrVoucherBatchNotFound = errors.New("Voucher batch is not found")
+)
+
+// VoucherRedeemer checks the voucher of an account when it logs in.
+type VoucherRedeemer interface {
+	Redeem(ctx context.Context, account *repository.Account) (*time.Time, error)
+}
+
+// VoucherUsecase creates guest vouchers and keeps them within their limits. A voucher is an account of
+// type voucher whose username is its code; it logs in with the code as its password too.
+type VoucherUsecase interface {
+	CreateVouchers(ctx context.Context, req CreateVouchersRequest) (*VoucherBatchResponse, error)
+	ListVouchers(ctx context.Context, batch string) (*VoucherBatchResponse, error)
+	// Redeem checks the voucher of the account still works, and starts its validity on first use. It
+	// returns when the voucher expires, nil when it has no time limit.
+	Redeem(ctx context.Context, account *repository.Account) (*time.Time, error)
+	// Enforce disconnects the online sessions of vouchers that expired or used up their data.
+	Enforce(ctx context.Context) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type voucherUsecase struct {
+	repo       repository.VoucherRepository
+	sessions   repository.AccountingRepository
+	accounting AccountingUsecase
+	audit      AuditUsecase
+}
+
+// NewVoucherUsecase checks data limits against the sessions RADIUS accounting reported, and ends the
+// sessions of spent vouchers through accounting.
+func NewVoucherUsecase(repo repository.VoucherRepository, sessions repository.AccountingRepository,
+	accounting AccountingUsecase, audit AuditUsecase) VoucherUsecase {
+	return &voucherUsecase{
+		repo:       repo,
+		sessions:   sessions,
+		accounting: accounting,
+		audit:      audit,
+	}
+}
+
+func (v *voucherUsecase) CreateVouchers(ctx context.Context, req CreateVouchersRequest) (*VoucherBatchResponse, error) {
+	if req.DurationMinutes == 0 && req.DataLimitMB == 0 {
+		return nil, ErrInvalidVoucherLimits
+	}
+	now := time.Now()
+	batch := req.Batch
+	if batch == "" {
+		batch = now.UTC().Format("20060102-150405")
+	}
+	role := req.Role
+	if role == "" {
+		role = defaultVoucherRole
+	}
+	var redeemBy *time.Time
+	if req.RedeemInDays > 0 {
+		deadline := now.AddDate(0, 0, req.RedeemInDays)
+		redeemBy = &deadline
+	}
+
+	// A new code could be taken by an existing account, the batch is then created with other codes.
+	var accounts []*repository.Account
+	var vouchers []*repository.Voucher
+	for attempt := 0; ; attempt++ {
+		accounts, vouchers = nil, nil
+		for i := 0; i < req.Count; i++ {
+			code, err := newVoucherCode()
+			if err != nil {
+				return nil, err
+			}
+			account := &repository.Account{ID: uuid.New(), Username: code, Type: repository.AccountTypeVoucher, Roles: role}
+			accounts = append(accounts, account)
+			vouchers = append(vouchers, &repository.Voucher{
+				AccountID: account.ID,
+				Batch:     batch,
+				Duration:  int64(req.DurationMinutes) * 60,
+				DataLimit: req.DataLimitMB * bytesPerMB,
+				RedeemBy:  redeemBy,
+				ExpiresAt: redeemBy,
+				CreatedAt: now,
+			})
+		}
+		err := v.repo.CreateVouchers(ctx, accounts, vouchers)
+		if err == nil {
+			break
+		}
+		if err != repository.ErrAccountIsDuplicated || attempt+1 == voucherCodeAttempts {
+			return nil, err
+		}
+	}
+
+	v.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "voucher_batch:" + batch,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  fmt.Sprintf("create %d vouchers", req.Count),
+	})
+	rsp := &VoucherBatchResponse{Batch: batch, Vouchers: make([]VoucherResponse, 0, len(vouchers))}
+	for i := range vouchers {
+		rsp.Vouchers = append(rsp.Vouchers, voucherResponse(accounts[i].Username, vouchers[i], now))
+	}
+	return rsp, nil
+}
+
+func (v *voucherUsecase) ListVouchers(ctx context.Context, batch string) (*VoucherBatchResponse, error) {
+	vouchers, err := v.repo.ListVouchers(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(vouchers) == 0 {
+		return nil, ErrVoucherBatchNotFound
+	}
+	now := time.Now()
+	rsp := &VoucherBatchResponse{Batch: batch, Vouchers: make([]VoucherResponse, 0, len(vouchers))}
+	for i := range vouchers {
+		rsp.Vouchers = append(rsp.Vouchers, voucherResponse(vouchers[i].Code, &vouchers[i].Voucher, now))
+	}
+	return rsp, nil
+}
+
+func (v *voucherUsecase) Redeem(ctx context.Context, account *repository.Account) (*time.Time, error) {
+	voucher, err := v.repo.GetVoucher(ctx, account.ID)
+	if err != nil {
+		if err == repository.ErrVoucherNotFound {
+			return nil, ErrVoucherExpired
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if expired(voucher, now) {
+		return nil, ErrVoucherExpired
+	}
+	if voucher.ActivatedAt == nil {
+		expiresAt := voucher.ExpiresAt
+		if voucher.Duration > 0 {
+			end := now.Add(time.Duration(voucher.Duration) * time.Second)
+			expiresAt = &end
+		}
+		activated, err := v.repo.ActivateVoucher(ctx, account.ID, now, expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if activated {
+			voucher.ActivatedAt, voucher.ExpiresAt = &now, expiresAt
+		} else {
+			// Another login activated it first.
+			if voucher, err = v.repo.GetVoucher(ctx, account.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	spent, err := v.dataUsed(ctx, account.ID, voucher)
+	if err != nil {
+		return nil, err
+	}
+	if spent {
+		return nil, ErrVoucherDataUsed
+	}
+	return voucher.ExpiresAt, nil
+}
+
+func (v *voucherUsecase) Enforce(ctx context.Context) error {
+	sessions, err := v.sessions.ListOnlineSessions(ctx)
+	if err != nil {
+		return err
+	}
+	// Accounts that are not vouchers, or whose voucher still works, are looked up once.
+	spent := make(map[uuid.UUID]bool)
+	now := time.Now()
+	for i := range sessions {
+		accountID := sessions[i].AccountID
+		if accountID == nil {
+			continue
+		}
+		done, ok := spent[*accountID]
+		if !ok {
+			voucher, err := v.repo.GetVoucher(ctx, *accountID)
+			if err != nil && err != repository.ErrVoucherNotFound {
+				return err
+			}
+			if voucher != nil {
+				done = expired(voucher, now)
+				if !done {
+					if done, err = v.dataUsed(ctx, *accountID, voucher); err != nil {
+						return err
+					}
+				}
+			}
+			spent[*accountID] = done
+		}
+		if !done {
+			continue
+		}
+		err := v.accounting.Disconnect(ctx, sessions[i].ID.String())
+		if err == ErrDynamicAuthorizationDisabled {
+			return err
+		}
+		if err != nil && err != ErrAccountingSessionStopped {
+			log.Warn().Err(err).Str("username", sessions[i].Username).Msg("failed to disconnect spent voucher")
+		}
+	}
+	return nil
+}
+
+// Run enforces the voucher limits every interval until ctx is done.
+func (v *voucherUsecase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := v.Enforce(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to enforce voucher limits")
+			}
+		}
+	}
+}
+
+// dataUsed adds up what the sessions of the voucher transferred since it was first used.
+func (v *voucherUsecase) dataUsed(ctx context.Context, accountID uuid.UUID, voucher *repository.Voucher) (bool, error) {
+	if voucher.DataLimit == 0 || voucher.ActivatedAt == nil {
+		return false, nil
+	}
+	sessions, err := v.sessions.ListAccountingSessions(ctx, accountID, *voucher.ActivatedAt)
+	if err != nil {
+		return false, err
+	}
+	var used int64
+	for i := range sessions {
+		used += sessions[i].InputOctets + sessions[i].OutputOctets
+	}
+	return used >= voucher.DataLimit, nil
+}
+
+func expired(voucher *repository.Voucher, now time.Time) bool {
+	return voucher.ExpiresAt != nil && !now.Before(*voucher.ExpiresAt)
+}
+
+// NormalizeVoucherCode spells a code the way it is stored, as guests may type it in lower case or with
+// the separators it is printed with.
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// IsVoucherCode tells whether a normalized code could be one newVoucherCode made.
+func IsVoucherCode(code string) bool {
+	if len(code) != voucherCodeLength {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if strings.IndexByte(voucherCodeAlphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// voucherCodeMatches compares the code a guest typed with the code of the voucher.
+func voucherCodeMatches(typed string, code string) bool {
+	return subtle.ConstantTimeCompare([]byte(NormalizeVoucherCode(typed)), []byte(code)) == 1
+}
+
+// FormatVoucherCode groups a code in fives to be read off paper.
+func FormatVoucherCode(code string) string {
+	if len(code) <= 5 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+func newVoucherCode() (string, error) {
+	max := big.NewInt(int64(len(voucherCodeAlphabet)))
+	code := make([]byte, voucherCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = voucherCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func voucherResponse(code string, voucher *repository.Voucher, now time.Time) VoucherResponse {
+	status := VoucherStatusUnused
+	if expired(voucher, now) {
+		status = VoucherStatusExpired
+	} else if voucher.ActivatedAt != nil {
+		status = VoucherStatusActive
+	}
+	return VoucherResponse{
+		Code:            code,
+		Status:          status,
+		DurationMinutes: voucher.Duration / 60,
+		DataLimitMB:     voucher.DataLimit / bytesPerMB,
+		RedeemBy:        voucher.RedeemBy,
+		ActivatedAt:     voucher.ActivatedAt,
+		ExpiresAt:       voucher.ExpiresAt,
+	}
+}
@@ -0,0 +1,146 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: voucher.go
+
+// Package model is a generated GoMock package.
+package model
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	repository "github.com/ambroseqiu/senao_hw/repository"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockVoucherRedeemer is a mock of VoucherRedeemer interface.
+type MockVoucherRedeemer struct {
+	ctrl     *gomock.Controller
+	recorder *MockVoucherRedeemerMockRecorder
+}
+
+// MockVoucherRedeemerMockRecorder is the mock recorder for MockVoucherRedeemer.
+type MockVoucherRedeemerMockRecorder struct {
+	mock *MockVoucherRedeemer
+}
+
+// NewMockVoucherRedeemer creates a new mock instance.
+func NewMockVoucherRedeemer(ctrl *gomock.Controller) *MockVoucherRedeemer {
+	mock := &MockVoucherRedeemer{ctrl: ctrl}
+	mock.recorder = &MockVoucherRedeemerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVoucherRedeemer) EXPECT() *MockVoucherRedeemerMockRecorder {
+	return m.recorder
+}
+
+// Redeem mocks base method.
+func (m *MockVoucherRedeemer) Redeem(ctx context.Context, account *repository.Account) (*time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeem", ctx, account)
+	ret0, _ := ret[0].(*time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Redeem indicates an expected call of Redeem.
+func (mr *MockVoucherRedeemerMockRecorder) Redeem(ctx, account interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeem", reflect.TypeOf((*MockVoucherRedeemer)(nil).Redeem), ctx, account)
+}
+
+// MockVoucherUsecase is a mock of VoucherUsecase interface.
+type MockVoucherUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockVoucherUsecaseMockRecorder
+}
+
+// MockVoucherUsecaseMockRecorder is the mock recorder for MockVoucherUsecase.
+type MockVoucherUsecaseMockRecorder struct {
+	mock *MockVoucherUsecase
+}
+
+// NewMockVoucherUsecase creates a new mock instance.
+func NewMockVoucherUsecase(ctrl *gomock.Controller) *MockVoucherUsecase {
+	mock := &MockVoucherUsecase{ctrl: ctrl}
+	mock.recorder = &MockVoucherUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVoucherUsecase) EXPECT() *MockVoucherUsecaseMockRecorder {
+	return m.recorder
+}
+
+// CreateVouchers mocks base method.
+func (m *MockVoucherUsecase) CreateVouchers(ctx context.Context, req CreateVouchersRequest) (*VoucherBatchResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVouchers", ctx, req)
+	ret0, _ := ret[0].(*VoucherBatchResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVouchers indicates an expected call of CreateVouchers.
+func (mr *MockVoucherUsecaseMockRecorder) CreateVouchers(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVouchers", reflect.TypeOf((*MockVoucherUsecase)(nil).CreateVouchers), ctx, req)
+}
+
+// Enforce mocks base method.
+func (m *MockVoucherUsecase) Enforce(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enforce", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enforce indicates an expected call of Enforce.
+func (mr *MockVoucherUsecaseMockRecorder) Enforce(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enforce", reflect.TypeOf((*MockVoucherUsecase)(nil).Enforce), ctx)
+}
+
+// ListVouchers mocks base method.
+func (m *MockVoucherUsecase) ListVouchers(ctx context.Context, batch string) (*VoucherBatchResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVouchers", ctx, batch)
+	ret0, _ := ret[0].(*VoucherBatchResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVouchers indicates an expected call of ListVouchers.
+func (mr *MockVoucherUsecaseMockRecorder) ListVouchers(ctx, batch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVouchers", reflect.TypeOf((*MockVoucherUsecase)(nil).ListVouchers), ctx, batch)
+}
+
+// Redeem mocks base method.
+func (m *MockVoucherUsecase) Redeem(ctx context.Context, account *repository.Account) (*time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeem", ctx, account)
+	ret0, _ := ret[0].(*time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Redeem indicates an expected call of Redeem.
+func (mr *MockVoucherUsecaseMockRecorder) Redeem(ctx, account interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeem", reflect.TypeOf((*MockVoucherUsecase)(nil).Redeem), ctx, account)
+}
+
+// Run mocks base method.
+func (m *MockVoucherUsecase) Run(ctx context.Context, interval time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx, interval)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockVoucherUsecaseMockRecorder) Run(ctx, interval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockVoucherUsecase)(nil).Run), ctx, interval)
+}
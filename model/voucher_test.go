@@ -0,0 +1,214 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateVouchers(t *testing.T) {
+	testCases := []struct {
+		name       string
+		req        CreateVouchersRequest
+		buildStubs func(repo *repository.MockVoucherRepository, audit *MockAuditUsecase)
+		check      func(t *testing.T, rsp *VoucherBatchResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  CreateVouchersRequest{Count: 3, Batch: "lobby", DurationMinutes: 60, DataLimitMB: 500, RedeemInDays: 7},
+			buildStubs: func(repo *repository.MockVoucherRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().CreateVouchers(gomock.Any(), gomock.Len(3), gomock.Len(3)).
+					DoAndReturn(func(_ context.Context, accounts []*repository.Account, vouchers []*repository.Voucher) error {
+						for i := range accounts {
+							require.Equal(t, repository.AccountTypeVoucher, accounts[i].Type)
+							require.Equal(t, "guest", accounts[i].Roles)
+							require.Len(t, accounts[i].Username, voucherCodeLength)
+							require.Equal(t, accounts[i].ID, vouchers[i].AccountID)
+							require.Equal(t, "lobby", vouchers[i].Batch)
+							require.EqualValues(t, 3600, vouchers[i].Duration)
+							require.EqualValues(t, 500<<20, vouchers[i].DataLimit)
+							require.NotNil(t, vouchers[i].RedeemBy)
+						}
+						return nil
+					})
+				audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry AuditEntry) {
+					require.Equal(t, AuditAdminAction, entry.Type)
+					require.Equal(t, "voucher_batch:lobby", entry.Target)
+				})
+			},
+			check: func(t *testing.T, rsp *VoucherBatchResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "lobby", rsp.Batch)
+				require.Len(t, rsp.Vouchers, 3)
+				require.Equal(t, VoucherStatusUnused, rsp.Vouchers[0].Status)
+				require.EqualValues(t, 60, rsp.Vouchers[0].DurationMinutes)
+				require.EqualValues(t, 500, rsp.Vouchers[0].DataLimitMB)
+			},
+		},
+		{
+			name: "RetryDuplicatedCode",
+			req:  CreateVouchersRequest{Count: 1, DurationMinutes: 60},
+			buildStubs: func(repo *repository.MockVoucherRepository, audit *MockAuditUsecase) {
+				gomock.InOrder(
+					repo.EXPECT().CreateVouchers(gomock.Any(), gomock.Any(), gomock.Any()).Return(repository.ErrAccountIsDuplicated),
+					repo.EXPECT().CreateVouchers(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+				)
+				audit.EXPECT().Record(gomock.Any(), gomock.Any())
+			},
+			check: func(t *testing.T, rsp *VoucherBatchResponse, err error) {
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.Batch)
+				require.Len(t, rsp.Vouchers, 1)
+			},
+		},
+		{
+			name: "NoLimits",
+			req:  CreateVouchersRequest{Count: 1},
+			buildStubs: func(repo *repository.MockVoucherRepository, audit *MockAuditUsecase) {
+				repo.EXPECT().CreateVouchers(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp *VoucherBatchResponse, err error) {
+				require.Equal(t, ErrInvalidVoucherLimits, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockVoucherRepository(ctrl)
+			audit := NewMockAuditUsecase(ctrl)
+			tc.buildStubs(repo, audit)
+
+			rsp, err := NewVoucherUsecase(repo, nil, nil, audit).CreateVouchers(context.Background(), tc.req)
+			tc.check(t, rsp, err)
+		})
+	}
+}
+
+func TestRedeemVoucher(t *testing.T) {
+	account := &repository.Account{ID: uuid.New(), Username: "ABCDE23456", Type: repository.AccountTypeVoucher}
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(repo *repository.MockVoucherRepository, sessions *repository.MockAccountingRepository)
+		check      func(t *testing.T, expiresAt *time.Time, err error)
+	}{
+		{
+			name: "FirstUse",
+			buildStubs: func(repo *repository.MockVoucherRepository, sessions *repository.MockAccountingRepository) {
+				repo.EXPECT().GetVoucher(gomock.Any(), account.ID).
+					Return(&repository.Voucher{AccountID: account.ID, Duration: 3600, DataLimit: 1 << 20}, nil)
+				repo.EXPECT().ActivateVoucher(gomock.Any(), account.ID, gomock.Any(), gomock.Any()).Return(true, nil)
+				sessions.EXPECT().ListAccountingSessions(gomock.Any(), account.ID, gomock.Any()).Return(nil, nil)
+			},
+			check: func(t *testing.T, expiresAt *time.Time, err error) {
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now().Add(time.Hour), *expiresAt, time.Minute)
+			},
+		},
+		{
+			name: "ActiveWithoutTimeLimit",
+			buildStubs: func(repo *repository.MockVoucherRepository, sessions *repository.MockAccountingRepository) {
+				repo.EXPECT().GetVoucher(gomock.Any(), account.ID).
+					Return(&repository.Voucher{AccountID: account.ID, DataLimit: 1 << 20, ActivatedAt: &past}, nil)
+				sessions.EXPECT().ListAccountingSessions(gomock.Any(), account.ID, past).
+					Return([]repository.AccountingSession{{InputOctets: 100, OutputOctets: 200}}, nil)
+			},
+			check: func(t *testing.T, expiresAt *time.Time, err error) {
+				require.NoError(t, err)
+				require.Nil(t, expiresAt)
+			},
+		},
+		{
+			name: "Expired",
+			buildStubs: func(repo *repository.MockVoucherRepository, sessions *repository.MockAccountingRepository) {
+				repo.EXPECT().GetVoucher(gomock.Any(), account.ID).
+					Return(&repository.Voucher{AccountID: account.ID, Duration: 60, ActivatedAt: &past, ExpiresAt: &past}, nil)
+			},
+			check: func(t *testing.T, expiresAt *time.Time, err error) {
+				require.Equal(t, ErrVoucherExpired, err)
+			},
+		},
+		{
+			name: "DataUsed",
+			buildStubs: func(repo *repository.MockVoucherRepository, sessions *repository.MockAccountingRepository) {
+				repo.EXPECT().GetVoucher(gomock.Any(), account.ID).
+					Return(&repository.Voucher{AccountID: account.ID, DataLimit: 1 << 20, ActivatedAt: &past, ExpiresAt: &future}, nil)
+				sessions.EXPECT().ListAccountingSessions(gomock.Any(), account.ID, past).
+					Return([]repository.AccountingSession{{InputOctets: 1 << 19}, {OutputOctets: 1 << 19}}, nil)
+			},
+			check: func(t *testing.T, expiresAt *time.Time, err error) {
+				require.Equal(t, ErrVoucherDataUsed, err)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(repo *repository.MockVoucherRepository, sessions *repository.MockAccountingRepository) {
+				repo.EXPECT().GetVoucher(gomock.Any(), account.ID).Return(nil, repository.ErrVoucherNotFound)
+			},
+			check: func(t *testing.T, expiresAt *time.Time, err error) {
+				require.Equal(t, ErrVoucherExpired, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := repository.NewMockVoucherRepository(ctrl)
+			sessions := repository.NewMockAccountingRepository(ctrl)
+			tc.buildStubs(repo, sessions)
+
+			expiresAt, err := NewVoucherUsecase(repo, sessions, nil, nil).Redeem(context.Background(), account)
+			tc.check(t, expiresAt, err)
+		})
+	}
+}
+
+func TestEnforceVouchers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockVoucherRepository(ctrl)
+	sessions := repository.NewMockAccountingRepository(ctrl)
+	accounting := NewMockAccountingUsecase(ctrl)
+
+	past := time.Now().Add(-time.Hour)
+	spent, staff := uuid.New(), uuid.New()
+	online := []repository.AccountingSession{
+		{ID: uuid.New(), AccountID: &spent, Username: "ABCDE23456"},
+		{ID: uuid.New(), AccountID: &staff, Username: "alice"},
+		{ID: uuid.New(), AccountID: &spent, Username: "ABCDE23456"},
+		{ID: uuid.New(), Username: "unknown"},
+	}
+	sessions.EXPECT().ListOnlineSessions(gomock.Any()).Return(online, nil)
+	repo.EXPECT().GetVoucher(gomock.Any(), spent).
+		Return(&repository.Voucher{AccountID: spent, ActivatedAt: &past, ExpiresAt: &past}, nil)
+	repo.EXPECT().GetVoucher(gomock.Any(), staff).Return(nil, repository.ErrVoucherNotFound)
+	accounting.EXPECT().Disconnect(gomock.Any(), online[0].ID.String()).Return(nil)
+	accounting.EXPECT().Disconnect(gomock.Any(), online[2].ID.String()).Return(ErrAccountingSessionStopped)
+
+	require.NoError(t, NewVoucherUsecase(repo, sessions, accounting, nil).Enforce(context.Background()))
+}
+
+func TestVoucherCode(t *testing.T) {
+	code, err := newVoucherCode()
+	require.NoError(t, err)
+	require.Len(t, code, voucherCodeLength)
+	require.NotContains(t, code, "0")
+	require.NotContains(t, code, "O")
+
+	require.Equal(t, "ABCDE-23456", FormatVoucherCode("ABCDE23456"))
+	require.Equal(t, "ABCDE23456", NormalizeVoucherCode("abcde-23456"))
+	require.True(t, IsVoucherCode(code))
+	require.False(t, IsVoucherCode("ALICE"))
+	require.False(t, IsVoucherCode("ABCDE0OI56"))
+	require.True(t, voucherCodeMatches("abcde 23456", "ABCDE23456"))
+	require.False(t, voucherCodeMatches("ABCDE23457", "ABCDE23456"))
+}
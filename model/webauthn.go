@@ -0,0 +1,248 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// COSE algorithms (RFC 9053) we accept for passkeys, in order of preference.
+const (
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+// Flags of the authenticator data.
+const (
+	webauthnFlagUserPresent  = 0x01
+	webauthnFlagUserVerified = 0x04
+	webauthnFlagAttestedData = 0x40
+)
+
+const (
+	webauthnCeremonyCreate = "webauthn.create"
+	webauthnCeremonyGet    = "webauthn.get"
+)
+
+var ErrInvalidPasskey = errors.New("Invalid passkey response")
+
+// WebAuthnConfig is the relying party passkeys are registered with. RPID is the domain of the
+// service and Origin the URL browsers run the ceremony from.
+type WebAuthnConfig struct {
+	RPID   string
+	RPName string
+	Origin string
+}
+
+// webauthnCredential is a passkey that was just registered.
+type webauthnCredential struct {
+	ID        []byte
+	PublicKey crypto.PublicKey
+	SignCount uint32
+}
+
+type webauthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type webauthnAuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// verifyRegistration checks the response of navigator.credentials.create. We ask for no attestation,
+// so the attestation statement is not checked, only the credential it carries.
+func (c WebAuthnConfig) verifyRegistration(credential PasskeyCredential, challenge string) (*webauthnCredential, error) {
+	if err := c.verifyClientData(credential.Response.ClientDataJSON, webauthnCeremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+	attestation, err := decodeBase64URL(credential.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	object, _, err := decodeCBOR(attestation)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	fields, _ := object.(map[interface{}]interface{})
+	raw, _ := fields["authData"].([]byte)
+	authData, err := c.verifyAuthenticatorData(raw, webauthnFlagUserPresent)
+	if err != nil {
+		return nil, err
+	}
+	if authData.Flags&webauthnFlagAttestedData == 0 {
+		return nil, ErrInvalidPasskey
+	}
+	if id, err := decodeBase64URL(credential.ID); err != nil || !bytes.Equal(id, authData.CredentialID) {
+		return nil, ErrInvalidPasskey
+	}
+	publicKey, err := parseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &webauthnCredential{
+		ID:        authData.CredentialID,
+		PublicKey: publicKey,
+		SignCount: authData.SignCount,
+	}, nil
+}
+
+// verifyAssertion checks the response of navigator.credentials.get against the stored public key
+// and returns the signature counter of the authenticator.
+func (c WebAuthnConfig) verifyAssertion(credential PasskeyCredential, challenge string, publicKey crypto.PublicKey) (uint32, error) {
+	if err := c.verifyClientData(credential.Response.ClientDataJSON, webauthnCeremonyGet, challenge); err != nil {
+		return 0, err
+	}
+	raw, err := decodeBase64URL(credential.Response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrInvalidPasskey
+	}
+	// A passkey login skips both the password and the second factor, so a touch is not enough: the
+	// authenticator must have verified the user with a PIN or biometric.
+	authData, err := c.verifyAuthenticatorData(raw, webauthnFlagUserPresent|webauthnFlagUserVerified)
+	if err != nil {
+		return 0, err
+	}
+	clientData, _ := decodeBase64URL(credential.Response.ClientDataJSON)
+	signature, err := decodeBase64URL(credential.Response.Signature)
+	if err != nil {
+		return 0, ErrInvalidPasskey
+	}
+	clientDataHash := sha256.Sum256(clientData)
+	signed := append(append([]byte(nil), raw...), clientDataHash[:]...)
+	if !VerifySignature(publicKey, signed, signature) {
+		return 0, ErrInvalidPasskey
+	}
+	return authData.SignCount, nil
+}
+
+func (c WebAuthnConfig) verifyClientData(encoded string, ceremony string, challenge string) error {
+	raw, err := decodeBase64URL(encoded)
+	if err != nil {
+		return ErrInvalidPasskey
+	}
+	var clientData webauthnClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return ErrInvalidPasskey
+	}
+	if clientData.Type != ceremony || clientData.Challenge != challenge || clientData.Origin != c.Origin {
+		return ErrInvalidPasskey
+	}
+	return nil
+}
+
+// verifyAuthenticatorData parses authenticator data and checks it was made for our RP ID with all
+// of flags set.
+func (c WebAuthnConfig) verifyAuthenticatorData(raw []byte, flags byte) (*webauthnAuthenticatorData, error) {
+	authData, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) || authData.Flags&flags != flags {
+		return nil, ErrInvalidPasskey
+	}
+	return authData, nil
+}
+
+// parseAuthenticatorData splits authenticator data: the RP ID hash, flags, signature counter and,
+// when attested credential data is present, the credential ID and its COSE public key.
+func parseAuthenticatorData(raw []byte) (*webauthnAuthenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, ErrInvalidPasskey
+	}
+	authData := &webauthnAuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if authData.Flags&webauthnFlagAttestedData == 0 {
+		return authData, nil
+	}
+	// The AAGUID comes first, we do not keep it.
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, ErrInvalidPasskey
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, ErrInvalidPasskey
+	}
+	authData.CredentialID = rest[:idLength]
+	rest = rest[idLength:]
+	// Extensions may follow the key, so the key ends where its CBOR item does.
+	if _, extensions, err := decodeCBOR(rest); err != nil {
+		return nil, ErrInvalidPasskey
+	} else {
+		authData.PublicKey = rest[:len(rest)-len(extensions)]
+	}
+	return authData, nil
+}
+
+// parseCOSEKey reads a COSE_Key (RFC 9052) of one of the algorithms we accept.
+func parseCOSEKey(raw []byte) (crypto.PublicKey, error) {
+	object, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	key, ok := object.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidPasskey
+	}
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+	switch {
+	case kty == 2 && alg == coseAlgES256:
+		// EC2 on P-256: -1 is the curve, -2 and -3 the coordinates.
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, ErrInvalidPasskey
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, ErrInvalidPasskey
+		}
+		return publicKey, nil
+	case kty == 1 && alg == coseAlgEdDSA:
+		// OKP: -1 is the curve, 6 for Ed25519, and -2 the public key.
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidPasskey
+		}
+		return ed25519.PublicKey(x), nil
+	case kty == 3 && alg == coseAlgRS256:
+		// RSA: -1 is the modulus and -2 the exponent.
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidPasskey
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+	return nil, ErrInvalidPasskey
+}
+
+// decodeBase64URL reads the unpadded base64url WebAuthn JSON uses, tolerating padding.
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
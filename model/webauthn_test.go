@@ -0,0 +1,223 @@
+package model
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testWebAuthn = WebAuthnConfig{RPID: "auth.example.com", RPName: "Senao", Origin: testIssuer}
+
+// encodeCBOR encodes the values a test authenticator sends: integers, byte and text strings, and maps.
+func encodeCBOR(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		}
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		out := head(5, uint64(len(v)))
+		for key, value := range v {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(value)...)
+		}
+		return out
+	}
+	panic("cannot encode")
+}
+
+// testAuthenticator plays a platform authenticator holding one P-256 passkey.
+type testAuthenticator struct {
+	key     *ecdsa.PrivateKey
+	id      []byte
+	counter uint32
+	flags   byte
+	config  WebAuthnConfig
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+	return &testAuthenticator{key: key, id: id, flags: webauthnFlagUserPresent | webauthnFlagUserVerified, config: testWebAuthn}
+}
+
+func (a *testAuthenticator) credentialID() string {
+	return base64.RawURLEncoding.EncodeToString(a.id)
+}
+
+func (a *testAuthenticator) clientData(ceremony string, challenge string) string {
+	clientData, _ := json.Marshal(webauthnClientData{Type: ceremony, Challenge: challenge, Origin: a.config.Origin})
+	return base64.RawURLEncoding.EncodeToString(clientData)
+}
+
+func (a *testAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.config.RPID))
+	authData := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.counter)
+}
+
+func (a *testAuthenticator) register(challenge string) PasskeyCredential {
+	authData := a.authenticatorData(webauthnFlagUserPresent | webauthnFlagUserVerified | webauthnFlagAttestedData)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.id)))
+	authData = append(authData, a.id...)
+	authData = append(authData, encodeCBOR(map[interface{}]interface{}{
+		1: 2, 3: coseAlgES256, -1: 1, -2: a.key.X.FillBytes(make([]byte, 32)), -3: a.key.Y.FillBytes(make([]byte, 32)),
+	})...)
+	attestation := encodeCBOR(map[interface{}]interface{}{
+		"fmt": "none", "attStmt": map[interface{}]interface{}{}, "authData": authData,
+	})
+	return PasskeyCredential{
+		ID:   a.credentialID(),
+		Type: "public-key",
+		Response: PasskeyCredentialResponse{
+			ClientDataJSON:    a.clientData(webauthnCeremonyCreate, challenge),
+			AttestationObject: base64.RawURLEncoding.EncodeToString(attestation),
+		},
+	}
+}
+
+func (a *testAuthenticator) assert(challenge string, userHandle string) PasskeyCredential {
+	authData := a.authenticatorData(a.flags)
+	clientData := a.clientData(webauthnCeremonyGet, challenge)
+	raw, _ := base64.RawURLEncoding.DecodeString(clientData)
+	clientDataHash := sha256.Sum256(raw)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	return PasskeyCredential{
+		ID:   a.credentialID(),
+		Type: "public-key",
+		Response: PasskeyCredentialResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: base64.RawURLEncoding.EncodeToString(authData),
+			Signature:         base64.RawURLEncoding.EncodeToString(signature),
+			UserHandle:        userHandle,
+		},
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	// Vectors from RFC 8949 appendix A.
+	testCase := []struct {
+		hex   string
+		value interface{}
+	}{
+		{hex: "00", value: int64(0)},
+		{hex: "1903e8", value: int64(1000)},
+		{hex: "20", value: int64(-1)},
+		{hex: "3903e7", value: int64(-1000)},
+		{hex: "4401020304", value: []byte{1, 2, 3, 4}},
+		{hex: "6449455446", value: "IETF"},
+		{hex: "83010203", value: []interface{}{int64(1), int64(2), int64(3)}},
+		{hex: "a201020304", value: map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{hex: "f5", value: true},
+		{hex: "f6", value: nil},
+	}
+	for _, tc := range testCase {
+		data, err := hex.DecodeString(tc.hex)
+		require.NoError(t, err)
+		value, rest, err := decodeCBOR(data)
+		require.NoError(t, err, tc.hex)
+		require.Equal(t, tc.value, value, tc.hex)
+		require.Empty(t, rest)
+	}
+
+	_, rest, err := decodeCBOR([]byte{0x01, 0x02})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x02}, rest)
+
+	for _, invalid := range []string{"", "19", "4401", "9f", "c11a514b67b0", "9b7fffffffffffffff", "a1f500"} {
+		data, _ := hex.DecodeString(invalid)
+		_, _, err := decodeCBOR(data)
+		require.Equal(t, ErrInvalidCBOR, err, invalid)
+	}
+}
+
+// FuzzDecodeCBOR feeds the decoder what a browser could post. It must not panic, and what it decodes
+// must leave a suffix of its input.
+func FuzzDecodeCBOR(f *testing.F) {
+	for _, seed := range []string{"00", "3903e7", "4401020304", "83010203", "a201020304", "f5", "9b7fffffffffffffff", "a1f500"} {
+		data, _ := hex.DecodeString(seed)
+		f.Add(data)
+	}
+	f.Add(encodeCBOR(map[interface{}]interface{}{1: 2, 3: -7, -1: 1, -2: make([]byte, 32), "fmt": "none"}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, rest, err := decodeCBOR(data)
+		if err != nil {
+			require.Equal(t, ErrInvalidCBOR, err)
+			return
+		}
+		require.Less(t, len(rest), len(data))
+		require.Equal(t, data[len(data)-len(rest):], rest)
+		parseAuthenticatorData(data)
+		parseCOSEKey(data)
+	})
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	credential, err := testWebAuthn.verifyRegistration(authenticator.register("challenge"), "challenge")
+	require.NoError(t, err)
+	require.Equal(t, authenticator.id, credential.ID)
+	require.True(t, authenticator.key.PublicKey.Equal(credential.PublicKey))
+
+	_, err = testWebAuthn.verifyRegistration(authenticator.register("challenge"), "other")
+	require.Equal(t, ErrInvalidPasskey, err)
+
+	otherRP := testWebAuthn
+	otherRP.RPID = "evil.example.com"
+	_, err = otherRP.verifyRegistration(authenticator.register("challenge"), "challenge")
+	require.Equal(t, ErrInvalidPasskey, err)
+
+	otherOrigin := testWebAuthn
+	otherOrigin.Origin = "https://evil.example.com"
+	_, err = otherOrigin.verifyRegistration(authenticator.register("challenge"), "challenge")
+	require.Equal(t, ErrInvalidPasskey, err)
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	authenticator.counter = 7
+	signCount, err := testWebAuthn.verifyAssertion(authenticator.assert("challenge", ""), "challenge", &authenticator.key.PublicKey)
+	require.NoError(t, err)
+	require.Equal(t, uint32(7), signCount)
+
+	_, err = testWebAuthn.verifyAssertion(authenticator.assert("challenge", ""), "other", &authenticator.key.PublicKey)
+	require.Equal(t, ErrInvalidPasskey, err)
+
+	other := newTestAuthenticator(t)
+	_, err = testWebAuthn.verifyAssertion(authenticator.assert("challenge", ""), "challenge", &other.key.PublicKey)
+	require.Equal(t, ErrInvalidPasskey, err)
+
+	// A touch without user verification does not log in.
+	authenticator.flags = webauthnFlagUserPresent
+	_, err = testWebAuthn.verifyAssertion(authenticator.assert("challenge", ""), "challenge", &authenticator.key.PublicKey)
+	require.Equal(t, ErrInvalidPasskey, err)
+}
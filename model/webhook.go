@@ -0,0 +1,329 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const webhookSinkName = "webhook"
+
+var (
+	webhookRequestTimeout = 10 * time.Second
+	webhookSecretBytes    = 32
+)
+
+var (
+	ErrInvalidWebhookEvent              = errors.New("Invalid webhook event type")
+	ErrInvalidWebhookID                 = errors.New("Invalid webhook id")
+	ErrWebhookEndpointNotFound          = errors.New("Webhook endpoint not found")
+	ErrWebhookDeadLetterNotFound        = errors.New("Webhook dead letter not found")
+	ErrWebhookDeadLetterAlreadyReplayed = errors.New("Webhook dead letter is already replayed")
+	ErrWebhookReplayFailed              = errors.New("Webhook replay failed")
+)
+
+type WebhookUsecase interface {
+	OutboxSink
+	RegisterEndpoint(ctx context.Context, req WebhookEndpointRequest) (*WebhookEndpointResponse, error)
+	ListEndpoints(ctx context.Context) ([]WebhookEndpointResponse, error)
+	DeleteEndpoint(ctx context.Context, id string) error
+	ListDeadLetters(ctx context.Context, includeReplayed bool) ([]WebhookDeadLetterResponse, error)
+	ReplayDeadLetter(ctx context.Context, id uint64) error
+}
+
+type webhookDelivery struct {
+	endpoint  repository.WebhookEndpoint
+	eventID   string
+	eventType string
+	payload   []byte
+}
+
+type webhookUsecase struct {
+	repo   repository.WebhookRepository
+	audit  AuditUsecase
+	client *http.Client
+}
+
+func NewWebhookUsecase(repo repository.WebhookRepository, audit AuditUsecase) WebhookUsecase {
+	return &webhookUsecase{
+		repo:   repo,
+		audit:  audit,
+		client: &http.Client{Timeout: webhookRequestTimeout},
+	}
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<payload>". Receivers recompute it
+// with their secret and compare it to the X-Webhook-Signature header, minus its "sha256=" prefix.
+func SignWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *webhookUsecase) Name() string {
+	return webhookSinkName
+}
+
+// Send delivers the outbox message to every endpoint subscribed to its type. Failed endpoints make the
+// relay retry the message later, which redelivers to every endpoint, so receivers dedupe by X-Webhook-Id.
+// On the last attempt the failed deliveries are dead-lettered instead.
+func (w *webhookUsecase) Send(ctx context.Context, message OutboxMessage) error {
+	endpoints, err := w.repo.ListEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for i := range endpoints {
+		if !webhookSubscribed(&endpoints[i], message.Type) {
+			continue
+		}
+		delivery := webhookDelivery{
+			endpoint:  endpoints[i],
+			eventID:   message.IdempotencyKey,
+			eventType: message.Type,
+			payload:   message.Payload,
+		}
+		if err := w.deliver(ctx, delivery); err != nil {
+			if message.LastAttempt {
+				w.deadLetter(ctx, delivery, message.Attempt, err)
+				continue
+			}
+			failures = append(failures, endpoints[i].URL+": "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (w *webhookUsecase) RegisterEndpoint(ctx context.Context, req WebhookEndpointRequest) (*WebhookEndpointResponse, error) {
+	for _, eventType := range req.Events {
+		if !isAccountEventType(eventType) {
+			return nil, ErrInvalidWebhookEvent
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := util.RandomToken(webhookSecretBytes)
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+	endpoint := &repository.WebhookEndpoint{
+		ID:          uuid.New(),
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      strings.Join(req.Events, ","),
+		Description: req.Description,
+	}
+	if err := w.repo.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+
+	w.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "webhook:" + endpoint.ID.String(),
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "register webhook endpoint " + endpoint.URL,
+	})
+
+	// The secret is only ever shown here.
+	rsp := webhookEndpointResponse(endpoint)
+	rsp.Secret = secret
+	return &rsp, nil
+}
+
+func (w *webhookUsecase) ListEndpoints(ctx context.Context) ([]WebhookEndpointResponse, error) {
+	endpoints, err := w.repo.ListEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]WebhookEndpointResponse, 0, len(endpoints))
+	for i := range endpoints {
+		rsp = append(rsp, webhookEndpointResponse(&endpoints[i]))
+	}
+	return rsp, nil
+}
+
+func (w *webhookUsecase) DeleteEndpoint(ctx context.Context, id string) error {
+	endpointID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidWebhookID
+	}
+	if err := w.repo.DeleteEndpoint(ctx, endpointID); err != nil {
+		if errors.Is(err, repository.ErrWebhookEndpointNotFound) {
+			return ErrWebhookEndpointNotFound
+		}
+		return err
+	}
+
+	w.audit.Record(ctx, AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  "webhook:" + id,
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "delete webhook endpoint",
+	})
+	return nil
+}
+
+func (w *webhookUsecase) ListDeadLetters(ctx context.Context, includeReplayed bool) ([]WebhookDeadLetterResponse, error) {
+	deadLetters, err := w.repo.ListDeadLetters(ctx, includeReplayed)
+	if err != nil {
+		return nil, err
+	}
+	rsp := make([]WebhookDeadLetterResponse, 0, len(deadLetters))
+	for i := range deadLetters {
+		rsp = append(rsp, webhookDeadLetterResponse(&deadLetters[i]))
+	}
+	return rsp, nil
+}
+
+// ReplayDeadLetter delivers the dead letter once more, synchronously, so the caller learns whether it went through.
+func (w *webhookUsecase) ReplayDeadLetter(ctx context.Context, id uint64) error {
+	deadLetter, err := w.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookDeadLetterNotFound) {
+			return ErrWebhookDeadLetterNotFound
+		}
+		return err
+	}
+	if deadLetter.ReplayedAt != nil {
+		return ErrWebhookDeadLetterAlreadyReplayed
+	}
+	endpoint, err := w.repo.GetEndpoint(ctx, deadLetter.EndpointID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookEndpointNotFound) {
+			return ErrWebhookEndpointNotFound
+		}
+		return err
+	}
+
+	entry := AuditEntry{
+		Type:    AuditAdminAction,
+		Target:  fmt.Sprintf("webhook_dead_letter:%d", id),
+		Outcome: AuditOutcomeSuccess,
+		Detail:  "replay webhook dead letter",
+	}
+	delivery := webhookDelivery{
+		endpoint:  *endpoint,
+		eventID:   deadLetter.EventID,
+		eventType: deadLetter.EventType,
+		payload:   []byte(deadLetter.Payload),
+	}
+	if err := w.deliver(ctx, delivery); err != nil {
+		entry.Outcome = AuditOutcomeFailure
+		w.audit.Record(ctx, entry)
+		return fmt.Errorf("%w: %v", ErrWebhookReplayFailed, err)
+	}
+	w.audit.Record(ctx, entry)
+	return w.repo.MarkDeadLetterReplayed(ctx, id, time.Now())
+}
+
+func (w *webhookUsecase) deliver(ctx context.Context, delivery webhookDelivery) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.endpoint.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, delivery.eventID)
+	req.Header.Set(WebhookEventHeader, delivery.eventType)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(delivery.endpoint.Secret, timestamp, delivery.payload))
+
+	rsp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	io.Copy(ioutil.Discard, rsp.Body)
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", rsp.StatusCode)
+	}
+	return nil
+}
+
+func (w *webhookUsecase) deadLetter(ctx context.Context, delivery webhookDelivery, attempts int, cause error) {
+	deadLetter := &repository.WebhookDeadLetter{
+		EndpointID: delivery.endpoint.ID,
+		EventID:    delivery.eventID,
+		EventType:  delivery.eventType,
+		Payload:    string(delivery.payload),
+		Attempts:   attempts,
+		LastError:  cause.Error(),
+	}
+	if err := w.repo.CreateDeadLetter(ctx, deadLetter); err != nil {
+		log.Error().Err(err).Str("event_id", delivery.eventID).Str("endpoint", delivery.endpoint.URL).
+			Msg("failed to store webhook dead letter")
+	}
+}
+
+func webhookSubscribed(endpoint *repository.WebhookEndpoint, eventType string) bool {
+	for _, subscribed := range strings.Split(endpoint.Events, ",") {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func isAccountEventType(eventType string) bool {
+	for _, known := range AccountEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookEndpointResponse(endpoint *repository.WebhookEndpoint) WebhookEndpointResponse {
+	return WebhookEndpointResponse{
+		ID:          endpoint.ID.String(),
+		URL:         endpoint.URL,
+		Events:      strings.Split(endpoint.Events, ","),
+		Description: endpoint.Description,
+		CreatedAt:   endpoint.CreatedAt,
+	}
+}
+
+func webhookDeadLetterResponse(deadLetter *repository.WebhookDeadLetter) WebhookDeadLetterResponse {
+	return WebhookDeadLetterResponse{
+		ID:         deadLetter.ID,
+		EndpointID: deadLetter.EndpointID.String(),
+		EventID:    deadLetter.EventID,
+		EventType:  deadLetter.EventType,
+		Attempts:   deadLetter.Attempts,
+		LastError:  deadLetter.LastError,
+		CreatedAt:  deadLetter.CreatedAt,
+		ReplayedAt: deadLetter.ReplayedAt,
+	}
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebhookEndpoint(url string, events ...string) repository.WebhookEndpoint {
+	return repository.WebhookEndpoint{
+		ID:     uuid.New(),
+		URL:    url,
+		Secret: util.RandomString(32),
+		Events: strings.Join(events, ","),
+	}
+}
+
+func newOutboxMessage(t *testing.T, eventType string) OutboxMessage {
+	account := &repository.Account{ID: uuid.New(), Username: util.RandomString(8)}
+	message, err := NewOutboxMessage(NewAccountEvent(eventType, account, ""))
+	require.NoError(t, err)
+	return OutboxMessage{
+		IdempotencyKey: message.IdempotencyKey,
+		Type:           message.Type,
+		Payload:        []byte(message.Payload),
+		Attempt:        1,
+	}
+}
+
+func TestWebhookSendSigned(t *testing.T) {
+	var calls int32
+	var endpoint repository.WebhookEndpoint
+	message := newOutboxMessage(t, EventAccountCreated)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		timestamp := r.Header.Get(WebhookTimestampHeader)
+		require.NotEmpty(t, timestamp)
+		require.Equal(t, "sha256="+SignWebhookPayload(endpoint.Secret, timestamp, body), r.Header.Get(WebhookSignatureHeader))
+		require.Equal(t, EventAccountCreated, r.Header.Get(WebhookEventHeader))
+		require.Equal(t, message.IdempotencyKey, r.Header.Get(WebhookIDHeader))
+		require.Equal(t, message.Payload, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	endpoint = newWebhookEndpoint(server.URL, EventAccountCreated, EventAccountDeleted)
+	other := newWebhookEndpoint(server.URL, EventAccountLoginFailed)
+
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockWebhookRepository(ctrl)
+	webhook := NewWebhookUsecase(mockRepo, NewMockAuditUsecase(ctrl))
+	mockRepo.EXPECT().ListEndpoints(gomock.Any()).Return([]repository.WebhookEndpoint{endpoint, other}, nil)
+
+	require.NoError(t, webhook.Send(context.Background(), message))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookSendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	endpoint := newWebhookEndpoint(server.URL, EventAccountLocked)
+
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockWebhookRepository(ctrl)
+	webhook := NewWebhookUsecase(mockRepo, NewMockAuditUsecase(ctrl))
+	mockRepo.EXPECT().ListEndpoints(gomock.Any()).Times(2).Return([]repository.WebhookEndpoint{endpoint}, nil)
+
+	// Before the last attempt the relay is asked to retry.
+	message := newOutboxMessage(t, EventAccountLocked)
+	mockRepo.EXPECT().CreateDeadLetter(gomock.Any(), gomock.Any()).Times(0)
+	err := webhook.Send(context.Background(), message)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "500")
+
+	// On the last attempt the delivery is dead-lettered instead.
+	message.Attempt = outboxMaxAttempts
+	message.LastAttempt = true
+	mockRepo.EXPECT().CreateDeadLetter(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, deadLetter *repository.WebhookDeadLetter) error {
+			require.Equal(t, endpoint.ID, deadLetter.EndpointID)
+			require.Equal(t, message.IdempotencyKey, deadLetter.EventID)
+			require.Equal(t, EventAccountLocked, deadLetter.EventType)
+			require.Equal(t, string(message.Payload), deadLetter.Payload)
+			require.Equal(t, outboxMaxAttempts, deadLetter.Attempts)
+			return nil
+		})
+	require.NoError(t, webhook.Send(context.Background(), message))
+}
+
+func TestWebhookReplayDeadLetter(t *testing.T) {
+	status := http.StatusBadGateway
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	endpoint := newWebhookEndpoint(server.URL, EventAccountCreated)
+	deadLetter := &repository.WebhookDeadLetter{
+		ID:         7,
+		EndpointID: endpoint.ID,
+		EventID:    uuid.New().String(),
+		EventType:  EventAccountCreated,
+		Payload:    `{"type":"account.created"}`,
+		Attempts:   outboxMaxAttempts,
+	}
+
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockWebhookRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	webhook := NewWebhookUsecase(mockRepo, mockAudit)
+
+	mockRepo.EXPECT().GetDeadLetter(gomock.Any(), deadLetter.ID).Times(2).Return(deadLetter, nil)
+	mockRepo.EXPECT().GetEndpoint(gomock.Any(), endpoint.ID).Times(2).Return(&endpoint, nil)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeFailure))
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+
+	err := webhook.ReplayDeadLetter(context.Background(), deadLetter.ID)
+	require.ErrorIs(t, err, ErrWebhookReplayFailed)
+
+	status = http.StatusOK
+	mockRepo.EXPECT().MarkDeadLetterReplayed(gomock.Any(), deadLetter.ID, gomock.Any()).Return(nil)
+	require.NoError(t, webhook.ReplayDeadLetter(context.Background(), deadLetter.ID))
+
+	replayedAt := time.Now()
+	deadLetter.ReplayedAt = &replayedAt
+	mockRepo.EXPECT().GetDeadLetter(gomock.Any(), deadLetter.ID).Return(deadLetter, nil)
+	require.EqualError(t, webhook.ReplayDeadLetter(context.Background(), deadLetter.ID), ErrWebhookDeadLetterAlreadyReplayed.Error())
+}
+
+func TestWebhookRegisterEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockWebhookRepository(ctrl)
+	mockAudit := NewMockAuditUsecase(ctrl)
+	webhook := NewWebhookUsecase(mockRepo, mockAudit)
+
+	_, err := webhook.RegisterEndpoint(context.Background(), WebhookEndpointRequest{
+		URL:    "https://crm.example.com/hooks",
+		Events: []string{EventAccountCreated, "account.renamed"},
+	})
+	require.EqualError(t, err, ErrInvalidWebhookEvent.Error())
+
+	mockRepo.EXPECT().CreateEndpoint(gomock.Any(), gomock.Any()).Return(nil)
+	mockAudit.EXPECT().Record(gomock.Any(), auditEntryOf(AuditAdminAction, AuditOutcomeSuccess))
+	rsp, err := webhook.RegisterEndpoint(context.Background(), WebhookEndpointRequest{
+		URL:    "https://crm.example.com/hooks",
+		Events: []string{EventAccountCreated, EventAccountDeleted},
+	})
+	require.NoError(t, err)
+	require.Len(t, rsp.Secret, webhookSecretBytes*2)
+	require.Equal(t, []string{EventAccountCreated, EventAccountDeleted}, rsp.Events)
+}
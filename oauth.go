@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultRevocationSyncInterval = 5 * time.Second
+
+func oauthIssuer() string {
+	if issuer := os.Getenv("OAUTH_ISSUER"); issuer != "" {
+		return strings.TrimSuffix(issuer, "/")
+	}
+	return fmt.Sprintf("http://%s:%s", os.Getenv("API_HOST"), os.Getenv("HTTP_PORT"))
+}
+
+// revocationSyncInterval bounds how long a token revoked on one replica stays usable on the others.
+func revocationSyncInterval() time.Duration {
+	return envDuration("OAUTH_REVOCATION_SYNC_INTERVAL", defaultRevocationSyncInterval)
+}
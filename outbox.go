@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/nats-io/nats.go"
+)
+
+// outboxSinks returns the sinks the outbox relay delivers to. Webhooks are always on, NATS and the
+// log file only when NATS_URL and OUTBOX_LOG_FILE are set.
+func outboxSinks(webhook model.WebhookUsecase) ([]model.OutboxSink, error) {
+	sinks := []model.OutboxSink{webhook}
+
+	if natsURL := os.Getenv("NATS_URL"); natsURL != "" {
+		conn, err := nats.Connect(natsURL, nats.Name("senao_hw outbox relay"), nats.MaxReconnects(-1))
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, model.NewNATSSink(conn, os.Getenv("NATS_SUBJECT_PREFIX")))
+	}
+	if path := os.Getenv("OUTBOX_LOG_FILE"); path != "" {
+		sinks = append(sinks, model.NewFileSink(path))
+	}
+	return sinks, nil
+}
+
+func outboxPollInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("OUTBOX_POLL_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Second
+	}
+	return interval
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/radius"
+)
+
+const (
+	defaultRADIUSAddress           = ":1812"
+	defaultRADIUSAccountingAddress = ":1813"
+	defaultVoucherEnforceInterval  = time.Minute
+)
+
+// loadRADIUSConfig reads the NAS clients named in RADIUS_CLIENTS, or returns nil when none are. Each
+// name has its address or network in RADIUS_CLIENT_<NAME>_ADDRESS, its shared secret in _SECRET,
+// _REQUIRE_MESSAGE_AUTHENTICATOR, true unless set otherwise, and the port it takes Disconnect and CoA
+// requests on in _COA_PORT. Replies are picked from the roles listed
+// in RADIUS_ROLES, in order, with RADIUS_ROLE_<ROLE>_REPLY; other accounts get RADIUS_DEFAULT_REPLY
+// unless RADIUS_REQUIRE_ROLE rejects them.
+func loadRADIUSConfig() (*radius.Config, error) {
+	config := &radius.Config{}
+	for _, name := range strings.Split(os.Getenv("RADIUS_CLIENTS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "RADIUS_CLIENT_" + envName(name) + "_"
+		network, err := parseNetwork(os.Getenv(prefix + "ADDRESS"))
+		if err != nil {
+			return nil, fmt.Errorf("%sADDRESS: %w", prefix, err)
+		}
+		secret := os.Getenv(prefix + "SECRET")
+		if secret == "" {
+			return nil, fmt.Errorf("%sSECRET is required", prefix)
+		}
+		require, err := strconv.ParseBool(envOrDefault(prefix+"REQUIRE_MESSAGE_AUTHENTICATOR", "true"))
+		if err != nil {
+			return nil, fmt.Errorf("%sREQUIRE_MESSAGE_AUTHENTICATOR: %w", prefix, err)
+		}
+		coaPort, err := strconv.Atoi(envOrDefault(prefix+"COA_PORT", strconv.Itoa(radius.DefaultDynamicAuthorizationPort)))
+		if err != nil {
+			return nil, fmt.Errorf("%sCOA_PORT: %w", prefix, err)
+		}
+		config.Clients = append(config.Clients, radius.Client{
+			Name:                        name,
+			Network:                     network,
+			Secret:                      []byte(secret),
+			RequireMessageAuthenticator: require,
+			DynamicAuthorizationPort:    coaPort,
+		})
+	}
+	if len(config.Clients) == 0 {
+		return nil, nil
+	}
+
+	for _, role := range strings.Split(os.Getenv("RADIUS_ROLES"), ",") {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+		name := "RADIUS_ROLE_" + envName(role) + "_REPLY"
+		attributes, err := radius.ParseAttributes(os.Getenv(name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		config.RoleReplies = append(config.RoleReplies, radius.RoleReply{Role: role, Attributes: attributes})
+	}
+	attributes, err := radius.ParseAttributes(os.Getenv("RADIUS_DEFAULT_REPLY"))
+	if err != nil {
+		return nil, fmt.Errorf("RADIUS_DEFAULT_REPLY: %w", err)
+	}
+	config.DefaultReply = attributes
+	config.RequireRole, _ = strconv.ParseBool(os.Getenv("RADIUS_REQUIRE_ROLE"))
+	return config, nil
+}
+
+func radiusAddress() string {
+	return envOrDefault("RADIUS_ADDRESS", defaultRADIUSAddress)
+}
+
+func radiusAccountingAddress() string {
+	return envOrDefault("RADIUS_ACCOUNTING_ADDRESS", defaultRADIUSAccountingAddress)
+}
+
+// voucherEnforceInterval is how often the sessions of spent vouchers are looked for and disconnected.
+func voucherEnforceInterval() time.Duration {
+	return envDuration("VOUCHER_ENFORCE_INTERVAL", defaultVoucherEnforceInterval)
+}
+
+// parseNetwork reads a network, or a single address as the network holding only it.
+func parseNetwork(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		return network, err
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is neither an address nor a network", value)
+	}
+	bits := 8 * len(ip)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
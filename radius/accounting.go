@@ -0,0 +1,79 @@
+package radius
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/rs/zerolog/log"
+)
+
+var accountingStatusTypes = map[uint32]string{
+	1: model.AccountingStart,
+	2: model.AccountingStop,
+	3: model.AccountingInterimUpdate,
+	7: model.AccountingOn,
+	8: model.AccountingOff,
+}
+
+// terminateCauses are the Acct-Terminate-Cause names of RFC 2866 section 5.10.
+var terminateCauses = map[uint32]string{
+	1: "User-Request", 2: "Lost-Carrier", 3: "Lost-Service", 4: "Idle-Timeout", 5: "Session-Timeout",
+	6: "Admin-Reset", 7: "Admin-Reboot", 8: "Port-Error", 9: "NAS-Error", 10: "NAS-Request",
+	11: "NAS-Reboot", 12: "Port-Unneeded", 13: "Port-Preempted", 14: "Port-Suspended",
+	15: "Service-Unavailable", 16: "Callback", 17: "User-Error", 18: "Host-Request",
+}
+
+// account records an Accounting-Request. It returns nil to drop the request when the record could not
+// be stored, so the NAS sends it again.
+func (s *Server) account(client *Client, address net.Addr, request *Packet) *Packet {
+	record := accountingRecord(request, time.Now())
+	record.Client = client.Name
+	record.NASAddress = address.(*net.UDPAddr).IP.String()
+	if err := s.accounting.Record(context.Background(), record); err != nil {
+		if err != model.ErrInvalidAccountingRecord {
+			log.Error().Err(err).Str("client", client.Name).Str("session", record.SessionID).Msg("failed to record RADIUS accounting")
+			return nil
+		}
+		// Sending it again would not make it valid.
+		log.Warn().Str("client", client.Name).Msg(err.Error())
+	}
+	return request.Response(CodeAccountingResponse)
+}
+
+// accountingRecord reads what the request reports. Counters are extended with their gigawords, and the
+// event time is taken from Event-Timestamp or else from when the request arrived and Acct-Delay-Time.
+func accountingRecord(request *Packet, received time.Time) model.AccountingRecord {
+	statusType := request.Integer(AttrAcctStatusType)
+	status, ok := accountingStatusTypes[statusType]
+	if !ok {
+		status = strconv.FormatUint(uint64(statusType), 10)
+	}
+	record := model.AccountingRecord{
+		NASIdentifier:    request.String(AttrNASIdentifier),
+		StatusType:       status,
+		SessionID:        request.String(AttrAcctSessionID),
+		Username:         request.String(AttrUserName),
+		CallingStationID: request.String(AttrCallingStationID),
+		CalledStationID:  request.String(AttrCalledStationID),
+		SessionTime:      time.Duration(request.Integer(AttrAcctSessionTime)) * time.Second,
+		InputOctets:      int64(request.Integer(AttrAcctInputGigawords))<<32 | int64(request.Integer(AttrAcctInputOctets)),
+		OutputOctets:     int64(request.Integer(AttrAcctOutputGigawords))<<32 | int64(request.Integer(AttrAcctOutputOctets)),
+		EventTime:        received.Add(-time.Duration(request.Integer(AttrAcctDelayTime)) * time.Second),
+	}
+	if framed := request.Get(AttrFramedIPAddress); len(framed) == net.IPv4len {
+		record.FramedIPAddress = net.IP(framed).String()
+	}
+	if timestamp := request.Integer(AttrEventTimestamp); timestamp != 0 {
+		record.EventTime = time.Unix(int64(timestamp), 0)
+	}
+	if cause := request.Integer(AttrAcctTerminateCause); cause != 0 {
+		record.TerminateCause = terminateCauses[cause]
+		if record.TerminateCause == "" {
+			record.TerminateCause = strconv.FormatUint(uint64(cause), 10)
+		}
+	}
+	return record
+}
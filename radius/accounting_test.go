@@ -0,0 +1,130 @@
+package radius
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func integer(value uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, value)
+}
+
+func accountingRequest(t *testing.T, identifier byte, statusType uint32, extra ...Attribute) []byte {
+	request := &Packet{Code: CodeAccountingRequest, Identifier: identifier}
+	request.Add(AttrAcctStatusType, integer(statusType))
+	request.Add(AttrAcctSessionID, []byte("session-1"))
+	request.Add(AttrUserName, []byte("alice"))
+	request.Attributes = append(request.Attributes, extra...)
+	data, err := request.SignRequest(testSecret)
+	require.NoError(t, err)
+	return data
+}
+
+func TestServerAccountingRequest(t *testing.T) {
+	testCase := []struct {
+		name          string
+		request       func(t *testing.T) []byte
+		buildStubs    func(accounting *model.MockAccountingUsecase)
+		checkResponse func(t *testing.T, response *Packet)
+	}{
+		{
+			name: "stop",
+			request: func(t *testing.T) []byte {
+				return accountingRequest(t, 1, 2,
+					Attribute{Type: AttrNASIdentifier, Value: []byte("ap1")},
+					Attribute{Type: AttrFramedIPAddress, Value: net.IPv4(10, 0, 0, 7).To4()},
+					Attribute{Type: AttrAcctSessionTime, Value: integer(600)},
+					Attribute{Type: AttrAcctInputOctets, Value: integer(100)},
+					Attribute{Type: AttrAcctInputGigawords, Value: integer(1)},
+					Attribute{Type: AttrAcctOutputOctets, Value: integer(200)},
+					Attribute{Type: AttrAcctTerminateCause, Value: integer(1)},
+					Attribute{Type: AttrEventTimestamp, Value: integer(1790000000)})
+			},
+			buildStubs: func(accounting *model.MockAccountingUsecase) {
+				accounting.EXPECT().Record(gomock.Any(), model.AccountingRecord{
+					Client:          "ap1",
+					NASAddress:      "127.0.0.1",
+					NASIdentifier:   "ap1",
+					StatusType:      model.AccountingStop,
+					SessionID:       "session-1",
+					Username:        "alice",
+					FramedIPAddress: "10.0.0.7",
+					SessionTime:     10 * time.Minute,
+					InputOctets:     1<<32 | 100,
+					OutputOctets:    200,
+					TerminateCause:  "User-Request",
+					EventTime:       time.Unix(1790000000, 0),
+				}).Return(nil)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.NotNil(t, response)
+				require.Equal(t, byte(CodeAccountingResponse), response.Code)
+			},
+		},
+		{
+			name: "invalid record acknowledged",
+			request: func(t *testing.T) []byte {
+				return accountingRequest(t, 1, 1)
+			},
+			buildStubs: func(accounting *model.MockAccountingUsecase) {
+				accounting.EXPECT().Record(gomock.Any(), gomock.Any()).Return(model.ErrInvalidAccountingRecord)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.NotNil(t, response)
+				require.Equal(t, byte(CodeAccountingResponse), response.Code)
+			},
+		},
+		{
+			name: "store failure dropped",
+			request: func(t *testing.T) []byte {
+				return accountingRequest(t, 1, 3)
+			},
+			buildStubs: func(accounting *model.MockAccountingUsecase) {
+				accounting.EXPECT().Record(gomock.Any(), gomock.Any()).Return(errors.New("database is down"))
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.Nil(t, response)
+			},
+		},
+		{
+			name: "wrong secret dropped",
+			request: func(t *testing.T) []byte {
+				request := &Packet{Code: CodeAccountingRequest, Identifier: 1}
+				request.Add(AttrAcctStatusType, integer(1))
+				request.Add(AttrAcctSessionID, []byte("session-1"))
+				data, err := request.SignRequest([]byte("wrong"))
+				require.NoError(t, err)
+				return data
+			},
+			buildStubs: func(accounting *model.MockAccountingUsecase) {},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.Nil(t, response)
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accounting := model.NewMockAccountingUsecase(ctrl)
+			tc.buildStubs(accounting)
+
+			conn := startServer(t, NewServer(testConfig(t), model.NewMockUsecaseHandler(ctrl), accounting, nil, model.NewMockAuditUsecase(ctrl)))
+			tc.checkResponse(t, exchange(t, conn, tc.request(t)))
+		})
+	}
+}
+
+func TestServerAccountingDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	conn := startServer(t, NewServer(testConfig(t), model.NewMockUsecaseHandler(ctrl), nil, nil, model.NewMockAuditUsecase(ctrl)))
+	require.Nil(t, exchange(t, conn, accountingRequest(t, 1, 1)))
+}
@@ -0,0 +1,158 @@
+package radius
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/repository"
+)
+
+const (
+	// DefaultDynamicAuthorizationPort is where NASes listen for Disconnect and CoA requests (RFC 5176).
+	DefaultDynamicAuthorizationPort = 3799
+	dynamicAuthorizationTimeout     = 3 * time.Second
+	dynamicAuthorizationAttempts    = 3
+)
+
+// errorCauses are the Error-Cause names of RFC 5176 section 3.5.
+var errorCauses = map[uint32]string{
+	201: "Residual-Session-Context-Removed", 202: "Invalid-EAP-Packet", 401: "Unsupported-Attribute",
+	402: "Missing-Attribute", 403: "NAS-Identification-Mismatch", 404: "Invalid-Request",
+	405: "Unsupported-Service", 406: "Unsupported-Extension", 407: "Invalid-Attribute-Value",
+	501: "Administratively-Prohibited", 502: "Request-Not-Routable", 503: "Session-Context-Not-Found",
+	504: "Session-Context-Not-Removable", 505: "Other-Proxy-Processing-Error", 506: "Resources-Unavailable",
+	507: "Request-Initiated", 508: "Multiple-Session-Selection-Unsupported",
+}
+
+// DynamicAuthorization sends Disconnect and CoA requests to the NAS that reported a session, with the
+// secret of its client.
+type DynamicAuthorization struct {
+	clients []Client
+	timeout time.Duration
+}
+
+func NewDynamicAuthorization(config Config) *DynamicAuthorization {
+	return &DynamicAuthorization{
+		clients: config.Clients,
+		timeout: dynamicAuthorizationTimeout,
+	}
+}
+
+func (d *DynamicAuthorization) Disconnect(ctx context.Context, session *repository.AccountingSession) error {
+	return d.send(ctx, CodeDisconnectRequest, session, nil)
+}
+
+func (d *DynamicAuthorization) ChangeAuthorization(ctx context.Context, session *repository.AccountingSession, attributes string) error {
+	parsed, err := ParseAttributes(attributes)
+	if err == nil && len(parsed) == 0 {
+		err = errors.New("no attributes")
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", model.ErrInvalidAuthorizationAttribute, err)
+	}
+	return d.send(ctx, CodeCoARequest, session, parsed)
+}
+
+// send sends the request until the NAS answers it. The session is identified by its Acct-Session-Id
+// and the attributes the NAS reported with it.
+func (d *DynamicAuthorization) send(ctx context.Context, code byte, session *repository.AccountingSession, attributes []Attribute) error {
+	client := d.client(session.Client)
+	if client == nil {
+		return fmt.Errorf("%w: client %s is not configured", model.ErrDynamicAuthorizationFailed, session.Client)
+	}
+	port := client.DynamicAuthorizationPort
+	if port == 0 {
+		port = DefaultDynamicAuthorizationPort
+	}
+	address := net.JoinHostPort(session.NASAddress, strconv.Itoa(port))
+
+	identifier := make([]byte, 1)
+	if _, err := rand.Read(identifier); err != nil {
+		return err
+	}
+	request := &Packet{Code: code, Identifier: identifier[0]}
+	request.Add(AttrAcctSessionID, []byte(session.SessionID))
+	if session.Username != "" {
+		request.Add(AttrUserName, []byte(session.Username))
+	}
+	if session.NASIdentifier != "" {
+		request.Add(AttrNASIdentifier, []byte(session.NASIdentifier))
+	}
+	if ip := net.ParseIP(session.NASAddress).To4(); ip != nil {
+		request.Add(AttrNASIPAddress, ip)
+	}
+	if session.CallingStationID != "" {
+		request.Add(AttrCallingStationID, []byte(session.CallingStationID))
+	}
+	// Event-Timestamp lets the NAS reject replayed requests (RFC 5176 section 3.5).
+	request.Add(AttrEventTimestamp, binary.BigEndian.AppendUint32(nil, uint32(time.Now().Unix())))
+	request.Attributes = append(request.Attributes, attributes...)
+	data, err := request.SignRequest(client.Secret)
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", model.ErrDynamicAuthorizationFailed, err)
+	}
+	defer conn.Close()
+	buffer := make([]byte, maxPacketLength)
+	for attempt := 0; attempt < dynamicAuthorizationAttempts; attempt++ {
+		if _, err := conn.Write(data); err != nil {
+			return fmt.Errorf("%w: %v", model.ErrDynamicAuthorizationFailed, err)
+		}
+		deadline := time.Now().Add(d.timeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetReadDeadline(deadline)
+		for {
+			n, err := conn.Read(buffer)
+			if err != nil {
+				if timeout, ok := err.(net.Error); ok && timeout.Timeout() && ctx.Err() == nil {
+					break
+				}
+				return fmt.Errorf("%w: %v", model.ErrDynamicAuthorizationFailed, err)
+			}
+			response, err := Parse(buffer[:n])
+			if err != nil || response.Identifier != request.Identifier ||
+				!VerifyResponse(buffer[:n], request.Authenticator, client.Secret) {
+				continue
+			}
+			return dynamicAuthorizationResult(code, response)
+		}
+	}
+	return fmt.Errorf("%w: no answer from %s", model.ErrDynamicAuthorizationFailed, address)
+}
+
+func dynamicAuthorizationResult(code byte, response *Packet) error {
+	if code == CodeDisconnectRequest && response.Code == CodeDisconnectACK ||
+		code == CodeCoARequest && response.Code == CodeCoAACK {
+		return nil
+	}
+	cause := response.Integer(AttrErrorCause)
+	name := errorCauses[cause]
+	if cause == 0 {
+		name = "request rejected"
+	} else if name == "" {
+		name = "Error-Cause " + strconv.FormatUint(uint64(cause), 10)
+	}
+	return fmt.Errorf("%w: %s", model.ErrDynamicAuthorizationFailed, name)
+}
+
+func (d *DynamicAuthorization) client(name string) *Client {
+	for i := range d.clients {
+		if d.clients[i].Name == name {
+			return &d.clients[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// startNAS answers Disconnect and CoA requests on a loopback port with the reply built by answer, and
+// returns the port and the requests it got.
+func startNAS(t *testing.T, answer func(request *Packet) *Packet) (int, <-chan *Packet) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	requests := make(chan *Packet, 4)
+	go func() {
+		buffer := make([]byte, maxPacketLength)
+		for {
+			n, address, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if !VerifyRequestAuthenticator(buffer[:n], testSecret) {
+				continue
+			}
+			request, err := Parse(buffer[:n])
+			if err != nil {
+				continue
+			}
+			requests <- request
+			response := answer(request)
+			if response == nil {
+				continue
+			}
+			data, err := response.Sign(testSecret)
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(data, address)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port, requests
+}
+
+func testDynamicAuthorization(t *testing.T, port int) *DynamicAuthorization {
+	config := testConfig(t)
+	config.Clients[0].DynamicAuthorizationPort = port
+	dynamic := NewDynamicAuthorization(config)
+	dynamic.timeout = 100 * time.Millisecond
+	return dynamic
+}
+
+func testSession() *repository.AccountingSession {
+	return &repository.AccountingSession{
+		Client:           "ap1",
+		SessionID:        "session-1",
+		Username:         "alice",
+		NASAddress:       "127.0.0.1",
+		CallingStationID: "AA-BB-CC-DD-EE-FF",
+	}
+}
+
+func TestDynamicAuthorizationDisconnect(t *testing.T) {
+	testCase := []struct {
+		name     string
+		answer   func(request *Packet) *Packet
+		checkErr func(t *testing.T, err error)
+	}{
+		{
+			name:   "ack",
+			answer: func(request *Packet) *Packet { return request.Response(CodeDisconnectACK) },
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "nak",
+			answer: func(request *Packet) *Packet {
+				response := request.Response(CodeDisconnectNAK)
+				response.Add(AttrErrorCause, integer(503))
+				return response
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.True(t, errors.Is(err, model.ErrDynamicAuthorizationFailed))
+				require.Contains(t, err.Error(), "Session-Context-Not-Found")
+			},
+		},
+		{
+			name:   "no answer",
+			answer: func(request *Packet) *Packet { return nil },
+			checkErr: func(t *testing.T, err error) {
+				require.True(t, errors.Is(err, model.ErrDynamicAuthorizationFailed))
+			},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			port, requests := startNAS(t, tc.answer)
+			err := testDynamicAuthorization(t, port).Disconnect(context.Background(), testSession())
+			tc.checkErr(t, err)
+
+			request := <-requests
+			require.Equal(t, byte(CodeDisconnectRequest), request.Code)
+			require.Equal(t, "session-1", request.String(AttrAcctSessionID))
+			require.Equal(t, "alice", request.String(AttrUserName))
+			require.NotZero(t, request.Integer(AttrEventTimestamp))
+		})
+	}
+}
+
+func TestDynamicAuthorizationChangeAuthorization(t *testing.T) {
+	port, requests := startNAS(t, func(request *Packet) *Packet { return request.Response(CodeCoAACK) })
+	dynamic := testDynamicAuthorization(t, port)
+
+	err := dynamic.ChangeAuthorization(context.Background(), testSession(), "Session-Timeout=600")
+	require.NoError(t, err)
+	request := <-requests
+	require.Equal(t, byte(CodeCoARequest), request.Code)
+	require.Equal(t, uint32(600), request.Integer(AttrSessionTimeout))
+
+	err = dynamic.ChangeAuthorization(context.Background(), testSession(), "Unknown=1")
+	require.True(t, errors.Is(err, model.ErrInvalidAuthorizationAttribute))
+	err = dynamic.ChangeAuthorization(context.Background(), testSession(), "")
+	require.True(t, errors.Is(err, model.ErrInvalidAuthorizationAttribute))
+}
@@ -0,0 +1,81 @@
+package radius
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/rs/zerolog/log"
+)
+
+// serviceTypeCallCheck is the Service-Type switches send MAC authentication bypass requests with.
+const serviceTypeCallCheck = 10
+
+// isMACAuthentication tells a MAC authentication bypass request apart from a login: the NAS sends the
+// MAC of the device as the username, and either Service-Type Call-Check or the MAC again as the password.
+func isMACAuthentication(client *Client, request *Packet, username string) bool {
+	mac, err := model.NormalizeMAC(username)
+	if err != nil {
+		return false
+	}
+	if request.Integer(AttrServiceType) == serviceTypeCallCheck {
+		return true
+	}
+	hidden := request.Get(AttrUserPassword)
+	if hidden == nil {
+		return false
+	}
+	password, err := DecryptUserPassword(hidden, client.Secret, request.Authenticator)
+	if err != nil {
+		return false
+	}
+	same, err := model.NormalizeMAC(password)
+	return err == nil && same == mac
+}
+
+// authenticateDevice answers a MAC authentication bypass request from the device registry. A device
+// gets the reply of its role, or the default reply without one, and is put on its VLAN when it has one.
+func (s *Server) authenticateDevice(ctx context.Context, client *Client, request *Packet, mac string) *Packet {
+	device, err := s.devices.Authorize(ctx, mac)
+	if err != nil {
+		switch err {
+		case model.ErrInvalidMAC, model.ErrDeviceNotRegistered, model.ErrDeviceExpired, model.ErrDeviceOwnerNotFound,
+			model.ErrDeviceOwnerLocked:
+			return reject(request, err.Error())
+		}
+		log.Error().Err(err).Str("client", client.Name).Str("mac", mac).Msg("failed to check RADIUS MAC authentication")
+		return nil
+	}
+
+	var roles []string
+	if device.Role != "" {
+		roles = []string{device.Role}
+	}
+	attributes, ok := s.reply(roles)
+	if device.VLAN > 0 {
+		attributes, ok = withVLAN(attributes, device.VLAN), true
+	}
+	if !ok {
+		return s.deny(ctx, request, device.MAC, ErrNoRoleAllowed)
+	}
+	response := request.Response(CodeAccessAccept)
+	response.Attributes = append(response.Attributes, attributes...)
+	return response
+}
+
+// withVLAN replaces the tunnel attributes of a reply with the ones putting the device on the VLAN.
+func withVLAN(attributes []Attribute, vlan int) []Attribute {
+	tunneled := make([]Attribute, 0, len(attributes)+3)
+	for _, attribute := range attributes {
+		switch attribute.Type {
+		case AttrTunnelType, AttrTunnelMediumType, AttrTunnelPrivateGroupID:
+			continue
+		}
+		tunneled = append(tunneled, attribute)
+	}
+	return append(tunneled,
+		Attribute{Type: AttrTunnelType, Value: []byte{0, 0, 0, 13}},
+		Attribute{Type: AttrTunnelMediumType, Value: []byte{0, 0, 0, 6}},
+		Attribute{Type: AttrTunnelPrivateGroupID, Value: append([]byte{0}, strconv.Itoa(vlan)...)},
+	)
+}
@@ -0,0 +1,105 @@
+package radius
+
+import (
+	"testing"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerMACAuthentication(t *testing.T) {
+	callCheck := Attribute{Type: AttrServiceType, Value: []byte{0, 0, 0, serviceTypeCallCheck}}
+
+	testCases := []struct {
+		name          string
+		request       func(t *testing.T) []byte
+		buildStubs    func(usecase *model.MockUsecaseHandler, devices *model.MockDeviceUsecase)
+		checkResponse func(t *testing.T, response *Packet)
+	}{
+		{
+			name: "AcceptOnVLAN",
+			request: func(t *testing.T) []byte {
+				return papRequest(t, 1, "001a2b3c4d5e", "001a2b3c4d5e", callCheck)
+			},
+			buildStubs: func(usecase *model.MockUsecaseHandler, devices *model.MockDeviceUsecase) {
+				devices.EXPECT().Authorize(gomock.Any(), "001a2b3c4d5e").
+					Return(&model.DeviceAuthorization{ID: uuid.New(), MAC: "00:1a:2b:3c:4d:5e", VLAN: 30, Role: "staff"}, nil)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessAccept, response.Code)
+				require.Equal(t, []byte{0, 0, 0, 13}, response.Get(AttrTunnelType))
+				require.Equal(t, append([]byte{0}, "30"...), response.Get(AttrTunnelPrivateGroupID))
+			},
+		},
+		{
+			name: "AcceptWithRoleReply",
+			request: func(t *testing.T) []byte {
+				return papRequest(t, 1, "00-1A-2B-3C-4D-5E", "00-1A-2B-3C-4D-5E")
+			},
+			buildStubs: func(usecase *model.MockUsecaseHandler, devices *model.MockDeviceUsecase) {
+				devices.EXPECT().Authorize(gomock.Any(), "00-1A-2B-3C-4D-5E").
+					Return(&model.DeviceAuthorization{ID: uuid.New(), MAC: "00:1a:2b:3c:4d:5e", Role: "staff"}, nil)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessAccept, response.Code)
+				require.Equal(t, append([]byte{0}, "20"...), response.Get(AttrTunnelPrivateGroupID))
+			},
+		},
+		{
+			name: "RejectNotRegistered",
+			request: func(t *testing.T) []byte {
+				return papRequest(t, 1, "001a2b3c4d5e", "", callCheck)
+			},
+			buildStubs: func(usecase *model.MockUsecaseHandler, devices *model.MockDeviceUsecase) {
+				devices.EXPECT().Authorize(gomock.Any(), "001a2b3c4d5e").Return(nil, model.ErrDeviceNotRegistered)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessReject, response.Code)
+				require.Equal(t, model.ErrDeviceNotRegistered.Error(), response.String(AttrReplyMessage))
+			},
+		},
+		{
+			name: "RejectOwnerLocked",
+			request: func(t *testing.T) []byte {
+				return papRequest(t, 1, "001a2b3c4d5e", "", callCheck)
+			},
+			buildStubs: func(usecase *model.MockUsecaseHandler, devices *model.MockDeviceUsecase) {
+				devices.EXPECT().Authorize(gomock.Any(), "001a2b3c4d5e").Return(nil, model.ErrDeviceOwnerLocked)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessReject, response.Code)
+				require.Equal(t, model.ErrDeviceOwnerLocked.Error(), response.String(AttrReplyMessage))
+			},
+		},
+		{
+			name: "LoginWithMACUsername",
+			request: func(t *testing.T) []byte {
+				return papRequest(t, 1, "001a2b3c4d5e", "password")
+			},
+			buildStubs: func(usecase *model.MockUsecaseHandler, devices *model.MockDeviceUsecase) {
+				devices.EXPECT().Authorize(gomock.Any(), gomock.Any()).Times(0)
+				usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "001a2b3c4d5e", Password: "password"}).
+					Return(&model.AccountResponse{Success: true, Username: "001a2b3c4d5e"}, nil)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessAccept, response.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			usecase := model.NewMockUsecaseHandler(ctrl)
+			devices := model.NewMockDeviceUsecase(ctrl)
+			audit := model.NewMockAuditUsecase(ctrl)
+			audit.EXPECT().Record(gomock.Any(), gomock.Any()).AnyTimes()
+			tc.buildStubs(usecase, devices)
+
+			conn := startServer(t, NewServer(testConfig(t), usecase, nil, devices, audit))
+			tc.checkResponse(t, exchange(t, conn, tc.request(t)))
+		})
+	}
+}
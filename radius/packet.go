@@ -0,0 +1,339 @@
+package radius
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+)
+
+// Packet codes (RFC 2865, RFC 2866, RFC 5176).
+const (
+	CodeAccessRequest      = 1
+	CodeAccessAccept       = 2
+	CodeAccessReject       = 3
+	CodeAccountingRequest  = 4
+	CodeAccountingResponse = 5
+	CodeAccessChallenge    = 11
+	CodeDisconnectRequest  = 40
+	CodeDisconnectACK      = 41
+	CodeDisconnectNAK      = 42
+	CodeCoARequest         = 43
+	CodeCoAACK             = 44
+	CodeCoANAK             = 45
+)
+
+// Attribute types (RFC 2865, RFC 2866, RFC 2868, RFC 2869, RFC 5176).
+const (
+	AttrUserName              = 1
+	AttrUserPassword          = 2
+	AttrCHAPPassword          = 3
+	AttrNASIPAddress          = 4
+	AttrNASPort               = 5
+	AttrServiceType           = 6
+	AttrFramedIPAddress       = 8
+	AttrFilterID              = 11
+	AttrReplyMessage          = 18
+	AttrState                 = 24
+	AttrClass                 = 25
+	AttrVendorSpecific        = 26
+	AttrSessionTimeout        = 27
+	AttrIdleTimeout           = 28
+	AttrCalledStationID       = 30
+	AttrCallingStationID      = 31
+	AttrNASIdentifier         = 32
+	AttrAcctStatusType        = 40
+	AttrAcctDelayTime         = 41
+	AttrAcctInputOctets       = 42
+	AttrAcctOutputOctets      = 43
+	AttrAcctSessionID         = 44
+	AttrAcctSessionTime       = 46
+	AttrAcctTerminateCause    = 49
+	AttrAcctInputGigawords    = 52
+	AttrAcctOutputGigawords   = 53
+	AttrEventTimestamp        = 55
+	AttrCHAPChallenge         = 60
+	AttrNASPortType           = 61
+	AttrTunnelType            = 64
+	AttrTunnelMediumType      = 65
+	AttrEAPMessage            = 79
+	AttrMessageAuthenticator  = 80
+	AttrTunnelPrivateGroupID  = 81
+	AttrErrorCause            = 101
+	headerLength              = 20
+	maxPacketLength           = 4096
+	maxAttributeValueLength   = 253
+	messageAuthenticatorBytes = 16
+)
+
+var (
+	ErrInvalidPacket               = errors.New("Invalid RADIUS packet")
+	ErrInvalidMessageAuthenticator = errors.New("Invalid Message-Authenticator")
+	ErrInvalidUserPassword         = errors.New("Invalid User-Password")
+)
+
+type Attribute struct {
+	Type  byte
+	Value []byte
+}
+
+// Packet is a RADIUS packet (RFC 2865 section 3). Authenticator is the request authenticator of a
+// request and the response authenticator of a response.
+type Packet struct {
+	Code          byte
+	Identifier    byte
+	Authenticator [16]byte
+	Attributes    []Attribute
+}
+
+// Parse decodes a packet. It checks the lengths but not the authenticators.
+func Parse(data []byte) (*Packet, error) {
+	if len(data) < headerLength {
+		return nil, ErrInvalidPacket
+	}
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < headerLength || length > maxPacketLength || length > len(data) {
+		return nil, ErrInvalidPacket
+	}
+	packet := &Packet{Code: data[0], Identifier: data[1]}
+	copy(packet.Authenticator[:], data[4:headerLength])
+	for rest := data[headerLength:length]; len(rest) > 0; {
+		if len(rest) < 2 || rest[1] < 2 || int(rest[1]) > len(rest) {
+			return nil, ErrInvalidPacket
+		}
+		packet.Attributes = append(packet.Attributes, Attribute{Type: rest[0], Value: rest[2:rest[1]]})
+		rest = rest[rest[1]:]
+	}
+	return packet, nil
+}
+
+// Encode encodes the packet as it is, without computing any authenticator.
+func (p *Packet) Encode() ([]byte, error) {
+	data := make([]byte, headerLength, maxPacketLength)
+	data[0] = p.Code
+	data[1] = p.Identifier
+	copy(data[4:headerLength], p.Authenticator[:])
+	for _, attribute := range p.Attributes {
+		if len(attribute.Value) > maxAttributeValueLength {
+			return nil, ErrInvalidPacket
+		}
+		data = append(data, attribute.Type, byte(len(attribute.Value)+2))
+		data = append(data, attribute.Value...)
+	}
+	if len(data) > maxPacketLength {
+		return nil, ErrInvalidPacket
+	}
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(data)))
+	return data, nil
+}
+
+// Get returns the value of the first attribute of the type, or nil.
+func (p *Packet) Get(attributeType byte) []byte {
+	for _, attribute := range p.Attributes {
+		if attribute.Type == attributeType {
+			return attribute.Value
+		}
+	}
+	return nil
+}
+
+func (p *Packet) String(attributeType byte) string {
+	return string(p.Get(attributeType))
+}
+
+// Integer returns the value of the first attribute of the type as an integer, or 0.
+func (p *Packet) Integer(attributeType byte) uint32 {
+	value := p.Get(attributeType)
+	if len(value) != 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(value)
+}
+
+func (p *Packet) Add(attributeType byte, value []byte) {
+	p.Attributes = append(p.Attributes, Attribute{Type: attributeType, Value: value})
+}
+
+// Response starts the response to the request: the identifier is copied and the authenticator field
+// holds the request authenticator until Sign replaces it.
+func (p *Packet) Response(code byte) *Packet {
+	return &Packet{Code: code, Identifier: p.Identifier, Authenticator: p.Authenticator}
+}
+
+// Sign encodes a response. It adds a Message-Authenticator (RFC 3579 section 3.2), computed over the
+// response with the request authenticator, then sets the response authenticator.
+func (p *Packet) Sign(secret []byte) ([]byte, error) {
+	p.Attributes = append(p.Attributes, Attribute{Type: AttrMessageAuthenticator, Value: make([]byte, messageAuthenticatorBytes)})
+	data, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(md5.New, secret)
+	mac.Write(data)
+	copy(data[len(data)-messageAuthenticatorBytes:], mac.Sum(nil))
+
+	hash := md5.New()
+	hash.Write(data)
+	hash.Write(secret)
+	copy(data[4:headerLength], hash.Sum(nil))
+	return data, nil
+}
+
+// SignRequest encodes a request whose authenticator is computed from its contents, as Accounting,
+// Disconnect and CoA requests are (RFC 2866 section 3, RFC 5176 section 3.5). Its Message-Authenticator
+// is computed with the authenticator zeroed.
+func (p *Packet) SignRequest(secret []byte) ([]byte, error) {
+	p.Authenticator = [16]byte{}
+	data, err := p.Sign(secret)
+	if err != nil {
+		return nil, err
+	}
+	copy(p.Authenticator[:], data[4:headerLength])
+	return data, nil
+}
+
+// VerifyRequestAuthenticator checks the authenticator of an Accounting, Disconnect or CoA request, and
+// its Message-Authenticator when it has one.
+func VerifyRequestAuthenticator(data []byte, secret []byte) bool {
+	if len(data) < headerLength {
+		return false
+	}
+	var zero [16]byte
+	if !VerifyResponse(data, zero, secret) {
+		return false
+	}
+	request, err := Parse(data)
+	if err != nil {
+		return false
+	}
+	if request.HasMessageAuthenticator() {
+		zeroed := append([]byte(nil), data...)
+		copy(zeroed[4:headerLength], zero[:])
+		return VerifyMessageAuthenticator(zeroed, secret) == nil
+	}
+	return true
+}
+
+// HasMessageAuthenticator reports whether the request carries a Message-Authenticator.
+func (p *Packet) HasMessageAuthenticator() bool {
+	return p.Get(AttrMessageAuthenticator) != nil
+}
+
+// VerifyMessageAuthenticator checks the Message-Authenticator of a request in its encoded form. The
+// HMAC-MD5 is computed over the request with the attribute zeroed.
+func VerifyMessageAuthenticator(data []byte, secret []byte) error {
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	copied := append([]byte(nil), data[:length]...)
+	var received []byte
+	for offset := headerLength; offset+2 <= length; offset += int(copied[offset+1]) {
+		if copied[offset+1] < 2 {
+			return ErrInvalidPacket
+		}
+		if copied[offset] == AttrMessageAuthenticator {
+			if copied[offset+1] != messageAuthenticatorBytes+2 || received != nil {
+				return ErrInvalidMessageAuthenticator
+			}
+			value := copied[offset+2 : offset+2+messageAuthenticatorBytes]
+			received = append([]byte(nil), value...)
+			for i := range value {
+				value[i] = 0
+			}
+		}
+	}
+	if received == nil {
+		return ErrInvalidMessageAuthenticator
+	}
+	mac := hmac.New(md5.New, secret)
+	mac.Write(copied)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		return ErrInvalidMessageAuthenticator
+	}
+	return nil
+}
+
+// VerifyResponse checks the response authenticator of a response to the request authenticator. An
+// Accounting-Request is checked the same way with a zero request authenticator.
+func VerifyResponse(data []byte, requestAuthenticator [16]byte, secret []byte) bool {
+	if len(data) < headerLength {
+		return false
+	}
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length < headerLength || length > len(data) {
+		return false
+	}
+	hash := md5.New()
+	hash.Write(data[:4])
+	hash.Write(requestAuthenticator[:])
+	hash.Write(data[headerLength:length])
+	hash.Write(secret)
+	return hmac.Equal(hash.Sum(nil), data[4:headerLength])
+}
+
+// DecryptUserPassword reverses the User-Password hiding of RFC 2865 section 5.2.
+func DecryptUserPassword(value []byte, secret []byte, authenticator [16]byte) (string, error) {
+	if len(value) == 0 || len(value)%16 != 0 || len(value) > 128 {
+		return "", ErrInvalidUserPassword
+	}
+	password := make([]byte, len(value))
+	previous := authenticator[:]
+	for offset := 0; offset < len(value); offset += 16 {
+		hash := md5.New()
+		hash.Write(secret)
+		hash.Write(previous)
+		block := hash.Sum(nil)
+		for i := 0; i < 16; i++ {
+			password[offset+i] = value[offset+i] ^ block[i]
+		}
+		previous = value[offset : offset+16]
+	}
+	return string(bytes.TrimRight(password, "\x00")), nil
+}
+
+// CHAPResponse is what a NAS puts in CHAP-Password for a password (RFC 2865 section 5.3): the CHAP
+// identifier followed by MD5 of the identifier, the password and the challenge.
+func CHAPResponse(ident byte, password string, challenge []byte) []byte {
+	hash := md5.New()
+	hash.Write([]byte{ident})
+	hash.Write([]byte(password))
+	hash.Write(challenge)
+	return hash.Sum([]byte{ident})
+}
+
+// VerifyCHAPPassword tells whether a CHAP-Password was made from password.
+func VerifyCHAPPassword(value []byte, password string, challenge []byte) bool {
+	if len(value) != 17 {
+		return false
+	}
+	return hmac.Equal(value, CHAPResponse(value[0], password, challenge))
+}
+
+// EncryptUserPassword hides a password the way a NAS does, for clients and tests.
+func EncryptUserPassword(password string, secret []byte, authenticator [16]byte) []byte {
+	padded := make([]byte, (len(password)+15)/16*16)
+	if len(padded) == 0 {
+		padded = make([]byte, 16)
+	}
+	copy(padded, password)
+	value := make([]byte, len(padded))
+	previous := authenticator[:]
+	for offset := 0; offset < len(padded); offset += 16 {
+		hash := md5.New()
+		hash.Write(secret)
+		hash.Write(previous)
+		block := hash.Sum(nil)
+		for i := 0; i < 16; i++ {
+			value[offset+i] = padded[offset+i] ^ block[i]
+		}
+		previous = value[offset : offset+16]
+	}
+	return value
+}
+
+// NewRequestAuthenticator returns a random request authenticator.
+func NewRequestAuthenticator() ([16]byte, error) {
+	var authenticator [16]byte
+	_, err := rand.Read(authenticator[:])
+	return authenticator, err
+}
@@ -0,0 +1,159 @@
+package radius
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	packet := &Packet{Code: CodeAccessRequest, Identifier: 7, Authenticator: [16]byte{1, 2, 3}}
+	packet.Add(AttrUserName, []byte("alice"))
+	packet.Add(AttrCallingStationID, []byte("AA-BB-CC-DD-EE-FF"))
+	data, err := packet.Encode()
+	require.NoError(t, err)
+	require.Len(t, data, 20+7+19)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, packet, parsed)
+	require.Equal(t, "alice", parsed.String(AttrUserName))
+	require.Nil(t, parsed.Get(AttrUserPassword))
+}
+
+func TestParseInvalidPacket(t *testing.T) {
+	valid, err := (&Packet{Code: CodeAccessRequest, Attributes: []Attribute{{Type: AttrUserName, Value: []byte("alice")}}}).Encode()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Short", data: valid[:10]},
+		{name: "LengthBeyondData", data: append(append([]byte(nil), valid[:4]...), valid[4:24]...)},
+		{name: "AttributeBeyondPacket", data: func() []byte {
+			data := append([]byte(nil), valid...)
+			data[21] = 40
+			return data
+		}()},
+		{name: "AttributeTooShort", data: func() []byte {
+			data := append([]byte(nil), valid...)
+			data[21] = 1
+			return data
+		}()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.data)
+			require.ErrorIs(t, err, ErrInvalidPacket)
+		})
+	}
+}
+
+func TestUserPassword(t *testing.T) {
+	secret := []byte("secret")
+	authenticator := [16]byte{9, 8, 7, 6, 5, 4, 3, 2, 1}
+	for _, password := range []string{"", "short", "exactly16bytes!!", "a password longer than one block"} {
+		hidden := EncryptUserPassword(password, secret, authenticator)
+		require.Zero(t, len(hidden)%16)
+		decrypted, err := DecryptUserPassword(hidden, secret, authenticator)
+		require.NoError(t, err)
+		require.Equal(t, password, decrypted)
+	}
+
+	hidden := EncryptUserPassword("password", secret, authenticator)
+	decrypted, err := DecryptUserPassword(hidden, []byte("other"), authenticator)
+	require.NoError(t, err)
+	require.NotEqual(t, "password", decrypted)
+
+	_, err = DecryptUserPassword(hidden[:15], secret, authenticator)
+	require.ErrorIs(t, err, ErrInvalidUserPassword)
+}
+
+func TestCHAPPassword(t *testing.T) {
+	challenge := []byte("0123456789abcdef")
+	value := CHAPResponse(7, "ABCDE23456", challenge)
+	require.Len(t, value, 17)
+	require.EqualValues(t, 7, value[0])
+	require.True(t, VerifyCHAPPassword(value, "ABCDE23456", challenge))
+	require.False(t, VerifyCHAPPassword(value, "ABCDE23457", challenge))
+	require.False(t, VerifyCHAPPassword(value, "ABCDE23456", []byte("other")))
+	require.False(t, VerifyCHAPPassword(value[:16], "ABCDE23456", challenge))
+}
+
+func TestMessageAuthenticator(t *testing.T) {
+	secret := []byte("secret")
+	request := &Packet{Code: CodeAccessRequest, Identifier: 1, Authenticator: [16]byte{1}}
+	request.Add(AttrUserName, []byte("alice"))
+	data, err := signedRequest(request, secret)
+	require.NoError(t, err)
+	require.NoError(t, VerifyMessageAuthenticator(data, secret))
+	require.ErrorIs(t, VerifyMessageAuthenticator(data, []byte("other")), ErrInvalidMessageAuthenticator)
+
+	data[len(data)-20] ^= 1
+	require.Error(t, VerifyMessageAuthenticator(data, secret))
+
+	unsigned, err := request.Encode()
+	require.NoError(t, err)
+	require.ErrorIs(t, VerifyMessageAuthenticator(unsigned, secret), ErrInvalidMessageAuthenticator)
+}
+
+func TestSignResponse(t *testing.T) {
+	secret := []byte("secret")
+	request := &Packet{Code: CodeAccessRequest, Identifier: 3, Authenticator: [16]byte{4, 5, 6}}
+	response := request.Response(CodeAccessAccept)
+	response.Add(AttrReplyMessage, []byte("welcome"))
+	data, err := response.Sign(secret)
+	require.NoError(t, err)
+
+	require.True(t, VerifyResponse(data, request.Authenticator, secret))
+	require.False(t, VerifyResponse(data, request.Authenticator, []byte("other")))
+	require.False(t, VerifyResponse(data, [16]byte{}, secret))
+
+	// The Message-Authenticator of a response is computed with the request authenticator in its place.
+	check := append([]byte(nil), data...)
+	copy(check[4:20], request.Authenticator[:])
+	require.NoError(t, VerifyMessageAuthenticator(check, secret))
+}
+
+func TestParseAttributes(t *testing.T) {
+	attributes, err := ParseAttributes("Service-Type=Administrative; tunnel-type=VLAN;Tunnel-Medium-Type=IEEE-802;" +
+		"Tunnel-Private-Group-Id=20;Session-Timeout=3600;Framed-IP-Address=10.0.0.5;Vendor-Specific:9:1=shell:priv-lvl=15")
+	require.NoError(t, err)
+	require.Equal(t, []Attribute{
+		{Type: AttrServiceType, Value: []byte{0, 0, 0, 6}},
+		{Type: AttrTunnelType, Value: []byte{0, 0, 0, 13}},
+		{Type: AttrTunnelMediumType, Value: []byte{0, 0, 0, 6}},
+		{Type: AttrTunnelPrivateGroupID, Value: []byte{0, '2', '0'}},
+		{Type: AttrSessionTimeout, Value: []byte{0, 0, 0x0e, 0x10}},
+		{Type: AttrFramedIPAddress, Value: []byte{10, 0, 0, 5}},
+		{Type: AttrVendorSpecific, Value: append([]byte{0, 0, 0, 9, 1, 19}, "shell:priv-lvl=15"...)},
+	}, attributes)
+
+	attributes, err = ParseAttributes("")
+	require.NoError(t, err)
+	require.Empty(t, attributes)
+
+	for _, text := range []string{"Unknown=1", "Service-Type", "Service-Type=Sometimes", "Framed-IP-Address=::1",
+		"Vendor-Specific:cisco:1=x", "Vendor-Specific:9=x"} {
+		_, err := ParseAttributes(text)
+		require.Error(t, err, text)
+	}
+}
+
+// signedRequest encodes a request with a Message-Authenticator, as a NAS sends it.
+func signedRequest(request *Packet, secret []byte) ([]byte, error) {
+	signed := *request
+	signed.Attributes = append(append([]Attribute(nil), request.Attributes...),
+		Attribute{Type: AttrMessageAuthenticator, Value: make([]byte, messageAuthenticatorBytes)})
+	data, err := signed.Encode()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(md5.New, secret)
+	mac.Write(data)
+	copy(data[len(data)-messageAuthenticatorBytes:], mac.Sum(nil))
+	return data, nil
+}
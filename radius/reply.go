@@ -0,0 +1,162 @@
+package radius
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+type attributeKind int
+
+const (
+	kindString attributeKind = iota
+	kindInteger
+	kindIPAddress
+	// kindTaggedInteger and kindTaggedString are the tunnel attributes of RFC 2868, which start with a tag.
+	kindTaggedInteger
+	kindTaggedString
+)
+
+type attributeDefinition struct {
+	Type   byte
+	Kind   attributeKind
+	Values map[string]uint32
+}
+
+// replyAttributes are the attributes a reply can be configured with, by their dictionary names.
+var replyAttributes = map[string]attributeDefinition{
+	"Service-Type": {Type: AttrServiceType, Kind: kindInteger, Values: map[string]uint32{
+		"Login": 1, "Framed": 2, "Callback-Login": 3, "Callback-Framed": 4, "Outbound": 5,
+		"Administrative": 6, "NAS-Prompt": 7, "Authenticate-Only": 8,
+	}},
+	"Framed-IP-Address": {Type: AttrFramedIPAddress, Kind: kindIPAddress},
+	"Filter-Id":         {Type: AttrFilterID, Kind: kindString},
+	"Reply-Message":     {Type: AttrReplyMessage, Kind: kindString},
+	"Class":             {Type: AttrClass, Kind: kindString},
+	"Session-Timeout":   {Type: AttrSessionTimeout, Kind: kindInteger},
+	"Idle-Timeout":      {Type: AttrIdleTimeout, Kind: kindInteger},
+	"Tunnel-Type": {Type: AttrTunnelType, Kind: kindTaggedInteger, Values: map[string]uint32{
+		"PPTP": 1, "L2TP": 3, "GRE": 10, "VLAN": 13,
+	}},
+	"Tunnel-Medium-Type": {Type: AttrTunnelMediumType, Kind: kindTaggedInteger, Values: map[string]uint32{
+		"IPv4": 1, "IPv6": 2, "IEEE-802": 6,
+	}},
+	"Tunnel-Private-Group-Id": {Type: AttrTunnelPrivateGroupID, Kind: kindTaggedString},
+}
+
+// ParseAttributes reads reply attributes written as Name=value pairs separated by semicolons, such as
+// "Service-Type=Administrative;Tunnel-Type=VLAN;Tunnel-Medium-Type=IEEE-802;Tunnel-Private-Group-Id=20".
+// Names are matched without regard to case. Vendor attributes are written Vendor-Specific:vendor:type=value,
+// with the vendor's enterprise number, and are sent as strings.
+func ParseAttributes(text string) ([]Attribute, error) {
+	var attributes []Attribute
+	for _, pair := range strings.Split(text, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not Name=value", pair)
+		}
+		attribute, err := parseAttribute(strings.TrimSpace(name), strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes, nil
+}
+
+func parseAttribute(name string, value string) (Attribute, error) {
+	if vendor, ok := cutPrefixFold(name, "Vendor-Specific:"); ok {
+		return parseVendorAttribute(vendor, value)
+	}
+	for dictionaryName, definition := range replyAttributes {
+		if strings.EqualFold(dictionaryName, name) {
+			encoded, err := definition.encode(value)
+			if err != nil {
+				return Attribute{}, fmt.Errorf("%s: %w", dictionaryName, err)
+			}
+			return Attribute{Type: definition.Type, Value: encoded}, nil
+		}
+	}
+	return Attribute{}, fmt.Errorf("unknown attribute %q", name)
+}
+
+func (d attributeDefinition) encode(value string) ([]byte, error) {
+	switch d.Kind {
+	case kindInteger, kindTaggedInteger:
+		number, err := d.integer(value)
+		if err != nil {
+			return nil, err
+		}
+		encoded := binary.BigEndian.AppendUint32(nil, number)
+		if d.Kind == kindTaggedInteger {
+			// The tag takes the first byte of the value, tag 0 leaves the tunnel unspecified.
+			encoded[0] = 0
+			if number > 0xffffff {
+				return nil, fmt.Errorf("%d does not fit in 24 bits", number)
+			}
+		}
+		return encoded, nil
+	case kindIPAddress:
+		ip := net.ParseIP(value).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an IPv4 address", value)
+		}
+		return ip, nil
+	case kindTaggedString:
+		return checkLength(append([]byte{0}, value...))
+	default:
+		return checkLength([]byte(value))
+	}
+}
+
+func (d attributeDefinition) integer(value string) (uint32, error) {
+	for name, number := range d.Values {
+		if strings.EqualFold(name, value) {
+			return number, nil
+		}
+	}
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is neither a number nor a known value", value)
+	}
+	return uint32(number), nil
+}
+
+// parseVendorAttribute encodes a Vendor-Specific attribute as RFC 2865 section 5.26 suggests: the vendor
+// number followed by one vendor attribute.
+func parseVendorAttribute(vendor string, value string) (Attribute, error) {
+	vendorID, vendorType, ok := strings.Cut(vendor, ":")
+	id, err := strconv.ParseUint(vendorID, 10, 32)
+	if !ok || err != nil {
+		return Attribute{}, fmt.Errorf("Vendor-Specific:%s is not Vendor-Specific:vendor:type", vendor)
+	}
+	attributeType, err := strconv.ParseUint(vendorType, 10, 8)
+	if err != nil || attributeType == 0 {
+		return Attribute{}, fmt.Errorf("Vendor-Specific:%s has no valid vendor type", vendor)
+	}
+	if len(value) > maxAttributeValueLength-6 {
+		return Attribute{}, fmt.Errorf("Vendor-Specific:%s is too long", vendor)
+	}
+	encoded := binary.BigEndian.AppendUint32(nil, uint32(id))
+	encoded = append(encoded, byte(attributeType), byte(len(value)+2))
+	return Attribute{Type: AttrVendorSpecific, Value: append(encoded, value...)}, nil
+}
+
+func checkLength(value []byte) ([]byte, error) {
+	if len(value) > maxAttributeValueLength {
+		return nil, fmt.Errorf("value is longer than %d bytes", maxAttributeValueLength)
+	}
+	return value, nil
+}
+
+func cutPrefixFold(s string, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
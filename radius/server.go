@@ -0,0 +1,427 @@
+package radius
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// duplicateWindow is how long a response is kept to answer retransmissions of the request with,
+	// RFC 5080 section 2.2.2 suggests a few seconds more than the NAS retries for.
+	duplicateWindow = 30 * time.Second
+	// challengeWindow is how long the user has to send the one-time code once challenged.
+	challengeWindow = 2 * time.Minute
+	maxPendingState = 10000
+)
+
+var (
+	ErrCHAPNotSupported            = errors.New("CHAP only works for vouchers, use PAP")
+	ErrEAPNotSupported             = errors.New("EAP is not supported, use PAP")
+	ErrNoPassword                  = errors.New("Access-Request has no User-Password")
+	ErrNoRoleAllowed               = errors.New("Account has no role allowed network access")
+	ErrChallengeNotFound           = errors.New("Challenge expired, log in again")
+	ErrUnknownClient               = errors.New("Request from an unknown RADIUS client")
+	ErrMissingMessageAuthenticator = errors.New("Request has no Message-Authenticator")
+)
+
+// Client is a NAS allowed to send requests, identified by its source address.
+type Client struct {
+	Name    string
+	Network *net.IPNet
+	Secret  []byte
+	// RequireMessageAuthenticator drops Access-Requests without a Message-Authenticator, which protects
+	// against forged responses (CVE-2024-3596). Requests that carry one are always checked.
+	RequireMessageAuthenticator bool
+	// DynamicAuthorizationPort is where the NAS takes Disconnect and CoA requests, 3799 when it is 0.
+	DynamicAuthorizationPort int
+}
+
+// RoleReply holds the attributes an Access-Accept carries for accounts with Role.
+type RoleReply struct {
+	Role       string
+	Attributes []Attribute
+}
+
+type Config struct {
+	Clients []Client
+	// RoleReplies is in order of precedence: an account gets the reply of the first role it has.
+	RoleReplies []RoleReply
+	// DefaultReply is sent to accounts with none of the roles, unless RequireRole rejects them.
+	DefaultReply []Attribute
+	RequireRole  bool
+}
+
+type cachedResponse struct {
+	data    []byte
+	expires time.Time
+}
+
+// challenge remembers the password an Access-Challenge asked a one-time code for.
+type challenge struct {
+	client   string
+	username string
+	password string
+	expires  time.Time
+}
+
+// Server answers Access-Requests (RFC 2865) with the accounts of the service. Passwords are checked by
+// UsecaseHandler.LoginAccount, so RADIUS logins count towards the same lockout as the API. It also
+// records the sessions reported by Accounting-Requests (RFC 2866).
+type Server struct {
+	config     Config
+	usecase    model.UsecaseHandler
+	accounting model.AccountingUsecase
+	devices    model.DeviceUsecase
+	audit      model.AuditUsecase
+
+	mu         sync.Mutex
+	responses  map[string]*cachedResponse
+	challenges map[string]*challenge
+}
+
+// NewServer answers Access-Requests with usecase, MAC authentication bypass requests with devices, and
+// Accounting-Requests with accounting. Without accounting, Accounting-Requests are dropped, and without
+// devices, MAC authentication bypass requests are logins like any other.
+func NewServer(config Config, usecase model.UsecaseHandler, accounting model.AccountingUsecase, devices model.DeviceUsecase,
+	audit model.AuditUsecase) *Server {
+	return &Server{
+		config:     config,
+		usecase:    usecase,
+		accounting: accounting,
+		devices:    devices,
+		audit:      audit,
+		responses:  make(map[string]*cachedResponse),
+		challenges: make(map[string]*challenge),
+	}
+}
+
+func (s *Server) ListenAndServe(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(conn)
+}
+
+// Serve answers the requests read from conn until it is closed. Each request is handled on its own
+// goroutine.
+func (s *Server) Serve(conn net.PacketConn) error {
+	buffer := make([]byte, maxPacketLength)
+	for {
+		n, address, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		data := append([]byte(nil), buffer[:n]...)
+		go s.handle(conn, address, data)
+	}
+}
+
+func (s *Server) handle(conn net.PacketConn, address net.Addr, data []byte) {
+	client := s.client(address)
+	if client == nil {
+		log.Warn().Str("address", address.String()).Msg(ErrUnknownClient.Error())
+		return
+	}
+	request, err := Parse(data)
+	if err != nil || !s.verify(client, request, data) {
+		log.Warn().Str("client", client.Name).Msg("dropped invalid RADIUS request")
+		return
+	}
+
+	// A retransmission gets the response already sent, it must not count as another login attempt.
+	key := fmt.Sprintf("%s/%d/%x", address, request.Identifier, request.Authenticator)
+	cached, first := s.reserve(key)
+	if !first {
+		if cached != nil {
+			conn.WriteTo(cached, address)
+		}
+		return
+	}
+	var response *Packet
+	if request.Code == CodeAccessRequest {
+		response = s.authenticate(client, address, request)
+	} else {
+		response = s.account(client, address, request)
+	}
+	if response == nil {
+		s.release(key)
+		return
+	}
+	encoded, err := response.Sign(client.Secret)
+	if err != nil {
+		log.Error().Err(err).Str("client", client.Name).Msg("failed to encode RADIUS response")
+		s.release(key)
+		return
+	}
+	s.store(key, encoded)
+	if _, err := conn.WriteTo(encoded, address); err != nil {
+		log.Error().Err(err).Str("client", client.Name).Msg("failed to send RADIUS response")
+	}
+}
+
+// verify checks the request came from the client. Access-Requests are checked with their
+// Message-Authenticator, Accounting-Requests with their authenticator.
+func (s *Server) verify(client *Client, request *Packet, data []byte) bool {
+	switch request.Code {
+	case CodeAccessRequest:
+		if request.HasMessageAuthenticator() {
+			return VerifyMessageAuthenticator(data, client.Secret) == nil
+		}
+		if client.RequireMessageAuthenticator {
+			log.Warn().Str("client", client.Name).Msg(ErrMissingMessageAuthenticator.Error())
+			return false
+		}
+		return true
+	case CodeAccountingRequest:
+		return s.accounting != nil && VerifyRequestAuthenticator(data, client.Secret)
+	default:
+		return false
+	}
+}
+
+// authenticate answers an Access-Request. It returns nil to drop the request, so the NAS tries again or
+// fails over, when the login could not be decided.
+func (s *Server) authenticate(client *Client, address net.Addr, request *Packet) *Packet {
+	username := request.String(AttrUserName)
+	ctx := model.WithRequestInfo(context.Background(), model.RequestInfo{
+		IP:        address.(*net.UDPAddr).IP.String(),
+		UserAgent: strings.TrimSpace("radius/" + client.Name + " " + request.String(AttrCallingStationID)),
+	})
+	if s.devices != nil && isMACAuthentication(client, request, username) {
+		return s.authenticateDevice(ctx, client, request, username)
+	}
+	if request.Get(AttrEAPMessage) != nil {
+		return s.deny(ctx, request, username, ErrEAPNotSupported)
+	}
+	var login model.AccountRequest
+	if value := request.Get(AttrCHAPPassword); value != nil {
+		code, err := chapVoucherCode(request, username, value)
+		if err != nil {
+			return s.deny(ctx, request, username, err)
+		}
+		login = model.AccountRequest{Username: username, Password: code}
+	} else {
+		var response *Packet
+		if login, response = s.papLogin(ctx, client, request, username); response != nil {
+			return response
+		}
+	}
+	rsp, err := s.usecase.LoginAccount(ctx, login)
+	if rsp == nil {
+		log.Error().Err(err).Str("client", client.Name).Str("username", username).Msg("failed to check RADIUS login")
+		return nil
+	}
+	if err == model.ErrLoginOTPRequired {
+		state, err := s.addChallenge(client.Name, login.Username, login.Password)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create RADIUS challenge")
+			return nil
+		}
+		response := request.Response(CodeAccessChallenge)
+		response.Add(AttrState, []byte(state))
+		response.Add(AttrReplyMessage, []byte(rsp.Reason))
+		return response
+	}
+	if !rsp.Success {
+		return reject(request, rsp.Reason)
+	}
+	attributes, ok := s.reply(rsp.Roles)
+	if !ok {
+		return s.deny(ctx, request, rsp.Username, ErrNoRoleAllowed)
+	}
+	response := request.Response(CodeAccessAccept)
+	response.Attributes = append(response.Attributes, attributes...)
+	if rsp.ExpiresAt != nil {
+		response.Attributes = sessionTimeout(response.Attributes, time.Until(*rsp.ExpiresAt))
+	}
+	return response
+}
+
+// papLogin reads the password of a PAP request, or the one-time code when it answers a challenge. It
+// returns the response instead when the request cannot be answered with a login.
+func (s *Server) papLogin(ctx context.Context, client *Client, request *Packet, username string) (model.AccountRequest, *Packet) {
+	hidden := request.Get(AttrUserPassword)
+	if hidden == nil {
+		return model.AccountRequest{}, s.deny(ctx, request, username, ErrNoPassword)
+	}
+	password, err := DecryptUserPassword(hidden, client.Secret, request.Authenticator)
+	if err != nil {
+		return model.AccountRequest{}, s.deny(ctx, request, username, err)
+	}
+	if state := request.Get(AttrState); state != nil {
+		// The answer to a challenge carries the one-time code in User-Password.
+		pending := s.takeChallenge(string(state), client.Name, username)
+		if pending == nil {
+			return model.AccountRequest{}, reject(request, ErrChallengeNotFound.Error())
+		}
+		return model.AccountRequest{Username: pending.username, Password: pending.password, OTP: password}, nil
+	}
+	return model.AccountRequest{Username: username, Password: password}, nil
+}
+
+// chapVoucherCode finds the spelling of the voucher code a CHAP-Password was made from. Only voucher
+// codes are kept in clear, so other accounts cannot log in with CHAP.
+func chapVoucherCode(request *Packet, username string, value []byte) (string, error) {
+	code := model.NormalizeVoucherCode(username)
+	if !model.IsVoucherCode(code) {
+		return "", ErrCHAPNotSupported
+	}
+	challenge := request.Get(AttrCHAPChallenge)
+	if challenge == nil {
+		challenge = request.Authenticator[:]
+	}
+	// Guests type the code the way it is printed, or in lower case.
+	formatted := model.FormatVoucherCode(code)
+	for _, password := range []string{username, code, formatted, strings.ToLower(code), strings.ToLower(formatted)} {
+		if VerifyCHAPPassword(value, password, challenge) {
+			return password, nil
+		}
+	}
+	return "", model.ErrLoginWrongPassword
+}
+
+// sessionTimeout ends the session when the account expires, sooner than a Session-Timeout of the role
+// would.
+func sessionTimeout(attributes []Attribute, remaining time.Duration) []Attribute {
+	seconds := uint32(1)
+	if remaining > time.Second {
+		seconds = uint32(remaining / time.Second)
+	}
+	limited := make([]Attribute, 0, len(attributes)+1)
+	for _, attribute := range attributes {
+		if attribute.Type == AttrSessionTimeout {
+			if len(attribute.Value) == 4 && binary.BigEndian.Uint32(attribute.Value) < seconds {
+				seconds = binary.BigEndian.Uint32(attribute.Value)
+			}
+			continue
+		}
+		limited = append(limited, attribute)
+	}
+	return append(limited, Attribute{Type: AttrSessionTimeout, Value: binary.BigEndian.AppendUint32(nil, seconds)})
+}
+
+// reply picks the attributes for an account with the roles.
+func (s *Server) reply(roles []string) ([]Attribute, bool) {
+	for _, reply := range s.config.RoleReplies {
+		for _, role := range roles {
+			if role == reply.Role {
+				return reply.Attributes, true
+			}
+		}
+	}
+	return s.config.DefaultReply, !s.config.RequireRole
+}
+
+// deny rejects a request LoginAccount did not see, the audit log still records it.
+func (s *Server) deny(ctx context.Context, request *Packet, username string, err error) *Packet {
+	s.audit.Record(ctx, model.AuditEntry{
+		Type:    model.AuditLoginDenied,
+		Target:  username,
+		Outcome: model.AuditOutcomeFailure,
+		Detail:  err.Error(),
+	})
+	return reject(request, err.Error())
+}
+
+func reject(request *Packet, message string) *Packet {
+	response := request.Response(CodeAccessReject)
+	if message != "" {
+		response.Add(AttrReplyMessage, []byte(message))
+	}
+	return response
+}
+
+func (s *Server) client(address net.Addr) *Client {
+	udp, ok := address.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	for i := range s.config.Clients {
+		if s.config.Clients[i].Network.Contains(udp.IP) {
+			return &s.config.Clients[i]
+		}
+	}
+	return nil
+}
+
+// reserve claims the request with the key. It returns false with the response to resend when the
+// request was already answered, or false with nil while it is still being handled.
+func (s *Server) reserve(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if cached, ok := s.responses[key]; ok && now.Before(cached.expires) {
+		return cached.data, false
+	}
+	if len(s.responses) >= maxPendingState {
+		for key, cached := range s.responses {
+			if !now.Before(cached.expires) {
+				delete(s.responses, key)
+			}
+		}
+	}
+	s.responses[key] = &cachedResponse{expires: now.Add(duplicateWindow)}
+	return nil, true
+}
+
+func (s *Server) store(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = &cachedResponse{data: data, expires: time.Now().Add(duplicateWindow)}
+}
+
+func (s *Server) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.responses, key)
+}
+
+func (s *Server) addChallenge(client string, username string, password string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(random)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(s.challenges) >= maxPendingState {
+		for state, pending := range s.challenges {
+			if !now.Before(pending.expires) {
+				delete(s.challenges, state)
+			}
+		}
+	}
+	s.challenges[state] = &challenge{client: client, username: username, password: password, expires: now.Add(challengeWindow)}
+	return state, nil
+}
+
+// takeChallenge returns the challenge with the state once, when the same client asks for the same user.
+func (s *Server) takeChallenge(state string, client string, username string) *challenge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, ok := s.challenges[state]
+	if !ok {
+		return nil
+	}
+	delete(s.challenges, state)
+	if pending.client != client || pending.username != username || !time.Now().Before(pending.expires) {
+		return nil
+	}
+	return pending
+}
@@ -0,0 +1,365 @@
+package radius
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("testing123")
+
+func testConfig(t *testing.T) Config {
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	adminReply, err := ParseAttributes("Service-Type=Administrative;Reply-Message=admin")
+	require.NoError(t, err)
+	staffReply, err := ParseAttributes("Tunnel-Type=VLAN;Tunnel-Medium-Type=IEEE-802;Tunnel-Private-Group-Id=20")
+	require.NoError(t, err)
+	return Config{
+		Clients:     []Client{{Name: "ap1", Network: loopback, Secret: testSecret, RequireMessageAuthenticator: true}},
+		RoleReplies: []RoleReply{{Role: "admin", Attributes: adminReply}, {Role: "staff", Attributes: staffReply}},
+	}
+}
+
+// startServer serves on a loopback port and returns a connection to send requests to it from.
+func startServer(t *testing.T, server *Server) net.Conn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(conn)
+	t.Cleanup(func() { conn.Close() })
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// exchange sends the request and returns the response, or nil when none came.
+func exchange(t *testing.T, conn net.Conn, data []byte) *Packet {
+	_, err := conn.Write(data)
+	require.NoError(t, err)
+	conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	buffer := make([]byte, maxPacketLength)
+	n, err := conn.Read(buffer)
+	if timeout, ok := err.(net.Error); ok && timeout.Timeout() {
+		return nil
+	}
+	require.NoError(t, err)
+	request, err := Parse(data)
+	require.NoError(t, err)
+	require.True(t, VerifyResponse(buffer[:n], request.Authenticator, testSecret))
+	response, err := Parse(buffer[:n])
+	require.NoError(t, err)
+	require.Equal(t, request.Identifier, response.Identifier)
+	return response
+}
+
+func papRequest(t *testing.T, identifier byte, username string, password string, extra ...Attribute) []byte {
+	authenticator, err := NewRequestAuthenticator()
+	require.NoError(t, err)
+	request := &Packet{Code: CodeAccessRequest, Identifier: identifier, Authenticator: authenticator}
+	request.Add(AttrUserName, []byte(username))
+	request.Add(AttrUserPassword, EncryptUserPassword(password, testSecret, authenticator))
+	request.Add(AttrCallingStationID, []byte("AA-BB-CC-DD-EE-FF"))
+	request.Attributes = append(request.Attributes, extra...)
+	data, err := signedRequest(request, testSecret)
+	require.NoError(t, err)
+	return data
+}
+
+func TestServerAccessRequest(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        func(config *Config)
+		buildStubs    func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase)
+		checkResponse func(t *testing.T, response *Packet)
+	}{
+		{
+			name: "AcceptWithRoleReply",
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "password"}).
+					DoAndReturn(func(ctx context.Context, _ model.AccountRequest) (*model.AccountResponse, error) {
+						info := model.RequestInfoFromContext(ctx)
+						require.Equal(t, "127.0.0.1", info.IP)
+						require.Equal(t, "radius/ap1 AA-BB-CC-DD-EE-FF", info.UserAgent)
+						return &model.AccountResponse{Success: true, Username: "alice", Roles: []string{"staff", "admin"}}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessAccept, response.Code)
+				require.Equal(t, []byte{0, 0, 0, 6}, response.Get(AttrServiceType))
+				require.Equal(t, "admin", response.String(AttrReplyMessage))
+				require.Nil(t, response.Get(AttrTunnelType))
+				require.NotNil(t, response.Get(AttrMessageAuthenticator))
+			},
+		},
+		{
+			name: "AcceptWithDefaultReply",
+			config: func(config *Config) {
+				config.DefaultReply = []Attribute{{Type: AttrSessionTimeout, Value: []byte{0, 0, 0, 60}}}
+			},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "alice"}, nil)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessAccept, response.Code)
+				require.Equal(t, []byte{0, 0, 0, 60}, response.Get(AttrSessionTimeout))
+			},
+		},
+		{
+			name: "AcceptUntilExpiry",
+			config: func(config *Config) {
+				config.DefaultReply = []Attribute{{Type: AttrSessionTimeout, Value: []byte{0, 0, 0x0e, 0x10}}}
+			},
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				expiresAt := time.Now().Add(10 * time.Minute)
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "ABCDE23456", ExpiresAt: &expiresAt}, nil)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessAccept, response.Code)
+				require.InDelta(t, 600, response.Integer(AttrSessionTimeout), 2)
+				count := 0
+				for _, attribute := range response.Attributes {
+					if attribute.Type == AttrSessionTimeout {
+						count++
+					}
+				}
+				require.Equal(t, 1, count)
+			},
+		},
+		{
+			name:   "RejectWithoutRole",
+			config: func(config *Config) { config.RequireRole = true },
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Success: true, Username: "alice", Roles: []string{"guest"}}, nil)
+				audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry model.AuditEntry) {
+					require.Equal(t, model.AuditLoginDenied, entry.Type)
+					require.Equal(t, "alice", entry.Target)
+				})
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessReject, response.Code)
+				require.Equal(t, ErrNoRoleAllowed.Error(), response.String(AttrReplyMessage))
+			},
+		},
+		{
+			name: "RejectWrongPassword",
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginWrongPassword.Error()}, model.ErrLoginWrongPassword)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessReject, response.Code)
+				require.Equal(t, model.ErrLoginWrongPassword.Error(), response.String(AttrReplyMessage))
+			},
+		},
+		{
+			name: "RejectBlocked",
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+					Return(&model.AccountResponse{Reason: model.ErrLoginAttemptBlocked.Error()}, model.ErrLoginAttemptBlocked)
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.EqualValues(t, CodeAccessReject, response.Code)
+				require.Equal(t, model.ErrLoginAttemptBlocked.Error(), response.String(AttrReplyMessage))
+			},
+		},
+		{
+			name: "DropInternalError",
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Return(nil, errors.New("database is down"))
+			},
+			checkResponse: func(t *testing.T, response *Packet) {
+				require.Nil(t, response)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			usecase := model.NewMockUsecaseHandler(ctrl)
+			audit := model.NewMockAuditUsecase(ctrl)
+			tc.buildStubs(usecase, audit)
+			config := testConfig(t)
+			if tc.config != nil {
+				tc.config(&config)
+			}
+			conn := startServer(t, NewServer(config, usecase, nil, nil, audit))
+			tc.checkResponse(t, exchange(t, conn, papRequest(t, 1, "alice", "password")))
+		})
+	}
+}
+
+func TestServerRejectsCHAP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	usecase := model.NewMockUsecaseHandler(ctrl)
+	audit := model.NewMockAuditUsecase(ctrl)
+	conn := startServer(t, NewServer(testConfig(t), usecase, nil, nil, audit))
+
+	usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry model.AuditEntry) {
+		require.Equal(t, model.AuditLoginDenied, entry.Type)
+		require.Equal(t, ErrCHAPNotSupported.Error(), entry.Detail)
+	})
+	request := &Packet{Code: CodeAccessRequest, Identifier: 2, Authenticator: [16]byte{1}}
+	request.Add(AttrUserName, []byte("alice"))
+	request.Add(AttrCHAPPassword, make([]byte, 17))
+	data, err := signedRequest(request, testSecret)
+	require.NoError(t, err)
+
+	response := exchange(t, conn, data)
+	require.EqualValues(t, CodeAccessReject, response.Code)
+	require.Equal(t, ErrCHAPNotSupported.Error(), response.String(AttrReplyMessage))
+}
+
+func TestServerVoucherCHAP(t *testing.T) {
+	challenge := []byte("0123456789abcdef")
+	chapRequest := func(t *testing.T, username string, password string) []byte {
+		request := &Packet{Code: CodeAccessRequest, Identifier: 3, Authenticator: [16]byte{1}}
+		request.Add(AttrUserName, []byte(username))
+		request.Add(AttrCHAPPassword, CHAPResponse(5, password, challenge))
+		request.Add(AttrCHAPChallenge, challenge)
+		data, err := signedRequest(request, testSecret)
+		require.NoError(t, err)
+		return data
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		usecase := model.NewMockUsecaseHandler(ctrl)
+		conn := startServer(t, NewServer(testConfig(t), usecase, nil, nil, model.NewMockAuditUsecase(ctrl)))
+
+		usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "abcde-23456", Password: "ABCDE-23456"}).
+			Return(&model.AccountResponse{Success: true, Username: "ABCDE23456"}, nil)
+		response := exchange(t, conn, chapRequest(t, "abcde-23456", "ABCDE-23456"))
+		require.EqualValues(t, CodeAccessAccept, response.Code)
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		usecase := model.NewMockUsecaseHandler(ctrl)
+		audit := model.NewMockAuditUsecase(ctrl)
+		conn := startServer(t, NewServer(testConfig(t), usecase, nil, nil, audit))
+
+		usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+		audit.EXPECT().Record(gomock.Any(), gomock.Any()).Do(func(_ context.Context, entry model.AuditEntry) {
+			require.Equal(t, model.AuditLoginDenied, entry.Type)
+			require.Equal(t, model.ErrLoginWrongPassword.Error(), entry.Detail)
+		})
+		response := exchange(t, conn, chapRequest(t, "ABCDE23456", "ABCDE23457"))
+		require.EqualValues(t, CodeAccessReject, response.Code)
+	})
+}
+
+func TestServerOTPChallenge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	usecase := model.NewMockUsecaseHandler(ctrl)
+	conn := startServer(t, NewServer(testConfig(t), usecase, nil, nil, model.NewMockAuditUsecase(ctrl)))
+
+	gomock.InOrder(
+		usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "password"}).
+			Return(&model.AccountResponse{Reason: model.ErrLoginOTPRequired.Error()}, model.ErrLoginOTPRequired),
+		usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "password", OTP: "123456"}).
+			Return(&model.AccountResponse{Success: true, Username: "alice", Roles: []string{"staff"}}, nil),
+	)
+	response := exchange(t, conn, papRequest(t, 1, "alice", "password"))
+	require.EqualValues(t, CodeAccessChallenge, response.Code)
+	require.Equal(t, model.ErrLoginOTPRequired.Error(), response.String(AttrReplyMessage))
+	state := response.Get(AttrState)
+	require.NotEmpty(t, state)
+
+	response = exchange(t, conn, papRequest(t, 2, "alice", "123456", Attribute{Type: AttrState, Value: state}))
+	require.EqualValues(t, CodeAccessAccept, response.Code)
+	require.Equal(t, []byte{0, 0, 0, 13}, response.Get(AttrTunnelType))
+
+	// A state answers one challenge only.
+	response = exchange(t, conn, papRequest(t, 3, "alice", "123456", Attribute{Type: AttrState, Value: state}))
+	require.EqualValues(t, CodeAccessReject, response.Code)
+	require.Equal(t, ErrChallengeNotFound.Error(), response.String(AttrReplyMessage))
+}
+
+func TestServerRetransmission(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	usecase := model.NewMockUsecaseHandler(ctrl)
+	conn := startServer(t, NewServer(testConfig(t), usecase, nil, nil, model.NewMockAuditUsecase(ctrl)))
+
+	usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).
+		Return(&model.AccountResponse{Reason: model.ErrLoginWrongPassword.Error()}, model.ErrLoginWrongPassword).Times(1)
+	data := papRequest(t, 1, "alice", "password")
+	first := exchange(t, conn, data)
+	again := exchange(t, conn, data)
+	require.Equal(t, first, again)
+}
+
+func TestServerDropsRequest(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  func(config *Config)
+		request func(t *testing.T) []byte
+	}{
+		{
+			name: "UnknownClient",
+			config: func(config *Config) {
+				_, network, _ := net.ParseCIDR("10.0.0.0/8")
+				config.Clients[0].Network = network
+			},
+			request: func(t *testing.T) []byte { return papRequest(t, 1, "alice", "password") },
+		},
+		{
+			name: "MissingMessageAuthenticator",
+			request: func(t *testing.T) []byte {
+				request := &Packet{Code: CodeAccessRequest, Identifier: 1, Authenticator: [16]byte{1}}
+				request.Add(AttrUserName, []byte("alice"))
+				request.Add(AttrUserPassword, EncryptUserPassword("password", testSecret, request.Authenticator))
+				data, err := request.Encode()
+				require.NoError(t, err)
+				return data
+			},
+		},
+		{
+			name: "WrongMessageAuthenticator",
+			request: func(t *testing.T) []byte {
+				data := papRequest(t, 1, "alice", "password")
+				data[len(data)-1] ^= 1
+				return data
+			},
+		},
+		{
+			name:    "WrongSecret",
+			config:  func(config *Config) { config.Clients[0].Secret = []byte("other") },
+			request: func(t *testing.T) []byte { return papRequest(t, 1, "alice", "password") },
+		},
+		{
+			name: "NotAccessRequest",
+			request: func(t *testing.T) []byte {
+				data := papRequest(t, 1, "alice", "password")
+				data[0] = CodeAccessAccept
+				return data
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			usecase := model.NewMockUsecaseHandler(ctrl)
+			usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+			config := testConfig(t)
+			if tc.config != nil {
+				tc.config(&config)
+			}
+			conn := startServer(t, NewServer(config, usecase, nil, nil, model.NewMockAuditUsecase(ctrl)))
+			require.Nil(t, exchange(t, conn, tc.request(t)))
+		})
+	}
+}
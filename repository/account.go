@@ -2,20 +2,33 @@ package repository
 
 import (
 	"context"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	"gorm.io/gorm"
 )
 
+const AccountSourceLDAP = "ldap"
+
 var (
 	ErrAccountIsDuplicated   = errors.New("Account is duplicated")
 	ErrAccountRecordNotFound = errors.New("Account is not found")
 )
 
 type AccountRepository interface {
-	CreateAccount(ctx context.Context, account *Account) error
+	CreateAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error
 	GetAccount(ctx context.Context, username string) (*Account, error)
+	GetAccountByID(ctx context.Context, id uuid.UUID) (*Account, error)
+	DeleteAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error
+	UpdateAccountMetadata(ctx context.Context, account *Account) error
+	UpdateAccountRoles(ctx context.Context, account *Account) error
+	ListAccountsBySource(ctx context.Context, source string) ([]Account, error)
+	// SearchAccounts lists the accounts whose username contains query, by username.
+	SearchAccounts(ctx context.Context, query string, limit int, offset int) ([]Account, error)
+	UpdateAccountLock(ctx context.Context, account *Account) error
+	UpdateAccountPassword(ctx context.Context, account *Account) error
 }
 
 type accountRepository struct {
@@ -28,8 +41,15 @@ func NewAccountRepository(db *gorm.DB) AccountRepository {
 	}
 }
 
-func (r *accountRepository) CreateAccount(ctx context.Context, account *Account) error {
-	if err := r.db.Create(&account).Error; err != nil {
+// CreateAccount stores the account and its outbox messages in one transaction.
+func (r *accountRepository) CreateAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+		return createOutboxMessages(tx, messages...)
+	})
+	if err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			return ErrAccountIsDuplicated
 		}
@@ -48,3 +68,102 @@ func (r *accountRepository) GetAccount(ctx context.Context, username string) (*A
 	}
 	return account, nil
 }
+
+func (r *accountRepository) GetAccountByID(ctx context.Context, id uuid.UUID) (*Account, error) {
+	account := &Account{}
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountRecordNotFound
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+// DeleteAccount deletes the account with its identities and stores its outbox messages in one transaction.
+func (r *accountRepository) DeleteAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(account)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAccountRecordNotFound
+		}
+		if err := tx.Where("account_id = ?", account.ID).Delete(&Identity{}).Error; err != nil {
+			return err
+		}
+		return createOutboxMessages(tx, messages...)
+	})
+}
+
+func (r *accountRepository) UpdateAccountMetadata(ctx context.Context, account *Account) error {
+	result := r.db.WithContext(ctx).Model(account).Update("metadata", account.Metadata)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountRecordNotFound
+	}
+	return nil
+}
+
+func (r *accountRepository) UpdateAccountRoles(ctx context.Context, account *Account) error {
+	result := r.db.WithContext(ctx).Model(account).Update("roles", account.Roles)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountRecordNotFound
+	}
+	return nil
+}
+
+func (r *accountRepository) ListAccountsBySource(ctx context.Context, source string) ([]Account, error) {
+	var accounts []Account
+	if err := r.db.WithContext(ctx).Where("source = ?", source).Order("username").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *accountRepository) SearchAccounts(ctx context.Context, query string, limit int, offset int) ([]Account, error) {
+	var accounts []Account
+	db := r.db.WithContext(ctx)
+	if query != "" {
+		db = db.Where("username ILIKE ?", "%"+likeEscaper.Replace(query)+"%")
+	}
+	if err := db.Order("username").Limit(limit).Offset(offset).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *accountRepository) UpdateAccountLock(ctx context.Context, account *Account) error {
+	result := r.db.WithContext(ctx).Model(account).Update("locked_at", account.LockedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountRecordNotFound
+	}
+	return nil
+}
+
+// UpdateAccountPassword saves the password of the account and whether it has to be reset.
+func (r *accountRepository) UpdateAccountPassword(ctx context.Context, account *Account) error {
+	result := r.db.WithContext(ctx).Model(account).Updates(map[string]interface{}{
+		"hashed_password":         account.HashedPassword,
+		"password_reset_required": account.PasswordResetRequired,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountRecordNotFound
+	}
+	return nil
+}
+
+// likeEscaper makes the wildcards of a search match themselves in a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
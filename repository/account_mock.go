@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
 )
 
 // MockAccountRepository is a mock of AccountRepository interface.
@@ -35,17 +36,41 @@ func (m *MockAccountRepository) EXPECT() *MockAccountRepositoryMockRecorder {
 }
 
 // CreateAccount mocks base method.
-func (m *MockAccountRepository) CreateAccount(ctx context.Context, account *Account) error {
+func (m *MockAccountRepository) CreateAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAccount", ctx, account)
+	varargs := []interface{}{ctx, account}
+	for _, a := range messages {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateAccount", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateAccount indicates an expected call of CreateAccount.
-func (mr *MockAccountRepositoryMockRecorder) CreateAccount(ctx, account interface{}) *gomock.Call {
+func (mr *MockAccountRepositoryMockRecorder) CreateAccount(ctx, account interface{}, messages ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAccountRepository)(nil).CreateAccount), ctx, account)
+	varargs := append([]interface{}{ctx, account}, messages...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAccountRepository)(nil).CreateAccount), varargs...)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockAccountRepository) DeleteAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, account}
+	for _, a := range messages {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteAccount", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockAccountRepositoryMockRecorder) DeleteAccount(ctx, account interface{}, messages ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, account}, messages...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAccountRepository)(nil).DeleteAccount), varargs...)
 }
 
 // GetAccount mocks base method.
@@ -62,3 +87,104 @@ func (mr *MockAccountRepositoryMockRecorder) GetAccount(ctx, username interface{
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccountRepository)(nil).GetAccount), ctx, username)
 }
+
+// GetAccountByID mocks base method.
+func (m *MockAccountRepository) GetAccountByID(ctx context.Context, id uuid.UUID) (*Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountByID", ctx, id)
+	ret0, _ := ret[0].(*Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountByID indicates an expected call of GetAccountByID.
+func (mr *MockAccountRepositoryMockRecorder) GetAccountByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByID", reflect.TypeOf((*MockAccountRepository)(nil).GetAccountByID), ctx, id)
+}
+
+// ListAccountsBySource mocks base method.
+func (m *MockAccountRepository) ListAccountsBySource(ctx context.Context, source string) ([]Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountsBySource", ctx, source)
+	ret0, _ := ret[0].([]Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountsBySource indicates an expected call of ListAccountsBySource.
+func (mr *MockAccountRepositoryMockRecorder) ListAccountsBySource(ctx, source interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountsBySource", reflect.TypeOf((*MockAccountRepository)(nil).ListAccountsBySource), ctx, source)
+}
+
+// SearchAccounts mocks base method.
+func (m *MockAccountRepository) SearchAccounts(ctx context.Context, query string, limit, offset int) ([]Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAccounts", ctx, query, limit, offset)
+	ret0, _ := ret[0].([]Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAccounts indicates an expected call of SearchAccounts.
+func (mr *MockAccountRepositoryMockRecorder) SearchAccounts(ctx, query, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAccounts", reflect.TypeOf((*MockAccountRepository)(nil).SearchAccounts), ctx, query, limit, offset)
+}
+
+// UpdateAccountLock mocks base method.
+func (m *MockAccountRepository) UpdateAccountLock(ctx context.Context, account *Account) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountLock", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccountLock indicates an expected call of UpdateAccountLock.
+func (mr *MockAccountRepositoryMockRecorder) UpdateAccountLock(ctx, account interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountLock", reflect.TypeOf((*MockAccountRepository)(nil).UpdateAccountLock), ctx, account)
+}
+
+// UpdateAccountMetadata mocks base method.
+func (m *MockAccountRepository) UpdateAccountMetadata(ctx context.Context, account *Account) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountMetadata", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccountMetadata indicates an expected call of UpdateAccountMetadata.
+func (mr *MockAccountRepositoryMockRecorder) UpdateAccountMetadata(ctx, account interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountMetadata", reflect.TypeOf((*MockAccountRepository)(nil).UpdateAccountMetadata), ctx, account)
+}
+
+// UpdateAccountPassword mocks base method.
+func (m *MockAccountRepository) UpdateAccountPassword(ctx context.Context, account *Account) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountPassword", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccountPassword indicates an expected call of UpdateAccountPassword.
+func (mr *MockAccountRepositoryMockRecorder) UpdateAccountPassword(ctx, account interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountPassword", reflect.TypeOf((*MockAccountRepository)(nil).UpdateAccountPassword), ctx, account)
+}
+
+// UpdateAccountRoles mocks base method.
+func (m *MockAccountRepository) UpdateAccountRoles(ctx context.Context, account *Account) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountRoles", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccountRoles indicates an expected call of UpdateAccountRoles.
+func (mr *MockAccountRepositoryMockRecorder) UpdateAccountRoles(ctx, account interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountRoles", reflect.TypeOf((*MockAccountRepository)(nil).UpdateAccountRoles), ctx, account)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -44,6 +45,7 @@ func getRandomAccount(t *testing.T) *Account {
 	return &Account{
 		Username:       userName,
 		HashedPassword: hashedPassword,
+		Type:           "user",
 	}
 }
 
@@ -58,10 +60,10 @@ func TestCreateAccount(t *testing.T) {
 
 	// 设置 mock 预期行为
 	mock.ExpectBegin()
-	sqlQuery := `INSERT INTO "accounts" ("id","username","hashed_password","created_at","updated_at","deleted_at") 
-		VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+	sqlQuery := `INSERT INTO "accounts" ("id","username","hashed_password","metadata","type","owner_id","description","roles","source","locked_at","password_reset_required","created_at","updated_at","deleted_at") 
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING "id"`
 	mock.ExpectQuery(sqlQuery).
-		WithArgs(account.ID, account.Username, account.HashedPassword, AnyTime{}, AnyTime{}, nil).
+		WithArgs(account.ID, account.Username, account.HashedPassword, account.Metadata, account.Type, nil, "", "", "", nil, false, AnyTime{}, AnyTime{}, nil).
 		WillReturnRows(rows)
 	mock.ExpectCommit()
 
@@ -69,6 +71,34 @@ func TestCreateAccount(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCreateAccountWithOutboxMessage(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	account := getRandomAccount(t)
+	message := &OutboxMessage{
+		IdempotencyKey: uuid.New().String(),
+		Type:           "account.created",
+		Payload:        "{}",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "accounts" ("id","username","hashed_password","metadata","type","owner_id","description","roles","source","locked_at","password_reset_required","created_at","updated_at","deleted_at") 
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING "id"`).
+		WithArgs(account.ID, account.Username, account.HashedPassword, account.Metadata, account.Type, nil, "", "", "", nil, false, AnyTime{}, AnyTime{}, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(account.ID))
+	mock.ExpectQuery(`INSERT INTO "outbox" ("created_at","idempotency_key","type","payload","delivered_sinks","attempts","last_error","next_attempt_at","published_at","failed_at") 
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`).
+		WithArgs(AnyTime{}, message.IdempotencyKey, message.Type, message.Payload, "", 0, "", AnyTime{}, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.CreateAccount(context.Background(), account, message)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), message.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestCreateAccountDuplicate(t *testing.T) {
 	repo, mockDB, mock := setUpAccountMock(t)
 	defer mockDB.Close()
@@ -76,10 +106,10 @@ func TestCreateAccountDuplicate(t *testing.T) {
 	account := getRandomAccount(t)
 
 	mock.ExpectBegin()
-	sqlQuery := `INSERT INTO "accounts" ("id","username","hashed_password","created_at","updated_at","deleted_at") 
-		VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+	sqlQuery := `INSERT INTO "accounts" ("id","username","hashed_password","metadata","type","owner_id","description","roles","source","locked_at","password_reset_required","created_at","updated_at","deleted_at") 
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING "id"`
 	mock.ExpectQuery(sqlQuery).
-		WithArgs(account.ID, account.Username, account.HashedPassword, AnyTime{}, AnyTime{}, nil).
+		WithArgs(account.ID, account.Username, account.HashedPassword, account.Metadata, account.Type, nil, "", "", "", nil, false, AnyTime{}, AnyTime{}, nil).
 		WillReturnError(gorm.ErrDuplicatedKey)
 	mock.ExpectRollback()
 
@@ -120,3 +150,116 @@ func TestGetAccountNotExisted(t *testing.T) {
 	require.EqualError(t, err, ErrAccountRecordNotFound.Error())
 	require.Nil(t, getAccount)
 }
+
+func TestGetAccountByIDNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	id := uuid.New()
+	sqlQuery := `SELECT * FROM "accounts" WHERE id = $1 AND "accounts"."deleted_at" IS NULL ORDER BY "accounts"."id" LIMIT 1`
+	mock.ExpectQuery(sqlQuery).
+		WithArgs(id).
+		WillReturnError(gorm.ErrRecordNotFound)
+	getAccount, err := repo.GetAccountByID(context.Background(), id)
+	require.EqualError(t, err, ErrAccountRecordNotFound.Error())
+	require.Nil(t, getAccount)
+}
+
+func TestUpdateAccountMetadata(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	account := getRandomAccount(t)
+	account.ID = uuid.New()
+	account.Metadata = `{"plan":"pro"}`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "accounts" SET "metadata"=$1,"updated_at"=$2 WHERE "accounts"."deleted_at" IS NULL AND "id" = $3`).
+		WithArgs(account.Metadata, AnyTime{}, account.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.UpdateAccountMetadata(context.Background(), account)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAccountRoles(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	account := getRandomAccount(t)
+	account.ID = uuid.New()
+	account.Roles = "admin staff"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "accounts" SET "roles"=$1,"updated_at"=$2 WHERE "accounts"."deleted_at" IS NULL AND "id" = $3`).
+		WithArgs(account.Roles, AnyTime{}, account.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.UpdateAccountRoles(context.Background(), account)
+	require.EqualError(t, err, ErrAccountRecordNotFound.Error())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAccountsBySource(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "username", "roles", "source"}).
+		AddRow(uuid.New(), "alice", "admin", AccountSourceLDAP).
+		AddRow(uuid.New(), "bob", "", AccountSourceLDAP)
+	mock.ExpectQuery(`SELECT * FROM "accounts" WHERE source = $1 AND "accounts"."deleted_at" IS NULL ORDER BY username`).
+		WithArgs(AccountSourceLDAP).
+		WillReturnRows(rows)
+
+	accounts, err := repo.ListAccountsBySource(context.Background(), AccountSourceLDAP)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, "admin", accounts[0].Roles)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchAccounts(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "accounts" WHERE username ILIKE $1 AND "accounts"."deleted_at" IS NULL ORDER BY username LIMIT 20 OFFSET 40`).
+		WithArgs(`%a\_b\%%`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(uuid.New(), "a_b%c"))
+
+	accounts, err := repo.SearchAccounts(context.Background(), "a_b%", 20, 40)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAccountLock(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	now := time.Now()
+	account := &Account{ID: uuid.New(), LockedAt: &now}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "accounts" SET "locked_at"=$1,"updated_at"=$2 WHERE "accounts"."deleted_at" IS NULL AND "id" = $3`).
+		WithArgs(AnyTime{}, AnyTime{}, account.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	require.EqualError(t, repo.UpdateAccountLock(context.Background(), account), ErrAccountRecordNotFound.Error())
+}
+
+func TestUpdateAccountPassword(t *testing.T) {
+	repo, mockDB, mock := setUpAccountMock(t)
+	defer mockDB.Close()
+
+	account := &Account{ID: uuid.New(), HashedPassword: "hash", PasswordResetRequired: true}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "accounts" SET "hashed_password"=$1,"password_reset_required"=$2,"updated_at"=$3 WHERE "accounts"."deleted_at" IS NULL AND "id" = $4`).
+		WithArgs("hash", true, AnyTime{}, account.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.UpdateAccountPassword(context.Background(), account))
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrAccountingSessionNotFound = errors.New("Accounting session is not found")
+
+type AccountingRepository interface {
+	// SaveAccountingSession creates the session, or updates the one with the same client and session ID.
+	// A session that stopped is not updated again, so a late Interim-Update cannot bring it back online.
+	SaveAccountingSession(ctx context.Context, session *AccountingSession) error
+	// StopClientSessions stops the sessions of the client that are still online, when it reports that it
+	// restarted.
+	StopClientSessions(ctx context.Context, client string, stoppedAt time.Time, cause string) (int64, error)
+	GetAccountingSession(ctx context.Context, id uuid.UUID) (*AccountingSession, error)
+	// ListOnlineSessions lists the sessions not stopped yet, oldest first.
+	ListOnlineSessions(ctx context.Context) ([]AccountingSession, error)
+	// ListAccountingSessions lists the sessions of the account that were online since the time, newest
+	// first.
+	ListAccountingSessions(ctx context.Context, accountID uuid.UUID, since time.Time) ([]AccountingSession, error)
+}
+
+type accountingRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountingRepository(db *gorm.DB) AccountingRepository {
+	return &accountingRepository{
+		db: db,
+	}
+}
+
+func (r *accountingRepository) SaveAccountingSession(ctx context.Context, session *AccountingSession) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "client"}, {Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"account_id", "username", "nas_address", "nas_identifier",
+			"calling_station_id", "called_station_id", "framed_ip_address", "updated_at", "stopped_at",
+			"session_time", "input_octets", "output_octets", "terminate_cause"}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: `"accounting_sessions"."stopped_at" IS NULL`}}},
+	}).Create(session).Error
+}
+
+func (r *accountingRepository) StopClientSessions(ctx context.Context, client string, stoppedAt time.Time, cause string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&AccountingSession{}).
+		Where("client = ? AND stopped_at IS NULL", client).
+		Updates(map[string]interface{}{"stopped_at": stoppedAt, "terminate_cause": cause})
+	return result.RowsAffected, result.Error
+}
+
+func (r *accountingRepository) GetAccountingSession(ctx context.Context, id uuid.UUID) (*AccountingSession, error) {
+	session := &AccountingSession{}
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountingSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *accountingRepository) ListOnlineSessions(ctx context.Context) ([]AccountingSession, error) {
+	var sessions []AccountingSession
+	err := r.db.WithContext(ctx).
+		Where("stopped_at IS NULL").
+		Order("started_at").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *accountingRepository) ListAccountingSessions(ctx context.Context, accountID uuid.UUID, since time.Time) ([]AccountingSession, error) {
+	var sessions []AccountingSession
+	err := r.db.WithContext(ctx).
+		Where("account_id = ? AND (stopped_at IS NULL OR stopped_at >= ?)", accountID, since).
+		Order("started_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
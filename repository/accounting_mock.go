@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: accounting.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockAccountingRepository is a mock of AccountingRepository interface.
+type MockAccountingRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountingRepositoryMockRecorder
+}
+
+// MockAccountingRepositoryMockRecorder is the mock recorder for MockAccountingRepository.
+type MockAccountingRepositoryMockRecorder struct {
+	mock *MockAccountingRepository
+}
+
+// NewMockAccountingRepository creates a new mock instance.
+func NewMockAccountingRepository(ctrl *gomock.Controller) *MockAccountingRepository {
+	mock := &MockAccountingRepository{ctrl: ctrl}
+	mock.recorder = &MockAccountingRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountingRepository) EXPECT() *MockAccountingRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetAccountingSession mocks base method.
+func (m *MockAccountingRepository) GetAccountingSession(ctx context.Context, id uuid.UUID) (*AccountingSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountingSession", ctx, id)
+	ret0, _ := ret[0].(*AccountingSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountingSession indicates an expected call of GetAccountingSession.
+func (mr *MockAccountingRepositoryMockRecorder) GetAccountingSession(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountingSession", reflect.TypeOf((*MockAccountingRepository)(nil).GetAccountingSession), ctx, id)
+}
+
+// ListAccountingSessions mocks base method.
+func (m *MockAccountingRepository) ListAccountingSessions(ctx context.Context, accountID uuid.UUID, since time.Time) ([]AccountingSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountingSessions", ctx, accountID, since)
+	ret0, _ := ret[0].([]AccountingSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountingSessions indicates an expected call of ListAccountingSessions.
+func (mr *MockAccountingRepositoryMockRecorder) ListAccountingSessions(ctx, accountID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountingSessions", reflect.TypeOf((*MockAccountingRepository)(nil).ListAccountingSessions), ctx, accountID, since)
+}
+
+// ListOnlineSessions mocks base method.
+func (m *MockAccountingRepository) ListOnlineSessions(ctx context.Context) ([]AccountingSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOnlineSessions", ctx)
+	ret0, _ := ret[0].([]AccountingSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOnlineSessions indicates an expected call of ListOnlineSessions.
+func (mr *MockAccountingRepositoryMockRecorder) ListOnlineSessions(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOnlineSessions", reflect.TypeOf((*MockAccountingRepository)(nil).ListOnlineSessions), ctx)
+}
+
+// SaveAccountingSession mocks base method.
+func (m *MockAccountingRepository) SaveAccountingSession(ctx context.Context, session *AccountingSession) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAccountingSession", ctx, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAccountingSession indicates an expected call of SaveAccountingSession.
+func (mr *MockAccountingRepositoryMockRecorder) SaveAccountingSession(ctx, session interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAccountingSession", reflect.TypeOf((*MockAccountingRepository)(nil).SaveAccountingSession), ctx, session)
+}
+
+// StopClientSessions mocks base method.
+func (m *MockAccountingRepository) StopClientSessions(ctx context.Context, client string, stoppedAt time.Time, cause string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StopClientSessions", ctx, client, stoppedAt, cause)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StopClientSessions indicates an expected call of StopClientSessions.
+func (mr *MockAccountingRepositoryMockRecorder) StopClientSessions(ctx, client, stoppedAt, cause interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopClientSessions", reflect.TypeOf((*MockAccountingRepository)(nil).StopClientSessions), ctx, client, stoppedAt, cause)
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpAccountingMock(t *testing.T) (AccountingRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewAccountingRepository(gormDB), mockDb, mock
+}
+
+func TestSaveAccountingSession(t *testing.T) {
+	repo, mockDB, mock := setUpAccountingMock(t)
+	defer mockDB.Close()
+
+	accountID := uuid.New()
+	session := &AccountingSession{
+		ID:          uuid.New(),
+		AccountID:   &accountID,
+		Username:    "alice",
+		Client:      "ap1",
+		SessionID:   "0001",
+		NASAddress:  "10.0.0.2",
+		StartedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		SessionTime: 60,
+		InputOctets: 1024,
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "accounting_sessions" ("id","account_id","username","client","session_id","nas_address","nas_identifier","calling_station_id","called_station_id","framed_ip_address","started_at","updated_at","stopped_at","session_time","input_octets","output_octets","terminate_cause") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17) `+
+		`ON CONFLICT ("client","session_id") DO UPDATE SET "account_id"="excluded"."account_id","username"="excluded"."username","nas_address"="excluded"."nas_address","nas_identifier"="excluded"."nas_identifier","calling_station_id"="excluded"."calling_station_id","called_station_id"="excluded"."called_station_id","framed_ip_address"="excluded"."framed_ip_address","updated_at"="excluded"."updated_at","stopped_at"="excluded"."stopped_at","session_time"="excluded"."session_time","input_octets"="excluded"."input_octets","output_octets"="excluded"."output_octets","terminate_cause"="excluded"."terminate_cause" `+
+		`WHERE "accounting_sessions"."stopped_at" IS NULL`).
+		WithArgs(session.ID, &accountID, "alice", "ap1", "0001", "10.0.0.2", "", "", "", "", AnyTime{}, AnyTime{}, nil,
+			int64(60), int64(1024), int64(0), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.SaveAccountingSession(context.Background(), session))
+}
+
+func TestStopClientSessions(t *testing.T) {
+	repo, mockDB, mock := setUpAccountingMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "accounting_sessions" SET "stopped_at"=$1,"terminate_cause"=$2,"updated_at"=$3 WHERE client = $4 AND stopped_at IS NULL`).
+		WithArgs(AnyTime{}, "NAS-Reboot", AnyTime{}, "ap1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	stopped, err := repo.StopClientSessions(context.Background(), "ap1", time.Now(), "NAS-Reboot")
+	require.NoError(t, err)
+	require.EqualValues(t, 3, stopped)
+}
+
+func TestGetAccountingSessionNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpAccountingMock(t)
+	defer mockDB.Close()
+
+	id := uuid.New()
+	mock.ExpectQuery(`SELECT * FROM "accounting_sessions" WHERE id = $1 ORDER BY "accounting_sessions"."id" LIMIT 1`).
+		WithArgs(id).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := repo.GetAccountingSession(context.Background(), id)
+	require.EqualError(t, err, ErrAccountingSessionNotFound.Error())
+}
+
+func TestListAccountingSessions(t *testing.T) {
+	repo, mockDB, mock := setUpAccountingMock(t)
+	defer mockDB.Close()
+
+	accountID := uuid.New()
+	since := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(`SELECT * FROM "accounting_sessions" WHERE account_id = $1 AND (stopped_at IS NULL OR stopped_at >= $2) ORDER BY started_at DESC`).
+		WithArgs(accountID, since).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "session_id", "input_octets"}).
+			AddRow(uuid.New(), "alice", "0002", 10).
+			AddRow(uuid.New(), "alice", "0001", 20))
+
+	sessions, err := repo.ListAccountingSessions(context.Background(), accountID, since)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.Equal(t, "0002", sessions[0].SessionID)
+	require.EqualValues(t, 20, sessions[1].InputOctets)
+}
+
+func TestListOnlineSessions(t *testing.T) {
+	repo, mockDB, mock := setUpAccountingMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "accounting_sessions" WHERE stopped_at IS NULL ORDER BY started_at`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(uuid.New(), "alice"))
+
+	sessions, err := repo.ListOnlineSessions(context.Background())
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAuditEventNotFound      = errors.New("Audit event is not found")
+	ErrAuditCheckpointNotFound = errors.New("Audit checkpoint is not found")
+)
+
+type AuditEventFilter struct {
+	Type       string
+	TypePrefix string
+	Actor      string
+	Target     string
+	Outcome    string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+	// Newest lists the latest events first.
+	Newest bool
+}
+
+type AuditRepository interface {
+	CreateAuditEvent(ctx context.Context, event *AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter AuditEventFilter) ([]AuditEvent, error)
+	IterateAuditEvents(ctx context.Context, filter AuditEventFilter, fn func(event *AuditEvent) error) error
+	LastAuditEvent(ctx context.Context) (*AuditEvent, error)
+	CreateAuditCheckpoint(ctx context.Context, checkpoint *AuditCheckpoint) error
+	LastAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error)
+	ListAuditCheckpoints(ctx context.Context) ([]AuditCheckpoint, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+// CreateAuditEvent links the event to the current head of the chain and appends it.
+func (r *auditRepository) CreateAuditEvent(ctx context.Context, event *AuditEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockID).Error; err != nil {
+			return err
+		}
+
+		var prevHash []string
+		if err := tx.Model(&AuditEvent{}).Order("id DESC").Limit(1).Pluck("hash", &prevHash).Error; err != nil {
+			return err
+		}
+		event.PrevHash = ""
+		if len(prevHash) > 0 {
+			event.PrevHash = prevHash[0]
+		}
+		event.CreatedAt = chainTime(time.Now())
+		event.Hash = AuditEventHash(event)
+
+		return tx.Create(event).Error
+	})
+}
+
+func (r *auditRepository) ListAuditEvents(ctx context.Context, filter AuditEventFilter) ([]AuditEvent, error) {
+	var events []AuditEvent
+	if err := r.filterQuery(ctx, filter).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *auditRepository) IterateAuditEvents(ctx context.Context, filter AuditEventFilter, fn func(event *AuditEvent) error) error {
+	rows, err := r.filterQuery(ctx, filter).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event := &AuditEvent{}
+		if err := r.db.ScanRows(rows, event); err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *auditRepository) LastAuditEvent(ctx context.Context) (*AuditEvent, error) {
+	event := &AuditEvent{}
+	if err := r.db.WithContext(ctx).Order("id DESC").First(event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAuditEventNotFound
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+func (r *auditRepository) CreateAuditCheckpoint(ctx context.Context, checkpoint *AuditCheckpoint) error {
+	if checkpoint.CreatedAt.IsZero() {
+		checkpoint.CreatedAt = chainTime(time.Now())
+	}
+	return r.db.WithContext(ctx).Create(checkpoint).Error
+}
+
+func (r *auditRepository) LastAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error) {
+	checkpoint := &AuditCheckpoint{}
+	if err := r.db.WithContext(ctx).Order("id DESC").First(checkpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAuditCheckpointNotFound
+		}
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+func (r *auditRepository) ListAuditCheckpoints(ctx context.Context) ([]AuditCheckpoint, error) {
+	var checkpoints []AuditCheckpoint
+	if err := r.db.WithContext(ctx).Order("id").Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+func (r *auditRepository) filterQuery(ctx context.Context, filter AuditEventFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&AuditEvent{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.TypePrefix != "" {
+		query = query.Where("type LIKE ?", likeEscaper.Replace(filter.TypePrefix)+"%")
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Target != "" {
+		query = query.Where("target = ?", filter.Target)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	if filter.Newest {
+		return query.Order("id DESC")
+	}
+	return query.Order("id")
+}
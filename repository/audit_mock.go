@@ -0,0 +1,137 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: audit.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAuditRepository is a mock of AuditRepository interface.
+type MockAuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepositoryMockRecorder
+}
+
+// MockAuditRepositoryMockRecorder is the mock recorder for MockAuditRepository.
+type MockAuditRepositoryMockRecorder struct {
+	mock *MockAuditRepository
+}
+
+// NewMockAuditRepository creates a new mock instance.
+func NewMockAuditRepository(ctrl *gomock.Controller) *MockAuditRepository {
+	mock := &MockAuditRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepository) EXPECT() *MockAuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateAuditCheckpoint mocks base method.
+func (m *MockAuditRepository) CreateAuditCheckpoint(ctx context.Context, checkpoint *AuditCheckpoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditCheckpoint", ctx, checkpoint)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditCheckpoint indicates an expected call of CreateAuditCheckpoint.
+func (mr *MockAuditRepositoryMockRecorder) CreateAuditCheckpoint(ctx, checkpoint interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditCheckpoint", reflect.TypeOf((*MockAuditRepository)(nil).CreateAuditCheckpoint), ctx, checkpoint)
+}
+
+// CreateAuditEvent mocks base method.
+func (m *MockAuditRepository) CreateAuditEvent(ctx context.Context, event *AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditEvent indicates an expected call of CreateAuditEvent.
+func (mr *MockAuditRepositoryMockRecorder) CreateAuditEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditEvent", reflect.TypeOf((*MockAuditRepository)(nil).CreateAuditEvent), ctx, event)
+}
+
+// IterateAuditEvents mocks base method.
+func (m *MockAuditRepository) IterateAuditEvents(ctx context.Context, filter AuditEventFilter, fn func(*AuditEvent) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IterateAuditEvents", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IterateAuditEvents indicates an expected call of IterateAuditEvents.
+func (mr *MockAuditRepositoryMockRecorder) IterateAuditEvents(ctx, filter, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IterateAuditEvents", reflect.TypeOf((*MockAuditRepository)(nil).IterateAuditEvents), ctx, filter, fn)
+}
+
+// LastAuditCheckpoint mocks base method.
+func (m *MockAuditRepository) LastAuditCheckpoint(ctx context.Context) (*AuditCheckpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastAuditCheckpoint", ctx)
+	ret0, _ := ret[0].(*AuditCheckpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastAuditCheckpoint indicates an expected call of LastAuditCheckpoint.
+func (mr *MockAuditRepositoryMockRecorder) LastAuditCheckpoint(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastAuditCheckpoint", reflect.TypeOf((*MockAuditRepository)(nil).LastAuditCheckpoint), ctx)
+}
+
+// LastAuditEvent mocks base method.
+func (m *MockAuditRepository) LastAuditEvent(ctx context.Context) (*AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastAuditEvent", ctx)
+	ret0, _ := ret[0].(*AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastAuditEvent indicates an expected call of LastAuditEvent.
+func (mr *MockAuditRepositoryMockRecorder) LastAuditEvent(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastAuditEvent", reflect.TypeOf((*MockAuditRepository)(nil).LastAuditEvent), ctx)
+}
+
+// ListAuditCheckpoints mocks base method.
+func (m *MockAuditRepository) ListAuditCheckpoints(ctx context.Context) ([]AuditCheckpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditCheckpoints", ctx)
+	ret0, _ := ret[0].([]AuditCheckpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditCheckpoints indicates an expected call of ListAuditCheckpoints.
+func (mr *MockAuditRepositoryMockRecorder) ListAuditCheckpoints(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditCheckpoints", reflect.TypeOf((*MockAuditRepository)(nil).ListAuditCheckpoints), ctx)
+}
+
+// ListAuditEvents mocks base method.
+func (m *MockAuditRepository) ListAuditEvents(ctx context.Context, filter AuditEventFilter) ([]AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", ctx, filter)
+	ret0, _ := ret[0].([]AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockAuditRepositoryMockRecorder) ListAuditEvents(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockAuditRepository)(nil).ListAuditEvents), ctx, filter)
+}
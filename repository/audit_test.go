@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ambroseqiu/senao_hw/util"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpAuditMock(t *testing.T) (AuditRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewAuditRepository(gormDB), mockDb, mock
+}
+
+func TestCreateAuditEvent(t *testing.T) {
+	repo, mockDB, mock := setUpAuditMock(t)
+	defer mockDB.Close()
+
+	event := &AuditEvent{
+		Type:      "login.succeeded",
+		Actor:     "anonymous",
+		Target:    util.RandomString(8),
+		IP:        "127.0.0.1",
+		UserAgent: "go-test",
+		RequestID: util.RandomString(12),
+		Outcome:   "success",
+	}
+
+	prevHash := util.RandomString(64)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock($1)`).
+		WithArgs(auditChainLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT "hash" FROM "audit_events" ORDER BY id DESC LIMIT 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(prevHash))
+	sqlQuery := `INSERT INTO "audit_events" ("created_at","type","actor","target","ip","user_agent","request_id","outcome","detail","prev_hash","hash") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING "id"`
+	mock.ExpectQuery(sqlQuery).
+		WithArgs(AnyTime{}, event.Type, event.Actor, event.Target, event.IP, event.UserAgent, event.RequestID, event.Outcome, event.Detail, prevHash, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.CreateAuditEvent(context.Background(), event)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, uint64(1), event.ID)
+	require.Equal(t, prevHash, event.PrevHash)
+	require.Equal(t, AuditEventHash(event), event.Hash)
+}
+
+func TestListAuditEvents(t *testing.T) {
+	repo, mockDB, mock := setUpAuditMock(t)
+	defer mockDB.Close()
+
+	target := util.RandomString(8)
+	since := time.Now().Add(-time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "created_at", "type", "actor", "target", "ip", "user_agent", "request_id", "outcome", "detail", "prev_hash", "hash"}).
+		AddRow(1, time.Now(), "login.wrong_password", "anonymous", target, "127.0.0.1", "go-test", "req1", "failure", "Wrong password", "", "hash1").
+		AddRow(2, time.Now(), "login.succeeded", "anonymous", target, "127.0.0.1", "go-test", "req2", "success", "", "hash1", "hash2")
+
+	sqlQuery := `SELECT * FROM "audit_events" WHERE target = $1 AND created_at >= $2 ORDER BY id LIMIT 10`
+	mock.ExpectQuery(sqlQuery).
+		WithArgs(target, since).
+		WillReturnRows(rows)
+
+	events, err := repo.ListAuditEvents(context.Background(), AuditEventFilter{
+		Target: target,
+		Since:  since,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "login.succeeded", events[1].Type)
+}
+
+func TestListNewestAuditEvents(t *testing.T) {
+	repo, mockDB, mock := setUpAuditMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "audit_events" WHERE target = $1 AND outcome = $2 ORDER BY id DESC LIMIT 50`).
+		WithArgs("alice", "failure").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(2, "login.wrong_otp").AddRow(1, "login.wrong_password"))
+
+	events, err := repo.ListAuditEvents(context.Background(), AuditEventFilter{
+		Target:  "alice",
+		Outcome: "failure",
+		Limit:   50,
+		Newest:  true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), events[0].ID)
+}
+
+func TestListAuditEventsByTypePrefix(t *testing.T) {
+	repo, mockDB, mock := setUpAuditMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "audit_events" WHERE type LIKE $1 AND target = $2 ORDER BY id DESC LIMIT 50`).
+		WithArgs(`login.%`, "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(2, "login.wrong_otp"))
+
+	events, err := repo.ListAuditEvents(context.Background(), AuditEventFilter{
+		TypePrefix: "login.",
+		Target:     "alice",
+		Limit:      50,
+		Newest:     true,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	mock.ExpectQuery(`SELECT * FROM "audit_events" WHERE type LIKE $1 ORDER BY id`).
+		WithArgs(`device\_%`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type"}))
+
+	_, err = repo.ListAuditEvents(context.Background(), AuditEventFilter{TypePrefix: "device_"})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditEventHash(t *testing.T) {
+	event := &AuditEvent{
+		CreatedAt: chainTime(time.Now()),
+		Type:      "login.wrong_password",
+		Actor:     "anonymous",
+		Target:    util.RandomString(8),
+		Outcome:   "failure",
+	}
+	hash := AuditEventHash(event)
+	require.Len(t, hash, 64)
+
+	// The same content read back in another time zone must hash the same.
+	sameEvent := *event
+	sameEvent.CreatedAt = event.CreatedAt.In(time.FixedZone("UTC+8", 8*60*60))
+	require.Equal(t, hash, AuditEventHash(&sameEvent))
+
+	tampered := *event
+	tampered.Outcome = "success"
+	require.NotEqual(t, hash, AuditEventHash(&tampered))
+
+	relinked := *event
+	relinked.PrevHash = util.RandomString(64)
+	require.NotEqual(t, hash, AuditEventHash(&relinked))
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var ErrBrowserSessionNotFound = errors.New("Browser session is not found")
+
+type BrowserSessionRepository interface {
+	CreateBrowserSession(ctx context.Context, session *BrowserSession) error
+	GetBrowserSession(ctx context.Context, tokenHash string) (*BrowserSession, error)
+	// ListBrowserSessions lists the sessions of the account that have not expired by now, last seen first.
+	ListBrowserSessions(ctx context.Context, accountID uuid.UUID, now time.Time) ([]BrowserSession, error)
+	TouchBrowserSession(ctx context.Context, id uuid.UUID, seenAt time.Time, expiresAt time.Time) error
+	DeleteBrowserSession(ctx context.Context, accountID uuid.UUID, id uuid.UUID) error
+}
+
+type browserSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewBrowserSessionRepository(db *gorm.DB) BrowserSessionRepository {
+	return &browserSessionRepository{
+		db: db,
+	}
+}
+
+func (r *browserSessionRepository) CreateBrowserSession(ctx context.Context, session *BrowserSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *browserSessionRepository) GetBrowserSession(ctx context.Context, tokenHash string) (*BrowserSession, error) {
+	session := &BrowserSession{}
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBrowserSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *browserSessionRepository) ListBrowserSessions(ctx context.Context, accountID uuid.UUID, now time.Time) ([]BrowserSession, error) {
+	var sessions []BrowserSession
+	err := r.db.WithContext(ctx).
+		Where("account_id = ? AND expires_at > ?", accountID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TouchBrowserSession records when the session was last used and moves its expiry on. It never moves
+// them back, so concurrent requests cannot overwrite a later use with an earlier one.
+func (r *browserSessionRepository) TouchBrowserSession(ctx context.Context, id uuid.UUID, seenAt time.Time, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&BrowserSession{}).
+		Where("id = ? AND last_seen_at < ?", id, seenAt).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "expires_at": expiresAt}).Error
+}
+
+func (r *browserSessionRepository) DeleteBrowserSession(ctx context.Context, accountID uuid.UUID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND account_id = ?", id, accountID).Delete(&BrowserSession{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBrowserSessionNotFound
+	}
+	return nil
+}
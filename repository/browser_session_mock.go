@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: browser_session.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockBrowserSessionRepository is a mock of BrowserSessionRepository interface.
+type MockBrowserSessionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockBrowserSessionRepositoryMockRecorder
+}
+
+// MockBrowserSessionRepositoryMockRecorder is the mock recorder for MockBrowserSessionRepository.
+type MockBrowserSessionRepositoryMockRecorder struct {
+	mock *MockBrowserSessionRepository
+}
+
+// NewMockBrowserSessionRepository creates a new mock instance.
+func NewMockBrowserSessionRepository(ctrl *gomock.Controller) *MockBrowserSessionRepository {
+	mock := &MockBrowserSessionRepository{ctrl: ctrl}
+	mock.recorder = &MockBrowserSessionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBrowserSessionRepository) EXPECT() *MockBrowserSessionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateBrowserSession mocks base method.
+func (m *MockBrowserSessionRepository) CreateBrowserSession(ctx context.Context, session *BrowserSession) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBrowserSession", ctx, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBrowserSession indicates an expected call of CreateBrowserSession.
+func (mr *MockBrowserSessionRepositoryMockRecorder) CreateBrowserSession(ctx, session interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBrowserSession", reflect.TypeOf((*MockBrowserSessionRepository)(nil).CreateBrowserSession), ctx, session)
+}
+
+// DeleteBrowserSession mocks base method.
+func (m *MockBrowserSessionRepository) DeleteBrowserSession(ctx context.Context, accountID, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBrowserSession", ctx, accountID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBrowserSession indicates an expected call of DeleteBrowserSession.
+func (mr *MockBrowserSessionRepositoryMockRecorder) DeleteBrowserSession(ctx, accountID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBrowserSession", reflect.TypeOf((*MockBrowserSessionRepository)(nil).DeleteBrowserSession), ctx, accountID, id)
+}
+
+// GetBrowserSession mocks base method.
+func (m *MockBrowserSessionRepository) GetBrowserSession(ctx context.Context, tokenHash string) (*BrowserSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBrowserSession", ctx, tokenHash)
+	ret0, _ := ret[0].(*BrowserSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBrowserSession indicates an expected call of GetBrowserSession.
+func (mr *MockBrowserSessionRepositoryMockRecorder) GetBrowserSession(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBrowserSession", reflect.TypeOf((*MockBrowserSessionRepository)(nil).GetBrowserSession), ctx, tokenHash)
+}
+
+// ListBrowserSessions mocks base method.
+func (m *MockBrowserSessionRepository) ListBrowserSessions(ctx context.Context, accountID uuid.UUID, now time.Time) ([]BrowserSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBrowserSessions", ctx, accountID, now)
+	ret0, _ := ret[0].([]BrowserSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBrowserSessions indicates an expected call of ListBrowserSessions.
+func (mr *MockBrowserSessionRepositoryMockRecorder) ListBrowserSessions(ctx, accountID, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBrowserSessions", reflect.TypeOf((*MockBrowserSessionRepository)(nil).ListBrowserSessions), ctx, accountID, now)
+}
+
+// TouchBrowserSession mocks base method.
+func (m *MockBrowserSessionRepository) TouchBrowserSession(ctx context.Context, id uuid.UUID, seenAt, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchBrowserSession", ctx, id, seenAt, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchBrowserSession indicates an expected call of TouchBrowserSession.
+func (mr *MockBrowserSessionRepositoryMockRecorder) TouchBrowserSession(ctx, id, seenAt, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchBrowserSession", reflect.TypeOf((*MockBrowserSessionRepository)(nil).TouchBrowserSession), ctx, id, seenAt, expiresAt)
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpBrowserSessionMock(t *testing.T) (BrowserSessionRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewBrowserSessionRepository(gormDB), mockDb, mock
+}
+
+func TestGetBrowserSessionNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpBrowserSessionMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "browser_sessions" WHERE token_hash = $1 ORDER BY "browser_sessions"."id" LIMIT 1`).
+		WithArgs("hash").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := repo.GetBrowserSession(context.Background(), "hash")
+	require.EqualError(t, err, ErrBrowserSessionNotFound.Error())
+}
+
+func TestTouchBrowserSession(t *testing.T) {
+	repo, mockDB, mock := setUpBrowserSessionMock(t)
+	defer mockDB.Close()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "browser_sessions" SET "expires_at"=$1,"last_seen_at"=$2 WHERE id = $3 AND last_seen_at < $4`).
+		WithArgs(AnyTime{}, AnyTime{}, id, AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	now := time.Now()
+	require.NoError(t, repo.TouchBrowserSession(context.Background(), id, now, now.Add(time.Hour)))
+}
+
+func TestDeleteBrowserSession(t *testing.T) {
+	accountID := uuid.New()
+	id := uuid.New()
+
+	testCases := []struct {
+		name    string
+		deleted int64
+		err     error
+	}{
+		{name: "ok", deleted: 1},
+		{name: "not found", deleted: 0, err: ErrBrowserSessionNotFound},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockDB, mock := setUpBrowserSessionMock(t)
+			defer mockDB.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`DELETE FROM "browser_sessions" WHERE id = $1 AND account_id = $2`).
+				WithArgs(id, accountID).
+				WillReturnResult(sqlmock.NewResult(0, tc.deleted))
+			mock.ExpectCommit()
+
+			err := repo.DeleteBrowserSession(context.Background(), accountID, id)
+			if tc.err != nil {
+				require.EqualError(t, err, tc.err.Error())
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
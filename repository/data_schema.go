@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -9,5 +11,335 @@ type Account struct {
 	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
 	Username       string    `gorm:"uniqueIndex"`
 	HashedPassword string
+	// Metadata is a JSON object of string values that hooks attach to the account.
+	Metadata string
+	// Type is "user" for people and "service" for service accounts, which have no password.
+	Type string
+	// OwnerID is the account responsible for a service account.
+	OwnerID     *uuid.UUID `gorm:"type:uuid"`
+	Description string
+	// Roles are the space separated roles of the account.
+	Roles string
+	// Source is empty for accounts kept by the service and "ldap" for accounts mirrored from a
+	// directory, whose password is checked by the directory.
+	Source string `gorm:"index"`
+	// LockedAt is when support staff locked the account, which cannot sign in until it is unlocked.
+	LockedAt *time.Time
+	// PasswordResetRequired makes the account set a new password at its next login, after support
+	// staff gave it a temporary one.
+	PasswordResetRequired bool
+	gorm.Model
+}
+
+// AuditEvent is an append-only record of a security-relevant event.
+type AuditEvent struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"index"`
+	Type      string    `gorm:"index"`
+	Actor     string    `gorm:"index"`
+	Target    string    `gorm:"index"`
+	IP        string
+	UserAgent string
+	RequestID string
+	Outcome   string
+	Detail    string
+	PrevHash  string
+	Hash      string `gorm:"index"`
+}
+
+// AuditCheckpoint is a signed snapshot of the head of the audit chain.
+type AuditCheckpoint struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time
+	EventID   uint64 `gorm:"index"`
+	EventHash string
+	KeyID     string
+	Signature string
+}
+
+type WebhookEndpoint struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	URL         string
+	Secret      string
+	Events      string
+	Description string
 	gorm.Model
 }
+
+// WebhookDeadLetter keeps a delivery that still failed after every retry, so it can be replayed later.
+type WebhookDeadLetter struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt  time.Time
+	EndpointID uuid.UUID `gorm:"type:uuid;index"`
+	EventID    string
+	EventType  string
+	Payload    string
+	Attempts   int
+	LastError  string
+	ReplayedAt *time.Time
+}
+
+// OutboxMessage is an event waiting to be relayed to the sinks. It is written in the same
+// transaction as the change it describes.
+type OutboxMessage struct {
+	ID             uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt      time.Time
+	IdempotencyKey string `gorm:"uniqueIndex"`
+	Type           string
+	Payload        string
+	DeliveredSinks string
+	Attempts       int
+	LastError      string
+	NextAttemptAt  time.Time  `gorm:"index"`
+	PublishedAt    *time.Time `gorm:"index"`
+	FailedAt       *time.Time
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+// OAuthClient is an application allowed to request tokens. Public clients have no secret.
+type OAuthClient struct {
+	ID           string `gorm:"primaryKey"`
+	SecretHash   string
+	Name         string
+	RedirectURIs string
+	GrantTypes   string
+	Scopes       string
+	SkipConsent  bool
+	// AccountID is the service account the client authenticates as, if any.
+	AccountID *uuid.UUID `gorm:"type:uuid;index"`
+	gorm.Model
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// OAuthAuthorizationCode is a single-use code handed to the client after login, stored by hash.
+type OAuthAuthorizationCode struct {
+	CodeHash            string `gorm:"primaryKey"`
+	CreatedAt           time.Time
+	ClientID            string
+	AccountID           uuid.UUID `gorm:"type:uuid"`
+	Username            string
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// Nonce and AuthTime are copied into the ID token issued for the code.
+	Nonce     string
+	AuthTime  time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// OAuthRefreshToken is stored by hash and revoked when it is exchanged for a new one.
+type OAuthRefreshToken struct {
+	TokenHash string `gorm:"primaryKey"`
+	CreatedAt time.Time
+	ClientID  string    `gorm:"index"`
+	AccountID uuid.UUID `gorm:"type:uuid;index"`
+	Username  string
+	Scope     string
+	AuthTime  time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+func (OAuthRefreshToken) TableName() string {
+	return "oauth_refresh_tokens"
+}
+
+// OAuthRevokedToken is an access token revoked before it expired. It can be dropped once it expires.
+type OAuthRevokedToken struct {
+	JTI       string `gorm:"primaryKey"`
+	ClientID  string
+	RevokedAt time.Time `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (OAuthRevokedToken) TableName() string {
+	return "oauth_revoked_tokens"
+}
+
+// SigningKey is a key the service signs tokens or audit checkpoints with. The private key is
+// encrypted under the master key; the public key is kept in the clear so it can be verified without it.
+type SigningKey struct {
+	ID                  string `gorm:"primaryKey"`
+	CreatedAt           time.Time
+	Purpose             string `gorm:"index"`
+	Algorithm           string
+	Status              string
+	PublicKey           []byte
+	EncryptedPrivateKey []byte
+	ActivatedAt         *time.Time
+	RetiredAt           *time.Time
+	// ExpiresAt is when a retired key stops being published.
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
+
+// PersonalAccessToken is a named, scoped token an account creates for scripts and CI. Only the
+// SHA-256 hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CreatedAt  time.Time
+	AccountID  uuid.UUID `gorm:"type:uuid;index"`
+	Name       string
+	TokenHash  string `gorm:"uniqueIndex"`
+	Scopes     string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// OAuthClientKey is a public key a client signs its RFC 7523 JWT assertions with. ID is the key's
+// RFC 7638 thumbprint, which the assertion names in its kid header.
+type OAuthClientKey struct {
+	ID        string `gorm:"primaryKey"`
+	ClientID  string `gorm:"index"`
+	Algorithm string
+	PublicKey []byte
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (OAuthClientKey) TableName() string {
+	return "oauth_client_keys"
+}
+
+// OAuthUsedAssertion remembers the jti of a JWT assertion until it expires, so it cannot be replayed.
+type OAuthUsedAssertion struct {
+	ClientID  string    `gorm:"primaryKey"`
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (OAuthUsedAssertion) TableName() string {
+	return "oauth_used_assertions"
+}
+
+// OAuthDeviceCode is a device authorization request (RFC 8628). It stays pending until the user
+// approves or denies it on the verification page, and is used once the device got its tokens.
+type OAuthDeviceCode struct {
+	DeviceCodeHash string `gorm:"primaryKey"`
+	UserCode       string `gorm:"uniqueIndex"`
+	CreatedAt      time.Time
+	ClientID       string
+	Scope          string
+	Status         string
+	AccountID      *uuid.UUID `gorm:"type:uuid"`
+	Username       string
+	AuthTime       *time.Time
+	// PollInterval is the number of seconds the device must wait between polls.
+	PollInterval int
+	LastPolledAt *time.Time
+	ExpiresAt    time.Time `gorm:"index"`
+	UsedAt       *time.Time
+}
+
+func (OAuthDeviceCode) TableName() string {
+	return "oauth_device_codes"
+}
+
+// Identity is a login method of an account besides its password: a subject at an external identity
+// provider, a passkey or a TOTP authenticator.
+type Identity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CreatedAt time.Time
+	AccountID uuid.UUID `gorm:"type:uuid;index"`
+	Type      string    `gorm:"not null;default:oidc"`
+	// Provider and Subject name the identity: the provider name and its subject for an external
+	// identity, "passkey" and the credential ID for a passkey, "totp" and the account ID for TOTP.
+	Provider string `gorm:"uniqueIndex:idx_identities_provider_subject"`
+	Subject  string `gorm:"uniqueIndex:idx_identities_provider_subject"`
+	Email    string `gorm:"index"`
+	Name     string
+	// Credential is the public key of a passkey, or the sealed secret of a TOTP authenticator.
+	Credential []byte
+	// Counter is the signature counter of a passkey, or the last time step a TOTP code was used for.
+	Counter     int64
+	LastLoginAt *time.Time
+}
+
+// AccountingSession is a network access session reported by a NAS with RADIUS accounting. The NAS
+// client name and its Acct-Session-Id identify it.
+type AccountingSession struct {
+	ID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	// AccountID is the account the username belongs to, or nil for a username the service does not know.
+	AccountID *uuid.UUID `gorm:"type:uuid;index"`
+	Username  string
+	Client    string `gorm:"uniqueIndex:idx_accounting_sessions_client_session"`
+	SessionID string `gorm:"uniqueIndex:idx_accounting_sessions_client_session"`
+	// NASAddress is where the accounting came from, Disconnect and CoA requests are sent there.
+	NASAddress       string
+	NASIdentifier    string
+	CallingStationID string
+	CalledStationID  string
+	FramedIPAddress  string
+	StartedAt        time.Time
+	UpdatedAt        time.Time
+	StoppedAt        *time.Time `gorm:"index"`
+	// SessionTime is in seconds. InputOctets is what the NAS received from the user, OutputOctets what it
+	// sent to the user.
+	SessionTime    int64
+	InputOctets    int64
+	OutputOctets   int64
+	TerminateCause string
+}
+
+// Voucher holds the limits of a guest voucher account, whose username is the voucher code. Its validity
+// starts when it is first used.
+type Voucher struct {
+	AccountID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Batch     string    `gorm:"index"`
+	// Duration is how many seconds the voucher works once used, and DataLimit how many octets it may
+	// transfer in both directions. Either may be 0 for no limit.
+	Duration  int64
+	DataLimit int64
+	// RedeemBy is when an unused voucher stops working, nil for never.
+	RedeemBy    *time.Time
+	ActivatedAt *time.Time
+	// ExpiresAt is when the voucher stops working: RedeemBy until it is used, then the end of its duration.
+	ExpiresAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// Device is a MAC address registered for MAC authentication bypass, for headless devices like printers
+// and cameras that cannot type a password.
+type Device struct {
+	ID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	// MAC is in lower case with colons, like "aa:bb:cc:dd:ee:ff".
+	MAC string `gorm:"uniqueIndex"`
+	// OwnerID is the account responsible for the device, nil for devices only admins manage.
+	OwnerID     *uuid.UUID `gorm:"type:uuid;index"`
+	Description string
+	// VLAN and Role are what the device is let on the network with, 0 and "" for the default reply.
+	VLAN      int
+	Role      string
+	ExpiresAt *time.Time
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// BrowserSession is an account signed in to a browser, which holds the session token in a cookie.
+type BrowserSession struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AccountID uuid.UUID `gorm:"type:uuid;index"`
+	TokenHash string    `gorm:"uniqueIndex"`
+	// CSRFToken is sent back with each request that changes something, cookies being sent on their own.
+	CSRFToken  string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	// ExpiresAt moves on with each use, up to MaxExpiresAt.
+	ExpiresAt    time.Time `gorm:"index"`
+	MaxExpiresAt time.Time
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const deviceBatchSize = 1000
+
+var (
+	ErrDeviceNotFound     = errors.New("Device is not found")
+	ErrDeviceIsDuplicated = errors.New("Device is already registered")
+)
+
+// DeviceOwner is a device with the username of its owner, empty when it has none.
+type DeviceOwner struct {
+	Device
+	Owner string
+}
+
+type DeviceRepository interface {
+	// CreateDevices stores the devices in one transaction, none of them when a MAC is already registered.
+	CreateDevices(ctx context.Context, devices ...*Device) error
+	GetDevice(ctx context.Context, id uuid.UUID) (*Device, error)
+	GetDeviceByMAC(ctx context.Context, mac string) (*Device, error)
+	// ListDevices lists the devices of the owner by MAC, or all of them when ownerID is nil.
+	ListDevices(ctx context.Context, ownerID *uuid.UUID) ([]DeviceOwner, error)
+	// ListDevicesByMAC lists the devices registered with any of the MACs.
+	ListDevicesByMAC(ctx context.Context, macs []string) ([]Device, error)
+	CountDevices(ctx context.Context, ownerID uuid.UUID) (int64, error)
+	DeleteDevice(ctx context.Context, id uuid.UUID) error
+}
+
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return &deviceRepository{
+		db: db,
+	}
+}
+
+func (r *deviceRepository) CreateDevices(ctx context.Context, devices ...*Device) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Imports can be too large for a single statement.
+		return tx.CreateInBatches(devices, deviceBatchSize).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDeviceIsDuplicated
+	}
+	return err
+}
+
+func (r *deviceRepository) GetDevice(ctx context.Context, id uuid.UUID) (*Device, error) {
+	device := &Device{}
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+	return device, nil
+}
+
+func (r *deviceRepository) GetDeviceByMAC(ctx context.Context, mac string) (*Device, error) {
+	device := &Device{}
+	if err := r.db.WithContext(ctx).Where("mac = ?", mac).First(device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceNotFound
+		}
+		return nil, err
+	}
+	return device, nil
+}
+
+func (r *deviceRepository) ListDevices(ctx context.Context, ownerID *uuid.UUID) ([]DeviceOwner, error) {
+	query := r.db.WithContext(ctx).Model(&Device{}).
+		Select("devices.*, accounts.username AS owner").
+		Joins("LEFT JOIN accounts ON accounts.id = devices.owner_id AND accounts.deleted_at IS NULL")
+	if ownerID != nil {
+		query = query.Where("devices.owner_id = ?", *ownerID)
+	}
+	var devices []DeviceOwner
+	if err := query.Order("devices.mac").Scan(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *deviceRepository) ListDevicesByMAC(ctx context.Context, macs []string) ([]Device, error) {
+	var devices []Device
+	if err := r.db.WithContext(ctx).Where("mac IN ?", macs).Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *deviceRepository) CountDevices(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&Device{}).Where("owner_id = ?", ownerID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *deviceRepository) DeleteDevice(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&Device{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
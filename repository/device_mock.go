@@ -0,0 +1,144 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: device.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockDeviceRepository is a mock of DeviceRepository interface.
+type MockDeviceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeviceRepositoryMockRecorder
+}
+
+// MockDeviceRepositoryMockRecorder is the mock recorder for MockDeviceRepository.
+type MockDeviceRepositoryMockRecorder struct {
+	mock *MockDeviceRepository
+}
+
+// NewMockDeviceRepository creates a new mock instance.
+func NewMockDeviceRepository(ctrl *gomock.Controller) *MockDeviceRepository {
+	mock := &MockDeviceRepository{ctrl: ctrl}
+	mock.recorder = &MockDeviceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeviceRepository) EXPECT() *MockDeviceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountDevices mocks base method.
+func (m *MockDeviceRepository) CountDevices(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDevices", ctx, ownerID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDevices indicates an expected call of CountDevices.
+func (mr *MockDeviceRepositoryMockRecorder) CountDevices(ctx, ownerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDevices", reflect.TypeOf((*MockDeviceRepository)(nil).CountDevices), ctx, ownerID)
+}
+
+// CreateDevices mocks base method.
+func (m *MockDeviceRepository) CreateDevices(ctx context.Context, devices ...*Device) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range devices {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateDevices", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDevices indicates an expected call of CreateDevices.
+func (mr *MockDeviceRepositoryMockRecorder) CreateDevices(ctx interface{}, devices ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, devices...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDevices", reflect.TypeOf((*MockDeviceRepository)(nil).CreateDevices), varargs...)
+}
+
+// DeleteDevice mocks base method.
+func (m *MockDeviceRepository) DeleteDevice(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDevice", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDevice indicates an expected call of DeleteDevice.
+func (mr *MockDeviceRepositoryMockRecorder) DeleteDevice(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDevice", reflect.TypeOf((*MockDeviceRepository)(nil).DeleteDevice), ctx, id)
+}
+
+// GetDevice mocks base method.
+func (m *MockDeviceRepository) GetDevice(ctx context.Context, id uuid.UUID) (*Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDevice", ctx, id)
+	ret0, _ := ret[0].(*Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDevice indicates an expected call of GetDevice.
+func (mr *MockDeviceRepositoryMockRecorder) GetDevice(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevice", reflect.TypeOf((*MockDeviceRepository)(nil).GetDevice), ctx, id)
+}
+
+// GetDeviceByMAC mocks base method.
+func (m *MockDeviceRepository) GetDeviceByMAC(ctx context.Context, mac string) (*Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceByMAC", ctx, mac)
+	ret0, _ := ret[0].(*Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceByMAC indicates an expected call of GetDeviceByMAC.
+func (mr *MockDeviceRepositoryMockRecorder) GetDeviceByMAC(ctx, mac interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceByMAC", reflect.TypeOf((*MockDeviceRepository)(nil).GetDeviceByMAC), ctx, mac)
+}
+
+// ListDevices mocks base method.
+func (m *MockDeviceRepository) ListDevices(ctx context.Context, ownerID *uuid.UUID) ([]DeviceOwner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDevices", ctx, ownerID)
+	ret0, _ := ret[0].([]DeviceOwner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDevices indicates an expected call of ListDevices.
+func (mr *MockDeviceRepositoryMockRecorder) ListDevices(ctx, ownerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDevices", reflect.TypeOf((*MockDeviceRepository)(nil).ListDevices), ctx, ownerID)
+}
+
+// ListDevicesByMAC mocks base method.
+func (m *MockDeviceRepository) ListDevicesByMAC(ctx context.Context, macs []string) ([]Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDevicesByMAC", ctx, macs)
+	ret0, _ := ret[0].([]Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDevicesByMAC indicates an expected call of ListDevicesByMAC.
+func (mr *MockDeviceRepositoryMockRecorder) ListDevicesByMAC(ctx, macs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDevicesByMAC", reflect.TypeOf((*MockDeviceRepository)(nil).ListDevicesByMAC), ctx, macs)
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpDeviceMock(t *testing.T) (DeviceRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewDeviceRepository(gormDB), mockDb, mock
+}
+
+func TestListDevices(t *testing.T) {
+	ownerID := uuid.New()
+	testCase := []struct {
+		name    string
+		ownerID *uuid.UUID
+		query   string
+		args    []driver.Value
+	}{
+		{
+			name: "all",
+			query: `SELECT devices.*, accounts.username AS owner FROM "devices" ` +
+				`LEFT JOIN accounts ON accounts.id = devices.owner_id AND accounts.deleted_at IS NULL ORDER BY devices.mac`,
+		},
+		{
+			name:    "owner",
+			ownerID: &ownerID,
+			query: `SELECT devices.*, accounts.username AS owner FROM "devices" ` +
+				`LEFT JOIN accounts ON accounts.id = devices.owner_id AND accounts.deleted_at IS NULL WHERE devices.owner_id = $1 ORDER BY devices.mac`,
+			args: []driver.Value{ownerID},
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockDB, mock := setUpDeviceMock(t)
+			defer mockDB.Close()
+
+			deviceID := uuid.New()
+			mock.ExpectQuery(tc.query).WithArgs(tc.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "mac", "owner_id", "vlan", "owner"}).
+					AddRow(deviceID, "aa:bb:cc:dd:ee:ff", ownerID, 20, "alice"))
+
+			devices, err := repo.ListDevices(context.Background(), tc.ownerID)
+			require.NoError(t, err)
+			require.Len(t, devices, 1)
+			require.Equal(t, deviceID, devices[0].ID)
+			require.Equal(t, "aa:bb:cc:dd:ee:ff", devices[0].MAC)
+			require.Equal(t, 20, devices[0].VLAN)
+			require.Equal(t, "alice", devices[0].Owner)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetDeviceByMAC(t *testing.T) {
+	testCase := []struct {
+		name string
+		rows *sqlmock.Rows
+		err  error
+	}{
+		{name: "found", rows: sqlmock.NewRows([]string{"id", "mac"}).AddRow(uuid.New(), "aa:bb:cc:dd:ee:ff")},
+		{name: "not found", rows: sqlmock.NewRows([]string{"id", "mac"}), err: ErrDeviceNotFound},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockDB, mock := setUpDeviceMock(t)
+			defer mockDB.Close()
+
+			mock.ExpectQuery(`SELECT * FROM "devices" WHERE mac = $1 ORDER BY "devices"."id" LIMIT 1`).
+				WithArgs("aa:bb:cc:dd:ee:ff").
+				WillReturnRows(tc.rows)
+
+			device, err := repo.GetDeviceByMAC(context.Background(), "aa:bb:cc:dd:ee:ff")
+			require.Equal(t, tc.err, err)
+			if tc.err == nil {
+				require.Equal(t, "aa:bb:cc:dd:ee:ff", device.MAC)
+			}
+		})
+	}
+}
+
+func TestDeleteDevice(t *testing.T) {
+	testCase := []struct {
+		name string
+		rows int64
+		err  error
+	}{
+		{name: "deleted", rows: 1},
+		{name: "not found", rows: 0, err: ErrDeviceNotFound},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockDB, mock := setUpDeviceMock(t)
+			defer mockDB.Close()
+
+			id := uuid.New()
+			mock.ExpectBegin()
+			mock.ExpectExec(`DELETE FROM "devices" WHERE id = $1`).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, tc.rows))
+			mock.ExpectCommit()
+
+			require.Equal(t, tc.err, repo.DeleteDevice(context.Background(), id))
+		})
+	}
+}
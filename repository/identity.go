@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Types of identities. External identities and passkeys log in on their own; TOTP is only a second
+// factor.
+const (
+	IdentityTypeOIDC    = "oidc"
+	IdentityTypePasskey = "passkey"
+	IdentityTypeTOTP    = "totp"
+)
+
+var (
+	ErrIdentityNotFound        = errors.New("Identity is not found")
+	ErrIdentityIsDuplicated    = errors.New("Identity is duplicated")
+	ErrLastLoginMethod         = errors.New("Login method is the last one of the account")
+	ErrIdentityCounterReplayed = errors.New("Identity counter did not increase")
+)
+
+// IdentityRepository stores the login methods of accounts: their password and their identities.
+type IdentityRepository interface {
+	GetIdentity(ctx context.Context, provider string, subject string) (*Identity, error)
+	ListIdentities(ctx context.Context, accountID uuid.UUID) ([]Identity, error)
+	// FindIdentitiesByEmail returns the external identities with the email address.
+	FindIdentitiesByEmail(ctx context.Context, email string) ([]Identity, error)
+	CreateIdentity(ctx context.Context, identity *Identity) error
+	// CreateFederatedAccount stores an account provisioned on its first federated login together with
+	// its identity and outbox messages in one transaction.
+	CreateFederatedAccount(ctx context.Context, account *Account, identity *Identity, messages ...*OutboxMessage) error
+	TouchIdentity(ctx context.Context, id interface{}, loginAt time.Time) error
+	// UseIdentityCounter moves the counter of an identity forward. It fails with
+	// ErrIdentityCounterReplayed when the counter is not above the stored one.
+	UseIdentityCounter(ctx context.Context, id uuid.UUID, counter int64, usedAt time.Time) error
+	SetPassword(ctx context.Context, accountID uuid.UUID, hashedPassword string) error
+	// DeleteIdentity and RemovePassword fail with ErrLastLoginMethod when the account would be left
+	// without a password, external identity or passkey.
+	DeleteIdentity(ctx context.Context, accountID uuid.UUID, id uuid.UUID) (*Identity, error)
+	RemovePassword(ctx context.Context, accountID uuid.UUID) error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{
+		db: db,
+	}
+}
+
+func (r *identityRepository) GetIdentity(ctx context.Context, provider string, subject string) (*Identity, error) {
+	identity := &Identity{}
+	if err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *identityRepository) ListIdentities(ctx context.Context, accountID uuid.UUID) ([]Identity, error) {
+	var identities []Identity
+	if err := r.db.WithContext(ctx).Where("account_id = ?", accountID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *identityRepository) FindIdentitiesByEmail(ctx context.Context, email string) ([]Identity, error) {
+	var identities []Identity
+	err := r.db.WithContext(ctx).Where("type = ? AND lower(email) = lower(?)", IdentityTypeOIDC, email).
+		Order("created_at").Find(&identities).Error
+	if err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *identityRepository) CreateIdentity(ctx context.Context, identity *Identity) error {
+	if err := r.db.WithContext(ctx).Create(identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrIdentityIsDuplicated
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *identityRepository) CreateFederatedAccount(ctx context.Context, account *Account, identity *Identity, messages ...*OutboxMessage) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(identity).Error; err != nil {
+			return err
+		}
+		return createOutboxMessages(tx, messages...)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrAccountIsDuplicated
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *identityRepository) TouchIdentity(ctx context.Context, id interface{}, loginAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&Identity{}).Where("id = ?", id).Update("last_login_at", loginAt).Error
+}
+
+func (r *identityRepository) UseIdentityCounter(ctx context.Context, id uuid.UUID, counter int64, usedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&Identity{}).Where("id = ? AND counter < ?", id, counter).
+		Updates(map[string]interface{}{"counter": counter, "last_login_at": usedAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdentityCounterReplayed
+	}
+	return nil
+}
+
+func (r *identityRepository) SetPassword(ctx context.Context, accountID uuid.UUID, hashedPassword string) error {
+	result := r.db.WithContext(ctx).Model(&Account{}).Where("id = ?", accountID).Update("hashed_password", hashedPassword)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountRecordNotFound
+	}
+	return nil
+}
+
+func (r *identityRepository) DeleteIdentity(ctx context.Context, accountID uuid.UUID, id uuid.UUID) (*Identity, error) {
+	identity := &Identity{}
+	err := r.keepLoginMethod(ctx, accountID, func(tx *gorm.DB, account *Account) error {
+		result := tx.Clauses(clause.Returning{}).Where("id = ? AND account_id = ?", id, accountID).Delete(identity)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrIdentityNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *identityRepository) RemovePassword(ctx context.Context, accountID uuid.UUID) error {
+	return r.keepLoginMethod(ctx, accountID, func(tx *gorm.DB, account *Account) error {
+		if err := tx.Model(account).Update("hashed_password", "").Error; err != nil {
+			return err
+		}
+		account.HashedPassword = ""
+		return nil
+	})
+}
+
+// keepLoginMethod makes change to the login methods of the account, with the account locked so
+// concurrent changes cannot remove its last methods together.
+func (r *identityRepository) keepLoginMethod(ctx context.Context, accountID uuid.UUID, change func(tx *gorm.DB, account *Account) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		account := &Account{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", accountID).First(account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAccountRecordNotFound
+			}
+			return err
+		}
+		if err := change(tx, account); err != nil {
+			return err
+		}
+		if account.HashedPassword != "" {
+			return nil
+		}
+		var usable int64
+		err := tx.Model(&Identity{}).Where("account_id = ? AND type IN ?", accountID, []string{IdentityTypeOIDC, IdentityTypePasskey}).
+			Count(&usable).Error
+		if err != nil {
+			return err
+		}
+		if usable == 0 {
+			return ErrLastLoginMethod
+		}
+		return nil
+	})
+}
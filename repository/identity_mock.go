@@ -0,0 +1,186 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: identity.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockIdentityRepository is a mock of IdentityRepository interface.
+type MockIdentityRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentityRepositoryMockRecorder
+}
+
+// MockIdentityRepositoryMockRecorder is the mock recorder for MockIdentityRepository.
+type MockIdentityRepositoryMockRecorder struct {
+	mock *MockIdentityRepository
+}
+
+// NewMockIdentityRepository creates a new mock instance.
+func NewMockIdentityRepository(ctrl *gomock.Controller) *MockIdentityRepository {
+	mock := &MockIdentityRepository{ctrl: ctrl}
+	mock.recorder = &MockIdentityRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdentityRepository) EXPECT() *MockIdentityRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateFederatedAccount mocks base method.
+func (m *MockIdentityRepository) CreateFederatedAccount(ctx context.Context, account *Account, identity *Identity, messages ...*OutboxMessage) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, account, identity}
+	for _, a := range messages {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateFederatedAccount", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFederatedAccount indicates an expected call of CreateFederatedAccount.
+func (mr *MockIdentityRepositoryMockRecorder) CreateFederatedAccount(ctx, account, identity interface{}, messages ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, account, identity}, messages...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFederatedAccount", reflect.TypeOf((*MockIdentityRepository)(nil).CreateFederatedAccount), varargs...)
+}
+
+// CreateIdentity mocks base method.
+func (m *MockIdentityRepository) CreateIdentity(ctx context.Context, identity *Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdentity", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIdentity indicates an expected call of CreateIdentity.
+func (mr *MockIdentityRepositoryMockRecorder) CreateIdentity(ctx, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdentity", reflect.TypeOf((*MockIdentityRepository)(nil).CreateIdentity), ctx, identity)
+}
+
+// DeleteIdentity mocks base method.
+func (m *MockIdentityRepository) DeleteIdentity(ctx context.Context, accountID, id uuid.UUID) (*Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdentity", ctx, accountID, id)
+	ret0, _ := ret[0].(*Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIdentity indicates an expected call of DeleteIdentity.
+func (mr *MockIdentityRepositoryMockRecorder) DeleteIdentity(ctx, accountID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdentity", reflect.TypeOf((*MockIdentityRepository)(nil).DeleteIdentity), ctx, accountID, id)
+}
+
+// FindIdentitiesByEmail mocks base method.
+func (m *MockIdentityRepository) FindIdentitiesByEmail(ctx context.Context, email string) ([]Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindIdentitiesByEmail", ctx, email)
+	ret0, _ := ret[0].([]Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindIdentitiesByEmail indicates an expected call of FindIdentitiesByEmail.
+func (mr *MockIdentityRepositoryMockRecorder) FindIdentitiesByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIdentitiesByEmail", reflect.TypeOf((*MockIdentityRepository)(nil).FindIdentitiesByEmail), ctx, email)
+}
+
+// GetIdentity mocks base method.
+func (m *MockIdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (*Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdentity", ctx, provider, subject)
+	ret0, _ := ret[0].(*Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdentity indicates an expected call of GetIdentity.
+func (mr *MockIdentityRepositoryMockRecorder) GetIdentity(ctx, provider, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdentity", reflect.TypeOf((*MockIdentityRepository)(nil).GetIdentity), ctx, provider, subject)
+}
+
+// ListIdentities mocks base method.
+func (m *MockIdentityRepository) ListIdentities(ctx context.Context, accountID uuid.UUID) ([]Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIdentities", ctx, accountID)
+	ret0, _ := ret[0].([]Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIdentities indicates an expected call of ListIdentities.
+func (mr *MockIdentityRepositoryMockRecorder) ListIdentities(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIdentities", reflect.TypeOf((*MockIdentityRepository)(nil).ListIdentities), ctx, accountID)
+}
+
+// RemovePassword mocks base method.
+func (m *MockIdentityRepository) RemovePassword(ctx context.Context, accountID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePassword", ctx, accountID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePassword indicates an expected call of RemovePassword.
+func (mr *MockIdentityRepositoryMockRecorder) RemovePassword(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePassword", reflect.TypeOf((*MockIdentityRepository)(nil).RemovePassword), ctx, accountID)
+}
+
+// SetPassword mocks base method.
+func (m *MockIdentityRepository) SetPassword(ctx context.Context, accountID uuid.UUID, hashedPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassword", ctx, accountID, hashedPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPassword indicates an expected call of SetPassword.
+func (mr *MockIdentityRepositoryMockRecorder) SetPassword(ctx, accountID, hashedPassword interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassword", reflect.TypeOf((*MockIdentityRepository)(nil).SetPassword), ctx, accountID, hashedPassword)
+}
+
+// TouchIdentity mocks base method.
+func (m *MockIdentityRepository) TouchIdentity(ctx context.Context, id interface{}, loginAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchIdentity", ctx, id, loginAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchIdentity indicates an expected call of TouchIdentity.
+func (mr *MockIdentityRepositoryMockRecorder) TouchIdentity(ctx, id, loginAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchIdentity", reflect.TypeOf((*MockIdentityRepository)(nil).TouchIdentity), ctx, id, loginAt)
+}
+
+// UseIdentityCounter mocks base method.
+func (m *MockIdentityRepository) UseIdentityCounter(ctx context.Context, id uuid.UUID, counter int64, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseIdentityCounter", ctx, id, counter, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseIdentityCounter indicates an expected call of UseIdentityCounter.
+func (mr *MockIdentityRepositoryMockRecorder) UseIdentityCounter(ctx, id, counter, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseIdentityCounter", reflect.TypeOf((*MockIdentityRepository)(nil).UseIdentityCounter), ctx, id, counter, usedAt)
+}
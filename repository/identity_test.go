@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpIdentityMock(t *testing.T) (IdentityRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewIdentityRepository(gormDB), mockDb, mock
+}
+
+func TestGetIdentityNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpIdentityMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "identities" WHERE provider = $1 AND subject = $2 ORDER BY "identities"."id" LIMIT 1`).
+		WithArgs("corp", "248289761001").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := repo.GetIdentity(context.Background(), "corp", "248289761001")
+	require.EqualError(t, err, ErrIdentityNotFound.Error())
+}
+
+func TestCreateFederatedAccount(t *testing.T) {
+	repo, mockDB, mock := setUpIdentityMock(t)
+	defer mockDB.Close()
+
+	now := time.Now()
+	account := &Account{ID: uuid.New(), Username: "jdoe", Type: AccountTypeUser}
+	identity := &Identity{ID: uuid.New(), CreatedAt: now, AccountID: account.ID, Type: IdentityTypeOIDC, Provider: "corp", Subject: "248289761001",
+		Email: "j.doe@contractor.example.com", LastLoginAt: &now}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "accounts" ("id","username","hashed_password","metadata","type","owner_id","description","roles","source","locked_at","password_reset_required","created_at","updated_at","deleted_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING "id"`).
+		WithArgs(account.ID, "jdoe", "", "", AccountTypeUser, nil, "", "", "", nil, false, AnyTime{}, AnyTime{}, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(account.ID))
+	mock.ExpectExec(`INSERT INTO "identities" ("id","created_at","account_id","type","provider","subject","email","name","credential","counter","last_login_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`).
+		WithArgs(identity.ID, AnyTime{}, account.ID, IdentityTypeOIDC, "corp", "248289761001", identity.Email, "", sqlmock.AnyArg(), 0, AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.CreateFederatedAccount(context.Background(), account, identity)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateFederatedAccountDuplicate(t *testing.T) {
+	repo, mockDB, mock := setUpIdentityMock(t)
+	defer mockDB.Close()
+
+	account := &Account{ID: uuid.New(), Username: "jdoe", Type: AccountTypeUser}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "accounts" ("id","username","hashed_password","metadata","type","owner_id","description","roles","source","locked_at","password_reset_required","created_at","updated_at","deleted_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING "id"`).
+		WithArgs(account.ID, "jdoe", "", "", AccountTypeUser, nil, "", "", "", nil, false, AnyTime{}, AnyTime{}, nil).
+		WillReturnError(gorm.ErrDuplicatedKey)
+	mock.ExpectRollback()
+
+	err := repo.CreateFederatedAccount(context.Background(), account, &Identity{ID: uuid.New(), AccountID: account.ID})
+	require.EqualError(t, err, ErrAccountIsDuplicated.Error())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteIdentity(t *testing.T) {
+	accountID := uuid.New()
+	id := uuid.New()
+	lockAccount := `SELECT * FROM "accounts" WHERE id = $1 AND "accounts"."deleted_at" IS NULL ORDER BY "accounts"."id" LIMIT 1 FOR UPDATE`
+	deleteIdentity := `DELETE FROM "identities" WHERE id = $1 AND account_id = $2 RETURNING *`
+	countUsable := `SELECT count(*) FROM "identities" WHERE account_id = $1 AND type IN ($2,$3)`
+
+	t.Run("ok", func(t *testing.T) {
+		repo, mockDB, mock := setUpIdentityMock(t)
+		defer mockDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockAccount).WithArgs(accountID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "hashed_password"}).AddRow(accountID, "jdoe", ""))
+		mock.ExpectQuery(deleteIdentity).WithArgs(id, accountID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "type", "provider"}).AddRow(id, accountID, IdentityTypeOIDC, "corp"))
+		mock.ExpectQuery(countUsable).WithArgs(accountID, IdentityTypeOIDC, IdentityTypePasskey).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectCommit()
+
+		identity, err := repo.DeleteIdentity(context.Background(), accountID, id)
+		require.NoError(t, err)
+		require.Equal(t, "corp", identity.Provider)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("last login method", func(t *testing.T) {
+		repo, mockDB, mock := setUpIdentityMock(t)
+		defer mockDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockAccount).WithArgs(accountID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "hashed_password"}).AddRow(accountID, "jdoe", ""))
+		mock.ExpectQuery(deleteIdentity).WithArgs(id, accountID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "type", "provider"}).AddRow(id, accountID, IdentityTypeOIDC, "corp"))
+		mock.ExpectQuery(countUsable).WithArgs(accountID, IdentityTypeOIDC, IdentityTypePasskey).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectRollback()
+
+		_, err := repo.DeleteIdentity(context.Background(), accountID, id)
+		require.EqualError(t, err, ErrLastLoginMethod.Error())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("account keeps its password", func(t *testing.T) {
+		repo, mockDB, mock := setUpIdentityMock(t)
+		defer mockDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockAccount).WithArgs(accountID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "hashed_password"}).AddRow(accountID, "jdoe", "hash"))
+		mock.ExpectQuery(deleteIdentity).WithArgs(id, accountID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "type", "provider"}).AddRow(id, accountID, IdentityTypeOIDC, "corp"))
+		mock.ExpectCommit()
+
+		_, err := repo.DeleteIdentity(context.Background(), accountID, id)
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseIdentityCounterReplayed(t *testing.T) {
+	repo, mockDB, mock := setUpIdentityMock(t)
+	defer mockDB.Close()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "identities" SET "counter"=$1,"last_login_at"=$2 WHERE id = $3 AND counter < $4`).
+		WithArgs(56789, AnyTime{}, id, 56789).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.UseIdentityCounter(context.Background(), id, 56789, time.Now())
+	require.EqualError(t, err, ErrIdentityCounterReplayed.Error())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
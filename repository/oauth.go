@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOAuthClientNotFound            = errors.New("OAuth client is not found")
+	ErrOAuthAuthorizationCodeNotFound = errors.New("OAuth authorization code is not found")
+	ErrOAuthRefreshTokenNotFound      = errors.New("OAuth refresh token is not found")
+	ErrOAuthClientKeyNotFound         = errors.New("OAuth client key is not found")
+	ErrOAuthAssertionReplayed         = errors.New("OAuth assertion was already used")
+	ErrOAuthClientKeyDuplicated       = errors.New("OAuth client key is duplicated")
+	ErrOAuthDeviceCodeNotFound        = errors.New("OAuth device code is not found")
+)
+
+// Statuses of a device code.
+const (
+	OAuthDeviceCodePending  = "pending"
+	OAuthDeviceCodeApproved = "approved"
+	OAuthDeviceCodeDenied   = "denied"
+)
+
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *OAuthClient) error
+	GetClient(ctx context.Context, id string) (*OAuthClient, error)
+	ListClients(ctx context.Context) ([]OAuthClient, error)
+	DeleteClient(ctx context.Context, id string) error
+	UpdateClientSecret(ctx context.Context, id string, secretHash string) error
+	CreateClientKey(ctx context.Context, key *OAuthClientKey) error
+	// ListClientKeys lists the keys of the client, revoked ones included, oldest first.
+	ListClientKeys(ctx context.Context, clientID string) ([]OAuthClientKey, error)
+	RevokeClientKey(ctx context.Context, clientID string, id string, revokedAt time.Time) error
+	// UseAssertion records the jti of a JWT assertion, failing with ErrOAuthAssertionReplayed when the
+	// client already used it. Expired entries are dropped on the way.
+	UseAssertion(ctx context.Context, assertion *OAuthUsedAssertion) error
+	CreateAuthorizationCode(ctx context.Context, code *OAuthAuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string, usedAt time.Time) (*OAuthAuthorizationCode, error)
+	CreateRefreshToken(ctx context.Context, token *OAuthRefreshToken) error
+	ConsumeRefreshToken(ctx context.Context, tokenHash string, revokedAt time.Time) (*OAuthRefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (*OAuthRefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string, clientID string, revokedAt time.Time) error
+	RevokeAccessToken(ctx context.Context, token *OAuthRevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// ListRevokedAccessTokens returns the revocations made since, of tokens that expire after now.
+	ListRevokedAccessTokens(ctx context.Context, since time.Time, now time.Time) ([]OAuthRevokedToken, error)
+	PurgeRevokedAccessTokens(ctx context.Context, expiredBefore time.Time) error
+	// CreateDeviceCode stores a device authorization and drops the expired ones.
+	CreateDeviceCode(ctx context.Context, code *OAuthDeviceCode) error
+	GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*OAuthDeviceCode, error)
+	// ApproveDeviceCode and DenyDeviceCode decide a code that is pending and not expired at.
+	ApproveDeviceCode(ctx context.Context, userCode string, accountID uuid.UUID, username string, at time.Time) error
+	DenyDeviceCode(ctx context.Context, userCode string, at time.Time) error
+	// PollDeviceCode records a poll of the device and returns the code as it was before, so the caller
+	// can tell when it was last polled.
+	PollDeviceCode(ctx context.Context, deviceCodeHash string, polledAt time.Time) (*OAuthDeviceCode, error)
+	UpdateDevicePollInterval(ctx context.Context, deviceCodeHash string, seconds int) error
+	// ConsumeDeviceCode marks an approved code used and returns it. A code can only be consumed once,
+	// even by concurrent requests.
+	ConsumeDeviceCode(ctx context.Context, deviceCodeHash string, usedAt time.Time) (*OAuthDeviceCode, error)
+}
+
+type oauthRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthRepository(db *gorm.DB) OAuthRepository {
+	return &oauthRepository{
+		db: db,
+	}
+}
+
+func (r *oauthRepository) CreateClient(ctx context.Context, client *OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *oauthRepository) GetClient(ctx context.Context, id string) (*OAuthClient, error) {
+	client := &OAuthClient{}
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r *oauthRepository) ListClients(ctx context.Context) ([]OAuthClient, error) {
+	var clients []OAuthClient
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (r *oauthRepository) DeleteClient(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&OAuthClient{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func (r *oauthRepository) UpdateClientSecret(ctx context.Context, id string, secretHash string) error {
+	result := r.db.WithContext(ctx).Model(&OAuthClient{}).Where("id = ?", id).Update("secret_hash", secretHash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func (r *oauthRepository) CreateClientKey(ctx context.Context, key *OAuthClientKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrOAuthClientKeyDuplicated
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *oauthRepository) ListClientKeys(ctx context.Context, clientID string) ([]OAuthClientKey, error) {
+	var keys []OAuthClientKey
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *oauthRepository) RevokeClientKey(ctx context.Context, clientID string, id string, revokedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&OAuthClientKey{}).
+		Where("id = ? AND client_id = ? AND revoked_at IS NULL", id, clientID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOAuthClientKeyNotFound
+	}
+	return nil
+}
+
+func (r *oauthRepository) UseAssertion(ctx context.Context, assertion *OAuthUsedAssertion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&OAuthUsedAssertion{}).Error; err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(assertion)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOAuthAssertionReplayed
+		}
+		return nil
+	})
+}
+
+func (r *oauthRepository) CreateAuthorizationCode(ctx context.Context, code *OAuthAuthorizationCode) error {
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+// ConsumeAuthorizationCode marks the code used and returns it. A code can only be consumed once,
+// even by concurrent requests.
+func (r *oauthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string, usedAt time.Time) (*OAuthAuthorizationCode, error) {
+	var codes []OAuthAuthorizationCode
+	err := r.db.WithContext(ctx).Raw(`UPDATE oauth_authorization_codes SET used_at = ?
+		WHERE code_hash = ? AND used_at IS NULL RETURNING *`, usedAt, codeHash).Scan(&codes).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return nil, ErrOAuthAuthorizationCodeNotFound
+	}
+	return &codes[0], nil
+}
+
+func (r *oauthRepository) CreateRefreshToken(ctx context.Context, token *OAuthRefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// ConsumeRefreshToken revokes the token and returns it, so it can be exchanged only once.
+func (r *oauthRepository) ConsumeRefreshToken(ctx context.Context, tokenHash string, revokedAt time.Time) (*OAuthRefreshToken, error) {
+	var tokens []OAuthRefreshToken
+	err := r.db.WithContext(ctx).Raw(`UPDATE oauth_refresh_tokens SET revoked_at = ?
+		WHERE token_hash = ? AND revoked_at IS NULL RETURNING *`, revokedAt, tokenHash).Scan(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, ErrOAuthRefreshTokenNotFound
+	}
+	return &tokens[0], nil
+}
+
+func (r *oauthRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*OAuthRefreshToken, error) {
+	token := &OAuthRefreshToken{}
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeRefreshToken revokes a refresh token of clientID. Tokens of other clients are left alone.
+func (r *oauthRepository) RevokeRefreshToken(ctx context.Context, tokenHash string, clientID string, revokedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&OAuthRefreshToken{}).
+		Where("token_hash = ? AND client_id = ? AND revoked_at IS NULL", tokenHash, clientID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOAuthRefreshTokenNotFound
+	}
+	return nil
+}
+
+// RevokeAccessToken records the revocation. Revoking a token twice keeps the first revocation.
+func (r *oauthRepository) RevokeAccessToken(ctx context.Context, token *OAuthRevokedToken) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *oauthRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&OAuthRevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *oauthRepository) ListRevokedAccessTokens(ctx context.Context, since time.Time, now time.Time) ([]OAuthRevokedToken, error) {
+	var tokens []OAuthRevokedToken
+	err := r.db.WithContext(ctx).Where("revoked_at >= ? AND expires_at > ?", since, now).Order("revoked_at").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *oauthRepository) PurgeRevokedAccessTokens(ctx context.Context, expiredBefore time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at < ?", expiredBefore).Delete(&OAuthRevokedToken{}).Error
+}
+
+func (r *oauthRepository) CreateDeviceCode(ctx context.Context, code *OAuthDeviceCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&OAuthDeviceCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(code).Error
+	})
+}
+
+func (r *oauthRepository) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*OAuthDeviceCode, error) {
+	code := &OAuthDeviceCode{}
+	if err := r.db.WithContext(ctx).Where("user_code = ?", userCode).First(code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthDeviceCodeNotFound
+		}
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *oauthRepository) ApproveDeviceCode(ctx context.Context, userCode string, accountID uuid.UUID, username string, at time.Time) error {
+	return r.decideDeviceCode(ctx, userCode, at, map[string]interface{}{
+		"status":     OAuthDeviceCodeApproved,
+		"account_id": accountID,
+		"username":   username,
+		"auth_time":  at,
+	})
+}
+
+func (r *oauthRepository) DenyDeviceCode(ctx context.Context, userCode string, at time.Time) error {
+	return r.decideDeviceCode(ctx, userCode, at, map[string]interface{}{"status": OAuthDeviceCodeDenied})
+}
+
+func (r *oauthRepository) decideDeviceCode(ctx context.Context, userCode string, at time.Time, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&OAuthDeviceCode{}).
+		Where("user_code = ? AND status = ? AND expires_at > ?", userCode, OAuthDeviceCodePending, at).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOAuthDeviceCodeNotFound
+	}
+	return nil
+}
+
+func (r *oauthRepository) PollDeviceCode(ctx context.Context, deviceCodeHash string, polledAt time.Time) (*OAuthDeviceCode, error) {
+	code := &OAuthDeviceCode{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("device_code_hash = ?", deviceCodeHash).First(code).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOAuthDeviceCodeNotFound
+			}
+			return err
+		}
+		return tx.Model(&OAuthDeviceCode{}).Where("device_code_hash = ?", deviceCodeHash).Update("last_polled_at", polledAt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *oauthRepository) UpdateDevicePollInterval(ctx context.Context, deviceCodeHash string, seconds int) error {
+	return r.db.WithContext(ctx).Model(&OAuthDeviceCode{}).
+		Where("device_code_hash = ?", deviceCodeHash).Update("poll_interval", seconds).Error
+}
+
+func (r *oauthRepository) ConsumeDeviceCode(ctx context.Context, deviceCodeHash string, usedAt time.Time) (*OAuthDeviceCode, error) {
+	var codes []OAuthDeviceCode
+	err := r.db.WithContext(ctx).Raw(`UPDATE oauth_device_codes SET used_at = ?
+		WHERE device_code_hash = ? AND status = ? AND used_at IS NULL RETURNING *`, usedAt, deviceCodeHash, OAuthDeviceCodeApproved).Scan(&codes).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return nil, ErrOAuthDeviceCodeNotFound
+	}
+	return &codes[0], nil
+}
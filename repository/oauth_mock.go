@@ -0,0 +1,412 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: oauth.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockOAuthRepository is a mock of OAuthRepository interface.
+type MockOAuthRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthRepositoryMockRecorder
+}
+
+// MockOAuthRepositoryMockRecorder is the mock recorder for MockOAuthRepository.
+type MockOAuthRepositoryMockRecorder struct {
+	mock *MockOAuthRepository
+}
+
+// NewMockOAuthRepository creates a new mock instance.
+func NewMockOAuthRepository(ctrl *gomock.Controller) *MockOAuthRepository {
+	mock := &MockOAuthRepository{ctrl: ctrl}
+	mock.recorder = &MockOAuthRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthRepository) EXPECT() *MockOAuthRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ApproveDeviceCode mocks base method.
+func (m *MockOAuthRepository) ApproveDeviceCode(ctx context.Context, userCode string, accountID uuid.UUID, username string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveDeviceCode", ctx, userCode, accountID, username, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveDeviceCode indicates an expected call of ApproveDeviceCode.
+func (mr *MockOAuthRepositoryMockRecorder) ApproveDeviceCode(ctx, userCode, accountID, username, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveDeviceCode", reflect.TypeOf((*MockOAuthRepository)(nil).ApproveDeviceCode), ctx, userCode, accountID, username, at)
+}
+
+// ConsumeAuthorizationCode mocks base method.
+func (m *MockOAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string, usedAt time.Time) (*OAuthAuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeAuthorizationCode", ctx, codeHash, usedAt)
+	ret0, _ := ret[0].(*OAuthAuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeAuthorizationCode indicates an expected call of ConsumeAuthorizationCode.
+func (mr *MockOAuthRepositoryMockRecorder) ConsumeAuthorizationCode(ctx, codeHash, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeAuthorizationCode", reflect.TypeOf((*MockOAuthRepository)(nil).ConsumeAuthorizationCode), ctx, codeHash, usedAt)
+}
+
+// ConsumeDeviceCode mocks base method.
+func (m *MockOAuthRepository) ConsumeDeviceCode(ctx context.Context, deviceCodeHash string, usedAt time.Time) (*OAuthDeviceCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeDeviceCode", ctx, deviceCodeHash, usedAt)
+	ret0, _ := ret[0].(*OAuthDeviceCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeDeviceCode indicates an expected call of ConsumeDeviceCode.
+func (mr *MockOAuthRepositoryMockRecorder) ConsumeDeviceCode(ctx, deviceCodeHash, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeDeviceCode", reflect.TypeOf((*MockOAuthRepository)(nil).ConsumeDeviceCode), ctx, deviceCodeHash, usedAt)
+}
+
+// ConsumeRefreshToken mocks base method.
+func (m *MockOAuthRepository) ConsumeRefreshToken(ctx context.Context, tokenHash string, revokedAt time.Time) (*OAuthRefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeRefreshToken", ctx, tokenHash, revokedAt)
+	ret0, _ := ret[0].(*OAuthRefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeRefreshToken indicates an expected call of ConsumeRefreshToken.
+func (mr *MockOAuthRepositoryMockRecorder) ConsumeRefreshToken(ctx, tokenHash, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeRefreshToken", reflect.TypeOf((*MockOAuthRepository)(nil).ConsumeRefreshToken), ctx, tokenHash, revokedAt)
+}
+
+// CreateAuthorizationCode mocks base method.
+func (m *MockOAuthRepository) CreateAuthorizationCode(ctx context.Context, code *OAuthAuthorizationCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthorizationCode", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuthorizationCode indicates an expected call of CreateAuthorizationCode.
+func (mr *MockOAuthRepositoryMockRecorder) CreateAuthorizationCode(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthorizationCode", reflect.TypeOf((*MockOAuthRepository)(nil).CreateAuthorizationCode), ctx, code)
+}
+
+// CreateClient mocks base method.
+func (m *MockOAuthRepository) CreateClient(ctx context.Context, client *OAuthClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClient", ctx, client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateClient indicates an expected call of CreateClient.
+func (mr *MockOAuthRepositoryMockRecorder) CreateClient(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClient", reflect.TypeOf((*MockOAuthRepository)(nil).CreateClient), ctx, client)
+}
+
+// CreateClientKey mocks base method.
+func (m *MockOAuthRepository) CreateClientKey(ctx context.Context, key *OAuthClientKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClientKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateClientKey indicates an expected call of CreateClientKey.
+func (mr *MockOAuthRepositoryMockRecorder) CreateClientKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClientKey", reflect.TypeOf((*MockOAuthRepository)(nil).CreateClientKey), ctx, key)
+}
+
+// CreateDeviceCode mocks base method.
+func (m *MockOAuthRepository) CreateDeviceCode(ctx context.Context, code *OAuthDeviceCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeviceCode", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDeviceCode indicates an expected call of CreateDeviceCode.
+func (mr *MockOAuthRepositoryMockRecorder) CreateDeviceCode(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeviceCode", reflect.TypeOf((*MockOAuthRepository)(nil).CreateDeviceCode), ctx, code)
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockOAuthRepository) CreateRefreshToken(ctx context.Context, token *OAuthRefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockOAuthRepositoryMockRecorder) CreateRefreshToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockOAuthRepository)(nil).CreateRefreshToken), ctx, token)
+}
+
+// DeleteClient mocks base method.
+func (m *MockOAuthRepository) DeleteClient(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteClient", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteClient indicates an expected call of DeleteClient.
+func (mr *MockOAuthRepositoryMockRecorder) DeleteClient(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteClient", reflect.TypeOf((*MockOAuthRepository)(nil).DeleteClient), ctx, id)
+}
+
+// DenyDeviceCode mocks base method.
+func (m *MockOAuthRepository) DenyDeviceCode(ctx context.Context, userCode string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DenyDeviceCode", ctx, userCode, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DenyDeviceCode indicates an expected call of DenyDeviceCode.
+func (mr *MockOAuthRepositoryMockRecorder) DenyDeviceCode(ctx, userCode, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DenyDeviceCode", reflect.TypeOf((*MockOAuthRepository)(nil).DenyDeviceCode), ctx, userCode, at)
+}
+
+// GetClient mocks base method.
+func (m *MockOAuthRepository) GetClient(ctx context.Context, id string) (*OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClient", ctx, id)
+	ret0, _ := ret[0].(*OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClient indicates an expected call of GetClient.
+func (mr *MockOAuthRepositoryMockRecorder) GetClient(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockOAuthRepository)(nil).GetClient), ctx, id)
+}
+
+// GetDeviceCodeByUserCode mocks base method.
+func (m *MockOAuthRepository) GetDeviceCodeByUserCode(ctx context.Context, userCode string) (*OAuthDeviceCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceCodeByUserCode", ctx, userCode)
+	ret0, _ := ret[0].(*OAuthDeviceCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceCodeByUserCode indicates an expected call of GetDeviceCodeByUserCode.
+func (mr *MockOAuthRepositoryMockRecorder) GetDeviceCodeByUserCode(ctx, userCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceCodeByUserCode", reflect.TypeOf((*MockOAuthRepository)(nil).GetDeviceCodeByUserCode), ctx, userCode)
+}
+
+// GetRefreshToken mocks base method.
+func (m *MockOAuthRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*OAuthRefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshToken", ctx, tokenHash)
+	ret0, _ := ret[0].(*OAuthRefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshToken indicates an expected call of GetRefreshToken.
+func (mr *MockOAuthRepositoryMockRecorder) GetRefreshToken(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshToken", reflect.TypeOf((*MockOAuthRepository)(nil).GetRefreshToken), ctx, tokenHash)
+}
+
+// IsAccessTokenRevoked mocks base method.
+func (m *MockOAuthRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAccessTokenRevoked", ctx, jti)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsAccessTokenRevoked indicates an expected call of IsAccessTokenRevoked.
+func (mr *MockOAuthRepositoryMockRecorder) IsAccessTokenRevoked(ctx, jti interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAccessTokenRevoked", reflect.TypeOf((*MockOAuthRepository)(nil).IsAccessTokenRevoked), ctx, jti)
+}
+
+// ListClientKeys mocks base method.
+func (m *MockOAuthRepository) ListClientKeys(ctx context.Context, clientID string) ([]OAuthClientKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClientKeys", ctx, clientID)
+	ret0, _ := ret[0].([]OAuthClientKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClientKeys indicates an expected call of ListClientKeys.
+func (mr *MockOAuthRepositoryMockRecorder) ListClientKeys(ctx, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClientKeys", reflect.TypeOf((*MockOAuthRepository)(nil).ListClientKeys), ctx, clientID)
+}
+
+// ListClients mocks base method.
+func (m *MockOAuthRepository) ListClients(ctx context.Context) ([]OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClients", ctx)
+	ret0, _ := ret[0].([]OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClients indicates an expected call of ListClients.
+func (mr *MockOAuthRepositoryMockRecorder) ListClients(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClients", reflect.TypeOf((*MockOAuthRepository)(nil).ListClients), ctx)
+}
+
+// ListRevokedAccessTokens mocks base method.
+func (m *MockOAuthRepository) ListRevokedAccessTokens(ctx context.Context, since, now time.Time) ([]OAuthRevokedToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRevokedAccessTokens", ctx, since, now)
+	ret0, _ := ret[0].([]OAuthRevokedToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRevokedAccessTokens indicates an expected call of ListRevokedAccessTokens.
+func (mr *MockOAuthRepositoryMockRecorder) ListRevokedAccessTokens(ctx, since, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRevokedAccessTokens", reflect.TypeOf((*MockOAuthRepository)(nil).ListRevokedAccessTokens), ctx, since, now)
+}
+
+// PollDeviceCode mocks base method.
+func (m *MockOAuthRepository) PollDeviceCode(ctx context.Context, deviceCodeHash string, polledAt time.Time) (*OAuthDeviceCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PollDeviceCode", ctx, deviceCodeHash, polledAt)
+	ret0, _ := ret[0].(*OAuthDeviceCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PollDeviceCode indicates an expected call of PollDeviceCode.
+func (mr *MockOAuthRepositoryMockRecorder) PollDeviceCode(ctx, deviceCodeHash, polledAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PollDeviceCode", reflect.TypeOf((*MockOAuthRepository)(nil).PollDeviceCode), ctx, deviceCodeHash, polledAt)
+}
+
+// PurgeRevokedAccessTokens mocks base method.
+func (m *MockOAuthRepository) PurgeRevokedAccessTokens(ctx context.Context, expiredBefore time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeRevokedAccessTokens", ctx, expiredBefore)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeRevokedAccessTokens indicates an expected call of PurgeRevokedAccessTokens.
+func (mr *MockOAuthRepositoryMockRecorder) PurgeRevokedAccessTokens(ctx, expiredBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeRevokedAccessTokens", reflect.TypeOf((*MockOAuthRepository)(nil).PurgeRevokedAccessTokens), ctx, expiredBefore)
+}
+
+// RevokeAccessToken mocks base method.
+func (m *MockOAuthRepository) RevokeAccessToken(ctx context.Context, token *OAuthRevokedToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccessToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAccessToken indicates an expected call of RevokeAccessToken.
+func (mr *MockOAuthRepositoryMockRecorder) RevokeAccessToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccessToken", reflect.TypeOf((*MockOAuthRepository)(nil).RevokeAccessToken), ctx, token)
+}
+
+// RevokeClientKey mocks base method.
+func (m *MockOAuthRepository) RevokeClientKey(ctx context.Context, clientID, id string, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeClientKey", ctx, clientID, id, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeClientKey indicates an expected call of RevokeClientKey.
+func (mr *MockOAuthRepositoryMockRecorder) RevokeClientKey(ctx, clientID, id, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeClientKey", reflect.TypeOf((*MockOAuthRepository)(nil).RevokeClientKey), ctx, clientID, id, revokedAt)
+}
+
+// RevokeRefreshToken mocks base method.
+func (m *MockOAuthRepository) RevokeRefreshToken(ctx context.Context, tokenHash, clientID string, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshToken", ctx, tokenHash, clientID, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshToken indicates an expected call of RevokeRefreshToken.
+func (mr *MockOAuthRepositoryMockRecorder) RevokeRefreshToken(ctx, tokenHash, clientID, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshToken", reflect.TypeOf((*MockOAuthRepository)(nil).RevokeRefreshToken), ctx, tokenHash, clientID, revokedAt)
+}
+
+// UpdateClientSecret mocks base method.
+func (m *MockOAuthRepository) UpdateClientSecret(ctx context.Context, id, secretHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateClientSecret", ctx, id, secretHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateClientSecret indicates an expected call of UpdateClientSecret.
+func (mr *MockOAuthRepositoryMockRecorder) UpdateClientSecret(ctx, id, secretHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateClientSecret", reflect.TypeOf((*MockOAuthRepository)(nil).UpdateClientSecret), ctx, id, secretHash)
+}
+
+// UpdateDevicePollInterval mocks base method.
+func (m *MockOAuthRepository) UpdateDevicePollInterval(ctx context.Context, deviceCodeHash string, seconds int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDevicePollInterval", ctx, deviceCodeHash, seconds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDevicePollInterval indicates an expected call of UpdateDevicePollInterval.
+func (mr *MockOAuthRepositoryMockRecorder) UpdateDevicePollInterval(ctx, deviceCodeHash, seconds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDevicePollInterval", reflect.TypeOf((*MockOAuthRepository)(nil).UpdateDevicePollInterval), ctx, deviceCodeHash, seconds)
+}
+
+// UseAssertion mocks base method.
+func (m *MockOAuthRepository) UseAssertion(ctx context.Context, assertion *OAuthUsedAssertion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAssertion", ctx, assertion)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseAssertion indicates an expected call of UseAssertion.
+func (mr *MockOAuthRepositoryMockRecorder) UseAssertion(ctx, assertion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAssertion", reflect.TypeOf((*MockOAuthRepository)(nil).UseAssertion), ctx, assertion)
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpOAuthMock(t *testing.T) (OAuthRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewOAuthRepository(gormDB), mockDb, mock
+}
+
+const consumeAuthorizationCodeQuery = `UPDATE oauth_authorization_codes SET used_at = $1
+		WHERE code_hash = $2 AND used_at IS NULL RETURNING *`
+
+func TestConsumeAuthorizationCode(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	usedAt := time.Now()
+	accountID := uuid.New()
+	rows := sqlmock.NewRows([]string{"code_hash", "created_at", "client_id", "account_id", "username", "redirect_uri",
+		"scope", "code_challenge", "code_challenge_method", "expires_at", "used_at"}).
+		AddRow("hash", time.Now(), "client", accountID, "alice", "https://app.example.com/callback",
+			"profile", "challenge", "S256", time.Now().Add(time.Minute), usedAt)
+	mock.ExpectQuery(consumeAuthorizationCodeQuery).
+		WithArgs(usedAt, "hash").
+		WillReturnRows(rows)
+
+	code, err := repo.ConsumeAuthorizationCode(context.Background(), "hash", usedAt)
+	require.NoError(t, err)
+	require.Equal(t, accountID, code.AccountID)
+	require.Equal(t, "challenge", code.CodeChallenge)
+	require.NotNil(t, code.UsedAt)
+}
+
+func TestConsumeAuthorizationCodeAlreadyUsed(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	usedAt := time.Now()
+	mock.ExpectQuery(consumeAuthorizationCodeQuery).
+		WithArgs(usedAt, "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"code_hash"}))
+
+	code, err := repo.ConsumeAuthorizationCode(context.Background(), "hash", usedAt)
+	require.EqualError(t, err, ErrOAuthAuthorizationCodeNotFound.Error())
+	require.Nil(t, code)
+}
+
+func TestConsumeRefreshTokenRevoked(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	revokedAt := time.Now()
+	mock.ExpectQuery(`UPDATE oauth_refresh_tokens SET revoked_at = $1
+		WHERE token_hash = $2 AND revoked_at IS NULL RETURNING *`).
+		WithArgs(revokedAt, "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"token_hash"}))
+
+	token, err := repo.ConsumeRefreshToken(context.Background(), "hash", revokedAt)
+	require.EqualError(t, err, ErrOAuthRefreshTokenNotFound.Error())
+	require.Nil(t, token)
+}
+
+func TestGetOAuthClientNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "oauth_clients" WHERE id = $1 AND "oauth_clients"."deleted_at" IS NULL ORDER BY "oauth_clients"."id" LIMIT 1`).
+		WithArgs("missing").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	client, err := repo.GetClient(context.Background(), "missing")
+	require.EqualError(t, err, ErrOAuthClientNotFound.Error())
+	require.Nil(t, client)
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	revokedAt := time.Now()
+	query := `UPDATE "oauth_refresh_tokens" SET "revoked_at"=$1 WHERE token_hash = $2 AND client_id = $3 AND revoked_at IS NULL`
+	mock.ExpectBegin()
+	mock.ExpectExec(query).
+		WithArgs(revokedAt, "hash", "client").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.RevokeRefreshToken(context.Background(), "hash", "client", revokedAt))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).
+		WithArgs(revokedAt, "hash", "other").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	err := repo.RevokeRefreshToken(context.Background(), "hash", "other", revokedAt)
+	require.EqualError(t, err, ErrOAuthRefreshTokenNotFound.Error())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	token := &OAuthRevokedToken{JTI: "jti", ClientID: "client", RevokedAt: time.Now(), ExpiresAt: time.Now().Add(time.Minute)}
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "oauth_revoked_tokens" ("jti","client_id","revoked_at","expires_at") VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`).
+		WithArgs("jti", "client", token.RevokedAt, token.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.RevokeAccessToken(context.Background(), token))
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT * FROM "oauth_revoked_tokens" WHERE revoked_at >= $1 AND expires_at > $2 ORDER BY revoked_at`).
+		WithArgs(token.RevokedAt, now).
+		WillReturnRows(sqlmock.NewRows([]string{"jti", "client_id", "revoked_at", "expires_at"}).
+			AddRow("jti", "client", token.RevokedAt, token.ExpiresAt))
+	tokens, err := repo.ListRevokedAccessTokens(context.Background(), token.RevokedAt, now)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, "jti", tokens[0].JTI)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseAssertionReplayed(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	assertion := &OAuthUsedAssertion{ClientID: "billing", JTI: "jti-1", ExpiresAt: time.Now().Add(time.Minute)}
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "oauth_used_assertions" WHERE expires_at < $1`).
+		WithArgs(AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO "oauth_used_assertions" ("client_id","jti","expires_at") VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`).
+		WithArgs(assertion.ClientID, assertion.JTI, AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.UseAssertion(context.Background(), assertion)
+	require.EqualError(t, err, ErrOAuthAssertionReplayed.Error())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDecideDeviceCode(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	at := time.Now()
+	accountID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "oauth_device_codes" SET "account_id"=$1,"auth_time"=$2,"status"=$3,"username"=$4 WHERE user_code = $5 AND status = $6 AND expires_at > $7`).
+		WithArgs(accountID, at, OAuthDeviceCodeApproved, "alice", "BCDFGHJK", OAuthDeviceCodePending, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.ApproveDeviceCode(context.Background(), "BCDFGHJK", accountID, "alice", at))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "oauth_device_codes" SET "status"=$1 WHERE user_code = $2 AND status = $3 AND expires_at > $4`).
+		WithArgs(OAuthDeviceCodeDenied, "BCDFGHJK", OAuthDeviceCodePending, at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	err := repo.DenyDeviceCode(context.Background(), "BCDFGHJK", at)
+	require.EqualError(t, err, ErrOAuthDeviceCodeNotFound.Error())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPollDeviceCode(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	lastPolledAt := time.Now().Add(-time.Second)
+	polledAt := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT * FROM "oauth_device_codes" WHERE device_code_hash = $1 ORDER BY "oauth_device_codes"."device_code_hash" LIMIT 1 FOR UPDATE`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"device_code_hash", "status", "poll_interval", "last_polled_at"}).
+			AddRow("hash", OAuthDeviceCodePending, 5, lastPolledAt))
+	mock.ExpectExec(`UPDATE "oauth_device_codes" SET "last_polled_at"=$1 WHERE device_code_hash = $2`).
+		WithArgs(polledAt, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	code, err := repo.PollDeviceCode(context.Background(), "hash", polledAt)
+	require.NoError(t, err)
+	require.Equal(t, 5, code.PollInterval)
+	require.WithinDuration(t, lastPolledAt, *code.LastPolledAt, 0)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumeDeviceCodeNotApproved(t *testing.T) {
+	repo, mockDB, mock := setUpOAuthMock(t)
+	defer mockDB.Close()
+
+	usedAt := time.Now()
+	mock.ExpectQuery(`UPDATE oauth_device_codes SET used_at = $1
+		WHERE device_code_hash = $2 AND status = $3 AND used_at IS NULL RETURNING *`).
+		WithArgs(usedAt, "hash", OAuthDeviceCodeApproved).
+		WillReturnRows(sqlmock.NewRows([]string{"device_code_hash"}))
+
+	code, err := repo.ConsumeDeviceCode(context.Background(), "hash", usedAt)
+	require.EqualError(t, err, ErrOAuthDeviceCodeNotFound.Error())
+	require.Nil(t, code)
+}
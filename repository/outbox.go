@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxRepository interface {
+	CreateOutboxMessage(ctx context.Context, message *OutboxMessage) error
+	ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error)
+	UpdateOutboxMessage(ctx context.Context, message *OutboxMessage) error
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (r *outboxRepository) CreateOutboxMessage(ctx context.Context, message *OutboxMessage) error {
+	return createOutboxMessages(r.db.WithContext(ctx), message)
+}
+
+// ClaimOutboxMessages leases up to limit due messages by pushing their next attempt past the lease,
+// so concurrent relays on other replicas skip them.
+func (r *outboxRepository) ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	var messages []OutboxMessage
+	now := time.Now()
+	err := r.db.WithContext(ctx).Raw(`UPDATE outbox SET next_attempt_at = ? WHERE id IN (
+		SELECT id FROM outbox
+		WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?
+		ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED
+	) RETURNING *`, now.Add(lease), now, limit).Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *outboxRepository) UpdateOutboxMessage(ctx context.Context, message *OutboxMessage) error {
+	return r.db.WithContext(ctx).Model(message).
+		Select("DeliveredSinks", "Attempts", "LastError", "NextAttemptAt", "PublishedAt", "FailedAt").
+		Updates(message).Error
+}
+
+func createOutboxMessages(tx *gorm.DB, messages ...*OutboxMessage) error {
+	for _, message := range messages {
+		if message.NextAttemptAt.IsZero() {
+			message.NextAttemptAt = time.Now()
+		}
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
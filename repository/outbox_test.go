@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpOutboxMock(t *testing.T) (OutboxRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewOutboxRepository(gormDB), mockDb, mock
+}
+
+func TestClaimOutboxMessages(t *testing.T) {
+	repo, mockDB, mock := setUpOutboxMock(t)
+	defer mockDB.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "created_at", "idempotency_key", "type", "payload", "delivered_sinks",
+		"attempts", "last_error", "next_attempt_at", "published_at", "failed_at"}).
+		AddRow(1, time.Now(), "event-1", "account.created", "{}", "webhook", 1, "nats: timeout", time.Now(), nil, nil)
+	mock.ExpectQuery(`UPDATE outbox SET next_attempt_at = $1 WHERE id IN (
+		SELECT id FROM outbox
+		WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= $2
+		ORDER BY id LIMIT $3 FOR UPDATE SKIP LOCKED
+	) RETURNING *`).
+		WithArgs(AnyTime{}, AnyTime{}, 10).
+		WillReturnRows(rows)
+
+	messages, err := repo.ClaimOutboxMessages(context.Background(), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, "event-1", messages[0].IdempotencyKey)
+	require.Equal(t, "webhook", messages[0].DeliveredSinks)
+}
+
+func TestUpdateOutboxMessage(t *testing.T) {
+	repo, mockDB, mock := setUpOutboxMock(t)
+	defer mockDB.Close()
+
+	publishedAt := time.Now()
+	message := &OutboxMessage{
+		ID:             1,
+		DeliveredSinks: "webhook,nats",
+		Attempts:       2,
+		NextAttemptAt:  time.Now(),
+		PublishedAt:    &publishedAt,
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "outbox" SET "delivered_sinks"=$1,"attempts"=$2,"last_error"=$3,"next_attempt_at"=$4,"published_at"=$5,"failed_at"=$6 WHERE "id" = $7`).
+		WithArgs("webhook,nats", 2, "", AnyTime{}, AnyTime{}, nil, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.UpdateOutboxMessage(context.Background(), message)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var ErrPersonalAccessTokenNotFound = errors.New("Personal access token is not found")
+
+type PersonalAccessTokenRepository interface {
+	CreatePersonalAccessToken(ctx context.Context, token *PersonalAccessToken) error
+	// ListPersonalAccessTokens lists the tokens of the account that are not revoked, newest first.
+	ListPersonalAccessTokens(ctx context.Context, accountID uuid.UUID) ([]PersonalAccessToken, error)
+	GetPersonalAccessToken(ctx context.Context, tokenHash string) (*PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, accountID uuid.UUID, id uuid.UUID, revokedAt time.Time) error
+	TouchPersonalAccessToken(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type personalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{
+		db: db,
+	}
+}
+
+func (r *personalAccessTokenRepository) CreatePersonalAccessToken(ctx context.Context, token *PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *personalAccessTokenRepository) ListPersonalAccessTokens(ctx context.Context, accountID uuid.UUID) ([]PersonalAccessToken, error) {
+	var tokens []PersonalAccessToken
+	err := r.db.WithContext(ctx).
+		Where("account_id = ? AND revoked_at IS NULL", accountID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *personalAccessTokenRepository) GetPersonalAccessToken(ctx context.Context, tokenHash string) (*PersonalAccessToken, error) {
+	token := &PersonalAccessToken{}
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonalAccessTokenNotFound
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *personalAccessTokenRepository) RevokePersonalAccessToken(ctx context.Context, accountID uuid.UUID, id uuid.UUID, revokedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&PersonalAccessToken{}).
+		Where("id = ? AND account_id = ? AND revoked_at IS NULL", id, accountID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
+
+// TouchPersonalAccessToken records when the token was last used. It never moves the time back, so
+// concurrent requests cannot overwrite a later use with an earlier one.
+func (r *personalAccessTokenRepository) TouchPersonalAccessToken(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, usedAt).
+		Update("last_used_at", usedAt).Error
+}
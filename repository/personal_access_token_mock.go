@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: personal_access_token.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockPersonalAccessTokenRepository is a mock of PersonalAccessTokenRepository interface.
+type MockPersonalAccessTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPersonalAccessTokenRepositoryMockRecorder
+}
+
+// MockPersonalAccessTokenRepositoryMockRecorder is the mock recorder for MockPersonalAccessTokenRepository.
+type MockPersonalAccessTokenRepositoryMockRecorder struct {
+	mock *MockPersonalAccessTokenRepository
+}
+
+// NewMockPersonalAccessTokenRepository creates a new mock instance.
+func NewMockPersonalAccessTokenRepository(ctrl *gomock.Controller) *MockPersonalAccessTokenRepository {
+	mock := &MockPersonalAccessTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockPersonalAccessTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPersonalAccessTokenRepository) EXPECT() *MockPersonalAccessTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreatePersonalAccessToken mocks base method.
+func (m *MockPersonalAccessTokenRepository) CreatePersonalAccessToken(ctx context.Context, token *PersonalAccessToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePersonalAccessToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePersonalAccessToken indicates an expected call of CreatePersonalAccessToken.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) CreatePersonalAccessToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePersonalAccessToken", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).CreatePersonalAccessToken), ctx, token)
+}
+
+// GetPersonalAccessToken mocks base method.
+func (m *MockPersonalAccessTokenRepository) GetPersonalAccessToken(ctx context.Context, tokenHash string) (*PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPersonalAccessToken", ctx, tokenHash)
+	ret0, _ := ret[0].(*PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPersonalAccessToken indicates an expected call of GetPersonalAccessToken.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) GetPersonalAccessToken(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPersonalAccessToken", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).GetPersonalAccessToken), ctx, tokenHash)
+}
+
+// ListPersonalAccessTokens mocks base method.
+func (m *MockPersonalAccessTokenRepository) ListPersonalAccessTokens(ctx context.Context, accountID uuid.UUID) ([]PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPersonalAccessTokens", ctx, accountID)
+	ret0, _ := ret[0].([]PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPersonalAccessTokens indicates an expected call of ListPersonalAccessTokens.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) ListPersonalAccessTokens(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPersonalAccessTokens", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).ListPersonalAccessTokens), ctx, accountID)
+}
+
+// RevokePersonalAccessToken mocks base method.
+func (m *MockPersonalAccessTokenRepository) RevokePersonalAccessToken(ctx context.Context, accountID, id uuid.UUID, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokePersonalAccessToken", ctx, accountID, id, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokePersonalAccessToken indicates an expected call of RevokePersonalAccessToken.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) RevokePersonalAccessToken(ctx, accountID, id, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokePersonalAccessToken", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).RevokePersonalAccessToken), ctx, accountID, id, revokedAt)
+}
+
+// TouchPersonalAccessToken mocks base method.
+func (m *MockPersonalAccessTokenRepository) TouchPersonalAccessToken(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchPersonalAccessToken", ctx, id, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchPersonalAccessToken indicates an expected call of TouchPersonalAccessToken.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) TouchPersonalAccessToken(ctx, id, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchPersonalAccessToken", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).TouchPersonalAccessToken), ctx, id, usedAt)
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpPersonalAccessTokenMock(t *testing.T) (PersonalAccessTokenRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewPersonalAccessTokenRepository(gormDB), mockDb, mock
+}
+
+func TestGetPersonalAccessTokenNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpPersonalAccessTokenMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "personal_access_tokens" WHERE token_hash = $1 ORDER BY "personal_access_tokens"."id" LIMIT 1`).
+		WithArgs("hash").
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := repo.GetPersonalAccessToken(context.Background(), "hash")
+	require.EqualError(t, err, ErrPersonalAccessTokenNotFound.Error())
+}
+
+func TestRevokePersonalAccessToken(t *testing.T) {
+	accountID := uuid.New()
+	id := uuid.New()
+
+	testCases := []struct {
+		name    string
+		revoked int64
+		err     error
+	}{
+		{name: "ok", revoked: 1},
+		{name: "not found", revoked: 0, err: ErrPersonalAccessTokenNotFound},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockDB, mock := setUpPersonalAccessTokenMock(t)
+			defer mockDB.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`UPDATE "personal_access_tokens" SET "revoked_at"=$1 WHERE id = $2 AND account_id = $3 AND revoked_at IS NULL`).
+				WithArgs(AnyTime{}, id, accountID).
+				WillReturnResult(sqlmock.NewResult(0, tc.revoked))
+			mock.ExpectCommit()
+
+			err := repo.RevokePersonalAccessToken(context.Background(), accountID, id, time.Now())
+			if tc.err != nil {
+				require.EqualError(t, err, tc.err.Error())
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTouchPersonalAccessToken(t *testing.T) {
+	repo, mockDB, mock := setUpPersonalAccessTokenMock(t)
+	defer mockDB.Close()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "personal_access_tokens" SET "last_used_at"=$1 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`).
+		WithArgs(AnyTime{}, id, AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.TouchPersonalAccessToken(context.Background(), id, time.Now())
+	require.NoError(t, err)
+}
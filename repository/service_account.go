@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Account types. Service accounts have no password and authenticate as an OAuth client. Voucher
+// accounts log in with their code and have the limits of their Voucher.
+const (
+	AccountTypeUser    = "user"
+	AccountTypeService = "service"
+	AccountTypeVoucher = "voucher"
+)
+
+var ErrServiceAccountNotFound = errors.New("Service account is not found")
+
+// ServiceAccountRepository stores service accounts together with the OAuth client they authenticate with.
+type ServiceAccountRepository interface {
+	// CreateServiceAccount stores the account, its client and its outbox messages in one transaction.
+	CreateServiceAccount(ctx context.Context, account *Account, client *OAuthClient, messages ...*OutboxMessage) error
+	ListServiceAccounts(ctx context.Context) ([]Account, error)
+	GetServiceAccount(ctx context.Context, username string) (*Account, error)
+	GetServiceAccountClient(ctx context.Context, account *Account) (*OAuthClient, error)
+	// DeleteServiceAccount deletes the account with its client and keys in one transaction.
+	DeleteServiceAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error
+}
+
+type serviceAccountRepository struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountRepository(db *gorm.DB) ServiceAccountRepository {
+	return &serviceAccountRepository{
+		db: db,
+	}
+}
+
+func (r *serviceAccountRepository) CreateServiceAccount(ctx context.Context, account *Account, client *OAuthClient, messages ...*OutboxMessage) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(client).Error; err != nil {
+			return err
+		}
+		return createOutboxMessages(tx, messages...)
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrAccountIsDuplicated
+	}
+	return err
+}
+
+func (r *serviceAccountRepository) ListServiceAccounts(ctx context.Context) ([]Account, error) {
+	var accounts []Account
+	if err := r.db.WithContext(ctx).Where("type = ?", AccountTypeService).Order("username").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *serviceAccountRepository) GetServiceAccount(ctx context.Context, username string) (*Account, error) {
+	account := &Account{}
+	if err := r.db.WithContext(ctx).Where("username = ? AND type = ?", username, AccountTypeService).First(account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+func (r *serviceAccountRepository) GetServiceAccountClient(ctx context.Context, account *Account) (*OAuthClient, error) {
+	client := &OAuthClient{}
+	if err := r.db.WithContext(ctx).Where("account_id = ?", account.ID).First(client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r *serviceAccountRepository) DeleteServiceAccount(ctx context.Context, account *Account, messages ...*OutboxMessage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		clients := tx.Model(&OAuthClient{}).Select("id").Where("account_id = ?", account.ID)
+		if err := tx.Where("client_id IN (?)", clients).Delete(&OAuthClientKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("account_id = ?", account.ID).Delete(&OAuthClient{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(account)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrServiceAccountNotFound
+		}
+		return createOutboxMessages(tx, messages...)
+	})
+}
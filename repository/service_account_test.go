@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpServiceAccountMock(t *testing.T) (ServiceAccountRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewServiceAccountRepository(gormDB), mockDb, mock
+}
+
+func TestGetServiceAccountNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpServiceAccountMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "accounts" WHERE (username = $1 AND type = $2) AND "accounts"."deleted_at" IS NULL ORDER BY "accounts"."id" LIMIT 1`).
+		WithArgs("alice", AccountTypeService).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := repo.GetServiceAccount(context.Background(), "alice")
+	require.EqualError(t, err, ErrServiceAccountNotFound.Error())
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+	repo, mockDB, mock := setUpServiceAccountMock(t)
+	defer mockDB.Close()
+
+	account := &Account{ID: uuid.New(), Username: "billing", Type: AccountTypeService}
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "oauth_client_keys" WHERE client_id IN (SELECT "id" FROM "oauth_clients" WHERE account_id = $1 AND "oauth_clients"."deleted_at" IS NULL)`).
+		WithArgs(account.ID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE "oauth_clients" SET "deleted_at"=$1 WHERE account_id = $2 AND "oauth_clients"."deleted_at" IS NULL`).
+		WithArgs(AnyTime{}, account.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "accounts" SET "deleted_at"=$1 WHERE "accounts"."id" = $2 AND "accounts"."deleted_at" IS NULL`).
+		WithArgs(AnyTime{}, account.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.DeleteServiceAccount(context.Background(), account)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
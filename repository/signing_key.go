@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var ErrSigningKeyNotFound = errors.New("Signing key is not found")
+
+// signingKeyLockID serializes changes to the signing keys of all replicas through a postgres advisory lock.
+const signingKeyLockID = 20261019
+
+type SigningKeyRepository interface {
+	ListSigningKeys(ctx context.Context, purpose string) ([]SigningKey, error)
+	GetSigningKey(ctx context.Context, id string) (*SigningKey, error)
+	// UpdateSigningKeys hands the keys of purpose to fn while holding a lock, then saves the keys fn
+	// changed and creates the keys fn returns as new, all in one transaction.
+	UpdateSigningKeys(ctx context.Context, purpose string, fn func(keys []SigningKey) (changed []SigningKey, created []SigningKey, err error)) error
+}
+
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) SigningKeyRepository {
+	return &signingKeyRepository{
+		db: db,
+	}
+}
+
+func (r *signingKeyRepository) ListSigningKeys(ctx context.Context, purpose string) ([]SigningKey, error) {
+	var keys []SigningKey
+	if err := r.db.WithContext(ctx).Where("purpose = ?", purpose).Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *signingKeyRepository) GetSigningKey(ctx context.Context, id string) (*SigningKey, error) {
+	key := &SigningKey{}
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSigningKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *signingKeyRepository) UpdateSigningKeys(ctx context.Context, purpose string,
+	fn func(keys []SigningKey) ([]SigningKey, []SigningKey, error)) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyLockID).Error; err != nil {
+			return err
+		}
+		var keys []SigningKey
+		if err := tx.Where("purpose = ?", purpose).Order("created_at").Find(&keys).Error; err != nil {
+			return err
+		}
+
+		changed, created, err := fn(keys)
+		if err != nil {
+			return err
+		}
+		for i := range changed {
+			err := tx.Model(&SigningKey{}).Where("id = ?", changed[i].ID).Updates(map[string]interface{}{
+				"status":       changed[i].Status,
+				"activated_at": changed[i].ActivatedAt,
+				"retired_at":   changed[i].RetiredAt,
+				"expires_at":   changed[i].ExpiresAt,
+				"revoked_at":   changed[i].RevokedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		for i := range created {
+			if err := tx.Create(&created[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: signing_key.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSigningKeyRepository is a mock of SigningKeyRepository interface.
+type MockSigningKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSigningKeyRepositoryMockRecorder
+}
+
+// MockSigningKeyRepositoryMockRecorder is the mock recorder for MockSigningKeyRepository.
+type MockSigningKeyRepositoryMockRecorder struct {
+	mock *MockSigningKeyRepository
+}
+
+// NewMockSigningKeyRepository creates a new mock instance.
+func NewMockSigningKeyRepository(ctrl *gomock.Controller) *MockSigningKeyRepository {
+	mock := &MockSigningKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockSigningKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSigningKeyRepository) EXPECT() *MockSigningKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetSigningKey mocks base method.
+func (m *MockSigningKeyRepository) GetSigningKey(ctx context.Context, id string) (*SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSigningKey", ctx, id)
+	ret0, _ := ret[0].(*SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSigningKey indicates an expected call of GetSigningKey.
+func (mr *MockSigningKeyRepositoryMockRecorder) GetSigningKey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSigningKey", reflect.TypeOf((*MockSigningKeyRepository)(nil).GetSigningKey), ctx, id)
+}
+
+// ListSigningKeys mocks base method.
+func (m *MockSigningKeyRepository) ListSigningKeys(ctx context.Context, purpose string) ([]SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSigningKeys", ctx, purpose)
+	ret0, _ := ret[0].([]SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSigningKeys indicates an expected call of ListSigningKeys.
+func (mr *MockSigningKeyRepositoryMockRecorder) ListSigningKeys(ctx, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSigningKeys", reflect.TypeOf((*MockSigningKeyRepository)(nil).ListSigningKeys), ctx, purpose)
+}
+
+// UpdateSigningKeys mocks base method.
+func (m *MockSigningKeyRepository) UpdateSigningKeys(ctx context.Context, purpose string, fn func([]SigningKey) ([]SigningKey, []SigningKey, error)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSigningKeys", ctx, purpose, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSigningKeys indicates an expected call of UpdateSigningKeys.
+func (mr *MockSigningKeyRepositoryMockRecorder) UpdateSigningKeys(ctx, purpose, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSigningKeys", reflect.TypeOf((*MockSigningKeyRepository)(nil).UpdateSigningKeys), ctx, purpose, fn)
+}
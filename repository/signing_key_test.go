@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpSigningKeyMock(t *testing.T) (SigningKeyRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewSigningKeyRepository(gormDB), mockDb, mock
+}
+
+var signingKeyColumns = []string{"id", "created_at", "purpose", "algorithm", "status", "public_key", "encrypted_private_key",
+	"activated_at", "retired_at", "expires_at", "revoked_at"}
+
+func TestGetSigningKeyNotFound(t *testing.T) {
+	repo, mockDB, mock := setUpSigningKeyMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectQuery(`SELECT * FROM "signing_keys" WHERE id = $1 ORDER BY "signing_keys"."id" LIMIT 1`).
+		WithArgs("kid").
+		WillReturnRows(sqlmock.NewRows(signingKeyColumns))
+
+	_, err := repo.GetSigningKey(context.Background(), "kid")
+	require.EqualError(t, err, ErrSigningKeyNotFound.Error())
+}
+
+func TestUpdateSigningKeys(t *testing.T) {
+	repo, mockDB, mock := setUpSigningKeyMock(t)
+	defer mockDB.Close()
+
+	activatedAt := time.Now().Add(-time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock($1)`).
+		WithArgs(signingKeyLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT * FROM "signing_keys" WHERE purpose = $1 ORDER BY created_at`).
+		WithArgs("token").
+		WillReturnRows(sqlmock.NewRows(signingKeyColumns).
+			AddRow("current", activatedAt, "token", "EdDSA", "current", []byte("public"), []byte("sealed"), activatedAt, nil, nil, nil))
+	mock.ExpectExec(`UPDATE "signing_keys" SET "activated_at"=$1,"expires_at"=$2,"retired_at"=$3,"revoked_at"=$4,"status"=$5 WHERE id = $6`).
+		WithArgs(AnyTime{}, AnyTime{}, AnyTime{}, nil, "retired", "current").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "signing_keys" ("id","created_at","purpose","algorithm","status","public_key","encrypted_private_key","activated_at","retired_at","expires_at","revoked_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`).
+		WithArgs("next", AnyTime{}, "token", "EdDSA", "next", []byte("public"), []byte("sealed"), nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.UpdateSigningKeys(context.Background(), "token", func(keys []SigningKey) ([]SigningKey, []SigningKey, error) {
+		require.Len(t, keys, 1)
+		now := time.Now()
+		keys[0].Status = "retired"
+		keys[0].RetiredAt = &now
+		keys[0].ExpiresAt = &now
+		return keys, []SigningKey{{ID: "next", Purpose: "token", Algorithm: "EdDSA", Status: "next",
+			PublicKey: []byte("public"), EncryptedPrivateKey: []byte("sealed")}}, nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateSigningKeysAborted(t *testing.T) {
+	repo, mockDB, mock := setUpSigningKeyMock(t)
+	defer mockDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock($1)`).
+		WithArgs(signingKeyLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT * FROM "signing_keys" WHERE purpose = $1 ORDER BY created_at`).
+		WithArgs("token").
+		WillReturnRows(sqlmock.NewRows(signingKeyColumns))
+	mock.ExpectRollback()
+
+	err := repo.UpdateSigningKeys(context.Background(), "token", func(keys []SigningKey) ([]SigningKey, []SigningKey, error) {
+		return nil, nil, ErrSigningKeyNotFound
+	})
+	require.ErrorIs(t, err, ErrSigningKeyNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var ErrVoucherNotFound = errors.New("Voucher is not found")
+
+// VoucherAccount is a voucher with the code it logs in with.
+type VoucherAccount struct {
+	Voucher
+	Code string
+}
+
+type VoucherRepository interface {
+	// CreateVouchers stores the voucher accounts and their vouchers in one transaction.
+	CreateVouchers(ctx context.Context, accounts []*Account, vouchers []*Voucher) error
+	GetVoucher(ctx context.Context, accountID uuid.UUID) (*Voucher, error)
+	// ActivateVoucher starts the validity of a voucher not used yet. It returns false when the voucher was
+	// already activated.
+	ActivateVoucher(ctx context.Context, accountID uuid.UUID, activatedAt time.Time, expiresAt *time.Time) (bool, error)
+	// ListVouchers lists the vouchers of the batch by code, without the ones whose account was deleted.
+	ListVouchers(ctx context.Context, batch string) ([]VoucherAccount, error)
+}
+
+type voucherRepository struct {
+	db *gorm.DB
+}
+
+func NewVoucherRepository(db *gorm.DB) VoucherRepository {
+	return &voucherRepository{
+		db: db,
+	}
+}
+
+func (r *voucherRepository) CreateVouchers(ctx context.Context, accounts []*Account, vouchers []*Voucher) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(accounts).Error; err != nil {
+			return err
+		}
+		return tx.Create(vouchers).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrAccountIsDuplicated
+	}
+	return err
+}
+
+func (r *voucherRepository) GetVoucher(ctx context.Context, accountID uuid.UUID) (*Voucher, error) {
+	voucher := &Voucher{}
+	if err := r.db.WithContext(ctx).Where("account_id = ?", accountID).First(voucher).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVoucherNotFound
+		}
+		return nil, err
+	}
+	return voucher, nil
+}
+
+func (r *voucherRepository) ActivateVoucher(ctx context.Context, accountID uuid.UUID, activatedAt time.Time, expiresAt *time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&Voucher{}).
+		Where("account_id = ? AND activated_at IS NULL", accountID).
+		Updates(map[string]interface{}{"activated_at": activatedAt, "expires_at": expiresAt})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *voucherRepository) ListVouchers(ctx context.Context, batch string) ([]VoucherAccount, error) {
+	var vouchers []VoucherAccount
+	err := r.db.WithContext(ctx).Model(&Voucher{}).
+		Select("vouchers.*, accounts.username AS code").
+		Joins("JOIN accounts ON accounts.id = vouchers.account_id AND accounts.deleted_at IS NULL").
+		Where("vouchers.batch = ?", batch).
+		Order("accounts.username").
+		Scan(&vouchers).Error
+	if err != nil {
+		return nil, err
+	}
+	return vouchers, nil
+}
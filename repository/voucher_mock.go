@@ -0,0 +1,96 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: voucher.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockVoucherRepository is a mock of VoucherRepository interface.
+type MockVoucherRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockVoucherRepositoryMockRecorder
+}
+
+// MockVoucherRepositoryMockRecorder is the mock recorder for MockVoucherRepository.
+type MockVoucherRepositoryMockRecorder struct {
+	mock *MockVoucherRepository
+}
+
+// NewMockVoucherRepository creates a new mock instance.
+func NewMockVoucherRepository(ctrl *gomock.Controller) *MockVoucherRepository {
+	mock := &MockVoucherRepository{ctrl: ctrl}
+	mock.recorder = &MockVoucherRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVoucherRepository) EXPECT() *MockVoucherRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ActivateVoucher mocks base method.
+func (m *MockVoucherRepository) ActivateVoucher(ctx context.Context, accountID uuid.UUID, activatedAt time.Time, expiresAt *time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActivateVoucher", ctx, accountID, activatedAt, expiresAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ActivateVoucher indicates an expected call of ActivateVoucher.
+func (mr *MockVoucherRepositoryMockRecorder) ActivateVoucher(ctx, accountID, activatedAt, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActivateVoucher", reflect.TypeOf((*MockVoucherRepository)(nil).ActivateVoucher), ctx, accountID, activatedAt, expiresAt)
+}
+
+// CreateVouchers mocks base method.
+func (m *MockVoucherRepository) CreateVouchers(ctx context.Context, accounts []*Account, vouchers []*Voucher) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVouchers", ctx, accounts, vouchers)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateVouchers indicates an expected call of CreateVouchers.
+func (mr *MockVoucherRepositoryMockRecorder) CreateVouchers(ctx, accounts, vouchers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVouchers", reflect.TypeOf((*MockVoucherRepository)(nil).CreateVouchers), ctx, accounts, vouchers)
+}
+
+// GetVoucher mocks base method.
+func (m *MockVoucherRepository) GetVoucher(ctx context.Context, accountID uuid.UUID) (*Voucher, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVoucher", ctx, accountID)
+	ret0, _ := ret[0].(*Voucher)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVoucher indicates an expected call of GetVoucher.
+func (mr *MockVoucherRepositoryMockRecorder) GetVoucher(ctx, accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVoucher", reflect.TypeOf((*MockVoucherRepository)(nil).GetVoucher), ctx, accountID)
+}
+
+// ListVouchers mocks base method.
+func (m *MockVoucherRepository) ListVouchers(ctx context.Context, batch string) ([]VoucherAccount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVouchers", ctx, batch)
+	ret0, _ := ret[0].([]VoucherAccount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVouchers indicates an expected call of ListVouchers.
+func (mr *MockVoucherRepositoryMockRecorder) ListVouchers(ctx, batch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVouchers", reflect.TypeOf((*MockVoucherRepository)(nil).ListVouchers), ctx, batch)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpVoucherMock(t *testing.T) (VoucherRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewVoucherRepository(gormDB), mockDb, mock
+}
+
+func TestActivateVoucher(t *testing.T) {
+	testCase := []struct {
+		name      string
+		rows      int64
+		activated bool
+	}{
+		{name: "first use", rows: 1, activated: true},
+		{name: "already activated", rows: 0, activated: false},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockDB, mock := setUpVoucherMock(t)
+			defer mockDB.Close()
+
+			accountID := uuid.New()
+			expiresAt := time.Now().Add(time.Hour)
+			mock.ExpectBegin()
+			mock.ExpectExec(`UPDATE "vouchers" SET "activated_at"=$1,"expires_at"=$2 WHERE account_id = $3 AND activated_at IS NULL`).
+				WithArgs(AnyTime{}, AnyTime{}, accountID).
+				WillReturnResult(sqlmock.NewResult(0, tc.rows))
+			mock.ExpectCommit()
+
+			activated, err := repo.ActivateVoucher(context.Background(), accountID, time.Now(), &expiresAt)
+			require.NoError(t, err)
+			require.Equal(t, tc.activated, activated)
+		})
+	}
+}
+
+func TestGetVoucherNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpVoucherMock(t)
+	defer mockDB.Close()
+
+	accountID := uuid.New()
+	mock.ExpectQuery(`SELECT * FROM "vouchers" WHERE account_id = $1 ORDER BY "vouchers"."account_id" LIMIT 1`).
+		WithArgs(accountID).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	voucher, err := repo.GetVoucher(context.Background(), accountID)
+	require.Nil(t, voucher)
+	require.Equal(t, ErrVoucherNotFound, err)
+}
+
+func TestListVouchers(t *testing.T) {
+	repo, mockDB, mock := setUpVoucherMock(t)
+	defer mockDB.Close()
+
+	accountID := uuid.New()
+	mock.ExpectQuery(`SELECT vouchers.*, accounts.username AS code FROM "vouchers" JOIN accounts ON accounts.id = vouchers.account_id AND accounts.deleted_at IS NULL WHERE vouchers.batch = $1 ORDER BY accounts.username`).
+		WithArgs("lobby").
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "batch", "duration", "data_limit", "code"}).
+			AddRow(accountID, "lobby", int64(3600), int64(0), "K7M4Q9XP2H"))
+
+	vouchers, err := repo.ListVouchers(context.Background(), "lobby")
+	require.NoError(t, err)
+	require.Len(t, vouchers, 1)
+	require.Equal(t, "K7M4Q9XP2H", vouchers[0].Code)
+	require.Equal(t, accountID, vouchers[0].AccountID)
+	require.EqualValues(t, 3600, vouchers[0].Duration)
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookEndpointNotFound   = errors.New("Webhook endpoint is not found")
+	ErrWebhookDeadLetterNotFound = errors.New("Webhook dead letter is not found")
+)
+
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error
+	GetEndpoint(ctx context.Context, id uuid.UUID) (*WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	CreateDeadLetter(ctx context.Context, deadLetter *WebhookDeadLetter) error
+	GetDeadLetter(ctx context.Context, id uint64) (*WebhookDeadLetter, error)
+	ListDeadLetters(ctx context.Context, includeReplayed bool) ([]WebhookDeadLetter, error)
+	MarkDeadLetterReplayed(ctx context.Context, id uint64, replayedAt time.Time) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+func (r *webhookRepository) CreateEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error {
+	return r.db.WithContext(ctx).Create(endpoint).Error
+}
+
+func (r *webhookRepository) GetEndpoint(ctx context.Context, id uuid.UUID) (*WebhookEndpoint, error) {
+	endpoint := &WebhookEndpoint{}
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(endpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookEndpointNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (r *webhookRepository) ListEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	var endpoints []WebhookEndpoint
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (r *webhookRepository) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&WebhookEndpoint{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepository) CreateDeadLetter(ctx context.Context, deadLetter *WebhookDeadLetter) error {
+	return r.db.WithContext(ctx).Create(deadLetter).Error
+}
+
+func (r *webhookRepository) GetDeadLetter(ctx context.Context, id uint64) (*WebhookDeadLetter, error) {
+	deadLetter := &WebhookDeadLetter{}
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(deadLetter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeadLetterNotFound
+		}
+		return nil, err
+	}
+	return deadLetter, nil
+}
+
+func (r *webhookRepository) ListDeadLetters(ctx context.Context, includeReplayed bool) ([]WebhookDeadLetter, error) {
+	var deadLetters []WebhookDeadLetter
+	query := r.db.WithContext(ctx)
+	if !includeReplayed {
+		query = query.Where("replayed_at IS NULL")
+	}
+	if err := query.Order("id").Find(&deadLetters).Error; err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+func (r *webhookRepository) MarkDeadLetterReplayed(ctx context.Context, id uint64, replayedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&WebhookDeadLetter{}).Where("id = ?", id).Update("replayed_at", replayedAt).Error
+}
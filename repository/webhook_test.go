@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setUpWebhookMock(t *testing.T) (WebhookRepository, *sql.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return NewWebhookRepository(gormDB), mockDb, mock
+}
+
+func TestDeleteWebhookEndpointNotExisted(t *testing.T) {
+	repo, mockDB, mock := setUpWebhookMock(t)
+	defer mockDB.Close()
+
+	id := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhook_endpoints" SET "deleted_at"=$1 WHERE id = $2 AND "webhook_endpoints"."deleted_at" IS NULL`).
+		WithArgs(AnyTime{}, id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.DeleteEndpoint(context.Background(), id)
+	require.EqualError(t, err, ErrWebhookEndpointNotFound.Error())
+}
+
+func TestListWebhookDeadLetters(t *testing.T) {
+	repo, mockDB, mock := setUpWebhookMock(t)
+	defer mockDB.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "created_at", "endpoint_id", "event_id", "event_type", "payload", "attempts", "last_error", "replayed_at"}).
+		AddRow(1, time.Now(), uuid.New(), uuid.New().String(), "account.created", "{}", 5, "unexpected response status 500", nil)
+	mock.ExpectQuery(`SELECT * FROM "webhook_dead_letters" WHERE replayed_at IS NULL ORDER BY id`).
+		WillReturnRows(rows)
+
+	deadLetters, err := repo.ListDeadLetters(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	require.Nil(t, deadLetters[0].ReplayedAt)
+}
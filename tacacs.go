@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/tacacs"
+)
+
+const defaultTACACSAddress = ":49"
+
+// loadTACACSConfig reads the devices named in TACACS_CLIENTS, or returns nil when none are. Each name
+// has its address or network in TACACS_CLIENT_<NAME>_ADDRESS and its key in _KEY. The roles listed in
+// TACACS_ROLES, in order, get the privilege level in TACACS_ROLE_<ROLE>_PRIV_LVL and the commands of
+// _ALLOW but not of _DENY; TACACS_REQUIRE_ROLE refuses accounts with none of them.
+func loadTACACSConfig() (*tacacs.Config, error) {
+	config := &tacacs.Config{}
+	for _, name := range strings.Split(os.Getenv("TACACS_CLIENTS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "TACACS_CLIENT_" + envName(name) + "_"
+		network, err := parseNetwork(os.Getenv(prefix + "ADDRESS"))
+		if err != nil {
+			return nil, fmt.Errorf("%sADDRESS: %w", prefix, err)
+		}
+		key := os.Getenv(prefix + "KEY")
+		if key == "" {
+			return nil, fmt.Errorf("%sKEY is required", prefix)
+		}
+		config.Clients = append(config.Clients, tacacs.Client{Name: name, Network: network, Key: []byte(key)})
+	}
+	if len(config.Clients) == 0 {
+		return nil, nil
+	}
+
+	for _, role := range strings.Split(os.Getenv("TACACS_ROLES"), ",") {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+		prefix := "TACACS_ROLE_" + envName(role) + "_"
+		privLvl, err := strconv.Atoi(envOrDefault(prefix+"PRIV_LVL", "0"))
+		if err != nil || privLvl < 0 || privLvl > 15 {
+			return nil, fmt.Errorf("%sPRIV_LVL must be from 0 to 15", prefix)
+		}
+		allow, err := tacacs.ParseRules(os.Getenv(prefix + "ALLOW"))
+		if err != nil {
+			return nil, fmt.Errorf("%sALLOW: %w", prefix, err)
+		}
+		deny, err := tacacs.ParseRules(os.Getenv(prefix + "DENY"))
+		if err != nil {
+			return nil, fmt.Errorf("%sDENY: %w", prefix, err)
+		}
+		config.Roles = append(config.Roles, tacacs.RoleRules{Role: role, PrivLvl: privLvl, Allow: allow, Deny: deny})
+	}
+	config.RequireRole, _ = strconv.ParseBool(os.Getenv("TACACS_REQUIRE_ROLE"))
+	return config, nil
+}
+
+func tacacsAddress() string {
+	return envOrDefault("TACACS_ADDRESS", defaultTACACSAddress)
+}
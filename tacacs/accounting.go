@@ -0,0 +1,31 @@
+package tacacs
+
+import (
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+)
+
+// account records an accounting request in the audit log, with what the task was and its arguments,
+// for example "stop task_id=12 service=shell cmd=show running-config <cr>".
+func (s *Server) account(c *connection, request *AcctRequest) *AcctReply {
+	var event string
+	switch {
+	case request.Flags&AcctFlagStart != 0:
+		event = "start"
+	case request.Flags&AcctFlagStop != 0:
+		event = "stop"
+	case request.Flags&AcctFlagWatchdog != 0:
+		event = "watchdog"
+	default:
+		return &AcctReply{Status: AcctStatusError, ServerMsg: "Accounting flags are not valid"}
+	}
+	s.audit.Record(requestContext(c, request.Port, request.RemAddr), model.AuditEntry{
+		Type:    model.AuditDeviceAccounting,
+		Actor:   request.User,
+		Target:  "device:" + c.client.Name,
+		Outcome: model.AuditOutcomeSuccess,
+		Detail:  strings.TrimSpace(event + " " + strings.Join(request.Args, " ")),
+	})
+	return &AcctReply{Status: AcctStatusSuccess}
+}
@@ -0,0 +1,170 @@
+package tacacs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// RoleRules is what accounts with Role may do on a device. A command is allowed when an Allow rule of
+// one of the account's roles matches it and no Deny rule of any of them does.
+type RoleRules struct {
+	Role string
+	// PrivLvl is the privilege level the shell starts at, none when it is 0.
+	PrivLvl int
+	Allow   []*regexp.Regexp
+	Deny    []*regexp.Regexp
+}
+
+// ParseRules reads the rules of a role: regular expressions separated by ";", each matched against the
+// whole command line, for example "show .*;ping .*".
+func ParseRules(value string) ([]*regexp.Regexp, error) {
+	var rules []*regexp.Regexp
+	for _, rule := range strings.Split(value, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		compiled, err := regexp.Compile("^(?:" + rule + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule, err)
+		}
+		rules = append(rules, compiled)
+	}
+	return rules, nil
+}
+
+// authorize answers whether the user may start a shell, or run the command in it. A shell start gets
+// the privilege level of the user's first role.
+func (s *Server) authorize(c *connection, request *AuthorRequest) *AuthorResponse {
+	ctx := requestContext(c, request.Port, request.RemAddr)
+	account, err := s.accounts.GetAccount(ctx, request.User)
+	if err != nil {
+		if err == repository.ErrAccountRecordNotFound {
+			return &AuthorResponse{Status: AuthorStatusFail, ServerMsg: model.ErrLoginAccountNotFound.Error()}
+		}
+		log.Error().Err(err).Str("client", c.client.Name).Str("username", request.User).Msg("failed to check TACACS+ authorization")
+		return &AuthorResponse{Status: AuthorStatusError, ServerMsg: "Authorization could not be checked"}
+	}
+	if account.Type == repository.AccountTypeService {
+		return s.refuse(ctx, c, request.User, "", model.ErrLoginServiceAccount)
+	}
+	if account.Type == repository.AccountTypeVoucher {
+		return s.refuse(ctx, c, request.User, "", ErrVoucherNotAllowed)
+	}
+	// Devices ask again for each command, so locking an account also stops a shell it already opened.
+	if account.LockedAt != nil {
+		return s.refuse(ctx, c, request.User, "", model.ErrLoginAccountLocked)
+	}
+	roles := strings.Fields(account.Roles)
+
+	service, command := commandLine(request.Args)
+	if service != "shell" {
+		return s.refuse(ctx, c, request.User, command, ErrServiceNotSupported)
+	}
+	if command == "" {
+		rules, ok := s.role(roles)
+		if !ok && s.config.RequireRole {
+			return s.refuse(ctx, c, request.User, command, ErrNoRoleAllowed)
+		}
+		response := &AuthorResponse{Status: AuthorStatusPassAdd}
+		if ok && rules.PrivLvl > 0 {
+			response.Args = append(response.Args, "priv-lvl="+strconv.Itoa(rules.PrivLvl))
+		}
+		return response
+	}
+	if !s.allowed(roles, command) {
+		return s.refuse(ctx, c, request.User, command, ErrCommandDenied)
+	}
+	return &AuthorResponse{Status: AuthorStatusPassAdd}
+}
+
+// allowed applies the rules of all the roles to the command, a deny rule of any role wins.
+func (s *Server) allowed(roles []string, command string) bool {
+	allowed := false
+	for _, rules := range s.config.Roles {
+		if !hasRole(roles, rules.Role) {
+			continue
+		}
+		for _, rule := range rules.Deny {
+			if rule.MatchString(command) {
+				return false
+			}
+		}
+		for _, rule := range rules.Allow {
+			allowed = allowed || rule.MatchString(command)
+		}
+	}
+	return allowed
+}
+
+// role picks the rules of the first configured role the account has.
+func (s *Server) role(roles []string) (*RoleRules, bool) {
+	for i := range s.config.Roles {
+		if hasRole(roles, s.config.Roles[i].Role) {
+			return &s.config.Roles[i], true
+		}
+	}
+	return nil, false
+}
+
+// refuse fails an authorization and records it in the audit log.
+func (s *Server) refuse(ctx context.Context, c *connection, username string, command string, err error) *AuthorResponse {
+	detail := err.Error()
+	if command != "" {
+		detail += ": " + command
+	}
+	s.audit.Record(ctx, model.AuditEntry{
+		Type:    model.AuditDeviceAuthorization,
+		Actor:   username,
+		Target:  "device:" + c.client.Name,
+		Outcome: model.AuditOutcomeFailure,
+		Detail:  detail,
+	})
+	return &AuthorResponse{Status: AuthorStatusFail, ServerMsg: err.Error()}
+}
+
+// commandLine reads the service and the command line from the arguments, which are "name=value" when
+// mandatory and "name*value" when optional. The "<cr>" ending a command is left out.
+func commandLine(args []string) (string, string) {
+	var service string
+	var command []string
+	for _, arg := range args {
+		name, value := splitArg(arg)
+		switch name {
+		case "service":
+			service = value
+		case "cmd":
+			if value != "" {
+				command = append([]string{value}, command...)
+			}
+		case "cmd-arg":
+			if value != "<cr>" {
+				command = append(command, value)
+			}
+		}
+	}
+	return service, strings.Join(command, " ")
+}
+
+func splitArg(arg string) (string, string) {
+	if i := strings.IndexAny(arg, "=*"); i >= 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,448 @@
+package tacacs
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Header fields (RFC 8907 section 4.1).
+const (
+	MajorVersion   = 0xc
+	VersionDefault = MajorVersion<<4 | 0
+	VersionOne     = MajorVersion<<4 | 1
+
+	TypeAuthentication = 1
+	TypeAuthorization  = 2
+	TypeAccounting     = 3
+
+	FlagUnencrypted   = 0x01
+	FlagSingleConnect = 0x04
+
+	headerLength  = 12
+	maxBodyLength = 1 << 16
+)
+
+// Authentication fields (RFC 8907 section 5).
+const (
+	AuthenActionLogin = 1
+
+	AuthenTypeASCII    = 1
+	AuthenTypePAP      = 2
+	AuthenTypeCHAP     = 3
+	AuthenTypeMSCHAP   = 5
+	AuthenTypeMSCHAPv2 = 6
+
+	AuthenStatusPass    = 1
+	AuthenStatusFail    = 2
+	AuthenStatusGetData = 3
+	AuthenStatusGetUser = 4
+	AuthenStatusGetPass = 5
+	AuthenStatusError   = 7
+
+	ReplyFlagNoEcho      = 0x01
+	ContinueFlagAbort    = 0x01
+	authenStartLength    = 8
+	authenReplyLength    = 6
+	authenContinueLength = 5
+)
+
+// Authorization and accounting fields (RFC 8907 sections 6 and 7).
+const (
+	AuthorStatusPassAdd = 0x01
+	AuthorStatusFail    = 0x10
+	AuthorStatusError   = 0x11
+
+	AcctFlagStart    = 0x02
+	AcctFlagStop     = 0x04
+	AcctFlagWatchdog = 0x08
+
+	AcctStatusSuccess = 0x01
+	AcctStatusError   = 0x02
+
+	authorResponseLength = 6
+	acctReplyLength      = 5
+)
+
+var (
+	ErrInvalidPacket = errors.New("Invalid TACACS+ packet")
+	ErrUnencrypted   = errors.New("Unobfuscated TACACS+ packets are not accepted")
+)
+
+type Header struct {
+	Version   byte
+	Type      byte
+	SeqNo     byte
+	Flags     byte
+	SessionID uint32
+}
+
+// Packet is a TACACS+ packet with its body in clear.
+type Packet struct {
+	Header
+	Body []byte
+}
+
+// ReadPacket reads a packet and removes the obfuscation of its body with the key. Packets that are not
+// obfuscated are refused.
+func ReadPacket(r io.Reader, key []byte) (*Packet, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	packet := &Packet{Header: Header{
+		Version:   header[0],
+		Type:      header[1],
+		SeqNo:     header[2],
+		Flags:     header[3],
+		SessionID: binary.BigEndian.Uint32(header[4:8]),
+	}}
+	length := binary.BigEndian.Uint32(header[8:12])
+	if packet.Version>>4 != MajorVersion || length > maxBodyLength {
+		return nil, ErrInvalidPacket
+	}
+	packet.Body = make([]byte, length)
+	if _, err := io.ReadFull(r, packet.Body); err != nil {
+		return nil, err
+	}
+	if packet.Flags&FlagUnencrypted != 0 {
+		return nil, ErrUnencrypted
+	}
+	obfuscate(packet.Header, key, packet.Body)
+	return packet, nil
+}
+
+// WritePacket obfuscates the body with the key and writes the packet.
+func WritePacket(w io.Writer, packet *Packet, key []byte) error {
+	if len(packet.Body) > maxBodyLength {
+		return ErrInvalidPacket
+	}
+	data := make([]byte, headerLength+len(packet.Body))
+	data[0] = packet.Version
+	data[1] = packet.Type
+	data[2] = packet.SeqNo
+	data[3] = packet.Flags &^ FlagUnencrypted
+	binary.BigEndian.PutUint32(data[4:8], packet.SessionID)
+	binary.BigEndian.PutUint32(data[8:12], uint32(len(packet.Body)))
+	copy(data[headerLength:], packet.Body)
+	obfuscate(packet.Header, key, data[headerLength:])
+	_, err := w.Write(data)
+	return err
+}
+
+// obfuscate XORs the body in place with the MD5 pad of RFC 8907 section 4.5, which both hides and
+// reveals it.
+func obfuscate(header Header, key []byte, body []byte) {
+	prefix := binary.BigEndian.AppendUint32(nil, header.SessionID)
+	prefix = append(prefix, key...)
+	prefix = append(prefix, header.Version, header.SeqNo)
+	var pad []byte
+	for i := range body {
+		if i%md5.Size == 0 {
+			sum := md5.Sum(append(prefix, pad...))
+			pad = sum[:]
+		}
+		body[i] ^= pad[i%md5.Size]
+	}
+}
+
+// AuthenStart is the first packet of an authentication session.
+type AuthenStart struct {
+	Action        byte
+	PrivLvl       byte
+	AuthenType    byte
+	AuthenService byte
+	User          string
+	Port          string
+	RemAddr       string
+	Data          []byte
+}
+
+func ParseAuthenStart(body []byte) (*AuthenStart, error) {
+	r := &bodyReader{data: body}
+	start := &AuthenStart{Action: r.byte(), PrivLvl: r.byte(), AuthenType: r.byte(), AuthenService: r.byte()}
+	userLen, portLen, remAddrLen, dataLen := r.byte(), r.byte(), r.byte(), r.byte()
+	start.User = string(r.bytes(int(userLen)))
+	start.Port = string(r.bytes(int(portLen)))
+	start.RemAddr = string(r.bytes(int(remAddrLen)))
+	start.Data = r.bytes(int(dataLen))
+	return start, r.done()
+}
+
+func (s *AuthenStart) Encode() ([]byte, error) {
+	if !fitsByte(s.User, s.Port, s.RemAddr, string(s.Data)) {
+		return nil, ErrInvalidPacket
+	}
+	body := make([]byte, 0, authenStartLength+len(s.User)+len(s.Port)+len(s.RemAddr)+len(s.Data))
+	body = append(body, s.Action, s.PrivLvl, s.AuthenType, s.AuthenService,
+		byte(len(s.User)), byte(len(s.Port)), byte(len(s.RemAddr)), byte(len(s.Data)))
+	body = append(body, s.User...)
+	body = append(body, s.Port...)
+	body = append(body, s.RemAddr...)
+	return append(body, s.Data...), nil
+}
+
+// AuthenReply answers an AuthenStart or AuthenContinue.
+type AuthenReply struct {
+	Status    byte
+	Flags     byte
+	ServerMsg string
+	Data      []byte
+}
+
+func ParseAuthenReply(body []byte) (*AuthenReply, error) {
+	r := &bodyReader{data: body}
+	reply := &AuthenReply{Status: r.byte(), Flags: r.byte()}
+	serverMsgLen, dataLen := r.uint16(), r.uint16()
+	reply.ServerMsg = string(r.bytes(serverMsgLen))
+	reply.Data = r.bytes(dataLen)
+	return reply, r.done()
+}
+
+func (r *AuthenReply) Encode() ([]byte, error) {
+	if !fitsUint16(r.ServerMsg, string(r.Data)) {
+		return nil, ErrInvalidPacket
+	}
+	body := make([]byte, authenReplyLength, authenReplyLength+len(r.ServerMsg)+len(r.Data))
+	body[0] = r.Status
+	body[1] = r.Flags
+	binary.BigEndian.PutUint16(body[2:4], uint16(len(r.ServerMsg)))
+	binary.BigEndian.PutUint16(body[4:6], uint16(len(r.Data)))
+	body = append(body, r.ServerMsg...)
+	return append(body, r.Data...), nil
+}
+
+// AuthenContinue carries what the user answered to an AuthenReply.
+type AuthenContinue struct {
+	UserMsg string
+	Data    []byte
+	Flags   byte
+}
+
+func ParseAuthenContinue(body []byte) (*AuthenContinue, error) {
+	r := &bodyReader{data: body}
+	userMsgLen, dataLen := r.uint16(), r.uint16()
+	cont := &AuthenContinue{Flags: r.byte()}
+	cont.UserMsg = string(r.bytes(userMsgLen))
+	cont.Data = r.bytes(dataLen)
+	return cont, r.done()
+}
+
+func (c *AuthenContinue) Encode() ([]byte, error) {
+	if !fitsUint16(c.UserMsg, string(c.Data)) {
+		return nil, ErrInvalidPacket
+	}
+	body := make([]byte, authenContinueLength, authenContinueLength+len(c.UserMsg)+len(c.Data))
+	binary.BigEndian.PutUint16(body[0:2], uint16(len(c.UserMsg)))
+	binary.BigEndian.PutUint16(body[2:4], uint16(len(c.Data)))
+	body[4] = c.Flags
+	body = append(body, c.UserMsg...)
+	return append(body, c.Data...), nil
+}
+
+// AuthorRequest asks whether the user may run a service or command, described by its arguments.
+type AuthorRequest struct {
+	AuthenMethod  byte
+	PrivLvl       byte
+	AuthenType    byte
+	AuthenService byte
+	User          string
+	Port          string
+	RemAddr       string
+	Args          []string
+}
+
+func ParseAuthorRequest(body []byte) (*AuthorRequest, error) {
+	r := &bodyReader{data: body}
+	request := &AuthorRequest{AuthenMethod: r.byte(), PrivLvl: r.byte(), AuthenType: r.byte(), AuthenService: r.byte()}
+	request.User, request.Port, request.RemAddr, request.Args = r.userArgs()
+	return request, r.done()
+}
+
+func (a *AuthorRequest) Encode() ([]byte, error) {
+	return encodeUserArgs([]byte{a.AuthenMethod, a.PrivLvl, a.AuthenType, a.AuthenService}, a.User, a.Port, a.RemAddr, a.Args)
+}
+
+// AuthorResponse answers an AuthorRequest, with the arguments to add on a pass.
+type AuthorResponse struct {
+	Status    byte
+	Args      []string
+	ServerMsg string
+	Data      []byte
+}
+
+func ParseAuthorResponse(body []byte) (*AuthorResponse, error) {
+	r := &bodyReader{data: body}
+	response := &AuthorResponse{Status: r.byte()}
+	argCnt := r.byte()
+	serverMsgLen, dataLen := r.uint16(), r.uint16()
+	argLens := r.bytes(int(argCnt))
+	response.ServerMsg = string(r.bytes(serverMsgLen))
+	response.Data = r.bytes(dataLen)
+	for _, argLen := range argLens {
+		response.Args = append(response.Args, string(r.bytes(int(argLen))))
+	}
+	return response, r.done()
+}
+
+func (a *AuthorResponse) Encode() ([]byte, error) {
+	if len(a.Args) > 255 || !fitsByte(a.Args...) || !fitsUint16(a.ServerMsg, string(a.Data)) {
+		return nil, ErrInvalidPacket
+	}
+	body := make([]byte, authorResponseLength)
+	body[0] = a.Status
+	body[1] = byte(len(a.Args))
+	binary.BigEndian.PutUint16(body[2:4], uint16(len(a.ServerMsg)))
+	binary.BigEndian.PutUint16(body[4:6], uint16(len(a.Data)))
+	for _, arg := range a.Args {
+		body = append(body, byte(len(arg)))
+	}
+	body = append(body, a.ServerMsg...)
+	body = append(body, a.Data...)
+	for _, arg := range a.Args {
+		body = append(body, arg...)
+	}
+	return body, nil
+}
+
+// AcctRequest reports the start, progress or stop of a task, described by its arguments.
+type AcctRequest struct {
+	Flags         byte
+	AuthenMethod  byte
+	PrivLvl       byte
+	AuthenType    byte
+	AuthenService byte
+	User          string
+	Port          string
+	RemAddr       string
+	Args          []string
+}
+
+func ParseAcctRequest(body []byte) (*AcctRequest, error) {
+	r := &bodyReader{data: body}
+	request := &AcctRequest{Flags: r.byte(), AuthenMethod: r.byte(), PrivLvl: r.byte(), AuthenType: r.byte(), AuthenService: r.byte()}
+	request.User, request.Port, request.RemAddr, request.Args = r.userArgs()
+	return request, r.done()
+}
+
+func (a *AcctRequest) Encode() ([]byte, error) {
+	return encodeUserArgs([]byte{a.Flags, a.AuthenMethod, a.PrivLvl, a.AuthenType, a.AuthenService}, a.User, a.Port, a.RemAddr, a.Args)
+}
+
+// AcctReply answers an AcctRequest.
+type AcctReply struct {
+	ServerMsg string
+	Data      []byte
+	Status    byte
+}
+
+func ParseAcctReply(body []byte) (*AcctReply, error) {
+	r := &bodyReader{data: body}
+	serverMsgLen, dataLen := r.uint16(), r.uint16()
+	reply := &AcctReply{Status: r.byte()}
+	reply.ServerMsg = string(r.bytes(serverMsgLen))
+	reply.Data = r.bytes(dataLen)
+	return reply, r.done()
+}
+
+func (a *AcctReply) Encode() ([]byte, error) {
+	if !fitsUint16(a.ServerMsg, string(a.Data)) {
+		return nil, ErrInvalidPacket
+	}
+	body := make([]byte, acctReplyLength, acctReplyLength+len(a.ServerMsg)+len(a.Data))
+	binary.BigEndian.PutUint16(body[0:2], uint16(len(a.ServerMsg)))
+	binary.BigEndian.PutUint16(body[2:4], uint16(len(a.Data)))
+	body[4] = a.Status
+	body = append(body, a.ServerMsg...)
+	return append(body, a.Data...), nil
+}
+
+// encodeUserArgs encodes the fields authorization and accounting requests share after their first
+// bytes.
+func encodeUserArgs(fields []byte, user string, port string, remAddr string, args []string) ([]byte, error) {
+	if len(args) > 255 || !fitsByte(user, port, remAddr) || !fitsByte(args...) {
+		return nil, ErrInvalidPacket
+	}
+	body := append(fields, byte(len(user)), byte(len(port)), byte(len(remAddr)), byte(len(args)))
+	for _, arg := range args {
+		body = append(body, byte(len(arg)))
+	}
+	body = append(body, user...)
+	body = append(body, port...)
+	body = append(body, remAddr...)
+	for _, arg := range args {
+		body = append(body, arg...)
+	}
+	return body, nil
+}
+
+func fitsByte(values ...string) bool {
+	for _, value := range values {
+		if len(value) > 255 {
+			return false
+		}
+	}
+	return true
+}
+
+func fitsUint16(values ...string) bool {
+	for _, value := range values {
+		if len(value) > 65535 {
+			return false
+		}
+	}
+	return true
+}
+
+// bodyReader reads the fields of a body, remembering when it ran past the end.
+type bodyReader struct {
+	data  []byte
+	short bool
+}
+
+func (r *bodyReader) bytes(n int) []byte {
+	if n > len(r.data) {
+		r.short = true
+		r.data = nil
+		return nil
+	}
+	value := r.data[:n]
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *bodyReader) byte() byte {
+	if value := r.bytes(1); value != nil {
+		return value[0]
+	}
+	return 0
+}
+
+func (r *bodyReader) uint16() int {
+	if value := r.bytes(2); value != nil {
+		return int(binary.BigEndian.Uint16(value))
+	}
+	return 0
+}
+
+func (r *bodyReader) userArgs() (string, string, string, []string) {
+	userLen, portLen, remAddrLen, argCnt := r.byte(), r.byte(), r.byte(), r.byte()
+	argLens := r.bytes(int(argCnt))
+	user := string(r.bytes(int(userLen)))
+	port := string(r.bytes(int(portLen)))
+	remAddr := string(r.bytes(int(remAddrLen)))
+	var args []string
+	for _, argLen := range argLens {
+		args = append(args, string(r.bytes(int(argLen))))
+	}
+	return user, port, remAddr, args
+}
+
+// done checks the fields used the whole body, as their lengths must add up to it.
+func (r *bodyReader) done() error {
+	if r.short || len(r.data) != 0 {
+		return ErrInvalidPacket
+	}
+	return nil
+}
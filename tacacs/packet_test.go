@@ -0,0 +1,87 @@
+package tacacs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketObfuscation(t *testing.T) {
+	key := []byte("tackey")
+	start := &AuthenStart{Action: AuthenActionLogin, PrivLvl: 1, AuthenType: AuthenTypePAP, User: "alice",
+		Port: "tty0", RemAddr: "10.0.0.9", Data: bytes.Repeat([]byte("p"), 40)}
+	body, err := start.Encode()
+	require.NoError(t, err)
+
+	var buffer bytes.Buffer
+	packet := &Packet{Header: Header{Version: VersionOne, Type: TypeAuthentication, SeqNo: 1, SessionID: 0x01020304}, Body: body}
+	require.NoError(t, WritePacket(&buffer, packet, key))
+	require.NotContains(t, buffer.String(), "alice")
+
+	read, err := ReadPacket(bytes.NewReader(buffer.Bytes()), key)
+	require.NoError(t, err)
+	require.Equal(t, packet.Header, read.Header)
+	parsed, err := ParseAuthenStart(read.Body)
+	require.NoError(t, err)
+	require.Equal(t, start, parsed)
+
+	read, err = ReadPacket(bytes.NewReader(buffer.Bytes()), []byte("wrong"))
+	require.NoError(t, err)
+	_, err = ParseAuthenStart(read.Body)
+	require.Error(t, err)
+}
+
+func TestReadPacketRefused(t *testing.T) {
+	testCase := []struct {
+		name   string
+		header []byte
+		err    error
+	}{
+		{name: "unencrypted", header: []byte{VersionDefault, TypeAuthentication, 1, FlagUnencrypted, 0, 0, 0, 1, 0, 0, 0, 0}, err: ErrUnencrypted},
+		{name: "wrong version", header: []byte{0x10, TypeAuthentication, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0}, err: ErrInvalidPacket},
+		{name: "too long", header: []byte{VersionDefault, TypeAuthentication, 1, 0, 0, 0, 0, 1, 0, 2, 0, 0}, err: ErrInvalidPacket},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadPacket(bytes.NewReader(tc.header), []byte("tackey"))
+			require.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestBodiesRoundTrip(t *testing.T) {
+	author := &AuthorRequest{AuthenMethod: 6, PrivLvl: 15, AuthenType: AuthenTypeASCII, AuthenService: 1, User: "alice",
+		Port: "tty0", RemAddr: "10.0.0.9", Args: []string{"service=shell", "cmd=show", "cmd-arg=version"}}
+	body, err := author.Encode()
+	require.NoError(t, err)
+	parsedAuthor, err := ParseAuthorRequest(body)
+	require.NoError(t, err)
+	require.Equal(t, author, parsedAuthor)
+	_, err = ParseAuthorRequest(body[:len(body)-1])
+	require.Equal(t, ErrInvalidPacket, err)
+
+	response := &AuthorResponse{Status: AuthorStatusPassAdd, Args: []string{"priv-lvl=15"}, ServerMsg: "ok"}
+	body, err = response.Encode()
+	require.NoError(t, err)
+	parsedResponse, err := ParseAuthorResponse(body)
+	require.NoError(t, err)
+	require.Equal(t, response.Args, parsedResponse.Args)
+	require.Equal(t, response.ServerMsg, parsedResponse.ServerMsg)
+
+	acct := &AcctRequest{Flags: AcctFlagStop, User: "alice", Args: []string{"task_id=1", "cmd=show version <cr>"}}
+	body, err = acct.Encode()
+	require.NoError(t, err)
+	parsedAcct, err := ParseAcctRequest(body)
+	require.NoError(t, err)
+	require.Equal(t, acct, parsedAcct)
+
+	cont := &AuthenContinue{UserMsg: "secret"}
+	body, err = cont.Encode()
+	require.NoError(t, err)
+	parsedCont, err := ParseAuthenContinue(body)
+	require.NoError(t, err)
+	require.Equal(t, cont.UserMsg, parsedCont.UserMsg)
+}
@@ -0,0 +1,348 @@
+package tacacs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// idleTimeout closes connections the device stopped talking on, and gives the user that long to
+	// answer a prompt.
+	idleTimeout = 2 * time.Minute
+	// maxPendingLogins bounds the interactive logins a single connection can have open at once.
+	maxPendingLogins = 16
+)
+
+var (
+	ErrUnknownClient       = errors.New("Connection from an unknown TACACS+ client")
+	ErrActionNotSupported  = errors.New("Only login is supported")
+	ErrCHAPNotSupported    = errors.New("CHAP needs passwords kept in clear, use PAP or ASCII")
+	ErrAuthenTypeUnknown   = errors.New("Authentication type is not supported")
+	ErrNoRoleAllowed       = errors.New("Account has no role allowed device access")
+	ErrInvalidSequence     = errors.New("Unexpected TACACS+ sequence number")
+	ErrServiceNotSupported = errors.New("Only the shell service is authorized")
+	ErrCommandDenied       = errors.New("Command is not allowed")
+	ErrVoucherNotAllowed   = errors.New("Vouchers only get network access, not device logins")
+)
+
+// Client is a device allowed to connect, identified by its source address.
+type Client struct {
+	Name    string
+	Network *net.IPNet
+	Key     []byte
+}
+
+type Config struct {
+	Clients []Client
+	// Roles is in order of precedence: an account gets the privilege level of the first role it has,
+	// and the command rules of all of them.
+	Roles []RoleRules
+	// RequireRole refuses logins of accounts with none of the roles.
+	RequireRole bool
+}
+
+// Server answers TACACS+ (RFC 8907) authentication, authorization and accounting from network devices.
+// Passwords are checked by UsecaseHandler.LoginAccount, so TACACS+ logins count towards the same
+// lockout as the API. Accounting records go to the audit log.
+type Server struct {
+	config   Config
+	usecase  model.UsecaseHandler
+	accounts repository.AccountRepository
+	audit    model.AuditUsecase
+}
+
+func NewServer(config Config, usecase model.UsecaseHandler, accounts repository.AccountRepository, audit model.AuditUsecase) *Server {
+	return &Server{
+		config:   config,
+		usecase:  usecase,
+		accounts: accounts,
+		audit:    audit,
+	}
+}
+
+func (s *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve handles the connections accepted from listener until it is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// connection is the state of a device connection. Without single-connect mode it carries one session.
+type connection struct {
+	net.Conn
+	client        *Client
+	singleConnect bool
+	logins        map[uint32]*login
+}
+
+// login is an ASCII login waiting for the user to answer a prompt.
+type login struct {
+	seqNo    byte
+	status   byte
+	start    *AuthenStart
+	username string
+	password string
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	client := s.client(conn.RemoteAddr())
+	if client == nil {
+		log.Warn().Str("address", conn.RemoteAddr().String()).Msg(ErrUnknownClient.Error())
+		return
+	}
+	c := &connection{Conn: conn, client: client, logins: make(map[uint32]*login)}
+	for first := true; ; first = false {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		request, err := ReadPacket(conn, client.Key)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Warn().Err(err).Str("client", client.Name).Msg("dropped TACACS+ connection")
+			}
+			return
+		}
+		if first {
+			c.singleConnect = request.Flags&FlagSingleConnect != 0
+		}
+		body, done, err := s.serve(c, request)
+		if err != nil {
+			log.Warn().Err(err).Str("client", client.Name).Msg("dropped TACACS+ connection")
+			return
+		}
+		if body != nil {
+			reply := &Packet{Header: request.Header, Body: body}
+			reply.SeqNo++
+			reply.Flags = 0
+			if c.singleConnect {
+				reply.Flags = FlagSingleConnect
+			}
+			if err := WritePacket(conn, reply, client.Key); err != nil {
+				log.Error().Err(err).Str("client", client.Name).Msg("failed to send TACACS+ reply")
+				return
+			}
+		}
+		if done && !c.singleConnect && len(c.logins) == 0 {
+			return
+		}
+	}
+}
+
+// serve answers a packet. It returns the body of the reply, nil when none is sent, and whether the
+// session is over. An error closes the connection.
+func (s *Server) serve(c *connection, request *Packet) ([]byte, bool, error) {
+	// The device sends the odd sequence numbers and the server the even ones, up to 255.
+	if request.SeqNo%2 == 0 || request.SeqNo == 255 {
+		return nil, true, ErrInvalidSequence
+	}
+	switch request.Type {
+	case TypeAuthentication:
+		return s.authentication(c, request)
+	case TypeAuthorization:
+		if request.SeqNo != 1 {
+			return nil, true, ErrInvalidSequence
+		}
+		authorization, err := ParseAuthorRequest(request.Body)
+		if err != nil {
+			return nil, true, err
+		}
+		body, err := s.authorize(c, authorization).Encode()
+		return body, true, err
+	case TypeAccounting:
+		if request.SeqNo != 1 {
+			return nil, true, ErrInvalidSequence
+		}
+		accounting, err := ParseAcctRequest(request.Body)
+		if err != nil {
+			return nil, true, err
+		}
+		body, err := s.account(c, accounting).Encode()
+		return body, true, err
+	default:
+		return nil, true, ErrInvalidPacket
+	}
+}
+
+func (s *Server) authentication(c *connection, request *Packet) ([]byte, bool, error) {
+	var reply *AuthenReply
+	if request.SeqNo == 1 {
+		start, err := ParseAuthenStart(request.Body)
+		if err != nil {
+			return nil, true, err
+		}
+		delete(c.logins, request.SessionID)
+		reply = s.start(c, request.SessionID, start)
+	} else {
+		pending, ok := c.logins[request.SessionID]
+		if !ok || pending.seqNo != request.SeqNo {
+			return nil, true, ErrInvalidSequence
+		}
+		cont, err := ParseAuthenContinue(request.Body)
+		if err != nil {
+			return nil, true, err
+		}
+		delete(c.logins, request.SessionID)
+		if cont.Flags&ContinueFlagAbort != 0 {
+			return nil, true, nil
+		}
+		reply = s.answer(c, request.SessionID, pending, cont)
+	}
+	if pending, ok := c.logins[request.SessionID]; ok {
+		// The device answers the prompt with the sequence number after the reply's.
+		pending.seqNo = request.SeqNo + 2
+		pending.status = reply.Status
+	}
+	body, err := reply.Encode()
+	return body, reply.Status != AuthenStatusGetUser && reply.Status != AuthenStatusGetPass &&
+		reply.Status != AuthenStatusGetData, err
+}
+
+// start answers an AuthenStart. PAP carries the password, ASCII prompts for what is missing.
+func (s *Server) start(c *connection, sessionID uint32, start *AuthenStart) *AuthenReply {
+	ctx := s.context(c, start)
+	if start.Action != AuthenActionLogin {
+		return s.deny(ctx, start.User, ErrActionNotSupported)
+	}
+	switch start.AuthenType {
+	case AuthenTypePAP:
+		return s.login(ctx, c, sessionID, start, model.AccountRequest{Username: start.User, Password: string(start.Data)}, false)
+	case AuthenTypeASCII:
+		if len(c.logins) >= maxPendingLogins {
+			return &AuthenReply{Status: AuthenStatusError, ServerMsg: "Too many logins in progress"}
+		}
+		pending := &login{start: start, username: start.User}
+		c.logins[sessionID] = pending
+		if pending.username == "" {
+			return &AuthenReply{Status: AuthenStatusGetUser, ServerMsg: "Username: "}
+		}
+		return &AuthenReply{Status: AuthenStatusGetPass, Flags: ReplyFlagNoEcho, ServerMsg: "Password: "}
+	case AuthenTypeCHAP, AuthenTypeMSCHAP, AuthenTypeMSCHAPv2:
+		return s.deny(ctx, start.User, ErrCHAPNotSupported)
+	default:
+		return s.deny(ctx, start.User, ErrAuthenTypeUnknown)
+	}
+}
+
+// answer continues an ASCII login with what the user answered to the last prompt.
+func (s *Server) answer(c *connection, sessionID uint32, pending *login, cont *AuthenContinue) *AuthenReply {
+	ctx := s.context(c, pending.start)
+	switch pending.status {
+	case AuthenStatusGetUser:
+		pending.username = cont.UserMsg
+		c.logins[sessionID] = pending
+		if pending.username == "" {
+			return &AuthenReply{Status: AuthenStatusGetUser, ServerMsg: "Username: "}
+		}
+		return &AuthenReply{Status: AuthenStatusGetPass, Flags: ReplyFlagNoEcho, ServerMsg: "Password: "}
+	case AuthenStatusGetPass:
+		pending.password = cont.UserMsg
+		return s.login(ctx, c, sessionID, pending.start, model.AccountRequest{Username: pending.username, Password: pending.password}, true)
+	default:
+		return s.login(ctx, c, sessionID, pending.start,
+			model.AccountRequest{Username: pending.username, Password: pending.password, OTP: cont.UserMsg}, false)
+	}
+}
+
+// login checks the credentials with LoginAccount. When a one-time code is needed and the login is
+// interactive, the user is prompted for it.
+func (s *Server) login(ctx context.Context, c *connection, sessionID uint32, start *AuthenStart, req model.AccountRequest, interactive bool) *AuthenReply {
+	// LoginAccount would redeem a voucher, which starts its validity, so vouchers are turned away first.
+	voucher, err := s.isVoucher(ctx, req.Username)
+	if err != nil {
+		log.Error().Err(err).Str("client", c.client.Name).Str("username", req.Username).Msg("failed to check TACACS+ login")
+		return &AuthenReply{Status: AuthenStatusError, ServerMsg: "Login could not be checked"}
+	}
+	if voucher {
+		return s.deny(ctx, req.Username, ErrVoucherNotAllowed)
+	}
+	rsp, err := s.usecase.LoginAccount(ctx, req)
+	if rsp == nil {
+		log.Error().Err(err).Str("client", c.client.Name).Str("username", req.Username).Msg("failed to check TACACS+ login")
+		return &AuthenReply{Status: AuthenStatusError, ServerMsg: "Login could not be checked"}
+	}
+	if err == model.ErrLoginOTPRequired && interactive {
+		c.logins[sessionID] = &login{start: start, username: req.Username, password: req.Password}
+		return &AuthenReply{Status: AuthenStatusGetData, Flags: ReplyFlagNoEcho, ServerMsg: "One-time code: "}
+	}
+	if !rsp.Success {
+		return &AuthenReply{Status: AuthenStatusFail, ServerMsg: rsp.Reason}
+	}
+	if _, ok := s.role(rsp.Roles); !ok && s.config.RequireRole {
+		return s.deny(ctx, rsp.Username, ErrNoRoleAllowed)
+	}
+	return &AuthenReply{Status: AuthenStatusPass}
+}
+
+// isVoucher tells whether a login names a voucher, spelled as typed or as printed.
+func (s *Server) isVoucher(ctx context.Context, username string) (bool, error) {
+	account, err := s.accounts.GetAccount(ctx, username)
+	if code := model.NormalizeVoucherCode(username); err == repository.ErrAccountRecordNotFound && code != username && model.IsVoucherCode(code) {
+		account, err = s.accounts.GetAccount(ctx, code)
+	}
+	if err == repository.ErrAccountRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return account.Type == repository.AccountTypeVoucher, nil
+}
+
+// deny fails a login LoginAccount did not see, the audit log still records it.
+func (s *Server) deny(ctx context.Context, username string, err error) *AuthenReply {
+	s.audit.Record(ctx, model.AuditEntry{
+		Type:    model.AuditLoginDenied,
+		Target:  username,
+		Outcome: model.AuditOutcomeFailure,
+		Detail:  err.Error(),
+	})
+	return &AuthenReply{Status: AuthenStatusFail, ServerMsg: err.Error()}
+}
+
+// context describes where a request came from for LoginAccount and the audit log: the device, its
+// port and the address the user connected to it from.
+func (s *Server) context(c *connection, start *AuthenStart) context.Context {
+	return requestContext(c, start.Port, start.RemAddr)
+}
+
+func requestContext(c *connection, port string, remAddr string) context.Context {
+	return model.WithRequestInfo(context.Background(), model.RequestInfo{
+		IP:        c.RemoteAddr().(*net.TCPAddr).IP.String(),
+		UserAgent: strings.TrimSpace("tacacs/" + c.client.Name + " " + port + " " + remAddr),
+	})
+}
+
+func (s *Server) client(address net.Addr) *Client {
+	tcp, ok := address.(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+	for i := range s.config.Clients {
+		if s.config.Clients[i].Network.Contains(tcp.IP) {
+			return &s.config.Clients[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package tacacs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("tackey")
+
+func testConfig(t *testing.T) Config {
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	adminAllow, err := ParseRules(".*")
+	require.NoError(t, err)
+	adminDeny, err := ParseRules("reload.*")
+	require.NoError(t, err)
+	netopsAllow, err := ParseRules("show .*;ping .*")
+	require.NoError(t, err)
+	return Config{
+		Clients: []Client{{Name: "core", Network: loopback, Key: testKey}},
+		Roles: []RoleRules{
+			{Role: "admin", PrivLvl: 15, Allow: adminAllow, Deny: adminDeny},
+			{Role: "netops", PrivLvl: 1, Allow: netopsAllow},
+		},
+	}
+}
+
+// startServer serves on a loopback port and returns a connection to it.
+func startServer(t *testing.T, server *Server) net.Conn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(func() { listener.Close() })
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// exchange sends the body and returns the body of the reply, or nil when the server closed the
+// connection instead.
+func exchange(t *testing.T, conn net.Conn, packetType byte, seqNo byte, body []byte) []byte {
+	request := &Packet{Header: Header{Version: VersionDefault, Type: packetType, SeqNo: seqNo, SessionID: 42}, Body: body}
+	require.NoError(t, WritePacket(conn, request, testKey))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	reply, err := ReadPacket(conn, testKey)
+	if err != nil {
+		return nil
+	}
+	require.Equal(t, seqNo+1, reply.SeqNo)
+	require.Equal(t, request.SessionID, reply.SessionID)
+	return reply.Body
+}
+
+func authenStart(t *testing.T, conn net.Conn, start *AuthenStart) *AuthenReply {
+	body, err := start.Encode()
+	require.NoError(t, err)
+	reply, err := ParseAuthenReply(exchange(t, conn, TypeAuthentication, 1, body))
+	require.NoError(t, err)
+	return reply
+}
+
+func authenContinue(t *testing.T, conn net.Conn, seqNo byte, message string) *AuthenReply {
+	body, err := (&AuthenContinue{UserMsg: message}).Encode()
+	require.NoError(t, err)
+	reply, err := ParseAuthenReply(exchange(t, conn, TypeAuthentication, seqNo, body))
+	require.NoError(t, err)
+	return reply
+}
+
+func papStart(authenType byte) *AuthenStart {
+	return &AuthenStart{Action: AuthenActionLogin, PrivLvl: 1, AuthenType: authenType, User: "alice",
+		Port: "tty1", RemAddr: "10.0.0.9", Data: []byte("password")}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	login := model.AccountRequest{Username: "alice", Password: "password"}
+
+	testCase := []struct {
+		name        string
+		start       *AuthenStart
+		requireRole bool
+		voucher     bool
+		buildStubs  func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase)
+		status      byte
+		message     string
+	}{
+		{
+			name:  "pap ok",
+			start: papStart(AuthenTypePAP),
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), login).
+					Return(&model.AccountResponse{Success: true, Username: "alice", Roles: []string{"netops"}}, nil)
+			},
+			status: AuthenStatusPass,
+		},
+		{
+			name:  "pap wrong password",
+			start: papStart(AuthenTypePAP),
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), login).
+					Return(&model.AccountResponse{Reason: model.ErrLoginWrongPassword.Error()}, model.ErrLoginWrongPassword)
+			},
+			status:  AuthenStatusFail,
+			message: model.ErrLoginWrongPassword.Error(),
+		},
+		{
+			name:  "pap otp required",
+			start: papStart(AuthenTypePAP),
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), login).
+					Return(&model.AccountResponse{Reason: model.ErrLoginOTPRequired.Error()}, model.ErrLoginOTPRequired)
+			},
+			status:  AuthenStatusFail,
+			message: model.ErrLoginOTPRequired.Error(),
+		},
+		{
+			name:  "login not checked",
+			start: papStart(AuthenTypePAP),
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), login).Return(nil, repository.ErrAccountRecordNotFound)
+			},
+			status:  AuthenStatusError,
+			message: "Login could not be checked",
+		},
+		{
+			name:        "no role allowed",
+			start:       papStart(AuthenTypePAP),
+			requireRole: true,
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), login).
+					Return(&model.AccountResponse{Success: true, Username: "alice", Roles: []string{"viewer"}}, nil)
+				audit.EXPECT().Record(gomock.Any(), model.AuditEntry{Type: model.AuditLoginDenied, Target: "alice",
+					Outcome: model.AuditOutcomeFailure, Detail: ErrNoRoleAllowed.Error()})
+			},
+			status:  AuthenStatusFail,
+			message: ErrNoRoleAllowed.Error(),
+		},
+		{
+			name:    "voucher",
+			start:   papStart(AuthenTypePAP),
+			voucher: true,
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				usecase.EXPECT().LoginAccount(gomock.Any(), gomock.Any()).Times(0)
+				audit.EXPECT().Record(gomock.Any(), model.AuditEntry{Type: model.AuditLoginDenied, Target: "alice",
+					Outcome: model.AuditOutcomeFailure, Detail: ErrVoucherNotAllowed.Error()})
+			},
+			status:  AuthenStatusFail,
+			message: ErrVoucherNotAllowed.Error(),
+		},
+		{
+			name:  "chap",
+			start: papStart(AuthenTypeCHAP),
+			buildStubs: func(usecase *model.MockUsecaseHandler, audit *model.MockAuditUsecase) {
+				audit.EXPECT().Record(gomock.Any(), model.AuditEntry{Type: model.AuditLoginDenied, Target: "alice",
+					Outcome: model.AuditOutcomeFailure, Detail: ErrCHAPNotSupported.Error()})
+			},
+			status:  AuthenStatusFail,
+			message: ErrCHAPNotSupported.Error(),
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			usecase := model.NewMockUsecaseHandler(ctrl)
+			audit := model.NewMockAuditUsecase(ctrl)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			tc.buildStubs(usecase, audit)
+			account := &repository.Account{Username: "alice", Type: repository.AccountTypeUser}
+			if tc.voucher {
+				account.Type = repository.AccountTypeVoucher
+			}
+			accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil).AnyTimes()
+			config := testConfig(t)
+			config.RequireRole = tc.requireRole
+
+			conn := startServer(t, NewServer(config, usecase, accounts, audit))
+			reply := authenStart(t, conn, tc.start)
+			require.Equal(t, tc.status, reply.Status)
+			require.Equal(t, tc.message, reply.ServerMsg)
+		})
+	}
+}
+
+func TestServerASCIILoginWithOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	usecase := model.NewMockUsecaseHandler(ctrl)
+	accounts := repository.NewMockAccountRepository(ctrl)
+	accounts.EXPECT().GetAccount(gomock.Any(), "alice").
+		Return(&repository.Account{Username: "alice", Type: repository.AccountTypeUser}, nil).AnyTimes()
+	conn := startServer(t, NewServer(testConfig(t), usecase, accounts, model.NewMockAuditUsecase(ctrl)))
+
+	start := papStart(AuthenTypeASCII)
+	start.User, start.Data = "", nil
+	reply := authenStart(t, conn, start)
+	require.Equal(t, byte(AuthenStatusGetUser), reply.Status)
+
+	reply = authenContinue(t, conn, 3, "alice")
+	require.Equal(t, byte(AuthenStatusGetPass), reply.Status)
+	require.Equal(t, byte(ReplyFlagNoEcho), reply.Flags)
+
+	usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "password"}).
+		Return(&model.AccountResponse{Reason: model.ErrLoginOTPRequired.Error()}, model.ErrLoginOTPRequired)
+	reply = authenContinue(t, conn, 5, "password")
+	require.Equal(t, byte(AuthenStatusGetData), reply.Status)
+
+	usecase.EXPECT().LoginAccount(gomock.Any(), model.AccountRequest{Username: "alice", Password: "password", OTP: "123456"}).
+		Return(&model.AccountResponse{Success: true, Username: "alice"}, nil)
+	reply = authenContinue(t, conn, 7, "123456")
+	require.Equal(t, byte(AuthenStatusPass), reply.Status)
+}
+
+func TestServerAuthorization(t *testing.T) {
+	testCase := []struct {
+		name       string
+		roles      string
+		locked     bool
+		voucher    bool
+		args       []string
+		buildStubs func(audit *model.MockAuditUsecase)
+		status     byte
+		replyArgs  []string
+	}{
+		{
+			name:      "shell",
+			roles:     "netops admin",
+			args:      []string{"service=shell", "cmd="},
+			status:    AuthorStatusPassAdd,
+			replyArgs: []string{"priv-lvl=15"},
+		},
+		{
+			name:   "allowed command",
+			roles:  "netops",
+			args:   []string{"service=shell", "cmd=show", "cmd-arg=running-config", "cmd-arg=<cr>"},
+			status: AuthorStatusPassAdd,
+		},
+		{
+			name:  "command not allowed",
+			roles: "netops",
+			args:  []string{"service=shell", "cmd=configure", "cmd-arg=terminal", "cmd-arg=<cr>"},
+			buildStubs: func(audit *model.MockAuditUsecase) {
+				audit.EXPECT().Record(gomock.Any(), model.AuditEntry{Type: model.AuditDeviceAuthorization, Actor: "alice",
+					Target: "device:core", Outcome: model.AuditOutcomeFailure, Detail: ErrCommandDenied.Error() + ": configure terminal"})
+			},
+			status: AuthorStatusFail,
+		},
+		{
+			name:  "command denied by another role",
+			roles: "admin netops",
+			args:  []string{"service=shell", "cmd=reload", "cmd-arg=<cr>"},
+			buildStubs: func(audit *model.MockAuditUsecase) {
+				audit.EXPECT().Record(gomock.Any(), gomock.Any())
+			},
+			status: AuthorStatusFail,
+		},
+		{
+			name:   "locked account",
+			roles:  "netops",
+			locked: true,
+			args:   []string{"service=shell", "cmd=show", "cmd-arg=running-config", "cmd-arg=<cr>"},
+			buildStubs: func(audit *model.MockAuditUsecase) {
+				audit.EXPECT().Record(gomock.Any(), model.AuditEntry{Type: model.AuditDeviceAuthorization, Actor: "alice",
+					Target: "device:core", Outcome: model.AuditOutcomeFailure, Detail: model.ErrLoginAccountLocked.Error()})
+			},
+			status: AuthorStatusFail,
+		},
+		{
+			name:    "voucher",
+			voucher: true,
+			args:    []string{"service=shell", "cmd="},
+			buildStubs: func(audit *model.MockAuditUsecase) {
+				audit.EXPECT().Record(gomock.Any(), model.AuditEntry{Type: model.AuditDeviceAuthorization, Actor: "alice",
+					Target: "device:core", Outcome: model.AuditOutcomeFailure, Detail: ErrVoucherNotAllowed.Error()})
+			},
+			status: AuthorStatusFail,
+		},
+		{
+			name:  "other service",
+			roles: "admin",
+			args:  []string{"service=ppp", "protocol=ip"},
+			buildStubs: func(audit *model.MockAuditUsecase) {
+				audit.EXPECT().Record(gomock.Any(), gomock.Any())
+			},
+			status: AuthorStatusFail,
+		},
+	}
+
+	for i := range testCase {
+		tc := testCase[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accounts := repository.NewMockAccountRepository(ctrl)
+			audit := model.NewMockAuditUsecase(ctrl)
+			account := &repository.Account{Username: "alice", Type: repository.AccountTypeUser, Roles: tc.roles}
+			if tc.locked {
+				lockedAt := time.Now()
+				account.LockedAt = &lockedAt
+			}
+			if tc.voucher {
+				account.Type = repository.AccountTypeVoucher
+			}
+			accounts.EXPECT().GetAccount(gomock.Any(), "alice").Return(account, nil)
+			if tc.buildStubs != nil {
+				tc.buildStubs(audit)
+			}
+
+			conn := startServer(t, NewServer(testConfig(t), model.NewMockUsecaseHandler(ctrl), accounts, audit))
+			body, err := (&AuthorRequest{User: "alice", Port: "tty1", RemAddr: "10.0.0.9", Args: tc.args}).Encode()
+			require.NoError(t, err)
+			response, err := ParseAuthorResponse(exchange(t, conn, TypeAuthorization, 1, body))
+			require.NoError(t, err)
+			require.Equal(t, tc.status, response.Status)
+			require.Equal(t, tc.replyArgs, response.Args)
+		})
+	}
+}
+
+func TestServerAccounting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	audit := model.NewMockAuditUsecase(ctrl)
+	audit.EXPECT().Record(gomock.Any(), model.AuditEntry{
+		Type:    model.AuditDeviceAccounting,
+		Actor:   "alice",
+		Target:  "device:core",
+		Outcome: model.AuditOutcomeSuccess,
+		Detail:  "stop task_id=7 service=shell cmd=show version <cr>",
+	})
+	conn := startServer(t, NewServer(testConfig(t), model.NewMockUsecaseHandler(ctrl), repository.NewMockAccountRepository(ctrl), audit))
+
+	body, err := (&AcctRequest{Flags: AcctFlagStop, User: "alice", Port: "tty1", RemAddr: "10.0.0.9",
+		Args: []string{"task_id=7", "service=shell", "cmd=show version <cr>"}}).Encode()
+	require.NoError(t, err)
+	reply, err := ParseAcctReply(exchange(t, conn, TypeAccounting, 1, body))
+	require.NoError(t, err)
+	require.Equal(t, byte(AcctStatusSuccess), reply.Status)
+}
+
+func TestServerClosesConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	conn := startServer(t, NewServer(testConfig(t), model.NewMockUsecaseHandler(ctrl), repository.NewMockAccountRepository(ctrl), model.NewMockAuditUsecase(ctrl)))
+
+	body, err := (&AuthenContinue{UserMsg: "alice"}).Encode()
+	require.NoError(t, err)
+	require.Nil(t, exchange(t, conn, TypeAuthentication, 3, body))
+}
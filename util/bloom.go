@@ -0,0 +1,60 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+// BloomFilter answers whether a key may have been added. It has no false negatives, and false
+// positives at about the rate it was sized for while it holds no more than the expected keys.
+type BloomFilter struct {
+	mu     sync.RWMutex
+	bits   []uint64
+	hashes uint64
+}
+
+// NewBloomFilter sizes a filter for expected keys at falsePositiveRate.
+func NewBloomFilter(expected int, falsePositiveRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	m := math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(expected)*math.Ln2))
+	return &BloomFilter{
+		bits:   make([]uint64, (uint64(m)+63)/64),
+		hashes: uint64(k),
+	}
+}
+
+func (f *BloomFilter) Add(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.each(key, func(bit uint64) {
+		f.bits[bit/64] |= 1 << (bit % 64)
+	})
+}
+
+func (f *BloomFilter) MayContain(key string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	found := true
+	f.each(key, func(bit uint64) {
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			found = false
+		}
+	})
+	return found
+}
+
+// each calls fn with the bit of every hash of key, derived from two halves of its SHA-256.
+func (f *BloomFilter) each(key string, fn func(bit uint64)) {
+	sum := sha256.Sum256([]byte(key))
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16])
+	size := uint64(len(f.bits)) * 64
+	for i := uint64(0); i < f.hashes; i++ {
+		fn((h1 + i*h2) % size)
+	}
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidEd25519Key  = errors.New("Invalid ed25519 key")
+	ErrInvalidPrivateKey  = errors.New("Invalid private key")
+	ErrInvalidPublicKey   = errors.New("Invalid public key")
+	ErrUnsupportedKeyType = errors.New("Unsupported key type")
+)
+
+// DecodeEd25519PrivateKey accepts a base64 encoded 32 byte seed or 64 byte private key.
+func DecodeEd25519PrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidEd25519Key
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, ErrInvalidEd25519Key
+}
+
+func DecodeEd25519PublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, ErrInvalidEd25519Key
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// Ed25519KeyID is a short fingerprint that identifies which key signed something.
+func Ed25519KeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParsePrivateKeys reads signing keys from PEM blocks (PKCS#8, PKCS#1 RSA or SEC 1 EC), or from comma
+// separated base64 Ed25519 keys.
+func ParsePrivateKeys(encoded string) ([]crypto.Signer, error) {
+	var keys []crypto.Signer
+	if !strings.Contains(encoded, "-----BEGIN") {
+		for _, part := range strings.Split(encoded, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			key, err := DecodeEd25519PrivateKey(part)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+
+	rest := []byte(encoded)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		key, err := parsePEMPrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	return keys, nil
+}
+
+func parsePEMPrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, ErrInvalidPrivateKey
+	}
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKeyType
+	}
+	return signer, nil
+}
+
+// ParsePublicKey reads a PKIX public key from a PEM block.
+func ParsePublicKey(encoded string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrInvalidPublicKey
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return key, nil
+}
+
+// EncodePrivateKey writes a private key as a PKCS#8 PEM block.
+func EncodePrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeEd25519Key(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	fromSeed, err := DecodeEd25519PrivateKey(base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, fromSeed)
+
+	fromKey, err := DecodeEd25519PrivateKey(base64.StdEncoding.EncodeToString(privateKey))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, fromKey)
+
+	decodedPublicKey, err := DecodeEd25519PublicKey(base64.StdEncoding.EncodeToString(publicKey))
+	require.NoError(t, err)
+	require.Equal(t, publicKey, decodedPublicKey)
+	require.Equal(t, Ed25519KeyID(publicKey), Ed25519KeyID(fromSeed.Public().(ed25519.PublicKey)))
+
+	_, err = DecodeEd25519PrivateKey("not base64")
+	require.EqualError(t, err, ErrInvalidEd25519Key.Error())
+	_, err = DecodeEd25519PublicKey(base64.StdEncoding.EncodeToString([]byte(RandomString(8))))
+	require.EqualError(t, err, ErrInvalidEd25519Key.Error())
+}
+
+func TestParsePrivateKeys(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, otherEdKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keys, err := ParsePrivateKeys(base64.StdEncoding.EncodeToString(edKey.Seed()) + ", " + base64.StdEncoding.EncodeToString(otherEdKey))
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, edKey, keys[0])
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	encoded := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+
+	keys, err = ParsePrivateKeys(encoded)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	require.IsType(t, &rsa.PrivateKey{}, keys[0])
+	require.IsType(t, &ecdsa.PrivateKey{}, keys[1])
+	require.Equal(t, edKey, keys[2])
+
+	_, err = ParsePrivateKeys("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n")
+	require.EqualError(t, err, ErrInvalidPrivateKey.Error())
+}
+
+func TestEncodePrivateKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	encoded, err := EncodePrivateKey(ecKey)
+	require.NoError(t, err)
+
+	keys, err := ParsePrivateKeys(encoded)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.True(t, ecKey.Equal(keys[0]))
+
+	der, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	require.NoError(t, err)
+	publicKey, err := ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	require.NoError(t, err)
+	require.True(t, ecKey.PublicKey.Equal(publicKey))
+
+	_, err = ParsePublicKey(encoded)
+	require.EqualError(t, err, ErrInvalidPublicKey.Error())
+}
@@ -30,3 +30,15 @@ func TestPasswordDifferentHashedPassword(t *testing.T) {
 	require.NotEmpty(t, hashedPassword2)
 	require.NotEqual(t, hashedPassword1, hashedPassword2)
 }
+
+func TestRandomSecurePassword(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		password, err := RandomSecurePassword(8)
+		require.NoError(t, err)
+		require.Len(t, password, 8)
+		require.Regexp(t, `^[a-zA-Z0-9]+$`, password)
+		require.Regexp(t, `[a-z]`, password)
+		require.Regexp(t, `[A-Z]`, password)
+		require.Regexp(t, `[0-9]`, password)
+	}
+}
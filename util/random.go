@@ -1,6 +1,9 @@
 package util
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"math/big"
 	"math/rand"
 	"strings"
 	"time"
@@ -44,3 +47,40 @@ func RandomPassword(n int) string {
 
 	return sb.String()
 }
+
+// RandomToken returns n cryptographically random bytes, hex encoded. Use it for secrets.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RandomSecurePassword returns a cryptographically random password of n letters and numbers with at
+// least one uppercase letter, one lowercase letter and one number. n must be at least 3.
+func RandomSecurePassword(n int) (string, error) {
+	charset := alphabet + upperAlphabet + number
+	for {
+		b := make([]byte, n)
+		hasLower, hasUpper, hasNumber := false, false, false
+		for i := range b {
+			k, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(len(charset))))
+			if err != nil {
+				return "", err
+			}
+			b[i] = charset[k.Int64()]
+			switch {
+			case strings.IndexByte(alphabet, b[i]) >= 0:
+				hasLower = true
+			case strings.IndexByte(upperAlphabet, b[i]) >= 0:
+				hasUpper = true
+			default:
+				hasNumber = true
+			}
+		}
+		if hasLower && hasUpper && hasNumber {
+			return string(b), nil
+		}
+	}
+}
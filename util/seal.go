@@ -0,0 +1,76 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+)
+
+const MasterKeySize = 32
+
+var (
+	ErrInvalidMasterKey  = errors.New("Invalid master key")
+	ErrSealedDataCorrupt = errors.New("Sealed data is corrupt or was sealed with another key")
+)
+
+// LoadMasterKey reads a 32 byte AES-256 key from path, either raw or base64 encoded.
+func LoadMasterKey(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == MasterKeySize {
+		return content, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil || len(key) != MasterKeySize {
+		return nil, ErrInvalidMasterKey
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext with AES-256-GCM. additionalData is authenticated but not stored, so a
+// sealed value only opens with the same additionalData, which binds it to its row.
+func Seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts a value made by Seal.
+func Open(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrSealedDataCorrupt
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrSealedDataCorrupt
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != MasterKeySize {
+		return nil, ErrInvalidMasterKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
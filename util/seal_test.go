@@ -0,0 +1,59 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeal(t *testing.T) {
+	key := make([]byte, MasterKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	sealed, err := Seal(key, []byte("private key"), []byte("kid"))
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed), "private key")
+
+	plaintext, err := Open(key, sealed, []byte("kid"))
+	require.NoError(t, err)
+	require.Equal(t, "private key", string(plaintext))
+
+	_, err = Open(key, sealed, []byte("other kid"))
+	require.ErrorIs(t, err, ErrSealedDataCorrupt)
+
+	otherKey := make([]byte, MasterKeySize)
+	_, err = Open(otherKey, sealed, []byte("kid"))
+	require.ErrorIs(t, err, ErrSealedDataCorrupt)
+
+	_, err = Seal(key[:16], []byte("private key"), nil)
+	require.ErrorIs(t, err, ErrInvalidMasterKey)
+}
+
+func TestLoadMasterKey(t *testing.T) {
+	key := make([]byte, MasterKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	raw := filepath.Join(dir, "raw")
+	require.NoError(t, os.WriteFile(raw, key, 0600))
+	loaded, err := LoadMasterKey(raw)
+	require.NoError(t, err)
+	require.Equal(t, key, loaded)
+
+	encoded := filepath.Join(dir, "encoded")
+	require.NoError(t, os.WriteFile(encoded, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+	loaded, err = LoadMasterKey(encoded)
+	require.NoError(t, err)
+	require.Equal(t, key, loaded)
+
+	short := filepath.Join(dir, "short")
+	require.NoError(t, os.WriteFile(short, []byte("c2hvcnQ="), 0600))
+	_, err = LoadMasterKey(short)
+	require.ErrorIs(t, err, ErrInvalidMasterKey)
+}
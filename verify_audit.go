@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ambroseqiu/senao_hw/model"
+	"github.com/ambroseqiu/senao_hw/util"
+)
+
+const defaultAuditCheckpointInterval = time.Hour
+
+func auditCheckpointInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("AUDIT_CHECKPOINT_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return defaultAuditCheckpointInterval
+	}
+	return interval
+}
+
+// verifyAudit walks the audit chain and prints the first broken link. Checkpoints are checked against
+// every audit key the key manager ever had, and against AUDIT_VERIFY_KEY for checkpoints signed
+// before the key manager existed. The master key is the trust anchor: a key whose private half
+// does not open under it is not trusted, so a key planted in the database stops the check.
+func verifyAudit(audit model.AuditUsecase, keys model.KeyManager) int {
+	verificationKeys, err := keys.VerificationKeys(context.Background(), model.KeyPurposeAudit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load audit keys: %v\n", err)
+		return 2
+	}
+	// Checkpoints signed with AUDIT_SIGNING_KEY name the key by its old fingerprint.
+	for _, key := range verificationKeys {
+		if publicKey, ok := key.Key.(ed25519.PublicKey); ok {
+			verificationKeys = append(verificationKeys, model.VerificationKey{
+				ID:        util.Ed25519KeyID(publicKey),
+				Key:       publicKey,
+				RevokedAt: key.RevokedAt,
+			})
+		}
+	}
+	if encoded := os.Getenv("AUDIT_VERIFY_KEY"); encoded != "" {
+		publicKey, err := util.DecodeEd25519PublicKey(encoded)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid AUDIT_VERIFY_KEY: %v\n", err)
+			return 2
+		}
+		verificationKeys = append(verificationKeys, model.VerificationKey{ID: util.Ed25519KeyID(publicKey), Key: publicKey})
+	}
+	if len(verificationKeys) == 0 {
+		fmt.Fprintln(os.Stderr, "there are no audit keys to verify with")
+		return 2
+	}
+
+	report, err := audit.VerifyChain(context.Background(), verificationKeys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to verify audit chain: %v\n", err)
+		return 2
+	}
+	if !report.OK() {
+		fmt.Printf("audit chain is broken at event #%d: %s\n", report.BrokenEventID, report.Reason)
+		fmt.Printf("checked %d events and %d checkpoints before the break\n", report.EventsChecked, report.CheckpointsChecked)
+		return 1
+	}
+	fmt.Printf("audit chain is intact: checked %d events and %d checkpoints\n", report.EventsChecked, report.CheckpointsChecked)
+	return 0
+}